@@ -0,0 +1,177 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Anonymizer turns AttestationResults into a form that can be shared for
+// posture analytics without exposing the identity of the attested devices or
+// of the verifier.  Identifiers are replaced by pseudonyms computed as the
+// base64url-encoded HMAC-SHA256 of the identifier under a caller-supplied key,
+// so that the same device is consistently mapped onto the same pseudonym
+// within an export, but cannot be recovered without the key.  Trust tiers and
+// trust vector claims are preserved.
+type Anonymizer struct {
+	key []byte
+}
+
+// NewAnonymizer returns an Anonymizer using the supplied HMAC key
+func NewAnonymizer(key []byte) (*Anonymizer, error) {
+	if len(key) == 0 {
+		return nil, errors.New("empty HMAC key")
+	}
+
+	return &Anonymizer{key: append([]byte(nil), key...)}, nil
+}
+
+// Anonymize returns a copy of the AttestationResult in which:
+//   - the verifier identity, the TEE evidence ID, the NAE session ID and
+//     identity, the thumbprint of the key that verified a migrated result,
+//     and the attested key ("akpub") and its "kid" and "jkt" are
+//     pseudonymized;
+//   - the nonce, the raw evidence and the reference to it, the TEE evidence,
+//     the Veraison annotated evidence and policy claims, the SEV-SNP report
+//     ID, and attested keys conveyed as JWK or COSE_Key are removed;
+//   - everything else, including status, trust vector, registered extensions
+//     and, if preserved on decoding, unknown claims, is retained.
+//
+// The result must be valid, and so is the returned copy.
+func (o Anonymizer) Anonymize(ar AttestationResult) (AttestationResult, error) {
+	m, err := ar.claimsMap()
+	if err != nil {
+		return AttestationResult{}, err
+	}
+
+	delete(m, "eat_nonce")
+	delete(m, "ear.raw-evidence")
+	delete(m, "ear.veraison.evidence-ref")
+
+	o.pseudonymizeMembers(m, "ear.verifier-id", "build", "developer")
+	o.pseudonymizeMembers(m, "ear.nae.tts-info", "sessionid", "identity")
+	o.pseudonymizeMembers(m, "ear.veraison.migration", "original-key-thumbprint")
+	o.pseudonymizeMembers(m, "ear.veraison.tee-info", "evidence-id")
+	deleteMembers(m, "ear.veraison.tee-info", "evidence")
+
+	submods, _ := m["submods"].(map[string]interface{})
+	for _, v := range submods {
+		if a, ok := v.(map[string]interface{}); ok {
+			o.anonymizeAppraisal(a)
+		}
+	}
+
+	ret := AttestationResult{Registry: ar.Registry}
+
+	if err := ret.replaceClaims(m); err != nil {
+		return AttestationResult{}, err
+	}
+
+	return ret, nil
+}
+
+func (o Anonymizer) anonymizeAppraisal(a map[string]interface{}) {
+	delete(a, "ear.veraison.annotated-evidence")
+	delete(a, "ear.veraison.policy-claims")
+
+	deleteMembers(a, "ear.veraison.snp-info", "report-id")
+
+	if ka, ok := a["ear.veraison.key-attestation"].(map[string]interface{}); ok {
+		anon := map[string]interface{}{}
+
+		// keys conveyed as JWK or COSE_Key are dropped, while the members
+		// that are plain identifiers are pseudonymized
+		for _, name := range []string{keyAttestationAKPub, keyAttestationKeyID, keyAttestationThumbprint} {
+			if id, ok := ka[name].(string); ok {
+				anon[name] = o.Pseudonym(id)
+			}
+		}
+
+		if len(anon) > 0 {
+			a["ear.veraison.key-attestation"] = anon
+		} else {
+			delete(a, "ear.veraison.key-attestation")
+		}
+	}
+}
+
+// pseudonymizeMembers replaces the named string members of the claim, if
+// present, with their pseudonyms
+func (o Anonymizer) pseudonymizeMembers(m map[string]interface{}, claim string, members ...string) {
+	obj, ok := m[claim].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for _, name := range members {
+		if id, ok := obj[name].(string); ok {
+			obj[name] = o.Pseudonym(id)
+		}
+	}
+}
+
+// deleteMembers removes the named members of the claim, if present
+func deleteMembers(m map[string]interface{}, claim string, members ...string) {
+	obj, ok := m[claim].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for _, name := range members {
+		delete(obj, name)
+	}
+}
+
+// Pseudonym returns the pseudonym for the supplied identifier
+func (o Anonymizer) Pseudonym(id string) string {
+	mac := hmac.New(sha256.New, o.key)
+	mac.Write([]byte(id))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (o Anonymizer) pseudonymPtr(id *string) *string {
+	if id == nil {
+		return nil
+	}
+
+	p := o.Pseudonym(*id)
+
+	return &p
+}
+
+// AnalyticsExporter writes anonymized AttestationResults to an io.Writer, one
+// JSON object per line.  It is safe for concurrent use.
+type AnalyticsExporter struct {
+	anon *Anonymizer
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewAnalyticsExporter returns an AnalyticsExporter that anonymizes results
+// using the supplied Anonymizer, and writes them to w
+func NewAnalyticsExporter(w io.Writer, anon *Anonymizer) *AnalyticsExporter {
+	return &AnalyticsExporter{anon: anon, enc: json.NewEncoder(w)}
+}
+
+// Export anonymizes the AttestationResult and writes it out
+func (o *AnalyticsExporter) Export(ar AttestationResult) error {
+	a, err := o.anon.Anonymize(ar)
+	if err != nil {
+		return fmt.Errorf("anonymizing: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.enc.Encode(a)
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHMACKey = []byte("analytics-export-key")
+
+func TestNewAnonymizer_empty_key(t *testing.T) {
+	_, err := NewAnonymizer(nil)
+	assert.EqualError(t, err, "empty HMAC key")
+}
+
+func TestAnonymizer_Anonymize(t *testing.T) {
+	anon, err := NewAnonymizer(testHMACKey)
+	require.NoError(t, err)
+
+	nonce := Nonces{testNonce}
+	rawEvidence := B64Url(testEvidence)
+	evidenceID := testEvidenceID
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Nonce = &nonce
+	ar.RawEvidence = &rawEvidence
+	ar.VeraisonTeeInfo = &VeraisonTeeInfo{
+		TeeName:    &testTeeName,
+		EvidenceID: &evidenceID,
+		Evidence:   &testEvidence,
+	}
+	ar.Submods = map[string]*Appraisal{
+		"test": {
+			Status:      &testStatus,
+			TrustVector: &TrustVector{InstanceIdentity: TrustworthyInstanceClaim},
+			AppraisalExtensions: AppraisalExtensions{
+				VeraisonAnnotatedEvidence: &map[string]interface{}{"k1": "v1"},
+				VeraisonKeyAttestation:    &map[string]interface{}{"akpub": "YWtwdWIK", "kid": "slot-7"},
+			},
+		},
+	}
+
+	actual, err := anon.Anonymize(ar)
+	require.NoError(t, err)
+
+	// identifiers are pseudonymized
+	assert.Equal(t, anon.Pseudonym(testVidBuild), *actual.VerifierID.Build)
+	assert.Equal(t, anon.Pseudonym(testVidDeveloper), *actual.VerifierID.Developer)
+	assert.NotEqual(t, testVidBuild, *actual.VerifierID.Build)
+	assert.Equal(t, anon.Pseudonym(testEvidenceID), *actual.VeraisonTeeInfo.EvidenceID)
+	assert.Equal(t,
+		map[string]interface{}{"akpub": anon.Pseudonym("YWtwdWIK"), "kid": anon.Pseudonym("slot-7")},
+		*actual.Submods["test"].VeraisonKeyAttestation)
+
+	// identifying material is dropped
+	assert.Nil(t, actual.Nonce)
+	assert.Nil(t, actual.RawEvidence)
+	assert.Nil(t, actual.VeraisonTeeInfo.Evidence)
+	assert.Nil(t, actual.Submods["test"].VeraisonAnnotatedEvidence)
+
+	// posture is preserved
+	assert.Equal(t, testTeeName, *actual.VeraisonTeeInfo.TeeName)
+	assert.Equal(t, testStatus, *actual.Submods["test"].Status)
+	assert.Equal(t, *ar.Submods["test"].TrustVector, *actual.Submods["test"].TrustVector)
+
+	// the original is untouched
+	assert.Equal(t, testVidBuild, *ar.VerifierID.Build)
+	assert.NotNil(t, ar.Submods["test"].VeraisonAnnotatedEvidence)
+}
+
+func TestAnonymizer_Anonymize_extensions(t *testing.T) {
+	anon, err := NewAnonymizer(testHMACKey)
+	require.NoError(t, err)
+
+	r := NewRegistry()
+	require.NoError(t, r.RegisterAppraisalExtension(testAcmeExtension))
+
+	measurement := B64Url(make([]byte, snpMeasurementSize))
+	reportID := B64Url(make([]byte, snpReportIDSize))
+	uri := "https://evidence.example/device-1234"
+
+	var ext AppraisalExtensions
+	require.NoError(t, r.SetExtensionClaim(&ext, "ear.acme.info", &testAcmeInfo{Firmware: "1.0"}))
+	require.NoError(t, ext.SetSNPInfo(VeraisonSNPInfo{Measurement: &measurement, ReportID: &reportID}))
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Registry = r
+	ar.UnknownClaims = map[string]interface{}{"ear.future-claim": "posture"}
+	ar.Submods = map[string]*Appraisal{
+		"test": {Status: &testStatus, AppraisalExtensions: ext},
+	}
+	require.NoError(t, ar.SetEvidenceRef(VeraisonEvidenceRef{MediaType: "application/eat+cwt", URI: &uri}))
+
+	actual, err := anon.Anonymize(ar)
+	require.NoError(t, err)
+
+	// registered extensions and unknown claims are preserved
+	assert.Equal(t, r, actual.Registry)
+	assert.Equal(t, &testAcmeInfo{Firmware: "1.0"}, actual.Submods["test"].ExtensionClaims["ear.acme.info"])
+	assert.Equal(t, ar.UnknownClaims, actual.UnknownClaims)
+	assert.Equal(t, &measurement, actual.Submods["test"].VeraisonSNPInfo.Measurement)
+
+	// while the identifying ones are not
+	assert.Nil(t, actual.Submods["test"].VeraisonSNPInfo.ReportID)
+	assert.Nil(t, actual.VeraisonEvidenceRef)
+}
+
+func TestAnonymizer_Anonymize_invalid(t *testing.T) {
+	anon, err := NewAnonymizer(testHMACKey)
+	require.NoError(t, err)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = nil
+
+	_, err = anon.Anonymize(ar)
+	assert.EqualError(t, err, "missing mandatory 'submods' (at least one appraisal must be present)")
+
+	var buf bytes.Buffer
+
+	err = NewAnalyticsExporter(&buf, anon).Export(ar)
+	assert.EqualError(t, err, "anonymizing: missing mandatory 'submods' (at least one appraisal must be present)")
+	assert.Zero(t, buf.Len())
+}
+
+func TestAnonymizer_Pseudonym(t *testing.T) {
+	a1, err := NewAnonymizer(testHMACKey)
+	require.NoError(t, err)
+
+	a2, err := NewAnonymizer([]byte("another-key"))
+	require.NoError(t, err)
+
+	assert.Equal(t, a1.Pseudonym("device-1"), a1.Pseudonym("device-1"))
+	assert.NotEqual(t, a1.Pseudonym("device-1"), a1.Pseudonym("device-2"))
+	assert.NotEqual(t, a1.Pseudonym("device-1"), a2.Pseudonym("device-1"))
+}
+
+func TestAnalyticsExporter_Export(t *testing.T) {
+	anon, err := NewAnonymizer(testHMACKey)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	e := NewAnalyticsExporter(&buf, anon)
+
+	require.NoError(t, e.Export(testAttestationResultsWithVeraisonExtns))
+	require.NoError(t, e.Export(testAttestationResultsWithVeraisonExtns))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var actual AttestationResult
+	require.NoError(t, actual.UnmarshalJSON(lines[0]))
+
+	assert.Equal(t, anon.Pseudonym(testVidBuild), *actual.VerifierID.Build)
+	assert.Nil(t, actual.Submods["test"].VeraisonPolicyClaims)
+	assert.NotContains(t, buf.String(), testVidDeveloper)
+}
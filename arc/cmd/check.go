@@ -0,0 +1,227 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+)
+
+const (
+	checkFormatText = "text"
+	checkFormatJSON = "json"
+
+	// kinds of problems reported by check
+	checkKindSyntax    = "syntax"
+	checkKindDuplicate = "duplicate"
+	checkKindUnknown   = "unknown"
+	checkKindDecode    = "decode"
+	checkKindMissing   = "missing"
+	checkKindInvalid   = "invalid"
+)
+
+var (
+	checkInput  string
+	checkFormat string
+)
+
+var checkCmd = NewCheckCmd()
+
+// checkProblem is a single problem found in the claims-set
+type checkProblem struct {
+	Claim   string `json:"claim,omitempty"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// checkResult is the JSON output of the check command
+type checkResult struct {
+	Input    string         `json:"input"`
+	Valid    bool           `json:"valid"`
+	Problems []checkProblem `json:"problems"`
+}
+
+func NewCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check [flags] <claims-file>",
+		Short: "Check an EAR claims-set for problems, without signing it",
+		Long: `Check an EAR claims-set for problems, without signing it
+
+Run the same checks that are applied when signing (mandatory claims, claim
+values, supported profile) and when decoding (syntax, duplicate and unknown
+claims) on the claims-set in "ear-claims.json", and list all the problems
+found.  No key is needed, which makes it suitable for linting EAR templates,
+e.g., in CI pipelines.
+
+	arc check ear-claims.json
+
+Same, with machine-readable output:
+
+	arc check --format=json ear-claims.json
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				data []byte
+				err  error
+			)
+
+			if err = checkCheckArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			checkInput = args[0]
+
+			if data, err = afero.ReadFile(fs, checkInput); err != nil {
+				return fmt.Errorf("loading EAR claims-set from %q: %w", checkInput, err)
+			}
+
+			problems := checkClaimsSet(data)
+
+			if jsonOutput || checkFormat == checkFormatJSON {
+				if problems == nil {
+					problems = []checkProblem{}
+				}
+
+				if err = printJSON(cmd, checkResult{
+					Input:    checkInput,
+					Valid:    len(problems) == 0,
+					Problems: problems,
+				}); err != nil {
+					return err
+				}
+			} else {
+				out := cmd.OutOrStdout()
+
+				for _, p := range problems {
+					if p.Claim != "" {
+						fmt.Fprintf(out, "%s: %s: %s (%s)\n", checkInput, p.Claim, p.Message, p.Kind)
+					} else {
+						fmt.Fprintf(out, "%s: %s (%s)\n", checkInput, p.Message, p.Kind)
+					}
+				}
+
+				if len(problems) == 0 {
+					fmt.Fprintf(out, ">> %q is a valid EAR claims-set\n", checkInput)
+				}
+			}
+
+			if len(problems) > 0 {
+				return fmt.Errorf("%d problem(s) found in %q", len(problems), checkInput)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&checkFormat, "format", "f", checkFormatText, "output format ("+checkFormatText+", "+checkFormatJSON+")",
+	)
+
+	return cmd
+}
+
+func checkCheckArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("no claims-set file supplied")
+	}
+
+	if checkFormat != checkFormatText && checkFormat != checkFormatJSON {
+		return fmt.Errorf("unsupported format %q (supported: %s, %s)", checkFormat, checkFormatText, checkFormatJSON)
+	}
+
+	return nil
+}
+
+// checkClaimsSet returns all the problems found in the supplied claims-set.
+// Unlike decoding, which stops at the first class of problems, all checks are
+// run to completion.
+func checkClaimsSet(data []byte) []checkProblem {
+	var (
+		problems []checkProblem
+		raw      map[string]interface{}
+		ar       ear.AttestationResult
+	)
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []checkProblem{{Kind: checkKindSyntax, Message: err.Error()}}
+	}
+
+	onDuplicate := func(pointer string) error {
+		problems = append(problems, checkProblem{
+			Claim:   pointer,
+			Kind:    checkKindDuplicate,
+			Message: "duplicate claim",
+		})
+		return nil
+	}
+
+	err := ar.DecodeJSON(
+		data,
+		ear.WithDuplicateClaimHandler(onDuplicate),
+		ear.WithUnknownClaims(ear.UnknownClaimsPreserve),
+	)
+
+	unknown := make([]string, 0, len(ar.UnknownClaims))
+	for k := range ar.UnknownClaims {
+		unknown = append(unknown, k)
+	}
+	sort.Strings(unknown)
+
+	for _, k := range unknown {
+		problems = append(problems, checkProblem{
+			Claim:   k,
+			Kind:    checkKindUnknown,
+			Message: "not an EAR claim",
+		})
+	}
+
+	var ve *ear.ValidationError
+
+	if err != nil && !errors.As(err, &ve) {
+		// some claims could not be decoded: report that, then validate
+		// what has been decoded successfully
+		problems = append(problems, checkProblem{Kind: checkKindDecode, Message: err.Error()})
+
+		if errors.As(ar.Validate(), &ve) {
+			// claims that are present but failed to decode would be
+			// reported as missing too
+			ve = withoutMissing(ve, raw)
+		}
+	}
+
+	if ve != nil {
+		for _, ce := range ve.Errors {
+			p := checkProblem{Claim: ce.Claim, Kind: checkKindInvalid, Message: ce.Error()}
+			if errors.Is(ce, ear.ErrMissingClaim) {
+				p.Kind = checkKindMissing
+			}
+			problems = append(problems, p)
+		}
+	}
+
+	return problems
+}
+
+func withoutMissing(ve *ear.ValidationError, raw map[string]interface{}) *ear.ValidationError {
+	ret := &ear.ValidationError{}
+
+	for _, ce := range ve.Errors {
+		if _, ok := raw[ce.Claim]; ok && errors.Is(ce, ear.ErrMissingClaim) {
+			continue
+		}
+		ret.Errors = append(ret.Errors, ce)
+	}
+
+	return ret
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
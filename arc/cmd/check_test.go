@@ -0,0 +1,138 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testBrokenClaimsSet = []byte(`{
+    "submods": {
+	    "test": {
+		    "ear.status": "affirming",
+		    "ear.appraisal-policy-id": "not-a-uri"
+	    }
+    },
+    "eat_profile": "tag:example.com,2024:other-profile",
+    "eat_profile": "tag:github.com,2023:veraison/ear",
+    "iat": "yesterday",
+    "x-custom": 1
+}`)
+
+func Test_CheckCmd_unknown_argument(t *testing.T) {
+	cmd := NewCheckCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_CheckCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{},
+			expected: "validating arguments: no claims-set file supplied",
+		},
+		{
+			args:     []string{"--format=yaml", "ear-claims.json"},
+			expected: `validating arguments: unsupported format "yaml" (supported: text, json)`,
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewCheckCmd()
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_CheckCmd_file_not_found(t *testing.T) {
+	makeFS(t, []fileEntry{})
+
+	cmd := NewCheckCmd()
+	cmd.SetArgs([]string{"ear-claims.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `loading EAR claims-set from "ear-claims.json": open ear-claims.json: file does not exist`)
+}
+
+func Test_CheckCmd_ok(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear-claims.json", testMiniClaimsSet},
+	})
+
+	var stdout bytes.Buffer
+
+	cmd := NewCheckCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"ear-claims.json"})
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+	assert.Equal(t, ">> \"ear-claims.json\" is a valid EAR claims-set\n", stdout.String())
+}
+
+func Test_CheckCmd_syntax_error(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear-claims.json", []byte(`{"iat": `)},
+	})
+
+	var stdout bytes.Buffer
+
+	cmd := NewCheckCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"ear-claims.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `1 problem(s) found in "ear-claims.json"`)
+	assert.Equal(t, "ear-claims.json: unexpected end of JSON input (syntax)\n", stdout.String())
+}
+
+func Test_CheckCmd_all_problems_reported(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear-claims.json", testBrokenClaimsSet},
+	})
+
+	var stdout bytes.Buffer
+
+	cmd := NewCheckCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--format=json", "ear-claims.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `4 problem(s) found in "ear-claims.json"`)
+
+	var res checkResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res), stdout.String())
+
+	assert.Equal(t, "ear-claims.json", res.Input)
+	assert.False(t, res.Valid)
+
+	kinds := make([]string, 0, len(res.Problems))
+	claims := make([]string, 0, len(res.Problems))
+	for _, p := range res.Problems {
+		kinds = append(kinds, p.Kind)
+		claims = append(claims, p.Claim)
+	}
+
+	assert.Equal(t, []string{"duplicate", "unknown", "decode", "missing"}, kinds)
+	assert.Equal(t, []string{"/eat_profile", "x-custom", "", "ear.verifier-id"}, claims)
+	assert.Contains(t, res.Problems[2].Message, "iat")
+	assert.Contains(t, res.Problems[2].Message, "not-a-uri")
+}
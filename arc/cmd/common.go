@@ -3,9 +3,22 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/veraison/ear"
+)
+
+// escaping and unescaping of JSON Pointer (RFC6901) reference tokens
+var (
+	jsonPointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	jsonPointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
 )
 
 func algList() string {
@@ -17,3 +30,98 @@ func algList() string {
 
 	return strings.Join(l, ", ")
 }
+
+// diag returns the writer for human-oriented messages.  In JSON mode these are
+// diverted to stderr so that stdout only carries the JSON document.
+func diag(cmd *cobra.Command) io.Writer {
+	if jsonOutput {
+		return cmd.ErrOrStderr()
+	}
+	return cmd.OutOrStdout()
+}
+
+// stdio is the file name that stands for stdin, when reading, and for stdout,
+// when writing
+const stdio = "-"
+
+// readFile returns the content of the named file, or of stdin if name is "-"
+func readFile(cmd *cobra.Command, name string) ([]byte, error) {
+	if name == stdio {
+		return io.ReadAll(cmd.InOrStdin())
+	}
+	return afero.ReadFile(fs, name)
+}
+
+// writeFile saves data to the named file, or writes it as-is to stdout if
+// name is "-"
+func writeFile(cmd *cobra.Command, name string, data []byte) error {
+	if name == stdio {
+		_, err := cmd.OutOrStdout().Write(data)
+		return err
+	}
+	return afero.WriteFile(fs, name, data, 0644)
+}
+
+// printJSON writes the JSON serialization of v to the command's stdout
+func printJSON(cmd *cobra.Command, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return fmt.Errorf("serializing JSON output: %w", err)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), string(b))
+
+	return nil
+}
+
+// redactionProfilesKey is the configuration key under which custom redaction
+// profiles are defined, e.g., in $HOME/.arc.yaml:
+//
+//	redaction-profiles:
+//	  auditor:
+//	    - /ear.raw-evidence
+const redactionProfilesKey = "redaction-profiles"
+
+// redactionProfiles returns the predefined redaction profiles, merged with
+// those defined in the configuration
+func redactionProfiles() (ear.RedactionProfiles, error) {
+	cfg := viper.Get(redactionProfilesKey)
+	if cfg == nil {
+		return ear.DefaultRedactionProfiles(), nil
+	}
+
+	j, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", redactionProfilesKey, err)
+	}
+
+	profiles, err := ear.ParseRedactionProfiles(j)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", redactionProfilesKey, err)
+	}
+
+	return profiles, nil
+}
+
+// redact replaces ar with its redacted version, according to the named
+// redaction profile
+func redact(ar *ear.AttestationResult, profileName string) error {
+	profiles, err := redactionProfiles()
+	if err != nil {
+		return err
+	}
+
+	profile, err := profiles.Get(profileName)
+	if err != nil {
+		return err
+	}
+
+	redacted, err := ar.Redact(profile)
+	if err != nil {
+		return fmt.Errorf("redacting EAR claims-set: %w", err)
+	}
+
+	*ar = *redacted
+
+	return nil
+}
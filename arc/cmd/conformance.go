@@ -0,0 +1,87 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+)
+
+var conformanceSelf bool
+
+var conformanceCmd = NewConformanceCmd()
+
+func NewConformanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "conformance --self",
+		Short: "Check the EAR implementation against the examples in the AR4SI and EAR specifications",
+		Long: `Check the EAR implementation against the examples in the AR4SI and EAR specifications
+
+Check that the EAR library arc has been built with accepts the valid
+specification examples, rejects the invalid ones, and derives the expected
+statuses:
+
+	arc conformance --self
+
+The command fails (with a non-zero exit code) if any of the checks fails.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkConformanceArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			report := ear.SpecConformance()
+
+			if jsonOutput {
+				if err := printJSON(cmd, report); err != nil {
+					return err
+				}
+				return report.Err()
+			}
+
+			out := cmd.OutOrStdout()
+
+			for _, c := range report.Checks {
+				if c.OK {
+					fmt.Fprintf(out, "ok   %s\n", c.Example)
+				} else {
+					fmt.Fprintf(out, "FAIL %s: %s\n", c.Example, c.Detail)
+				}
+			}
+
+			if err := report.Err(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(out, ">> all %d specification examples passed\n", len(report.Checks))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(
+		&conformanceSelf, "self", false, "check the implementation arc has been built with",
+	)
+
+	return cmd
+}
+
+func checkConformanceArgs(args []string) error {
+	if len(args) != 0 {
+		return errors.New("no arguments expected")
+	}
+
+	if !conformanceSelf {
+		return errors.New("nothing to check (use --self)")
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(conformanceCmd)
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+func Test_ConformanceCmd_no_self(t *testing.T) {
+	cmd := NewConformanceCmd()
+	cmd.SetArgs([]string{})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "validating arguments: nothing to check (use --self)")
+}
+
+func Test_ConformanceCmd_ok(t *testing.T) {
+	var stdout bytes.Buffer
+
+	cmd := NewConformanceCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--self"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, stdout.String(), "ok   minimal-affirming\n")
+	assert.Contains(t, stdout.String(), "specification examples passed")
+}
+
+func Test_ConformanceCmd_json_output(t *testing.T) {
+	var stdout bytes.Buffer
+
+	cmd := NewConformanceCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--self"})
+
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	require.NoError(t, cmd.Execute())
+
+	var report ear.ConformanceReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.True(t, report.OK())
+	assert.NotEmpty(t, report.Checks)
+}
@@ -0,0 +1,289 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+	cose "github.com/veraison/go-cose"
+)
+
+// convertFormats are the values accepted by --from and --to
+var convertFormats = []string{"cbor", "cwt", "json", "jwt"}
+
+var (
+	convertFrom   string
+	convertTo     string
+	convertInput  string
+	convertOutput string
+	convertPKey   string
+	convertSKey   string
+	convertAlg    string
+)
+
+var convertCmd = NewConvertCmd()
+
+// convertResult is the JSON output of the convert command
+type convertResult struct {
+	Input  string `json:"input"`
+	From   string `json:"from"`
+	Output string `json:"output"`
+	To     string `json:"to"`
+}
+
+func NewConvertCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert [flags] <input-file> <output-file>",
+		Short: "Convert an EAR between the JSON, CBOR, JWT and CWT formats",
+		Long: `Convert an EAR between the JSON, CBOR, JWT and CWT formats
+
+The json and cbor formats are unsigned claims-sets, jwt and cwt are signed
+EARs.  Signed inputs are verified using the key supplied with --pkey, signed
+outputs are signed using the key supplied with --skey.  Both use the algorithm
+supplied with --alg.
+
+Verify the JWT in "my-ear.jwt" using the public key in the default key file
+"pkey.json", and save its claims-set in CBOR to "my-ear.cbor":
+
+	arc convert --from=jwt --to=cbor my-ear.jwt my-ear.cbor
+
+Re-sign the EAR in "my-ear.cwt" as a JWT, using the key in "skey.json":
+
+	arc convert --from=cwt --to=jwt my-ear.cwt my-ear.jwt
+
+Supported formats: ` + convertFormatList() + `
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				data []byte
+				ar   ear.AttestationResult
+				err  error
+			)
+
+			if err = checkConvertArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			convertInput, convertOutput = args[0], args[1]
+
+			if data, err = afero.ReadFile(fs, convertInput); err != nil {
+				return fmt.Errorf("loading %s EAR from %q: %w", convertFrom, convertInput, err)
+			}
+
+			if err = convertDecode(&ar, data); err != nil {
+				return fmt.Errorf("decoding %s EAR from %q: %w", convertFrom, convertInput, err)
+			}
+
+			if data, err = convertEncode(ar); err != nil {
+				return fmt.Errorf("encoding %s EAR: %w", convertTo, err)
+			}
+
+			if err = afero.WriteFile(fs, convertOutput, data, 0644); err != nil {
+				return fmt.Errorf("saving %s EAR to file %q: %w", convertTo, convertOutput, err)
+			}
+
+			fmt.Fprintf(diag(cmd), ">> converted %q (%s) into %q (%s)\n",
+				convertInput, convertFrom, convertOutput, convertTo)
+
+			if jsonOutput {
+				return printJSON(cmd, convertResult{
+					Input:  convertInput,
+					From:   convertFrom,
+					Output: convertOutput,
+					To:     convertTo,
+				})
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&convertFrom, "from", "f", "", "format of the input file ("+convertFormatList()+")",
+	)
+
+	cmd.Flags().StringVarP(
+		&convertTo, "to", "t", "", "format of the output file ("+convertFormatList()+")",
+	)
+
+	cmd.Flags().StringVarP(
+		&convertPKey, "pkey", "p", "pkey.json", "verification key for signed inputs (JWK, PEM or DER)",
+	)
+
+	cmd.Flags().StringVarP(
+		&convertSKey, "skey", "s", "skey.json", "signing key for signed outputs (JWK, PEM or DER), or key URI of a registered scheme",
+	)
+
+	cmd.Flags().StringVarP(
+		&convertAlg, "alg", "a", "ES256", "signature algorithm ("+algList()+")",
+	)
+
+	return cmd
+}
+
+// convertDecode populates ar from data, in the --from format
+func convertDecode(ar *ear.AttestationResult, data []byte) error {
+	switch convertFrom {
+	case "json":
+		return ar.DecodeJSON(data)
+	case "cbor":
+		return ar.DecodeCBOR(data)
+	}
+
+	vfyK, err := loadConvertKey(convertPKey, "verification")
+	if err != nil {
+		return err
+	}
+
+	if convertFrom == "jwt" {
+		return ar.Verify(data, jwa.KeyAlgorithmFrom(convertAlg), vfyK)
+	}
+
+	pub, err := jwk.PublicRawKeyOf(vfyK)
+	if err != nil {
+		return fmt.Errorf("extracting public key from %q: %w", convertPKey, err)
+	}
+
+	alg, err := coseAlgorithm(convertAlg)
+	if err != nil {
+		return err
+	}
+
+	verifier, err := cose.NewVerifier(alg, pub)
+	if err != nil {
+		return fmt.Errorf("creating COSE verifier: %w", err)
+	}
+
+	return ar.VerifyCWT(data, verifier)
+}
+
+// convertEncode serializes ar in the --to format
+func convertEncode(ar ear.AttestationResult) ([]byte, error) {
+	switch convertTo {
+	case "json":
+		return ar.MarshalJSONIndent("", "    ")
+	case "cbor":
+		return ar.MarshalCBOR()
+	}
+
+	var (
+		sigK interface{}
+		err  error
+	)
+
+	if ear.IsSignerURI(convertSKey) {
+		if sigK, err = ear.OpenSigner(convertSKey); err != nil {
+			return nil, fmt.Errorf("opening signing key %q: %w", convertSKey, err)
+		}
+	} else if sigK, err = loadConvertKey(convertSKey, "signing"); err != nil {
+		return nil, err
+	}
+
+	if convertTo == "jwt" {
+		return ar.Sign(jwa.KeyAlgorithmFrom(convertAlg), sigK)
+	}
+
+	key, ok := sigK.(crypto.Signer)
+
+	if k, isJWK := sigK.(jwk.Key); isJWK {
+		var raw interface{}
+		if err = k.Raw(&raw); err != nil {
+			return nil, fmt.Errorf("extracting private key from %q: %w", convertSKey, err)
+		}
+
+		key, ok = raw.(crypto.Signer)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("key in %q cannot be used for signing", convertSKey)
+	}
+
+	alg, err := coseAlgorithm(convertAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := cose.NewSigner(alg, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating COSE signer: %w", err)
+	}
+
+	return ar.SignCWT(signer)
+}
+
+func loadConvertKey(file, usage string) (jwk.Key, error) {
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s key from %q: %w", usage, file, err)
+	}
+
+	key, err := ear.LoadKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s key from %q: %w", usage, file, err)
+	}
+
+	return key, nil
+}
+
+// coseAlgorithm returns the COSE algorithm with the same name as the JOSE
+// algorithm alg
+func coseAlgorithm(alg string) (cose.Algorithm, error) {
+	for _, a := range cwtAlgorithms {
+		if a.String() == alg {
+			return a, nil
+		}
+	}
+
+	return 0, fmt.Errorf("algorithm %q is not supported for CWT", alg)
+}
+
+func checkConvertArgs(args []string) error {
+	if len(args) != 2 {
+		return errors.New("input and output files must be supplied")
+	}
+
+	for _, f := range []struct{ flag, format string }{
+		{"--from", convertFrom},
+		{"--to", convertTo},
+	} {
+		if f.format == "" {
+			return fmt.Errorf("no %s format supplied", f.flag)
+		}
+
+		if !isConvertFormat(f.format) {
+			return fmt.Errorf("unsupported %s format %q (supported: %s)", f.flag, f.format, convertFormatList())
+		}
+	}
+
+	if convertFrom == convertTo {
+		return fmt.Errorf("input and output formats are both %q", convertFrom)
+	}
+
+	return nil
+}
+
+func isConvertFormat(format string) bool {
+	for _, f := range convertFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func convertFormatList() string {
+	return strings.Join(convertFormats, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+func Test_ConvertCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"--from=jwt", "--to=cbor", "ear.jwt"},
+			expected: "validating arguments: input and output files must be supplied",
+		},
+		{
+			args:     []string{"--to=cbor", "ear.jwt", "ear.cbor"},
+			expected: "validating arguments: no --from format supplied",
+		},
+		{
+			args:     []string{"--from=jwt", "--to=yaml", "ear.jwt", "ear.yaml"},
+			expected: `validating arguments: unsupported --to format "yaml" (supported: cbor, cwt, json, jwt)`,
+		},
+		{
+			args:     []string{"--from=jwt", "--to=jwt", "ear.jwt", "ear2.jwt"},
+			expected: `validating arguments: input and output formats are both "jwt"`,
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewConvertCmd()
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_ConvertCmd_bad_signature(t *testing.T) {
+	tampered := append([]byte(nil), testJWT...)
+	tampered[len(tampered)-2] ^= 0x01
+
+	makeFS(t, []fileEntry{
+		{"ear.jwt", tampered},
+		{"pkey.json", testPKey},
+	})
+
+	cmd := NewConvertCmd()
+	cmd.SetArgs([]string{"--from=jwt", "--to=cbor", "ear.jwt", "ear.cbor"})
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, `decoding jwt EAR from "ear.jwt": failed verifying JWT message`)
+}
+
+func Test_ConvertCmd_round_trip(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear.jwt", testJWT},
+		{"pkey.json", testPKey},
+		{"skey.json", testSKey},
+	})
+
+	steps := [][]string{
+		{"--from=jwt", "--to=cbor", "ear.jwt", "ear.cbor"},
+		{"--from=cbor", "--to=cwt", "ear.cbor", "ear.cwt"},
+		{"--from=cwt", "--to=json", "ear.cwt", "ear.json"},
+		{"--from=json", "--to=jwt", "ear.json", "ear2.jwt"},
+	}
+
+	for _, args := range steps {
+		var stdout bytes.Buffer
+
+		cmd := NewConvertCmd()
+		cmd.SetOut(&stdout)
+		cmd.SetArgs(args)
+
+		require.NoError(t, cmd.Execute(), "converting with %v", args)
+		assert.Contains(t, stdout.String(), ">> converted")
+	}
+
+	var expected, actual ear.AttestationResult
+
+	vfyK, err := ear.LoadKey(testPKey)
+	require.NoError(t, err)
+
+	require.NoError(t, expected.Verify(testJWT, jwa.ES256, vfyK))
+
+	data, err := afero.ReadFile(fs, "ear2.jwt")
+	require.NoError(t, err)
+
+	require.NoError(t, actual.Verify(data, jwa.ES256, vfyK))
+	assert.Equal(t, expected, actual)
+}
+
+func Test_ConvertCmd_unsupported_cwt_alg(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear.json", testMiniClaimsSet},
+		{"skey.json", testSKey},
+	})
+
+	cmd := NewConvertCmd()
+	cmd.SetArgs([]string{"--from=json", "--to=cwt", "--alg=HS256", "ear.json", "ear.cwt"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `encoding cwt EAR: algorithm "HS256" is not supported for CWT`)
+}
+
+func Test_ConvertCmd_skey_uri(t *testing.T) {
+	testHSMScheme(t)
+
+	makeFS(t, []fileEntry{
+		{"ear.json", testMiniClaimsSet},
+		{"pkey.json", testPKey},
+	})
+
+	steps := [][]string{
+		{"--from=json", "--to=cwt", "--skey=testhsm:ec", "ear.json", "ear.cwt"},
+		{"--from=cwt", "--to=jwt", "--skey=testhsm:ec", "ear.cwt", "ear.jwt"},
+	}
+
+	for _, args := range steps {
+		cmd := NewConvertCmd()
+		cmd.SetArgs(args)
+
+		require.NoError(t, cmd.Execute(), "converting with %v", args)
+	}
+
+	vfyK, err := ear.LoadKey(testPKey)
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "ear.jwt")
+	require.NoError(t, err)
+
+	var ar ear.AttestationResult
+	assert.NoError(t, ar.Verify(data, jwa.ES256, vfyK))
+}
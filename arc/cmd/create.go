@@ -5,9 +5,10 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
-	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/veraison/ear"
@@ -18,13 +19,32 @@ var (
 	createSKey   string
 	createAlg    string
 	createOutput string
+
+	createTemplate bool
+	createSubmod   string
+	createStatus   string
+	createPolicyID string
+	createIAT      string
+	createNonce    string
 )
 
+// templateSubmod is the name of the submod of the built-in claims-set, unless
+// --submod is supplied
+const templateSubmod = "test"
+
 var createCmd = NewCreateCmd()
 
+// createResult is the JSON output of the create command
+type createResult struct {
+	Output     string `json:"output"`
+	Claims     string `json:"claims,omitempty"`
+	SigningKey string `json:"signing-key"`
+	Algorithm  string `json:"alg"`
+}
+
 func NewCreateCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "create [flags] <jwt-file>",
+		Use:   "create [flags] [<claims-file>] <jwt-file>",
 		Short: "Read the EAR claims-set from a JSON file, sign it and save the resulting JWT to jwt-file",
 		Long: `Read the EAR claims-set from a JSON file, sign it and save the resulting JWT to jwt-file
 
@@ -32,55 +52,98 @@ Create an EAR from the default claims-set file "ear-claims.json".  Sign it with
 the key in the default key file "skey.json", and save the result to "my-ear.jwt".
 
 	arc create my-ear.jwt
+
+The claims-set file can also be supplied as the first argument, in place of
+--claims.  Use "-" to read the claims-set from stdin, and to write the JWT to
+stdout, e.g., in a pipeline:
+
+	policy-engine | arc create --skey k.json --alg ES256 - - > my-ear.jwt
+
+Claims can be set, or overridden, using flags.  With --template, the EAR is
+created from a built-in minimal claims-set rather than from a file, e.g., to
+quickly mint a token for a test or a demo:
+
+	arc create --template --submod=cpu --status=affirming --iat=now my-ear.jwt
 	`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
-				claimsSet, sKey, arBytes []byte
-				sigK                     jwk.Key
-				ar                       ear.AttestationResult
-				err                      error
+				claimsSet, arBytes []byte
+				sigK               interface{}
+				alg                jwa.SignatureAlgorithm
+				ar                 ear.AttestationResult
+				err                error
 			)
 
 			if err = checkCreateArgs(args); err != nil {
 				return fmt.Errorf("validating arguments: %w", err)
 			}
 
-			createOutput = args[0]
+			if len(args) == 2 {
+				createClaims = args[0]
+			}
+			createOutput = args[len(args)-1]
+
+			if createTemplate {
+				createClaims = ""
+				ar = templateClaimsSet()
+			} else {
+				if claimsSet, err = readFile(cmd, createClaims); err != nil {
+					return fmt.Errorf("loading EAR claims-set from %q: %w", createClaims, err)
+				}
 
-			if claimsSet, err = afero.ReadFile(fs, createClaims); err != nil {
-				return fmt.Errorf("loading EAR claims-set from %q: %w", createClaims, err)
+				if err = ar.UnmarshalJSON(claimsSet); err != nil {
+					return fmt.Errorf("decoding EAR claims-set from %q: %w", createClaims, err)
+				}
 			}
 
-			if err = ar.UnmarshalJSON(claimsSet); err != nil {
-				return fmt.Errorf("decoding EAR claims-set from %q: %w", createClaims, err)
+			if err = applyCreateOverrides(&ar); err != nil {
+				return fmt.Errorf("overriding claims: %w", err)
 			}
 
-			// read the signing key from createSKey
-			if sKey, err = afero.ReadFile(fs, createSKey); err != nil {
-				return fmt.Errorf("loading signing key from %q: %w", createSKey, err)
+			if alg, err = parseAlg(createAlg); err != nil {
+				return err
 			}
 
-			if sigK, err = jwk.ParseKey(sKey); err != nil {
-				return fmt.Errorf("parsing signing key from %q: %w", createSKey, err)
+			if sigK, err = loadSigningKey(createSKey, alg); err != nil {
+				return err
 			}
 
-			if arBytes, err = ar.Sign(jwa.KeyAlgorithmFrom(createAlg), sigK); err != nil {
+			if arBytes, err = ar.Sign(alg, sigK); err != nil {
 				return fmt.Errorf("signing EAR: %w", err)
 			}
 
 			// save to createOutput
-			if err = afero.WriteFile(fs, createOutput, arBytes, 0644); err != nil {
+			if err = writeFile(cmd, createOutput, arBytes); err != nil {
 				return fmt.Errorf("saving signer EAR to file %q: %w", createOutput, err)
 			}
 
-			fmt.Printf(">> created %q from %q using %q as signing key\n", createOutput, createClaims, createSKey)
+			// keep stdout for the JWT, if that is where it went
+			out := diag(cmd)
+			if createOutput == stdio {
+				out = cmd.ErrOrStderr()
+			}
+
+			if createTemplate {
+				fmt.Fprintf(out, ">> created %q from the built-in template using %q as signing key\n", createOutput, createSKey)
+			} else {
+				fmt.Fprintf(out, ">> created %q from %q using %q as signing key\n", createOutput, createClaims, createSKey)
+			}
+
+			if jsonOutput {
+				return printJSON(cmd, createResult{
+					Output:     createOutput,
+					Claims:     createClaims,
+					SigningKey: createSKey,
+					Algorithm:  createAlg,
+				})
+			}
 
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(
-		&createSKey, "skey", "s", "skey.json", "signing key in JWK format",
+		&createSKey, "skey", "s", "skey.json", "signing key (JWK, PEM or DER), or key URI (e.g., pkcs11:...) of a registered scheme",
 	)
 
 	cmd.Flags().StringVarP(
@@ -91,16 +154,185 @@ the key in the default key file "skey.json", and save the result to "my-ear.jwt"
 		&createAlg, "alg", "a", "ES256", "signing algorithm ("+algList()+")",
 	)
 
+	cmd.Flags().BoolVar(
+		&createTemplate, "template", false, "start from a built-in minimal claims-set instead of a file",
+	)
+
+	cmd.Flags().StringVar(
+		&createSubmod, "submod", "",
+		"submod that --status and --policy-id apply to, added if missing (default is the only submod)",
+	)
+
+	cmd.Flags().StringVar(
+		&createStatus, "status", "", "set ear.status (none, affirming, warning, contraindicated)",
+	)
+
+	cmd.Flags().StringVar(
+		&createPolicyID, "policy-id", "", "set ear.appraisal-policy-id",
+	)
+
+	cmd.Flags().StringVar(
+		&createIAT, "iat", "", `set iat, in seconds since the epoch, or "now"`,
+	)
+
+	cmd.Flags().StringVar(
+		&createNonce, "nonce", "", "set eat_nonce (base64url-encoded)",
+	)
+
 	return cmd
 }
 
+// templateClaimsSet returns the claims-set used with --template
+func templateClaimsSet() ear.AttestationResult {
+	name := createSubmod
+	if name == "" {
+		name = templateSubmod
+	}
+
+	return *ear.NewAttestationResult(name, "arc-"+version, "https://github.com/veraison/ear")
+}
+
+// applyCreateOverrides sets the claims supplied using flags
+func applyCreateOverrides(ar *ear.AttestationResult) error {
+	if createIAT != "" {
+		iat, err := parseIAT(createIAT)
+		if err != nil {
+			return fmt.Errorf("--iat: %w", err)
+		}
+		ar.IssuedAt = &iat
+	}
+
+	if createNonce != "" {
+		nonces, err := ear.NewNonces(createNonce)
+		if err != nil {
+			return fmt.Errorf("--nonce: %w", err)
+		}
+		ar.Nonce = nonces
+	}
+
+	if createStatus == "" && createPolicyID == "" {
+		return nil
+	}
+
+	appraisal, err := overriddenSubmod(ar)
+	if err != nil {
+		return err
+	}
+
+	if createStatus != "" {
+		tier, err := ear.ToTrustTier(createStatus)
+		if err != nil {
+			return fmt.Errorf("--status: %w", err)
+		}
+		appraisal.Status = tier
+	}
+
+	if createPolicyID != "" {
+		ids, err := ear.NewPolicyIDs(createPolicyID)
+		if err != nil {
+			return fmt.Errorf("--policy-id: %w", err)
+		}
+		appraisal.AppraisalPolicyID = ids
+	}
+
+	return nil
+}
+
+// overriddenSubmod returns the Appraisal that --status and --policy-id apply
+// to: the one named by --submod, which is added if missing, or else the only
+// one in the claims-set
+func overriddenSubmod(ar *ear.AttestationResult) (*ear.Appraisal, error) {
+	if createSubmod == "" {
+		if len(ar.Submods) != 1 {
+			return nil, fmt.Errorf("--submod is needed to choose among %d submods", len(ar.Submods))
+		}
+
+		for _, a := range ar.Submods {
+			return a, nil
+		}
+	}
+
+	if ar.Submods == nil {
+		ar.Submods = map[string]*ear.Appraisal{}
+	}
+
+	a, ok := ar.Submods[createSubmod]
+	if !ok || a == nil {
+		a = &ear.Appraisal{}
+		ar.Submods[createSubmod] = a
+	}
+
+	return a, nil
+}
+
+func parseIAT(s string) (int64, error) {
+	if s == "now" {
+		return time.Now().Unix(), nil
+	}
+
+	iat, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf(`expecting "now" or seconds since the epoch, found %q`, s)
+	}
+
+	return iat, nil
+}
+
 func checkCreateArgs(args []string) error {
-	if len(args) != 1 {
+	if len(args) == 0 {
 		return errors.New("no output file supplied")
 	}
+
+	if len(args) > 2 {
+		return errors.New("too many arguments")
+	}
+
+	if createTemplate && len(args) == 2 {
+		return errors.New("a claims-set file cannot be supplied with --template")
+	}
+
+	if jsonOutput && args[len(args)-1] == stdio {
+		return errors.New("--json cannot be used when writing the JWT to stdout")
+	}
+
 	return nil
 }
 
+// loadSigningKey returns the signing key referenced by ref, checked against
+// alg.  Key URIs are resolved through the openers registered with
+// ear.RegisterSignerScheme, and give a crypto.Signer; anything else is taken
+// as the path of a key file.
+func loadSigningKey(ref string, alg jwa.SignatureAlgorithm) (interface{}, error) {
+	if ear.IsSignerURI(ref) {
+		signer, err := ear.OpenSigner(ref)
+		if err != nil {
+			return nil, fmt.Errorf("opening signing key %q: %w", ref, err)
+		}
+
+		if _, err = checkKeyMaterial(signer.Public(), alg); err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", ref, err)
+		}
+
+		return signer, nil
+	}
+
+	data, err := afero.ReadFile(fs, ref)
+	if err != nil {
+		return nil, fmt.Errorf("loading signing key from %q: %w", ref, err)
+	}
+
+	key, err := ear.LoadKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key from %q: %w", ref, err)
+	}
+
+	if err = validateKey(key, alg.String(), keyForSigning); err != nil {
+		return nil, fmt.Errorf("signing key from %q: %w", ref, err)
+	}
+
+	return key, nil
+}
+
 func init() {
 	rootCmd.AddCommand(createCmd)
 }
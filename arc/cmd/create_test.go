@@ -3,9 +3,19 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"sync"
 	"testing"
 
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
 )
 
 func Test_CreateCmd_unknown_argument(t *testing.T) {
@@ -66,7 +76,7 @@ func Test_CreateCmd_skey_file_bad_format(t *testing.T) {
 	}
 	cmd.SetArgs(args)
 
-	expectedErr := `parsing signing key from "empty-skey.json": failed to unmarshal JSON into key hint: EOF`
+	expectedErr := `parsing signing key from "empty-skey.json": no key data`
 
 	err := cmd.Execute()
 	assert.EqualError(t, err, expectedErr)
@@ -89,7 +99,7 @@ func Test_CreateCmd_skey_not_ok_for_signing(t *testing.T) {
 	}
 	cmd.SetArgs(args)
 
-	expectedErr := `failed to generate signature for signer #0 (alg=ES256): failed to sign payload: failed to retrieve ecdsa.PrivateKey out of *jwk.ecdsaPublicKey: failed to produce ecdsa.PrivateKey from *jwk.ecdsaPublicKey: argument to AssignIfCompatible() must be compatible with *ecdsa.PublicKey (was *ecdsa.PrivateKey)`
+	expectedErr := `signing key from "pkey.json": a private key is required for signing, but a public key was supplied`
 
 	err := cmd.Execute()
 	assert.ErrorContains(t, err, expectedErr)
@@ -154,7 +164,7 @@ func Test_CreateCmd_unknown_signing_alg(t *testing.T) {
 	}
 	cmd.SetArgs(args)
 
-	expectedErr := `expected algorithm to be of type jwa.SignatureAlgorithm but got ("XYZ", jwa.InvalidKeyAlgorithm)`
+	expectedErr := `unknown signature algorithm "XYZ": use one of `
 
 	err := cmd.Execute()
 	assert.ErrorContains(t, err, expectedErr)
@@ -183,3 +193,471 @@ func Test_CreateCmd_ok(t *testing.T) {
 	_, err = fs.Stat("ear.jwt")
 	assert.NoError(t, err)
 }
+
+func Test_CreateCmd_EdDSA_PS_ok(t *testing.T) {
+	tvs := []struct {
+		alg  string
+		skey []byte
+		pkey []byte
+	}{
+		{"EdDSA", testEd25519SKeyPEM, testEd25519PKeyPEM},
+		{"PS256", testRSAPSSSKeyPEM, testRSAPSSPKeyPEM},
+		{"PS384", testRSAPSSSKeyPEM, testRSAPSSPKeyPEM},
+		{"PS512", testRSAPSSSKeyPEM, testRSAPSSPKeyPEM},
+	}
+
+	for i, tv := range tvs {
+		files := []fileEntry{
+			{"skey.pem", tv.skey},
+			{"pkey.pem", tv.pkey},
+			{"ear-claims.json", testMiniClaimsSet},
+		}
+		makeFS(t, files)
+
+		cmd := NewCreateCmd()
+		cmd.SetArgs([]string{
+			"--skey=skey.pem",
+			"--claims=ear-claims.json",
+			"--alg=" + tv.alg,
+			"ear.jwt",
+		})
+		require.NoError(t, cmd.Execute(), "failed test vector at index %d", i)
+
+		cmd = NewVerifyCmd()
+		cmd.SetArgs([]string{
+			"--pkey=pkey.pem",
+			"--alg=" + tv.alg,
+			"ear.jwt",
+		})
+		assert.NoError(t, cmd.Execute(), "failed test vector at index %d", i)
+	}
+}
+
+func Test_CreateCmd_key_alg_mismatch(t *testing.T) {
+	tvs := []struct {
+		alg      string
+		skey     []byte
+		expected string
+	}{
+		{"PS256", testEd25519SKeyPEM, `signing key from "skey.pem": an Ed25519 key cannot be used with PS256: use EdDSA`},
+		{"EdDSA", testRSAPSSSKeyPEM, `signing key from "skey.pem": an RSA key cannot be used with EdDSA: use one of RS256, RS384, RS512, PS256, PS384, PS512`},
+		{"EdDSA", testSKeyPEM, `signing key from "skey.pem": an EC key cannot be used with EdDSA: use ES256, ES384 or ES512`},
+		{"EdDSA", testEd25519PKeyPEM, `signing key from "skey.pem": a private key is required for signing, but a public key was supplied`},
+	}
+
+	for i, tv := range tvs {
+		files := []fileEntry{
+			{"skey.pem", tv.skey},
+			{"ear-claims.json", testMiniClaimsSet},
+		}
+		makeFS(t, files)
+
+		cmd := NewCreateCmd()
+		cmd.SetArgs([]string{
+			"--skey=skey.pem",
+			"--claims=ear-claims.json",
+			"--alg=" + tv.alg,
+			"ear.jwt",
+		})
+		assert.EqualError(t, cmd.Execute(), tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+var registerTestHSM sync.Once
+
+// testHSMScheme stands in for an HSM: "testhsm:ec" opens the test EC key as a
+// crypto.Signer
+func testHSMScheme(t *testing.T) {
+	registerTestHSM.Do(func() {
+		err := ear.RegisterSignerScheme("testhsm", func(uri string) (crypto.Signer, error) {
+			if uri != "testhsm:ec" {
+				return nil, fmt.Errorf("no such key %q", uri)
+			}
+
+			key, err := ear.LoadKey(testSKeyPEM)
+			if err != nil {
+				return nil, err
+			}
+
+			var raw interface{}
+			if err := key.Raw(&raw); err != nil {
+				return nil, err
+			}
+
+			return raw.(crypto.Signer), nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func Test_CreateCmd_skey_uri_ok(t *testing.T) {
+	testHSMScheme(t)
+
+	files := []fileEntry{
+		{"pkey.pem", testPKeyPEM},
+		{"ear-claims.json", testMiniClaimsSet},
+	}
+	makeFS(t, files)
+
+	cmd := NewCreateCmd()
+	cmd.SetArgs([]string{
+		"--skey=testhsm:ec",
+		"--claims=ear-claims.json",
+		"--alg=ES256",
+		"ear.jwt",
+	})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewVerifyCmd()
+	cmd.SetArgs([]string{
+		"--pkey=pkey.pem",
+		"--alg=ES256",
+		"ear.jwt",
+	})
+	assert.NoError(t, cmd.Execute())
+}
+
+// testKMS is a KMS backend holding the test EC key as "ec"
+type testKMS struct {
+	signer crypto.Signer
+}
+
+func (o testKMS) PublicKey(_ context.Context, keyID string) (crypto.PublicKey, error) {
+	if keyID != "ec" {
+		return nil, fmt.Errorf("key %q not found", keyID)
+	}
+	return o.signer.Public(), nil
+}
+
+func (o testKMS) Sign(_ context.Context, _ string, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return o.signer.Sign(rand.Reader, digest, opts)
+}
+
+var registerTestKMS sync.Once
+
+func Test_CreateCmd_skey_kms(t *testing.T) {
+	registerTestKMS.Do(func() {
+		key, err := ear.LoadKey(testSKeyPEM)
+		require.NoError(t, err)
+
+		var raw interface{}
+		require.NoError(t, key.Raw(&raw))
+
+		require.NoError(t, ear.RegisterKMSBackend("test", testKMS{raw.(crypto.Signer)}))
+	})
+
+	makeFS(t, []fileEntry{
+		{"pkey.pem", testPKeyPEM},
+		{"ear-claims.json", testMiniClaimsSet},
+	})
+
+	cmd := NewCreateCmd()
+	cmd.SetArgs([]string{"--skey=kms://test/ec", "--claims=ear-claims.json", "ear.jwt"})
+	require.NoError(t, cmd.Execute())
+
+	cmd = NewVerifyCmd()
+	cmd.SetArgs([]string{"--pkey=pkey.pem", "ear.jwt"})
+	assert.NoError(t, cmd.Execute())
+
+	tvs := []struct {
+		skey     string
+		expected string
+	}{
+		{
+			"kms://test/rsa",
+			`opening signing key "kms://test/rsa": opening kms key: fetching public key of "rsa": key "rsa" not found`,
+		},
+		{
+			"kms://aws/alias/ear",
+			`opening signing key "kms://aws/alias/ear": opening kms key: unknown KMS backend "aws" (registered: gcp, test)`,
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewCreateCmd()
+		cmd.SetArgs([]string{"--skey=" + tv.skey, "--claims=ear-claims.json", "ear.jwt"})
+		assert.EqualError(t, cmd.Execute(), tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_CreateCmd_skey_uri_fail(t *testing.T) {
+	testHSMScheme(t)
+
+	tvs := []struct {
+		skey     string
+		alg      string
+		expected string
+	}{
+		{
+			"testhsm:ec", "EdDSA",
+			`signing key "testhsm:ec": an EC key cannot be used with EdDSA: use ES256, ES384 or ES512`,
+		},
+		{
+			"testhsm:rsa", "PS256",
+			`opening signing key "testhsm:rsa": opening testhsm key: no such key "testhsm:rsa"`,
+		},
+		{
+			"pkcs11:object=ear", "ES256",
+			`opening signing key "pkcs11:object=ear": no opener registered for "pkcs11" URIs (registered: kms, testhsm)`,
+		},
+	}
+
+	for i, tv := range tvs {
+		makeFS(t, []fileEntry{{"ear-claims.json", testMiniClaimsSet}})
+
+		cmd := NewCreateCmd()
+		cmd.SetArgs([]string{
+			"--skey=" + tv.skey,
+			"--claims=ear-claims.json",
+			"--alg=" + tv.alg,
+			"ear.jwt",
+		})
+		assert.EqualError(t, cmd.Execute(), tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_CreateCmd_pem_skey_ok(t *testing.T) {
+	cmd := NewCreateCmd()
+
+	files := []fileEntry{
+		{"skey.pem", testSKeyPEM},
+		{"ear-claims.json", testMiniClaimsSet},
+	}
+	makeFS(t, files)
+
+	args := []string{
+		"--skey=skey.pem",
+		"--claims=ear-claims.json",
+		"ear.jwt",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+
+	_, err = fs.Stat("ear.jwt")
+	assert.NoError(t, err)
+}
+
+func Test_CreateCmd_json_output(t *testing.T) {
+	cmd := NewCreateCmd()
+
+	files := []fileEntry{
+		{"skey.json", testSKey},
+		{"ear-claims.json", testMiniClaimsSet},
+	}
+	makeFS(t, files)
+
+	var stdout, stderr bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	args := []string{
+		"--skey=skey.json",
+		"--claims=ear-claims.json",
+		"--alg=ES256",
+		"ear.jwt",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	expected := `{
+    "output": "ear.jwt",
+    "claims": "ear-claims.json",
+    "signing-key": "skey.json",
+    "alg": "ES256"
+}
+`
+	assert.Equal(t, expected, stdout.String())
+	assert.Contains(t, stderr.String(), ">> created")
+}
+
+func Test_CreateCmd_stdin_stdout(t *testing.T) {
+	cmd := NewCreateCmd()
+
+	files := []fileEntry{
+		{"skey.json", testSKey},
+	}
+	makeFS(t, files)
+
+	var stdout, stderr bytes.Buffer
+	cmd.SetIn(bytes.NewReader(testMiniClaimsSet))
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	args := []string{
+		"--skey=skey.json",
+		"--alg=ES256",
+		"-",
+		"-",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	// stdout only carries the JWT
+	vfyK, err := ear.LoadKey(testPKey)
+	require.NoError(t, err)
+
+	var ar ear.AttestationResult
+	assert.NoError(t, ar.Verify(stdout.Bytes(), jwa.ES256, vfyK))
+
+	assert.Contains(t, stderr.String(), `>> created "-" from "-"`)
+}
+
+func Test_CreateCmd_too_many_args(t *testing.T) {
+	cmd := NewCreateCmd()
+
+	cmd.SetArgs([]string{"ear-claims.json", "ear.jwt", "extra"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "validating arguments: too many arguments")
+}
+
+func Test_CreateCmd_json_output_to_stdout(t *testing.T) {
+	cmd := NewCreateCmd()
+
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	cmd.SetArgs([]string{"-"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "validating arguments: --json cannot be used when writing the JWT to stdout")
+}
+
+func Test_CreateCmd_template(t *testing.T) {
+	cmd := NewCreateCmd()
+
+	files := []fileEntry{
+		{"skey.json", testSKey},
+	}
+	makeFS(t, files)
+
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+
+	args := []string{
+		"--template",
+		"--submod=cpu",
+		"--status=affirming",
+		"--policy-id=https://veraison.example/policy/1",
+		"--iat=1666091373",
+		"--nonce=3q2-7w3q2-8",
+		"ear.jwt",
+	}
+	cmd.SetArgs(args)
+
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, stdout.String(), `>> created "ear.jwt" from the built-in template`)
+
+	ar := testVerifyCreated(t, "ear.jwt")
+
+	assert.Equal(t, int64(1666091373), *ar.IssuedAt)
+	assert.Equal(t, ear.Nonces{"3q2-7w3q2-8"}, *ar.Nonce)
+	require.Contains(t, ar.Submods, "cpu")
+	assert.Len(t, ar.Submods, 1)
+	assert.Equal(t, ear.TrustTierAffirming, *ar.Submods["cpu"].Status)
+	assert.True(t, ar.Submods["cpu"].AppraisalPolicyID.Contains("https://veraison.example/policy/1"))
+}
+
+func Test_CreateCmd_overrides(t *testing.T) {
+	cmd := NewCreateCmd()
+
+	files := []fileEntry{
+		{"skey.json", testSKey},
+		{"ear-claims.json", testMiniClaimsSet},
+	}
+	makeFS(t, files)
+
+	args := []string{
+		"--status=warning",
+		"--iat=now",
+		"ear.jwt",
+	}
+	cmd.SetArgs(args)
+
+	require.NoError(t, cmd.Execute())
+
+	ar := testVerifyCreated(t, "ear.jwt")
+
+	// the only submod is picked
+	assert.Equal(t, ear.TrustTierWarning, *ar.Submods["test"].Status)
+	assert.Greater(t, *ar.IssuedAt, int64(1666091373))
+}
+
+func Test_CreateCmd_overrides_fail(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"--iat=yesterday", "ear.jwt"},
+			expected: `overriding claims: --iat: expecting "now" or seconds since the epoch, found "yesterday"`,
+		},
+		{
+			args:     []string{"--status=great", "ear.jwt"},
+			expected: `overriding claims: --status: `,
+		},
+		{
+			args:     []string{"--template", "--submod=gpu", "--nonce=short", "ear.jwt"},
+			expected: `overriding claims: --nonce: `,
+		},
+		{
+			args:     []string{"--template", "ear-claims.json", "ear.jwt"},
+			expected: `validating arguments: a claims-set file cannot be supplied with --template`,
+		},
+	}
+
+	for i, tv := range tvs {
+		makeFS(t, []fileEntry{
+			{"skey.json", testSKey},
+			{"ear-claims.json", testMiniClaimsSet},
+		})
+
+		cmd := NewCreateCmd()
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.ErrorContains(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_CreateCmd_overrides_ambiguous_submod(t *testing.T) {
+	var ar ear.AttestationResult
+	require.NoError(t, ar.UnmarshalJSON(testMiniClaimsSet))
+	ar.Submods["gpu"] = ar.Submods["test"]
+
+	claims, err := ar.MarshalJSON()
+	require.NoError(t, err)
+
+	makeFS(t, []fileEntry{
+		{"skey.json", testSKey},
+		{"ear-claims.json", claims},
+	})
+
+	cmd := NewCreateCmd()
+	cmd.SetArgs([]string{"--status=affirming", "ear.jwt"})
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, "overriding claims: --submod is needed to choose among 2 submods")
+}
+
+func testVerifyCreated(t *testing.T, name string) ear.AttestationResult {
+	data, err := afero.ReadFile(fs, name)
+	require.NoError(t, err)
+
+	vfyK, err := ear.LoadKey(testPKey)
+	require.NoError(t, err)
+
+	var ar ear.AttestationResult
+	require.NoError(t, ar.Verify(data, jwa.ES256, vfyK))
+
+	return ar
+}
@@ -0,0 +1,220 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const decodeWarning = "!! WARNING: the signature has NOT been verified, do not trust the content below"
+
+var (
+	decodeInput string
+	decodeClaim string
+)
+
+var decodeCmd = NewDecodeCmd()
+
+// decodeResult is the JSON output of the decode command
+type decodeResult struct {
+	Input    string      `json:"input"`
+	Verified bool        `json:"verified"`
+	Header   interface{} `json:"header,omitempty"`
+	Claims   interface{} `json:"claims,omitempty"`
+	Claim    string      `json:"claim,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+}
+
+func NewDecodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decode [flags] <jwt-file>",
+		Short: "Pretty-print the header and claims of a signed EAR WITHOUT verifying it",
+		Long: `Pretty-print the header and claims of a signed EAR WITHOUT verifying it
+
+This is meant for debugging tokens when the verification key is not at hand:
+the claims are printed as they appear in the token, without any validation, and
+must not be trusted.  Use the verify command to check them.
+
+Print the JWS protected header and the claims-set of "my-ear.jwt":
+
+	arc decode my-ear.jwt
+
+Print only the status of the "PSA_IOT" submod (claims are selected using their
+name or a JSON Pointer):
+
+	arc decode --claim=/submods/PSA_IOT/ear.status my-ear.jwt
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				data           []byte
+				header, claims interface{}
+				err            error
+			)
+
+			if err = checkDecodeArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			decodeInput = args[0]
+
+			if data, err = afero.ReadFile(fs, decodeInput); err != nil {
+				return fmt.Errorf("loading signed EAR from %q: %w", decodeInput, err)
+			}
+
+			if header, claims, err = decodeToken(bytes.TrimSpace(data)); err != nil {
+				return fmt.Errorf("decoding signed EAR from %q: %w", decodeInput, err)
+			}
+
+			fmt.Fprintln(cmd.ErrOrStderr(), decodeWarning)
+
+			res := decodeResult{Input: decodeInput}
+
+			if decodeClaim != "" {
+				v, err := lookupClaim(claims, decodeClaim)
+				if err != nil {
+					return fmt.Errorf("extracting claim %q: %w", decodeClaim, err)
+				}
+
+				res.Claim = decodeClaim
+				res.Value = v
+			} else {
+				res.Header = header
+				res.Claims = claims
+			}
+
+			if jsonOutput {
+				return printJSON(cmd, res)
+			}
+
+			out := cmd.OutOrStdout()
+
+			if decodeClaim != "" {
+				return printIndented(out, res.Value)
+			}
+
+			fmt.Fprintln(out, "[header]")
+			if err = printIndented(out, header); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, "[claims-set]")
+			return printIndented(out, claims)
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&decodeClaim, "claim", "c", "", "only print the claim with this name, or at this JSON Pointer",
+	)
+
+	return cmd
+}
+
+func checkDecodeArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("no input file supplied")
+	}
+	return nil
+}
+
+// decodeToken returns the protected header and claims-set of the supplied JWS,
+// without verifying its signature
+func decodeToken(data []byte) (interface{}, interface{}, error) {
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(msg.Signatures()) != 1 {
+		return nil, nil, fmt.Errorf("expecting one signature, found %d", len(msg.Signatures()))
+	}
+
+	hdr, err := json.Marshal(msg.Signatures()[0].ProtectedHeaders())
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding header: %w", err)
+	}
+
+	header, err := decodeJSONValue(hdr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding header: %w", err)
+	}
+
+	claims, err := decodeJSONValue(msg.Payload())
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	return header, claims, nil
+}
+
+// decodeJSONValue decodes JSON data, preserving numbers as they are
+func decodeJSONValue(data []byte) (interface{}, error) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// lookupClaim returns the claim with the supplied name or, if the selector
+// starts with "/", the value at the corresponding JSON Pointer
+func lookupClaim(claims interface{}, selector string) (interface{}, error) {
+	if !strings.HasPrefix(selector, "/") {
+		selector = "/" + jsonPointerEscaper.Replace(selector)
+	}
+
+	v := claims
+
+	for _, seg := range strings.Split(selector[1:], "/") {
+		seg = jsonPointerUnescaper.Replace(seg)
+
+		switch t := v.(type) {
+		case map[string]interface{}:
+			e, ok := t[seg]
+			if !ok {
+				return nil, errors.New("not found")
+			}
+			v = e
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, errors.New("not found")
+			}
+			v = t[i]
+		default:
+			return nil, errors.New("not found")
+		}
+	}
+
+	return v, nil
+}
+
+func printIndented(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return fmt.Errorf("serializing output: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(b))
+
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(decodeCmd)
+}
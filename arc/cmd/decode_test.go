@@ -0,0 +1,138 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DecodeCmd_unknown_argument(t *testing.T) {
+	cmd := NewDecodeCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_DecodeCmd_no_input(t *testing.T) {
+	cmd := NewDecodeCmd()
+	cmd.SetArgs([]string{})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "validating arguments: no input file supplied")
+}
+
+func Test_DecodeCmd_bad_input(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear.jwt", testMiniClaimsSet},
+	})
+
+	cmd := NewDecodeCmd()
+	cmd.SetArgs([]string{"ear.jwt"})
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, `decoding signed EAR from "ear.jwt": `)
+}
+
+func Test_DecodeCmd_ok(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear.jwt", testJWT},
+	})
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := NewDecodeCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"ear.jwt"})
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	assert.Contains(t, stderr.String(), decodeWarning)
+	assert.Contains(t, stdout.String(), "[header]\n{\n    \"alg\": \"ES256\",\n    \"typ\": \"JWT\"\n}\n")
+	assert.Contains(t, stdout.String(), "[claims-set]\n")
+	assert.Contains(t, stdout.String(), `"iat": 1666091373`)
+}
+
+func Test_DecodeCmd_claim(t *testing.T) {
+	tvs := []struct {
+		claim    string
+		expected string
+	}{
+		{"iat", "1666091373\n"},
+		{"/submods/test/ear.status", "\"affirming\"\n"},
+		{"ear.verifier-id", "{\n    \"build\": \"rrtrap-v1.0.0\",\n    \"developer\": \"Acme Inc.\"\n}\n"},
+	}
+
+	for i, tv := range tvs {
+		makeFS(t, []fileEntry{
+			{"ear.jwt", testJWT},
+		})
+
+		var stdout bytes.Buffer
+
+		cmd := NewDecodeCmd()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"--claim=" + tv.claim, "ear.jwt"})
+
+		err := cmd.Execute()
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, stdout.String(), "failed test vector at index %d", i)
+	}
+}
+
+func Test_DecodeCmd_claim_not_found(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear.jwt", testJWT},
+	})
+
+	cmd := NewDecodeCmd()
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--claim=/submods/other", "ear.jwt"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `extracting claim "/submods/other": not found`)
+}
+
+func Test_DecodeCmd_json_output(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear.jwt", testJWT},
+	})
+
+	var stdout, stderr bytes.Buffer
+
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		jsonOutput = false
+	}()
+
+	// the flag may have been set by a previous test
+	decodeClaim = ""
+
+	rootCmd.SetArgs([]string{"--json", "decode", "ear.jwt"})
+
+	err := rootCmd.Execute()
+	require.NoError(t, err)
+
+	var res map[string]interface{}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res), stdout.String())
+
+	assert.Equal(t, "ear.jwt", res["input"])
+	assert.Equal(t, false, res["verified"])
+	assert.Contains(t, res, "header")
+	assert.Contains(t, res, "claims")
+	assert.Contains(t, stderr.String(), decodeWarning)
+}
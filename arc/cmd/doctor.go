@@ -0,0 +1,477 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+	"github.com/veraison/ear/policy"
+)
+
+const (
+	// outcomes of the doctor checks
+	doctorOK      = "ok"
+	doctorProblem = "problem"
+	doctorSkipped = "skipped"
+)
+
+var (
+	doctorInput  string
+	doctorKeys   string
+	doctorPolicy string
+	doctorSkew   time.Duration
+)
+
+// doctorNow is the clock used to diagnose clock skew
+var doctorNow = time.Now
+
+var doctorCmd = NewDoctorCmd()
+
+// doctorFinding is the outcome of a single doctor check
+type doctorFinding struct {
+	Check   string `json:"check"`
+	Outcome string `json:"outcome"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// doctorResult is the JSON output of the doctor command
+type doctorResult struct {
+	Input    string          `json:"input"`
+	KeySet   string          `json:"key-set"`
+	Policy   string          `json:"policy,omitempty"`
+	Healthy  bool            `json:"healthy"`
+	Findings []doctorFinding `json:"findings"`
+}
+
+func NewDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor [flags] <jwt-file>",
+		Short: "Diagnose why a signed EAR in jwt-file is not accepted",
+		Long: `Diagnose why a signed EAR in jwt-file is not accepted
+
+Inspect the signed EAR, the key set and, optionally, the relying party policy
+together, and look for the most common misconfigurations: a kid that matches
+none of the keys, an algorithm that does not fit the key, clocks out of sync,
+an unexpected profile and missing submods.  Each problem found is reported
+with a suggested fix.
+
+Diagnose "my-ear.jwt" against the keys in "jwks.json" (a JWK Set, or a single
+JWK, PEM or DER key):
+
+	arc doctor --keys=jwks.json my-ear.jwt
+
+Also check the profile and the required submods in the policy "policy.json"
+(see the policy package), allowing for up to 30 seconds of clock skew:
+
+	arc doctor --keys=jwks.json --policy=policy.json --skew=30s my-ear.jwt
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				token, keyBytes, policyBytes []byte
+				keys                         jwk.Set
+				p                            *policy.Policy
+				err                          error
+			)
+
+			if err = checkDoctorArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			doctorInput = args[0]
+
+			if token, err = afero.ReadFile(fs, doctorInput); err != nil {
+				return fmt.Errorf("loading signed EAR from %q: %w", doctorInput, err)
+			}
+
+			if keyBytes, err = afero.ReadFile(fs, doctorKeys); err != nil {
+				return fmt.Errorf("loading key set from %q: %w", doctorKeys, err)
+			}
+
+			if keys, err = loadKeySet(keyBytes); err != nil {
+				return fmt.Errorf("parsing key set from %q: %w", doctorKeys, err)
+			}
+
+			if doctorPolicy != "" {
+				if policyBytes, err = afero.ReadFile(fs, doctorPolicy); err != nil {
+					return fmt.Errorf("loading policy from %q: %w", doctorPolicy, err)
+				}
+
+				if p, err = policy.Parse(policyBytes); err != nil {
+					return fmt.Errorf("parsing policy from %q: %w", doctorPolicy, err)
+				}
+			}
+
+			findings := diagnose(token, keys, p, doctorNow(), doctorSkew)
+
+			var problems int
+			for _, f := range findings {
+				if f.Outcome == doctorProblem {
+					problems++
+				}
+			}
+
+			if jsonOutput {
+				if err = printJSON(cmd, doctorResult{
+					Input:    doctorInput,
+					KeySet:   doctorKeys,
+					Policy:   doctorPolicy,
+					Healthy:  problems == 0,
+					Findings: findings,
+				}); err != nil {
+					return err
+				}
+			} else {
+				out := cmd.OutOrStdout()
+
+				for _, f := range findings {
+					fmt.Fprintf(out, "[%s] %s: %s\n", f.Outcome, f.Check, f.Message)
+					if f.Fix != "" {
+						fmt.Fprintf(out, "    fix: %s\n", f.Fix)
+					}
+				}
+
+				if problems == 0 {
+					fmt.Fprintf(out, ">> no problems found in %q\n", doctorInput)
+				}
+			}
+
+			if problems > 0 {
+				return fmt.Errorf("%d problem(s) found in %q", problems, doctorInput)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&doctorKeys, "keys", "k", "pkey.json", "verification key set (JWK Set, or a single JWK, PEM or DER key)",
+	)
+
+	cmd.Flags().StringVar(
+		&doctorPolicy, "policy", "", "relying party policy (JSON) to diagnose against",
+	)
+
+	cmd.Flags().DurationVar(
+		&doctorSkew, "skew", 0, "clock skew tolerated when checking iat, nbf and exp",
+	)
+
+	return cmd
+}
+
+func checkDoctorArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("no input file supplied")
+	}
+
+	if doctorSkew < 0 {
+		return fmt.Errorf("negative clock skew %s", doctorSkew)
+	}
+
+	return nil
+}
+
+// loadKeySet parses either a JWK Set, or a single key in any of the formats
+// accepted by ear.LoadKey
+func loadKeySet(data []byte) (jwk.Set, error) {
+	if set, err := jwk.Parse(data); err == nil {
+		return set, nil
+	}
+
+	key, err := ear.LoadKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	set := jwk.NewSet()
+	if err := set.AddKey(key); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// diagnose runs the doctor checks on the signed EAR in token.  The checks
+// depending on a failed one are reported as skipped.
+func diagnose(
+	token []byte,
+	keys jwk.Set,
+	p *policy.Policy,
+	now time.Time,
+	skew time.Duration,
+) []doctorFinding {
+	var findings []doctorFinding
+
+	report := func(check, outcome, message, fix string) {
+		findings = append(findings, doctorFinding{
+			Check: check, Outcome: outcome, Message: message, Fix: fix,
+		})
+	}
+
+	msg, err := jws.Parse(token)
+	if err != nil || len(msg.Signatures()) != 1 {
+		if err == nil {
+			err = fmt.Errorf("expecting exactly one signature, found %d", len(msg.Signatures()))
+		}
+		report("token", doctorProblem, err.Error(),
+			"make sure the file holds a single EAR in JWS compact serialization, with no surrounding whitespace or quotes")
+		return findings
+	}
+
+	hdrs := msg.Signatures()[0].ProtectedHeaders()
+	alg := hdrs.Algorithm()
+
+	report("token", doctorOK, fmt.Sprintf("JWS signed with %s", alg), "")
+
+	key := diagnoseKid(hdrs.KeyID(), keys, report)
+	diagnoseAlg(alg, key, report)
+
+	if key != nil && !hasProblem(findings, "alg") {
+		if _, err := jws.Verify(token, jws.WithKey(alg, key)); err != nil {
+			report("signature", doctorProblem, err.Error(),
+				"the EAR was signed with a different key, or has been modified after signing: "+
+					"check that the key set holds the verifier's current public key")
+		} else {
+			report("signature", doctorOK, "signature verified", "")
+		}
+	} else {
+		report("signature", doctorSkipped, "no usable verification key", "")
+	}
+
+	var claims doctorClaims
+
+	// the claims are inspected whether or not the signature verifies, so
+	// that all the problems are reported at once.  They are not decoded as
+	// an EAR, which would stop at the first invalid claim.
+	if err := json.Unmarshal(msg.Payload(), &claims); err != nil {
+		report("claims", doctorProblem, err.Error(),
+			"run \"arc check\" on the claims-set for a detailed list of problems")
+		return findings
+	}
+
+	diagnoseClock(claims, now, skew, report)
+	diagnoseProfile(claims, p, report)
+	diagnoseSubmods(claims, p, report)
+
+	return findings
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasProblem(findings []doctorFinding, check string) bool {
+	for _, f := range findings {
+		if f.Check == check && f.Outcome == doctorProblem {
+			return true
+		}
+	}
+	return false
+}
+
+// doctorClaims are the claims inspected by the doctor
+type doctorClaims struct {
+	Profile   *string                    `json:"eat_profile"`
+	IssuedAt  *int64                     `json:"iat"`
+	NotBefore *int64                     `json:"nbf"`
+	Expiry    *int64                     `json:"exp"`
+	Submods   map[string]json.RawMessage `json:"submods"`
+}
+
+type doctorReporter func(check, outcome, message, fix string)
+
+// diagnoseKid looks up the key to verify with, and returns it if found
+func diagnoseKid(kid string, keys jwk.Set, report doctorReporter) jwk.Key {
+	if keys.Len() == 0 {
+		report("kid", doctorProblem, "the key set is empty",
+			"add the verifier's public key to the key set")
+		return nil
+	}
+
+	if kid == "" {
+		if keys.Len() == 1 {
+			key, _ := keys.Key(0)
+			report("kid", doctorOK, "no kid in the JWS header, using the only key in the key set", "")
+			return key
+		}
+
+		report("kid", doctorProblem,
+			fmt.Sprintf("no kid in the JWS header, and the key set has %d keys", keys.Len()),
+			"have the verifier set a kid in the JWS header (see ear.WithKeyID), or supply only its key")
+		return nil
+	}
+
+	if key, ok := keys.LookupKeyID(kid); ok {
+		report("kid", doctorOK, fmt.Sprintf("kid %q found in the key set", kid), "")
+		return key
+	}
+
+	var known []string
+
+	for i := 0; i < keys.Len(); i++ {
+		key, _ := keys.Key(i)
+
+		if tp, err := key.Thumbprint(crypto.SHA256); err == nil &&
+			base64.RawURLEncoding.EncodeToString(tp) == kid {
+			report("kid", doctorOK, fmt.Sprintf("kid %q matches the thumbprint of a key in the key set", kid), "")
+			return key
+		}
+
+		if k := key.KeyID(); k != "" {
+			known = append(known, fmt.Sprintf("%q", k))
+		}
+	}
+
+	message := fmt.Sprintf("kid %q matches no key in the key set", kid)
+	if len(known) > 0 {
+		sort.Strings(known)
+		message += fmt.Sprintf(" (known kids: %s)", strings.Join(known, ", "))
+	}
+
+	report("kid", doctorProblem, message,
+		"the verifier may have rotated its key: fetch its current key set, "+
+			"or set the key's kid to the one in the JWS header")
+
+	return nil
+}
+
+// diagnoseAlg checks that the algorithm in the JWS header fits the key
+func diagnoseAlg(alg jwa.SignatureAlgorithm, key jwk.Key, report doctorReporter) {
+	if key == nil {
+		report("alg", doctorSkipped, "no verification key", "")
+		return
+	}
+
+	if err := validateKey(key, alg.String(), keyForVerification); err != nil {
+		fix := "sign with an algorithm that fits the verifier key, or verify with a key that fits " + alg.String()
+		if keyAlg := key.Algorithm().String(); keyAlg != "" && keyAlg != alg.String() {
+			fix = fmt.Sprintf("sign with %s, or drop or fix the \"alg\" parameter of the key", keyAlg)
+		}
+
+		report("alg", doctorProblem, err.Error(), fix)
+		return
+	}
+
+	report("alg", doctorOK, fmt.Sprintf("%s fits the %s key", alg, key.KeyType()), "")
+}
+
+// diagnoseClock compares the time claims with the local clock
+func diagnoseClock(c doctorClaims, now time.Time, skew time.Duration, report doctorReporter) {
+	const fix = "synchronize the verifier and relying party clocks (e.g., using NTP), " +
+		"or tolerate some clock skew (see --skew and ear.WithAcceptableSkew)"
+
+	var problems []string
+
+	if c.IssuedAt != nil {
+		if d := time.Unix(*c.IssuedAt, 0).Sub(now); d > skew {
+			problems = append(problems, fmt.Sprintf("iat is %s in the future", d))
+		}
+	}
+
+	if c.NotBefore != nil {
+		if d := time.Unix(*c.NotBefore, 0).Sub(now); d > skew {
+			problems = append(problems, fmt.Sprintf("nbf is %s in the future", d))
+		}
+	}
+
+	if c.Expiry != nil {
+		if d := now.Sub(time.Unix(*c.Expiry, 0)); d > skew {
+			problems = append(problems, fmt.Sprintf("the EAR expired %s ago", d))
+		}
+	}
+
+	if len(problems) > 0 {
+		report("clock", doctorProblem, strings.Join(problems, ", "), fix)
+		return
+	}
+
+	report("clock", doctorOK, "time claims are consistent with the local clock", "")
+}
+
+// diagnoseProfile checks that the profile is the one expected by the policy
+// or, without a policy, one that is supported
+func diagnoseProfile(c doctorClaims, p *policy.Policy, report doctorReporter) {
+	if c.Profile == nil {
+		report("profile", doctorProblem, "no eat_profile in the EAR",
+			fmt.Sprintf("have the verifier set eat_profile to %q", ear.EatProfile))
+		return
+	}
+
+	profile := *c.Profile
+
+	if p != nil && p.Profile != "" {
+		if profile != p.Profile {
+			report("profile", doctorProblem,
+				fmt.Sprintf("eat_profile is %q, but the policy expects %q", profile, p.Profile),
+				"align the profile in the policy with the one the verifier emits")
+			return
+		}
+	} else if !contains(ear.SupportedProfiles(), profile) {
+		report("profile", doctorProblem,
+			fmt.Sprintf("eat_profile %q is not supported", profile),
+			fmt.Sprintf("have the verifier emit %q, or upgrade arc", ear.EatProfile))
+		return
+	}
+
+	report("profile", doctorOK, fmt.Sprintf("eat_profile is %q", profile), "")
+}
+
+// diagnoseSubmods checks that the submods required by the policy are there
+func diagnoseSubmods(c doctorClaims, p *policy.Policy, report doctorReporter) {
+	if p == nil {
+		report("submods", doctorSkipped, "no policy supplied", "")
+		return
+	}
+
+	var missing []string
+
+	for _, r := range p.Submods {
+		if r.Name == policy.AnySubmod || !r.Required {
+			continue
+		}
+
+		if _, ok := c.Submods[r.Name]; !ok {
+			missing = append(missing, fmt.Sprintf("%q", r.Name))
+		}
+	}
+
+	if len(missing) == 0 {
+		report("submods", doctorOK, "all the submods required by the policy are present", "")
+		return
+	}
+
+	present := make([]string, 0, len(c.Submods))
+	for name := range c.Submods {
+		present = append(present, fmt.Sprintf("%q", name))
+	}
+	sort.Strings(present)
+
+	report("submods", doctorProblem,
+		fmt.Sprintf("missing required submod(s) %s (present: %s)",
+			strings.Join(missing, ", "), strings.Join(present, ", ")),
+		"check that the submod names in the policy match those the verifier uses "+
+			"for the attester's components, and that evidence for them was supplied")
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,365 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+var (
+	testDoctorNow = time.Unix(1666091373, 0)
+
+	testDoctorKeySet = []byte(`{
+    "keys": [
+        {
+            "kid": "verifier-2023",
+            "kty": "EC",
+            "crv": "P-256",
+            "x": "usWxHK2PmfnHKwXPS54m0kTcGJ90UiglWiGahtagnv8",
+            "y": "IBOL-C3BttVivg-lSreASjpkttcsz-1rb7btKLv8EX4"
+        },
+        {
+            "kid": "verifier-2022",
+            "kty": "EC",
+            "crv": "P-256",
+            "x": "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",
+            "y": "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"
+        }
+    ]
+}`)
+
+	testDoctorPolicy = []byte(`{
+    "profile": "tag:github.com,2023:veraison/ear",
+    "submods": [
+        { "name": "test", "required": true },
+        { "name": "gpu", "required": true }
+    ]
+}`)
+)
+
+// testDoctorJWT signs the claims-set in testMiniClaimsSet, after applying
+// tweak, with testSKey, using the supplied kid
+func testDoctorJWT(t *testing.T, kid string, tweak func(*ear.AttestationResult)) []byte {
+	var ar ear.AttestationResult
+	require.NoError(t, ar.UnmarshalJSON(testMiniClaimsSet))
+
+	if tweak != nil {
+		tweak(&ar)
+	}
+
+	key, err := ear.LoadKey(testSKey)
+	require.NoError(t, err)
+
+	var opts []ear.SignOption
+	if kid != "" {
+		opts = append(opts, ear.WithKeyID(kid))
+	}
+
+	token, err := ar.Sign(jwa.ES256, key, opts...)
+	require.NoError(t, err)
+
+	return token
+}
+
+func runDoctor(t *testing.T, files []fileEntry, args ...string) (doctorResult, error) {
+	makeFS(t, files)
+
+	doctorNow = func() time.Time { return testDoctorNow }
+	jsonOutput = true
+	t.Cleanup(func() {
+		doctorNow = time.Now
+		jsonOutput = false
+	})
+
+	var stdout bytes.Buffer
+
+	cmd := NewDoctorCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+
+	var res doctorResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res))
+
+	return res, err
+}
+
+func doctorOutcomes(res doctorResult) map[string]string {
+	ret := map[string]string{}
+	for _, f := range res.Findings {
+		ret[f.Check] = f.Outcome
+	}
+	return ret
+}
+
+func Test_DoctorCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{},
+			expected: "validating arguments: no input file supplied",
+		},
+		{
+			args:     []string{"--skew=-1s", "ear.jwt"},
+			expected: "validating arguments: negative clock skew -1s",
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewDoctorCmd()
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_DoctorCmd_missing_files(t *testing.T) {
+	makeFS(t, []fileEntry{{"ear.jwt", testJWT}})
+
+	cmd := NewDoctorCmd()
+	cmd.SetArgs([]string{"--keys=jwks.json", "ear.jwt"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `loading key set from "jwks.json": open jwks.json: file does not exist`)
+}
+
+func Test_DoctorCmd_healthy(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2023", nil)},
+		{"jwks.json", testDoctorKeySet},
+	}
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "ear.jwt")
+	require.NoError(t, err)
+
+	assert.True(t, res.Healthy)
+	assert.Equal(t, map[string]string{
+		"token":     doctorOK,
+		"kid":       doctorOK,
+		"alg":       doctorOK,
+		"signature": doctorOK,
+		"clock":     doctorOK,
+		"profile":   doctorOK,
+		"submods":   doctorSkipped,
+	}, doctorOutcomes(res))
+}
+
+func Test_DoctorCmd_single_key_without_kid(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "", nil)},
+		{"pkey.pem", testPKeyPEM},
+	}
+
+	res, err := runDoctor(t, files, "--keys=pkey.pem", "ear.jwt")
+	require.NoError(t, err)
+	assert.True(t, res.Healthy)
+}
+
+func Test_DoctorCmd_kid_mismatch(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2024", nil)},
+		{"jwks.json", testDoctorKeySet},
+	}
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "ear.jwt")
+	assert.EqualError(t, err, `1 problem(s) found in "ear.jwt"`)
+
+	require.Equal(t, "kid", res.Findings[1].Check)
+	assert.Equal(t, doctorProblem, res.Findings[1].Outcome)
+	assert.Equal(t,
+		`kid "verifier-2024" matches no key in the key set (known kids: "verifier-2022", "verifier-2023")`,
+		res.Findings[1].Message)
+	assert.Contains(t, res.Findings[1].Fix, "rotated")
+
+	assert.Equal(t, doctorSkipped, doctorOutcomes(res)["signature"])
+}
+
+func Test_DoctorCmd_no_kid_many_keys(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "", nil)},
+		{"jwks.json", testDoctorKeySet},
+	}
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "ear.jwt")
+	assert.Error(t, err)
+	assert.Equal(t, "no kid in the JWS header, and the key set has 2 keys", res.Findings[1].Message)
+}
+
+func Test_DoctorCmd_wrong_key(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2022", nil)},
+		{"jwks.json", testDoctorKeySet},
+	}
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "ear.jwt")
+	assert.Error(t, err)
+	assert.Equal(t, doctorProblem, doctorOutcomes(res)["signature"])
+}
+
+func Test_DoctorCmd_alg_mismatch(t *testing.T) {
+	keySet := []byte(`{
+    "keys": [
+        {
+            "kid": "verifier-2023",
+            "alg": "ES384",
+            "kty": "EC",
+            "crv": "P-256",
+            "x": "usWxHK2PmfnHKwXPS54m0kTcGJ90UiglWiGahtagnv8",
+            "y": "IBOL-C3BttVivg-lSreASjpkttcsz-1rb7btKLv8EX4"
+        }
+    ]
+}`)
+
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2023", nil)},
+		{"jwks.json", keySet},
+	}
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "ear.jwt")
+	assert.Error(t, err)
+
+	require.Equal(t, "alg", res.Findings[2].Check)
+	assert.Equal(t, doctorProblem, res.Findings[2].Outcome)
+	assert.Equal(t, `key is restricted to "ES384" (its "alg" parameter), but "ES256" was requested`,
+		res.Findings[2].Message)
+	assert.Equal(t, `sign with ES384, or drop or fix the "alg" parameter of the key`, res.Findings[2].Fix)
+	assert.Equal(t, doctorSkipped, doctorOutcomes(res)["signature"])
+}
+
+func Test_DoctorCmd_clock_skew(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2023", func(ar *ear.AttestationResult) {
+			iat := testDoctorNow.Add(2 * time.Minute).Unix()
+			exp := testDoctorNow.Add(-time.Hour).Unix()
+			ar.IssuedAt = &iat
+			ar.Expiry = &exp
+		})},
+		{"jwks.json", testDoctorKeySet},
+	}
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "ear.jwt")
+	assert.EqualError(t, err, `1 problem(s) found in "ear.jwt"`)
+
+	f := res.Findings[4]
+	require.Equal(t, "clock", f.Check)
+	assert.Equal(t, "iat is 2m0s in the future, the EAR expired 1h0m0s ago", f.Message)
+	assert.Contains(t, f.Fix, "--skew")
+
+	// tolerating the skew sorts out iat, but not the expiry
+	res, err = runDoctor(t, files, "--keys=jwks.json", "--skew=5m", "ear.jwt")
+	assert.Error(t, err)
+	assert.Equal(t, "the EAR expired 1h0m0s ago", res.Findings[4].Message)
+}
+
+func Test_DoctorCmd_clock_not_expired(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2023", func(ar *ear.AttestationResult) {
+			exp := testDoctorNow.Add(2 * time.Minute).Unix()
+			ar.Expiry = &exp
+		})},
+		{"jwks.json", testDoctorKeySet},
+	}
+
+	// an EAR that expires soon is fine, whatever the tolerated skew
+	for _, skew := range []string{"--skew=0s", "--skew=5m"} {
+		res, err := runDoctor(t, files, "--keys=jwks.json", skew, "ear.jwt")
+		assert.NoError(t, err, skew)
+		assert.Equal(t, doctorOK, doctorOutcomes(res)["clock"], skew)
+	}
+
+	// an EAR that expired within the tolerated skew is still fine
+	files[0].content = testDoctorJWT(t, "verifier-2023", func(ar *ear.AttestationResult) {
+		exp := testDoctorNow.Add(-time.Minute).Unix()
+		ar.Expiry = &exp
+	})
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "--skew=5m", "ear.jwt")
+	assert.NoError(t, err)
+	assert.Equal(t, doctorOK, doctorOutcomes(res)["clock"])
+}
+
+func Test_DoctorCmd_policy(t *testing.T) {
+	otherProfile := []byte(`{ "profile": "tag:example.com,2024:other-profile" }`)
+
+	files := []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2023", nil)},
+		{"jwks.json", testDoctorKeySet},
+		{"policy.json", testDoctorPolicy},
+		{"other-profile.json", otherProfile},
+	}
+
+	res, err := runDoctor(t, files, "--keys=jwks.json", "--policy=policy.json", "ear.jwt")
+	assert.EqualError(t, err, `1 problem(s) found in "ear.jwt"`)
+
+	f := res.Findings[6]
+	require.Equal(t, "submods", f.Check)
+	assert.Equal(t, doctorProblem, f.Outcome)
+	assert.Equal(t, `missing required submod(s) "gpu" (present: "test")`, f.Message)
+
+	res, err = runDoctor(t, files, "--keys=jwks.json", "--policy=other-profile.json", "ear.jwt")
+	assert.Error(t, err)
+
+	f = res.Findings[5]
+	require.Equal(t, "profile", f.Check)
+	assert.Equal(t,
+		`eat_profile is "tag:github.com,2023:veraison/ear", but the policy expects "tag:example.com,2024:other-profile"`,
+		f.Message)
+}
+
+func Test_DoctorCmd_unsupported_profile(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testJWTUnsupportedProfile},
+		{"pkey.json", testPKey},
+	}
+
+	res, err := runDoctor(t, files, "ear.jwt")
+	assert.Error(t, err)
+	assert.Equal(t, doctorOK, doctorOutcomes(res)["signature"])
+	assert.Equal(t, doctorProblem, doctorOutcomes(res)["profile"])
+}
+
+func Test_DoctorCmd_not_a_jws(t *testing.T) {
+	files := []fileEntry{
+		{"ear.jwt", testMiniClaimsSet},
+		{"pkey.json", testPKey},
+	}
+
+	res, err := runDoctor(t, files, "ear.jwt")
+	assert.Error(t, err)
+	require.Len(t, res.Findings, 1)
+	assert.Equal(t, "token", res.Findings[0].Check)
+	assert.Equal(t, doctorProblem, res.Findings[0].Outcome)
+}
+
+func Test_DoctorCmd_text_output(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear.jwt", testDoctorJWT(t, "verifier-2024", nil)},
+		{"jwks.json", testDoctorKeySet},
+	})
+
+	var stdout bytes.Buffer
+
+	cmd := NewDoctorCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--keys=jwks.json", "ear.jwt"})
+
+	assert.Error(t, cmd.Execute())
+	assert.Contains(t, stdout.String(), `[problem] kid: kid "verifier-2024" matches no key in the key set`)
+	assert.Contains(t, stdout.String(), "    fix: the verifier may have rotated its key")
+}
@@ -0,0 +1,350 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+)
+
+var (
+	grepVerify  bool
+	grepPKey    string
+	grepAlg     string
+	grepContext bool
+)
+
+var grepCmd = NewGrepCmd()
+
+// grepMatch is a claim that matches the grep expression.  Context is the
+// object that contains the claim, and is only set when --context is used.
+type grepMatch struct {
+	File    string      `json:"file"`
+	Path    string      `json:"path"`
+	Value   interface{} `json:"value"`
+	Context interface{} `json:"context,omitempty"`
+}
+
+// grepResult is the JSON output of the grep command
+type grepResult struct {
+	Expression string      `json:"expression"`
+	Verified   bool        `json:"verified"`
+	Matches    []grepMatch `json:"matches"`
+}
+
+// grepExpr is a parsed grep expression, i.e., a claim path pattern optionally
+// followed by the value the claim must have
+type grepExpr struct {
+	path     *regexp.Regexp
+	value    string
+	hasValue bool
+}
+
+func NewGrepCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grep [flags] <expression> <path>...",
+		Short: "Search a tree of EARs for claims matching an expression",
+		Long: `Search a tree of EARs for claims matching an expression
+
+The expression is a dot-separated claim path, optionally followed by "=" and
+the value the claim must have.  In the path, "*" matches any sequence of
+characters, including dots.  Each path argument can be a file or a directory,
+which is searched recursively.  Files can contain either a signed EAR (JWT)
+or an EAR claims-set (JSON).  Files that cannot be decoded are reported and
+skipped.
+
+List the contraindicated submods in the EARs under "ears/":
+
+	arc grep 'submods.*.ear.status=contraindicated' ears/
+
+Same, considering only EARs whose signature can be verified using the key in
+"pkey.json", and showing the appraisal each match belongs to:
+
+	arc grep --verify --pkey pkey.json --context 'submods.*.ear.status=contraindicated' ears/
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				expr    *grepExpr
+				vfyK    jwk.Key
+				files   []string
+				matches []grepMatch
+				err     error
+			)
+
+			if err = checkGrepArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			if expr, err = parseGrepExpr(args[0]); err != nil {
+				return fmt.Errorf("parsing expression %q: %w", args[0], err)
+			}
+
+			if grepVerify {
+				if vfyK, err = loadGrepKey(); err != nil {
+					return err
+				}
+			}
+
+			for _, root := range args[1:] {
+				if files, err = listGrepFiles(root, files); err != nil {
+					return fmt.Errorf("listing files in %q: %w", root, err)
+				}
+			}
+
+			for _, f := range files {
+				ar, err := loadGrepEAR(f, vfyK)
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), ">> skipping %q: %v\n", f, err)
+					continue
+				}
+
+				matches = append(matches, expr.match(f, ar, grepContext)...)
+			}
+
+			if jsonOutput {
+				if err = printJSON(cmd, grepResult{
+					Expression: args[0],
+					Verified:   grepVerify,
+					Matches:    matches,
+				}); err != nil {
+					return err
+				}
+			} else {
+				printGrepMatches(cmd.OutOrStdout(), matches)
+			}
+
+			if len(matches) == 0 {
+				return errors.New("no matches found")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(
+		&grepVerify, "verify", false, "only consider signed EARs whose signature can be verified",
+	)
+
+	cmd.Flags().StringVarP(
+		&grepPKey, "pkey", "p", "pkey.json", "verification key (JWK, PEM or DER, used with --verify)",
+	)
+
+	cmd.Flags().StringVarP(
+		&grepAlg, "alg", "a", "ES256", "verification algorithm ("+algList()+")",
+	)
+
+	cmd.Flags().BoolVarP(
+		&grepContext, "context", "C", false, "print the object containing each matching claim",
+	)
+
+	return cmd
+}
+
+func checkGrepArgs(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no expression supplied")
+	}
+
+	if len(args) == 1 {
+		return errors.New("no input path supplied")
+	}
+
+	return nil
+}
+
+func parseGrepExpr(s string) (*grepExpr, error) {
+	var e grepExpr
+
+	pattern, value, hasValue := strings.Cut(s, "=")
+	if pattern == "" {
+		return nil, errors.New("empty claim path")
+	}
+
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	if err != nil {
+		return nil, err
+	}
+
+	e.path = re
+	e.value = value
+	e.hasValue = hasValue
+
+	return &e, nil
+}
+
+// match returns the claims in ar that match the expression.  Claims are
+// reported in the order in which AttestationResult.Walk visits them.
+func (o grepExpr) match(file string, ar *ear.AttestationResult, withContext bool) []grepMatch {
+	var matches []grepMatch
+
+	// Walk reports containers before their contents, so the parent of a
+	// claim is always available by the time the claim is visited
+	containers := map[string]interface{}{}
+
+	ar.Walk(func(pointer string, v any) bool {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			containers[pointer] = v
+		}
+
+		if !o.path.MatchString(pointerToDotted(pointer)) {
+			return true
+		}
+
+		if o.hasValue && grepValueString(v) != o.value {
+			return true
+		}
+
+		m := grepMatch{File: file, Path: pointer, Value: v}
+
+		// top-level claims have no context other than the whole claims-set
+		if i := strings.LastIndex(pointer, "/"); withContext && i > 0 {
+			m.Context = containers[pointer[:i]]
+		}
+
+		matches = append(matches, m)
+
+		return true
+	})
+
+	return matches
+}
+
+// pointerToDotted turns a JSON pointer into the dot-separated form used in
+// grep expressions
+func pointerToDotted(pointer string) string {
+	segs := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, s := range segs {
+		segs[i] = jsonPointerUnescaper.Replace(s)
+	}
+
+	return strings.Join(segs, ".")
+}
+
+func grepValueString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(b)
+	}
+}
+
+func listGrepFiles(root string, files []string) ([]string, error) {
+	err := afero.Walk(fs, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+func loadGrepKey() (jwk.Key, error) {
+	pKey, err := afero.ReadFile(fs, grepPKey)
+	if err != nil {
+		return nil, fmt.Errorf("loading verification key from %q: %w", grepPKey, err)
+	}
+
+	vfyK, err := ear.LoadKey(pKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing verification key from %q: %w", grepPKey, err)
+	}
+
+	return vfyK, nil
+}
+
+// loadGrepEAR decodes the EAR in the supplied file.  If a verification key is
+// supplied, the file must contain a signed EAR that verifies with it.
+// Otherwise, claims-sets are accepted too, and the signature of signed EARs
+// is not checked.
+func loadGrepEAR(file string, vfyK jwk.Key) (*ear.AttestationResult, error) {
+	var ar ear.AttestationResult
+
+	data, err := afero.ReadFile(fs, file)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("{")) {
+		if vfyK != nil {
+			return nil, errors.New("unsigned claims-set")
+		}
+
+		if err = ar.UnmarshalJSON(data); err != nil {
+			return nil, fmt.Errorf("decoding claims-set: %w", err)
+		}
+
+		return &ar, nil
+	}
+
+	if vfyK != nil {
+		if err = ar.Verify(data, jwa.KeyAlgorithmFrom(grepAlg), vfyK); err != nil {
+			return nil, fmt.Errorf("verifying signed EAR: %w", err)
+		}
+
+		return &ar, nil
+	}
+
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signed EAR: %w", err)
+	}
+
+	if err = ar.UnmarshalJSON(msg.Payload()); err != nil {
+		return nil, fmt.Errorf("decoding signed EAR payload: %w", err)
+	}
+
+	return &ar, nil
+}
+
+func printGrepMatches(w io.Writer, matches []grepMatch) {
+	for _, m := range matches {
+		fmt.Fprintf(w, "%s: %s=%s\n", m.File, pointerToDotted(m.Path), grepValueString(m.Value))
+
+		if m.Context != nil {
+			b, err := json.MarshalIndent(m.Context, "    ", "    ")
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "    %s\n", b)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(grepCmd)
+}
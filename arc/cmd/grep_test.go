@@ -0,0 +1,182 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testContraindicatedClaimsSet = []byte(strings.Replace(
+	string(testMiniClaimsSet), `"affirming"`, `"contraindicated"`, 1,
+))
+
+func testGrepFS(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"pkey.json", testPKey},
+		{"ears/a.jwt", testJWT},
+		{"ears/more/b.json", testContraindicatedClaimsSet},
+		{"ears/junk.txt", []byte("not an EAR")},
+	})
+}
+
+func Test_GrepCmd_unknown_argument(t *testing.T) {
+	cmd := NewGrepCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_GrepCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{},
+			expected: "validating arguments: no expression supplied",
+		},
+		{
+			args:     []string{"submods.*.ear.status"},
+			expected: "validating arguments: no input path supplied",
+		},
+		{
+			args:     []string{"=affirming", "ears"},
+			expected: `parsing expression "=affirming": empty claim path`,
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewGrepCmd()
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_GrepCmd_ok(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"submods.*.ear.status=contraindicated", "ears"},
+			expected: "ears/more/b.json: submods.test.ear.status=contraindicated\n",
+		},
+		{
+			args: []string{"submods.*.ear.status", "ears"},
+			expected: "ears/a.jwt: submods.test.ear.status=affirming\n" +
+				"ears/more/b.json: submods.test.ear.status=contraindicated\n",
+		},
+		{
+			args:     []string{"*.executables=3", "ears/a.jwt"},
+			expected: "ears/a.jwt: submods.test.ear.trustworthiness-vector.executables=3\n",
+		},
+		{
+			// only verified EARs are considered
+			args:     []string{"--verify", "--pkey=pkey.json", "submods.*.ear.status", "ears"},
+			expected: "ears/a.jwt: submods.test.ear.status=affirming\n",
+		},
+	}
+
+	for i, tv := range tvs {
+		testGrepFS(t)
+
+		var stdout, stderr bytes.Buffer
+
+		cmd := NewGrepCmd()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, stdout.String(), "failed test vector at index %d", i)
+	}
+}
+
+func Test_GrepCmd_skipped_files(t *testing.T) {
+	testGrepFS(t)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := NewGrepCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"--verify", "eat_profile", "ears"})
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	assert.Contains(t, stderr.String(), `>> skipping "ears/junk.txt": verifying signed EAR: `)
+	assert.Contains(t, stderr.String(), `>> skipping "ears/more/b.json": unsigned claims-set`)
+}
+
+func Test_GrepCmd_no_match(t *testing.T) {
+	testGrepFS(t)
+
+	cmd := NewGrepCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"submods.*.ear.status=warning", "ears"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "no matches found")
+}
+
+func Test_GrepCmd_missing_path(t *testing.T) {
+	testGrepFS(t)
+
+	cmd := NewGrepCmd()
+	cmd.SetArgs([]string{"eat_profile", "nowhere"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `listing files in "nowhere": open nowhere: file does not exist`)
+}
+
+func Test_GrepCmd_json_output(t *testing.T) {
+	testGrepFS(t)
+
+	var stdout, stderr bytes.Buffer
+
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		jsonOutput = false
+	}()
+
+	rootCmd.SetArgs([]string{
+		"--json",
+		"grep",
+		"--context",
+		"submods.*.ear.status=contraindicated",
+		"ears",
+	})
+
+	err := rootCmd.Execute()
+	require.NoError(t, err)
+
+	var res grepResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res), stdout.String())
+
+	require.Len(t, res.Matches, 1)
+	assert.Equal(t, "ears/more/b.json", res.Matches[0].File)
+	assert.Equal(t, "/submods/test/ear.status", res.Matches[0].Path)
+	assert.Equal(t, "contraindicated", res.Matches[0].Value)
+	assert.Equal(t, map[string]interface{}{
+		"ear.status":              "contraindicated",
+		"ear.appraisal-policy-id": "https://veraison.example/policy/1/60a0068d",
+	}, res.Matches[0].Context)
+}
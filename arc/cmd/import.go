@@ -0,0 +1,150 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+)
+
+// importConverter turns a foreign attestation result into an EAR
+type importConverter func(data []byte, submod string) (*ear.AttestationResult, error)
+
+// importFormats maps the values accepted by --format onto the library
+// converters
+var importFormats = map[string]importConverter{
+	"dcap":            ear.FromDCAP,
+	"legacy-veraison": ear.FromLegacyVeraison,
+	"nitro":           ear.FromNitro,
+	"playintegrity":   ear.FromPlayIntegrity,
+}
+
+var (
+	importInput  string
+	importFormat string
+	importOutput string
+	importSubmod string
+)
+
+var importCmd = NewImportCmd()
+
+// importResult is the JSON output of the import command
+type importResult struct {
+	Input  string `json:"input"`
+	Format string `json:"format"`
+	Output string `json:"output"`
+}
+
+func NewImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import [flags] <input-file>",
+		Short: "Convert a foreign attestation result into an EAR claims-set",
+		Long: `Convert a foreign attestation result into an EAR claims-set
+
+Convert the legacy Veraison attestation result in "result.json" into an EAR
+claims-set, and save it to "ear-claims.json", ready to be signed with the
+create command.
+
+	arc import --format=legacy-veraison -o ear-claims.json result.json
+
+Convert the (already verified) payload of an AWS Nitro Enclaves attestation
+document, in JSON, likewise.
+
+	arc import --format=nitro -o ear-claims.json nitro-doc.json
+
+Supported formats: ` + importFormatList() + `
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				data, claimsSet []byte
+				ar              *ear.AttestationResult
+				err             error
+			)
+
+			if err = checkImportArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			importInput = args[0]
+
+			if data, err = afero.ReadFile(fs, importInput); err != nil {
+				return fmt.Errorf("loading %s result from %q: %w", importFormat, importInput, err)
+			}
+
+			if ar, err = importFormats[importFormat](data, importSubmod); err != nil {
+				return fmt.Errorf("converting %s result from %q: %w", importFormat, importInput, err)
+			}
+
+			if claimsSet, err = ar.MarshalJSONIndent("", "    "); err != nil {
+				return fmt.Errorf("serializing EAR claims-set: %w", err)
+			}
+
+			if err = afero.WriteFile(fs, importOutput, claimsSet, 0644); err != nil {
+				return fmt.Errorf("saving EAR claims-set to file %q: %w", importOutput, err)
+			}
+
+			fmt.Fprintf(diag(cmd), ">> imported %q (%s) into %q\n", importInput, importFormat, importOutput)
+
+			if jsonOutput {
+				return printJSON(cmd, importResult{
+					Input:  importInput,
+					Format: importFormat,
+					Output: importOutput,
+				})
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&importFormat, "format", "f", "", "format of the input file ("+importFormatList()+")",
+	)
+
+	cmd.Flags().StringVarP(
+		&importOutput, "output", "o", "ear-claims.json", "EAR claims-set in JSON",
+	)
+
+	cmd.Flags().StringVarP(
+		&importSubmod, "submod", "s", "legacy", "submod name for formats that carry a single appraisal",
+	)
+
+	return cmd
+}
+
+func checkImportArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("no input file supplied")
+	}
+
+	if importFormat == "" {
+		return errors.New("no input format supplied")
+	}
+
+	if _, ok := importFormats[importFormat]; !ok {
+		return fmt.Errorf("unsupported format %q (supported: %s)", importFormat, importFormatList())
+	}
+
+	return nil
+}
+
+func importFormatList() string {
+	l := make([]string, 0, len(importFormats))
+	for f := range importFormats {
+		l = append(l, f)
+	}
+	sort.Strings(l)
+
+	return strings.Join(l, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+var testLegacyResult = []byte(`{
+    "eat_profile": "tag:github.com,2022:veraison/ear",
+    "iat": 1666091373,
+    "ear.status": "affirming",
+    "ear.appraisal-policy-id": "https://veraison.example/policy/1/60a0068d",
+    "ear.verifier-id": {
+        "build": "rrtrap-v1.0.0",
+        "developer": "Acme Inc."
+    }
+}`)
+
+func Test_ImportCmd_unknown_argument(t *testing.T) {
+	cmd := NewImportCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_ImportCmd_bad_args(t *testing.T) {
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args:     []string{"--format=legacy-veraison"},
+			expected: "validating arguments: no input file supplied",
+		},
+		{
+			args:     []string{"result.json"},
+			expected: "validating arguments: no input format supplied",
+		},
+		{
+			args:     []string{"--format=sev-snp", "result.json"},
+			expected: `validating arguments: unsupported format "sev-snp" (supported: dcap, legacy-veraison, nitro, playintegrity)`,
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewImportCmd()
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_ImportCmd_input_file_not_found(t *testing.T) {
+	cmd := NewImportCmd()
+
+	makeFS(t, []fileEntry{})
+
+	cmd.SetArgs([]string{"--format=legacy-veraison", "result.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `loading legacy-veraison result from "result.json": open result.json: file does not exist`)
+}
+
+func Test_ImportCmd_bad_input(t *testing.T) {
+	cmd := NewImportCmd()
+
+	makeFS(t, []fileEntry{
+		{"result.json", testMiniClaimsSet},
+	})
+
+	cmd.SetArgs([]string{"--format=legacy-veraison", "result.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `converting legacy-veraison result from "result.json": unexpected eat_profile tag:github.com,2023:veraison/ear (expecting "tag:github.com,2022:veraison/ear")`)
+}
+
+func Test_ImportCmd_ok(t *testing.T) {
+	cmd := NewImportCmd()
+
+	makeFS(t, []fileEntry{
+		{"result.json", testLegacyResult},
+	})
+
+	cmd.SetArgs([]string{
+		"--format=legacy-veraison",
+		"--submod=PSA_IOT",
+		"-o", "ear-claims.json",
+		"result.json",
+	})
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	data, err := afero.ReadFile(fs, "ear-claims.json")
+	require.NoError(t, err)
+
+	var ar ear.AttestationResult
+	require.NoError(t, ar.UnmarshalJSON(data))
+
+	assert.Equal(t, ear.EatProfile, ar.Profile.String())
+	require.Contains(t, ar.Submods, "PSA_IOT")
+	assert.Equal(t, ear.TrustTierAffirming, *ar.Submods["PSA_IOT"].Status)
+}
+
+func Test_ImportCmd_foreign_formats_ok(t *testing.T) {
+	tvs := []struct {
+		format   string
+		input    []byte
+		expected ear.TrustTier
+	}{
+		{
+			format: "nitro",
+			input: []byte(`{
+    "module_id": "i-0123456789abcdef0-enc0123456789abcdef",
+    "digest": "SHA384",
+    "timestamp": 1666091373000,
+    "pcrs": {"0": "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8gISIjJCUmJygpKissLS4v"}
+}`),
+			expected: ear.TrustTierWarning,
+		},
+		{
+			format: "dcap",
+			input: []byte(`{
+    "tcbStatus": "SWHardeningNeeded",
+    "advisoryIDs": ["INTEL-SA-00615"],
+    "mrEnclave": "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+    "mrSigner": "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100",
+    "verifiedAt": 1666091373
+}`),
+			expected: ear.TrustTierWarning,
+		},
+		{
+			format: "playintegrity",
+			input: []byte(`{
+    "requestDetails": {
+        "requestPackageName": "com.example.app",
+        "nonce": "AAECAwQFBgcICQoLDA0ODw",
+        "timestampMillis": "1666091373000"
+    },
+    "appIntegrity": {"appRecognitionVerdict": "PLAY_RECOGNIZED"},
+    "deviceIntegrity": {"deviceRecognitionVerdict": ["MEETS_DEVICE_INTEGRITY"]}
+}`),
+			expected: ear.TrustTierAffirming,
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewImportCmd()
+
+		makeFS(t, []fileEntry{
+			{"result.json", tv.input},
+		})
+
+		cmd.SetArgs([]string{"--format=" + tv.format, "--submod=app", "result.json"})
+
+		err := cmd.Execute()
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		data, err := afero.ReadFile(fs, "ear-claims.json")
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		var ar ear.AttestationResult
+		require.NoError(t, ar.UnmarshalJSON(data), "failed test vector at index %d", i)
+
+		require.Contains(t, ar.Submods, "app", "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, *ar.Submods["app"].Status, "failed test vector at index %d", i)
+	}
+}
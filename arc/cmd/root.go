@@ -4,23 +4,28 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/veraison/ear"
+	"github.com/veraison/ear/kms/gcp"
 )
 
 var (
-	cfgFile string
-	fs      = afero.NewOsFs()
+	cfgFile    string
+	jsonOutput bool
+	fs         = afero.NewOsFs()
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:           "arc",
 	Short:         "EAR (EAT Attestation Result) command line utility",
-	Version:       "0.0.1",
+	Version:       version,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 }
@@ -33,6 +38,12 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.arc.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "emit structured JSON to stdout (diagnostics go to stderr)")
+
+	// --skey kms://gcp/projects/.../cryptoKeyVersions/N
+	cobra.CheckErr(ear.RegisterKMSBackend("gcp", &gcp.KMSClient{
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -0,0 +1,174 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+)
+
+const (
+	schemaFormatJSON = "json"
+	schemaFormatCDDL = "cddl"
+)
+
+var (
+	schemaOutput string
+	schemaFormat string
+)
+
+var schemaCmd = NewSchemaCmd()
+
+// schemaViolation is a place where the claims-set does not conform to the
+// schema
+type schemaViolation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// schemaResult is the JSON output of the schema command, when validating a
+// claims-set
+type schemaResult struct {
+	Input      string            `json:"input"`
+	Valid      bool              `json:"valid"`
+	Violations []schemaViolation `json:"violations"`
+}
+
+func NewSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema [flags] [<claims-file>]",
+		Short: "Emit the JSON Schema or the CDDL of the EAR claims-set, or validate a claims-set against the schema",
+		Long: `Emit the JSON Schema or the CDDL of the EAR claims-set, or validate a claims-set against the schema
+
+The schema (JSON Schema draft 2020-12) is generated from the EAR library, and
+covers the supported profiles and claims.  It is a machine-readable contract
+for producers and consumers of EARs that are not written in Go.
+
+Save the schema to "ear.schema.json":
+
+	arc schema --output=ear.schema.json
+
+Check that the claims-set in "ear-claims.json" conforms to the schema:
+
+	arc schema ear-claims.json
+
+Save the CDDL of the CBOR serialization of the claims-set, as carried in CWTs,
+to "ear.cddl":
+
+	arc schema --format=cddl --output=ear.cddl
+
+The schema only checks the structure of the claims-set.  Use the check command
+for the complete set of checks applied when signing and decoding.
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("validating arguments: at most one claims-set file expected")
+			}
+
+			if schemaFormat != schemaFormatJSON && schemaFormat != schemaFormatCDDL {
+				return fmt.Errorf(
+					"unsupported format %q (supported: %s, %s)", schemaFormat, schemaFormatJSON, schemaFormatCDDL,
+				)
+			}
+
+			if schemaFormat == schemaFormatCDDL {
+				if len(args) == 1 {
+					return errors.New("validating arguments: claims-sets can only be checked against the JSON Schema")
+				}
+
+				return saveSchema(cmd, []byte(ear.CDDL()))
+			}
+
+			schema, err := ear.JSONSchema()
+			if err != nil {
+				return fmt.Errorf("generating schema: %w", err)
+			}
+
+			if len(args) == 0 {
+				return saveSchema(cmd, append(schema, '\n'))
+			}
+
+			return validateClaimsSetSchema(cmd, args[0], schema)
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&schemaOutput, "output", "o", stdio, `file to save the schema to ("-" for stdout)`,
+	)
+	cmd.Flags().StringVarP(
+		&schemaFormat, "format", "f", schemaFormatJSON,
+		"schema format ("+schemaFormatJSON+" for the JSON Schema, "+schemaFormatCDDL+" for the CDDL of the CBOR serialization)",
+	)
+
+	return cmd
+}
+
+func saveSchema(cmd *cobra.Command, schema []byte) error {
+	if err := writeFile(cmd, schemaOutput, schema); err != nil {
+		return fmt.Errorf("saving schema to %q: %w", schemaOutput, err)
+	}
+
+	if schemaOutput != stdio {
+		fmt.Fprintf(diag(cmd), ">> schema saved to %q\n", schemaOutput)
+	}
+
+	return nil
+}
+
+func validateClaimsSetSchema(cmd *cobra.Command, input string, schema []byte) error {
+	data, err := readFile(cmd, input)
+	if err != nil {
+		return fmt.Errorf("loading EAR claims-set from %q: %w", input, err)
+	}
+
+	var violations []schemaViolation
+
+	err = ear.ValidateAgainstSchema(schema, data)
+
+	var se *ear.SchemaError
+	if errors.As(err, &se) {
+		for _, v := range se.Violations {
+			violations = append(violations, schemaViolation{Pointer: v.Pointer, Message: v.Message})
+		}
+	} else if err != nil {
+		return fmt.Errorf("validating %q: %w", input, err)
+	}
+
+	if jsonOutput {
+		if violations == nil {
+			violations = []schemaViolation{}
+		}
+
+		if err = printJSON(cmd, schemaResult{
+			Input:      input,
+			Valid:      len(violations) == 0,
+			Violations: violations,
+		}); err != nil {
+			return err
+		}
+	} else {
+		out := cmd.OutOrStdout()
+
+		for _, v := range violations {
+			fmt.Fprintf(out, "%s: #%s: %s\n", input, v.Pointer, v.Message)
+		}
+
+		if len(violations) == 0 {
+			fmt.Fprintf(out, ">> %q conforms to the EAR schema\n", input)
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%d schema violation(s) found in %q", len(violations), input)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
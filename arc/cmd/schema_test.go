@@ -0,0 +1,157 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+func Test_SchemaCmd_emit(t *testing.T) {
+	makeFS(t, []fileEntry{})
+
+	var stdout bytes.Buffer
+
+	cmd := NewSchemaCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{})
+
+	require.NoError(t, cmd.Execute())
+
+	expected, err := ear.JSONSchema()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), stdout.String())
+
+	stdout.Reset()
+
+	cmd = NewSchemaCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--output=ear.schema.json"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, ">> schema saved to \"ear.schema.json\"\n", stdout.String())
+
+	data, err := afero.ReadFile(fs, "ear.schema.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(data))
+}
+
+func Test_SchemaCmd_emit_cddl(t *testing.T) {
+	makeFS(t, []fileEntry{})
+
+	var stdout bytes.Buffer
+
+	cmd := NewSchemaCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--format=cddl", "--output=ear.cddl"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, ">> schema saved to \"ear.cddl\"\n", stdout.String())
+
+	data, err := afero.ReadFile(fs, "ear.cddl")
+	require.NoError(t, err)
+	assert.Equal(t, ear.CDDL(), string(data))
+}
+
+func Test_SchemaCmd_validate_ok(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear-claims.json", testMiniClaimsSet},
+	})
+
+	var stdout bytes.Buffer
+
+	cmd := NewSchemaCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"ear-claims.json"})
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, ">> \"ear-claims.json\" conforms to the EAR schema\n", stdout.String())
+}
+
+func Test_SchemaCmd_validate_violations(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"ear-claims.json", testBrokenClaimsSet},
+	})
+
+	var stdout bytes.Buffer
+
+	cmd := NewSchemaCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"ear-claims.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `2 schema violation(s) found in "ear-claims.json"`)
+	assert.Equal(t,
+		"ear-claims.json: #: missing mandatory \"ear.verifier-id\"\n"+
+			"ear-claims.json: #/iat: expecting integer, got string\n",
+		stdout.String())
+
+	jsonOutput = true
+	defer func() { jsonOutput = false }()
+
+	stdout.Reset()
+
+	cmd = NewSchemaCmd()
+	cmd.SilenceUsage = true
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"ear-claims.json"})
+
+	assert.Error(t, cmd.Execute())
+
+	var res schemaResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &res), stdout.String())
+	assert.False(t, res.Valid)
+	assert.Equal(t, []schemaViolation{
+		{"", `missing mandatory "ear.verifier-id"`},
+		{"/iat", "expecting integer, got string"},
+	}, res.Violations)
+}
+
+func Test_SchemaCmd_fail(t *testing.T) {
+	makeFS(t, []fileEntry{
+		{"bad.json", []byte(`{"iat": `)},
+	})
+
+	tvs := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			[]string{"a.json", "b.json"},
+			"validating arguments: at most one claims-set file expected",
+		},
+		{
+			[]string{"missing.json"},
+			`loading EAR claims-set from "missing.json": open missing.json: file does not exist`,
+		},
+		{
+			[]string{"bad.json"},
+			`validating "bad.json": decoding document: unexpected EOF`,
+		},
+		{
+			[]string{"--format=xsd"},
+			`unsupported format "xsd" (supported: json, cddl)`,
+		},
+		{
+			[]string{"--format=cddl", "bad.json"},
+			"validating arguments: claims-sets can only be checked against the JSON Schema",
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewSchemaCmd()
+		cmd.SilenceUsage = true
+		cmd.SetArgs(tv.args)
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
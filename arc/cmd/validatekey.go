@@ -0,0 +1,277 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+)
+
+const (
+	keyForSigning      = "signing"
+	keyForVerification = "verification"
+
+	minRSAKeyBits = 2048
+)
+
+var (
+	validateKeyInput string
+	validateKeyAlg   string
+	validateKeyFor   string
+)
+
+var validateKeyCmd = NewValidateKeyCmd()
+
+// validateKeyResult is the JSON output of the validate-key command
+type validateKeyResult struct {
+	KeyFile   string `json:"key-file"`
+	Algorithm string `json:"alg"`
+	Usage     string `json:"for"`
+	Valid     bool   `json:"valid"`
+}
+
+func NewValidateKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate-key [flags] <key-file>",
+		Short: "Check that the key in key-file is fit for signing or verifying EARs",
+		Long: `Check that the key in key-file is fit for signing or verifying EARs
+
+The key is checked for well-formedness, compatibility with the requested
+algorithm, cryptographic strength (curve or modulus size) and presence (or
+absence) of private key material.
+
+Check that the key in "skey.json" can be used to sign ES256 EARs:
+
+	arc validate-key --alg=ES256 --for=signing skey.json
+
+Check that the key in "pkey.json" can be used to verify ES256 EARs:
+
+	arc validate-key --alg=ES256 --for=verification pkey.json
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				keyBytes []byte
+				key      jwk.Key
+				err      error
+			)
+
+			if err = checkValidateKeyArgs(args); err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
+			validateKeyInput = args[0]
+
+			if keyBytes, err = afero.ReadFile(fs, validateKeyInput); err != nil {
+				return fmt.Errorf("loading key from %q: %w", validateKeyInput, err)
+			}
+
+			if key, err = ear.LoadKey(keyBytes); err != nil {
+				return fmt.Errorf("parsing key from %q: %w", validateKeyInput, err)
+			}
+
+			if err = validateKey(key, validateKeyAlg, validateKeyFor); err != nil {
+				return fmt.Errorf("key from %q: %w", validateKeyInput, err)
+			}
+
+			fmt.Fprintf(diag(cmd), ">> %q is a valid %s %s key\n",
+				validateKeyInput, validateKeyAlg, validateKeyFor)
+
+			if jsonOutput {
+				return printJSON(cmd, validateKeyResult{
+					KeyFile:   validateKeyInput,
+					Algorithm: validateKeyAlg,
+					Usage:     validateKeyFor,
+					Valid:     true,
+				})
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&validateKeyAlg, "alg", "a", "ES256", "signature algorithm ("+algList()+")",
+	)
+
+	cmd.Flags().StringVarP(
+		&validateKeyFor, "for", "f", keyForSigning,
+		"intended key usage ("+keyForSigning+" or "+keyForVerification+")",
+	)
+
+	return cmd
+}
+
+func checkValidateKeyArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("no key file supplied")
+	}
+
+	switch validateKeyFor {
+	case keyForSigning, keyForVerification:
+	default:
+		return fmt.Errorf(
+			"unknown key usage %q (must be %q or %q)",
+			validateKeyFor, keyForSigning, keyForVerification,
+		)
+	}
+
+	return nil
+}
+
+// validateKey checks that key can be used with the named algorithm for the
+// stated purpose (signing or verification).
+func validateKey(key jwk.Key, algName, usage string) error {
+	isPrivate, err := checkKeyAlg(key, algName)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case usage == keyForSigning && !isPrivate:
+		return errors.New("a private key is required for signing, but a public key was supplied")
+	case usage == keyForVerification && isPrivate && key.KeyType() != jwa.OctetSeq:
+		return errors.New("a public key should be used for verification, but a private key was supplied: " +
+			"distribute only the public part of the key")
+	}
+
+	return nil
+}
+
+// checkKeyAlg checks that key can be used with the named algorithm, whatever
+// the usage, and reports whether it contains private (or secret) material.
+// The verify command uses it so that private keys are still accepted there.
+func checkKeyAlg(key jwk.Key, algName string) (bool, error) {
+	alg, err := parseAlg(algName)
+	if err != nil {
+		return false, err
+	}
+
+	if keyAlg := key.Algorithm().String(); keyAlg != "" && keyAlg != algName {
+		return false, fmt.Errorf("key is restricted to %q (its \"alg\" parameter), but %q was requested", keyAlg, algName)
+	}
+
+	if use := key.KeyUsage(); use != "" && use != string(jwk.ForSignature) {
+		return false, fmt.Errorf("key is intended for %q (its \"use\" parameter), not for signatures", use)
+	}
+
+	var raw interface{}
+	if err := key.Raw(&raw); err != nil {
+		return false, fmt.Errorf("extracting raw key: %w", err)
+	}
+
+	return checkKeyMaterial(raw, alg)
+}
+
+// parseAlg returns the named signature algorithm, provided it can be used with
+// EARs
+func parseAlg(algName string) (jwa.SignatureAlgorithm, error) {
+	var alg jwa.SignatureAlgorithm
+	if err := alg.Accept(algName); err != nil {
+		return "", fmt.Errorf("unknown signature algorithm %q: use one of %s", algName, algList())
+	}
+
+	if alg == jwa.NoSignature {
+		return "", errors.New(`algorithm "none" cannot be used with EARs`)
+	}
+
+	return alg, nil
+}
+
+// checkKeyMaterial checks that the raw key type and strength are compatible
+// with alg, and reports whether it contains private (or secret) material.
+func checkKeyMaterial(raw interface{}, alg jwa.SignatureAlgorithm) (bool, error) {
+	switch k := raw.(type) {
+	case *ecdsa.PrivateKey:
+		return true, checkECDSAKey(&k.PublicKey, alg)
+	case *ecdsa.PublicKey:
+		return false, checkECDSAKey(k, alg)
+	case *rsa.PrivateKey:
+		return true, checkRSAKey(&k.PublicKey, alg)
+	case *rsa.PublicKey:
+		return false, checkRSAKey(k, alg)
+	case ed25519.PrivateKey:
+		return true, checkEdDSAKey(alg)
+	case ed25519.PublicKey:
+		return false, checkEdDSAKey(alg)
+	case []byte:
+		return true, checkHMACKey(k, alg)
+	default:
+		return false, fmt.Errorf("unsupported key type %T for signature algorithm %s", raw, alg)
+	}
+}
+
+func checkECDSAKey(k *ecdsa.PublicKey, alg jwa.SignatureAlgorithm) error {
+	expected := map[jwa.SignatureAlgorithm]elliptic.Curve{
+		jwa.ES256: elliptic.P256(),
+		jwa.ES384: elliptic.P384(),
+		jwa.ES512: elliptic.P521(),
+	}
+
+	curve, ok := expected[alg]
+	if !ok {
+		return fmt.Errorf("an EC key cannot be used with %s: use ES256, ES384 or ES512", alg)
+	}
+
+	if k.Curve != curve {
+		return fmt.Errorf("%s requires curve %s, but the key uses %s",
+			alg, curve.Params().Name, k.Curve.Params().Name)
+	}
+
+	return nil
+}
+
+func checkRSAKey(k *rsa.PublicKey, alg jwa.SignatureAlgorithm) error {
+	switch alg {
+	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512:
+	default:
+		return fmt.Errorf("an RSA key cannot be used with %s: use one of RS256, RS384, RS512, PS256, PS384, PS512", alg)
+	}
+
+	if bits := k.N.BitLen(); bits < minRSAKeyBits {
+		return fmt.Errorf("RSA modulus is %d bits long: at least %d bits are required", bits, minRSAKeyBits)
+	}
+
+	return nil
+}
+
+func checkEdDSAKey(alg jwa.SignatureAlgorithm) error {
+	if alg != jwa.EdDSA {
+		return fmt.Errorf("an Ed25519 key cannot be used with %s: use EdDSA", alg)
+	}
+
+	return nil
+}
+
+func checkHMACKey(k []byte, alg jwa.SignatureAlgorithm) error {
+	minLen := map[jwa.SignatureAlgorithm]int{
+		jwa.HS256: 32,
+		jwa.HS384: 48,
+		jwa.HS512: 64,
+	}
+
+	l, ok := minLen[alg]
+	if !ok {
+		return fmt.Errorf("a symmetric key cannot be used with %s: use HS256, HS384 or HS512", alg)
+	}
+
+	if len(k) < l {
+		return fmt.Errorf("%s requires a secret of at least %d bytes, but the key is %d bytes long", alg, l, len(k))
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(validateKeyCmd)
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testP384SKey = []byte(`{
+    "crv": "P-384",
+    "d": "_bTY2V_wdFKaXCNiWsQ82FuGhwm60uvtX9Qqj6g5cIBleMiP54u5scYyX0luv9ke",
+    "kty": "EC",
+    "x": "KM9yVi9sRcxY2loZOmhbFXUhh-KtRXxJG-pcAQcjyRyOWcnMeB4L7aNBarlzvjhY",
+    "y": "ovQ5hkbm57hI2sgTmNU-fwtzreMRosnVCOdyATKuLdPFbh7sFi0Sc5vxI9jRxYg9"
+}`)
+	testRSA1024PKey = []byte(`{
+    "e": "AQAB",
+    "kty": "RSA",
+    "n": "52dF8KxhJU_fCPyOszOdSwryFhxfTWwcrAzU1vHdrHIsoNHnZzS8ZBIXgSCirCTnYJXt8HMalx5f9TwvSNb9cit2J1unCuIky9AoI-8DxxqSkqCF16QQWzfS42JxzVszcKTTl-7QQ5O06yRbWkAN1Dx_q8fXQ0HMwKuG3riGO8E"
+}`)
+	testShortOctKey = []byte(`{
+    "k": "dG9vLXNob3J0",
+    "kty": "oct"
+}`)
+	testRestrictedSKey = []byte(`{
+    "kty": "EC",
+    "crv": "P-256",
+    "alg": "ES384",
+    "x": "usWxHK2PmfnHKwXPS54m0kTcGJ90UiglWiGahtagnv8",
+    "y": "IBOL-C3BttVivg-lSreASjpkttcsz-1rb7btKLv8EX4",
+    "d": "V8kgd2ZBRuh2dgyVINBUqpPDr7BOMGcF22CQMIUHtNM"
+}`)
+)
+
+func Test_ValidateKeyCmd_unknown_argument(t *testing.T) {
+	cmd := NewValidateKeyCmd()
+
+	args := []string{"--unknown-argument=val"}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "unknown flag: --unknown-argument")
+}
+
+func Test_ValidateKeyCmd_no_key_file(t *testing.T) {
+	cmd := NewValidateKeyCmd()
+	cmd.SetArgs([]string{})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "validating arguments: no key file supplied")
+}
+
+func Test_ValidateKeyCmd_bad_usage(t *testing.T) {
+	cmd := NewValidateKeyCmd()
+	cmd.SetArgs([]string{"--for=encryption", "key.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `validating arguments: unknown key usage "encryption" (must be "signing" or "verification")`)
+}
+
+func Test_ValidateKeyCmd_key_file_not_found(t *testing.T) {
+	cmd := NewValidateKeyCmd()
+
+	makeFS(t, []fileEntry{})
+
+	cmd.SetArgs([]string{"non-existent.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `loading key from "non-existent.json": open non-existent.json: file does not exist`)
+}
+
+func Test_ValidateKeyCmd_key_file_bad_format(t *testing.T) {
+	cmd := NewValidateKeyCmd()
+
+	makeFS(t, []fileEntry{{"key.json", testEmptyKey}})
+
+	cmd.SetArgs([]string{"key.json"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `parsing key from "key.json": no key data`)
+}
+
+func Test_ValidateKeyCmd_fail(t *testing.T) {
+	tvs := []struct {
+		key      []byte
+		args     []string
+		expected string
+	}{
+		{
+			key:      testPKey,
+			args:     []string{"--alg=ES256", "--for=signing"},
+			expected: `key from "key.json": a private key is required for signing, but a public key was supplied`,
+		},
+		{
+			key:      testSKey,
+			args:     []string{"--alg=ES256", "--for=verification"},
+			expected: `key from "key.json": a public key should be used for verification, but a private key was supplied: distribute only the public part of the key`,
+		},
+		{
+			key:      testSKey,
+			args:     []string{"--alg=ES384"},
+			expected: `key from "key.json": ES384 requires curve P-384, but the key uses P-256`,
+		},
+		{
+			key:      testP384SKey,
+			args:     []string{"--alg=RS256"},
+			expected: `key from "key.json": an EC key cannot be used with RS256: use ES256, ES384 or ES512`,
+		},
+		{
+			key:      testRSA1024PKey,
+			args:     []string{"--alg=PS256", "--for=verification"},
+			expected: `key from "key.json": RSA modulus is 1024 bits long: at least 2048 bits are required`,
+		},
+		{
+			key:      testShortOctKey,
+			args:     []string{"--alg=HS256"},
+			expected: `key from "key.json": HS256 requires a secret of at least 32 bytes, but the key is 9 bytes long`,
+		},
+		{
+			key:      testRestrictedSKey,
+			args:     []string{"--alg=ES256"},
+			expected: `key from "key.json": key is restricted to "ES384" (its "alg" parameter), but "ES256" was requested`,
+		},
+		{
+			key:      testSKey,
+			args:     []string{"--alg=XYZ"},
+			expected: `key from "key.json": unknown signature algorithm "XYZ": use one of ` + algList(),
+		},
+		{
+			key:      testSKey,
+			args:     []string{"--alg=none"},
+			expected: `key from "key.json": algorithm "none" cannot be used with EARs`,
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewValidateKeyCmd()
+
+		makeFS(t, []fileEntry{{"key.json", tv.key}})
+
+		cmd.SetArgs(append(tv.args, "key.json"))
+
+		err := cmd.Execute()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func Test_ValidateKeyCmd_ok(t *testing.T) {
+	tvs := []struct {
+		key  []byte
+		args []string
+	}{
+		{
+			key:  testSKey,
+			args: []string{"--alg=ES256", "--for=signing"},
+		},
+		{
+			key:  testPKey,
+			args: []string{"--alg=ES256", "--for=verification"},
+		},
+		{
+			key:  testP384SKey,
+			args: []string{"--alg=ES384"},
+		},
+	}
+
+	for i, tv := range tvs {
+		cmd := NewValidateKeyCmd()
+
+		makeFS(t, []fileEntry{{"key.json", tv.key}})
+
+		cmd.SetArgs(append(tv.args, "key.json"))
+
+		err := cmd.Execute()
+		assert.NoError(t, err, "failed test vector at index %d", i)
+	}
+}
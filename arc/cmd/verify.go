@@ -19,10 +19,25 @@ var (
 	verifyPKey    string
 	verifyColor   bool
 	verifyVerbose bool
+	verifyRedact  string
+
+	verifyExpectedNonce   string
+	verifyExpectedProfile string
+	verifyMinTier         string
+	verifyRequiredSubmods []string
 )
 
 var verifyCmd = NewVerifyCmd()
 
+// verifyResult is the JSON output of the verify command
+type verifyResult struct {
+	Input           string                 `json:"input"`
+	VerificationKey string                 `json:"verification-key"`
+	Algorithm       string                 `json:"alg"`
+	Verified        bool                   `json:"verified"`
+	ClaimsSet       *ear.AttestationResult `json:"claims-set"`
+}
+
 func NewVerifyCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "verify [flags] <jwt-file>",
@@ -34,6 +49,19 @@ file "pkey.json".  If cryptographic verification is successful, print the
 embedded EAR claims-set and present a report of the trustworthiness vector.
 
 	arc verify my-ear.jwt
+
+Same, but only display the claims that can be shared with partners (see the
+--redaction-profile flag):
+
+	arc verify --redaction-profile=partner my-ear.jwt
+
+Also check that the EAR meets the expectations of a relying party: the
+command fails (with a non-zero exit code) if the challenge nonce is missing,
+if the overall status is below "affirming", or if there is no appraisal for
+the "cpu" submod:
+
+	arc verify --expected-nonce=TUlEQk5IMjhpaW9pc2pQeXh4eHh4eHh4eHh4eHh4eHg= \
+		--min-tier=affirming --require-submod=cpu my-ear.jwt
 	`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
@@ -47,49 +75,89 @@ embedded EAR claims-set and present a report of the trustworthiness vector.
 				return fmt.Errorf("validating arguments: %w", err)
 			}
 
+			expectations, err := verifyExpectations()
+			if err != nil {
+				return fmt.Errorf("validating arguments: %w", err)
+			}
+
 			verifyInput = args[0]
 
 			if arBytes, err = afero.ReadFile(fs, verifyInput); err != nil {
 				return fmt.Errorf("loading signed EAR from %q: %w", verifyInput, err)
 			}
 
+			if _, err = parseAlg(verifyAlg); err != nil {
+				return err
+			}
+
 			// read the verification key from verifyPKey
 			if pKey, err = afero.ReadFile(fs, verifyPKey); err != nil {
 				return fmt.Errorf("loading verification key from %q: %w", verifyPKey, err)
 			}
 
-			if vfyK, err = jwk.ParseKey(pKey); err != nil {
+			if vfyK, err = ear.LoadKey(pKey); err != nil {
 				return fmt.Errorf("parsing verification key from %q: %w", verifyPKey, err)
 			}
 
+			if _, err = checkKeyAlg(vfyK, verifyAlg); err != nil {
+				return fmt.Errorf("verification key from %q: %w", verifyPKey, err)
+			}
+
 			if err = ar.Verify(arBytes, jwa.KeyAlgorithmFrom(verifyAlg), vfyK); err != nil {
 				return fmt.Errorf("verifying signed EAR from %s: %w", verifyInput, err)
 			}
 
-			fmt.Printf(">> %q signature successfully verified using %q\n", verifyInput, verifyPKey)
+			fmt.Fprintf(diag(cmd), ">> %q signature successfully verified using %q\n", verifyInput, verifyPKey)
+
+			// expectations are checked on the complete claims-set, before
+			// redaction, but only reported once the output has been
+			// printed, so that the offending claims can be inspected
+			expectErr := ar.Expect(expectations...)
+
+			if verifyRedact != "" {
+				if err = redact(&ar, verifyRedact); err != nil {
+					return err
+				}
+			}
+
+			if jsonOutput {
+				if err = printJSON(cmd, verifyResult{
+					Input:           verifyInput,
+					VerificationKey: verifyPKey,
+					Algorithm:       verifyAlg,
+					Verified:        true,
+					ClaimsSet:       &ar,
+				}); err != nil {
+					return err
+				}
+
+				return expectErr
+			}
+
+			out := cmd.OutOrStdout()
 
-			fmt.Println("[claims-set]")
+			fmt.Fprintln(out, "[claims-set]")
 			if claimsSet, err = ar.MarshalJSONIndent("", "    "); err != nil {
 				return fmt.Errorf("unable to re-serialize the EAR claims-set: %w", err)
 			}
-			fmt.Println(string(claimsSet))
+			fmt.Fprintln(out, string(claimsSet))
 
-			fmt.Println("[trustworthiness vectors]")
+			fmt.Fprintln(out, "[trustworthiness vectors]")
 			for submodName, appraisal := range ar.Submods {
-				fmt.Printf("submod(%s):\n", submodName)
+				fmt.Fprintf(out, "submod(%s):\n", submodName)
 				if appraisal.TrustVector != nil {
-					fmt.Println(appraisal.TrustVector.Report(!verifyVerbose, verifyColor))
+					fmt.Fprintln(out, appraisal.TrustVector.Report(!verifyVerbose, verifyColor))
 				} else {
-					fmt.Println("not present")
+					fmt.Fprintln(out, "not present")
 				}
 			}
 
-			return nil
+			return expectErr
 		},
 	}
 
 	cmd.Flags().StringVarP(
-		&verifyPKey, "pkey", "p", "pkey.json", "verification key in JWK format",
+		&verifyPKey, "pkey", "p", "pkey.json", "verification key (JWK, PEM or DER)",
 	)
 
 	cmd.Flags().StringVarP(
@@ -100,13 +168,64 @@ embedded EAR claims-set and present a report of the trustworthiness vector.
 		&verifyVerbose, "verbose", "v", false, "verbose trustworthiness vector report (default is brief)",
 	)
 
+	cmd.Flags().StringVarP(
+		&verifyRedact, "redaction-profile", "r", "",
+		"redact the claims-set using the named profile (internal, partner, public, or any defined in the config file)",
+	)
+
 	cmd.Flags().BoolVarP(
 		&verifyColor, "color", "c", false, "render trustworthiness vector tiers with colors (default is b&w)",
 	)
 
+	cmd.Flags().StringVar(
+		&verifyExpectedNonce, "expected-nonce", "", "fail unless eat_nonce contains the supplied nonce",
+	)
+
+	cmd.Flags().StringVar(
+		&verifyExpectedProfile, "expected-profile", "", "fail unless eat_profile is the supplied profile",
+	)
+
+	cmd.Flags().StringVar(
+		&verifyMinTier, "min-tier", "",
+		"fail unless the overall status is at least the supplied tier (none, affirming, warning, contraindicated)",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&verifyRequiredSubmods, "require-submod", nil,
+		"fail unless the EAR has an appraisal for the named submod (can be repeated)",
+	)
+
 	return cmd
 }
 
+// verifyExpectations translates the --expected-* flags into the corresponding
+// expectations
+func verifyExpectations() ([]ear.Hook, error) {
+	var expectations []ear.Hook
+
+	if verifyExpectedNonce != "" {
+		expectations = append(expectations, ear.ExpectNonce(verifyExpectedNonce))
+	}
+
+	if verifyExpectedProfile != "" {
+		expectations = append(expectations, ear.ExpectProfile(verifyExpectedProfile))
+	}
+
+	if verifyMinTier != "" {
+		tier, err := ear.ToTrustTier(verifyMinTier)
+		if err != nil {
+			return nil, fmt.Errorf("--min-tier: %w", err)
+		}
+		expectations = append(expectations, ear.ExpectMinTier(*tier))
+	}
+
+	for _, submod := range verifyRequiredSubmods {
+		expectations = append(expectations, ear.ExpectSubmod(submod))
+	}
+
+	return expectations, nil
+}
+
 func checkVerifyArgs(args []string) error {
 	if len(args) != 1 {
 		return errors.New("no input file supplied")
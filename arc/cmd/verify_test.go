@@ -3,9 +3,14 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
 )
 
 func Test_VerifyCmd_unknown_argument(t *testing.T) {
@@ -64,7 +69,7 @@ func Test_VerifyCmd_pkey_file_bad_format(t *testing.T) {
 	}
 	cmd.SetArgs(args)
 
-	expectedErr := `parsing verification key from "empty-pkey.json": failed to unmarshal JSON into key hint: EOF`
+	expectedErr := `parsing verification key from "empty-pkey.json": no key data`
 
 	err := cmd.Execute()
 	assert.EqualError(t, err, expectedErr)
@@ -151,7 +156,29 @@ func Test_VerifyCmd_unknown_verification_alg(t *testing.T) {
 	}
 	cmd.SetArgs(args)
 
-	expectedErr := `verifying signed EAR from ear.jwt: failed verifying JWT message: WithKey() option must be specified using jwa.SignatureAlgorithm (got jwa.InvalidKeyAlgorithm)`
+	expectedErr := `unknown signature algorithm "XYZ": use one of `
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, expectedErr)
+}
+
+func Test_VerifyCmd_key_alg_mismatch(t *testing.T) {
+	cmd := NewVerifyCmd()
+
+	files := []fileEntry{
+		{"pkey.pem", testEd25519PKeyPEM},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	args := []string{
+		"--pkey=pkey.pem",
+		"--alg=ES256",
+		"ear.jwt",
+	}
+	cmd.SetArgs(args)
+
+	expectedErr := `verification key from "pkey.pem": an Ed25519 key cannot be used with ES256: use EdDSA`
 
 	err := cmd.Execute()
 	assert.EqualError(t, err, expectedErr)
@@ -176,3 +203,196 @@ func Test_VerifyCmd_ok(t *testing.T) {
 	err := cmd.Execute()
 	assert.NoError(t, err)
 }
+
+func Test_VerifyCmd_pem_pkey_ok(t *testing.T) {
+	cmd := NewVerifyCmd()
+
+	files := []fileEntry{
+		{"pkey.pem", testPKeyPEM},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	args := []string{
+		"--pkey=pkey.pem",
+		"ear.jwt",
+	}
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_VerifyCmd_unsupported_profile(t *testing.T) {
+	cmd := NewVerifyCmd()
+
+	files := []fileEntry{
+		{"pkey.json", testPKey},
+		{"ear.jwt", testJWTUnsupportedProfile},
+	}
+	makeFS(t, files)
+
+	args := []string{
+		"--pkey=pkey.json",
+		"--alg=ES256",
+		"ear.jwt",
+	}
+	cmd.SetArgs(args)
+
+	expectedErr := `verifying signed EAR from ear.jwt: unsupported eat_profile "tag:example.com,2024:other-profile" (accepted: "tag:github.com,2023:veraison/ear")`
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, expectedErr)
+}
+
+func Test_VerifyCmd_json_output(t *testing.T) {
+	files := []fileEntry{
+		{"pkey.json", testPKey},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	var stdout, stderr bytes.Buffer
+
+	rootCmd.SetOut(&stdout)
+	rootCmd.SetErr(&stderr)
+	defer func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		jsonOutput = false
+	}()
+
+	rootCmd.SetArgs([]string{
+		"--json",
+		"verify",
+		"--pkey=pkey.json",
+		"--alg=ES256",
+		"ear.jwt",
+	})
+
+	err := rootCmd.Execute()
+	require.NoError(t, err)
+
+	var res map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &res)
+	require.NoError(t, err, stdout.String())
+
+	assert.Equal(t, "ear.jwt", res["input"])
+	assert.Equal(t, true, res["verified"])
+	assert.Contains(t, res, "claims-set")
+	assert.Contains(t, stderr.String(), "signature successfully verified")
+}
+
+func Test_VerifyCmd_redaction_profile(t *testing.T) {
+	files := []fileEntry{
+		{"pkey.json", testPKey},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	var stdout bytes.Buffer
+
+	cmd := NewVerifyCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--redaction-profile=public", "ear.jwt"})
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	assert.NotContains(t, stdout.String(), "ear.raw-evidence")
+	assert.NotContains(t, stdout.String(), "ear.appraisal-policy-id")
+	assert.Contains(t, stdout.String(), `"ear.status": "affirming"`)
+}
+
+func Test_VerifyCmd_redaction_profile_from_config(t *testing.T) {
+	files := []fileEntry{
+		{"pkey.json", testPKey},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	viper.Set(redactionProfilesKey, map[string]interface{}{
+		"auditor": []interface{}{"/ear.raw-evidence"},
+	})
+	defer viper.Set(redactionProfilesKey, nil)
+
+	var stdout bytes.Buffer
+
+	cmd := NewVerifyCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{"--redaction-profile=auditor", "ear.jwt"})
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	assert.NotContains(t, stdout.String(), "ear.raw-evidence")
+	assert.Contains(t, stdout.String(), "ear.appraisal-policy-id")
+
+	cmd = NewVerifyCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--redaction-profile=nobody", "ear.jwt"})
+
+	err = cmd.Execute()
+	assert.EqualError(t, err, `unknown redaction profile "nobody" (known: auditor, internal, partner, public)`)
+}
+
+func Test_VerifyCmd_expectations_ok(t *testing.T) {
+	files := []fileEntry{
+		{"pkey.json", testPKey},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	cmd := NewVerifyCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{
+		"--expected-profile=tag:github.com,2023:veraison/ear",
+		"--min-tier=affirming",
+		"--require-submod=test",
+		"ear.jwt",
+	})
+
+	err := cmd.Execute()
+	assert.NoError(t, err)
+}
+
+func Test_VerifyCmd_expectations_not_met(t *testing.T) {
+	files := []fileEntry{
+		{"pkey.json", testPKey},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	var stdout bytes.Buffer
+
+	cmd := NewVerifyCmd()
+	cmd.SetOut(&stdout)
+	cmd.SetArgs([]string{
+		"--expected-nonce=MTIzNDU2Nzg5MDEyMzQ1Ng",
+		"--min-tier=affirming",
+		"--require-submod=test",
+		"--require-submod=cpu",
+		"ear.jwt",
+	})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, `expectation not met: eat_nonce does not contain "MTIzNDU2Nzg5MDEyMzQ1Ng"; submod "cpu" not found`)
+	assert.ErrorIs(t, err, ear.ErrExpectationNotMet)
+
+	// the claims-set is still displayed
+	assert.Contains(t, stdout.String(), "[claims-set]")
+}
+
+func Test_VerifyCmd_bad_min_tier(t *testing.T) {
+	files := []fileEntry{
+		{"pkey.json", testPKey},
+		{"ear.jwt", testJWT},
+	}
+	makeFS(t, files)
+
+	cmd := NewVerifyCmd()
+	cmd.SetArgs([]string{"--min-tier=great", "ear.jwt"})
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "validating arguments: --min-tier: ")
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/spf13/cobra"
+	"github.com/veraison/ear"
+	cose "github.com/veraison/go-cose"
+)
+
+const earModule = "github.com/veraison/ear"
+
+// version is the arc release.  Release builds override it with:
+//
+//	-ldflags "-X github.com/veraison/ear/arc/cmd.version=<version>"
+var version = "0.0.1"
+
+// cwtAlgorithms are the COSE algorithms supported by go-cose
+var cwtAlgorithms = []cose.Algorithm{
+	cose.AlgorithmPS256,
+	cose.AlgorithmPS384,
+	cose.AlgorithmPS512,
+	cose.AlgorithmES256,
+	cose.AlgorithmES384,
+	cose.AlgorithmES512,
+	cose.AlgorithmEd25519,
+}
+
+const versionLineFmt = "%-18s%s\n"
+
+var versionCmd = NewVersionCmd()
+
+// versionResult is the JSON output of the version command
+type versionResult struct {
+	Version        string              `json:"version"`
+	LibraryVersion string              `json:"library-version"`
+	GoVersion      string              `json:"go-version"`
+	Profiles       []string            `json:"profiles"`
+	Serializations []string            `json:"serializations"`
+	Algorithms     map[string][]string `json:"algorithms"`
+}
+
+func NewVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Report version and capabilities of arc and the EAR library",
+		Long: `Report version and capabilities of arc and the EAR library
+
+Print the arc and library versions, together with the supported EAT profiles,
+serializations and signature algorithms, e.g., to compare the capabilities of
+deployed components:
+
+	arc version
+	`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return errors.New("validating arguments: no arguments expected")
+			}
+
+			res := newVersionResult()
+
+			if jsonOutput {
+				return printJSON(cmd, res)
+			}
+
+			out := cmd.OutOrStdout()
+
+			fmt.Fprintf(out, versionLineFmt, "arc version:", res.Version)
+			fmt.Fprintf(out, versionLineFmt, "library version:", res.LibraryVersion)
+			fmt.Fprintf(out, versionLineFmt, "go version:", res.GoVersion)
+			fmt.Fprintf(out, versionLineFmt, "profiles:", strings.Join(res.Profiles, ", "))
+			fmt.Fprintf(out, versionLineFmt, "serializations:", strings.Join(res.Serializations, ", "))
+			for _, s := range res.Serializations {
+				fmt.Fprintf(out, versionLineFmt, s+" algorithms:", strings.Join(res.Algorithms[s], ", "))
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newVersionResult() versionResult {
+	jwtAlgs := make([]string, 0, len(jwa.SignatureAlgorithms()))
+	for _, a := range jwa.SignatureAlgorithms() {
+		if a != jwa.NoSignature {
+			jwtAlgs = append(jwtAlgs, a.String())
+		}
+	}
+
+	cwtAlgs := make([]string, 0, len(cwtAlgorithms))
+	for _, a := range cwtAlgorithms {
+		cwtAlgs = append(cwtAlgs, a.String())
+	}
+
+	return versionResult{
+		Version:        version,
+		LibraryVersion: libraryVersion(),
+		GoVersion:      runtime.Version(),
+		Profiles:       ear.SupportedProfiles(),
+		Serializations: []string{"JWT", "CWT"},
+		Algorithms: map[string][]string{
+			"JWT": jwtAlgs,
+			"CWT": cwtAlgs,
+		},
+	}
+}
+
+// libraryVersion returns the version of the EAR library recorded in the build
+// info.  arc lives in the same module as the library, so this is the main
+// module version, unless arc is built as a dependency of some other module.
+func libraryVersion() string {
+	libVersion := "unknown"
+
+	bi, ok := debug.ReadBuildInfo()
+	if ok {
+		if bi.Main.Path == earModule {
+			libVersion = bi.Main.Version
+		}
+
+		for _, dep := range bi.Deps {
+			if dep.Path == earModule {
+				libVersion = dep.Version
+			}
+		}
+	}
+
+	return libVersion
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+}
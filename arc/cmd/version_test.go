@@ -0,0 +1,60 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+func Test_VersionCmd_bad_args(t *testing.T) {
+	cmd := NewVersionCmd()
+	cmd.SetArgs([]string{"extra"})
+
+	err := cmd.Execute()
+	assert.EqualError(t, err, "validating arguments: no arguments expected")
+}
+
+func Test_VersionCmd_ok(t *testing.T) {
+	cmd := NewVersionCmd()
+	cmd.SetArgs([]string{})
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), "arc version:      "+version+"\n")
+	assert.Contains(t, out.String(), "profiles:         "+ear.EatProfile+"\n")
+	assert.Contains(t, out.String(), "serializations:   JWT, CWT\n")
+}
+
+func Test_VersionCmd_json_output(t *testing.T) {
+	var out bytes.Buffer
+
+	rootCmd.SetArgs([]string{"--json", "version"})
+	rootCmd.SetOut(&out)
+	defer func() {
+		rootCmd.SetOut(nil)
+		jsonOutput = false
+	}()
+
+	err := rootCmd.Execute()
+	require.NoError(t, err)
+
+	var res versionResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &res))
+
+	assert.Equal(t, version, res.Version)
+	assert.Equal(t, []string{ear.EatProfile}, res.Profiles)
+	assert.Contains(t, res.Algorithms["JWT"], "ES256")
+	assert.Contains(t, res.Algorithms["CWT"], "ES256")
+	assert.NotContains(t, res.Algorithms["JWT"], "none")
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Audit events
+const (
+	AuditEventIssued   = "issued"
+	AuditEventVerified = "verified"
+)
+
+// AuditSummary is the digest of an AttestationResult that is recorded in the
+// audit log
+type AuditSummary struct {
+	Profile    string            `json:"eat_profile,omitempty"`
+	IssuedAt   int64             `json:"iat,omitempty"`
+	VerifierID *VerifierIdentity `json:"ear.verifier-id,omitempty"`
+	// Status maps submod names onto the names of their ear.status tier
+	Status map[string]string `json:"status"`
+}
+
+// AuditRecord is an entry in the audit log.  Each record carries the hash of
+// the one preceding it, so that removing, reordering or altering records
+// breaks the chain.
+type AuditRecord struct {
+	Seq      uint64       `json:"seq"`
+	Time     time.Time    `json:"time"`
+	Event    string       `json:"event"`
+	Summary  AuditSummary `json:"summary"`
+	PrevHash string       `json:"prev-hash"`
+	// Hash is the hex-encoded SHA-256 of the JSON serialization of the
+	// record with an empty Hash
+	Hash string `json:"hash"`
+}
+
+func (o AuditRecord) computeHash() (string, error) {
+	o.Hash = ""
+
+	b, err := json.Marshal(o)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.Sum256(b)
+
+	return hex.EncodeToString(h[:]), nil
+}
+
+// AuditLog appends hash-chained AuditRecords to an io.Writer (typically a file
+// opened in append mode), one JSON object per line.  It is safe for concurrent
+// use.
+type AuditLog struct {
+	mu   sync.Mutex
+	w    io.Writer
+	seq  uint64
+	prev string
+	now  func() time.Time
+}
+
+// NewAuditLog returns an AuditLog writing to w.  When appending to an existing
+// log, last must be the final record in it (as returned by VerifyAuditChain),
+// so that the chain continues from there; otherwise, it must be nil.
+func NewAuditLog(w io.Writer, last *AuditRecord) *AuditLog {
+	l := &AuditLog{w: w, now: time.Now}
+
+	if last != nil {
+		l.seq = last.Seq + 1
+		l.prev = last.Hash
+	}
+
+	return l
+}
+
+// Append records the supplied event for the AttestationResult and returns the
+// record that has been written
+func (o *AuditLog) Append(event string, ar *AttestationResult) (AuditRecord, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	rec := AuditRecord{
+		Seq:      o.seq,
+		Time:     o.now().UTC(),
+		Event:    event,
+		Summary:  summarize(ar),
+		PrevHash: o.prev,
+	}
+
+	hash, err := rec.computeHash()
+	if err != nil {
+		return AuditRecord{}, fmt.Errorf("hashing audit record: %w", err)
+	}
+	rec.Hash = hash
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return AuditRecord{}, fmt.Errorf("serializing audit record: %w", err)
+	}
+
+	if _, err := o.w.Write(append(b, '\n')); err != nil {
+		return AuditRecord{}, fmt.Errorf("writing audit record: %w", err)
+	}
+
+	o.seq++
+	o.prev = hash
+
+	return rec, nil
+}
+
+// Hook returns a Hook that appends the supplied event to the log.  It can be
+// passed to WithAfterSign (with AuditEventIssued), so that only the results
+// for which a token has actually been produced are logged, or to
+// WithAfterVerify (with AuditEventVerified).
+func (o *AuditLog) Hook(event string) Hook {
+	return func(ar *AttestationResult) error {
+		_, err := o.Append(event, ar)
+		return err
+	}
+}
+
+func summarize(ar *AttestationResult) AuditSummary {
+	s := AuditSummary{
+		VerifierID: ar.VerifierID,
+		Status:     make(map[string]string, len(ar.Submods)),
+	}
+
+	if ar.Profile != nil {
+		s.Profile = ar.Profile.String()
+	}
+
+	if ar.IssuedAt != nil {
+		s.IssuedAt = *ar.IssuedAt
+	}
+
+	for name, appraisal := range ar.Submods {
+		if appraisal != nil && appraisal.Status != nil {
+			s.Status[name] = appraisal.Status.String()
+		}
+	}
+
+	return s
+}
+
+// VerifyAuditChain reads an audit log from r and checks that records are in
+// sequence, that each is correctly linked to its predecessor, and that none
+// has been altered.  On success, the last record is returned (nil if the log
+// is empty), which can be used to resume appending with NewAuditLog.
+func VerifyAuditChain(r io.Reader) (*AuditRecord, error) {
+	var (
+		last *AuditRecord
+		prev string
+		seq  uint64
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var rec AuditRecord
+
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if rec.Seq != seq {
+			return nil, fmt.Errorf("line %d: expecting seq %d, found %d", line, seq, rec.Seq)
+		}
+
+		if rec.PrevHash != prev {
+			return nil, fmt.Errorf("line %d: broken chain (prev-hash mismatch)", line)
+		}
+
+		hash, err := rec.computeHash()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if hash != rec.Hash {
+			return nil, fmt.Errorf("line %d: hash mismatch", line)
+		}
+
+		prev = rec.Hash
+		seq++
+		last = &rec
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading audit log: %w", err)
+	}
+
+	return last, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAuditLog(t *testing.T, n int) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	l := NewAuditLog(&buf, nil)
+	l.now = func() time.Time { return time.Unix(testIAT, 0) }
+
+	ar := testAttestationResultsWithVeraisonExtns
+
+	for i := 0; i < n; i++ {
+		_, err := l.Append(AuditEventIssued, &ar)
+		require.NoError(t, err)
+	}
+
+	return &buf
+}
+
+func TestAuditLog_chain(t *testing.T) {
+	buf := testAuditLog(t, 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+
+	last, err := VerifyAuditChain(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	require.NotNil(t, last)
+
+	assert.Equal(t, uint64(2), last.Seq)
+	assert.Equal(t, AuditEventIssued, last.Event)
+	assert.Equal(t, map[string]string{"test": "affirming"}, last.Summary.Status)
+	assert.Equal(t, testIAT, last.Summary.IssuedAt)
+
+	// resume appending from where the log left off
+	l := NewAuditLog(buf, last)
+	rec, err := l.Append(AuditEventVerified, &testAttestationResultsWithVeraisonExtns)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), rec.Seq)
+	assert.Equal(t, last.Hash, rec.PrevHash)
+
+	_, err = VerifyAuditChain(strings.NewReader(buf.String()))
+	assert.NoError(t, err)
+}
+
+func TestVerifyAuditChain_empty(t *testing.T) {
+	last, err := VerifyAuditChain(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Nil(t, last)
+}
+
+func TestVerifyAuditChain_tampering(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(testAuditLog(t, 3).String()), "\n")
+
+	tvs := []struct {
+		lines    []string
+		expected string
+	}{
+		{
+			// record removed
+			lines:    []string{lines[0], lines[2]},
+			expected: "line 2: expecting seq 1, found 2",
+		},
+		{
+			// records reordered
+			lines:    []string{lines[1], lines[0], lines[2]},
+			expected: "line 1: expecting seq 0, found 1",
+		},
+		{
+			// record altered
+			lines: []string{
+				lines[0],
+				strings.Replace(lines[1], `"affirming"`, `"warning"`, 1),
+				lines[2],
+			},
+			expected: "line 2: hash mismatch",
+		},
+		{
+			// record re-chained after truncation
+			lines:    []string{lines[0], strings.Replace(lines[1], `"prev-hash":"`, `"prev-hash":"00`, 1)},
+			expected: "line 2: broken chain (prev-hash mismatch)",
+		},
+		{
+			lines:    []string{"not JSON"},
+			expected: "line 1: invalid character 'o' in literal null (expecting 'u')",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := VerifyAuditChain(strings.NewReader(strings.Join(tv.lines, "\n")))
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestAuditLog_Hook(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	var buf bytes.Buffer
+
+	l := NewAuditLog(&buf, nil)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK,
+		WithAfterSign(l.Hook(AuditEventIssued)))
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.Verify(token, jwa.ES256, vfyK, WithAfterVerify(l.Hook(AuditEventVerified)))
+	require.NoError(t, err)
+
+	last, err := VerifyAuditChain(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), last.Seq)
+	assert.Equal(t, AuditEventVerified, last.Event)
+}
+
+func TestAuditLog_Hook_sign_failure_not_logged(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	var buf bytes.Buffer
+
+	l := NewAuditLog(&buf, nil)
+
+	// the pointer matches no claim, so no token is produced
+	_, err := testAttestationResultsWithVeraisonExtns.SignSD(jwa.ES256, sigK,
+		[]string{"/submods/nope"}, WithAfterSign(l.Hook(AuditEventIssued)))
+	require.EqualError(t, err, `no claim matches "/submods/nope"`)
+
+	assert.Zero(t, buf.Len())
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"fmt"
+	"time"
+)
+
+// AttestationResultBuilder assembles an AttestationResult without the need to
+// deal with pointer fields directly.  The profile defaults to EatProfile and
+// the issuance time to the moment NewAttestationResultBuilder is called.
+// Errors (e.g., a duplicate submod) are recorded as they happen and reported
+// by Build, so calls can be chained freely:
+//
+//	ar, err := NewAttestationResultBuilder().
+//		WithVerifier("rrtrap-v1.0.0", "Acme Inc.").
+//		AddSubmod("PSA_IOT", NewAppraisal(TrustTierAffirming)).
+//		Build()
+type AttestationResultBuilder struct {
+	ar  AttestationResult
+	err error
+}
+
+// NewAttestationResultBuilder returns a builder for a new AttestationResult
+func NewAttestationResultBuilder() *AttestationResultBuilder {
+	profile := Profile(EatProfile)
+	iat := time.Now().Unix()
+
+	return &AttestationResultBuilder{
+		ar: AttestationResult{
+			Profile:  &profile,
+			IssuedAt: &iat,
+			Submods:  map[string]*Appraisal{},
+		},
+	}
+}
+
+// WithProfile sets the eat_profile claim
+func (o *AttestationResultBuilder) WithProfile(profile string) *AttestationResultBuilder {
+	p := Profile(profile)
+	o.ar.Profile = &p
+	return o
+}
+
+// WithVerifier sets the ear.verifier-id claim
+func (o *AttestationResultBuilder) WithVerifier(build, developer string) *AttestationResultBuilder {
+	o.ar.VerifierID = &VerifierIdentity{
+		Build:     &build,
+		Developer: &developer,
+	}
+	return o
+}
+
+// WithIssuedAt sets the iat claim
+func (o *AttestationResultBuilder) WithIssuedAt(t time.Time) *AttestationResultBuilder {
+	iat := t.Unix()
+	o.ar.IssuedAt = &iat
+	return o
+}
+
+// WithExpiry sets the exp claim
+func (o *AttestationResultBuilder) WithExpiry(t time.Time) *AttestationResultBuilder {
+	exp := t.Unix()
+	o.ar.Expiry = &exp
+	return o
+}
+
+// WithNotBefore sets the nbf claim
+func (o *AttestationResultBuilder) WithNotBefore(t time.Time) *AttestationResultBuilder {
+	nbf := t.Unix()
+	o.ar.NotBefore = &nbf
+	return o
+}
+
+// WithNonce sets the eat_nonce claim to the supplied base64url-encoded
+// nonce(s)
+func (o *AttestationResultBuilder) WithNonce(nonces ...string) *AttestationResultBuilder {
+	n := Nonces(nonces)
+	o.ar.Nonce = &n
+	return o
+}
+
+// WithRawEvidence sets the ear.raw-evidence claim
+func (o *AttestationResultBuilder) WithRawEvidence(evidence []byte) *AttestationResultBuilder {
+	rawEvidence := B64Url(evidence)
+	o.ar.RawEvidence = &rawEvidence
+	return o
+}
+
+// AddSubmod adds the supplied Appraisal under the given submod name.  Adding
+// the same submod twice is an error.
+func (o *AttestationResultBuilder) AddSubmod(name string, appraisal *Appraisal) *AttestationResultBuilder {
+	if o.err != nil {
+		return o
+	}
+
+	if appraisal == nil {
+		o.err = fmt.Errorf("submod %q: nil appraisal", name)
+		return o
+	}
+
+	if _, ok := o.ar.Submods[name]; ok {
+		o.err = fmt.Errorf("submod %q: already present", name)
+		return o
+	}
+
+	o.ar.Submods[name] = appraisal
+	return o
+}
+
+// Build returns the assembled AttestationResult after validating it.  The
+// first error encountered while building, if any, is returned instead.
+func (o *AttestationResultBuilder) Build() (*AttestationResult, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	if err := o.ar.validate(); err != nil {
+		return nil, err
+	}
+
+	// copy the submods, so that the builder can be reused without affecting
+	// the results it has already produced
+	ar := o.ar
+	ar.Submods = make(map[string]*Appraisal, len(o.ar.Submods))
+	for name, appraisal := range o.ar.Submods {
+		ar.Submods[name] = appraisal
+	}
+
+	return &ar, nil
+}
+
+// NewAppraisal returns a pointer to a new Appraisal with the supplied status
+// and an empty trust vector.
+func NewAppraisal(status TrustTier) *Appraisal {
+	return &Appraisal{
+		Status:      &status,
+		TrustVector: &TrustVector{},
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestationResultBuilder_ok(t *testing.T) {
+	appraisal := NewAppraisal(TrustTierAffirming)
+
+	ar, err := NewAttestationResultBuilder().
+		WithVerifier(testVidBuild, testVidDeveloper).
+		WithIssuedAt(time.Unix(testIAT, 0)).
+		WithNonce(testNonce).
+		WithRawEvidence(testEvidence).
+		AddSubmod("test", appraisal).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, EatProfile, ar.Profile.String())
+	assert.Equal(t, testIAT, *ar.IssuedAt)
+	assert.Equal(t, testVerifierID, *ar.VerifierID)
+	assert.Equal(t, Nonces{testNonce}, *ar.Nonce)
+	assert.Equal(t, B64Url(testEvidence), *ar.RawEvidence)
+	assert.Equal(t, appraisal, ar.Submods["test"])
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
+}
+
+func TestAttestationResultBuilder_fail(t *testing.T) {
+	tvs := []struct {
+		builder  *AttestationResultBuilder
+		expected string
+	}{
+		{
+			builder:  NewAttestationResultBuilder(),
+			expected: "missing mandatory 'verifier-id', 'submods' (at least one appraisal must be present)",
+		},
+		{
+			builder: NewAttestationResultBuilder().
+				WithVerifier(testVidBuild, testVidDeveloper).
+				AddSubmod("test", NewAppraisal(TrustTierAffirming)).
+				AddSubmod("test", NewAppraisal(TrustTierWarning)),
+			expected: `submod "test": already present`,
+		},
+		{
+			builder: NewAttestationResultBuilder().
+				WithVerifier(testVidBuild, testVidDeveloper).
+				AddSubmod("test", nil),
+			expected: `submod "test": nil appraisal`,
+		},
+		{
+			builder: NewAttestationResultBuilder().
+				WithProfile(testUnsupportedProfile.String()).
+				WithVerifier(testVidBuild, testVidDeveloper).
+				AddSubmod("test", NewAppraisal(TrustTierAffirming)),
+			expected: "invalid value(s) for eat_profile (1.2.3.4.5)",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := tv.builder.Build()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
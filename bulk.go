@@ -0,0 +1,188 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// BulkSigningKey is one of the keys a BulkSigner can sign with
+type BulkSigningKey struct {
+	Alg jwa.KeyAlgorithm
+	Key interface{}
+	// Interval is the minimum time between two consecutive signatures with
+	// this key, e.g., to stay within the API quota of a KMS.  Zero means no
+	// limit.
+	Interval time.Duration
+	// Options are passed to Sign for every signature made with this key
+	Options []SignOption
+}
+
+// BulkSignRequest is a claims-set submitted to a BulkSigner
+type BulkSignRequest struct {
+	// ID is an opaque correlation identifier, copied to the response
+	ID string
+	// KeyName selects the signing key
+	KeyName string
+	Result  *AttestationResult
+}
+
+// BulkSignResponse carries the outcome of a BulkSignRequest: either the
+// signed token, or the error that prevented signing.
+type BulkSignResponse struct {
+	ID    string
+	Token []byte
+	Err   error
+}
+
+// BulkSigner signs claims-sets received on a channel using a bounded pool of
+// workers.  Responses are delivered on an unbuffered channel, so a consumer
+// that cannot keep up stalls the workers, which in turn stop draining the
+// request channel.  Responses are not ordered; use the correlation ID to match
+// them to their requests.
+type BulkSigner struct {
+	workers int
+	keys    map[string]*bulkKey
+}
+
+type bulkKey struct {
+	BulkSigningKey
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewBulkSigner returns a BulkSigner that runs the given number of workers
+// and signs with the supplied keys, indexed by name.
+func NewBulkSigner(workers int, keys map[string]BulkSigningKey) (*BulkSigner, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("invalid number of workers: %d", workers)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+
+	o := &BulkSigner{
+		workers: workers,
+		keys:    make(map[string]*bulkKey, len(keys)),
+	}
+
+	for name, k := range keys {
+		if k.Interval < 0 {
+			return nil, fmt.Errorf("key %q: negative interval", name)
+		}
+		o.keys[name] = &bulkKey{BulkSigningKey: k}
+	}
+
+	return o, nil
+}
+
+// Run starts the workers and returns the channel on which responses are
+// delivered.  The response channel is closed once the request channel has
+// been closed and drained, or when ctx is done, in which case pending
+// requests are abandoned.
+func (o *BulkSigner) Run(ctx context.Context, in <-chan BulkSignRequest) <-chan BulkSignResponse {
+	out := make(chan BulkSignResponse)
+
+	var wg sync.WaitGroup
+
+	wg.Add(o.workers)
+
+	for i := 0; i < o.workers; i++ {
+		go func() {
+			defer wg.Done()
+			o.work(ctx, in, out)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+func (o *BulkSigner) work(ctx context.Context, in <-chan BulkSignRequest, out chan<- BulkSignResponse) {
+	for {
+		var (
+			req BulkSignRequest
+			ok  bool
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case req, ok = <-in:
+			if !ok {
+				return
+			}
+		}
+
+		res := BulkSignResponse{ID: req.ID}
+		res.Token, res.Err = o.sign(ctx, req)
+
+		select {
+		case <-ctx.Done():
+			return
+		case out <- res:
+		}
+	}
+}
+
+func (o *BulkSigner) sign(ctx context.Context, req BulkSignRequest) ([]byte, error) {
+	k, ok := o.keys[req.KeyName]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", req.KeyName)
+	}
+
+	if req.Result == nil {
+		return nil, errors.New("nil attestation result")
+	}
+
+	if err := k.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	return req.Result.Sign(k.Alg, k.Key, k.Options...)
+}
+
+// wait blocks until the key can be used again, having reserved the slot for
+// the caller
+func (o *bulkKey) wait(ctx context.Context) error {
+	if o.Interval == 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	now := time.Now()
+	slot := o.next
+	if slot.Before(now) {
+		slot = now
+	}
+	o.next = slot.Add(o.Interval)
+	o.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkSigner_Run(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	bs, err := NewBulkSigner(3, map[string]BulkSigningKey{
+		"k1": {Alg: jwa.ES256, Key: sigK},
+	})
+	require.NoError(t, err)
+
+	in := make(chan BulkSignRequest)
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			in <- BulkSignRequest{
+				ID:      fmt.Sprintf("req-%d", i),
+				KeyName: "k1",
+				Result:  &testAttestationResultsWithVeraisonExtns,
+			}
+		}
+		in <- BulkSignRequest{ID: "bad-key", KeyName: "k2"}
+		close(in)
+	}()
+
+	seen := map[string]BulkSignResponse{}
+
+	for res := range bs.Run(context.Background(), in) {
+		seen[res.ID] = res
+	}
+
+	require.Len(t, seen, 11)
+
+	for i := 0; i < 10; i++ {
+		res := seen[fmt.Sprintf("req-%d", i)]
+		require.NoError(t, res.Err)
+
+		var actual AttestationResult
+		require.NoError(t, actual.Verify(res.Token, jwa.ES256, vfyK))
+	}
+
+	assert.EqualError(t, seen["bad-key"].Err, `unknown signing key "k2"`)
+}
+
+func TestBulkSigner_rate_limit(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	interval := 20 * time.Millisecond
+
+	bs, err := NewBulkSigner(4, map[string]BulkSigningKey{
+		"k1": {Alg: jwa.ES256, Key: sigK, Interval: interval},
+	})
+	require.NoError(t, err)
+
+	in := make(chan BulkSignRequest, 4)
+	for i := 0; i < 4; i++ {
+		in <- BulkSignRequest{KeyName: "k1", Result: &testAttestationResultsWithVeraisonExtns}
+	}
+	close(in)
+
+	start := time.Now()
+
+	for res := range bs.Run(context.Background(), in) {
+		require.NoError(t, res.Err)
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 3*interval)
+}
+
+func TestBulkSigner_cancel(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	bs, err := NewBulkSigner(2, map[string]BulkSigningKey{
+		"k1": {Alg: jwa.ES256, Key: sigK, Interval: time.Hour},
+	})
+	require.NoError(t, err)
+
+	in := make(chan BulkSignRequest)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	out := bs.Run(ctx, in)
+
+	cancel()
+
+	for range out {
+	}
+}
+
+func TestNewBulkSigner_fail(t *testing.T) {
+	tvs := []struct {
+		workers  int
+		keys     map[string]BulkSigningKey
+		expected string
+	}{
+		{
+			workers:  0,
+			expected: "invalid number of workers: 0",
+		},
+		{
+			workers:  1,
+			expected: "no signing keys",
+		},
+		{
+			workers: 1,
+			keys: map[string]BulkSigningKey{
+				"k1": {Interval: -time.Second},
+			},
+			expected: `key "k1": negative interval`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := NewBulkSigner(tv.workers, tv.keys)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// CachedSigner signs AttestationResults as JWTs with a fixed algorithm, key
+// and set of options.  Unlike AttestationResult.Sign, which converts the key
+// and builds the JWS protected header on each call, a CachedSigner does that
+// work once, in NewCachedSigner, which makes it suitable for verifiers that
+// emit EARs at a high rate.  The tokens it produces are equivalent to those
+// produced by Sign with the same arguments.
+//
+// A CachedSigner is safe for concurrent use, provided that any hooks supplied
+// via WithBeforeSign, and the TimestampAuthority supplied via
+// WithTimestampAuthority, are.
+type CachedSigner struct {
+	opts   *signOptions
+	signer jws.Signer
+	key    interface{}
+	header string
+}
+
+var _ Signer = (*CachedSigner)(nil)
+
+// NewCachedSigner returns a CachedSigner using the supplied algorithm, key
+// and options.  The key can be a jwk.Key or a raw crypto key, and is checked
+// against the algorithm upfront.
+func NewCachedSigner(alg jwa.KeyAlgorithm, key interface{}, opts ...SignOption) (*CachedSigner, error) {
+	sigAlg, ok := alg.(jwa.SignatureAlgorithm)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a signature algorithm", alg)
+	}
+
+	if sigAlg == jwa.NoSignature {
+		return nil, errors.New(`algorithm "none" cannot be used to sign EARs`)
+	}
+
+	if key == nil {
+		return nil, errors.New("no signing key supplied")
+	}
+
+	so := newSignOptions(opts)
+
+	hdrs, err := so.jwsHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := key
+
+	if k, ok := key.(jwk.Key); ok {
+		// like jws.Sign, let the kid of the key take precedence
+		if kid := k.KeyID(); kid != "" {
+			if err := hdrs.Set(jws.KeyIDKey, kid); err != nil {
+				return nil, fmt.Errorf("setting kid: %w", err)
+			}
+		}
+
+		if err := k.Raw(&raw); err != nil {
+			return nil, fmt.Errorf("extracting raw key: %w", err)
+		}
+	}
+
+	if err := hdrs.Set(jws.AlgorithmKey, sigAlg); err != nil {
+		return nil, fmt.Errorf("setting alg: %w", err)
+	}
+
+	if err := hdrs.Set(jws.TypeKey, "JWT"); err != nil {
+		return nil, fmt.Errorf("setting typ: %w", err)
+	}
+
+	signer, err := jws.NewSigner(sigAlg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkKeyAlgorithm(sigAlg, raw); err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	if err := checkSigningKey(signer, raw); err != nil {
+		return nil, fmt.Errorf("checking key against %s: %w", sigAlg, err)
+	}
+
+	h, err := json.Marshal(hdrs)
+	if err != nil {
+		return nil, fmt.Errorf("encoding JWS protected header: %w", err)
+	}
+
+	return &CachedSigner{
+		opts:   so,
+		signer: signer,
+		key:    raw,
+		header: base64.RawURLEncoding.EncodeToString(h),
+	}, nil
+}
+
+// Sign validates the AttestationResult, runs the before-sign hooks and signs
+// it, then runs the after-sign hooks, as AttestationResult.Sign does.  On
+// success, the complete JWT is returned.
+func (o *CachedSigner) Sign(ar AttestationResult) ([]byte, error) {
+	if err := ar.prepareForSigning(o.opts); err != nil {
+		return nil, err
+	}
+
+	if o.opts.tsa != nil {
+		if err := ar.addTimestamp(o.opts.tsa); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		payload []byte
+		err     error
+	)
+
+	if o.opts.canonical {
+		if payload, err = ar.MarshalCanonicalJSON(); err != nil {
+			return nil, fmt.Errorf("encoding canonical JSON claims-set: %w", err)
+		}
+	} else if payload, err = json.Marshal(ar.AsMap()); err != nil {
+		return nil, fmt.Errorf("encoding JSON claims-set: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+
+	buf := make([]byte, 0, len(o.header)+1+enc.EncodedLen(len(payload))+1+128)
+	buf = append(buf, o.header...)
+	buf = append(buf, '.')
+	buf = append(buf, enc.EncodeToString(payload)...)
+
+	sig, err := o.signer.Sign(buf, o.key)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+
+	buf = append(buf, '.')
+	buf = append(buf, enc.EncodeToString(sig)...)
+
+	if err := o.opts.signed(&ar); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// SignMany signs each of the supplied AttestationResults in turn, returning
+// the tokens in the same order.  It stops at the first AttestationResult that
+// cannot be signed.
+func (o *CachedSigner) SignMany(ars []*AttestationResult) ([][]byte, error) {
+	tokens := make([][]byte, len(ars))
+
+	for i, ar := range ars {
+		if ar == nil {
+			return nil, fmt.Errorf("attestation result at index %d: nil", i)
+		}
+
+		token, err := o.Sign(*ar)
+		if err != nil {
+			return nil, fmt.Errorf("attestation result at index %d: %w", i, err)
+		}
+
+		tokens[i] = token
+	}
+
+	return tokens, nil
+}
+
+// checkSigningKey makes a trial signature to weed out keys that do not fit
+// the algorithm.  Asymmetric keys are also checked by verifying the trial
+// signature, since any crypto.Signer is accepted by the jws signers, whatever
+// its type.
+func checkSigningKey(signer jws.Signer, key interface{}) error {
+	sig, err := signer.Sign(nil, key)
+	if err != nil {
+		return err
+	}
+
+	cs, ok := key.(crypto.Signer)
+	if !ok {
+		return nil
+	}
+
+	verifier, err := jws.NewVerifier(signer.Algorithm())
+	if err != nil {
+		return err
+	}
+
+	return verifier.Verify(nil, sig, cs.Public())
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedSigner_Sign(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	s, err := NewCachedSigner(jwa.ES256, sigK, WithKeyID("k1"), WithJWKSURL("https://veraison.example/jwks"))
+	require.NoError(t, err)
+
+	token, err := s.Sign(testAttestationResultsWithVeraisonExtns)
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	require.NoError(t, actual.Verify(token, jwa.ES256, vfyK))
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns.AsMap(), actual.AsMap())
+
+	msg, err := jws.Parse(token)
+	require.NoError(t, err)
+
+	hdrs := msg.Signatures()[0].ProtectedHeaders()
+	assert.Equal(t, jwa.ES256, hdrs.Algorithm())
+	assert.Equal(t, "JWT", hdrs.Type())
+	assert.Equal(t, "k1", hdrs.KeyID())
+	assert.Equal(t, "https://veraison.example/jwks", hdrs.JWKSetURL())
+}
+
+func TestCachedSigner_same_as_Sign(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+	require.NoError(t, sigK.Set(jwk.KeyIDKey, "from-key"))
+
+	opts := []SignOption{WithKeyID("from-option"), WithCanonicalJSON()}
+
+	s, err := NewCachedSigner(jwa.ES256, sigK, opts...)
+	require.NoError(t, err)
+
+	cached, err := s.Sign(testAttestationResultsWithVeraisonExtns)
+	require.NoError(t, err)
+
+	plain, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, opts...)
+	require.NoError(t, err)
+
+	// ECDSA signatures are randomized, so only compare the signing inputs
+	signingInput := func(token []byte) []byte {
+		msg, err := jws.Parse(token)
+		require.NoError(t, err)
+		_, err = jws.Verify(token, jws.WithKey(jwa.ES256, vfyK))
+		require.NoError(t, err)
+		h, err := msg.Signatures()[0].ProtectedHeaders().MarshalJSON()
+		require.NoError(t, err)
+		return append(h, msg.Payload()...)
+	}
+
+	assert.Equal(t, signingInput(plain), signingInput(cached))
+}
+
+func TestCachedSigner_raw_key(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	var raw ecdsa.PrivateKey
+	require.NoError(t, sigK.Raw(&raw))
+
+	s, err := NewCachedSigner(jwa.ES256, &raw)
+	require.NoError(t, err)
+
+	token, err := s.Sign(testAttestationResultsWithVeraisonExtns)
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	assert.NoError(t, actual.Verify(token, jwa.ES256, vfyK))
+}
+
+func TestCachedSigner_hooks(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	s, err := NewCachedSigner(jwa.ES256, sigK, WithBeforeSign(func(ar *AttestationResult) error {
+		if ar.Nonce == nil {
+			return errors.New("no nonce")
+		}
+		return nil
+	}))
+	require.NoError(t, err)
+
+	_, err = s.Sign(testAttestationResultsWithVeraisonExtns)
+	assert.EqualError(t, err, "before-sign hook: no nonce")
+
+	_, err = s.Sign(AttestationResult{})
+	assert.ErrorIs(t, err, ErrMissingClaim)
+}
+
+func TestCachedSigner_SignMany(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	s, err := NewCachedSigner(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	ars := make([]*AttestationResult, 20)
+	for i := range ars {
+		ar := testAttestationResultsWithVeraisonExtns
+		iat := testIAT + int64(i)
+		ar.IssuedAt = &iat
+		ars[i] = &ar
+	}
+
+	tokens, err := s.SignMany(ars)
+	require.NoError(t, err)
+	require.Len(t, tokens, len(ars))
+
+	for i, token := range tokens {
+		var actual AttestationResult
+		require.NoError(t, actual.Verify(token, jwa.ES256, vfyK))
+		assert.Equal(t, testIAT+int64(i), *actual.IssuedAt)
+	}
+
+	_, err = s.SignMany([]*AttestationResult{ars[0], {}})
+	assert.ErrorContains(t, err, "attestation result at index 1: ")
+
+	_, err = s.SignMany([]*AttestationResult{nil})
+	assert.EqualError(t, err, "attestation result at index 0: nil")
+}
+
+func TestCachedSigner_concurrent(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	s, err := NewCachedSigner(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	tokens := make([][]byte, 16)
+	errs := make([]error, len(tokens))
+
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = s.Sign(testAttestationResultsWithVeraisonExtns)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := range tokens {
+		require.NoError(t, errs[i])
+
+		var actual AttestationResult
+		assert.NoError(t, actual.Verify(tokens[i], jwa.ES256, vfyK))
+	}
+}
+
+func TestNewCachedSigner_fail(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	tvs := []struct {
+		alg      jwa.KeyAlgorithm
+		key      interface{}
+		expected string
+	}{
+		{
+			alg:      jwa.RSA_OAEP,
+			key:      sigK,
+			expected: `"RSA-OAEP" is not a signature algorithm`,
+		},
+		{
+			alg:      jwa.NoSignature,
+			key:      sigK,
+			expected: `algorithm "none" cannot be used to sign EARs`,
+		},
+		{
+			alg:      jwa.ES256,
+			expected: "no signing key supplied",
+		},
+		{
+			alg:      jwa.RS256,
+			key:      sigK,
+			expected: "signing key: RS256 requires an RSA key, but an EC key was supplied",
+		},
+		{
+			alg:      jwa.ES256,
+			key:      vfyK,
+			expected: "checking key against ES256: ",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := NewCachedSigner(tv.alg, tv.key)
+		assert.ErrorContains(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
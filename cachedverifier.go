@@ -0,0 +1,175 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// VerifierConfig is the configuration of a CachedVerifier
+type VerifierConfig struct {
+	// Keys are the verification keys.  The key is selected using the kid in
+	// the JWS header, which may also be the RFC7638 thumbprint of the key.
+	// Tokens without a kid are only accepted if there is exactly one key.
+	Keys jwk.Set
+	// Algorithms are the accepted signature algorithms.  Tokens signed with
+	// any other algorithm are rejected before the key is even looked up.
+	Algorithms []jwa.SignatureAlgorithm
+	// Skew is the tolerance allowed when checking time claims (see
+	// WithAcceptableSkew)
+	Skew time.Duration
+	// Profiles, if not empty, are the accepted eat_profile values.  They must
+	// be supported by the package (see SupportedProfiles).
+	Profiles []string
+	// Options are passed to Verify for every token
+	Options []VerifyOption
+}
+
+// CachedVerifier verifies JWT-wrapped EARs against a fixed configuration.
+// The keys are converted, and their thumbprints computed, once in
+// NewCachedVerifier, so that verifying a token only involves looking up the
+// key and checking the signature.  Since the algorithm comes from an
+// allow-list that is checked against both the JWS header and the key, the
+// verifier cannot be tricked into using a key with an unintended algorithm.
+//
+// A CachedVerifier is safe for concurrent use, provided that the supplied
+// options are (e.g., the hooks supplied via WithAfterVerify).
+type CachedVerifier struct {
+	algs    []string
+	byKid   map[string]*cachedKey
+	only    *cachedKey
+	opts    []VerifyOption
+	maxSize int
+}
+
+type cachedKey struct {
+	alg string
+	raw interface{}
+}
+
+var _ Verifier = (*CachedVerifier)(nil)
+
+// NewCachedVerifier returns a CachedVerifier for the supplied configuration
+func NewCachedVerifier(cfg VerifierConfig) (*CachedVerifier, error) {
+	if cfg.Keys == nil || cfg.Keys.Len() == 0 {
+		return nil, errors.New("no verification keys")
+	}
+
+	if len(cfg.Algorithms) == 0 {
+		return nil, errors.New("no accepted algorithms")
+	}
+
+	if cfg.Skew < 0 {
+		return nil, fmt.Errorf("negative clock skew %s", cfg.Skew)
+	}
+
+	o := &CachedVerifier{
+		algs:  make([]string, 0, len(cfg.Algorithms)),
+		byKid: map[string]*cachedKey{},
+	}
+
+	for _, alg := range cfg.Algorithms {
+		if alg == jwa.NoSignature {
+			return nil, errors.New(`algorithm "none" cannot be accepted`)
+		}
+		o.algs = append(o.algs, alg.String())
+	}
+
+	supported := SupportedProfiles()
+
+	for _, p := range cfg.Profiles {
+		if !contains(supported, p) {
+			return nil, ProfileError{Received: p, Accepted: supported}
+		}
+	}
+
+	for i := 0; i < cfg.Keys.Len(); i++ {
+		key, _ := cfg.Keys.Key(i)
+
+		ck := &cachedKey{alg: key.Algorithm().String()}
+		if err := key.Raw(&ck.raw); err != nil {
+			return nil, fmt.Errorf("key at index %d: extracting raw key: %w", i, err)
+		}
+
+		tp, err := keyThumbprint(key)
+		if err != nil {
+			return nil, fmt.Errorf("key at index %d: %w", i, err)
+		}
+
+		// explicit kids take precedence over thumbprints
+		if _, ok := o.byKid[tp]; !ok {
+			o.byKid[tp] = ck
+		}
+
+		if kid := key.KeyID(); kid != "" {
+			o.byKid[kid] = ck
+		}
+
+		if cfg.Keys.Len() == 1 {
+			o.only = ck
+		}
+	}
+
+	opts := append([]VerifyOption{WithAcceptableSkew(cfg.Skew)}, cfg.Options...)
+
+	if len(cfg.Profiles) > 0 {
+		profiles := append([]string(nil), cfg.Profiles...)
+		opts = append(opts, func(vo *verifyOptions) {
+			vo.profiles = profiles
+		})
+	}
+
+	o.opts = opts
+	o.maxSize = newVerifyOptions(opts).maxSize
+
+	return o, nil
+}
+
+// Verify checks that the token is signed with one of the accepted algorithms,
+// selects the verification key, and verifies the token as
+// AttestationResult.Verify does.  On success, the decoded AttestationResult is
+// returned.
+func (o *CachedVerifier) Verify(data []byte) (*AttestationResult, error) {
+	// the size limit is checked before the header is parsed
+	if err := (verifyOptions{maxSize: o.maxSize}).checkTokenSize(data); err != nil {
+		return nil, err
+	}
+
+	hdrs, err := protectedHeaders(data)
+	if err != nil {
+		return nil, err
+	}
+
+	alg := hdrs.Algorithm()
+	if !contains(o.algs, alg.String()) {
+		return nil, AlgorithmError{Received: alg.String(), Accepted: o.algs}
+	}
+
+	var key *cachedKey
+
+	if kid := hdrs.KeyID(); kid != "" {
+		if key = o.byKid[kid]; key == nil {
+			return nil, fmt.Errorf("no key found for kid %q", kid)
+		}
+	} else if key = o.only; key == nil {
+		return nil, errors.New("no kid in JWS header")
+	}
+
+	if key.alg != "" && key.alg != alg.String() {
+		return nil, fmt.Errorf("JWS alg %q does not match key alg %q", alg, key.alg)
+	}
+
+	var ar AttestationResult
+
+	if err := ar.Verify(data, alg, key.raw, o.opts...); err != nil {
+		return nil, err
+	}
+
+	return &ar, nil
+}
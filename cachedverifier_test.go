@@ -0,0 +1,220 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testVerifierKeySet(t *testing.T, keys ...jwk.Key) jwk.Set {
+	set := jwk.NewSet()
+	for _, k := range keys {
+		require.NoError(t, set.AddKey(k))
+	}
+	return set
+}
+
+func TestCachedVerifier_Verify(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	kid, err := keyThumbprint(vfyK)
+	require.NoError(t, err)
+
+	other, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+	require.NoError(t, other.Set(jwk.KeyIDKey, "other"))
+
+	v, err := NewCachedVerifier(VerifierConfig{
+		Keys:       testVerifierKeySet(t, other, vfyK),
+		Algorithms: []jwa.SignatureAlgorithm{jwa.ES256},
+		Profiles:   []string{EatProfile},
+	})
+	require.NoError(t, err)
+
+	for _, k := range []string{kid, "other"} {
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID(k))
+		require.NoError(t, err)
+
+		ar, err := v.Verify(token)
+		require.NoError(t, err, k)
+		assert.Equal(t, testAttestationResultsWithVeraisonExtns.AsMap(), ar.AsMap(), k)
+	}
+}
+
+func TestCachedVerifier_single_key_no_kid(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	v, err := NewCachedVerifier(VerifierConfig{
+		Keys:       testVerifierKeySet(t, vfyK),
+		Algorithms: []jwa.SignatureAlgorithm{jwa.ES256},
+	})
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	_, err = v.Verify(token)
+	assert.NoError(t, err)
+}
+
+func TestCachedVerifier_Verify_fail(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+	require.NoError(t, vfyK.Set(jwk.KeyIDKey, "k1"))
+
+	restricted, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+	require.NoError(t, restricted.Set(jwk.KeyIDKey, "k2"))
+	require.NoError(t, restricted.Set(jwk.AlgorithmKey, jwa.ES384))
+
+	v, err := NewCachedVerifier(VerifierConfig{
+		Keys:       testVerifierKeySet(t, vfyK, restricted),
+		Algorithms: []jwa.SignatureAlgorithm{jwa.ES256},
+		Options:    []VerifyOption{WithMaxTokenSize(2048)},
+	})
+	require.NoError(t, err)
+
+	sign := func(alg jwa.SignatureAlgorithm, key interface{}, opts ...SignOption) []byte {
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(alg, key, opts...)
+		require.NoError(t, err)
+		return token
+	}
+
+	hmacKey, err := jwk.FromRaw([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	tvs := []struct {
+		token    []byte
+		expected string
+	}{
+		{
+			// alg confusion: HMAC using the (public) key material
+			token:    sign(jwa.HS256, hmacKey, WithKeyID("k1")),
+			expected: `JWS alg "HS256" is not accepted (accepted: "ES256")`,
+		},
+		{
+			token:    sign(jwa.ES256, sigK, WithKeyID("k3")),
+			expected: `no key found for kid "k3"`,
+		},
+		{
+			token:    sign(jwa.ES256, sigK),
+			expected: "no kid in JWS header",
+		},
+		{
+			token:    sign(jwa.ES256, sigK, WithKeyID("k2")),
+			expected: `JWS alg "ES256" does not match key alg "ES384"`,
+		},
+		{
+			token:    []byte("not a token"),
+			expected: "parsing JWS message: ",
+		},
+		{
+			token:    make([]byte, 4096),
+			expected: ErrTokenTooLarge.Error(),
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := v.Verify(tv.token)
+		assert.ErrorContains(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestCachedVerifier_profiles(t *testing.T) {
+	const other = "tag:example.com,2024:cached-verifier"
+
+	require.NoError(t, RegisterProfile(other))
+	defer delete(defaultRegistry.profiles, other)
+
+	sigK, vfyK := testKeyPair(t)
+
+	v, err := NewCachedVerifier(VerifierConfig{
+		Keys:       testVerifierKeySet(t, vfyK),
+		Algorithms: []jwa.SignatureAlgorithm{jwa.ES256},
+		Profiles:   []string{other},
+	})
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	_, err = v.Verify(token)
+
+	var pe ProfileError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, EatProfile, pe.Received)
+	assert.Equal(t, []string{other}, pe.Accepted)
+}
+
+func TestCachedVerifier_skew(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	exp := testIAT + 60
+	ar.Expiry = &exp
+
+	token, err := ar.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	clock := WithClock(func() time.Time { return time.Unix(testIAT+90, 0) })
+
+	for _, tv := range []struct {
+		skew time.Duration
+		ok   bool
+	}{{0, false}, {time.Minute, true}} {
+		v, err := NewCachedVerifier(VerifierConfig{
+			Keys:       testVerifierKeySet(t, vfyK),
+			Algorithms: []jwa.SignatureAlgorithm{jwa.ES256},
+			Skew:       tv.skew,
+			Options:    []VerifyOption{clock},
+		})
+		require.NoError(t, err)
+
+		_, err = v.Verify(token)
+		assert.Equal(t, tv.ok, err == nil, "skew %s: %v", tv.skew, err)
+	}
+}
+
+func TestNewCachedVerifier_fail(t *testing.T) {
+	_, vfyK := testKeyPair(t)
+	keys := testVerifierKeySet(t, vfyK)
+	es256 := []jwa.SignatureAlgorithm{jwa.ES256}
+
+	tvs := []struct {
+		cfg      VerifierConfig
+		expected string
+	}{
+		{
+			cfg:      VerifierConfig{Algorithms: es256},
+			expected: "no verification keys",
+		},
+		{
+			cfg:      VerifierConfig{Keys: keys},
+			expected: "no accepted algorithms",
+		},
+		{
+			cfg:      VerifierConfig{Keys: keys, Algorithms: []jwa.SignatureAlgorithm{jwa.NoSignature}},
+			expected: `algorithm "none" cannot be accepted`,
+		},
+		{
+			cfg:      VerifierConfig{Keys: keys, Algorithms: es256, Skew: -time.Second},
+			expected: "negative clock skew -1s",
+		},
+		{
+			cfg:      VerifierConfig{Keys: keys, Algorithms: es256, Profiles: []string{"tag:example.com,2024:nope"}},
+			expected: `unsupported eat_profile "tag:example.com,2024:nope"`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := NewCachedVerifier(tv.cfg)
+		assert.ErrorContains(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
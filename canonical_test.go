@@ -0,0 +1,65 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeJSON(t *testing.T) {
+	tvs := []struct {
+		data     string
+		expected string
+	}{
+		{
+			data:     `{ "b": 1, "a": [true, null, "x"] }`,
+			expected: `{"a":[true,null,"x"],"b":1}`,
+		},
+		{
+			data:     `[1.0, 1e3, 1E-7, 100000000000000000000000, -0.50]`,
+			expected: `[1,1000,1e-7,1e+23,-0.5]`,
+		},
+		{
+			data:     `"é\u0001\/<>&\n"`,
+			expected: "\"é\\u0001/<>&\\n\"",
+		},
+		{
+			// U+FB33 sorts after U+1F600 (surrogate D83D) in UTF-16 order
+			data:     `{"דּ": 1, "😀": 2, "a": 3}`,
+			expected: "{\"a\":3,\"\U0001F600\":2,\"דּ\":1}",
+		},
+	}
+
+	for i, tv := range tvs {
+		actual, err := canonicalizeJSON([]byte(tv.data))
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, string(actual), "failed test vector at index %d", i)
+	}
+}
+
+func TestSign_canonical_JSON(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	expected, err := testAttestationResultsWithVeraisonExtns.MarshalCanonicalJSON()
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithCanonicalJSON())
+	require.NoError(t, err)
+
+	msg, err := jws.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, expected, msg.Payload())
+	assert.Equal(t, "JWT", msg.Signatures()[0].ProtectedHeaders().Type())
+
+	var actual AttestationResult
+
+	err = actual.Verify(token, jwa.ES256, vfyK)
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual)
+}
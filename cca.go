@@ -0,0 +1,218 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxREMs is the number of Realm Extensible Measurements of an Arm CCA realm
+const maxREMs = 4
+
+// CCALifecycle is the lifecycle state of an Arm CCA platform, as reported in
+// the platform token.  The most significant byte is the state, the least
+// significant one is an implementation defined sub-state.
+type CCALifecycle uint16
+
+// Arm CCA platform lifecycle states
+const (
+	CCALifecycleUnknown                     CCALifecycle = 0x0000
+	CCALifecycleAssemblyAndTest             CCALifecycle = 0x1000
+	CCALifecyclePlatformRoTProvisioning     CCALifecycle = 0x2000
+	CCALifecycleSecured                     CCALifecycle = 0x3000
+	CCALifecycleNonCCAPlatformDebug         CCALifecycle = 0x4000
+	CCALifecycleRecoverableCCAPlatformDebug CCALifecycle = 0x5000
+	CCALifecycleDecommissioned              CCALifecycle = 0x6000
+)
+
+var ccaLifecycleNames = map[CCALifecycle]string{
+	CCALifecycleUnknown:                     "unknown",
+	CCALifecycleAssemblyAndTest:             "assembly_and_test",
+	CCALifecyclePlatformRoTProvisioning:     "cca_platform_rot_provisioning",
+	CCALifecycleSecured:                     "secured",
+	CCALifecycleNonCCAPlatformDebug:         "non_cca_platform_debug",
+	CCALifecycleRecoverableCCAPlatformDebug: "recoverable_cca_platform_debug",
+	CCALifecycleDecommissioned:              "decommissioned",
+}
+
+// State returns the lifecycle state, without the sub-state
+func (o CCALifecycle) State() CCALifecycle {
+	return o & 0xff00
+}
+
+// IsValid returns true if the value encodes a known lifecycle state
+func (o CCALifecycle) IsValid() bool {
+	_, ok := ccaLifecycleNames[o.State()]
+	return ok
+}
+
+func (o CCALifecycle) String() string {
+	name, ok := ccaLifecycleNames[o.State()]
+	if !ok {
+		return fmt.Sprintf("CCALifecycle(0x%04x)", uint16(o))
+	}
+
+	return name
+}
+
+// VeraisonCCAInfo is the "ear.veraison.cca-info" claim, in which a verifier
+// appraising Arm CCA evidence records the realm measurements it has seen, the
+// reference values they have been matched against, and the lifecycle state of
+// the platform.  All the members are optional.
+type VeraisonCCAInfo struct {
+	// RealmInitialMeasurement is the RIM of the realm
+	RealmInitialMeasurement *B64Url `json:"rim,omitempty"`
+	// RealmExtensibleMeasurements are the REMs of the realm, in index order
+	RealmExtensibleMeasurements []B64Url `json:"rem,omitempty"`
+	// RIMReference identifies the reference value the RIM has been matched
+	// against
+	RIMReference *string `json:"rim-ref,omitempty"`
+	// REMReferences identify the reference values the REMs have been matched
+	// against, in the same order as RealmExtensibleMeasurements
+	REMReferences []string `json:"rem-refs,omitempty"`
+	// PlatformLifecycle is the lifecycle state of the CCA platform
+	PlatformLifecycle *CCALifecycle `json:"platform-lifecycle,omitempty"`
+}
+
+// SetCCAInfo validates and sets the "ear.veraison.cca-info" claim
+func (o *AppraisalExtensions) SetCCAInfo(info VeraisonCCAInfo) error {
+	if err := info.validate(); err != nil {
+		return err
+	}
+
+	o.VeraisonCCAInfo = &info
+
+	return nil
+}
+
+func (o VeraisonCCAInfo) validate() error {
+	if o.RealmInitialMeasurement != nil {
+		if err := checkCCAMeasurement(*o.RealmInitialMeasurement); err != nil {
+			return fmt.Errorf(`"rim": %w`, err)
+		}
+	}
+
+	if len(o.RealmExtensibleMeasurements) > maxREMs {
+		return fmt.Errorf(`"rem": %d measurements, at most %d expected`,
+			len(o.RealmExtensibleMeasurements), maxREMs)
+	}
+
+	for i, m := range o.RealmExtensibleMeasurements {
+		if err := checkCCAMeasurement(m); err != nil {
+			return fmt.Errorf(`"rem"[%d]: %w`, i, err)
+		}
+	}
+
+	if o.RIMReference != nil {
+		if err := checkCCAReference(*o.RIMReference); err != nil {
+			return fmt.Errorf(`"rim-ref": %w`, err)
+		}
+	}
+
+	if len(o.REMReferences) > maxREMs {
+		return fmt.Errorf(`"rem-refs": %d references, at most %d expected`,
+			len(o.REMReferences), maxREMs)
+	}
+
+	for i, ref := range o.REMReferences {
+		if err := checkCCAReference(ref); err != nil {
+			return fmt.Errorf(`"rem-refs"[%d]: %w`, i, err)
+		}
+	}
+
+	if o.PlatformLifecycle != nil && !o.PlatformLifecycle.IsValid() {
+		return fmt.Errorf(`"platform-lifecycle": unknown state 0x%04x`, uint16(*o.PlatformLifecycle))
+	}
+
+	return nil
+}
+
+// checkCCAMeasurement makes sure the measurement is the size of a SHA-256,
+// SHA-384 or SHA-512 digest, which are the algorithms a realm can use
+func checkCCAMeasurement(m []byte) error {
+	switch len(m) {
+	case 32, 48, 64:
+		return nil
+	default:
+		return fmt.Errorf("%d bytes, expecting 32, 48 or 64", len(m))
+	}
+}
+
+func checkCCAReference(ref string) error {
+	if ref == "" {
+		return errors.New("empty reference")
+	}
+
+	return checkFreeText(ref)
+}
+
+func ToVeraisonCCAInfo(v interface{}) (*VeraisonCCAInfo, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "cca-info"`)
+	}
+
+	var info VeraisonCCAInfo
+
+	for key, val := range vMap {
+		switch key {
+		case "rim":
+			b, err := b64urlBytesPtrParser(val)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "rim": %w`, err)
+			}
+			info.RealmInitialMeasurement = b.(*B64Url)
+		case "rem":
+			a, ok := val.([]interface{})
+			if !ok {
+				return nil, errors.New(`invalid value for "rem": not an array`)
+			}
+			for i, e := range a {
+				b, err := b64urlBytesParser(e)
+				if err != nil {
+					return nil, fmt.Errorf(`invalid value for "rem"[%d]: %w`, i, err)
+				}
+				info.RealmExtensibleMeasurements = append(info.RealmExtensibleMeasurements, b.(B64Url))
+			}
+		case "rim-ref":
+			s, err := str(val)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "rim-ref": %w`, err)
+			}
+			info.RIMReference = &s
+		case "rem-refs":
+			a, ok := val.([]interface{})
+			if !ok {
+				return nil, errors.New(`invalid value for "rem-refs": not an array`)
+			}
+			for i, e := range a {
+				s, err := str(e)
+				if err != nil {
+					return nil, fmt.Errorf(`invalid value for "rem-refs"[%d]: %w`, i, err)
+				}
+				info.REMReferences = append(info.REMReferences, s)
+			}
+		case "platform-lifecycle":
+			i, err := int64PtrParser(val)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "platform-lifecycle": %w`, err)
+			}
+			n := *(i.(*int64))
+			if n < 0 || n > 0xffff {
+				return nil, fmt.Errorf(`invalid value for "platform-lifecycle": %d out of range`, n)
+			}
+			lc := CCALifecycle(n)
+			info.PlatformLifecycle = &lc
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "cca-info" object`, key)
+		}
+	}
+
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
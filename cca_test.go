@@ -0,0 +1,31 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCCALifecycle(t *testing.T) {
+	tvs := []struct {
+		lc    CCALifecycle
+		state CCALifecycle
+		valid bool
+		str   string
+	}{
+		{0x0000, CCALifecycleUnknown, true, "unknown"},
+		{0x3000, CCALifecycleSecured, true, "secured"},
+		{0x30ff, CCALifecycleSecured, true, "secured"},
+		{0x5001, CCALifecycleRecoverableCCAPlatformDebug, true, "recoverable_cca_platform_debug"},
+		{0x7000, 0x7000, false, "CCALifecycle(0x7000)"},
+	}
+
+	for i, tv := range tvs {
+		assert.Equal(t, tv.state, tv.lc.State(), "failed test vector at index %d", i)
+		assert.Equal(t, tv.valid, tv.lc.IsValid(), "failed test vector at index %d", i)
+		assert.Equal(t, tv.str, tv.lc.String(), "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CDDL returns the CDDL (RFC8610) of the CBOR serialization of the EAR
+// claims-set, as carried in CWTs, for the default Registry.  See
+// Registry.CDDL.
+func CDDL() string {
+	return defaultRegistry.CDDL()
+}
+
+// CDDL returns the CDDL (RFC8610) of the CBOR serialization of the EAR
+// claims-set, as produced by MarshalCBOR and SignCWT.  Like JSONSchema, it is
+// derived from the Go types modelling the claims, and from the CBOR claim
+// keys used by the encoder, so that it can be used to check interoperability
+// with CDDL-driven implementations of AR4SI.  The eat_profile values accepted
+// by the Registry are listed.  Claims with no CBOR key, such as those of the
+// registered AppraisalExtensions, are text-keyed, and are matched by the
+// generic "* text => any" entries.  The claims-set is the "ear-claims" rule.
+func (o *Registry) CDDL() string {
+	var b strings.Builder
+
+	b.WriteString("; EAR claims-set, CBOR serialization\n\n")
+
+	profiles := make([]string, 0, len(o.SupportedProfiles()))
+	for _, p := range o.SupportedProfiles() {
+		profiles = append(profiles, cddlProfile(p))
+	}
+
+	fmt.Fprintf(&b, "ear-claims = %s\n\n", cddlMap(reflect.TypeOf(AttestationResult{}), 0, map[string]string{
+		"eat_profile": strings.Join(profiles, " / "),
+		"submods":     "{ + text => ear-appraisal }",
+	}))
+
+	fmt.Fprintf(&b, "ear-appraisal = %s\n\n", cddlMap(reflect.TypeOf(Appraisal{}), 0, nil))
+	fmt.Fprintf(&b, "ear-trustworthiness-vector = %s\n\n", cddlMap(reflect.TypeOf(TrustVector{}), 0, nil))
+	fmt.Fprintf(&b, "ear-trust-claim = -128..127\n\n")
+	fmt.Fprintf(&b, "ear-tier = &(\n%s\n)\n", strings.Join(cddlTiers(), ",\n"))
+
+	for _, keys := range []map[string]int64{
+		cwtResultKeys, cwtAppraisalKeys, cwtVerifierIDKeys, cwtTrustVectorKeys,
+	} {
+		b.WriteString("\n")
+		for _, name := range cddlSortedLabels(keys) {
+			fmt.Fprintf(&b, "%s-label = %d\n", name, keys[name])
+		}
+	}
+
+	return b.String()
+}
+
+// cddlKeys are the CBOR integer keys of the members of the types serialized
+// as CBOR maps with integer keys
+var cddlKeys = map[reflect.Type]map[string]int64{
+	reflect.TypeOf(AttestationResult{}): cwtResultKeys,
+	reflect.TypeOf(Appraisal{}):         cwtAppraisalKeys,
+	reflect.TypeOf(TrustVector{}):       cwtTrustVectorKeys,
+	reflect.TypeOf(VerifierIdentity{}):  cwtVerifierIDKeys,
+}
+
+// cddlOpen are the types that may carry text-keyed members besides the
+// modelled ones
+var cddlOpen = map[reflect.Type]bool{
+	reflect.TypeOf(AttestationResult{}): true,
+	reflect.TypeOf(Appraisal{}):         true,
+}
+
+// cddlTypes maps the types with a rule of their own (or with a custom
+// serialization) onto their CDDL type
+var cddlTypes = map[reflect.Type]string{
+	reflect.TypeOf(B64Url{}):      "bytes",
+	reflect.TypeOf(TrustTier(0)):  "ear-tier",
+	reflect.TypeOf(TrustClaim(0)): "ear-trust-claim",
+	reflect.TypeOf(TrustVector{}): "ear-trustworthiness-vector",
+	reflect.TypeOf(Appraisal{}):   "ear-appraisal",
+	reflect.TypeOf(Profile("")):   "text / bytes",
+	reflect.TypeOf(Nonces{}):      "text / [+ text]",
+	reflect.TypeOf(PolicyIDs{}):   "text / [+ text]",
+	reflect.TypeOf([]byte{}):      "text", // base64, as in JSON
+}
+
+// cddlType returns the CDDL type of the CBOR serialization of t.  Maps are
+// laid out for the given nesting depth.
+func cddlType(t reflect.Type, depth int) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if s, ok := cddlTypes[t]; ok {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return cddlMap(t, depth, nil)
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return "{ * text => any }"
+		}
+		return "{ * text => " + cddlType(t.Elem(), depth) + " }"
+	case reflect.Slice, reflect.Array:
+		return "[* " + cddlType(t.Elem(), depth) + "]"
+	case reflect.String:
+		return "text"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		bits := t.Bits()
+		return fmt.Sprintf("%d..%d", -(1 << (bits - 1)), 1<<(bits-1)-1)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return fmt.Sprintf("0..%d", uint64(1)<<t.Bits()-1)
+	case reflect.Uint, reflect.Uint64:
+		return "uint"
+	case reflect.Int, reflect.Int64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	default:
+		return "any"
+	}
+}
+
+// cddlMap returns the CDDL map type of the struct type t.  The types of the
+// named members can be overridden.
+func cddlMap(t reflect.Type, depth int, overrides map[string]string) string {
+	indent := strings.Repeat("  ", depth+1)
+	keys := cddlKeys[t]
+
+	var entries []string
+
+	for _, f := range cddlFields(t) {
+		spec, _ := parseTag(f.Tag, "json")
+
+		key := strconv.Quote(spec.Name)
+		if _, ok := keys[spec.Name]; ok {
+			key = spec.Name + "-label"
+		}
+
+		typ, ok := overrides[spec.Name]
+		if !ok {
+			typ = cddlType(f.Type, depth+1)
+		}
+
+		occur := ""
+		if !spec.IsMandatory {
+			occur = "? "
+		}
+
+		entries = append(entries, fmt.Sprintf("%s%s%s => %s", indent, occur, key, typ))
+	}
+
+	if cddlOpen[t] {
+		entries = append(entries, indent+"* text => any")
+	}
+
+	return "{\n" + strings.Join(entries, ",\n") + "\n" + strings.Repeat("  ", depth) + "}"
+}
+
+// cddlFields returns the serialized fields of the struct type t, flattening
+// embedded structs
+func cddlFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if _, ok := parseTag(f.Tag, "json"); !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				fields = append(fields, cddlFields(f.Type)...)
+			}
+			continue
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// cddlProfile returns the CDDL literal of an eat_profile value: URIs are text
+// strings, and OIDs byte strings
+func cddlProfile(p string) string {
+	if Profile(p).IsOID() {
+		if b, err := oidToBytes(p); err == nil {
+			return "h'" + hex.EncodeToString(b) + "'"
+		}
+	}
+
+	return strconv.Quote(p)
+}
+
+func cddlTiers() []string {
+	names := trustTierNames()
+
+	ret := make([]string, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, fmt.Sprintf("  %s: %d", name, StringToTrustTier[name]))
+	}
+
+	return ret
+}
+
+// cddlSortedLabels returns the claim names in keys, sorted by key
+func cddlSortedLabels(keys map[string]int64) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		ki, kj := keys[names[i]], keys[names[j]]
+		// registered keys first, then private use (negative) ones
+		if (ki < 0) != (kj < 0) {
+			return kj < 0
+		}
+		if ki < 0 {
+			return ki > kj
+		}
+		return ki < kj
+	})
+
+	return names
+}
@@ -0,0 +1,563 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests below check CBOR encodings against the CDDL returned by CDDL,
+// using a matcher for the subset of CDDL it is written in.
+
+// cddlNode is a type in a parsed CDDL document
+type cddlNode interface{}
+
+type (
+	cddlName   string
+	cddlInt    int64
+	cddlText   string
+	cddlBytes  []byte
+	cddlRange  struct{ lo, hi int64 }
+	cddlChoice []cddlNode
+	cddlMapT   []cddlEntry
+	cddlArrayT []cddlEntry
+)
+
+// cddlEntry is a member of a map or array group
+type cddlEntry struct {
+	occur string
+	key   cddlNode
+	value cddlNode
+}
+
+type cddlParser struct {
+	toks []string
+	pos  int
+	refs []string
+}
+
+func cddlTokenize(s string) ([]string, error) {
+	var toks []string
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == ';':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "=>"), strings.HasPrefix(s[i:], ".."):
+			toks = append(toks, s[i:i+2])
+			i += 2
+		case strings.ContainsRune("{}[](),/&:=?*+", rune(c)):
+			toks = append(toks, string(c))
+			i++
+		case c == '"', c == 'h' && i+1 < len(s) && s[i+1] == '\'':
+			q := byte('"')
+			start := i
+			if c == 'h' {
+				q = '\''
+				i++
+			}
+			end := strings.IndexByte(s[i+1:], q)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated literal at %d", start)
+			}
+			i += end + 2
+			toks = append(toks, s[start:i])
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			}
+			toks = append(toks, s[start:i])
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(s) && (strings.ContainsRune("_-.", rune(s[i])) && !strings.HasPrefix(s[i:], "..") ||
+				s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || s[i] >= '0' && s[i] <= '9') {
+				i++
+			}
+			toks = append(toks, s[start:i])
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+
+	return toks, nil
+}
+
+func (p *cddlParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *cddlParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *cddlParser) expect(tok string) error {
+	if t := p.next(); t != tok {
+		return fmt.Errorf("expecting %q, got %q", tok, t)
+	}
+	return nil
+}
+
+func (p *cddlParser) parseType() (cddlNode, error) {
+	var choice cddlChoice
+
+	for {
+		n, err := p.parseType1()
+		if err != nil {
+			return nil, err
+		}
+		choice = append(choice, n)
+
+		if p.peek() != "/" {
+			break
+		}
+		p.next()
+	}
+
+	if len(choice) == 1 {
+		return choice[0], nil
+	}
+	return choice, nil
+}
+
+func (p *cddlParser) parseType1() (cddlNode, error) {
+	t := p.next()
+
+	switch {
+	case t == "{", t == "[":
+		entries, err := p.parseGroup(map[string]string{"{": "}", "[": "]"}[t])
+		if err != nil {
+			return nil, err
+		}
+		if t == "{" {
+			return cddlMapT(entries), nil
+		}
+		return cddlArrayT(entries), nil
+	case t == "&":
+		return p.parseEnum()
+	case strings.HasPrefix(t, `"`):
+		s, err := strconv.Unquote(t)
+		return cddlText(s), err
+	case strings.HasPrefix(t, "h'"):
+		b, err := hex.DecodeString(t[2 : len(t)-1])
+		return cddlBytes(b), err
+	case t != "" && (t[0] == '-' || t[0] >= '0' && t[0] <= '9'):
+		lo, err := strconv.ParseInt(t, 10, 64)
+		if err != nil || p.peek() != ".." {
+			return cddlInt(lo), err
+		}
+		p.next()
+		hi, err := strconv.ParseInt(p.next(), 10, 64)
+		return cddlRange{lo, hi}, err
+	case t != "" && strings.ContainsRune("_abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ", rune(t[0])):
+		p.refs = append(p.refs, t)
+		return cddlName(t), nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+}
+
+func (p *cddlParser) parseGroup(closing string) ([]cddlEntry, error) {
+	var entries []cddlEntry
+
+	for p.peek() != closing {
+		var e cddlEntry
+
+		if t := p.peek(); t == "?" || t == "*" || t == "+" {
+			e.occur = p.next()
+		}
+
+		n, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() == "=>" {
+			p.next()
+			e.key = n
+			if n, err = p.parseType(); err != nil {
+				return nil, err
+			}
+		}
+		e.value = n
+
+		entries = append(entries, e)
+
+		if p.peek() == "," {
+			p.next()
+		} else if p.peek() != closing {
+			return nil, fmt.Errorf("expecting %q, got %q", closing, p.peek())
+		}
+	}
+	p.next()
+
+	return entries, nil
+}
+
+func (p *cddlParser) parseEnum() (cddlNode, error) {
+	var choice cddlChoice
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	for p.peek() != ")" {
+		p.next()
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseInt(p.next(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		choice = append(choice, cddlInt(v))
+
+		if p.peek() == "," {
+			p.next()
+		}
+	}
+	p.next()
+
+	return choice, nil
+}
+
+var cddlPrelude = map[string]bool{
+	"int": true, "uint": true, "text": true, "bytes": true,
+	"bool": true, "float": true, "any": true,
+}
+
+// cddlParse parses the rules of a CDDL document, and checks that all the
+// referenced rules are defined
+func cddlParse(s string) (map[string]cddlNode, error) {
+	toks, err := cddlTokenize(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &cddlParser{toks: toks}
+	rules := map[string]cddlNode{}
+
+	for p.peek() != "" {
+		name := p.next()
+		if err := p.expect("="); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		if rules[name], err = p.parseType(); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+	}
+
+	for _, ref := range p.refs {
+		if _, ok := rules[ref]; !ok && !cddlPrelude[ref] {
+			return nil, fmt.Errorf("undefined rule %s", ref)
+		}
+	}
+
+	return rules, nil
+}
+
+func cddlAsInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return int64(n), n <= 1<<63-1
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// cddlMatch checks the decoded CBOR value v against the type n
+func cddlMatch(rules map[string]cddlNode, n cddlNode, v interface{}, path string) error {
+	fail := fmt.Errorf("%s: %#v does not match %v", path, v, n)
+
+	switch n := n.(type) {
+	case cddlName:
+		if r, ok := rules[string(n)]; ok {
+			err := cddlMatch(rules, r, v, path)
+			if _, isMap := r.(cddlMapT); err != nil && !isMap {
+				// report the rule, rather than its definition
+				return fail
+			}
+			return err
+		}
+		ok := false
+		switch n {
+		case "any":
+			ok = true
+		case "int":
+			_, ok = cddlAsInt(v)
+		case "uint":
+			_, ok = v.(uint64)
+		case "text":
+			_, ok = v.(string)
+		case "bytes":
+			_, ok = v.([]byte)
+		case "bool":
+			_, ok = v.(bool)
+		case "float":
+			_, ok = v.(float64)
+		}
+		if !ok {
+			return fail
+		}
+	case cddlInt:
+		if i, ok := cddlAsInt(v); !ok || i != int64(n) {
+			return fail
+		}
+	case cddlRange:
+		if i, ok := cddlAsInt(v); !ok || i < n.lo || i > n.hi {
+			return fail
+		}
+	case cddlText:
+		if s, ok := v.(string); !ok || s != string(n) {
+			return fail
+		}
+	case cddlBytes:
+		if b, ok := v.([]byte); !ok || !bytes.Equal(b, n) {
+			return fail
+		}
+	case cddlChoice:
+		for _, c := range n {
+			if cddlMatch(rules, c, v, path) == nil {
+				return nil
+			}
+		}
+		return fail
+	case cddlArrayT:
+		a, ok := v.([]interface{})
+		if !ok || len(n) != 1 || n[0].occur == "+" && len(a) == 0 {
+			return fail
+		}
+		for i, item := range a {
+			if err := cddlMatch(rules, n[0].value, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case cddlMapT:
+		m, ok := v.(map[interface{}]interface{})
+		if !ok {
+			return fail
+		}
+		counts := make([]int, len(n))
+	members:
+		for k, mv := range m {
+			for i, e := range n {
+				if cddlMatch(rules, e.key, k, path) == nil {
+					if err := cddlMatch(rules, e.value, mv, fmt.Sprintf("%s/%v", path, k)); err != nil {
+						return err
+					}
+					counts[i]++
+					continue members
+				}
+			}
+			return fmt.Errorf("%s: unexpected key %#v", path, k)
+		}
+		for i, e := range n {
+			if (e.occur == "" || e.occur == "+") && counts[i] == 0 {
+				return fmt.Errorf("%s: missing %v", path, e.key)
+			}
+		}
+	default:
+		return fail
+	}
+
+	return nil
+}
+
+func cddlValidate(t *testing.T, schema string, data []byte) error {
+	rules, err := cddlParse(schema)
+	require.NoError(t, err)
+
+	var v interface{}
+	require.NoError(t, cbor.Unmarshal(data, &v))
+
+	return cddlMatch(rules, rules["ear-claims"], v, "ear-claims")
+}
+
+func TestCDDL_well_formed(t *testing.T) {
+	rules, err := cddlParse(CDDL())
+	require.NoError(t, err)
+
+	for _, name := range []string{
+		"ear-claims", "ear-appraisal", "ear-trustworthiness-vector",
+		"ear-trust-claim", "ear-tier", "iat-label", "ear.status-label",
+		"developer-label", "sourced-data-label",
+	} {
+		assert.Contains(t, rules, name)
+	}
+
+	assert.Equal(t, cddlInt(1000), rules["ear.status-label"])
+	assert.Equal(t, cddlInt(-70003), rules["ear.veraison.tee-info-label"])
+	assert.Equal(t, cddlChoice{cddlInt(0), cddlInt(2), cddlInt(32), cddlInt(96)}, rules["ear-tier"])
+}
+
+func TestCDDL_serialized_results(t *testing.T) {
+	schema := CDDL()
+
+	rawEvidence := B64Url(testEvidence)
+
+	rich := testAttestationResultsWithVeraisonExtns
+	rich.RawEvidence = &rawEvidence
+	rich.Nonce = &Nonces{"0123456789abcdef", "fedcba9876543210"}
+	rich.Submods = map[string]*Appraisal{
+		"test": {
+			Status: &testStatus,
+			TrustVector: &TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+				Executables:      ContraindicatedRuntimeClaim,
+			},
+			AppraisalPolicyID: &testPolicyID,
+		},
+	}
+
+	for i, ar := range []AttestationResult{
+		testAttestationResultsWithVeraisonExtns,
+		rich,
+		*NewAttestationResult("test", "build", "developer"),
+	} {
+		data, err := ar.MarshalCBOR()
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.NoError(t, cddlValidate(t, schema, data), "failed test vector at index %d", i)
+	}
+}
+
+func TestCDDL_spec_examples(t *testing.T) {
+	schema := CDDL()
+
+	examples, err := SpecExamples()
+	require.NoError(t, err)
+
+	for _, ex := range examples {
+		if !ex.Valid {
+			continue
+		}
+
+		var ar AttestationResult
+		require.NoError(t, ar.UnmarshalJSON(ex.Claims), "example %s", ex.Name)
+
+		data, err := ar.MarshalCBOR()
+		require.NoError(t, err, "example %s", ex.Name)
+		assert.NoError(t, cddlValidate(t, schema, data), "example %s", ex.Name)
+	}
+}
+
+func TestCDDL_invalid_claims(t *testing.T) {
+	schema := CDDL()
+
+	submod := func(m map[interface{}]interface{}) map[interface{}]interface{} {
+		return m[uint64(266)].(map[interface{}]interface{})["test"].(map[interface{}]interface{})
+	}
+
+	tvs := []struct {
+		tamper   func(m map[interface{}]interface{})
+		expected string
+	}{
+		{
+			func(m map[interface{}]interface{}) { delete(m, uint64(6)) },
+			"ear-claims: missing iat-label",
+		},
+		{
+			func(m map[interface{}]interface{}) { m[uint64(6)] = "now" },
+			`ear-claims/6: "now" does not match int`,
+		},
+		{
+			func(m map[interface{}]interface{}) { m[uint64(266)] = map[interface{}]interface{}{} },
+			"ear-claims/266: missing text",
+		},
+		{
+			func(m map[interface{}]interface{}) { m[int64(-80000)] = true },
+			"ear-claims: unexpected key -80000",
+		},
+		{
+			func(m map[interface{}]interface{}) { submod(m)[uint64(1000)] = uint64(5) },
+			"ear-claims/266/test/1000: 0x5 does not match ear-tier",
+		},
+		{
+			func(m map[interface{}]interface{}) {
+				submod(m)[uint64(1001)] = map[interface{}]interface{}{uint64(8): uint64(0)}
+			},
+			"ear-claims/266/test/1001: unexpected key 0x8",
+		},
+		{
+			func(m map[interface{}]interface{}) {
+				submod(m)[uint64(1001)] = map[interface{}]interface{}{uint64(0): uint64(200)}
+			},
+			"ear-claims/266/test/1001/0: 0xc8 does not match ear-trust-claim",
+		},
+		{
+			func(m map[interface{}]interface{}) { m[uint64(1004)] = map[interface{}]interface{}{uint64(0): "d"} },
+			"ear-claims/1004: missing build-label",
+		},
+	}
+
+	ar := *NewAttestationResult("test", "build", "developer")
+
+	data, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	for i, tv := range tvs {
+		var m map[interface{}]interface{}
+		require.NoError(t, cbor.Unmarshal(data, &m), "failed test vector at index %d", i)
+
+		tv.tamper(m)
+
+		tampered, err := cbor.Marshal(m)
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		assert.EqualError(t, cddlValidate(t, schema, tampered), tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestRegistry_CDDL(t *testing.T) {
+	reg := NewRegistry()
+
+	require.NoError(t, reg.RegisterProfile("tag:example.com,2023:acme"))
+	require.NoError(t, reg.RegisterProfile("1.3.6.1.4.1.99999.1"))
+
+	schema := reg.CDDL()
+
+	oid, err := oidToBytes("1.3.6.1.4.1.99999.1")
+	require.NoError(t, err)
+
+	assert.Contains(t, schema, fmt.Sprintf(
+		`eat_profile-label => "%s" / h'%x' / "tag:example.com,2023:acme",`, EatProfile, oid,
+	))
+
+	ar := *NewAttestationResult("test", "build", "developer")
+
+	data, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	var m map[interface{}]interface{}
+	require.NoError(t, cbor.Unmarshal(data, &m))
+
+	for i, profile := range []interface{}{"tag:example.com,2023:acme", oid} {
+		m[uint64(265)] = profile
+
+		data, err = cbor.Marshal(m)
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		assert.NoError(t, cddlValidate(t, schema, data), "failed test vector at index %d", i)
+		assert.Error(t, cddlValidate(t, CDDL(), data), "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+)
+
+const encryptedClaimsClaim = "ear.veraison.encrypted-claims"
+
+// VeraisonEncryptedClaims is the "ear.veraison.encrypted-claims" claim.  It
+// maps the JSON Pointers (RFC6901) of the claims that have been encrypted by
+// EncryptClaims onto the JWEs, in compact serialization, of their JSON
+// encoding.
+type VeraisonEncryptedClaims map[string]string
+
+func (o VeraisonEncryptedClaims) validate() error {
+	for _, p := range sortedKeys(o) {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("%q is not a JSON Pointer", p)
+		}
+
+		if p == "/"+encryptedClaimsClaim {
+			return fmt.Errorf("%q cannot be encrypted", p)
+		}
+
+		if strings.Count(o[p], ".") != 4 {
+			return fmt.Errorf("%q: not a JWE in compact serialization", p)
+		}
+	}
+
+	return nil
+}
+
+func ToVeraisonEncryptedClaims(v interface{}) (*VeraisonEncryptedClaims, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "encrypted-claims"`)
+	}
+
+	ec := make(VeraisonEncryptedClaims, len(vMap))
+
+	for key, val := range vMap {
+		s, err := str(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+		}
+		ec[key] = s
+	}
+
+	if err := ec.validate(); err != nil {
+		return nil, err
+	}
+
+	return &ec, nil
+}
+
+// EncryptClaims encrypts the claims identified by the supplied JSON Pointers
+// to a relying party's key, leaving the rest of the claims-set readable.  As
+// in a RedactionProfile, a "*" reference token matches any member, e.g.,
+// "/submods/*/ear.veraison.policy-claims".  Each matching claim is removed,
+// and the JWE of its JSON encoding is added to the
+// "ear.veraison.encrypted-claims" claim.  keyAlg is the key management
+// algorithm, which must match the type of encKey; the content encryption
+// algorithm can be set using WithContentEncryption.  Mandatory claims cannot
+// be encrypted.  Once signed, the result can be routed on its public claims,
+// and verified, by intermediaries that do not hold the decryption key.
+func (o *AttestationResult) EncryptClaims(
+	pointers []string,
+	keyAlg jwa.KeyEncryptionAlgorithm,
+	encKey interface{},
+	opts ...EncryptOption,
+) error {
+	eo := newEncryptOptions(opts)
+
+	if err := RedactionProfile(pointers).Validate(); err != nil {
+		return err
+	}
+
+	m, err := o.claimsMap()
+	if err != nil {
+		return err
+	}
+
+	ec, _ := m[encryptedClaimsClaim].(map[string]interface{})
+	if ec == nil {
+		ec = map[string]interface{}{}
+	}
+
+	for _, p := range pointers {
+		if p == "/"+encryptedClaimsClaim {
+			return fmt.Errorf("%q cannot be encrypted", p)
+		}
+
+		found := extractClaims(m, strings.Split(p[1:], "/"), "")
+		if len(found) == 0 {
+			return fmt.Errorf("no claim matches %q", p)
+		}
+
+		for _, cp := range sortedKeys(found) {
+			if _, ok := ec[cp]; ok {
+				return fmt.Errorf("%q: already encrypted", cp)
+			}
+
+			plaintext, err := json.Marshal(found[cp])
+			if err != nil {
+				return fmt.Errorf("encoding %q: %w", cp, err)
+			}
+
+			data, err := jwe.Encrypt(
+				plaintext,
+				jwe.WithKey(keyAlg, encKey),
+				jwe.WithContentEncryption(eo.contentAlg),
+			)
+			if err != nil {
+				return fmt.Errorf("encrypting %q: %w", cp, err)
+			}
+
+			ec[cp] = string(data)
+		}
+	}
+
+	m[encryptedClaimsClaim] = ec
+
+	return o.replaceClaims(m)
+}
+
+// DecryptClaims restores the claims encrypted by EncryptClaims, using the
+// relying party's decKey and the key management algorithm keyAlg, and removes
+// the "ear.veraison.encrypted-claims" claim.  It fails if any of the claims
+// cannot be decrypted.  Use WithClaimDecryption to decrypt while verifying or
+// decoding.
+func (o *AttestationResult) DecryptClaims(
+	keyAlg jwa.KeyEncryptionAlgorithm,
+	decKey interface{},
+) error {
+	if o.VeraisonEncryptedClaims == nil {
+		return fmt.Errorf("%q claim not found", encryptedClaimsClaim)
+	}
+
+	m, err := o.claimsMap()
+	if err != nil {
+		return err
+	}
+
+	if err := decryptClaims(m, keyAlg, decKey); err != nil {
+		return err
+	}
+
+	return o.replaceClaims(m)
+}
+
+// WithClaimDecryption makes decoding restore the claims encrypted by
+// EncryptClaims, as DecryptClaims does, before the claims-set is parsed.
+// Results without encrypted claims are decoded as usual.
+func WithClaimDecryption(keyAlg jwa.KeyEncryptionAlgorithm, decKey interface{}) DecodeOption {
+	return func(o *decodeOptions) {
+		o.decrypt = func(m map[string]interface{}) error {
+			return decryptClaims(m, keyAlg, decKey)
+		}
+	}
+}
+
+// claimsMap returns the claims-set in the form produced by encoding/json
+func (o AttestationResult) claimsMap() (map[string]interface{}, error) {
+	j, err := json.Marshal(o.AsMap())
+	if err != nil {
+		return nil, fmt.Errorf("encoding claims-set: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return nil, fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	return m, nil
+}
+
+// replaceClaims populates the receiver from the modified claims-set m, which
+// must be valid.  Unknown claims and the Registry are retained.
+func (o *AttestationResult) replaceClaims(m map[string]interface{}) error {
+	ar := AttestationResult{Registry: o.Registry}
+
+	do := newDecodeOptions([]DecodeOption{WithUnknownClaims(UnknownClaimsPreserve)})
+
+	if err := ar.decodeMap(m, do); err != nil {
+		return fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	if err := ar.validate(); err != nil {
+		return err
+	}
+
+	*o = ar
+
+	return nil
+}
+
+// decryptClaims replaces the "ear.veraison.encrypted-claims" claim in m with
+// the claims it carries
+func decryptClaims(
+	m map[string]interface{},
+	keyAlg jwa.KeyEncryptionAlgorithm,
+	decKey interface{},
+) error {
+	v, ok := m[encryptedClaimsClaim]
+	if !ok {
+		return nil
+	}
+
+	ec, err := ToVeraisonEncryptedClaims(v)
+	if err != nil {
+		return fmt.Errorf("%s: %w", encryptedClaimsClaim, err)
+	}
+
+	for _, p := range sortedKeys(*ec) {
+		plaintext, err := jwe.Decrypt([]byte((*ec)[p]), jwe.WithKey(keyAlg, decKey))
+		if err != nil {
+			return fmt.Errorf("decrypting %q: %w", p, err)
+		}
+
+		var claim interface{}
+		if err := json.Unmarshal(plaintext, &claim); err != nil {
+			return fmt.Errorf("decoding %q: %w", p, err)
+		}
+
+		if err := setClaim(m, strings.Split(p[1:], "/"), claim); err != nil {
+			return fmt.Errorf("restoring %q: %w", p, err)
+		}
+	}
+
+	delete(m, encryptedClaimsClaim)
+
+	return nil
+}
+
+// setClaim sets the member of m identified by the supplied JSON Pointer
+// reference tokens, which must not already exist
+func setClaim(m map[string]interface{}, tokens []string, v interface{}) error {
+	tok := jsonPointerUnescaper.Replace(tokens[0])
+
+	if len(tokens) == 1 {
+		if _, ok := m[tok]; ok {
+			return errors.New("claim is also present in clear")
+		}
+		m[tok] = v
+		return nil
+	}
+
+	child, ok := m[tok].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("no object found at %q", tok)
+	}
+
+	return setClaim(child, tokens[1:], v)
+}
@@ -0,0 +1,159 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEncryptedClaimsResult(t *testing.T) (AttestationResult, AttestationResult, *ecdsa.PrivateKey) {
+	rpKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	clear := testAttestationResultsWithVeraisonExtns
+	clear.RawEvidence = &B64Url{0xde, 0xad, 0xbe, 0xef}
+
+	sealed := clear
+	err = sealed.EncryptClaims(
+		[]string{"/ear.raw-evidence", "/submods/*/ear.veraison.policy-claims"},
+		jwa.ECDH_ES_A256KW, &rpKey.PublicKey,
+	)
+	require.NoError(t, err)
+
+	return clear, sealed, rpKey
+}
+
+func TestAttestationResult_EncryptClaims(t *testing.T) {
+	clear, sealed, rpKey := testEncryptedClaimsResult(t)
+
+	assert.Nil(t, sealed.RawEvidence)
+	assert.Nil(t, sealed.Submods["test"].VeraisonPolicyClaims)
+	assert.Equal(t, clear.Submods["test"].VeraisonAnnotatedEvidence, sealed.Submods["test"].VeraisonAnnotatedEvidence)
+
+	require.NotNil(t, sealed.VeraisonEncryptedClaims)
+	assert.Equal(t,
+		[]string{"/ear.raw-evidence", "/submods/test/ear.veraison.policy-claims"},
+		sortedKeys(*sealed.VeraisonEncryptedClaims))
+
+	// the receiver is left untouched
+	assert.NotNil(t, clear.Submods["test"].VeraisonPolicyClaims)
+
+	actual := sealed
+	require.NoError(t, actual.DecryptClaims(jwa.ECDH_ES_A256KW, rpKey))
+	assert.Equal(t, clear, actual)
+}
+
+func TestAttestationResult_EncryptClaims_sign_verify(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	clear, sealed, rpKey := testEncryptedClaimsResult(t)
+
+	token, err := sealed.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	// intermediaries can verify and route without the decryption key
+	var actual AttestationResult
+	require.NoError(t, actual.Verify(token, jwa.ES256, vfyK))
+	assert.Equal(t, sealed, actual)
+
+	actual = AttestationResult{}
+	err = actual.Verify(token, jwa.ES256, vfyK,
+		WithDecodeOptions(WithClaimDecryption(jwa.ECDH_ES_A256KW, rpKey)))
+	require.NoError(t, err)
+	assert.Equal(t, clear, actual)
+
+	data, err := sealed.MarshalCBOR()
+	require.NoError(t, err)
+
+	actual = AttestationResult{}
+	require.NoError(t, actual.DecodeCBOR(data, WithClaimDecryption(jwa.ECDH_ES_A256KW, rpKey)))
+	assert.Equal(t, clear, actual)
+}
+
+func TestAttestationResult_EncryptClaims_fail(t *testing.T) {
+	rpKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		pointers []string
+		expected string
+	}{
+		{
+			pointers: []string{"ear.raw-evidence"},
+			expected: `"ear.raw-evidence" is not a JSON Pointer`,
+		},
+		{
+			pointers: []string{"/ear.raw-evidence"},
+			expected: `no claim matches "/ear.raw-evidence"`,
+		},
+		{
+			pointers: []string{"/ear.veraison.encrypted-claims"},
+			expected: `"/ear.veraison.encrypted-claims" cannot be encrypted`,
+		},
+		{
+			pointers: []string{"/iat"},
+			expected: `decoding claims-set: missing mandatory 'iat'`,
+		},
+		{
+			pointers: []string{"/submods/*/ear.veraison.policy-claims", "/submods/test/ear.veraison.policy-claims"},
+			expected: `no claim matches "/submods/test/ear.veraison.policy-claims"`,
+		},
+	}
+
+	for i, tv := range tvs {
+		ar := testAttestationResultsWithVeraisonExtns
+		err := ar.EncryptClaims(tv.pointers, jwa.ECDH_ES_A256KW, &rpKey.PublicKey)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		assert.Equal(t, testAttestationResultsWithVeraisonExtns, ar, "failed test vector at index %d", i)
+	}
+}
+
+func TestAttestationResult_DecryptClaims_fail(t *testing.T) {
+	_, sealed, _ := testEncryptedClaimsResult(t)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	err = sealed.DecryptClaims(jwa.ECDH_ES_A256KW, otherKey)
+	assert.ErrorContains(t, err, `decrypting "/ear.raw-evidence"`)
+
+	err = testAttestationResultsWithVeraisonExtns.DecryptClaims(jwa.ECDH_ES_A256KW, otherKey)
+	assert.EqualError(t, err, `"ear.veraison.encrypted-claims" claim not found`)
+}
+
+func TestToVeraisonEncryptedClaims_fail(t *testing.T) {
+	tvs := []struct {
+		v        interface{}
+		expected string
+	}{
+		{
+			v:        "not-a-map",
+			expected: `unexpected format for "encrypted-claims"`,
+		},
+		{
+			v:        map[string]interface{}{"/ear.raw-evidence": 1},
+			expected: `invalid value for "/ear.raw-evidence": expecting string, found int`,
+		},
+		{
+			v:        map[string]interface{}{"ear.raw-evidence": "a.b.c.d.e"},
+			expected: `"ear.raw-evidence" is not a JSON Pointer`,
+		},
+		{
+			v:        map[string]interface{}{"/ear.raw-evidence": "a.b.c"},
+			expected: `"/ear.raw-evidence": not a JWE in compact serialization`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := ToVeraisonEncryptedClaims(tv.v)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
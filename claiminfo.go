@@ -0,0 +1,244 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ClaimDescription gives the meaning of a TrustClaim value within one of the
+// trustworthiness claims
+type ClaimDescription struct {
+	// Tag is the identifier-like name of the value, which is also accepted
+	// in place of the integer value when decoding
+	Tag string
+	// Short is a few words summary, e.g., for tables and error messages
+	Short string
+	// Long is the full description, as given in AR4SI
+	Long string
+}
+
+// ClaimInfo collects what is known about a TrustClaim value
+type ClaimInfo struct {
+	Value TrustClaim
+	// Tier is the trust tier that the value contributes to the appraisal
+	Tier TrustTier
+	// Descriptions maps the names of the trustworthiness claims (e.g.,
+	// "executables") onto the meaning that the value has in each of them.
+	// Values in the "none" tier share the same meaning across all claims.
+	// Claims in which the value is not defined are not listed.
+	Descriptions map[string]ClaimDescription
+}
+
+// Describe returns the meaning of the value in the named trustworthiness
+// claim, if defined
+func (o ClaimInfo) Describe(claim string) (ClaimDescription, bool) {
+	d, ok := o.Descriptions[claim]
+	return d, ok
+}
+
+// claimDetails maps the trustworthiness claim names onto the tables
+// describing their values
+func claimDetails() map[string]detailsMap {
+	return map[string]detailsMap{
+		"instance-identity": instanceIdentityDetails,
+		"configuration":     configurationDetails,
+		"executables":       executablesDetails,
+		"file-system":       fileSystemDetails,
+		"hardware":          hardwareDetails,
+		"runtime-opaque":    runtimeOpaqueDetails,
+		"storage-opaque":    storageOpaqueDetails,
+		"sourced-data":      sourcedDataDetails,
+	}
+}
+
+// DescribeClaim returns the tier and the per-claim descriptions of the
+// supplied TrustClaim value
+func DescribeClaim(c TrustClaim) ClaimInfo {
+	info := ClaimInfo{
+		Value:        c,
+		Tier:         c.GetTier(),
+		Descriptions: map[string]ClaimDescription{},
+	}
+
+	for name := range claimDetails() {
+		if d, ok := defaultRegistry.claimValue(name, c); ok {
+			info.Descriptions[name] = ClaimDescription{
+				Tag:   d.tag,
+				Short: d.short,
+				Long:  d.long,
+			}
+		}
+	}
+
+	return info
+}
+
+// KnownClaims returns the information about all the TrustClaim values that
+// have a defined meaning in at least one of the trustworthiness claims,
+// sorted by value
+func KnownClaims() []ClaimInfo {
+	values := map[TrustClaim]bool{}
+
+	for c := range noneDetails {
+		values[c] = true
+	}
+
+	for _, dm := range claimDetails() {
+		for c := range dm {
+			values[c] = true
+		}
+	}
+
+	defaultRegistry.mu.RLock()
+	for _, dm := range defaultRegistry.claimValues {
+		for c := range dm {
+			values[c] = true
+		}
+	}
+	defaultRegistry.mu.RUnlock()
+
+	ret := make([]ClaimInfo, 0, len(values))
+	for c := range values {
+		ret = append(ret, DescribeClaim(c))
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Value < ret[j].Value })
+
+	return ret
+}
+
+// TrustClaimValue describes a vendor-defined TrustClaim value
+type TrustClaimValue struct {
+	Value TrustClaim
+	// Tier is the trust tier the value belongs to.  It must agree with the
+	// AR4SI range the value falls in.
+	Tier TrustTier
+	// Claims lists the trustworthiness claims (e.g., "hardware") in which
+	// the value can be used.  If empty, the value applies to all of them.
+	Claims []string
+
+	ClaimDescription
+}
+
+// RegisterTrustClaimValue adds a vendor-defined value to the TrustClaim
+// tables of the default Registry, so that it is accepted (including by its
+// tag) when decoding, and described by Report and DescribeClaim like the
+// AR4SI-defined ones.  Values in the "none" tier (-1 to 1) are reserved, and
+// already defined values cannot be redefined.  Registration is safe for
+// concurrent use, though values are best registered at initialization time,
+// before results using them are decoded.
+func RegisterTrustClaimValue(v TrustClaimValue) error {
+	return defaultRegistry.registerTrustClaimValue(v)
+}
+
+func (o *Registry) registerTrustClaimValue(v TrustClaimValue) error {
+	if v.Tier == TrustTierNone || v.Value.IsNone() {
+		return fmt.Errorf("value %d: the none tier is reserved", v.Value)
+	}
+
+	if actual := v.Value.GetTier(); actual != v.Tier {
+		return fmt.Errorf("value %d: belongs to the %s tier, not %s", v.Value, actual, v.Tier)
+	}
+
+	if v.Tag == "" || v.Short == "" || v.Long == "" {
+		return fmt.Errorf("value %d: tag, short and long descriptions are mandatory", v.Value)
+	}
+
+	builtin := claimDetails()
+
+	claims := v.Claims
+	if len(claims) == 0 {
+		for name := range builtin {
+			claims = append(claims, name)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, name := range claims {
+		if _, ok := builtin[name]; !ok {
+			return fmt.Errorf("value %d: unknown trustworthiness claim %q", v.Value, name)
+		}
+
+		if _, ok := o.lookupClaimValue(name, v.Value); ok {
+			return fmt.Errorf("value %d: already defined for %q", v.Value, name)
+		}
+	}
+
+	// tags are looked up across all claims, so they must identify a single
+	// value
+	if c, ok := o.lookupClaimTag(v.Tag); ok && c != v.Value {
+		return fmt.Errorf("value %d: tag %q already used by value %d", v.Value, v.Tag, c)
+	}
+
+	if o.claimValues == nil {
+		o.claimValues = map[string]detailsMap{}
+	}
+
+	for _, name := range claims {
+		if o.claimValues[name] == nil {
+			o.claimValues[name] = detailsMap{}
+		}
+		o.claimValues[name][v.Value] = details{tag: v.Tag, short: v.Short, long: v.Long}
+	}
+
+	return nil
+}
+
+// claimValue returns the details of the value c in the named trustworthiness
+// claim, whether defined by AR4SI or registered
+func (o *Registry) claimValue(name string, c TrustClaim) (details, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.lookupClaimValue(name, c)
+}
+
+// claimTag returns the value, defined by AR4SI or registered, with the
+// supplied tag
+func (o *Registry) claimTag(tag string) (TrustClaim, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.lookupClaimTag(tag)
+}
+
+// lookupClaimValue is claimValue, for callers holding the lock
+func (o *Registry) lookupClaimValue(name string, c TrustClaim) (details, bool) {
+	if d, ok := noneDetails[c]; ok {
+		return d, true
+	}
+
+	if d, ok := claimDetails()[name][c]; ok {
+		return d, true
+	}
+
+	d, ok := o.claimValues[name][c]
+
+	return d, ok
+}
+
+// lookupClaimTag is claimTag, for callers holding the lock
+func (o *Registry) lookupClaimTag(tag string) (TrustClaim, bool) {
+	dms := []detailsMap{noneDetails}
+	for _, dm := range claimDetails() {
+		dms = append(dms, dm)
+	}
+	for _, dm := range o.claimValues {
+		dms = append(dms, dm)
+	}
+
+	for _, dm := range dms {
+		for c, d := range dm {
+			if d.tag == tag {
+				return c, true
+			}
+		}
+	}
+
+	return NoClaim, false
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeClaim_none_tier(t *testing.T) {
+	info := DescribeClaim(VerifierMalfunctionClaim)
+
+	assert.Equal(t, TrustTierNone, info.Tier)
+	assert.Len(t, info.Descriptions, 8)
+
+	d, ok := info.Describe("hardware")
+	require.True(t, ok)
+	assert.Equal(t, "verifier_malfunction", d.Tag)
+	assert.Equal(t, "verifier malfunction", d.Short)
+}
+
+func TestDescribeClaim_per_claim(t *testing.T) {
+	info := DescribeClaim(TrustClaim(2))
+
+	assert.Equal(t, TrustTierAffirming, info.Tier)
+
+	d, ok := info.Describe("instance-identity")
+	require.True(t, ok)
+	assert.Equal(t, "recognized_instance", d.Tag)
+
+	d, ok = info.Describe("executables")
+	require.True(t, ok)
+	assert.Equal(t, "approved_rt", d.Tag)
+
+	_, ok = info.Describe("no-such-claim")
+	assert.False(t, ok)
+}
+
+func TestDescribeClaim_undefined(t *testing.T) {
+	info := DescribeClaim(TrustClaim(20))
+
+	assert.Equal(t, TrustTierAffirming, info.Tier)
+	assert.Empty(t, info.Descriptions)
+}
+
+func TestKnownClaims(t *testing.T) {
+	claims := KnownClaims()
+	require.NotEmpty(t, claims)
+
+	for i := 1; i < len(claims); i++ {
+		assert.Less(t, claims[i-1].Value, claims[i].Value)
+	}
+
+	for _, c := range claims {
+		assert.NotEmpty(t, c.Descriptions, "value %d", c.Value)
+	}
+}
+
+func TestRegisterTrustClaimValue_ok(t *testing.T) {
+	v := TrustClaimValue{
+		Value:  TrustClaim(25),
+		Tier:   TrustTierAffirming,
+		Claims: []string{"executables"},
+		ClaimDescription: ClaimDescription{
+			Tag:   "vendor_measured_boot",
+			Short: "vendor-specific measured boot",
+			Long:  "All executables were loaded through the vendor's measured boot chain.",
+		},
+	}
+
+	require.NoError(t, RegisterTrustClaimValue(v))
+	t.Cleanup(func() { testUnregisterTrustClaimValues(v.Value) })
+
+	c, err := ToTrustClaim("vendor_measured_boot")
+	require.NoError(t, err)
+	assert.Equal(t, v.Value, *c)
+
+	info := DescribeClaim(v.Value)
+	assert.Equal(t, TrustTierAffirming, info.Tier)
+	assert.Equal(t, map[string]ClaimDescription{"executables": v.ClaimDescription}, info.Descriptions)
+
+	tv := TrustVector{Executables: v.Value}
+	assert.Contains(t, tv.Report(true, false), "vendor-specific measured boot")
+
+	// a second registration of the same value is refused
+	assert.EqualError(t, RegisterTrustClaimValue(v), `value 25: already defined for "executables"`)
+}
+
+func TestRegisterTrustClaimValue_concurrent(t *testing.T) {
+	values := []TrustClaim{20, 21, 22, 23}
+	t.Cleanup(func() { testUnregisterTrustClaimValues(values...) })
+
+	var wg sync.WaitGroup
+
+	for _, c := range values {
+		wg.Add(2)
+
+		go func(c TrustClaim) {
+			defer wg.Done()
+
+			tag := fmt.Sprintf("vendor_value_%d", c)
+			assert.NoError(t, RegisterTrustClaimValue(TrustClaimValue{
+				Value:            c,
+				Tier:             TrustTierAffirming,
+				ClaimDescription: ClaimDescription{Tag: tag, Short: tag, Long: tag},
+			}))
+		}(c)
+
+		go func(c TrustClaim) {
+			defer wg.Done()
+
+			_ = DescribeClaim(c)
+			_, _ = ToTrustClaim(fmt.Sprintf("vendor_value_%d", c))
+			_ = TrustVector{Hardware: c}.Report(true, false)
+		}(c)
+	}
+
+	wg.Wait()
+
+	for _, c := range values {
+		actual, err := ToTrustClaim(fmt.Sprintf("vendor_value_%d", c))
+		require.NoError(t, err)
+		assert.Equal(t, c, *actual)
+		assert.Len(t, DescribeClaim(c).Descriptions, 8)
+	}
+}
+
+// testUnregisterTrustClaimValues removes the supplied values from the default
+// Registry
+func testUnregisterTrustClaimValues(values ...TrustClaim) {
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	for _, dm := range defaultRegistry.claimValues {
+		for _, c := range values {
+			delete(dm, c)
+		}
+	}
+}
+
+func TestRegisterTrustClaimValue_fail(t *testing.T) {
+	desc := ClaimDescription{Tag: "vendor_x", Short: "x", Long: "x"}
+
+	tvs := []struct {
+		v        TrustClaimValue
+		expected string
+	}{
+		{
+			v:        TrustClaimValue{Value: 1, Tier: TrustTierNone, ClaimDescription: desc},
+			expected: "value 1: the none tier is reserved",
+		},
+		{
+			v:        TrustClaimValue{Value: 40, Tier: TrustTierAffirming, ClaimDescription: desc},
+			expected: "value 40: belongs to the warning tier, not affirming",
+		},
+		{
+			v:        TrustClaimValue{Value: 25, Tier: TrustTierAffirming},
+			expected: "value 25: tag, short and long descriptions are mandatory",
+		},
+		{
+			v:        TrustClaimValue{Value: 25, Tier: TrustTierAffirming, Claims: []string{"firmware"}, ClaimDescription: desc},
+			expected: `value 25: unknown trustworthiness claim "firmware"`,
+		},
+		{
+			v:        TrustClaimValue{Value: 2, Tier: TrustTierAffirming, Claims: []string{"hardware"}, ClaimDescription: desc},
+			expected: `value 2: already defined for "hardware"`,
+		},
+		{
+			v: TrustClaimValue{
+				Value:            25,
+				Tier:             TrustTierAffirming,
+				ClaimDescription: ClaimDescription{Tag: "approved_rt", Short: "x", Long: "x"},
+			},
+			expected: `value 25: tag "approved_rt" already used by value 2`,
+		},
+	}
+
+	for i, tv := range tvs {
+		err := RegisterTrustClaimValue(tv.v)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
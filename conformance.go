@@ -0,0 +1,198 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//go:embed spec/examples.json
+var specExamplesJSON []byte
+
+// SpecExample is an example result from the AR4SI (draft-ietf-rats-ar4si) or
+// EAR (draft-fv-rats-ear) specifications, together with the behaviour they
+// mandate for it
+type SpecExample struct {
+	// Name identifies the example
+	Name string `json:"name"`
+	// Source is the specification the example is taken from
+	Source string `json:"source"`
+	// Description says what the example illustrates
+	Description string `json:"description"`
+	// Claims is the EAR claims-set, in JSON
+	Claims json.RawMessage `json:"claims"`
+	// Valid says whether Claims must be accepted
+	Valid bool `json:"valid"`
+	// DerivedStatus maps the submods of a valid example onto the status
+	// derived from their trust vectors by UpdateStatusFromTrustVector
+	DerivedStatus map[string]string `json:"derived-status,omitempty"`
+	// OverallStatus is the OverallStatus of a valid example
+	OverallStatus string `json:"overall-status,omitempty"`
+}
+
+// SpecExamples returns the corpus of specification examples embedded in the
+// package, which SpecConformance checks the package against
+func SpecExamples() ([]SpecExample, error) {
+	var examples []SpecExample
+
+	if err := json.Unmarshal(specExamplesJSON, &examples); err != nil {
+		return nil, fmt.Errorf("decoding specification examples: %w", err)
+	}
+
+	return examples, nil
+}
+
+// ConformanceCheck is the outcome of checking a SpecExample
+type ConformanceCheck struct {
+	Example string `json:"example"`
+	OK      bool   `json:"ok"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// ConformanceReport collects the outcomes of SpecConformance
+type ConformanceReport struct {
+	Checks []ConformanceCheck `json:"checks"`
+}
+
+// OK returns true if the package behaves as mandated for all the examples
+func (o ConformanceReport) OK() bool {
+	return o.Err() == nil
+}
+
+// Err returns an error summarising the failed checks, or nil if there is none
+func (o ConformanceReport) Err() error {
+	var failed []string
+
+	for _, c := range o.Checks {
+		if !c.OK {
+			failed = append(failed, fmt.Sprintf("%s: %s", c.Example, c.Detail))
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("specification conformance failed (%s)", strings.Join(failed, "; "))
+}
+
+// SpecConformance checks the behaviour of the package against the corpus of
+// specification examples returned by SpecExamples.  Each valid example must
+// be accepted, survive JSON and CBOR round trips unchanged, and yield the
+// expected statuses, while each invalid one must be rejected.  Applications
+// can run it, e.g., in their own test suites, to find out whether the version
+// of the package they build with has drifted from the specifications.
+func SpecConformance() *ConformanceReport {
+	var report ConformanceReport
+
+	examples, err := SpecExamples()
+	if err != nil {
+		report.Checks = append(report.Checks, ConformanceCheck{
+			Example: "*", Detail: err.Error(),
+		})
+		return &report
+	}
+
+	for _, e := range examples {
+		c := ConformanceCheck{Example: e.Name, OK: true}
+
+		if err := e.check(); err != nil {
+			c.OK = false
+			c.Detail = err.Error()
+		}
+
+		report.Checks = append(report.Checks, c)
+	}
+
+	return &report
+}
+
+func (o SpecExample) check() error {
+	var ar AttestationResult
+
+	err := ar.DecodeJSON(o.Claims)
+
+	if !o.Valid {
+		if err == nil {
+			return errors.New("invalid example accepted")
+		}
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("valid example rejected: %w", err)
+	}
+
+	if err := checkRoundTrips(ar); err != nil {
+		return err
+	}
+
+	if o.OverallStatus != "" {
+		if actual := ar.OverallStatus().String(); actual != o.OverallStatus {
+			return fmt.Errorf("overall status: expecting %s, found %s", o.OverallStatus, actual)
+		}
+	}
+
+	ar.UpdateStatusFromTrustVector()
+
+	actual := ar.StatusMap()
+
+	names := make([]string, 0, len(o.DerivedStatus))
+	for name := range o.DerivedStatus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tier, ok := actual[name]
+		if !ok {
+			return fmt.Errorf("derived status: submod %q not found", name)
+		}
+
+		if tier.String() != o.DerivedStatus[name] {
+			return fmt.Errorf("derived status of %q: expecting %s, found %s",
+				name, o.DerivedStatus[name], tier)
+		}
+	}
+
+	return nil
+}
+
+func checkRoundTrips(ar AttestationResult) error {
+	data, err := ar.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("encoding to JSON: %w", err)
+	}
+
+	var fromJSON AttestationResult
+	if err := fromJSON.UnmarshalJSON(data); err != nil {
+		return fmt.Errorf("decoding re-encoded JSON: %w", err)
+	}
+
+	if !reflect.DeepEqual(ar, fromJSON) {
+		return errors.New("JSON round trip is lossy")
+	}
+
+	data, err = ar.MarshalCBOR()
+	if err != nil {
+		return fmt.Errorf("encoding to CBOR: %w", err)
+	}
+
+	var fromCBOR AttestationResult
+	if err := fromCBOR.UnmarshalCBOR(data); err != nil {
+		return fmt.Errorf("decoding CBOR: %w", err)
+	}
+
+	if !reflect.DeepEqual(ar, fromCBOR) {
+		return errors.New("CBOR round trip is lossy")
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecConformance(t *testing.T) {
+	report := SpecConformance()
+
+	require.NoError(t, report.Err())
+	assert.True(t, report.OK())
+
+	examples, err := SpecExamples()
+	require.NoError(t, err)
+	assert.Len(t, report.Checks, len(examples))
+}
+
+func TestSpecExamples_well_formed(t *testing.T) {
+	examples, err := SpecExamples()
+	require.NoError(t, err)
+
+	names := map[string]bool{}
+
+	for _, e := range examples {
+		assert.False(t, names[e.Name], "duplicate example %q", e.Name)
+		names[e.Name] = true
+
+		assert.NotEmpty(t, e.Source, e.Name)
+		assert.NotEmpty(t, e.Description, e.Name)
+
+		if e.Valid {
+			assert.NotEmpty(t, e.OverallStatus, e.Name)
+		} else {
+			assert.Empty(t, e.DerivedStatus, e.Name)
+		}
+	}
+}
+
+func TestSpecExample_check_drift(t *testing.T) {
+	claims := json.RawMessage(testMiniClaimsSetJSON())
+
+	tvs := []struct {
+		example  SpecExample
+		expected string
+	}{
+		{
+			example:  SpecExample{Claims: claims, Valid: false},
+			expected: "invalid example accepted",
+		},
+		{
+			example:  SpecExample{Claims: json.RawMessage(`{}`), Valid: true},
+			expected: "valid example rejected: missing mandatory 'eat_profile', 'ear.verifier-id', 'iat', 'submods'",
+		},
+		{
+			example:  SpecExample{Claims: claims, Valid: true, OverallStatus: "warning"},
+			expected: "overall status: expecting warning, found affirming",
+		},
+		{
+			example:  SpecExample{Claims: claims, Valid: true, DerivedStatus: map[string]string{"gpu": "affirming"}},
+			expected: `derived status: submod "gpu" not found`,
+		},
+		{
+			example:  SpecExample{Claims: claims, Valid: true, DerivedStatus: map[string]string{"test": "warning"}},
+			expected: `derived status of "test": expecting warning, found affirming`,
+		},
+	}
+
+	for i, tv := range tvs {
+		err := tv.example.check()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestConformanceReport_Err(t *testing.T) {
+	report := ConformanceReport{Checks: []ConformanceCheck{
+		{Example: "a", OK: true},
+		{Example: "b", Detail: "invalid example accepted"},
+	}}
+
+	assert.False(t, report.OK())
+	assert.EqualError(t, report.Err(), "specification conformance failed (b: invalid example accepted)")
+}
+
+func testMiniClaimsSetJSON() []byte {
+	return []byte(`{
+		"eat_profile": "tag:github.com,2023:veraison/ear",
+		"iat": 1666091373,
+		"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."},
+		"submods": {"test": {"ear.status": "affirming"}}
+	}`)
+}
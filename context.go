@@ -0,0 +1,24 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import "context"
+
+// contextKey is unexported so that the key used by NewContext cannot collide
+// with keys defined in other packages
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the supplied (verified)
+// AttestationResult, so that it can be handed down a chain of request
+// handlers.  Use FromContext to retrieve it.
+func NewContext(ctx context.Context, ar *AttestationResult) context.Context {
+	return context.WithValue(ctx, contextKey{}, ar)
+}
+
+// FromContext returns the AttestationResult stored in ctx by NewContext, if
+// any
+func FromContext(ctx context.Context) (*AttestationResult, bool) {
+	ar, ok := ctx.Value(contextKey{}).(*AttestationResult)
+	return ar, ok && ar != nil
+}
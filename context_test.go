@@ -0,0 +1,29 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext_roundtrip(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+
+	ctx := NewContext(context.Background(), &ar)
+
+	actual, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Same(t, &ar, actual)
+}
+
+func TestContext_missing(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = FromContext(NewContext(context.Background(), nil))
+	assert.False(t, ok)
+}
@@ -0,0 +1,655 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fxamacker/cbor/v2"
+	cose "github.com/veraison/go-cose"
+)
+
+// CBOR claim keys, as per EAT (draft-ietf-rats-eat) and the EAR CBOR
+// serialization (draft-fv-rats-ear).  Claims that do not have a registered key
+// are serialized using their JSON name as a text key.
+var (
+	cwtResultKeys = map[string]int64{
+		"exp":                   4,
+		"nbf":                   5,
+		"iat":                   6,
+		"eat_nonce":             10,
+		"eat_profile":           265,
+		"submods":               266,
+		"ear.raw-evidence":      1002,
+		"ear.verifier-id":       1004,
+		"ear.veraison.tee-info": -70003,
+		"ear.nae.tts-info":      -70004,
+
+		"ear.veraison.migration":        -70006,
+		"ear.veraison.encrypted-claims": -70014,
+		"ear.veraison.evidence-ref":     -70015,
+		"ear.veraison.timestamp":        -70016,
+	}
+
+	cwtAppraisalKeys = map[string]int64{
+		"ear.status":                      1000,
+		"ear.trustworthiness-vector":      1001,
+		"ear.appraisal-policy-id":         1003,
+		"ear.veraison.annotated-evidence": -70000,
+		"ear.veraison.policy-claims":      -70001,
+		"ear.veraison.key-attestation":    -70002,
+
+		"ear.veraison.annotated-evidence-digest": -70005,
+		"ear.veraison.appraisal-qos":             -70007,
+		"ear.veraison.claim-provenance":          -70008,
+		"ear.veraison.cca-info":                  -70010,
+		"ear.veraison.tdx-info":                  -70011,
+		"ear.veraison.sgx-info":                  -70012,
+		"ear.veraison.snp-info":                  -70013,
+	}
+
+	cwtTrustVectorKeys = map[string]int64{
+		"instance-identity": 0,
+		"configuration":     1,
+		"executables":       2,
+		"file-system":       3,
+		"hardware":          4,
+		"runtime-opaque":    5,
+		"storage-opaque":    6,
+		"sourced-data":      7,
+	}
+
+	cwtVerifierIDKeys = map[string]int64{
+		"developer": 0,
+		"build":     1,
+	}
+)
+
+// MarshalCBOR validates and serializes to CBOR an AttestationResult object,
+// using the EAT integer keys for registered claims.
+func (o AttestationResult) MarshalCBOR() ([]byte, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	m, err := o.asCBORMap()
+	if err != nil {
+		return nil, err
+	}
+
+	em, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+
+	return em.Marshal(m)
+}
+
+// UnmarshalCBOR de-serializes an AttestationResult object from its CBOR
+// representation and validates it.
+func (o *AttestationResult) UnmarshalCBOR(data []byte) error {
+	return o.DecodeCBOR(data)
+}
+
+// DecodeCBOR is like UnmarshalCBOR, but allows the decoding to be configured
+// using DecodeOptions.
+func (o *AttestationResult) DecodeCBOR(data []byte, opts ...DecodeOption) error {
+	m, err := cborClaimsMap(data)
+	if err != nil {
+		return err
+	}
+
+	if err := o.decodeMap(m, newDecodeOptions(opts)); err != nil {
+		return err
+	}
+
+	return o.validate()
+}
+
+// cborClaimsMap decodes a CBOR claims-set into the JSON-keyed form expected
+// by decodeMap
+func cborClaimsMap(data []byte) (map[string]interface{}, error) {
+	// unlike JSON, CBOR decoding always rejects duplicate map keys, which
+	// RFC8949 deems invalid
+	dm, err := cbor.DecOptions{DupMapKey: cbor.DupMapKeyEnforcedAPF}.DecMode()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[interface{}]interface{}
+	if err := dm.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	return fromCBORClaimsSet(raw)
+}
+
+// fromCBORClaimsSet turns a CBOR-keyed claims-set into the JSON-keyed form
+// expected by decodeMap
+func fromCBORClaimsSet(raw map[interface{}]interface{}) (map[string]interface{}, error) {
+	return fromCBORMap(raw, cwtResultKeys, map[string]cborConverter{
+		"ear.verifier-id": func(v interface{}) (interface{}, error) {
+			return fromCBORValue(v, cwtVerifierIDKeys)
+		},
+		"eat_profile": func(v interface{}) (interface{}, error) {
+			if b, ok := v.([]byte); ok {
+				return oidFromBytes(b)
+			}
+			return v, nil
+		},
+		"submods": func(v interface{}) (interface{}, error) {
+			return fromCBORSubmods(v)
+		},
+	})
+}
+
+// SignCWT validates the AttestationResult object, encodes it to CBOR and wraps
+// it in a COSE_Sign1 envelope using the supplied signer.  The signing
+// algorithm is taken from the signer and recorded in the protected header.
+// Hooks supplied via WithBeforeSign and WithAfterSign are run as they are by
+// Sign.  WithTimestampAuthority adds an RFC3161 time-stamp for the claims-set,
+// as it does for Sign.  On success, the complete COSE_Sign1 message is
+// returned.
+func (o AttestationResult) SignCWT(signer cose.Signer, opts ...SignOption) ([]byte, error) {
+	so := newSignOptions(opts)
+
+	if err := o.prepareForSigning(so); err != nil {
+		return nil, err
+	}
+
+	if so.tsa != nil {
+		if err := o.addTimestamp(so.tsa); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := o.MarshalCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("encoding CBOR claims-set: %w", err)
+	}
+
+	headers := cose.Headers{
+		Protected: cose.ProtectedHeader{
+			cose.HeaderLabelAlgorithm: signer.Algorithm(),
+		},
+	}
+
+	signed, err := cose.Sign1(rand.Reader, signer, headers, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := so.signed(&o); err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+// VerifyCWT cryptographically verifies the COSE_Sign1 data using the supplied
+// verifier.  The payload is then decoded and validated.  On success, the
+// target AttestationResult object is populated with the decoded claims.  Hooks
+// supplied via WithAfterVerify, a sink supplied via WithQuarantine and a
+// FrozenResult supplied via WithFrozenResult are honoured as they are by
+// Verify, and so are the exp, nbf, maximum age and maximum size checks, and
+// the time-stamp checks requested with WithTimestampVerifier and the algorithm
+// checks requested with WithStrictAlgorithm and WithAllowedAlgorithms.
+// Verification reports are currently only available for JWT.
+func (o *AttestationResult) VerifyCWT(
+	data []byte,
+	verifier cose.Verifier,
+	opts ...VerifyOption,
+) error {
+	vo := newVerifyOptions(opts)
+
+	if vo.report != nil {
+		return errors.New("verification report is not supported for CWT")
+	}
+
+	if err := vo.checkTokenSize(data); err != nil {
+		return err
+	}
+
+	var msg cose.Sign1Message
+
+	err := msg.UnmarshalCBOR(data)
+	if err == nil {
+		err = vo.checkCOSEAlgorithm(msg, verifier.Algorithm())
+	}
+	if err == nil {
+		err = msg.Verify(nil, verifier)
+	}
+
+	if err != nil {
+		return vo.verificationFailed(data, verifier.Algorithm().String(), "COSE_Sign1 message", err)
+	}
+
+	m, err := cborClaimsMap(msg.Payload)
+	if err != nil {
+		return fmt.Errorf("decoding CBOR claims-set: %w", err)
+	}
+
+	do := newDecodeOptions(vo.decode)
+
+	// the time-stamp covers the JSON form of the claims-set, as signed
+	var claimsSet []byte
+
+	if vo.tsv != nil {
+		if claimsSet, err = cwtTimestampedClaimsSet(msg.Payload, do); err != nil {
+			return fmt.Errorf("decoding CBOR claims-set: %w", err)
+		}
+	}
+
+	if err := o.decodeVerified(m, do, vo); err != nil {
+		return fmt.Errorf("decoding CBOR claims-set: %w", err)
+	}
+
+	_, err = o.finishVerification(vo, claimsSet)
+
+	return err
+}
+
+// cwtTimestampedClaimsSet returns the JSON claims-set from which SignCWT has
+// obtained the time-stamp of a CWT payload, i.e., the one decoded as is,
+// before any normalization or decryption requested by the relying party
+func cwtTimestampedClaimsSet(payload []byte, do *decodeOptions) ([]byte, error) {
+	m, err := cborClaimsMap(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var ar AttestationResult
+
+	if err := ar.decodeMap(m, &decodeOptions{registry: do.registry, unknown: UnknownClaimsPreserve}); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(ar.AsMap())
+}
+
+// checkCOSEAlgorithm checks the alg in the protected header of the
+// COSE_Sign1 message against the caller's expectations, if any
+func (o verifyOptions) checkCOSEAlgorithm(msg cose.Sign1Message, expected cose.Algorithm) error {
+	if !o.strictAlg && len(o.algs) == 0 {
+		return nil
+	}
+
+	received, err := msg.Headers.Protected.Algorithm()
+	if err != nil {
+		return err
+	}
+
+	return o.checkAlgorithmName(received.String(), expected.String())
+}
+
+// asCBORMap returns the claims-set as a map keyed by CBOR claim keys.  The
+// JSON serialization is used as the starting point so that both encodings
+// share the same claim semantics.
+func (o AttestationResult) asCBORMap() (map[interface{}]interface{}, error) {
+	j, err := json.Marshal(o.AsMap())
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	return toCBORMap(m, cwtResultKeys, map[string]cborConverter{
+		"ear.verifier-id": func(v interface{}) (interface{}, error) {
+			return toCBORValue(v, cwtVerifierIDKeys)
+		},
+		"ear.raw-evidence": func(v interface{}) (interface{}, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("not a base64 string")
+			}
+			return base64.RawURLEncoding.DecodeString(s)
+		},
+		"eat_profile": func(v interface{}) (interface{}, error) {
+			if p, ok := v.(string); ok && Profile(p).IsOID() {
+				return oidToBytes(p)
+			}
+			return v, nil
+		},
+		"ear.veraison.timestamp": toCBORBytes,
+		"submods":                toCBORSubmods,
+	})
+}
+
+type cborConverter func(interface{}) (interface{}, error)
+
+// toCBORKeyAttestation encodes the COSE_Key of an attested key as a byte string
+func toCBORKeyAttestation(v interface{}) (interface{}, error) {
+	ak, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a map object")
+	}
+
+	return toCBORMap(ak, nil, map[string]cborConverter{
+		"cose-key": toCBORBytes,
+	})
+}
+
+// toCBORCCAInfo encodes the CCA realm measurements as byte strings
+func toCBORCCAInfo(v interface{}) (interface{}, error) {
+	info, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a map object")
+	}
+
+	return toCBORMap(info, nil, map[string]cborConverter{
+		"rim": toCBORBytes,
+		"rem": toCBORBytesArray,
+	})
+}
+
+// toCBORTDXInfo encodes the TDX measurements as byte strings
+func toCBORTDXInfo(v interface{}) (interface{}, error) {
+	info, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a map object")
+	}
+
+	return toCBORMap(info, nil, map[string]cborConverter{
+		"mrtd":  toCBORBytes,
+		"rtmrs": toCBORBytesArray,
+	})
+}
+
+// toCBORSGXInfo encodes the SGX measurements as byte strings
+func toCBORSGXInfo(v interface{}) (interface{}, error) {
+	info, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a map object")
+	}
+
+	return toCBORMap(info, nil, map[string]cborConverter{
+		"mrenclave": toCBORBytes,
+		"mrsigner":  toCBORBytes,
+	})
+}
+
+// toCBORSNPInfo encodes the SEV-SNP measurement and report ID as byte strings
+func toCBORSNPInfo(v interface{}) (interface{}, error) {
+	info, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a map object")
+	}
+
+	return toCBORMap(info, nil, map[string]cborConverter{
+		"measurement": toCBORBytes,
+		"report-id":   toCBORBytes,
+	})
+}
+
+// toCBORBytesArray turns an array of base64url strings into one of byte
+// strings
+func toCBORBytesArray(v interface{}) (interface{}, error) {
+	a, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("not an array")
+	}
+
+	ret := make([]interface{}, len(a))
+	for i, e := range a {
+		b, err := toCBORBytes(e)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		ret[i] = b
+	}
+
+	return ret, nil
+}
+
+// toCBORBytes turns a base64url string from the JSON form into a byte string
+func toCBORBytes(v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("not a base64 string")
+	}
+
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func toCBORSubmods(v interface{}) (interface{}, error) {
+	submods, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not a map object")
+	}
+
+	ret := map[interface{}]interface{}{}
+
+	for name, val := range submods {
+		appraisal, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: not a map object", name)
+		}
+
+		m, err := toCBORMap(appraisal, cwtAppraisalKeys, map[string]cborConverter{
+			"ear.status": func(v interface{}) (interface{}, error) {
+				tier, err := ToTrustTier(v)
+				if err != nil {
+					return nil, err
+				}
+				return int64(*tier), nil
+			},
+			"ear.trustworthiness-vector": func(v interface{}) (interface{}, error) {
+				return toCBORValue(v, cwtTrustVectorKeys)
+			},
+			"ear.veraison.key-attestation": toCBORKeyAttestation,
+			"ear.veraison.cca-info":        toCBORCCAInfo,
+			"ear.veraison.tdx-info":        toCBORTDXInfo,
+			"ear.veraison.sgx-info":        toCBORSGXInfo,
+			"ear.veraison.snp-info":        toCBORSNPInfo,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		ret[name] = m
+	}
+
+	return ret, nil
+}
+
+func toCBORMap(
+	m map[string]interface{},
+	keys map[string]int64,
+	converters map[string]cborConverter,
+) (map[interface{}]interface{}, error) {
+	ret := make(map[interface{}]interface{}, len(m))
+
+	for name, val := range m {
+		var (
+			cVal interface{}
+			err  error
+		)
+
+		if conv, ok := converters[name]; ok {
+			cVal, err = conv(val)
+		} else {
+			cVal, err = toCBORValue(val, nil)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if key, ok := keys[name]; ok {
+			ret[key] = cVal
+		} else {
+			ret[name] = cVal
+		}
+	}
+
+	return ret, nil
+}
+
+// toCBORValue converts a value decoded from JSON into its CBOR counterpart.
+// If keys is not nil, it is used to map the names of the entries of a JSON
+// object to CBOR integer keys.
+func toCBORValue(v interface{}, keys map[string]int64) (interface{}, error) {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	case map[string]interface{}:
+		return toCBORMap(t, keys, nil)
+	case []interface{}:
+		ret := make([]interface{}, len(t))
+		for i, e := range t {
+			c, err := toCBORValue(e, nil)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			ret[i] = c
+		}
+		return ret, nil
+	default:
+		return v, nil
+	}
+}
+
+func fromCBORSubmods(v interface{}) (interface{}, error) {
+	submods, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("not a map object")
+	}
+
+	ret := map[string]interface{}{}
+
+	for k, val := range submods {
+		name, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("submod name must be a string, found %T", k)
+		}
+
+		appraisal, ok := val.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: not a map object", name)
+		}
+
+		m, err := fromCBORMap(appraisal, cwtAppraisalKeys, map[string]cborConverter{
+			"ear.trustworthiness-vector": func(v interface{}) (interface{}, error) {
+				return fromCBORValue(v, cwtTrustVectorKeys)
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		ret[name] = m
+	}
+
+	return ret, nil
+}
+
+func fromCBORMap(
+	m map[interface{}]interface{},
+	keys map[string]int64,
+	converters map[string]cborConverter,
+) (map[string]interface{}, error) {
+	names := make(map[int64]string, len(keys))
+	for name, key := range keys {
+		names[key] = name
+	}
+
+	ret := make(map[string]interface{}, len(m))
+
+	for k, val := range m {
+		var name string
+
+		switch t := k.(type) {
+		case string:
+			name = t
+		case int64, uint64:
+			i, err := cborInt(t)
+			if err != nil {
+				return nil, err
+			}
+
+			n, ok := names[i]
+			if !ok {
+				return nil, fmt.Errorf("unexpected claim key %d", i)
+			}
+			name = n
+		default:
+			return nil, fmt.Errorf("unexpected claim key type %T", k)
+		}
+
+		var (
+			jVal interface{}
+			err  error
+		)
+
+		if conv, ok := converters[name]; ok {
+			jVal, err = conv(val)
+		} else {
+			jVal, err = fromCBORValue(val, nil)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		ret[name] = jVal
+	}
+
+	return ret, nil
+}
+
+// fromCBORValue converts a decoded CBOR value into the form expected by the
+// JSON parsers.  Byte strings are base64url-encoded as per EAT §7.2.2.  If
+// keys is not nil, it is used to map the integer keys of a CBOR map to names.
+func fromCBORValue(v interface{}, keys map[string]int64) (interface{}, error) {
+	switch t := v.(type) {
+	case uint64:
+		return cborInt(t)
+	case []byte:
+		return base64.RawURLEncoding.EncodeToString(t), nil
+	case map[interface{}]interface{}:
+		return fromCBORMap(t, keys, nil)
+	case []interface{}:
+		ret := make([]interface{}, len(t))
+		for i, e := range t {
+			j, err := fromCBORValue(e, nil)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			ret[i] = j
+		}
+		return ret, nil
+	default:
+		return v, nil
+	}
+}
+
+func cborInt(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case int64:
+		return t, nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, fmt.Errorf("integer overflow: %d", t)
+		}
+		return int64(t), nil
+	default:
+		return 0, fmt.Errorf("expecting integer, found %T", v)
+	}
+}
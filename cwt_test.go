@@ -0,0 +1,245 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+func testCOSESignerVerifier(t *testing.T) (cose.Signer, cose.Verifier) {
+	k, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	var skey ecdsa.PrivateKey
+	require.NoError(t, k.Raw(&skey))
+
+	signer, err := cose.NewSigner(cose.AlgorithmES256, &skey)
+	require.NoError(t, err)
+
+	verifier, err := cose.NewVerifier(cose.AlgorithmES256, &skey.PublicKey)
+	require.NoError(t, err)
+
+	return signer, verifier
+}
+
+func TestMarshalCBOR_claim_keys(t *testing.T) {
+	rawEvidence := B64Url(testEvidence)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.RawEvidence = &rawEvidence
+	ar.Submods = map[string]*Appraisal{
+		"test": {
+			Status: &testStatus,
+			TrustVector: &TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+				Executables:      ApprovedRuntimeClaim,
+			},
+		},
+	}
+
+	data, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	var m map[int64]interface{}
+	require.NoError(t, cbor.Unmarshal(data, &m))
+
+	assert.Equal(t, EatProfile, m[265])
+	assert.Equal(t, uint64(testIAT), m[6])
+	assert.Equal(t, testEvidence, m[1002])
+	assert.Equal(t, map[interface{}]interface{}{
+		uint64(0): testVidDeveloper,
+		uint64(1): testVidBuild,
+	}, m[1004])
+
+	submods, ok := m[266].(map[interface{}]interface{})
+	require.True(t, ok)
+
+	appraisal, ok := submods["test"].(map[interface{}]interface{})
+	require.True(t, ok)
+
+	assert.Equal(t, uint64(TrustTierAffirming), appraisal[uint64(1000)])
+	assert.Equal(t, map[interface{}]interface{}{
+		uint64(0): uint64(TrustworthyInstanceClaim),
+		uint64(1): uint64(NoClaim),
+		uint64(2): uint64(ApprovedRuntimeClaim),
+		uint64(3): uint64(NoClaim),
+		uint64(4): uint64(NoClaim),
+		uint64(5): uint64(NoClaim),
+		uint64(6): uint64(NoClaim),
+		uint64(7): uint64(NoClaim),
+	}, appraisal[uint64(1001)])
+}
+
+func TestCBOR_RoundTrip(t *testing.T) {
+	rawEvidence := B64Url(testEvidence)
+
+	expected := testAttestationResultsWithVeraisonExtns
+	expected.RawEvidence = &rawEvidence
+
+	data, err := expected.MarshalCBOR()
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	err = actual.UnmarshalCBOR(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestUnmarshalCBOR_fail(t *testing.T) {
+	tvs := []struct {
+		data     map[interface{}]interface{}
+		expected string
+	}{
+		{
+			data:     map[interface{}]interface{}{99: "x"},
+			expected: "unexpected claim key 99",
+		},
+		{
+			data:     map[interface{}]interface{}{266: "x"},
+			expected: "submods: not a map object",
+		},
+		{
+			data: map[interface{}]interface{}{
+				265: EatProfile,
+				6:   testIAT,
+			},
+			expected: "missing mandatory 'ear.verifier-id', 'submods'",
+		},
+	}
+
+	for i, tv := range tvs {
+		data, err := cbor.Marshal(tv.data)
+		require.NoError(t, err)
+
+		var ar AttestationResult
+		err = ar.UnmarshalCBOR(data)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestCWT_RoundTrip_pass(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.VerifyCWT(token, verifier)
+	require.NoError(t, err)
+
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual)
+}
+
+func TestCWT_RoundTrip_tampering(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	token[len(token)-1] ^= 1
+
+	var quarantined []QuarantineRecord
+
+	sink := QuarantineSinkFunc(func(r QuarantineRecord) error {
+		quarantined = append(quarantined, r)
+		return nil
+	})
+
+	var actual AttestationResult
+
+	err = actual.VerifyCWT(token, verifier, WithQuarantine(sink))
+	assert.ErrorContains(t, err, "failed verifying COSE_Sign1 message")
+
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, "ES256", quarantined[0].Algorithm)
+}
+
+func TestSignCWT_fail(t *testing.T) {
+	signer, _ := testCOSESignerVerifier(t)
+
+	var ar AttestationResult
+
+	_, err := ar.SignCWT(signer)
+	assert.ErrorContains(t, err, "missing mandatory 'eat_profile', 'iat', 'verifier-id'")
+}
+
+func TestVerifyCWT_report_unsupported(t *testing.T) {
+	_, verifier := testCOSESignerVerifier(t)
+
+	var (
+		ar     AttestationResult
+		report VerificationReport
+	)
+
+	err := ar.VerifyCWT([]byte{}, verifier, WithVerificationReport(&report))
+	assert.EqualError(t, err, "verification report is not supported for CWT")
+}
+
+func TestVerifyCWT_exp_nbf(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	exp, nbf := testIAT+3600, testIAT+600
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Expiry = &exp
+	ar.NotBefore = &nbf
+
+	token, err := ar.SignCWT(signer)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		now      int64
+		skew     time.Duration
+		expected string
+	}{
+		{now: nbf + 60},
+		{now: nbf - 60, expected: "result is not yet valid (nbf: 1666091973)"},
+		{now: nbf - 60, skew: 2 * time.Minute},
+		{now: exp + 60, expected: "result has expired (exp: 1666094973)"},
+		{now: exp + 60, skew: 2 * time.Minute},
+	}
+
+	for i, tv := range tvs {
+		var actual AttestationResult
+
+		clock := func() time.Time { return time.Unix(tv.now, 0) }
+
+		err := actual.VerifyCWT(token, verifier,
+			WithClock(clock), WithAcceptableSkew(tv.skew))
+
+		if tv.expected == "" {
+			require.NoError(t, err, "failed test vector at index %d", i)
+			assert.Equal(t, ar, actual, "failed test vector at index %d", i)
+		} else {
+			assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestVerifyCWT_max_token_age(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	clock := func() time.Time { return time.Unix(testIAT+3600, 0) }
+
+	var actual AttestationResult
+
+	err = actual.VerifyCWT(token, verifier, WithClock(clock), WithMaxTokenAge(time.Hour))
+	require.NoError(t, err)
+
+	err = actual.VerifyCWT(token, verifier, WithClock(clock), WithMaxTokenAge(time.Minute))
+	assert.EqualError(t, err, "result is too old (iat: 1666091373, max age: 1m0s)")
+}
@@ -0,0 +1,475 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/x448/float16"
+)
+
+// diagSchema drives the annotation of CBOR map keys in ToDiag
+type diagSchema struct {
+	// names maps integer keys onto claim names
+	names map[int64]string
+	// values gives the schema of the value of a claim, by claim name
+	values map[string]*diagSchema
+	// each is the schema of every value in the map (e.g., for submods)
+	each *diagSchema
+}
+
+func newDiagSchema(keys map[string]int64, values map[string]*diagSchema) *diagSchema {
+	names := make(map[int64]string, len(keys))
+	for name, key := range keys {
+		names[key] = name
+	}
+
+	return &diagSchema{names: names, values: values}
+}
+
+var (
+	diagAppraisalSchema = newDiagSchema(cwtAppraisalKeys, map[string]*diagSchema{
+		"ear.trustworthiness-vector": newDiagSchema(cwtTrustVectorKeys, nil),
+	})
+
+	diagResultSchema = newDiagSchema(cwtResultKeys, map[string]*diagSchema{
+		"ear.verifier-id": newDiagSchema(cwtVerifierIDKeys, nil),
+		"submods":         {each: diagAppraisalSchema},
+	})
+
+	diagCOSEHeaderSchema = newDiagSchema(map[string]int64{
+		"alg":          1,
+		"crit":         2,
+		"content type": 3,
+		"kid":          4,
+	}, nil)
+)
+
+// COSE_Sign1 tag (RFC9052)
+const diagCOSESign1Tag = 18
+
+// ToDiag renders CBOR data in diagnostic notation (RFC8949, §8), annotating
+// the integer keys of a CBOR-encoded AttestationResult with the corresponding
+// claim names, e.g.:
+//
+//	{
+//	  / eat_profile / 265: "tag:github.com,2023:veraison/ear",
+//	  / iat / 6: 1666091373,
+//	  ...
+//	}
+//
+// data can be either a claims-set, as produced by MarshalCBOR, or a tagged
+// COSE_Sign1 message, as produced by SignCWT, in which case the protected
+// header and the payload are rendered as embedded CBOR.
+func ToDiag(data []byte) (string, error) {
+	d := diagDecoder{data: data}
+
+	var b strings.Builder
+
+	if err := d.item(&b, diagResultSchema, 0); err != nil {
+		return "", err
+	}
+
+	if d.off != len(d.data) {
+		return "", fmt.Errorf("%d trailing bytes", len(d.data)-d.off)
+	}
+
+	return b.String(), nil
+}
+
+// diagMaxNestedLevels bounds the nesting of arrays, maps and tags, like the
+// default MaxNestedLevels of the CBOR decoder used by UnmarshalCBOR.  Besides
+// the recursion, this bounds the indentation written for each map entry.
+const diagMaxNestedLevels = 32
+
+type diagDecoder struct {
+	data []byte
+	off  int
+
+	// nested is the number of arrays, maps and tags the next item is in,
+	// including those of the enclosing data, if embedded
+	nested int
+}
+
+var errDiagTruncated = errors.New("unexpected end of data")
+
+// head decodes the initial byte and argument of a data item.  For indefinite
+// length items, indef is set and arg is meaningless.
+func (o *diagDecoder) head() (major byte, info byte, arg uint64, indef bool, err error) {
+	if o.off >= len(o.data) {
+		return 0, 0, 0, false, errDiagTruncated
+	}
+
+	ib := o.data[o.off]
+	o.off++
+
+	major, info = ib>>5, ib&0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info <= 27:
+		n := 1 << (info - 24)
+		if o.off+n > len(o.data) {
+			return 0, 0, 0, false, errDiagTruncated
+		}
+
+		buf := make([]byte, 8)
+		copy(buf[8-n:], o.data[o.off:o.off+n])
+		o.off += n
+
+		return major, info, binary.BigEndian.Uint64(buf), false, nil
+	case info == 31 && major >= 2 && major <= 5:
+		return major, info, 0, true, nil
+	case info == 31 && major == 7:
+		return 0, 0, 0, false, errors.New("unexpected break")
+	default:
+		return 0, 0, 0, false, fmt.Errorf("malformed initial byte 0x%02x", ib)
+	}
+}
+
+func (o *diagDecoder) isBreak() bool {
+	if o.off < len(o.data) && o.data[o.off] == 0xff {
+		o.off++
+		return true
+	}
+	return false
+}
+
+func (o *diagDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(o.data)-o.off) {
+		return nil, errDiagTruncated
+	}
+
+	b := o.data[o.off : o.off+int(n)]
+	o.off += int(n)
+
+	return b, nil
+}
+
+// item renders the next data item.  schema, if not nil, is used to annotate
+// map keys.
+func (o *diagDecoder) item(b *strings.Builder, schema *diagSchema, depth int) error {
+	major, info, arg, indef, err := o.head()
+	if err != nil {
+		return err
+	}
+
+	if major >= 4 && major <= 6 {
+		if o.nested >= diagMaxNestedLevels {
+			return fmt.Errorf("exceeded max nested level %d", diagMaxNestedLevels)
+		}
+
+		o.nested++
+		defer func() { o.nested-- }()
+	}
+
+	switch major {
+	case 0:
+		b.WriteString(strconv.FormatUint(arg, 10))
+	case 1:
+		if arg == math.MaxUint64 {
+			b.WriteString("-18446744073709551616")
+		} else {
+			b.WriteString("-" + strconv.FormatUint(arg+1, 10))
+		}
+	case 2, 3:
+		return o.str(b, major, arg, indef)
+	case 4:
+		return o.array(b, arg, indef, depth)
+	case 5:
+		return o.kvs(b, schema, arg, indef, depth)
+	case 6:
+		return o.tag(b, arg, depth)
+	case 7:
+		return o.simple(b, info, arg)
+	}
+
+	return nil
+}
+
+func (o *diagDecoder) str(b *strings.Builder, major byte, n uint64, indef bool) error {
+	if indef {
+		b.WriteString("(_ ")
+		for i := 0; !o.isBreak(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+
+			m, _, arg, in, err := o.head()
+			if err != nil {
+				return err
+			}
+			if m != major || in {
+				return errors.New("malformed indefinite length string chunk")
+			}
+
+			if err := o.str(b, major, arg, false); err != nil {
+				return err
+			}
+		}
+		b.WriteString(")")
+		return nil
+	}
+
+	s, err := o.bytes(n)
+	if err != nil {
+		return err
+	}
+
+	if major == 2 {
+		b.WriteString("h'" + hex.EncodeToString(s) + "'")
+		return nil
+	}
+
+	q, err := json.Marshal(string(s))
+	if err != nil {
+		return err
+	}
+	b.Write(q)
+
+	return nil
+}
+
+func (o *diagDecoder) array(b *strings.Builder, n uint64, indef bool, depth int) error {
+	if indef {
+		b.WriteString("[_ ")
+	} else {
+		b.WriteString("[")
+	}
+
+	for i := uint64(0); indef && !o.isBreak() || !indef && i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := o.item(b, nil, depth); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("]")
+
+	return nil
+}
+
+func (o *diagDecoder) kvs(b *strings.Builder, schema *diagSchema, n uint64, indef bool, depth int) error {
+	indent := strings.Repeat("  ", depth+1)
+
+	if indef {
+		b.WriteString("{_")
+	} else {
+		b.WriteString("{")
+	}
+
+	i := uint64(0)
+	for ; indef && !o.isBreak() || !indef && i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n" + indent)
+
+		var key strings.Builder
+		keyStart := o.off
+
+		if err := o.item(&key, nil, depth+1); err != nil {
+			return err
+		}
+
+		name := o.keyName(schema, keyStart)
+		if name != "" {
+			b.WriteString("/ " + name + " / ")
+		}
+		b.WriteString(key.String() + ": ")
+
+		if err := o.item(b, schema.valueSchema(name), depth+1); err != nil {
+			return err
+		}
+	}
+
+	if i > 0 {
+		b.WriteString("\n" + strings.Repeat("  ", depth))
+	}
+	b.WriteString("}")
+
+	return nil
+}
+
+// keyName returns the claim name of the integer key that starts at offset
+// start, if known to the schema
+func (o *diagDecoder) keyName(schema *diagSchema, start int) string {
+	if schema == nil || schema.names == nil {
+		return ""
+	}
+
+	k := diagDecoder{data: o.data[start:o.off]}
+
+	major, _, arg, _, err := k.head()
+	if err != nil || arg > math.MaxInt64 {
+		return ""
+	}
+
+	switch major {
+	case 0:
+		return schema.names[int64(arg)]
+	case 1:
+		return schema.names[-1-int64(arg)]
+	}
+
+	return ""
+}
+
+func (o *diagSchema) valueSchema(name string) *diagSchema {
+	if o == nil {
+		return nil
+	}
+
+	if o.each != nil {
+		return o.each
+	}
+
+	return o.values[name]
+}
+
+func (o *diagDecoder) tag(b *strings.Builder, tag uint64, depth int) error {
+	b.WriteString(strconv.FormatUint(tag, 10) + "(")
+
+	if tag == diagCOSESign1Tag {
+		if err := o.coseSign1(b, depth); err != nil {
+			return err
+		}
+	} else if err := o.item(b, nil, depth); err != nil {
+		return err
+	}
+
+	b.WriteString(")")
+
+	return nil
+}
+
+// coseSign1 renders the COSE_Sign1 array, showing the protected header and the
+// payload as embedded CBOR
+func (o *diagDecoder) coseSign1(b *strings.Builder, depth int) error {
+	major, _, n, indef, err := o.head()
+	if err != nil {
+		return err
+	}
+
+	if major != 4 || indef || n != 4 {
+		return errors.New("malformed COSE_Sign1")
+	}
+
+	indent := strings.Repeat("  ", depth+1)
+
+	b.WriteString("[")
+
+	for i, schema := range []*diagSchema{diagCOSEHeaderSchema, nil, diagResultSchema, nil} {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n" + indent)
+
+		if i == 1 || i == 3 {
+			if err := o.item(b, nil, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := o.embedded(b, schema, depth+1); err != nil {
+			return err
+		}
+	}
+
+	b.WriteString("\n" + strings.Repeat("  ", depth) + "]")
+
+	return nil
+}
+
+// embedded renders a byte string containing CBOR as << item >>
+func (o *diagDecoder) embedded(b *strings.Builder, schema *diagSchema, depth int) error {
+	major, _, n, indef, err := o.head()
+	if err != nil {
+		return err
+	}
+
+	if major != 2 || indef {
+		return errors.New("malformed COSE_Sign1: expecting a byte string")
+	}
+
+	data, err := o.bytes(n)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		b.WriteString("h''")
+		return nil
+	}
+
+	e := diagDecoder{data: data, nested: o.nested}
+
+	b.WriteString("<< ")
+	if err := e.item(b, schema, depth); err != nil {
+		return err
+	}
+	b.WriteString(" >>")
+
+	if e.off != len(e.data) {
+		return errors.New("malformed COSE_Sign1: trailing bytes in embedded CBOR")
+	}
+
+	return nil
+}
+
+func (o *diagDecoder) simple(b *strings.Builder, info byte, arg uint64) error {
+	var f float64
+
+	switch info {
+	case 20:
+		b.WriteString("false")
+		return nil
+	case 21:
+		b.WriteString("true")
+		return nil
+	case 22:
+		b.WriteString("null")
+		return nil
+	case 23:
+		b.WriteString("undefined")
+		return nil
+	case 25:
+		f = float64(float16.Frombits(uint16(arg)).Float32())
+	case 26:
+		f = float64(math.Float32frombits(uint32(arg)))
+	case 27:
+		f = math.Float64frombits(arg)
+	default:
+		b.WriteString("simple(" + strconv.FormatUint(arg, 10) + ")")
+		return nil
+	}
+
+	switch {
+	case math.IsNaN(f):
+		b.WriteString("NaN")
+	case math.IsInf(f, 1):
+		b.WriteString("Infinity")
+	case math.IsInf(f, -1):
+		b.WriteString("-Infinity")
+	default:
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		b.WriteString(s)
+	}
+
+	return nil
+}
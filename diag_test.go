@@ -0,0 +1,135 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToDiag_claims_set(t *testing.T) {
+	ar := AttestationResult{
+		Profile:    &testProfile,
+		IssuedAt:   &testIAT,
+		VerifierID: &testVerifierID,
+		Submods: map[string]*Appraisal{
+			"test": {
+				Status: &testStatus,
+				TrustVector: &TrustVector{
+					InstanceIdentity: TrustworthyInstanceClaim,
+				},
+			},
+		},
+	}
+
+	data, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	expected := `{
+  / iat / 6: 1666091373,
+  / eat_profile / 265: "tag:github.com,2023:veraison/ear",
+  / submods / 266: {
+    "test": {
+      / ear.status / 1000: 2,
+      / ear.trustworthiness-vector / 1001: {
+        / instance-identity / 0: 2,
+        / configuration / 1: 0,
+        / executables / 2: 0,
+        / file-system / 3: 0,
+        / hardware / 4: 0,
+        / runtime-opaque / 5: 0,
+        / storage-opaque / 6: 0,
+        / sourced-data / 7: 0
+      }
+    }
+  },
+  / ear.verifier-id / 1004: {
+    / developer / 0: "Acme Inc.",
+    / build / 1: "rrtrap-v1.0.0"
+  }
+}`
+
+	actual, err := ToDiag(data)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestToDiag_COSE_Sign1(t *testing.T) {
+	signer, _ := testCOSESignerVerifier(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	actual, err := ToDiag(token)
+	require.NoError(t, err)
+
+	assert.Contains(t, actual, "18([\n  << {\n    / alg / 1: -7\n  } >>,")
+	assert.Contains(t, actual, `/ ear.veraison.key-attestation / -70002: {`)
+}
+
+func TestToDiag_generic(t *testing.T) {
+	tvs := []struct {
+		data     []byte
+		expected string
+	}{
+		{data: []byte{0x38, 0x63}, expected: "-100"},
+		{data: []byte{0x43, 0x01, 0x02, 0x03}, expected: "h'010203'"},
+		{data: []byte{0x9f, 0x01, 0xf5, 0xf6, 0xff}, expected: "[_ 1, true, null]"},
+		{data: []byte{0x7f, 0x61, 0x61, 0x61, 0x62, 0xff}, expected: `(_ "a", "b")`},
+		{data: []byte{0xf9, 0x3e, 0x00}, expected: "1.5"},
+		{data: []byte{0xfb, 0x7f, 0xf0, 0, 0, 0, 0, 0, 0}, expected: "Infinity"},
+		{data: []byte{0xc1, 0x1a, 0x63, 0x4e, 0xc2, 0x6d}, expected: "1(1666105965)"},
+		{data: []byte{0xa0}, expected: "{}"},
+	}
+
+	for i, tv := range tvs {
+		actual, err := ToDiag(tv.data)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, actual, "failed test vector at index %d", i)
+	}
+}
+
+func TestToDiag_max_nesting(t *testing.T) {
+	// as deep as the CBOR decoder allows
+	data := append(bytes.Repeat([]byte{0xa1, 0x00}, 31), 0x81, 0x00)
+
+	var v interface{}
+	require.NoError(t, cbor.Unmarshal(data, &v))
+
+	_, err := ToDiag(data)
+	assert.NoError(t, err)
+
+	// one level deeper is rejected by both
+	data = append([]byte{0x81}, data...)
+
+	assert.Error(t, cbor.Unmarshal(data, &v))
+
+	_, err = ToDiag(data)
+	assert.EqualError(t, err, "exceeded max nested level 32")
+}
+
+func TestToDiag_fail(t *testing.T) {
+	tvs := []struct {
+		data     []byte
+		expected string
+	}{
+		{data: []byte{}, expected: "unexpected end of data"},
+		{data: []byte{0x43, 0x01}, expected: "unexpected end of data"},
+		{data: []byte{0x01, 0x02}, expected: "1 trailing bytes"},
+		{data: []byte{0x1c}, expected: "malformed initial byte 0x1c"},
+		{data: []byte{0xd2, 0x80}, expected: "malformed COSE_Sign1"},
+		{data: append(bytes.Repeat([]byte{0x81}, 33), 0x00), expected: "exceeded max nested level 32"},
+		{data: append(bytes.Repeat([]byte{0xa1, 0x00}, 1<<16), 0x00), expected: "exceeded max nested level 32"},
+		{data: append(bytes.Repeat([]byte{0xc1}, 1<<16), 0x00), expected: "exceeded max nested level 32"},
+	}
+
+	for i, tv := range tvs {
+		_, err := ToDiag(tv.data)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// maxDIDDocumentSize bounds the size of the DID documents fetched by
+// DIDWebResolver
+const maxDIDDocumentSize = 1 << 20
+
+// DIDWebResolver is a KeyResolver for verifiers that publish their keys in a
+// did:web DID document rather than at a JWKS endpoint.  Such verifiers are
+// expected to use their DID as the developer in the "ear.verifier-id" claim,
+// and to sign with a kid that is a DID URL identifying one of the
+// verification methods in the document, e.g.,
+// "did:web:verifier.example#key-1".  Once the token is decoded, its
+// verifier-id developer is checked to be the DID of the kid (see
+// AddResultCheck), so that a trusted verifier cannot pass its results off as
+// another's.  The DID
+// document is fetched on each resolution.
+type DIDWebResolver struct {
+	// DIDs are the trusted verifier DIDs.  A kid referencing any other DID
+	// is rejected.
+	DIDs []string
+	// Client is used to fetch the DID documents.  If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+func (o DIDWebResolver) ResolveKey(ctx context.Context, hdrs jws.Headers) (jwk.Key, error) {
+	kid := hdrs.KeyID()
+	if kid == "" {
+		return nil, errors.New("no kid in JWS header")
+	}
+
+	did, fragment, ok := strings.Cut(kid, "#")
+	if !ok || fragment == "" {
+		return nil, fmt.Errorf("kid %q is not a DID URL with a fragment", kid)
+	}
+
+	if !contains(o.DIDs, did) {
+		return nil, fmt.Errorf("DID %q is not trusted", did)
+	}
+
+	u, err := didWebURL(did)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := o.fetch(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("fetching DID document for %q: %w", did, err)
+	}
+
+	if doc.ID != did {
+		return nil, fmt.Errorf("DID document id %q does not match %q", doc.ID, did)
+	}
+
+	for _, vm := range doc.VerificationMethod {
+		if vm.ID != kid && vm.ID != "#"+fragment {
+			continue
+		}
+
+		if len(vm.PublicKeyJWK) == 0 {
+			return nil, fmt.Errorf("verification method %q has no publicKeyJwk", kid)
+		}
+
+		key, err := jwk.ParseKey(vm.PublicKeyJWK)
+		if err != nil {
+			return nil, fmt.Errorf("parsing publicKeyJwk of %q: %w", kid, err)
+		}
+
+		AddResultCheck(ctx, func(ar *AttestationResult) error {
+			return checkDeveloper(did, ar)
+		})
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("no verification method %q in DID document", kid)
+}
+
+// checkDeveloper makes sure that the result was issued by the verifier that
+// owns the signing key
+func checkDeveloper(did string, ar *AttestationResult) error {
+	if ar.VerifierID == nil || ar.VerifierID.Developer == nil {
+		return fmt.Errorf("no verifier-id developer to match DID %q", did)
+	}
+
+	if dev := *ar.VerifierID.Developer; dev != did {
+		return fmt.Errorf("verifier-id developer %q does not match DID %q", dev, did)
+	}
+
+	return nil
+}
+
+// didDocument is the subset of a DID document used by DIDWebResolver
+type didDocument struct {
+	ID                 string `json:"id"`
+	VerificationMethod []struct {
+		ID           string          `json:"id"`
+		PublicKeyJWK json.RawMessage `json:"publicKeyJwk"`
+	} `json:"verificationMethod"`
+}
+
+func (o DIDWebResolver) fetch(ctx context.Context, u string) (*didDocument, error) {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", u, res.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxDIDDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxDIDDocumentSize {
+		return nil, fmt.Errorf("GET %s: document exceeds %d bytes", u, maxDIDDocumentSize)
+	}
+
+	var doc didDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decoding DID document: %w", err)
+	}
+
+	return &doc, nil
+}
+
+// didWebURL returns the location of the DID document for the supplied did:web
+// DID, as per the did:web method specification:
+//
+//	https://w3c-ccg.github.io/did-method-web/#read-resolve
+func didWebURL(did string) (string, error) {
+	id := strings.TrimPrefix(did, "did:web:")
+	if id == did || id == "" {
+		return "", fmt.Errorf("%q is not a did:web DID", did)
+	}
+
+	segments := strings.Split(id, ":")
+
+	host, err := url.PathUnescape(segments[0])
+	if err != nil || host == "" || strings.ContainsAny(host, "/?#@") {
+		return "", fmt.Errorf("invalid host in DID %q", did)
+	}
+
+	// without a path, the document is in the well-known location
+	path := "/.well-known"
+	if len(segments) > 1 {
+		for _, s := range segments[1:] {
+			if s == "" {
+				return "", fmt.Errorf("empty path segment in DID %q", did)
+			}
+		}
+		path = "/" + strings.Join(segments[1:], "/")
+	}
+
+	return "https://" + host + path + "/did.json", nil
+}
@@ -0,0 +1,238 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDIDWebURL(t *testing.T) {
+	tvs := []struct {
+		did      string
+		expected string
+	}{
+		{"did:web:verifier.example", "https://verifier.example/.well-known/did.json"},
+		{"did:web:verifier.example%3A8443", "https://verifier.example:8443/.well-known/did.json"},
+		{"did:web:verifier.example:ear:v1", "https://verifier.example/ear/v1/did.json"},
+	}
+
+	for i, tv := range tvs {
+		actual, err := didWebURL(tv.did)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, actual, "failed test vector at index %d", i)
+	}
+}
+
+func TestDIDWebURL_fail(t *testing.T) {
+	tvs := []struct {
+		did      string
+		expected string
+	}{
+		{"did:key:z6Mk", `"did:key:z6Mk" is not a did:web DID`},
+		{"did:web:", `"did:web:" is not a did:web DID`},
+		{"did:web:evil.example%2Fverifier.example", `invalid host in DID "did:web:evil.example%2Fverifier.example"`},
+		{"did:web:verifier.example::v1", `empty path segment in DID "did:web:verifier.example::v1"`},
+	}
+
+	for i, tv := range tvs {
+		_, err := didWebURL(tv.did)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+// testDIDServer returns a TLS server publishing a DID document with the test
+// verification key as "#key-1", and the server's DID
+func testDIDServer(t *testing.T) (*httptest.Server, string) {
+	_, vfyK := testKeyPair(t)
+
+	var did string
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/did.json" {
+			http.NotFound(w, r)
+			return
+		}
+
+		doc := map[string]interface{}{
+			"id": did,
+			"verificationMethod": []interface{}{
+				map[string]interface{}{
+					"id":           did + "#key-1",
+					"type":         "JsonWebKey2020",
+					"controller":   did,
+					"publicKeyJwk": vfyK,
+				},
+				map[string]interface{}{
+					"id":   "#key-2",
+					"type": "Multikey",
+				},
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/did+json")
+		_ = json.NewEncoder(w).Encode(doc)
+	}))
+
+	did = "did:web:" + strings.ReplaceAll(strings.TrimPrefix(srv.URL, "https://"), ":", "%3A")
+
+	return srv, did
+}
+
+// testDIDResult returns the test result as issued by the verifier with the
+// supplied DID
+func testDIDResult(did string) AttestationResult {
+	ar := deepCopy(testAttestationResultsWithVeraisonExtns)
+	ar.VerifierID = &VerifierIdentity{Build: &testVidBuild, Developer: &did}
+
+	return ar
+}
+
+func TestVerifyContext_DIDWebResolver(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	srv, did := testDIDServer(t)
+	defer srv.Close()
+
+	resolver := DIDWebResolver{DIDs: []string{did}, Client: srv.Client()}
+
+	expected := testDIDResult(did)
+
+	token, err := expected.Sign(jwa.ES256, sigK, WithKeyID(did+"#key-1"))
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.VerifyContext(context.Background(), token, resolver)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestVerifyContext_DIDWebResolver_developer_mismatch(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	srv, did := testDIDServer(t)
+	defer srv.Close()
+
+	resolver := DIDWebResolver{DIDs: []string{did}, Client: srv.Client()}
+
+	// signed with the key of a trusted verifier, but claiming to come from
+	// another one
+	token, err := testDIDResult("did:web:other.example").Sign(jwa.ES256, sigK, WithKeyID(did+"#key-1"))
+	require.NoError(t, err)
+
+	hookCalled := false
+	hook := func(*AttestationResult) error {
+		hookCalled = true
+		return nil
+	}
+
+	var actual AttestationResult
+
+	err = actual.VerifyContext(context.Background(), token, resolver, WithAfterVerify(hook))
+	assert.EqualError(t, err,
+		`after-verify hook: verifier-id developer "did:web:other.example" does not match DID "`+did+`"`)
+	assert.False(t, hookCalled)
+}
+
+func TestVerifyContext_DIDWebResolver_wrapped_developer_mismatch(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	srv, did := testDIDServer(t)
+	defer srv.Close()
+
+	resolver := DIDWebResolver{DIDs: []string{did}, Client: srv.Client()}
+
+	resolved := 0
+	wrapped := KeyResolverFunc(func(ctx context.Context, hdrs jws.Headers) (jwk.Key, error) {
+		resolved++
+		return resolver.ResolveKey(ctx, hdrs)
+	})
+
+	token, err := testDIDResult("did:web:other.example").Sign(jwa.ES256, sigK, WithKeyID(did+"#key-1"))
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.VerifyContext(context.Background(), token, wrapped)
+	assert.EqualError(t, err,
+		`after-verify hook: verifier-id developer "did:web:other.example" does not match DID "`+did+`"`)
+	assert.Equal(t, 1, resolved)
+
+	// the check applies to the result of each verification only
+	token, err = testDIDResult(did).Sign(jwa.ES256, sigK, WithKeyID(did+"#key-1"))
+	require.NoError(t, err)
+
+	assert.NoError(t, actual.VerifyContext(context.Background(), token, wrapped))
+}
+
+func TestVerifyContext_DIDWebResolver_fail(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	srv, did := testDIDServer(t)
+	defer srv.Close()
+
+	resolver := DIDWebResolver{DIDs: []string{did}, Client: srv.Client()}
+
+	tvs := []struct {
+		kid      string
+		expected string
+	}{
+		{
+			kid:      "key-1",
+			expected: `resolving verification key: kid "key-1" is not a DID URL with a fragment`,
+		},
+		{
+			kid:      "did:web:evil.example#key-1",
+			expected: `resolving verification key: DID "did:web:evil.example" is not trusted`,
+		},
+		{
+			kid:      did + "#key-3",
+			expected: `resolving verification key: no verification method "` + did + `#key-3" in DID document`,
+		},
+		{
+			kid:      did + "#key-2",
+			expected: `resolving verification key: verification method "` + did + `#key-2" has no publicKeyJwk`,
+		},
+	}
+
+	for i, tv := range tvs {
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID(tv.kid))
+		require.NoError(t, err)
+
+		var actual AttestationResult
+
+		err = actual.VerifyContext(context.Background(), token, resolver)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestDIDWebResolver_document_not_found(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	srv, did := testDIDServer(t)
+	defer srv.Close()
+
+	// the same server, under a path-based DID: the document is not found
+	pathDID := did + ":ear"
+	resolver := DIDWebResolver{DIDs: []string{pathDID}, Client: srv.Client()}
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID(pathDID+"#key-1"))
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.VerifyContext(context.Background(), token, resolver)
+	assert.ErrorContains(t, err, "404 Not Found")
+}
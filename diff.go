@@ -0,0 +1,246 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffKind says how a claim differs between two AttestationResults
+type DiffKind int
+
+const (
+	// DiffAdded is a claim only present in the other result
+	DiffAdded DiffKind = iota
+	// DiffRemoved is a claim only present in the receiver
+	DiffRemoved
+	// DiffChanged is a claim present in both results, with different values
+	DiffChanged
+)
+
+func (o DiffKind) String() string {
+	switch o {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("DiffKind(%d)", int(o))
+	}
+}
+
+// Difference is a difference found by AttestationResult.Diff
+type Difference struct {
+	// Submod is the name of the submod the difference is in, or empty for
+	// the claims at the top level of the result
+	Submod string
+	// Claim is the name of the claim that differs.  It is empty if the
+	// whole submod has been added or removed.
+	Claim string
+	// Dimension is the trustworthiness claim (e.g., "executables") that
+	// differs, if Claim is "ear.trustworthiness-vector" and the trust
+	// vector is present in both results
+	Dimension string
+	Kind      DiffKind
+	// Old and New are the values in the receiver and in the other result,
+	// respectively.  Old is nil for added claims, and New for removed ones.
+	Old interface{}
+	New interface{}
+}
+
+// Path returns the location of the difference, e.g.,
+// "submods[cpu].ear.trustworthiness-vector.executables"
+func (o Difference) Path() string {
+	var elems []string
+
+	if o.Submod != "" {
+		elems = append(elems, fmt.Sprintf("submods[%s]", o.Submod))
+	}
+
+	if o.Claim != "" {
+		elems = append(elems, o.Claim)
+	}
+
+	if o.Dimension != "" {
+		elems = append(elems, o.Dimension)
+	}
+
+	return strings.Join(elems, ".")
+}
+
+func (o Difference) String() string {
+	switch o.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("%s: added %s", o.Path(), diffValue(o.New))
+	case DiffRemoved:
+		return fmt.Sprintf("%s: removed %s", o.Path(), diffValue(o.Old))
+	default:
+		return fmt.Sprintf("%s: changed from %s to %s",
+			o.Path(), diffValue(o.Old), diffValue(o.New))
+	}
+}
+
+// diffValue renders a claim value in its JSON form
+func diffValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// Differences is the list of differences between two AttestationResults, as
+// returned by AttestationResult.Diff
+type Differences []Difference
+
+// Ignoring returns the differences that do not concern the named claims, at
+// any level.  This is handy to leave out the claims that are expected to
+// differ, such as "iat".
+func (o Differences) Ignoring(claims ...string) Differences {
+	var ret Differences
+
+	for _, d := range o {
+		if !contains(claims, d.Claim) {
+			ret = append(ret, d)
+		}
+	}
+
+	return ret
+}
+
+func (o Differences) String() string {
+	lines := make([]string, 0, len(o))
+	for _, d := range o {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares the receiver with other, claim by claim, e.g., to assess the
+// impact of a new verifier version or of a policy change on the results.  The
+// top-level claims are compared first, then the submods in lexicographic
+// order.  Within a submod, trust vectors are compared one trustworthiness
+// claim at a time.  An empty list means that the results are equivalent.
+func (o AttestationResult) Diff(other AttestationResult) Differences {
+	var ret Differences
+
+	ours, theirs := o.AsMap(), other.AsMap()
+	delete(ours, "submods")
+	delete(theirs, "submods")
+
+	ret = append(ret, diffClaims("", ours, theirs)...)
+
+	for _, name := range unionKeys(submodsAsMap(o.Submods), submodsAsMap(other.Submods)) {
+		a, b := o.Submods[name], other.Submods[name]
+
+		switch {
+		case a == nil && b == nil:
+			continue
+		case a == nil:
+			ret = append(ret, Difference{Submod: name, Kind: DiffAdded, New: b.AsMap()})
+		case b == nil:
+			ret = append(ret, Difference{Submod: name, Kind: DiffRemoved, Old: a.AsMap()})
+		default:
+			ret = append(ret, a.diff(name, *b)...)
+		}
+	}
+
+	return ret
+}
+
+func (o Appraisal) diff(submod string, other Appraisal) Differences {
+	const tvClaim = "ear.trustworthiness-vector"
+
+	ours, theirs := o.AsMap(), other.AsMap()
+
+	// trust vectors are compared separately, when both are present
+	if o.TrustVector != nil && other.TrustVector != nil {
+		delete(ours, tvClaim)
+		delete(theirs, tvClaim)
+	}
+
+	ret := diffClaims(submod, ours, theirs)
+
+	if o.TrustVector == nil || other.TrustVector == nil {
+		return ret
+	}
+
+	a, b := o.TrustVector.AsMap(), other.TrustVector.AsMap()
+
+	for _, dim := range trustVectorClaimNames() {
+		if a[dim] != b[dim] {
+			ret = append(ret, Difference{
+				Submod:    submod,
+				Claim:     tvClaim,
+				Dimension: dim,
+				Kind:      DiffChanged,
+				Old:       a[dim],
+				New:       b[dim],
+			})
+		}
+	}
+
+	return ret
+}
+
+// diffClaims compares two claims-sets in the form returned by AsMap
+func diffClaims(submod string, ours, theirs map[string]interface{}) Differences {
+	var ret Differences
+
+	for _, claim := range unionKeys(ours, theirs) {
+		a, inOurs := ours[claim]
+		b, inTheirs := theirs[claim]
+
+		d := Difference{Submod: submod, Claim: claim, Old: a, New: b}
+
+		switch {
+		case !inOurs:
+			d.Kind = DiffAdded
+		case !inTheirs:
+			d.Kind = DiffRemoved
+		case !reflect.DeepEqual(a, b):
+			d.Kind = DiffChanged
+		default:
+			continue
+		}
+
+		ret = append(ret, d)
+	}
+
+	return ret
+}
+
+func submodsAsMap(submods map[string]*Appraisal) map[string]interface{} {
+	ret := make(map[string]interface{}, len(submods))
+	for name, a := range submods {
+		ret[name] = a
+	}
+	return ret
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := sortedKeys(a)
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// trustVectorClaimNames returns the names of the trustworthiness claims, in
+// the order in which they are defined by AR4SI
+func trustVectorClaimNames() []string {
+	return structTagNames(reflect.TypeOf(TrustVector{}), "json")
+}
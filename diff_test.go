@@ -0,0 +1,94 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDiffResult() AttestationResult {
+	ar := NewAttestationResult("cpu", "v1.0.0", "https://veraison-project.org")
+	iat := int64(1666091373)
+	ar.IssuedAt = &iat
+	ar.Submods["cpu"].TrustVector.Executables = ApprovedRuntimeClaim
+	ar.Submods["cpu"].TrustVector.Hardware = GenuineHardwareClaim
+	ar.UpdateStatusFromTrustVector()
+
+	return *ar
+}
+
+func TestAttestationResult_Diff_equal(t *testing.T) {
+	assert.Empty(t, testDiffResult().Diff(testDiffResult()))
+	assert.Empty(t, testAttestationResultsWithVeraisonExtns.Diff(testAttestationResultsWithVeraisonExtns))
+}
+
+func TestAttestationResult_Diff(t *testing.T) {
+	old, updated := testDiffResult(), testDiffResult()
+
+	iat := int64(1666091400)
+	updated.IssuedAt = &iat
+	build := "v1.1.0"
+	updated.VerifierID = &VerifierIdentity{Build: &build, Developer: old.VerifierID.Developer}
+
+	updated.Submods["cpu"].TrustVector.Executables = UnrecognizedRuntimeClaim
+	updated.Submods["cpu"].UpdateStatusFromTrustVector()
+
+	status := TrustTierAffirming
+	updated.Submods["gpu"] = &Appraisal{Status: &status}
+
+	actual := old.Diff(updated)
+	require.Len(t, actual, 5)
+
+	assert.Equal(t, Difference{Claim: "iat", Kind: DiffChanged, Old: int64(1666091373), New: int64(1666091400)}, actual[1])
+
+	assert.Equal(t, "cpu", actual[2].Submod)
+	assert.Equal(t, "ear.status", actual[2].Claim)
+	assert.Equal(t, TrustTierAffirming, actual[2].Old)
+	assert.Equal(t, TrustTierWarning, actual[2].New)
+
+	assert.Equal(t, Difference{
+		Submod:    "cpu",
+		Claim:     "ear.trustworthiness-vector",
+		Dimension: "executables",
+		Kind:      DiffChanged,
+		Old:       ApprovedRuntimeClaim,
+		New:       UnrecognizedRuntimeClaim,
+	}, actual[3])
+
+	expected := `ear.verifier-id: changed from {"build":"v1.0.0","developer":"https://veraison-project.org"} to {"build":"v1.1.0","developer":"https://veraison-project.org"}
+iat: changed from 1666091373 to 1666091400
+submods[cpu].ear.status: changed from "affirming" to "warning"
+submods[cpu].ear.trustworthiness-vector.executables: changed from 2 to 33
+submods[gpu]: added {"ear.status":"affirming"}`
+
+	assert.Equal(t, expected, actual.String())
+
+	assert.Len(t, actual.Ignoring("iat", "ear.verifier-id"), 3)
+
+	// the other way round
+	reverse := updated.Diff(old)
+	require.Len(t, reverse, 5)
+	assert.Equal(t, DiffRemoved, reverse[4].Kind)
+	assert.Equal(t, "submods[gpu]: removed {\"ear.status\":\"affirming\"}", reverse[4].String())
+}
+
+func TestAttestationResult_Diff_trust_vector_presence(t *testing.T) {
+	old, updated := testDiffResult(), testDiffResult()
+	updated.Submods["cpu"].TrustVector = nil
+
+	actual := old.Diff(updated)
+	require.Len(t, actual, 1)
+	assert.Equal(t, "submods[cpu].ear.trustworthiness-vector", actual[0].Path())
+	assert.Equal(t, DiffRemoved, actual[0].Kind)
+}
+
+func TestDiffKind_String(t *testing.T) {
+	assert.Equal(t, "added", DiffAdded.String())
+	assert.Equal(t, "removed", DiffRemoved.String())
+	assert.Equal(t, "changed", DiffChanged.String())
+	assert.Equal(t, "DiffKind(7)", DiffKind(7).String())
+}
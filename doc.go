@@ -15,13 +15,13 @@ claims could be created as follows:
 
 	myStatus := TrustTierAffirming
 	myTimestamp := time.Now().Format(time.RFC3339)
-	myPolicyID := `https://veraison.example/policy/1A4DF345-B512-4F3B-8461-967DE7F60ECA`
-	myProfile := EatProfile
+	myPolicyID := PolicyIDs{`https://veraison.example/policy/1A4DF345-B512-4F3B-8461-967DE7F60ECA`}
+	myProfile := Profile(EatProfile)
 
 	ar := AttestationResult{
 		Status:            &myStatus,
 		Timestamp:         &testTimestamp,
-		AppraisalPolicyID: &testPolicyID,
+		AppraisalPolicyID: &myPolicyID,
 		Profile:           &testProfile,
 	}
 
@@ -91,6 +91,30 @@ entity.
 		// handle troubles with appraisal
 	}
 
+Claims-sets in which the same JSON member name appears more than once in an
+object are rejected, since they could be read differently by different
+parsers.  WithDuplicateClaimHandler can be used to flag them instead.
+
+# CBOR and COSE
+
+Relying parties that already speak CBOR can consume the attestation result as
+a CWT instead.  The claims-set is encoded using the integer keys registered by
+EAT and EAR, and wrapped in a COSE_Sign1 envelope by SignCWT:
+
+	signer, _ := cose.NewSigner(cose.AlgorithmES256, myECDSAPrivateKey)
+
+	buf, _ = ar.SignCWT(signer)
+
+VerifyCWT is the counterpart of Verify:
+
+	verifier, _ := cose.NewVerifier(cose.AlgorithmES256, myECDSAPublicKey)
+
+	err := ar.VerifyCWT(buf, verifier)
+
+When debugging interop issues, ToDiag prints a CBOR claims-set or a COSE_Sign1
+message in diagnostic notation, with the integer claim keys annotated with
+their names.
+
 # Pretty printing
 
 The package provides a Report method that allows pretty printing of the
@@ -0,0 +1,101 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DuplicateClaimHandler is invoked for each member name that occurs more than
+// once in the same JSON object of a claims-set.  The offending member is
+// identified by its JSON Pointer (RFC6901), e.g., "/submods/test/ear.status".
+// Returning an error rejects the claims-set; returning nil accepts it, in
+// which case the last occurrence of the member wins.
+type DuplicateClaimHandler func(pointer string) error
+
+// WithDuplicateClaimHandler overrides the default handling of duplicate JSON
+// member names, which is to reject the claims-set.  Since different parsers
+// disagree on which occurrence wins, duplicates can be used to smuggle claims
+// past intermediaries; relax this only to flag (e.g., log) duplicates coming
+// from known-quirky verifiers.
+func WithDuplicateClaimHandler(h DuplicateClaimHandler) DecodeOption {
+	return func(o *decodeOptions) {
+		o.duplicate = h
+	}
+}
+
+// RejectDuplicateClaims is the default DuplicateClaimHandler
+func RejectDuplicateClaims(pointer string) error {
+	return fmt.Errorf("duplicate claim %q", pointer)
+}
+
+// checkDuplicateClaims scans the supplied JSON text and calls the handler for
+// each duplicate member name it finds
+func checkDuplicateClaims(data []byte, h DuplicateClaimHandler) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	return walkJSONValue(dec, "", h)
+}
+
+func walkJSONValue(dec *json.Decoder, pointer string, h DuplicateClaimHandler) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	d, ok := t.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch d {
+	case '{':
+		seen := map[string]bool{}
+
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			name := t.(string)
+			member := pointer + "/" + escapeJSONPointer(name)
+
+			if seen[name] {
+				if err := h(member); err != nil {
+					return err
+				}
+			}
+			seen[name] = true
+
+			if err := walkJSONValue(dec, member, h); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			if err := walkJSONValue(dec, fmt.Sprintf("%s/%d", pointer, i), h); err != nil {
+				return err
+			}
+		}
+	}
+
+	// consume the closing delimiter
+	_, err = dec.Token()
+
+	return err
+}
+
+var (
+	jsonPointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	jsonPointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func escapeJSONPointer(s string) string {
+	return jsonPointerEscaper.Replace(s)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDuplicateClaims(t *testing.T) {
+	tvs := []struct {
+		data     string
+		expected []string
+	}{
+		{
+			data: `{"a": 1, "b": [{"c": 2}, {"c": 3}]}`,
+		},
+		{
+			data:     `{"a": 1, "a": 2}`,
+			expected: []string{"/a"},
+		},
+		{
+			data:     `{"submods": {"x": {"ear.status": 2, "ear.status": 32}}}`,
+			expected: []string{"/submods/x/ear.status"},
+		},
+		{
+			data:     `{"l": [{"a/b": 1, "a/b": 2}], "l": []}`,
+			expected: []string{"/l/0/a~1b", "/l"},
+		},
+	}
+
+	for i, tv := range tvs {
+		var actual []string
+
+		err := checkDuplicateClaims([]byte(tv.data), func(p string) error {
+			actual = append(actual, p)
+			return nil
+		})
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, actual, "failed test vector at index %d", i)
+	}
+}
+
+func TestDecodeJSON_duplicate_claims(t *testing.T) {
+	data := []byte(`{
+		"eat_profile": "tag:github.com,2023:veraison/ear",
+		"iat": 1666091373,
+		"iat": 1666091373,
+		"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."},
+		"submods": {"test": {"ear.status": "affirming"}}
+	}`)
+
+	var ar AttestationResult
+
+	err := ar.DecodeJSON(data)
+	assert.EqualError(t, err, `duplicate claim "/iat"`)
+
+	var flagged []string
+
+	err = ar.DecodeJSON(data, WithDuplicateClaimHandler(func(p string) error {
+		flagged = append(flagged, p)
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/iat"}, flagged)
+}
+
+func TestVerify_duplicate_claims(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	payload, err := testAttestationResultsWithVeraisonExtns.MarshalJSON()
+	require.NoError(t, err)
+
+	// the smuggled profile comes first, so that the legitimate one wins
+	payload = append([]byte(`{"eat_profile":"smuggled",`), payload[1:]...)
+
+	token, err := jws.Sign(payload, jws.WithKey(jwa.ES256, sigK))
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.Verify(token, jwa.ES256, vfyK)
+	assert.EqualError(t, err, `duplicate claim "/eat_profile"`)
+
+	err = ar.Verify(token, jwa.ES256, vfyK, WithDecodeOptions(
+		WithDuplicateClaimHandler(func(string) error { return nil }),
+	))
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, ar)
+}
+
+func TestDecodeCBOR_duplicate_keys(t *testing.T) {
+	data, err := testAttestationResultsWithVeraisonExtns.MarshalCBOR()
+	require.NoError(t, err)
+
+	var m map[interface{}]interface{}
+	require.NoError(t, cbor.Unmarshal(data, &m))
+
+	// re-encode with a duplicated iat (6) by hand: bump the map length and
+	// append the extra entry
+	require.Equal(t, byte(0xa0|len(m)), data[0])
+
+	dup := append([]byte{data[0] + 1}, data[1:]...)
+	dup = append(dup, 0x06, 0x00)
+
+	var ar AttestationResult
+
+	err = ar.DecodeCBOR(dup)
+	assert.ErrorContains(t, err, "duplicate map key")
+}
@@ -8,10 +8,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/lestrrat-go/jwx/v2/cert"
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 )
 
@@ -22,19 +26,40 @@ const EatProfile = "tag:github.com,2023:veraison/ear"
 // by the verifier.  It is serialized to JSON and signed by the verifier using
 // JWT.
 type AttestationResult struct {
-	Profile     *string               `json:"eat_profile"`
+	Profile     *Profile              `json:"eat_profile"`
 	VerifierID  *VerifierIdentity     `json:"ear.verifier-id"`
 	RawEvidence *B64Url               `json:"ear.raw-evidence,omitempty"`
 	IssuedAt    *int64                `json:"iat"`
-	Nonce       *string               `json:"eat_nonce,omitempty"`
+	Expiry      *int64                `json:"exp,omitempty"`
+	NotBefore   *int64                `json:"nbf,omitempty"`
+	Nonce       *Nonces               `json:"eat_nonce,omitempty"`
 	Submods     map[string]*Appraisal `json:"submods"`
 
 	AttestationResultExtensions
+
+	// UnknownClaims holds the claims that are not otherwise modelled, e.g.,
+	// those introduced by later revisions of AR4SI.  It is only populated
+	// on decoding if UnknownClaimsPreserve is requested.  Its entries are
+	// emitted on encoding, unless they clash with a known claim.
+	UnknownClaims map[string]interface{} `json:"-"`
+
+	// Registry, if not nil, supplies the accepted profiles and appraisal
+	// extensions in place of the default Registry.  It is set on decoding
+	// if WithRegistry is used.
+	Registry *Registry `json:"-"`
 }
 
 type AttestationResultExtensions struct {
 	VeraisonTeeInfo *VeraisonTeeInfo `json:"ear.veraison.tee-info,omitempty"`
 	NAETTSInfo      *NAETTSInfo      `json:"ear.nae.tts-info,omitempty"`
+
+	VeraisonMigration *VeraisonMigrationInfo `json:"ear.veraison.migration,omitempty"`
+
+	VeraisonEncryptedClaims *VeraisonEncryptedClaims `json:"ear.veraison.encrypted-claims,omitempty"`
+
+	VeraisonEvidenceRef *VeraisonEvidenceRef `json:"ear.veraison.evidence-ref,omitempty"`
+
+	VeraisonTimestamp *B64Url `json:"ear.veraison.timestamp,omitempty"`
 }
 
 // B64Url is base64url (§5 of RFC4648) without padding.
@@ -47,6 +72,23 @@ func (o B64Url) MarshalJSON() ([]byte, error) {
 	)
 }
 
+func (o *B64Url) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+
+	*o = b
+
+	return nil
+}
+
 // NewAttestationResult returns a pointer to a new fully-initialized
 // AttestationResult.
 func NewAttestationResult(
@@ -56,7 +98,7 @@ func NewAttestationResult(
 ) *AttestationResult {
 	status := TrustTierNone
 	iat := time.Now().Unix()
-	profile := EatProfile
+	profile := Profile(EatProfile)
 
 	return &AttestationResult{
 		Profile:  &profile,
@@ -98,12 +140,24 @@ func (o AttestationResult) MarshalJSONIndent(prefix, indent string) ([]byte, err
 // UnmarshalJSON de-serializes an AttestationResult object from its JSON
 // representation and validates it.
 func (o *AttestationResult) UnmarshalJSON(data []byte) error {
+	return o.DecodeJSON(data)
+}
+
+// DecodeJSON is like UnmarshalJSON, but allows the decoding to be configured
+// using DecodeOptions.
+func (o *AttestationResult) DecodeJSON(data []byte, opts ...DecodeOption) error {
+	do := newDecodeOptions(opts)
+
 	var oMap map[string]interface{}
 	if err := json.Unmarshal(data, &oMap); err != nil {
 		return err
 	}
 
-	if err := o.populateFromMap(oMap); err != nil {
+	if err := checkDuplicateClaims(data, do.duplicate); err != nil {
+		return err
+	}
+
+	if err := o.decodeMap(oMap, do); err != nil {
 		return err
 	}
 
@@ -111,8 +165,13 @@ func (o *AttestationResult) UnmarshalJSON(data []byte) error {
 }
 
 // AsMap returns a map[string]interface{} with EAR claim names mapped onto
-// corresponding values.
-func (o AttestationResult) AsMap() map[string]interface{} {
+// corresponding values.  WithClaimKeys selects another key space.
+func (o AttestationResult) AsMap(opts ...MapOption) map[string]interface{} {
+	var mo mapOptions
+	for _, opt := range opts {
+		opt(&mo)
+	}
+
 	m, err := structAsMap(o, "json")
 	if err != nil {
 		// An error can only be returned if there is issue in implementation of
@@ -120,7 +179,37 @@ func (o AttestationResult) AsMap() map[string]interface{} {
 		// constituents incorrectly implement AsMap() themselves.
 		panic(err)
 	}
-	return m
+
+	// keep eat_profile a plain string for consumers of the map
+	if p, ok := m["eat_profile"].(Profile); ok {
+		m["eat_profile"] = string(p)
+	}
+
+	if submods, ok := m["submods"].(map[string]interface{}); ok {
+		for name, a := range o.Submods {
+			if sm, ok := submods[name].(map[string]interface{}); ok && a != nil {
+				a.addExtensionClaims(sm)
+			}
+		}
+	}
+
+	for k, v := range o.UnknownClaims {
+		if _, ok := m[k]; !ok && !isKnownClaim(k) {
+			m[k] = v
+		}
+	}
+
+	switch mo.keys {
+	case JSONClaimKeys:
+		return m
+	case GoClaimKeys:
+		if m, err = o.asGoKeyedMap(m); err != nil {
+			panic(err)
+		}
+		return m
+	default:
+		panic(fmt.Sprintf("unknown claim key space %s", mo.keys))
+	}
 }
 
 // UpdateStatusFromTrustVector ensure that Status trustworthiness of each
@@ -130,87 +219,409 @@ func (o AttestationResult) AsMap() map[string]interface{} {
 // claim's tier. This means that the overall result will not assert to be more
 // trustworthy than individual vector claims (though it could be less
 // trustworthy if had been manually set that way).
-func (o *AttestationResult) UpdateStatusFromTrustVector() {
-	for _, appraisal := range o.Submods {
-		appraisal.UpdateStatusFromTrustVector()
+//
+// The above is the default (WorstClaimPolicy) behaviour.  A different
+// StatusPolicy can be supplied for the whole result using WithStatusPolicy,
+// or for individual submods using WithSubmodStatusPolicy.
+func (o *AttestationResult) UpdateStatusFromTrustVector(opts ...StatusOption) {
+	so := newStatusOptions(opts)
+
+	for submodName, appraisal := range o.Submods {
+		appraisal.updateStatus(so.policyFor(submodName))
+	}
+}
+
+// ExplainStatus is like UpdateStatusFromTrustVector, but also returns, for
+// each submod, which trust vector claims forced the resulting status.  This
+// allows operators to work out why a submod has been given a certain tier.
+func (o *AttestationResult) ExplainStatus(opts ...StatusOption) map[string]StatusExplanation {
+	so := newStatusOptions(opts)
+
+	ret := make(map[string]StatusExplanation, len(o.Submods))
+
+	for submodName, appraisal := range o.Submods {
+		if appraisal == nil {
+			continue
+		}
+		ret[submodName] = appraisal.explainStatus(so.policyFor(submodName))
+	}
+
+	return ret
+}
+
+// UpdateSubmod supports re-appraisal flows that only affect one submod.  The
+// update function is invoked on the Appraisal associated with the named
+// submod, after which the Appraisal status is derived afresh from its trust
+// vector (using the supplied StatusOptions, if any), so that a re-appraisal
+// can improve the status as well as worsen it.  If the Appraisal has no trust
+// vector, the status set by the update function is kept.  The issuance time
+// is then refreshed, and the time-stamp, if any, is dropped, as it no longer
+// covers the claims-set.  Any token previously obtained from the
+// AttestationResult no longer reflects its content, and must be replaced with
+// a freshly signed one.
+func (o *AttestationResult) UpdateSubmod(
+	name string,
+	update func(*Appraisal),
+	opts ...StatusOption,
+) error {
+	appraisal, ok := o.Submods[name]
+	if !ok || appraisal == nil {
+		return fmt.Errorf("submod %q not found", name)
+	}
+
+	update(appraisal)
+
+	if appraisal.TrustVector != nil {
+		appraisal.Status = NewTrustTier(TrustTierNone)
 	}
+
+	so := newStatusOptions(opts)
+	appraisal.updateStatus(so.policyFor(name))
+
+	iat := time.Now().Unix()
+	o.IssuedAt = &iat
+	o.VeraisonTimestamp = nil
+
+	return nil
+}
+
+// Validate checks that the AttestationResult is well-formed, i.e., that all
+// mandatory claims are present and that claim values are acceptable.  All the
+// problems found are reported, in a *ValidationError.  Sign, MarshalJSON and
+// the decoding functions already do this; Validate is for callers that want to
+// check a claims-set without encoding it, e.g., linters.  See ValidateStrict
+// for the consistency checks between claims.
+func (o AttestationResult) Validate() error {
+	return o.validate()
 }
 
 func (o AttestationResult) validate() error {
-	var missing, invalid, summary []string
+	var ve ValidationError
 
 	if o.Profile == nil {
-		missing = append(missing, "'eat_profile'")
-	} else if *o.Profile != EatProfile {
-		invalid = append(invalid, fmt.Sprintf("eat_profile (%s)", *o.Profile))
+		ve.addMissing("eat_profile", "'eat_profile'")
+	} else if err := o.registry().checkProfile(string(*o.Profile)); err != nil {
+		ve.addInvalid("eat_profile", fmt.Sprintf("eat_profile (%s)", *o.Profile), err)
+	} else {
+		o.validateProfile(&ve)
 	}
 
 	if o.IssuedAt == nil {
-		missing = append(missing, "'iat'")
+		ve.addMissing("iat", "'iat'")
 	}
 
 	if o.VerifierID == nil {
-		missing = append(missing, "'verifier-id'")
+		ve.addMissing("ear.verifier-id", "'verifier-id'")
+	} else {
+		o.VerifierID.validateFreeText(&ve)
+	}
+
+	if o.Expiry != nil && o.NotBefore != nil && *o.Expiry < *o.NotBefore {
+		ve.addInvalid("exp", fmt.Sprintf("exp (%d is before nbf)", *o.Expiry), nil)
 	}
 
 	if o.Nonce != nil {
-		nLen := len(*o.Nonce)
-		if nLen > 88 || nLen < 8 {
-			invalid = append(invalid, fmt.Sprintf("eat_nonce (%d bytes)", nLen))
+		o.Nonce.validate(&ve)
+	}
+
+	if o.NAETTSInfo != nil {
+		if err := o.NAETTSInfo.Validate(); err != nil {
+			ve.addInvalid("ear.nae.tts-info", fmt.Sprintf("'ear.nae.tts-info' (%s)", err), err)
 		}
 	}
 
-	if len(o.Submods) == 0 {
-		missing = append(missing, "'submods' (at least one appraisal must be present)")
-	} else {
-		for submodName, appraisal := range o.Submods {
-			if err := appraisal.validate(); err != nil {
-				msg := fmt.Sprintf("submods[%s]: %s", submodName, err.Error())
-				invalid = append(invalid, msg)
-			}
+	if o.VeraisonEncryptedClaims != nil {
+		if err := o.VeraisonEncryptedClaims.validate(); err != nil {
+			ve.addInvalid("ear.veraison.encrypted-claims",
+				fmt.Sprintf("'ear.veraison.encrypted-claims' (%s)", err), err)
 		}
 	}
 
-	if len(missing) == 0 && len(invalid) == 0 {
-		return nil
+	if o.VeraisonEvidenceRef != nil {
+		if err := o.VeraisonEvidenceRef.Validate(); err != nil {
+			ve.addInvalid("ear.veraison.evidence-ref",
+				fmt.Sprintf("'ear.veraison.evidence-ref' (%s)", err), err)
+		}
 	}
 
-	if len(missing) != 0 {
-		summary = append(summary, fmt.Sprintf("missing mandatory %s", strings.Join(missing, ", ")))
+	if o.VeraisonTimestamp != nil && len(*o.VeraisonTimestamp) == 0 {
+		err := errors.New("empty time-stamp token")
+		ve.addInvalid("ear.veraison.timestamp",
+			fmt.Sprintf("'ear.veraison.timestamp' (%s)", err), err)
 	}
 
-	if len(invalid) != 0 {
-		summary = append(summary, fmt.Sprintf("invalid value(s) for %s", strings.Join(invalid, ", ")))
+	if len(o.Submods) == 0 {
+		ve.addMissing("submods", "'submods' (at least one appraisal must be present)")
+	} else {
+		submodNames := make([]string, 0, len(o.Submods))
+		for submodName := range o.Submods {
+			submodNames = append(submodNames, submodName)
+		}
+		sort.Strings(submodNames)
+
+		for _, submodName := range submodNames {
+			err := o.Submods[submodName].validateWith(o.registry())
+			if err == nil {
+				continue
+			}
+
+			ve.invalid = append(ve.invalid, fmt.Sprintf("submods[%s]: %s", submodName, err.Error()))
+
+			var ave *ValidationError
+			if errors.As(err, &ave) {
+				for _, ce := range ave.Errors {
+					c := *ce
+					c.Claim = fmt.Sprintf("submods[%s].%s", submodName, ce.Claim)
+					c.Submod = submodName
+					ve.Errors = append(ve.Errors, &c)
+				}
+			}
+		}
 	}
 
-	return errors.New(strings.Join(summary, "; "))
+	return ve.orNil()
 }
 
 // Verify cryptographically verifies the JWT data using the supplied key and
 // algorithm.  The payload is then parsed and validated.  On success, the target
 // AttestationResult object is populated with the decoded claims (possibly
-// including the Trustworthiness vector).
-func (o *AttestationResult) Verify(data []byte, alg jwa.KeyAlgorithm, key interface{}) error {
-	token, err := jwt.Parse(data, jwt.WithKey(alg, key))
+// including the Trustworthiness vector).  If the eat_profile is not supported,
+// a ProfileError is returned.  Any hooks supplied via WithAfterVerify are run
+// on the populated object before returning.  A VerificationReport can be
+// requested using WithVerificationReport.  Tokens failing verification can be
+// preserved for later analysis using WithQuarantine.  If the token carries exp
+// or nbf claims, they are checked against the clock supplied via WithClock
+// (the system clock by default), allowing for the skew set with
+// WithAcceptableSkew.  WithMaxTokenAge additionally bounds the age of the
+// result based on its iat claim, and WithMaxTokenSize the size of the token.
+// WithTimestampVerifier requires, and checks, an RFC3161 time-stamp.
+// WithStrictAlgorithm and WithAllowedAlgorithms reject, with an
+// AlgorithmError, tokens whose JWS alg is not the expected one (as they do
+// for VerifyCWT and VerifySD).
+// WithFrozenResult supplies a read-only copy of the verified result, which can
+// be handed over to code that must not modify it.
+func (o *AttestationResult) Verify(
+	data []byte,
+	alg jwa.KeyAlgorithm,
+	key interface{},
+	opts ...VerifyOption,
+) error {
+	vo := newVerifyOptions(opts)
+
+	if err := vo.checkTokenSize(data); err != nil {
+		return err
+	}
+
+	var token jwt.Token
+
+	err := vo.checkAlgorithm(data, alg)
+	if err == nil {
+		err = checkKeyAlgorithm(alg, key)
+	}
+	if err == nil {
+		token, err = jwt.Parse(data,
+			jwt.WithKey(alg, key),
+			jwt.WithClock(jwt.ClockFunc(vo.now)),
+			jwt.WithAcceptableSkew(vo.skew),
+		)
+	}
 	if err != nil {
-		return fmt.Errorf("failed verifying JWT message: %w", err)
+		return vo.verificationFailed(data, alg.String(), "JWT message", err)
+	}
+
+	do := newDecodeOptions(vo.decode)
+
+	// jwt.Parse has already collapsed any duplicate claims, so look at the
+	// raw payload
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing JWS: %w", err)
+	}
+
+	if err := checkDuplicateClaims(msg.Payload(), do.duplicate); err != nil {
+		return err
 	}
 
 	claims := token.PrivateClaims()
 	claims["iat"] = token.IssuedAt().Unix()
 
-	return o.populateFromMap(claims)
+	if exp := token.Expiration(); !exp.IsZero() {
+		claims["exp"] = exp.Unix()
+	}
+
+	if nbf := token.NotBefore(); !nbf.IsZero() {
+		claims["nbf"] = nbf.Unix()
+	}
+
+	if err := o.decodeVerified(claims, do, vo); err != nil {
+		return err
+	}
+
+	timestampedAt, err := o.finishVerification(vo, msg.Payload())
+	if err != nil {
+		return err
+	}
+
+	if vo.report != nil {
+		if err := vo.report.fill(data, alg, key, token, o); err != nil {
+			return fmt.Errorf("compiling verification report: %w", err)
+		}
+
+		if !timestampedAt.IsZero() {
+			vo.report.TimestampedAt = &timestampedAt
+		}
+	}
+
+	return nil
+}
+
+// verificationFailed reports a token that failed cryptographic verification,
+// after handing it over to the quarantine sink, if one has been supplied
+func (o verifyOptions) verificationFailed(data []byte, alg, format string, err error) error {
+	if o.quarantine != nil {
+		rec := QuarantineRecord{
+			Token:      append([]byte(nil), data...),
+			Reason:     err.Error(),
+			Algorithm:  alg,
+			ReceivedAt: time.Now(),
+		}
+
+		if qErr := o.quarantine.Quarantine(rec); qErr != nil {
+			return fmt.Errorf("failed verifying %s: %w (quarantine failed: %v)", format, err, qErr)
+		}
+	}
+
+	return fmt.Errorf("failed verifying %s: %w", format, err)
+}
+
+// decodeVerified populates the AttestationResult from the claims-set of a
+// verified token, whatever its format, and validates it
+func (o *AttestationResult) decodeVerified(
+	claims map[string]interface{},
+	do *decodeOptions,
+	vo *verifyOptions,
+) error {
+	if err := o.decodeMap(claims, do); err != nil {
+		return err
+	}
+
+	if err := o.registry().checkProfile(string(*o.Profile)); err != nil {
+		return err
+	}
+
+	if len(vo.profiles) > 0 && !contains(vo.profiles, string(*o.Profile)) {
+		return ProfileError{Received: string(*o.Profile), Accepted: vo.profiles}
+	}
+
+	return o.validate()
+}
+
+// finishVerification completes the verification of a decoded result, whatever
+// the token format: the validity period and the age of the result are checked,
+// as is the time-stamp, against the JSON claims-set it covers, if a
+// TimestampVerifier has been supplied.  The after-verify hooks are then run,
+// and the frozen copy, if any, is filled in.  The time-stamp generation time
+// is returned.
+func (o *AttestationResult) finishVerification(vo *verifyOptions, claimsSet []byte) (time.Time, error) {
+	var timestampedAt time.Time
+
+	if err := o.checkValidity(vo.now(), vo.skew); err != nil {
+		return timestampedAt, err
+	}
+
+	if err := o.checkAge(vo.now(), vo.maxAge, vo.skew); err != nil {
+		return timestampedAt, err
+	}
+
+	if vo.tsv != nil {
+		var err error
+
+		if timestampedAt, err = o.checkTimestamp(claimsSet, vo.tsv, vo.skew); err != nil {
+			return timestampedAt, err
+		}
+	}
+
+	if err := runHooks(vo.afterVerify, o); err != nil {
+		return timestampedAt, fmt.Errorf("after-verify hook: %w", err)
+	}
+
+	if vo.frozen != nil {
+		*vo.frozen = *Freeze(*o)
+	}
+
+	return timestampedAt, nil
+}
+
+// jwsHeaders returns the protected headers carrying the key discovery hints
+// set by the options
+func (o signOptions) jwsHeaders() (jws.Headers, error) {
+	hdrs := jws.NewHeaders()
+
+	if o.keyID != "" {
+		if err := hdrs.Set(jws.KeyIDKey, o.keyID); err != nil {
+			return nil, fmt.Errorf("setting kid: %w", err)
+		}
+	}
+
+	if o.jwksURL != "" {
+		if err := hdrs.Set(jws.JWKSetURLKey, o.jwksURL); err != nil {
+			return nil, fmt.Errorf("setting jku: %w", err)
+		}
+	}
+
+	if len(o.certChain) > 0 {
+		var chain cert.Chain
+
+		for _, c := range o.certChain {
+			if err := chain.AddString(base64.StdEncoding.EncodeToString(c.Raw)); err != nil {
+				return nil, fmt.Errorf("adding certificate to x5c: %w", err)
+			}
+		}
+
+		if err := hdrs.Set(jws.X509CertChainKey, &chain); err != nil {
+			return nil, fmt.Errorf("setting x5c: %w", err)
+		}
+	}
+
+	return hdrs, nil
 }
 
 // Sign validates the AttestationResult object, encodes it to JSON and wraps it
-// in a JWT using the supplied private key for signing.  The key must be
-// compatible with the requested signing algorithm.  On success, the complete
-// JWT token is returned.
-func (o AttestationResult) Sign(alg jwa.KeyAlgorithm, key interface{}) ([]byte, error) {
-	if err := o.validate(); err != nil {
+// in a JWT using the supplied private key for signing.  The key can be a
+// jwk.Key, a raw private key, or any crypto.Signer, such as one backed by an
+// HSM or a KMS (see OpenSigner), in which case the private key material is
+// never handled by this package.  The key must be compatible with the
+// requested signing algorithm: an EC key on the matching curve for ES256,
+// ES384 and ES512, an RSA key for RS* and PS*, and an Ed25519 key for EdDSA.
+// Any hooks supplied via WithBeforeSign are run after validation, before
+// signing, and what they change is validated too.  Hooks supplied via
+// WithAfterSign are run once the token has been produced.  Key discovery hints
+// can be added to the JWS header using WithKeyID and WithJWKSURL, and a
+// certificate chain using WithCertChain.  WithCanonicalJSON selects the
+// canonical encoding of the payload.  WithTimestampAuthority adds an RFC3161
+// time-stamp for the claims-set.  On success, the complete JWT token is
+// returned.
+func (o AttestationResult) Sign(
+	alg jwa.KeyAlgorithm,
+	key interface{},
+	opts ...SignOption,
+) ([]byte, error) {
+	so := newSignOptions(opts)
+
+	if err := checkKeyAlgorithm(alg, key); err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	if err := o.prepareForSigning(so); err != nil {
 		return nil, err
 	}
 
+	if so.tsa != nil {
+		if err := o.addTimestamp(so.tsa); err != nil {
+			return nil, err
+		}
+	}
+
 	token := jwt.New()
 	for k, v := range o.AsMap() {
 		if err := token.Set(k, v); err != nil {
@@ -218,13 +629,130 @@ func (o AttestationResult) Sign(alg jwa.KeyAlgorithm, key interface{}) ([]byte,
 		}
 	}
 
-	return jwt.Sign(token, jwt.WithKey(alg, key))
+	hdrs, err := so.jwsHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	var signed []byte
+
+	if so.canonical {
+		payload, err := o.MarshalCanonicalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("encoding canonical JSON claims-set: %w", err)
+		}
+
+		if err := hdrs.Set(jws.TypeKey, "JWT"); err != nil {
+			return nil, fmt.Errorf("setting typ: %w", err)
+		}
+
+		signed, err = jws.Sign(payload, jws.WithKey(alg, key, jws.WithProtectedHeaders(hdrs)))
+		if err != nil {
+			return nil, err
+		}
+	} else if signed, err = jwt.Sign(token, jwt.WithKey(alg, key, jws.WithProtectedHeaders(hdrs))); err != nil {
+		return nil, err
+	}
+
+	if err := so.signed(&o); err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+// prepareForSigning computes the evidence digests, if requested, validates the
+// claims-set and runs the before-sign hooks.  Since hooks can modify the
+// claims-set, it is validated again once they have run.
+func (o *AttestationResult) prepareForSigning(so *signOptions) error {
+	if so.evidenceDigest {
+		if err := o.addAnnotatedEvidenceDigests(); err != nil {
+			return err
+		}
+	}
+
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	if len(so.beforeSign) == 0 {
+		return nil
+	}
+
+	if err := runHooks(so.beforeSign, o); err != nil {
+		return fmt.Errorf("before-sign hook: %w", err)
+	}
+
+	if err := o.validate(); err != nil {
+		return fmt.Errorf("before-sign hook: %w", err)
+	}
+
+	return nil
+}
+
+func (o *AttestationResult) decodeMap(m map[string]interface{}, do *decodeOptions) error {
+	if do.normalizer != nil {
+		if err := do.normalizer.Normalize(m); err != nil {
+			return fmt.Errorf("normalizing claims-set: %w", err)
+		}
+	}
+
+	if do.decrypt != nil {
+		if err := do.decrypt(m); err != nil {
+			return fmt.Errorf("decrypting claims: %w", err)
+		}
+	}
+
+	if err := checkRawEvidenceSize(m, do.maxRawEvidence); err != nil {
+		return err
+	}
+
+	extra := getExtraKeys(m, knownClaims())
+	sort.Strings(extra)
+
+	if do.registry != nil {
+		o.Registry = do.registry
+	}
+
+	o.UnknownClaims = nil
+
+	if len(extra) > 0 {
+		switch do.unknown {
+		case UnknownClaimsReject:
+			return fmt.Errorf("unexpected: %s", strings.Join(extra, ", "))
+		case UnknownClaimsPreserve:
+			o.UnknownClaims = make(map[string]interface{}, len(extra))
+			for _, k := range extra {
+				o.UnknownClaims[k] = m[k]
+			}
+		}
+	}
+
+	return o.populateFromMap(m)
+}
+
+// knownClaims returns the names of the top-level claims modelled by
+// AttestationResult
+func knownClaims() []string {
+	return structTagNames(reflect.TypeOf(AttestationResult{}), "json")
+}
+
+func isKnownClaim(name string) bool {
+	return contains(knownClaims(), name)
 }
 
 func (o *AttestationResult) populateFromMap(m map[string]interface{}) error {
 	// entries not explicitly listed will use the stringPtrParser
 	parsers := map[string]parser{
 		"iat": int64PtrParser,
+		"exp": int64PtrParser,
+		"nbf": int64PtrParser,
+		"eat_profile": func(v interface{}) (interface{}, error) {
+			return ToProfile(v)
+		},
+		"eat_nonce": func(v interface{}) (interface{}, error) {
+			return ToNonces(v)
+		},
 		"ear.trustworthiness-vector": func(v interface{}) (interface{}, error) {
 			return ToTrustVector(v)
 		},
@@ -242,7 +770,7 @@ func (o *AttestationResult) populateFromMap(m map[string]interface{}) error {
 			var problems []string
 
 			for key, val := range vMap {
-				appraisal, err := ToAppraisal(val)
+				appraisal, err := toAppraisal(val, o.registry())
 				if err != nil {
 					problems = append(problems,
 						fmt.Sprintf("%s: %s", key, err.Error()))
@@ -264,6 +792,16 @@ func (o *AttestationResult) populateFromMap(m map[string]interface{}) error {
 		"ear.nae.tts-info": func(v interface{}) (interface{}, error) {
 			return ToNAETTSInfo(v)
 		},
+		"ear.veraison.migration": func(v interface{}) (interface{}, error) {
+			return ToVeraisonMigrationInfo(v)
+		},
+		"ear.veraison.encrypted-claims": func(v interface{}) (interface{}, error) {
+			return ToVeraisonEncryptedClaims(v)
+		},
+		"ear.veraison.evidence-ref": func(v interface{}) (interface{}, error) {
+			return ToVeraisonEvidenceRef(v)
+		},
+		"ear.veraison.timestamp": b64urlBytesPtrParser,
 	}
 
 	return populateStructFromMap(o, m, "json", parsers, stringPtrParser, true)
@@ -20,22 +20,36 @@ import (
 type Appraisal struct {
 	Status            *TrustTier   `json:"ear.status"`
 	TrustVector       *TrustVector `json:"ear.trustworthiness-vector,omitempty"`
-	AppraisalPolicyID *string      `json:"ear.appraisal-policy-id,omitempty"`
+	AppraisalPolicyID *PolicyIDs   `json:"ear.appraisal-policy-id,omitempty"`
 
 	AppraisalExtensions
 }
 
 // AppraisalExtensions contains any proprietary claims that can be optionally
-// attached to the Appraisal.  For now only veraison-specific extensions are
-// supported.
+// attached to the Appraisal.  Veraison-specific extensions are modelled
+// directly, while those defined elsewhere can be plugged in using
+// RegisterAppraisalExtension.
 type AppraisalExtensions struct {
 	VeraisonAnnotatedEvidence *map[string]interface{} `json:"ear.veraison.annotated-evidence,omitempty"`
 	VeraisonPolicyClaims      *map[string]interface{} `json:"ear.veraison.policy-claims,omitempty"`
 	VeraisonKeyAttestation    *map[string]interface{} `json:"ear.veraison.key-attestation,omitempty"`
+
+	VeraisonAnnotatedEvidenceDigest *string                  `json:"ear.veraison.annotated-evidence-digest,omitempty"`
+	VeraisonAppraisalQoS            *VeraisonAppraisalQoS    `json:"ear.veraison.appraisal-qos,omitempty"`
+	VeraisonClaimProvenance         *VeraisonClaimProvenance `json:"ear.veraison.claim-provenance,omitempty"`
+	VeraisonCCAInfo                 *VeraisonCCAInfo         `json:"ear.veraison.cca-info,omitempty"`
+	VeraisonTDXInfo                 *VeraisonTDXInfo         `json:"ear.veraison.tdx-info,omitempty"`
+	VeraisonSGXInfo                 *VeraisonSGXInfo         `json:"ear.veraison.sgx-info,omitempty"`
+	VeraisonSNPInfo                 *VeraisonSNPInfo         `json:"ear.veraison.snp-info,omitempty"`
+
+	// ExtensionClaims holds the claims belonging to registered
+	// AppraisalExtensions, indexed by claim name
+	ExtensionClaims map[string]interface{} `json:"-"`
 }
 
 // SetKeyAttestation sets the value of `akpub` in the
-// "ear.veraison.key-attestation" claim.
+// "ear.veraison.key-attestation" claim, replacing any previous key, but not
+// its "kid".
 // The following key types are currently supported: *rsa.PublicKey,
 // *ecdsa.PublicKey, ed25519.PublicKey (not a pointer).
 // Unsupported key types result in an error.
@@ -53,9 +67,9 @@ func (o *AppraisalExtensions) SetKeyAttestation(pub any) error {
 
 	akpub := base64.RawURLEncoding.EncodeToString(k)
 
-	o.VeraisonKeyAttestation = &map[string]interface{}{
-		"akpub": akpub,
-	}
+	o.setKeyAttestationValue(map[string]interface{}{
+		keyAttestationAKPub: akpub,
+	})
 
 	return nil
 }
@@ -98,13 +112,54 @@ func (o AppraisalExtensions) GetKeyAttestation() (any, error) {
 // that the overall result will not assert to be more trustworthy than
 // individual vector claims (though it could be less trustworthy if had been
 // manually set that way).
-func (o *Appraisal) UpdateStatusFromTrustVector() {
-	for _, claimValue := range o.TrustVector.AsMap() {
-		claimTier := claimValue.GetTier()
-		if *o.Status < claimTier {
-			*o.Status = claimTier
-		}
+//
+// The above is the default (WorstClaimPolicy) behaviour.  A different
+// StatusPolicy can be supplied using WithStatusPolicy, and claims can be made
+// advisory using WithAdvisoryClaims.
+func (o *Appraisal) UpdateStatusFromTrustVector(opts ...StatusOption) {
+	so := newStatusOptions(opts)
+
+	o.updateStatus(so.withAdvisory(so.policy))
+}
+
+func (o *Appraisal) updateStatus(policy StatusPolicy) {
+	if o.TrustVector == nil {
+		return
 	}
+
+	if o.Status == nil {
+		o.Status = NewTrustTier(TrustTierNone)
+	}
+
+	*o.Status = policy.DeriveStatus(*o.Status, *o.TrustVector)
+}
+
+// ExplainStatus is like UpdateStatusFromTrustVector, but also reports which
+// trust vector claims forced the resulting status, e.g., to tell why an
+// Appraisal is contraindicated.
+func (o *Appraisal) ExplainStatus(opts ...StatusOption) StatusExplanation {
+	so := newStatusOptions(opts)
+
+	return o.explainStatus(so.withAdvisory(so.policy))
+}
+
+func (o *Appraisal) explainStatus(policy StatusPolicy) StatusExplanation {
+	var e StatusExplanation
+
+	if o.Status != nil {
+		e.Previous = *o.Status
+	}
+
+	if o.TrustVector == nil {
+		e.Status = e.Previous
+		return e
+	}
+
+	status, claims := explainStatus(policy, e.Previous, *o.TrustVector)
+	e.Status, e.Claims = status, claims
+	o.Status = &status
+
+	return e
 }
 
 // AsMap returns a map[string]interface{} with EAR Appraisal claim names mapped
@@ -117,18 +172,74 @@ func (o Appraisal) AsMap() map[string]interface{} {
 		// constituents incorrectly implement AsMap() themselves.
 		panic(err)
 	}
+
+	o.addExtensionClaims(m)
+
 	return m
 }
 
 func (o Appraisal) validate() error {
+	return o.validateWith(defaultRegistry)
+}
+
+func (o Appraisal) validateWith(reg *Registry) error {
+	var ve ValidationError
+
 	if o.Status == nil {
-		return errors.New("missing mandatory 'ear.status'")
+		ve.addMissing("ear.status", "'ear.status'")
 	}
 
-	return nil
+	if o.AppraisalPolicyID != nil {
+		o.AppraisalPolicyID.validate(&ve)
+	}
+
+	if o.VeraisonKeyAttestation != nil {
+		if err := validateKeyAttestation(*o.VeraisonKeyAttestation); err != nil {
+			ve.addInvalid("ear.veraison.key-attestation",
+				fmt.Sprintf("'ear.veraison.key-attestation' (%s)", err), err)
+		}
+	}
+
+	if o.VeraisonCCAInfo != nil {
+		if err := o.VeraisonCCAInfo.validate(); err != nil {
+			ve.addInvalid("ear.veraison.cca-info",
+				fmt.Sprintf("'ear.veraison.cca-info' (%s)", err), err)
+		}
+	}
+
+	if o.VeraisonTDXInfo != nil {
+		if err := o.VeraisonTDXInfo.validate(); err != nil {
+			ve.addInvalid("ear.veraison.tdx-info",
+				fmt.Sprintf("'ear.veraison.tdx-info' (%s)", err), err)
+		}
+	}
+
+	if o.VeraisonSGXInfo != nil {
+		if err := o.VeraisonSGXInfo.validate(); err != nil {
+			ve.addInvalid("ear.veraison.sgx-info",
+				fmt.Sprintf("'ear.veraison.sgx-info' (%s)", err), err)
+		}
+	}
+
+	if o.VeraisonSNPInfo != nil {
+		if err := o.VeraisonSNPInfo.validate(); err != nil {
+			ve.addInvalid("ear.veraison.snp-info",
+				fmt.Sprintf("'ear.veraison.snp-info' (%s)", err), err)
+		}
+	}
+
+	o.validateExtensionClaims(reg, &ve)
+
+	return ve.orNil()
 }
 
+// ToAppraisal parses an appraisal decoded from JSON, recognising the
+// extensions registered with the default Registry
 func ToAppraisal(v interface{}) (*Appraisal, error) {
+	return toAppraisal(v, defaultRegistry)
+}
+
+func toAppraisal(v interface{}, reg *Registry) (*Appraisal, error) {
 	var appraisal Appraisal
 
 	m, ok := v.(map[string]interface{})
@@ -143,12 +254,37 @@ func ToAppraisal(v interface{}) (*Appraisal, error) {
 		"ear.trustworthiness-vector": func(v interface{}) (interface{}, error) {
 			return ToTrustVector(v)
 		},
+		"ear.appraisal-policy-id": func(v interface{}) (interface{}, error) {
+			return ToPolicyIDs(v)
+		},
 		"ear.veraison.annotated-evidence": stringMapPtrParser,
 		"ear.veraison.policy-claims":      stringMapPtrParser,
 		"ear.veraison.key-attestation":    stringMapPtrParser,
+		"ear.veraison.appraisal-qos": func(v interface{}) (interface{}, error) {
+			return ToVeraisonAppraisalQoS(v)
+		},
+		"ear.veraison.claim-provenance": func(v interface{}) (interface{}, error) {
+			return ToVeraisonClaimProvenance(v)
+		},
+		"ear.veraison.cca-info": func(v interface{}) (interface{}, error) {
+			return ToVeraisonCCAInfo(v)
+		},
+		"ear.veraison.tdx-info": func(v interface{}) (interface{}, error) {
+			return ToVeraisonTDXInfo(v)
+		},
+		"ear.veraison.sgx-info": func(v interface{}) (interface{}, error) {
+			return ToVeraisonSGXInfo(v)
+		},
+		"ear.veraison.snp-info": func(v interface{}) (interface{}, error) {
+			return ToVeraisonSNPInfo(v)
+		},
+	}
+
+	if err := populateStructFromMap(&appraisal, m, "json", parsers, stringPtrParser, true); err != nil {
+		return &appraisal, err
 	}
 
-	err := populateStructFromMap(&appraisal, m, "json", parsers, stringPtrParser, true)
+	err := appraisal.decodeExtensionClaims(reg, m)
 
 	return &appraisal, err
 }
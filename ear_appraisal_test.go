@@ -4,10 +4,13 @@
 package ear
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"testing"
+	"time"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -89,3 +92,268 @@ func TestAppraisalExtensions_GetKeyAttestation_fail_akpub_no_b64url(t *testing.T
 	_, err := tv.GetKeyAttestation()
 	assert.EqualError(t, err, `"ear.veraison.key-attestation" malformed: decoding "akpub": illegal base64 data at input byte 84`)
 }
+
+// testAppraisalExtensions returns appraisal extensions with the Veraison
+// platform-specific, QoS and provenance claims set
+func testAppraisalExtensions(t *testing.T) AppraisalExtensions {
+	var ext AppraisalExtensions
+
+	snpMeasurement := B64Url(bytes.Repeat([]byte{0x44}, 48))
+	snpReportID := B64Url(bytes.Repeat([]byte{0x55}, 32))
+	bootloader, tee, snp, microcode := uint8(3), uint8(0), uint8(8), uint8(115)
+	guestPolicy := SNPGuestPolicy(0x30000)
+
+	require.NoError(t, ext.SetSNPInfo(VeraisonSNPInfo{
+		Measurement: &snpMeasurement,
+		ReportID:    &snpReportID,
+		ReportedTCB: &SNPTCBVersion{
+			Bootloader: &bootloader,
+			TEE:        &tee,
+			SNP:        &snp,
+			Microcode:  &microcode,
+		},
+		GuestPolicy: &guestPolicy,
+	}))
+
+	rim := B64Url(bytes.Repeat([]byte{0xaa}, 32))
+	rimRef := "cca-ref/realm/1"
+	lc := CCALifecycleSecured | 0x01
+
+	require.NoError(t, ext.SetCCAInfo(VeraisonCCAInfo{
+		RealmInitialMeasurement: &rim,
+		RealmExtensibleMeasurements: []B64Url{
+			bytes.Repeat([]byte{0x01}, 32),
+			bytes.Repeat([]byte{0x02}, 32),
+		},
+		RIMReference:      &rimRef,
+		REMReferences:     []string{"cca-ref/rem/0", "cca-ref/rem/1"},
+		PlatformLifecycle: &lc,
+	}))
+
+	mrtd := B64Url(bytes.Repeat([]byte{0x11}, 48))
+	tdxStatus := IntelTCBUpToDate
+
+	require.NoError(t, ext.SetTDXInfo(VeraisonTDXInfo{
+		MRTD: &mrtd,
+		RTMRs: []B64Url{
+			bytes.Repeat([]byte{0x00}, 48),
+			bytes.Repeat([]byte{0x01}, 48),
+			bytes.Repeat([]byte{0x02}, 48),
+			bytes.Repeat([]byte{0x03}, 48),
+		},
+		TCBStatus: &tdxStatus,
+	}))
+
+	mrenclave := B64Url(bytes.Repeat([]byte{0x22}, 32))
+	mrsigner := B64Url(bytes.Repeat([]byte{0x33}, 32))
+	svn := uint16(3)
+	sgxStatus := IntelTCBSWHardeningNeeded
+
+	require.NoError(t, ext.SetSGXInfo(VeraisonSGXInfo{
+		MRENCLAVE: &mrenclave,
+		MRSIGNER:  &mrsigner,
+		ISVSVN:    &svn,
+		TCBStatus: &sgxStatus,
+	}))
+
+	var qos VeraisonAppraisalQoS
+	qos.SetDuration(2 * time.Millisecond)
+	qos.SetEndorsementLookups(0)
+	ext.VeraisonAppraisalQoS = &qos
+
+	require.NoError(t, ext.SetClaimProvenance("hardware", "rule-1"))
+	require.NoError(t, ext.SetClaimProvenance("configuration", "rule-2"))
+
+	return ext
+}
+
+func TestAppraisalExtensions_roundtrip(t *testing.T) {
+	ext := testAppraisalExtensions(t)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = map[string]*Appraisal{
+		"test": {Status: &testStatus, AppraisalExtensions: ext},
+	}
+
+	jsonData, err := ar.MarshalJSON()
+	require.NoError(t, err)
+
+	var fromJSON AttestationResult
+	require.NoError(t, fromJSON.UnmarshalJSON(jsonData))
+
+	cborData, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	var fromCBOR AttestationResult
+	require.NoError(t, fromCBOR.UnmarshalCBOR(cborData))
+
+	// measurements are byte strings in CBOR
+	rim, err := cbor.Marshal([]byte(*ext.VeraisonCCAInfo.RealmInitialMeasurement))
+	require.NoError(t, err)
+	assert.True(t, bytes.Contains(cborData, rim))
+
+	tvs := []struct {
+		get  func(AppraisalExtensions) interface{}
+		json string
+	}{
+		{
+			get:  func(e AppraisalExtensions) interface{} { return e.VeraisonSNPInfo },
+			json: `"reported-tcb":{"bootloader":3,"tee":0,"snp":8,"microcode":115}`,
+		},
+		{
+			get:  func(e AppraisalExtensions) interface{} { return e.VeraisonCCAInfo },
+			json: `"platform-lifecycle":12289`,
+		},
+		{
+			get: func(e AppraisalExtensions) interface{} { return e.VeraisonTDXInfo },
+		},
+		{
+			get: func(e AppraisalExtensions) interface{} { return e.VeraisonSGXInfo },
+		},
+		{
+			get:  func(e AppraisalExtensions) interface{} { return e.VeraisonAppraisalQoS },
+			json: `"ear.veraison.appraisal-qos":{"duration-us":2000,"endorsement-lookups":0}`,
+		},
+		{
+			get:  func(e AppraisalExtensions) interface{} { return e.VeraisonClaimProvenance },
+			json: `"ear.veraison.claim-provenance":{"configuration":"rule-2","hardware":"rule-1"}`,
+		},
+	}
+
+	for i, tv := range tvs {
+		assert.Contains(t, string(jsonData), tv.json, "failed test vector at index %d", i)
+		assert.Equal(t, tv.get(ext), tv.get(fromJSON.Submods["test"].AppraisalExtensions),
+			"failed test vector at index %d", i)
+		assert.Equal(t, tv.get(ext), tv.get(fromCBOR.Submods["test"].AppraisalExtensions),
+			"failed test vector at index %d", i)
+	}
+}
+
+func TestAppraisalExtensions_set_fail(t *testing.T) {
+	guestPolicy := SNPGuestPolicy(0x10000)
+	sgxStatus := IntelTCBStatus("Great")
+	rtmr := B64Url(bytes.Repeat([]byte{0x00}, 48))
+
+	tvs := []struct {
+		set      func(*AppraisalExtensions) error
+		expected string
+	}{
+		{
+			set: func(e *AppraisalExtensions) error {
+				return e.SetSNPInfo(VeraisonSNPInfo{GuestPolicy: &guestPolicy})
+			},
+			expected: `"guest-policy": reserved bit 17 not set in 0x10000`,
+		},
+		{
+			set: func(e *AppraisalExtensions) error {
+				return e.SetCCAInfo(VeraisonCCAInfo{
+					RealmExtensibleMeasurements: []B64Url{bytes.Repeat([]byte{0x01}, 32), {0x03}},
+				})
+			},
+			expected: `"rem"[1]: 1 bytes, expecting 32, 48 or 64`,
+		},
+		{
+			set: func(e *AppraisalExtensions) error {
+				return e.SetTDXInfo(VeraisonTDXInfo{RTMRs: []B64Url{rtmr, rtmr, rtmr, rtmr, rtmr}})
+			},
+			expected: `"rtmrs": 5 registers, at most 4 expected`,
+		},
+		{
+			set: func(e *AppraisalExtensions) error {
+				return e.SetSGXInfo(VeraisonSGXInfo{TCBStatus: &sgxStatus})
+			},
+			expected: `"tcb-status": unknown status "Great"`,
+		},
+		{
+			set: func(e *AppraisalExtensions) error {
+				return e.SetClaimProvenance("firmware", "psa/fw#1")
+			},
+			expected: `"firmware" is not a trustworthiness vector claim`,
+		},
+	}
+
+	for i, tv := range tvs {
+		ext := testAppraisalExtensions(t)
+		expected := ext
+
+		err := tv.set(&ext)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		assert.Equal(t, expected, ext, "failed test vector at index %d", i)
+	}
+}
+
+// toVeraisonErr adapts a ToVeraisonX function to TestToVeraisonExtension_fail
+func toVeraisonErr[T any](f func(interface{}) (T, error)) func(interface{}) error {
+	return func(v interface{}) error {
+		_, err := f(v)
+		return err
+	}
+}
+
+func TestToVeraisonExtension_fail(t *testing.T) {
+	var (
+		toSNP        = toVeraisonErr(ToVeraisonSNPInfo)
+		toCCA        = toVeraisonErr(ToVeraisonCCAInfo)
+		toTDX        = toVeraisonErr(ToVeraisonTDXInfo)
+		toSGX        = toVeraisonErr(ToVeraisonSGXInfo)
+		toQoS        = toVeraisonErr(ToVeraisonAppraisalQoS)
+		toProvenance = toVeraisonErr(ToVeraisonClaimProvenance)
+	)
+
+	tvs := []struct {
+		to       func(interface{}) error
+		v        interface{}
+		expected string
+	}{
+		{toSNP, "snp", `unexpected format for "snp-info"`},
+		{toSNP, map[string]interface{}{"measurement": "AAAA"}, `invalid value for "measurement": 3 bytes, expecting 48`},
+		{toSNP, map[string]interface{}{"report-id": 1.0}, `invalid value for "report-id": not a string`},
+		{toSNP, map[string]interface{}{"reported-tcb": 1.0}, `unexpected format for "reported-tcb"`},
+		{toSNP, map[string]interface{}{"reported-tcb": map[string]interface{}{"snp": 256.0}}, `invalid value for "snp": 256 out of range`},
+		{toSNP, map[string]interface{}{"reported-tcb": map[string]interface{}{"fmc": 1.0}}, `found unknown key "fmc" in "reported-tcb" object`},
+		{toSNP, map[string]interface{}{"guest-policy": -1.0}, `invalid value for "guest-policy": negative`},
+		{toSNP, map[string]interface{}{"guest-policy": 0.0}, `"guest-policy": reserved bit 17 not set in 0x0`},
+		{toSNP, map[string]interface{}{"vmpl": 0.0}, `found unknown key "vmpl" in "snp-info" object`},
+
+		{toCCA, "cca", `unexpected format for "cca-info"`},
+		{toCCA, map[string]interface{}{"rim": 1.0}, `invalid value for "rim": not a base64 string`},
+		{toCCA, map[string]interface{}{"rim": "AAAA"}, `"rim": 3 bytes, expecting 32, 48 or 64`},
+		{toCCA, map[string]interface{}{"rem": "AAAA"}, `invalid value for "rem": not an array`},
+		{toCCA, map[string]interface{}{"rem": []interface{}{1.0}}, `invalid value for "rem"[0]: not a base64 string`},
+		{toCCA, map[string]interface{}{"rim-ref": ""}, `"rim-ref": empty reference`},
+		{toCCA, map[string]interface{}{"rem-refs": []interface{}{"a", "b", "c", "d", "e"}}, `"rem-refs": 5 references, at most 4 expected`},
+		{toCCA, map[string]interface{}{"platform-lifecycle": 28672.0}, `"platform-lifecycle": unknown state 0x7000`},
+		{toCCA, map[string]interface{}{"platform-lifecycle": 65536.0}, `invalid value for "platform-lifecycle": 65536 out of range`},
+		{toCCA, map[string]interface{}{"realm-pubkey": "AAAA"}, `found unknown key "realm-pubkey" in "cca-info" object`},
+
+		{toTDX, "tdx", `unexpected format for "tdx-info"`},
+		{toTDX, map[string]interface{}{"mrtd": 1.0}, `invalid value for "mrtd": not a string`},
+		{toTDX, map[string]interface{}{"mrtd": "!!"}, `invalid value for "mrtd": neither hex nor base64url`},
+		{toTDX, map[string]interface{}{"mrtd": "AAAA"}, `invalid value for "mrtd": 3 bytes, expecting 48`},
+		{toTDX, map[string]interface{}{"rtmrs": "AAAA"}, `invalid value for "rtmrs": not an array`},
+		{toTDX, map[string]interface{}{"tcb-status": 1.0}, `invalid value for "tcb-status": expecting string, found float64`},
+		{toTDX, map[string]interface{}{"tcb-status": "Fine"}, `"tcb-status": unknown status "Fine"`},
+		{toTDX, map[string]interface{}{"mrsigner": "AAAA"}, `found unknown key "mrsigner" in "tdx-info" object`},
+
+		{toSGX, "sgx", `unexpected format for "sgx-info"`},
+		{toSGX, map[string]interface{}{"mrsigner": "abcd"}, `invalid value for "mrsigner": 3 bytes, expecting 32`},
+		{toSGX, map[string]interface{}{"isv-svn": "1"}, `invalid value for "isv-svn": not an int64`},
+		{toSGX, map[string]interface{}{"isv-svn": 65536.0}, `invalid value for "isv-svn": 65536 out of range`},
+		{toSGX, map[string]interface{}{"mrtd": "AAAA"}, `found unknown key "mrtd" in "sgx-info" object`},
+
+		{toQoS, []interface{}{}, `unexpected format for "appraisal-qos"`},
+		{toQoS, map[string]interface{}{"duration-us": "fast"}, `invalid value for "duration-us": not an int64`},
+		{toQoS, map[string]interface{}{"evidence-size": -1.0}, `invalid value for "evidence-size": negative`},
+		{toQoS, map[string]interface{}{"cpu": 1.0}, `found unknown key "cpu" in "appraisal-qos" object`},
+
+		{toProvenance, []interface{}{}, `unexpected format for "claim-provenance"`},
+		{toProvenance, map[string]interface{}{"hardware": 1.0}, `invalid value for "hardware": expecting string, found float64`},
+		{toProvenance, map[string]interface{}{"cpu": "rule-1"}, `"cpu" is not a trustworthiness vector claim`},
+		{toProvenance, map[string]interface{}{"hardware": ""}, `empty rule ID for "hardware"`},
+		{toProvenance, map[string]interface{}{"hardware": "rule\n1"}, `rule ID for "hardware": contains control character U+000A`},
+	}
+
+	for i, tv := range tvs {
+		assert.EqualError(t, tv.to(tv.v), tv.expected, "failed test vector at index %d", i)
+	}
+}
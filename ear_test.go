@@ -4,8 +4,10 @@
 package ear
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -34,18 +36,18 @@ var (
 
 	testStatus     = TrustTierAffirming
 	testIAT        = int64(1666091373)
-	testPolicyID   = "policy://test/01234"
+	testPolicyID   = PolicyIDs{"policy://test/01234"}
 	testVerifierID = VerifierIdentity{
 		Build:     &testVidBuild,
 		Developer: &testVidDeveloper,
 	}
-	testProfile            = EatProfile
-	testUnsupportedProfile = "1.2.3.4.5"
-	testNonce              = "0123456789abcdef"
-	testBadNonce           = "1337"
-	testEvidenceID         = "405e0c3127e455ebc22361210b43ca9499ca80d3f6b1dc79b89fa35290cee3d9"
-	testEvidence           = []byte("evidence")
-	testTeeName            = "aws-nitro"
+	testProfile            Profile = EatProfile
+	testUnsupportedProfile Profile = "1.2.3.4.5"
+	testNonce                      = "0123456789abcdef"
+	testBadNonce                   = "1337"
+	testEvidenceID                 = "405e0c3127e455ebc22361210b43ca9499ca80d3f6b1dc79b89fa35290cee3d9"
+	testEvidence                   = []byte("evidence")
+	testTeeName                    = "aws-nitro"
 
 	testAttestationResultsWithVeraisonExtns = AttestationResult{
 		IssuedAt:   &testIAT,
@@ -122,12 +124,12 @@ func TestToJSON_fail(t *testing.T) {
 				IssuedAt:   &testIAT,
 				Profile:    &testProfile,
 				VerifierID: &testVerifierID,
-				Nonce:      &testBadNonce,
+				Nonce:      &Nonces{testBadNonce},
 				Submods: map[string]*Appraisal{
 					"test": {Status: &testTrustTier},
 				},
 			},
-			expected: `invalid value(s) for eat_nonce (4 bytes)`,
+			expected: `invalid value(s) for eat_nonce (3 bytes, expecting 8 to 64)`,
 		},
 	}
 
@@ -303,14 +305,14 @@ func TestUpdateStatusFromTrustVector(t *testing.T) {
 }
 
 func TestAsMap(t *testing.T) {
-	policyID := "foo"
+	policyID := PolicyIDs{"https://veraison.example/policy/foo"}
 
 	ar := NewAttestationResult("someScheme", "test", "test")
 	status := NewTrustTier(TrustTierAffirming)
 	ar.Submods["someScheme"].Status = status
 	ar.Submods["someScheme"].TrustVector.Executables = ApprovedRuntimeClaim
 	ar.Submods["someScheme"].AppraisalPolicyID = &policyID
-	ar.Nonce = &testNonce
+	ar.Nonce = &Nonces{testNonce}
 
 	expected := map[string]interface{}{
 		"submods": map[string]interface{}{
@@ -326,11 +328,11 @@ func TestAsMap(t *testing.T) {
 					"storage-opaque":    NoClaim,
 					"sourced-data":      NoClaim,
 				},
-				"ear.appraisal-policy-id": "foo",
+				"ear.appraisal-policy-id": policyID,
 			},
 		},
 		"eat_profile": EatProfile,
-		"eat_nonce":   testNonce,
+		"eat_nonce":   Nonces{testNonce},
 	}
 
 	m := ar.AsMap()
@@ -359,7 +361,7 @@ func Test_populateFromMap(t *testing.T) {
 					"storage-opaque":    0,
 					"sourced-data":      0,
 				},
-				"ear.appraisal-policy-id": "foo",
+				"ear.appraisal-policy-id": "https://veraison.example/policy/foo",
 			},
 		},
 		"ear.raw-evidence": "SSBkaWRuJ3QgZG8gaXQ",
@@ -374,7 +376,7 @@ func Test_populateFromMap(t *testing.T) {
 	err := ar.populateFromMap(m)
 	assert.NoError(t, err)
 	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
-	assert.Equal(t, EatProfile, *ar.Profile)
+	assert.Equal(t, EatProfile, ar.Profile.String())
 }
 
 func TestTrustTier_ColorString(t *testing.T) {
@@ -393,3 +395,394 @@ func TestNewAttestationResult(t *testing.T) {
 	assert.Equal(t, "testBuild", *ar.VerifierID.Build)
 	assert.Equal(t, "testDev", *ar.VerifierID.Developer)
 }
+
+func TestVerify_fail_unsupported_profile(t *testing.T) {
+	token := `eyJhbGciOiJFUzI1NiIsInR5cCI6IkpXVCJ9.eyJlYXIudmVyaWZpZXItaWQiOnsiYnVpbGQiOiJycnRyYXAtdjEuMC4wIiwiZGV2ZWxvcGVyIjoiQWNtZSBJbmMuIn0sImVhdF9wcm9maWxlIjoidGFnOmV4YW1wbGUuY29tLDIwMjQ6b3RoZXItcHJvZmlsZSIsImlhdCI6MTY2NjA5MTM3Mywic3VibW9kcyI6eyJ0ZXN0Ijp7ImVhci5hcHByYWlzYWwtcG9saWN5LWlkIjoicG9saWN5Oi8vdGVzdC8wMTIzNCIsImVhci5zdGF0dXMiOiJhZmZpcm1pbmciLCJlYXIudmVyYWlzb24uYW5ub3RhdGVkLWV2aWRlbmNlIjp7ImsxIjoidjEiLCJrMiI6InYyIn0sImVhci52ZXJhaXNvbi5rZXktYXR0ZXN0YXRpb24iOnsiYWtwdWIiOiJZV3R3ZFdJSyJ9LCJlYXIudmVyYWlzb24ucG9saWN5LWNsYWltcyI6eyJiYXIiOiJiYXoiLCJmb28iOiJiYXIifX19fQ.Up3NdchdE48ZfdSntA0fsuM2VqyBlVFCWCfo36ulzupn4BYfEVae719ojPQRxiK7_mOEfLgVQjLfskw-tTqPPw`
+
+	k, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.Verify([]byte(token), jwa.ES256, k)
+	assert.EqualError(t, err, `unsupported eat_profile "tag:example.com,2024:other-profile" (accepted: "tag:github.com,2023:veraison/ear")`)
+
+	var profileErr ProfileError
+	require.True(t, errors.As(err, &profileErr))
+	assert.Equal(t, "tag:example.com,2024:other-profile", profileErr.Received)
+	assert.Equal(t, []string{EatProfile}, profileErr.Accepted)
+}
+
+func TestValidate_ProfileError(t *testing.T) {
+	ar := AttestationResult{
+		IssuedAt:   &testIAT,
+		VerifierID: &testVerifierID,
+		Profile:    &testUnsupportedProfile,
+		Submods: map[string]*Appraisal{
+			"test": {Status: &testStatus},
+		},
+	}
+
+	_, err := ar.MarshalJSON()
+	assert.EqualError(t, err, `invalid value(s) for eat_profile (1.2.3.4.5)`)
+
+	var profileErr ProfileError
+	require.True(t, errors.As(err, &profileErr))
+	assert.Equal(t, testUnsupportedProfile.String(), profileErr.Received)
+}
+
+func TestUpdateSubmod(t *testing.T) {
+	ar := NewAttestationResult("test", "test", "test")
+	ar.Submods["other"] = &Appraisal{Status: NewTrustTier(TrustTierAffirming)}
+
+	iat := int64(0)
+	ar.IssuedAt = &iat
+
+	err := ar.UpdateSubmod("test", func(a *Appraisal) {
+		a.TrustVector.Executables = UnrecognizedRuntimeClaim
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, TrustTierWarning, *ar.Submods["test"].Status)
+	assert.NotZero(t, *ar.IssuedAt)
+
+	// other submods are not affected
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["other"].Status)
+
+	err = ar.UpdateSubmod("test", func(a *Appraisal) {
+		a.TrustVector.FileSystem = ContraindicatedFilesClaim
+	}, WithAdvisoryClaims("file-system"))
+	require.NoError(t, err)
+	assert.Equal(t, TrustTierWarning, *ar.Submods["test"].Status)
+}
+
+func TestUpdateSubmod_improving_trust_vector(t *testing.T) {
+	ar := NewAttestationResult("test", "test", "test")
+
+	ts := B64Url{0x30}
+	ar.VeraisonTimestamp = &ts
+
+	err := ar.UpdateSubmod("test", func(a *Appraisal) {
+		a.TrustVector.Executables = ContraindicatedRuntimeClaim
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TrustTierContraindicated, *ar.Submods["test"].Status)
+	assert.Nil(t, ar.VeraisonTimestamp)
+
+	// the re-appraisal finds that the runtime is fine after all
+	err = ar.UpdateSubmod("test", func(a *Appraisal) {
+		a.TrustVector.Executables = ApprovedRuntimeClaim
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
+
+	err = ar.UpdateSubmod("test", func(a *Appraisal) {
+		a.TrustVector.Executables = UnrecognizedRuntimeClaim
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TrustTierWarning, *ar.Submods["test"].Status)
+
+	// with no trust vector, the status is the one set by the update
+	err = ar.UpdateSubmod("test", func(a *Appraisal) {
+		a.TrustVector = nil
+		a.Status = NewTrustTier(TrustTierAffirming)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
+}
+
+func TestUpdateSubmod_not_found(t *testing.T) {
+	ar := NewAttestationResult("test", "test", "test")
+
+	err := ar.UpdateSubmod("missing", func(*Appraisal) {})
+	assert.EqualError(t, err, `submod "missing" not found`)
+}
+
+func TestVerify_exp_nbf(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	exp, nbf := testIAT+3600, testIAT+600
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Expiry = &exp
+	ar.NotBefore = &nbf
+
+	token, err := ar.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		now      int64
+		skew     time.Duration
+		expected string
+	}{
+		{now: nbf + 60},
+		{now: nbf - 60, expected: `"nbf" not satisfied`},
+		{now: nbf - 60, skew: 2 * time.Minute},
+		{now: exp + 60, expected: `"exp" not satisfied`},
+		{now: exp + 60, skew: 2 * time.Minute},
+	}
+
+	for i, tv := range tvs {
+		var actual AttestationResult
+
+		clock := func() time.Time { return time.Unix(tv.now, 0) }
+
+		err := actual.Verify(token, jwa.ES256, vfyK,
+			WithClock(clock), WithAcceptableSkew(tv.skew))
+
+		if tv.expected == "" {
+			require.NoError(t, err, "failed test vector at index %d", i)
+			assert.Equal(t, ar, actual, "failed test vector at index %d", i)
+		} else {
+			assert.ErrorContains(t, err, tv.expected, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestVerify_max_token_age(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		now      int64
+		skew     time.Duration
+		expected string
+	}{
+		{now: testIAT + 300},
+		{now: testIAT + 360, expected: "result is too old (iat: 1666091373, max age: 5m0s)"},
+		{now: testIAT + 360, skew: 2 * time.Minute},
+	}
+
+	for i, tv := range tvs {
+		var actual AttestationResult
+
+		clock := func() time.Time { return time.Unix(tv.now, 0) }
+
+		err := actual.Verify(token, jwa.ES256, vfyK, WithClock(clock),
+			WithAcceptableSkew(tv.skew), WithMaxTokenAge(5*time.Minute))
+
+		if tv.expected == "" {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+		} else {
+			assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestVerify_algorithm_expectations(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	es256, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	hmacKey, err := jwk.FromRaw([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	hs256, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.HS256, hmacKey)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		token    []byte
+		alg      jwa.SignatureAlgorithm
+		opts     []VerifyOption
+		expected *AlgorithmError
+	}{
+		{
+			token: es256,
+			alg:   jwa.ES256,
+			opts:  []VerifyOption{WithStrictAlgorithm(), WithAllowedAlgorithms(jwa.ES256, jwa.ES384)},
+		},
+		{
+			token:    es256,
+			alg:      jwa.ES384,
+			opts:     []VerifyOption{WithStrictAlgorithm()},
+			expected: &AlgorithmError{Received: "ES256", Accepted: []string{"ES384"}},
+		},
+		{
+			token:    hs256,
+			alg:      jwa.ES256,
+			opts:     []VerifyOption{WithAllowedAlgorithms(jwa.ES256, jwa.ES384)},
+			expected: &AlgorithmError{Received: "HS256", Accepted: []string{"ES256", "ES384"}},
+		},
+	}
+
+	for i, tv := range tvs {
+		var actual AttestationResult
+
+		err := actual.Verify(tv.token, tv.alg, vfyK, tv.opts...)
+
+		if tv.expected == nil {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+			continue
+		}
+
+		var ae AlgorithmError
+		require.True(t, errors.As(err, &ae), "failed test vector at index %d", i)
+		assert.Equal(t, *tv.expected, ae, "failed test vector at index %d", i)
+	}
+}
+
+func TestVerifyCWT_VerifySD_algorithm_expectations(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	cwt, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	sigK, vfyK := testKeyPair(t)
+
+	sd, err := testAttestationResultsWithVeraisonExtns.SignSD(jwa.ES256, sigK, nil)
+	require.NoError(t, err)
+
+	verifyCWT := func(ar *AttestationResult, _ jwa.SignatureAlgorithm, opts ...VerifyOption) error {
+		return ar.VerifyCWT(cwt, verifier, opts...)
+	}
+
+	verifySD := func(ar *AttestationResult, alg jwa.SignatureAlgorithm, opts ...VerifyOption) error {
+		return ar.VerifySD(sd.Bytes(), alg, vfyK, opts...)
+	}
+
+	tvs := []struct {
+		verify   func(*AttestationResult, jwa.SignatureAlgorithm, ...VerifyOption) error
+		alg      jwa.SignatureAlgorithm
+		opts     []VerifyOption
+		expected *AlgorithmError
+	}{
+		{
+			verify: verifyCWT,
+			opts:   []VerifyOption{WithStrictAlgorithm(), WithAllowedAlgorithms(jwa.ES256, jwa.ES384)},
+		},
+		{
+			verify:   verifyCWT,
+			opts:     []VerifyOption{WithAllowedAlgorithms(jwa.ES384, jwa.EdDSA)},
+			expected: &AlgorithmError{Received: "ES256", Accepted: []string{"ES384", "EdDSA"}},
+		},
+		{
+			verify: verifySD,
+			alg:    jwa.ES256,
+			opts:   []VerifyOption{WithStrictAlgorithm(), WithAllowedAlgorithms(jwa.ES256)},
+		},
+		{
+			verify:   verifySD,
+			alg:      jwa.ES384,
+			opts:     []VerifyOption{WithStrictAlgorithm()},
+			expected: &AlgorithmError{Received: "ES256", Accepted: []string{"ES384"}},
+		},
+		{
+			verify:   verifySD,
+			alg:      jwa.ES256,
+			opts:     []VerifyOption{WithAllowedAlgorithms(jwa.PS256)},
+			expected: &AlgorithmError{Received: "ES256", Accepted: []string{"PS256"}},
+		},
+	}
+
+	for i, tv := range tvs {
+		var actual AttestationResult
+
+		err := tv.verify(&actual, tv.alg, tv.opts...)
+
+		if tv.expected == nil {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+			continue
+		}
+
+		var ae AlgorithmError
+		require.True(t, errors.As(err, &ae), "failed test vector at index %d", i)
+		assert.Equal(t, *tv.expected, ae, "failed test vector at index %d", i)
+	}
+}
+
+func TestAlgorithmError_Error(t *testing.T) {
+	err := AlgorithmError{Received: "HS256", Accepted: []string{"ES256", "PS256"}}
+	assert.EqualError(t, err, `JWS alg "HS256" is not accepted (accepted: "ES256", "PS256")`)
+}
+
+func TestValidate_exp_before_nbf(t *testing.T) {
+	exp, nbf := testIAT, testIAT+1
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Expiry = &exp
+	ar.NotBefore = &nbf
+
+	_, err := ar.MarshalJSON()
+	assert.EqualError(t, err, "invalid value(s) for exp (1666091373 is before nbf)")
+}
+
+func TestAttestationResult_Validate(t *testing.T) {
+	assert.NoError(t, testAttestationResultsWithVeraisonExtns.Validate())
+
+	var ar AttestationResult
+
+	err := ar.Validate()
+
+	var ve *ValidationError
+	require.True(t, errors.As(err, &ve))
+	assert.Equal(t, []string{"eat_profile", "iat", "ear.verifier-id", "submods"}, ve.Missing())
+}
+
+func TestDecodeJSON_unknown_claims(t *testing.T) {
+	data := []byte(`{
+		"eat_profile": "tag:github.com,2023:veraison/ear",
+		"iat": 1666091373,
+		"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."},
+		"submods": {"test": {"ear.status": "affirming"}},
+		"ear.future-claim": {"a": [1, 2]},
+		"another": "x"
+	}`)
+
+	var ar AttestationResult
+
+	err := ar.DecodeJSON(data)
+	require.NoError(t, err)
+	assert.Nil(t, ar.UnknownClaims)
+
+	err = ar.DecodeJSON(data, WithUnknownClaims(UnknownClaimsReject))
+	assert.EqualError(t, err, "unexpected: another, ear.future-claim")
+
+	err = ar.DecodeJSON(data, WithUnknownClaims(UnknownClaimsPreserve))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"ear.future-claim": map[string]interface{}{"a": []interface{}{1.0, 2.0}},
+		"another":          "x",
+	}, ar.UnknownClaims)
+
+	out, err := ar.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(out))
+}
+
+func TestAsMap_unknown_claims_do_not_override(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.UnknownClaims = map[string]interface{}{
+		"eat_profile": "smuggled",
+		"eat_nonce":   "smuggled",
+		"x":           true,
+	}
+
+	m := ar.AsMap()
+	assert.Equal(t, EatProfile, m["eat_profile"])
+	assert.NotContains(t, m, "eat_nonce")
+	assert.Equal(t, true, m["x"])
+}
+
+func TestVerify_preserve_unknown_claims(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	expected := testAttestationResultsWithVeraisonExtns
+	expected.UnknownClaims = map[string]interface{}{"ear.future-claim": "x"}
+
+	token, err := expected.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.Verify(token, jwa.ES256, vfyK,
+		WithDecodeOptions(WithUnknownClaims(UnknownClaimsPreserve)))
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package eartest
+
+import (
+	"testing"
+
+	"github.com/veraison/ear"
+)
+
+// AssertStatus fails the test if the named submod is missing from the EAR,
+// or if its status is not the expected one
+func AssertStatus(t testing.TB, ar *ear.AttestationResult, submod string, expected ear.TrustTier) {
+	t.Helper()
+
+	if ar == nil {
+		t.Fatalf("nil attestation result")
+	}
+
+	a, ok := ar.Submods[submod]
+	if !ok {
+		t.Fatalf("submod %q not found", submod)
+	}
+
+	if a.Status == nil {
+		t.Fatalf("submod %q: no status", submod)
+	}
+
+	if *a.Status != expected {
+		t.Errorf("submod %q: expecting status %s, got %s", submod, expected, *a.Status)
+	}
+}
+
+// AssertAffirming fails the test unless all the submods in the EAR are
+// affirming
+func AssertAffirming(t testing.TB, ar *ear.AttestationResult) {
+	t.Helper()
+
+	if ar == nil {
+		t.Fatalf("nil attestation result")
+	}
+
+	for name := range ar.Submods {
+		AssertStatus(t, ar, name, ear.TrustTierAffirming)
+	}
+}
+
+// AssertIssued fails the test unless the verifier has issued exactly n EARs
+func AssertIssued(t testing.TB, v *MockVerifier, n int) {
+	t.Helper()
+
+	if issued := len(v.Issued()); issued != n {
+		t.Errorf("expecting %d issued EARs, got %d", n, issued)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package eartest provides an in-process mock verifier and relying party that
+exchange challenges and EARs over HTTP, so that attestation flows can be
+integration-tested without a real Veraison deployment:
+
+	v := eartest.NewMockVerifier()
+	defer v.Close()
+
+	rp, err := eartest.NewMockRelyingParty(ctx, v.URL)
+	if err != nil {
+		// ...
+	}
+
+	ar, err := rp.Attest(ctx, evidence)
+	if err != nil {
+		// ...
+	}
+
+	eartest.AssertStatus(t, ar, eartest.DefaultSubmod, ear.TrustTierAffirming)
+
+The mock verifier exposes the following endpoints:
+
+  - POST /challenge returns a fresh nonce, as {"nonce": "<base64url>"};
+  - POST /appraise takes {"nonce": "<base64url>", "evidence": "<base64>"}
+    and returns the EAR (as a JWT) bound to the nonce;
+  - GET /.well-known/jwks.json returns the EAR verification key.
+*/
+package eartest
@@ -0,0 +1,101 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package eartest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+func TestMockFlow_affirming(t *testing.T) {
+	v := NewMockVerifier()
+	defer v.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rp, err := NewMockRelyingParty(ctx, v.URL)
+	require.NoError(t, err)
+
+	ar, err := rp.Attest(ctx, []byte("evidence"))
+	require.NoError(t, err)
+
+	AssertAffirming(t, ar)
+	AssertStatus(t, ar, DefaultSubmod, ear.TrustTierAffirming)
+	AssertIssued(t, v, 1)
+}
+
+func TestMockFlow_custom_appraisal(t *testing.T) {
+	v := NewMockVerifier()
+	defer v.Close()
+
+	v.SetAppraise(func(evidence []byte) *ear.Appraisal {
+		if bytes.Equal(evidence, []byte("tampered")) {
+			return ear.NewAppraisal(ear.TrustTierContraindicated)
+		}
+		return ear.NewAppraisal(ear.TrustTierAffirming)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rp, err := NewMockRelyingParty(ctx, v.URL)
+	require.NoError(t, err)
+
+	ar, err := rp.Attest(ctx, []byte("tampered"))
+	require.NoError(t, err)
+
+	AssertStatus(t, ar, DefaultSubmod, ear.TrustTierContraindicated)
+}
+
+func TestMockVerifier_nonce_reuse(t *testing.T) {
+	v := NewMockVerifier()
+	defer v.Close()
+
+	body := []byte(`{"nonce": "never-issued", "evidence": ""}`)
+
+	res, err := http.Post(v.URL+"/appraise", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	AssertIssued(t, v, 0)
+}
+
+func TestMockRelyingParty_wrong_nonce(t *testing.T) {
+	v := NewMockVerifier()
+	defer v.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rp, err := NewMockRelyingParty(ctx, v.URL)
+	require.NoError(t, err)
+
+	var challenge ChallengeResponse
+
+	body, err := rp.post(ctx, "/challenge", nil, http.StatusCreated)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(body, &challenge))
+
+	req, err := json.Marshal(AppraiseRequest{Nonce: challenge.Nonce})
+	require.NoError(t, err)
+
+	token, err := rp.post(ctx, "/appraise", req, http.StatusOK)
+	require.NoError(t, err)
+
+	_, err = rp.Verify(ctx, token, "AAECAwQFBgcICQoLDA0ODw")
+	assert.EqualError(t, err, "EAR is not bound to the challenge nonce")
+
+	ar, err := rp.Verify(ctx, token, challenge.Nonce)
+	require.NoError(t, err)
+	AssertAffirming(t, ar)
+}
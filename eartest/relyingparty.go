@@ -0,0 +1,110 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package eartest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/veraison/ear"
+)
+
+// MockRelyingParty drives the challenge-response exchange with a verifier and
+// checks the EARs it gets back: the signature is verified using the keys
+// published by the verifier, and the eat_nonce must match the challenge.
+type MockRelyingParty struct {
+	verifierURL string
+	client      *http.Client
+	resolver    ear.KeyResolver
+}
+
+// NewMockRelyingParty returns a relying party that talks to the verifier at
+// verifierURL (e.g., MockVerifier.URL).  Verification keys are fetched from
+// the verifier's JWKS endpoint, which stops being refreshed when ctx is done.
+func NewMockRelyingParty(ctx context.Context, verifierURL string) (*MockRelyingParty, error) {
+	resolver, err := ear.NewJWKSResolver(ctx, verifierURL+JWKSPath, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MockRelyingParty{
+		verifierURL: verifierURL,
+		client:      http.DefaultClient,
+		resolver:    resolver,
+	}, nil
+}
+
+// Attest obtains a challenge from the verifier, submits the evidence with it,
+// and returns the verified EAR
+func (o *MockRelyingParty) Attest(ctx context.Context, evidence []byte) (*ear.AttestationResult, error) {
+	var challenge ChallengeResponse
+
+	body, err := o.post(ctx, "/challenge", nil, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(body, &challenge); err != nil {
+		return nil, fmt.Errorf("decoding challenge: %w", err)
+	}
+
+	req, err := json.Marshal(AppraiseRequest{Nonce: challenge.Nonce, Evidence: evidence})
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := o.post(ctx, "/appraise", req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Verify(ctx, token, challenge.Nonce)
+}
+
+// Verify checks an EAR obtained out of band (e.g., presented by an attester
+// in a passport model flow) against the expected nonce
+func (o *MockRelyingParty) Verify(ctx context.Context, token []byte, nonce string) (*ear.AttestationResult, error) {
+	var ar ear.AttestationResult
+
+	if err := ar.VerifyContext(ctx, token, o.resolver); err != nil {
+		return nil, fmt.Errorf("verifying EAR: %w", err)
+	}
+
+	if ar.Nonce == nil || !ar.Nonce.Contains(nonce) {
+		return nil, errors.New("EAR is not bound to the challenge nonce")
+	}
+
+	return &ar, nil
+}
+
+func (o *MockRelyingParty) post(ctx context.Context, path string, body []byte, status int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.verifierURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != status {
+		return nil, fmt.Errorf("POST %s: %s: %s", path, res.Status, bytes.TrimSpace(data))
+	}
+
+	return data, nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package eartest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/veraison/ear"
+)
+
+const (
+	// DefaultSubmod is the name of the submod in the EARs issued by the
+	// mock verifier
+	DefaultSubmod = "mock"
+
+	// JWKSPath is where the mock verifier publishes its verification key
+	JWKSPath = "/.well-known/jwks.json"
+
+	mockVerifierBuild     = "mock-verifier"
+	mockVerifierDeveloper = "Veraison eartest"
+)
+
+// AppraiseFunc computes the appraisal of the supplied evidence
+type AppraiseFunc func(evidence []byte) *ear.Appraisal
+
+// ChallengeResponse is the body returned by the /challenge endpoint
+type ChallengeResponse struct {
+	Nonce string `json:"nonce"`
+}
+
+// AppraiseRequest is the body expected by the /appraise endpoint
+type AppraiseRequest struct {
+	Nonce    string `json:"nonce"`
+	Evidence []byte `json:"evidence"`
+}
+
+// MockVerifier is an HTTP verifier that hands out nonces and issues EARs
+// signed with an ephemeral ES256 key.  Unless otherwise configured with
+// SetAppraise, every piece of evidence is appraised as affirming.
+type MockVerifier struct {
+	// URL is the base URL of the verifier, e.g., http://127.0.0.1:1234
+	URL string
+
+	srv *httptest.Server
+	key jwk.Key
+	pub jwk.Set
+
+	mu       sync.Mutex
+	appraise AppraiseFunc
+	nonces   map[string]bool
+	issued   []ear.AttestationResult
+}
+
+// NewMockVerifier starts a mock verifier.  The caller must invoke Close when
+// done with it.
+func NewMockVerifier() *MockVerifier {
+	raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	key, pub := mustKeyPair(raw)
+
+	o := &MockVerifier{
+		key:    key,
+		pub:    pub,
+		nonces: map[string]bool{},
+		appraise: func([]byte) *ear.Appraisal {
+			return ear.NewAppraisal(ear.TrustTierAffirming)
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/challenge", o.handleChallenge)
+	mux.HandleFunc("/appraise", o.handleAppraise)
+	mux.HandleFunc(JWKSPath, o.handleJWKS)
+
+	o.srv = httptest.NewServer(mux)
+	o.URL = o.srv.URL
+
+	return o
+}
+
+func mustKeyPair(raw *ecdsa.PrivateKey) (jwk.Key, jwk.Set) {
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	pub, err := key.PublicKey()
+	if err != nil {
+		panic(err)
+	}
+
+	tp, err := pub.Thumbprint(crypto.SHA256)
+	if err != nil {
+		panic(err)
+	}
+
+	kid := base64.RawURLEncoding.EncodeToString(tp)
+
+	for _, k := range []jwk.Key{key, pub} {
+		if err := k.Set(jwk.KeyIDKey, kid); err != nil {
+			panic(err)
+		}
+		if err := k.Set(jwk.AlgorithmKey, jwa.ES256); err != nil {
+			panic(err)
+		}
+	}
+
+	set := jwk.NewSet()
+	if err := set.AddKey(pub); err != nil {
+		panic(err)
+	}
+
+	return key, set
+}
+
+// Close shuts the verifier down
+func (o *MockVerifier) Close() {
+	o.srv.Close()
+}
+
+// SetAppraise replaces the function used to appraise the evidence
+func (o *MockVerifier) SetAppraise(f AppraiseFunc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.appraise = f
+}
+
+// Issued returns the EARs that have been issued so far, in order
+func (o *MockVerifier) Issued() []ear.AttestationResult {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]ear.AttestationResult(nil), o.issued...)
+}
+
+// VerificationKeys returns the JWKS containing the EAR verification key
+func (o *MockVerifier) VerificationKeys() jwk.Set {
+	return o.pub
+}
+
+func (o *MockVerifier) handleChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+
+	o.mu.Lock()
+	o.nonces[nonce] = true
+	o.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, ChallengeResponse{Nonce: nonce})
+}
+
+func (o *MockVerifier) handleAppraise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AppraiseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "malformed request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// nonces are single use
+	if !o.nonces[req.Nonce] {
+		http.Error(w, "unknown or already used nonce", http.StatusBadRequest)
+		return
+	}
+	delete(o.nonces, req.Nonce)
+
+	ar, err := ear.NewAttestationResultBuilder().
+		WithVerifier(mockVerifierBuild, mockVerifierDeveloper).
+		WithNonce(req.Nonce).
+		AddSubmod(DefaultSubmod, o.appraise(req.Evidence)).
+		Build()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	token, err := ar.Sign(jwa.ES256, o.key, ear.WithKeyID(o.key.KeyID()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	o.issued = append(o.issued, *ar)
+
+	w.Header().Set("Content-Type", "application/jwt")
+	_, _ = w.Write(token)
+}
+
+func (o *MockVerifier) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, o.pub)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// DecodeClaimsValue decodes an EAR claims-set that is embedded in another
+// token, e.g., as the value of a claim of an access token.  The supported
+// representations are:
+//
+//   - a JSON object, as decoded by encoding/json (map[string]interface{}), or
+//     as raw JSON ([]byte or json.RawMessage)
+//   - a string containing the base64 (either alphabet, with or without
+//     padding) encoding of the JSON claims-set
+//   - a string containing a compact JWS (i.e., a signed EAR)
+//
+// The decoded claims-set is validated.  Note that the signature of an
+// embedded JWS is NOT verified: if the outer token cannot vouch for the EAR,
+// use Verify on the embedded string instead.
+func DecodeClaimsValue(v any, opts ...DecodeOption) (*AttestationResult, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if data, err = json.Marshal(t); err != nil {
+			return nil, fmt.Errorf("encoding claims-set: %w", err)
+		}
+	case json.RawMessage:
+		data = t
+	case []byte:
+		data = t
+	case string:
+		if data, err = embeddedClaimsFromString(t); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported representation of an EAR claims-set: %T", v)
+	}
+
+	var ar AttestationResult
+
+	if err := ar.DecodeJSON(data, opts...); err != nil {
+		return nil, err
+	}
+
+	return &ar, nil
+}
+
+func embeddedClaimsFromString(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.Count(s, ".") == 2 {
+		msg, err := jws.Parse([]byte(s))
+		if err != nil {
+			return nil, fmt.Errorf("parsing embedded JWS: %w", err)
+		}
+		return msg.Payload(), nil
+	}
+
+	for _, enc := range []*base64.Encoding{
+		base64.RawURLEncoding,
+		base64.URLEncoding,
+		base64.RawStdEncoding,
+		base64.StdEncoding,
+	} {
+		data, err := enc.DecodeString(s)
+		if err == nil && bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			return data, nil
+		}
+	}
+
+	return nil, errors.New("neither a compact JWS nor a base64-encoded claims-set")
+}
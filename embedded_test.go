@@ -0,0 +1,80 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeClaimsValue_ok(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	j, err := testAttestationResultsWithVeraisonExtns.MarshalJSON()
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(j, &m))
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	// an access token carrying the EAR as a nested object
+	var outer map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"sub": "alice", "ear": `+string(j)+`}`), &outer))
+
+	tvs := []interface{}{
+		m,
+		outer["ear"],
+		j,
+		json.RawMessage(j),
+		base64.RawURLEncoding.EncodeToString(j),
+		base64.StdEncoding.EncodeToString(j),
+		string(token),
+	}
+
+	for i, tv := range tvs {
+		ar, err := DecodeClaimsValue(tv)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, testAttestationResultsWithVeraisonExtns.AsMap(), ar.AsMap(), "failed test vector at index %d", i)
+	}
+}
+
+func TestDecodeClaimsValue_fail(t *testing.T) {
+	tvs := []struct {
+		v        interface{}
+		expected string
+	}{
+		{
+			42,
+			"unsupported representation of an EAR claims-set: int",
+		},
+		{
+			"not base64!",
+			"neither a compact JWS nor a base64-encoded claims-set",
+		},
+		{
+			base64.StdEncoding.EncodeToString([]byte(`"a string"`)),
+			"neither a compact JWS nor a base64-encoded claims-set",
+		},
+		{
+			"a.b.c",
+			"parsing embedded JWS: failed to decode protected headers: failed to decode source: illegal base64 data at input byte 0",
+		},
+		{
+			map[string]interface{}{"iat": 1666091373},
+			"missing mandatory 'eat_profile', 'ear.verifier-id', 'submods'",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := DecodeClaimsValue(tv.v)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
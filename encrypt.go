@@ -0,0 +1,117 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+)
+
+// EncryptOption configures the behaviour of AttestationResult.Encrypt
+type EncryptOption func(*encryptOptions)
+
+type encryptOptions struct {
+	contentAlg jwa.ContentEncryptionAlgorithm
+	sign       []SignOption
+}
+
+func newEncryptOptions(opts []EncryptOption) *encryptOptions {
+	o := &encryptOptions{contentAlg: jwa.A256GCM}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithContentEncryption sets the JWE content encryption algorithm (the "enc"
+// header parameter).  The default is A256GCM.
+func WithContentEncryption(alg jwa.ContentEncryptionAlgorithm) EncryptOption {
+	return func(o *encryptOptions) {
+		o.contentAlg = alg
+	}
+}
+
+// WithSignOptions supplies the SignOptions used to produce the signed EAR
+// that Encrypt wraps
+func WithSignOptions(opts ...SignOption) EncryptOption {
+	return func(o *encryptOptions) {
+		o.sign = append(o.sign, opts...)
+	}
+}
+
+// Encrypt signs the AttestationResult using the supplied algorithm and key (as
+// Sign does), then encrypts the resulting JWT to the recipient's encKey,
+// yielding a nested JWT (sign-then-encrypt, as described in §11.2 of RFC7519)
+// in JWE compact serialization.  keyAlg is the key management algorithm
+// (e.g., ECDH-ES+A256KW, RSA-OAEP-256), which must match the type of encKey.
+// This keeps the claims confidential while the EAR traverses untrusted
+// intermediaries.
+func (o AttestationResult) Encrypt(
+	alg jwa.KeyAlgorithm,
+	sigKey interface{},
+	keyAlg jwa.KeyEncryptionAlgorithm,
+	encKey interface{},
+	opts ...EncryptOption,
+) ([]byte, error) {
+	eo := newEncryptOptions(opts)
+
+	token, err := o.Sign(alg, sigKey, eo.sign...)
+	if err != nil {
+		return nil, err
+	}
+
+	hdrs := jwe.NewHeaders()
+
+	if err := hdrs.Set(jwe.ContentTypeKey, "JWT"); err != nil {
+		return nil, fmt.Errorf("setting cty: %w", err)
+	}
+
+	data, err := jwe.Encrypt(
+		token,
+		jwe.WithKey(keyAlg, encKey),
+		jwe.WithContentEncryption(eo.contentAlg),
+		jwe.WithProtectedHeaders(hdrs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting signed EAR: %w", err)
+	}
+
+	return data, nil
+}
+
+// Decrypt is the counterpart of Encrypt: it decrypts the supplied JWE using
+// the recipient's decKey and the key management algorithm keyAlg, then
+// verifies the nested JWT and populates the AttestationResult from its
+// claims-set, as Verify does.
+func (o *AttestationResult) Decrypt(
+	data []byte,
+	keyAlg jwa.KeyEncryptionAlgorithm,
+	decKey interface{},
+	alg jwa.KeyAlgorithm,
+	vfyKey interface{},
+	opts ...VerifyOption,
+) error {
+	msg, err := jwe.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing JWE: %w", err)
+	}
+
+	if cty := msg.ProtectedHeaders().ContentType(); cty != "JWT" {
+		return fmt.Errorf("unexpected JWE content type %q (expecting \"JWT\")", cty)
+	}
+
+	token, err := jwe.Decrypt(data, jwe.WithKey(keyAlg, decKey))
+	if err != nil {
+		return fmt.Errorf("decrypting JWE: %w", err)
+	}
+
+	if len(token) == 0 {
+		return errors.New("decrypting JWE: empty payload")
+	}
+
+	return o.Verify(token, alg, vfyKey, opts...)
+}
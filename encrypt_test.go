@@ -0,0 +1,104 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypt_Decrypt_roundtrip(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		keyAlg     jwa.KeyEncryptionAlgorithm
+		contentAlg jwa.ContentEncryptionAlgorithm
+		encKey     interface{}
+		decKey     interface{}
+	}{
+		{jwa.ECDH_ES_A256KW, jwa.A256GCM, &ecKey.PublicKey, ecKey},
+		{jwa.RSA_OAEP_256, jwa.A128CBC_HS256, &rsaKey.PublicKey, rsaKey},
+	}
+
+	for i, tv := range tvs {
+		data, err := testAttestationResultsWithVeraisonExtns.Encrypt(
+			jwa.ES256, sigK, tv.keyAlg, tv.encKey,
+			WithContentEncryption(tv.contentAlg),
+			WithSignOptions(WithKeyID("test")),
+		)
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		msg, err := jwe.Parse(data)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.contentAlg, msg.ProtectedHeaders().ContentEncryption(), "failed test vector at index %d", i)
+
+		var actual AttestationResult
+		err = actual.Decrypt(data, tv.keyAlg, tv.decKey, jwa.ES256, vfyK)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual, "failed test vector at index %d", i)
+	}
+}
+
+func TestDecrypt_fail(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	recipient, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	data, err := testAttestationResultsWithVeraisonExtns.Encrypt(
+		jwa.ES256, sigK, jwa.ECDH_ES_A256KW, &recipient.PublicKey,
+	)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.Decrypt(data, jwa.ECDH_ES_A256KW, other, jwa.ES256, vfyK)
+	assert.ErrorContains(t, err, "decrypting JWE: ")
+
+	// a JWE that does not wrap a JWT is refused
+	plain, err := jwe.Encrypt([]byte("hello"), jwe.WithKey(jwa.ECDH_ES_A256KW, &recipient.PublicKey))
+	require.NoError(t, err)
+
+	err = ar.Decrypt(plain, jwa.ECDH_ES_A256KW, recipient, jwa.ES256, vfyK)
+	assert.EqualError(t, err, `unexpected JWE content type "" (expecting "JWT")`)
+
+	err = ar.Decrypt([]byte("not a JWE"), jwa.ECDH_ES_A256KW, recipient, jwa.ES256, vfyK)
+	assert.ErrorContains(t, err, "parsing JWE: ")
+}
+
+func TestEncrypt_invalid_result(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	_, err = ar.Encrypt(jwa.ES256, sigK, jwa.ECDH_ES_A256KW, nil)
+	assert.ErrorContains(t, err, "missing mandatory 'eat_profile'")
+}
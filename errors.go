@@ -0,0 +1,249 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ProfileError is returned when an AttestationResult carries an eat_profile
+// that is not supported.  It reports both the profile that has been received
+// and the ones that would have been accepted.
+type ProfileError struct {
+	// Received is the value of the eat_profile claim
+	Received string
+	// Accepted is the list of eat_profile values that are supported
+	Accepted []string
+}
+
+func (o ProfileError) Error() string {
+	accepted := make([]string, 0, len(o.Accepted))
+	for _, a := range o.Accepted {
+		accepted = append(accepted, fmt.Sprintf("%q", a))
+	}
+
+	return fmt.Sprintf("unsupported eat_profile %q (accepted: %s)",
+		o.Received, strings.Join(accepted, ", "))
+}
+
+// AlgorithmError is returned when the alg in the JWS (or COSE) protected
+// header of a token is not one of those the caller is prepared to accept (see WithStrictAlgorithm and
+// WithAllowedAlgorithms).  This is reported before any attempt is made at
+// verifying the signature, so that algorithm confusion is not mistaken for a
+// bad signature.
+type AlgorithmError struct {
+	// Received is the alg in the protected header
+	Received string
+	// Accepted are the algorithms that would have been accepted
+	Accepted []string
+}
+
+func (o AlgorithmError) Error() string {
+	accepted := make([]string, 0, len(o.Accepted))
+	for _, a := range o.Accepted {
+		accepted = append(accepted, fmt.Sprintf("%q", a))
+	}
+
+	return fmt.Sprintf("JWS alg %q is not accepted (accepted: %s)",
+		o.Received, strings.Join(accepted, ", "))
+}
+
+// SupportedProfiles returns the list of eat_profile values accepted by this
+// package: EatProfile, followed by those added using RegisterProfile
+func SupportedProfiles() []string {
+	return defaultRegistry.SupportedProfiles()
+}
+
+// ErrTokenTooLarge is returned when a token exceeds the size set using
+// WithMaxTokenSize
+var ErrTokenTooLarge = errors.New("token too large")
+
+// ErrExpectationNotMet is returned by AttestationResult.Expect when the result
+// does not meet the relying party's expectations
+var ErrExpectationNotMet = errors.New("expectation not met")
+
+// ErrMissingClaim and ErrInvalidClaim classify the ClaimErrors in a
+// ValidationError.  They can be tested for using errors.Is.
+var (
+	ErrMissingClaim = errors.New("missing mandatory claim")
+	ErrInvalidClaim = errors.New("invalid claim value")
+)
+
+// missingClaim is the type of the errors reporting a specific missing claim.
+// They are also ErrMissingClaim.
+type missingClaim string
+
+func (o missingClaim) Error() string {
+	return fmt.Sprintf("missing mandatory '%s'", string(o))
+}
+
+func (o missingClaim) Is(target error) bool {
+	return target == ErrMissingClaim
+}
+
+// Errors reporting specific missing mandatory claims, which can be tested for
+// using errors.Is, e.g., errors.Is(err, ErrMissingIAT).  ErrMissingStatus
+// matches a missing ear.status in any submod.
+var (
+	ErrMissingProfile    error = missingClaim("eat_profile")
+	ErrMissingIAT        error = missingClaim("iat")
+	ErrMissingVerifierID error = missingClaim("ear.verifier-id")
+	ErrMissingSubmods    error = missingClaim("submods")
+	ErrMissingStatus     error = missingClaim("ear.status")
+)
+
+// ClaimError describes a problem with an individual claim
+type ClaimError struct {
+	// Claim is the name of the offending claim.  Claims belonging to an
+	// Appraisal are prefixed with "submods[<submod-name>].".
+	Claim string
+	// Submod is the name of the submod the claim belongs to, or empty for
+	// the claims at the top level of the result
+	Submod string
+	// Kind is either ErrMissingClaim or ErrInvalidClaim
+	Kind error
+	// Err is the underlying cause, if any (e.g., a ProfileError)
+	Err error
+}
+
+// name returns the name of the claim, without the submod prefix
+func (o ClaimError) name() string {
+	if o.Submod == "" {
+		return o.Claim
+	}
+	return strings.TrimPrefix(o.Claim, fmt.Sprintf("submods[%s].", o.Submod))
+}
+
+// Pointer returns the location of the claim in the claims-set as a JSON
+// Pointer (RFC6901), e.g., "/submods/cpu/ear.status"
+func (o ClaimError) Pointer() string {
+	if o.Submod == "" {
+		return "/" + escapeJSONPointer(o.Claim)
+	}
+	return "/submods/" + escapeJSONPointer(o.Submod) + "/" + escapeJSONPointer(o.name())
+}
+
+func (o ClaimError) Error() string {
+	if o.Kind == ErrMissingClaim {
+		return fmt.Sprintf("missing mandatory '%s'", o.Claim)
+	}
+
+	if o.Err != nil {
+		return fmt.Sprintf("invalid value for '%s': %s", o.Claim, o.Err.Error())
+	}
+
+	return fmt.Sprintf("invalid value for '%s'", o.Claim)
+}
+
+func (o ClaimError) Is(target error) bool {
+	if target == o.Kind {
+		return true
+	}
+
+	return o.Kind == ErrMissingClaim && target == missingClaim(o.name())
+}
+
+func (o ClaimError) Unwrap() error {
+	return o.Err
+}
+
+// ValidationError is returned when an AttestationResult (or one of its
+// Appraisals) fails validation.  It renders as a single summary message, while
+// giving access to the individual ClaimErrors, so that programmatic consumers
+// can tell a missing iat from a bad nonce without resorting to string matching.
+// errors.Is and errors.As are applied to each of the ClaimErrors in turn, so
+// errors.Is(err, ErrMissingIAT) holds even if other problems have been found.
+type ValidationError struct {
+	Errors []*ClaimError
+
+	// human readable descriptions of the missing and invalid claims used to
+	// render the summary message
+	missing, invalid []string
+}
+
+func (o *ValidationError) addMissing(claim, desc string) {
+	o.Errors = append(o.Errors, &ClaimError{Claim: claim, Kind: ErrMissingClaim})
+	o.missing = append(o.missing, desc)
+}
+
+func (o *ValidationError) addInvalid(claim, desc string, err error) {
+	o.Errors = append(o.Errors, &ClaimError{Claim: claim, Kind: ErrInvalidClaim, Err: err})
+	o.invalid = append(o.invalid, desc)
+}
+
+// orNil returns the ValidationError if at least one problem has been recorded,
+// nil otherwise
+func (o *ValidationError) orNil() error {
+	if len(o.Errors) == 0 {
+		return nil
+	}
+	return o
+}
+
+func (o ValidationError) Error() string {
+	var summary []string
+
+	if len(o.missing) != 0 {
+		summary = append(summary, fmt.Sprintf("missing mandatory %s", strings.Join(o.missing, ", ")))
+	}
+
+	if len(o.invalid) != 0 {
+		summary = append(summary, fmt.Sprintf("invalid value(s) for %s", strings.Join(o.invalid, ", ")))
+	}
+
+	return strings.Join(summary, "; ")
+}
+
+// Missing returns the names of the missing claims
+func (o ValidationError) Missing() []string {
+	return o.claims(ErrMissingClaim)
+}
+
+// Invalid returns the names of the claims with invalid values
+func (o ValidationError) Invalid() []string {
+	return o.claims(ErrInvalidClaim)
+}
+
+func (o ValidationError) claims(kind error) []string {
+	var ret []string
+	for _, e := range o.Errors {
+		if e.Kind == kind {
+			ret = append(ret, e.Claim)
+		}
+	}
+	return ret
+}
+
+// Unwrap returns the ClaimErrors, in the order in which the claims have been
+// checked: top-level claims first, then the claims of each submod, with
+// submods sorted by name.  This is the multi-error form recognised by errors.Is
+// and errors.As since Go 1.20; the Is and As methods provide the same
+// behaviour with earlier Go versions.
+func (o ValidationError) Unwrap() []error {
+	ret := make([]error, 0, len(o.Errors))
+	for _, e := range o.Errors {
+		ret = append(ret, e)
+	}
+	return ret
+}
+
+func (o ValidationError) Is(target error) bool {
+	for _, err := range o.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o ValidationError) As(target interface{}) bool {
+	for _, err := range o.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
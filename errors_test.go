@@ -0,0 +1,154 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationError_claims(t *testing.T) {
+	badNonce := Nonces{testBadNonce}
+
+	ar := AttestationResult{
+		Profile:    &testUnsupportedProfile,
+		VerifierID: &testVerifierID,
+		Nonce:      &badNonce,
+		Submods: map[string]*Appraisal{
+			"test": {},
+		},
+	}
+
+	err := ar.validate()
+	require.Error(t, err)
+
+	assert.EqualError(t, err, "missing mandatory 'iat'; invalid value(s) for "+
+		"eat_profile (1.2.3.4.5), eat_nonce (3 bytes, expecting 8 to 64), submods[test]: missing mandatory 'ear.status'")
+
+	var ve *ValidationError
+	require.True(t, errors.As(err, &ve))
+
+	assert.Equal(t, []string{"iat", "submods[test].ear.status"}, ve.Missing())
+	assert.Equal(t, []string{"eat_profile", "eat_nonce"}, ve.Invalid())
+
+	assert.ErrorIs(t, err, ErrMissingClaim)
+	assert.ErrorIs(t, err, ErrInvalidClaim)
+
+	var profileErr ProfileError
+	assert.True(t, errors.As(err, &profileErr))
+
+	var ce *ClaimError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, "eat_profile", ce.Claim)
+}
+
+func TestValidationError_only_invalid(t *testing.T) {
+	badNonce := Nonces{testBadNonce}
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Nonce = &badNonce
+
+	err := ar.validate()
+
+	assert.ErrorIs(t, err, ErrInvalidClaim)
+	assert.False(t, errors.Is(err, ErrMissingClaim))
+}
+
+func TestClaimError_Error(t *testing.T) {
+	tvs := []struct {
+		err      ClaimError
+		expected string
+	}{
+		{
+			err:      ClaimError{Claim: "iat", Kind: ErrMissingClaim},
+			expected: "missing mandatory 'iat'",
+		},
+		{
+			err:      ClaimError{Claim: "eat_nonce", Kind: ErrInvalidClaim},
+			expected: "invalid value for 'eat_nonce'",
+		},
+		{
+			err: ClaimError{
+				Claim: "eat_profile",
+				Kind:  ErrInvalidClaim,
+				Err:   ProfileError{Received: "x", Accepted: []string{"y"}},
+			},
+			expected: `invalid value for 'eat_profile': unsupported eat_profile "x" (accepted: "y")`,
+		},
+	}
+
+	for i, tv := range tvs {
+		assert.EqualError(t, tv.err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestValidationError_specific_missing_claims(t *testing.T) {
+	badNonce := Nonces{testBadNonce}
+
+	ar := AttestationResult{
+		Profile:    &testUnsupportedProfile,
+		VerifierID: &testVerifierID,
+		Nonce:      &badNonce,
+		Submods: map[string]*Appraisal{
+			"test": {},
+		},
+	}
+
+	err := ar.validate()
+	require.Error(t, err)
+
+	assert.ErrorIs(t, err, ErrMissingIAT)
+	assert.ErrorIs(t, err, ErrMissingStatus)
+	assert.ErrorIs(t, err, ErrMissingClaim)
+	assert.False(t, errors.Is(err, ErrMissingProfile))
+	assert.False(t, errors.Is(err, ErrMissingVerifierID))
+	assert.False(t, errors.Is(err, ErrMissingSubmods))
+}
+
+func TestValidationError_Unwrap(t *testing.T) {
+	ar := AttestationResult{
+		Submods: map[string]*Appraisal{
+			"b/c": {},
+			"a":   {},
+		},
+	}
+
+	err := ar.validate()
+	require.Error(t, err)
+
+	var ve *ValidationError
+	require.True(t, errors.As(err, &ve))
+
+	var pointers []string
+	for _, e := range ve.Unwrap() {
+		var ce *ClaimError
+		require.True(t, errors.As(e, &ce))
+		pointers = append(pointers, ce.Pointer())
+	}
+
+	expected := []string{
+		"/eat_profile",
+		"/iat",
+		"/ear.verifier-id",
+		"/submods/a/ear.status",
+		"/submods/b~1c/ear.status",
+	}
+	assert.Equal(t, expected, pointers)
+}
+
+func TestMissingClaim_Is(t *testing.T) {
+	assert.ErrorIs(t, ErrMissingIAT, ErrMissingClaim)
+	assert.False(t, errors.Is(ErrMissingIAT, ErrInvalidClaim))
+	assert.EqualError(t, ErrMissingSubmods, "missing mandatory 'submods'")
+
+	ce := ClaimError{Claim: "submods[x].ear.status", Submod: "x", Kind: ErrMissingClaim}
+	assert.ErrorIs(t, ce, ErrMissingStatus)
+	assert.False(t, errors.Is(ce, ErrMissingIAT))
+
+	ce = ClaimError{Claim: "iat", Kind: ErrInvalidClaim}
+	assert.False(t, errors.Is(ce, ErrMissingIAT))
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// evidenceDigestAlg is the (only) hash algorithm used for the
+// "ear.veraison.annotated-evidence-digest" claim.  The name is taken from the
+// IANA "Named Information Hash Algorithm" registry.
+const evidenceDigestAlg = "sha-256"
+
+// WithAnnotatedEvidenceDigest makes Sign add the
+// "ear.veraison.annotated-evidence-digest" claim to every Appraisal that
+// carries annotated evidence.  The digest remains in the signed token even if
+// the (potentially large, or sensitive) evidence claim is stripped later on,
+// so that the evidence content the appraisal was based on can still be
+// proven (see CheckAnnotatedEvidenceDigest).
+func WithAnnotatedEvidenceDigest() SignOption {
+	return func(o *signOptions) {
+		o.evidenceDigest = true
+	}
+}
+
+// SetAnnotatedEvidenceDigest computes the digest of the annotated evidence
+// currently attached to the Appraisal and stores it in the
+// "ear.veraison.annotated-evidence-digest" claim
+func (o *AppraisalExtensions) SetAnnotatedEvidenceDigest() error {
+	if o.VeraisonAnnotatedEvidence == nil {
+		return errors.New(`"ear.veraison.annotated-evidence" claim not found`)
+	}
+
+	d, err := annotatedEvidenceDigest(*o.VeraisonAnnotatedEvidence)
+	if err != nil {
+		return err
+	}
+
+	o.VeraisonAnnotatedEvidenceDigest = &d
+
+	return nil
+}
+
+// CheckAnnotatedEvidenceDigest checks that the supplied annotated evidence
+// matches the digest in the "ear.veraison.annotated-evidence-digest" claim.
+// If evidence is nil, the annotated evidence carried in the Appraisal itself
+// is checked instead, which is only possible if it has not been redacted.
+func (o AppraisalExtensions) CheckAnnotatedEvidenceDigest(evidence map[string]interface{}) error {
+	if o.VeraisonAnnotatedEvidenceDigest == nil {
+		return errors.New(`"ear.veraison.annotated-evidence-digest" claim not found`)
+	}
+
+	if evidence == nil {
+		if o.VeraisonAnnotatedEvidence == nil {
+			return errors.New("no annotated evidence to check")
+		}
+		evidence = *o.VeraisonAnnotatedEvidence
+	}
+
+	expected := *o.VeraisonAnnotatedEvidenceDigest
+
+	if alg, _, _ := strings.Cut(expected, ":"); alg != evidenceDigestAlg {
+		return fmt.Errorf("unsupported digest algorithm %q", alg)
+	}
+
+	actual, err := annotatedEvidenceDigest(evidence)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) != 1 {
+		return errors.New("annotated evidence does not match its digest")
+	}
+
+	return nil
+}
+
+// annotatedEvidenceDigest hashes the canonical JSON encoding of the annotated
+// evidence, so that the result does not depend on the order in which map keys
+// happen to be serialized, or on the representation of numbers
+func annotatedEvidenceDigest(evidence map[string]interface{}) (string, error) {
+	j, err := json.Marshal(evidence)
+	if err != nil {
+		return "", fmt.Errorf("encoding annotated evidence: %w", err)
+	}
+
+	c, err := canonicalizeJSON(j)
+	if err != nil {
+		return "", fmt.Errorf("canonicalizing annotated evidence: %w", err)
+	}
+
+	sum := sha256.Sum256(c)
+
+	return evidenceDigestAlg + ":" + base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// addAnnotatedEvidenceDigests replaces the Submods with copies in which the
+// annotated evidence digest has been set, leaving the caller's Appraisals
+// untouched
+func (o *AttestationResult) addAnnotatedEvidenceDigests() error {
+	submods := make(map[string]*Appraisal, len(o.Submods))
+
+	for name, a := range o.Submods {
+		if a == nil || a.VeraisonAnnotatedEvidence == nil {
+			submods[name] = a
+			continue
+		}
+
+		c := *a
+		if err := c.SetAnnotatedEvidenceDigest(); err != nil {
+			return fmt.Errorf("submod %q: %w", name, err)
+		}
+		submods[name] = &c
+	}
+
+	o.Submods = submods
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotatedEvidenceDigest_independent_of_encoding(t *testing.T) {
+	a, err := annotatedEvidenceDigest(map[string]interface{}{"k1": "v1", "k2": 1.0})
+	require.NoError(t, err)
+
+	b, err := annotatedEvidenceDigest(map[string]interface{}{"k2": 1, "k1": "v1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, a, b)
+	assert.Regexp(t, `^sha-256:[A-Za-z0-9_-]{43}$`, a)
+}
+
+func TestSign_with_annotated_evidence_digest(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(
+		jwa.ES256, sigK, WithAnnotatedEvidenceDigest(),
+	)
+	require.NoError(t, err)
+
+	// the caller's appraisal is left untouched
+	assert.Nil(t, testAttestationResultsWithVeraisonExtns.Submods["test"].VeraisonAnnotatedEvidenceDigest)
+
+	var ar AttestationResult
+	require.NoError(t, ar.Verify(token, jwa.ES256, vfyK))
+
+	appraisal := ar.Submods["test"]
+	require.NotNil(t, appraisal.VeraisonAnnotatedEvidenceDigest)
+	assert.NoError(t, appraisal.CheckAnnotatedEvidenceDigest(nil))
+
+	// once the evidence has been redacted, the original can still be checked
+	evidence := *appraisal.VeraisonAnnotatedEvidence
+	appraisal.VeraisonAnnotatedEvidence = nil
+
+	assert.EqualError(t, appraisal.CheckAnnotatedEvidenceDigest(nil), "no annotated evidence to check")
+	assert.NoError(t, appraisal.CheckAnnotatedEvidenceDigest(evidence))
+	assert.EqualError(t,
+		appraisal.CheckAnnotatedEvidenceDigest(map[string]interface{}{"k1": "forged"}),
+		"annotated evidence does not match its digest",
+	)
+}
+
+func TestSignCWT_with_annotated_evidence_digest(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer, WithAnnotatedEvidenceDigest())
+	require.NoError(t, err)
+
+	var ar AttestationResult
+	require.NoError(t, ar.VerifyCWT(token, verifier))
+
+	assert.NoError(t, ar.Submods["test"].CheckAnnotatedEvidenceDigest(nil))
+}
+
+func TestCheckAnnotatedEvidenceDigest_fail(t *testing.T) {
+	unknownAlg := "sha-1:AAAA"
+
+	tvs := []struct {
+		extns    AppraisalExtensions
+		expected string
+	}{
+		{
+			extns:    AppraisalExtensions{},
+			expected: `"ear.veraison.annotated-evidence-digest" claim not found`,
+		},
+		{
+			extns:    AppraisalExtensions{VeraisonAnnotatedEvidenceDigest: &unknownAlg},
+			expected: `unsupported digest algorithm "sha-1"`,
+		},
+	}
+
+	for i, tv := range tvs {
+		err := tv.extns.CheckAnnotatedEvidenceDigest(map[string]interface{}{})
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+
+	var extns AppraisalExtensions
+	assert.EqualError(t, extns.SetAnnotatedEvidenceDigest(), `"ear.veraison.annotated-evidence" claim not found`)
+}
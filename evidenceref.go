@@ -0,0 +1,216 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// VeraisonEvidenceRef is the "ear.veraison.evidence-ref" claim.  It links the
+// result to the evidence token (typically an EAT) it has been derived from,
+// by digest, by reference, or both, together with its media type.  Unlike
+// "ear.raw-evidence", it does not require the evidence to be carried in the
+// result, and, unlike opaque bytes, it says what the evidence is.
+type VeraisonEvidenceRef struct {
+	// MediaType is the media type of the evidence, e.g.,
+	// `application/eat+cwt; eat_profile="tag:psacertified.org,2023:psa#tfm"`
+	MediaType string `json:"media-type"`
+	// Digest is the digest of the evidence bytes, in the same
+	// "sha-256:<base64url>" form as the annotated evidence digest
+	Digest *string `json:"digest,omitempty"`
+	// URI locates the evidence, when it is stored away from the result
+	URI *string `json:"uri,omitempty"`
+}
+
+// NewVeraisonEvidenceRef returns an evidence reference carrying the digest of
+// the supplied evidence, which has the supplied media type.  A URI can be
+// added to the result, if the evidence is also available elsewhere.
+func NewVeraisonEvidenceRef(mediaType string, evidence []byte) (*VeraisonEvidenceRef, error) {
+	d := evidenceDigest(evidence)
+
+	ref := VeraisonEvidenceRef{MediaType: mediaType, Digest: &d}
+
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &ref, nil
+}
+
+// Validate checks that the media type is well-formed, and that at least one
+// of digest and URI is present and well-formed
+func (o VeraisonEvidenceRef) Validate() error {
+	if o.MediaType == "" {
+		return errors.New(`empty or missing "media-type"`)
+	}
+
+	if _, _, err := mime.ParseMediaType(o.MediaType); err != nil {
+		return fmt.Errorf(`invalid "media-type": %w`, err)
+	}
+
+	if o.Digest == nil && o.URI == nil {
+		return errors.New(`at least one of "digest" and "uri" must be present`)
+	}
+
+	if o.Digest != nil {
+		if err := checkEvidenceDigest(*o.Digest); err != nil {
+			return fmt.Errorf(`invalid "digest": %w`, err)
+		}
+	}
+
+	if o.URI != nil {
+		u, err := url.Parse(*o.URI)
+		if err != nil {
+			return fmt.Errorf(`invalid "uri": %w`, err)
+		}
+
+		if !u.IsAbs() {
+			return fmt.Errorf(`invalid "uri": %q is not an absolute URI`, *o.URI)
+		}
+	}
+
+	return nil
+}
+
+func ToVeraisonEvidenceRef(v interface{}) (*VeraisonEvidenceRef, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "evidence-ref"`)
+	}
+
+	var ref VeraisonEvidenceRef
+
+	for key, val := range vMap {
+		s, err := str(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+		}
+
+		switch key {
+		case "media-type":
+			ref.MediaType = s
+		case "digest":
+			ref.Digest = &s
+		case "uri":
+			ref.URI = &s
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "evidence-ref" object`, key)
+		}
+	}
+
+	if err := ref.Validate(); err != nil {
+		return nil, fmt.Errorf(`"evidence-ref" validation failed: %w`, err)
+	}
+
+	return &ref, nil
+}
+
+// SetEvidenceRef sets the "ear.veraison.evidence-ref" claim to a copy of the
+// supplied reference, after validating it
+func (o *AttestationResult) SetEvidenceRef(ref VeraisonEvidenceRef) error {
+	if err := ref.Validate(); err != nil {
+		return err
+	}
+
+	o.VeraisonEvidenceRef = &ref
+
+	return nil
+}
+
+// CheckEvidenceRef checks that the supplied evidence matches the
+// "ear.veraison.evidence-ref" claim: its digest must be that in the claim,
+// and, unless mediaType is empty, its media type must be the same as that in
+// the claim.  If evidence is nil, the content of the "ear.raw-evidence" claim
+// is checked instead.  A reference without digest cannot be checked.
+func (o AttestationResult) CheckEvidenceRef(mediaType string, evidence []byte) error {
+	ref := o.VeraisonEvidenceRef
+	if ref == nil {
+		return errors.New(`"ear.veraison.evidence-ref" claim not found`)
+	}
+
+	if mediaType != "" {
+		same, err := sameMediaType(mediaType, ref.MediaType)
+		if err != nil {
+			return err
+		}
+
+		if !same {
+			return fmt.Errorf("media type mismatch: expecting %q, found %q", ref.MediaType, mediaType)
+		}
+	}
+
+	if ref.Digest == nil {
+		return errors.New("evidence reference has no digest")
+	}
+
+	if evidence == nil {
+		var ok bool
+		if evidence, ok = o.GetRawEvidence(); !ok {
+			return errors.New("no evidence to check")
+		}
+	}
+
+	if alg, _, _ := strings.Cut(*ref.Digest, ":"); alg != evidenceDigestAlg {
+		return fmt.Errorf("unsupported digest algorithm %q", alg)
+	}
+
+	actual := evidenceDigest(evidence)
+
+	if subtle.ConstantTimeCompare([]byte(actual), []byte(*ref.Digest)) != 1 {
+		return errors.New("evidence does not match its digest")
+	}
+
+	return nil
+}
+
+func evidenceDigest(evidence []byte) string {
+	sum := sha256.Sum256(evidence)
+	return evidenceDigestAlg + ":" + base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func checkEvidenceDigest(d string) error {
+	alg, value, ok := strings.Cut(d, ":")
+	if !ok {
+		return fmt.Errorf("%q is not in <alg>:<base64url> form", d)
+	}
+
+	if alg != evidenceDigestAlg {
+		return fmt.Errorf("unsupported digest algorithm %q", alg)
+	}
+
+	sum, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return err
+	}
+
+	if len(sum) != sha256.Size {
+		return fmt.Errorf("expecting %d bytes, found %d", sha256.Size, len(sum))
+	}
+
+	return nil
+}
+
+// sameMediaType compares two media types, ignoring the case of the type,
+// subtype and parameter names, and any whitespace
+func sameMediaType(a, b string) (bool, error) {
+	aType, aParams, err := mime.ParseMediaType(a)
+	if err != nil {
+		return false, fmt.Errorf("parsing media type %q: %w", a, err)
+	}
+
+	bType, bParams, err := mime.ParseMediaType(b)
+	if err != nil {
+		return false, fmt.Errorf("parsing media type %q: %w", b, err)
+	}
+
+	return aType == bType && reflect.DeepEqual(aParams, bParams), nil
+}
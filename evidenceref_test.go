@@ -0,0 +1,121 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEvidenceMediaType = `application/eat+cwt; eat_profile="tag:psacertified.org,2023:psa#tfm"`
+
+var testEvidenceToken = []byte{0xd2, 0x84, 0x43, 0xa1, 0x01, 0x26}
+
+func TestVeraisonEvidenceRef_round_trip(t *testing.T) {
+	ref, err := NewVeraisonEvidenceRef(testEvidenceMediaType, testEvidenceToken)
+	require.NoError(t, err)
+
+	uri := "https://evidence.example/tokens/1234"
+	ref.URI = &uri
+
+	ar := testAttestationResultsWithVeraisonExtns
+	require.NoError(t, ar.SetEvidenceRef(*ref))
+
+	data, err := ar.MarshalJSON()
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	require.NoError(t, actual.UnmarshalJSON(data))
+	assert.Equal(t, ar, actual)
+
+	data, err = ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	actual = AttestationResult{}
+	require.NoError(t, actual.UnmarshalCBOR(data))
+	assert.Equal(t, ar, actual)
+}
+
+func TestAttestationResult_CheckEvidenceRef(t *testing.T) {
+	ref, err := NewVeraisonEvidenceRef(testEvidenceMediaType, testEvidenceToken)
+	require.NoError(t, err)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	require.NoError(t, ar.SetEvidenceRef(*ref))
+
+	assert.NoError(t, ar.CheckEvidenceRef("", testEvidenceToken))
+	assert.NoError(t, ar.CheckEvidenceRef(
+		`Application/EAT+CWT;eat_profile="tag:psacertified.org,2023:psa#tfm"`, testEvidenceToken))
+
+	err = ar.CheckEvidenceRef("application/eat+jwt", testEvidenceToken)
+	assert.ErrorContains(t, err, `media type mismatch: expecting "application/eat+cwt; eat_profile=`)
+
+	err = ar.CheckEvidenceRef("", []byte("tampered"))
+	assert.EqualError(t, err, "evidence does not match its digest")
+
+	err = ar.CheckEvidenceRef("", nil)
+	assert.EqualError(t, err, "no evidence to check")
+
+	ar.SetRawEvidence(testEvidenceToken)
+	assert.NoError(t, ar.CheckEvidenceRef("", nil))
+
+	uri := "https://evidence.example/tokens/1234"
+	require.NoError(t, ar.SetEvidenceRef(VeraisonEvidenceRef{MediaType: "application/eat+cwt", URI: &uri}))
+	err = ar.CheckEvidenceRef("", testEvidenceToken)
+	assert.EqualError(t, err, "evidence reference has no digest")
+
+	err = testAttestationResultsWithVeraisonExtns.CheckEvidenceRef("", testEvidenceToken)
+	assert.EqualError(t, err, `"ear.veraison.evidence-ref" claim not found`)
+}
+
+func TestToVeraisonEvidenceRef_fail(t *testing.T) {
+	tvs := []struct {
+		v        interface{}
+		expected string
+	}{
+		{
+			v:        "not-a-map",
+			expected: `unexpected format for "evidence-ref"`,
+		},
+		{
+			v:        map[string]interface{}{"media-type": 1},
+			expected: `invalid value for "media-type": expecting string, found int`,
+		},
+		{
+			v:        map[string]interface{}{"media-type": "application/eat+cwt", "size": "12"},
+			expected: `found unknown key "size" in "evidence-ref" object`,
+		},
+		{
+			v:        map[string]interface{}{"uri": "https://evidence.example/1"},
+			expected: `"evidence-ref" validation failed: empty or missing "media-type"`,
+		},
+		{
+			v:        map[string]interface{}{"media-type": "application/"},
+			expected: `"evidence-ref" validation failed: invalid "media-type": mime: expected token after slash`,
+		},
+		{
+			v:        map[string]interface{}{"media-type": "application/eat+cwt"},
+			expected: `"evidence-ref" validation failed: at least one of "digest" and "uri" must be present`,
+		},
+		{
+			v:        map[string]interface{}{"media-type": "application/eat+cwt", "digest": "sha-384:AAAA"},
+			expected: `"evidence-ref" validation failed: invalid "digest": unsupported digest algorithm "sha-384"`,
+		},
+		{
+			v:        map[string]interface{}{"media-type": "application/eat+cwt", "digest": "sha-256:AAAA"},
+			expected: `"evidence-ref" validation failed: invalid "digest": expecting 32 bytes, found 3`,
+		},
+		{
+			v:        map[string]interface{}{"media-type": "application/eat+cwt", "uri": "tokens/1"},
+			expected: `"evidence-ref" validation failed: invalid "uri": "tokens/1" is not an absolute URI`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := ToVeraisonEvidenceRef(tv.v)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpectNonce returns a Hook that checks that the supplied nonce is among the
+// ones in eat_nonce, i.e., that the result is fresh with respect to the
+// relying party's challenge
+func ExpectNonce(nonce string) Hook {
+	return func(ar *AttestationResult) error {
+		if ar.Nonce == nil || !ar.Nonce.Contains(nonce) {
+			return fmt.Errorf("eat_nonce does not contain %q", nonce)
+		}
+		return nil
+	}
+}
+
+// ExpectProfile returns a Hook that checks that eat_profile is the supplied
+// one
+func ExpectProfile(profile string) Hook {
+	return func(ar *AttestationResult) error {
+		if ar.Profile == nil || ar.Profile.String() != profile {
+			return fmt.Errorf("eat_profile is not %q", profile)
+		}
+		return nil
+	}
+}
+
+// ExpectMinTier returns a Hook that checks that the overall status of the
+// result, as computed by OverallStatus with the supplied options, is at least
+// the supplied tier
+func ExpectMinTier(min TrustTier, opts ...OverallStatusOption) Hook {
+	return func(ar *AttestationResult) error {
+		if status := ar.OverallStatus(opts...); !status.IsAtLeast(min) {
+			return fmt.Errorf("overall status %q is below %q", status, min)
+		}
+		return nil
+	}
+}
+
+// ExpectSubmod returns a Hook that checks that the result carries an
+// appraisal for the named submod
+func ExpectSubmod(name string) Hook {
+	return func(ar *AttestationResult) error {
+		if ar.Submods[name] == nil {
+			return fmt.Errorf("submod %q not found", name)
+		}
+		return nil
+	}
+}
+
+// Expect checks the AttestationResult, typically a verified one, against the
+// relying party's expectations (e.g., those built using ExpectNonce,
+// ExpectProfile, ExpectMinTier and ExpectSubmod).  Unlike WithAfterVerify,
+// which stops at the first failing hook, all the expectations are checked, and
+// the unmet ones are reported together in an error that wraps
+// ErrExpectationNotMet.
+func (o AttestationResult) Expect(expectations ...Hook) error {
+	var unmet []string
+
+	for _, e := range expectations {
+		if err := e(&o); err != nil {
+			unmet = append(unmet, err.Error())
+		}
+	}
+
+	if len(unmet) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrExpectationNotMet, strings.Join(unmet, "; "))
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestationResult_Expect(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Nonce = &Nonces{"1234567890123456"}
+
+	tvs := []struct {
+		expectations []Hook
+		expected     string
+	}{
+		{nil, ""},
+		{
+			[]Hook{
+				ExpectNonce("1234567890123456"),
+				ExpectProfile(EatProfile),
+				ExpectMinTier(TrustTierAffirming),
+				ExpectSubmod("test"),
+			},
+			"",
+		},
+		{[]Hook{ExpectMinTier(TrustTierNone)}, ""},
+		{
+			[]Hook{ExpectNonce("abcdefgh"), ExpectSubmod("test")},
+			`expectation not met: eat_nonce does not contain "abcdefgh"`,
+		},
+		{
+			[]Hook{ExpectProfile("tag:example.com,2024:other"), ExpectSubmod("cpu")},
+			`expectation not met: eat_profile is not "tag:example.com,2024:other"; submod "cpu" not found`,
+		},
+	}
+
+	for i, tv := range tvs {
+		err := ar.Expect(tv.expectations...)
+		if tv.expected == "" {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+		} else {
+			assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+			assert.ErrorIs(t, err, ErrExpectationNotMet, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestExpectMinTier(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = map[string]*Appraisal{
+		"good": {Status: NewTrustTier(TrustTierAffirming)},
+		"meh":  {Status: NewTrustTier(TrustTierWarning)},
+	}
+
+	assert.EqualError(t, ExpectMinTier(TrustTierAffirming)(&ar), `overall status "warning" is below "affirming"`)
+	assert.NoError(t, ExpectMinTier(TrustTierWarning)(&ar))
+	assert.NoError(t, ExpectMinTier(TrustTierAffirming, WithExcludedSubmods("meh"))(&ar))
+}
+
+func TestExpect_after_verify(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+	err = ar.Verify(token, jwa.ES256, vfyK, WithAfterVerify(ExpectSubmod("cpu")))
+	assert.ErrorContains(t, err, `submod "cpu" not found`)
+}
@@ -0,0 +1,181 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AppraisalExtension describes a family of appraisal claims defined outside
+// this package, e.g., by a vendor.  Once registered using
+// RegisterAppraisalExtension, appraisal claims whose name starts with Prefix
+// are decoded into the type returned by New, validated, and made available
+// through AppraisalExtensions.GetExtensionClaim.  On encoding, they are
+// serialized like any other claim.
+type AppraisalExtension struct {
+	// Prefix is the claim name prefix owned by the extension, e.g.,
+	// "ear.acme.".  It must not be the prefix of any claim modelled by this
+	// package, nor overlap with the prefix of another extension.
+	Prefix string
+	// New returns a pointer to a new value of the type the claim named
+	// name decodes into.  The claim is decoded from its JSON representation
+	// using encoding/json.
+	New func(name string) interface{}
+	// Validate, if not nil, checks a claim belonging to the extension.  It
+	// is invoked on decoding, by SetExtensionClaim, and whenever the
+	// appraisal is validated.
+	Validate func(name string, v interface{}) error
+	// Schema, if not nil, is the JSON Schema that the claims of the
+	// extension conform to, for inclusion in the schema returned by
+	// JSONSchema.  If nil, any value is allowed by the schema.
+	Schema map[string]interface{}
+}
+
+// RegisterAppraisalExtension adds an extension to those recognised when
+// decoding appraisals.  Like RegisterProfile, it is meant to be called at
+// initialization time, and operates on the default Registry.
+func RegisterAppraisalExtension(ext AppraisalExtension) error {
+	return defaultRegistry.RegisterAppraisalExtension(ext)
+}
+
+func (o AppraisalExtension) validate(name string, v interface{}) error {
+	if o.Validate == nil {
+		return nil
+	}
+
+	return o.Validate(name, v)
+}
+
+// decode parses the JSON representation of the named claim, as found in a
+// map decoded from JSON or CBOR, into the extension's type
+func (o AppraisalExtension) decode(name string, v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := o.New(name)
+
+	if err := json.Unmarshal(data, ret); err != nil {
+		return nil, err
+	}
+
+	if err := o.validate(name, ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// SetExtensionClaim validates and sets a claim belonging to an
+// AppraisalExtension registered with the default Registry.  A nil value
+// removes the claim.
+func (o *AppraisalExtensions) SetExtensionClaim(name string, v interface{}) error {
+	return o.setExtensionClaim(defaultRegistry, name, v)
+}
+
+func (o *AppraisalExtensions) setExtensionClaim(reg *Registry, name string, v interface{}) error {
+	ext, ok := reg.lookupAppraisalExtension(name)
+	if !ok {
+		return fmt.Errorf("claim %q does not belong to a registered extension", name)
+	}
+
+	if v == nil {
+		delete(o.ExtensionClaims, name)
+		return nil
+	}
+
+	if err := ext.validate(name, v); err != nil {
+		return err
+	}
+
+	if o.ExtensionClaims == nil {
+		o.ExtensionClaims = map[string]interface{}{}
+	}
+
+	o.ExtensionClaims[name] = v
+
+	return nil
+}
+
+// GetExtensionClaim returns the value of a claim belonging to a registered
+// AppraisalExtension, or an error if it is not present
+func (o AppraisalExtensions) GetExtensionClaim(name string) (interface{}, error) {
+	v, ok := o.ExtensionClaims[name]
+	if !ok {
+		return nil, fmt.Errorf("%q claim not found", name)
+	}
+
+	return v, nil
+}
+
+// decodeExtensionClaims populates ExtensionClaims from the entries of m that
+// belong to a registered extension.  Other unmodelled claims are ignored, as
+// they have always been.
+func (o *AppraisalExtensions) decodeExtensionClaims(reg *Registry, m map[string]interface{}) error {
+	var problems []string
+
+	o.ExtensionClaims = nil
+
+	for _, name := range sortedKeys(m) {
+		ext, ok := reg.lookupAppraisalExtension(name)
+		if !ok {
+			continue
+		}
+
+		v, err := ext.decode(name, m[name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+
+		if o.ExtensionClaims == nil {
+			o.ExtensionClaims = map[string]interface{}{}
+		}
+
+		o.ExtensionClaims[name] = v
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid extension claim(s): %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func (o AppraisalExtensions) validateExtensionClaims(reg *Registry, ve *ValidationError) {
+	for _, name := range sortedKeys(o.ExtensionClaims) {
+		ext, ok := reg.lookupAppraisalExtension(name)
+		if !ok {
+			ve.addInvalid(name, fmt.Sprintf("'%s' (no registered extension)", name), nil)
+			continue
+		}
+
+		if err := ext.validate(name, o.ExtensionClaims[name]); err != nil {
+			ve.addInvalid(name, fmt.Sprintf("'%s' (%s)", name, err), err)
+		}
+	}
+}
+
+// addExtensionClaims adds the extension claims to the map representation of
+// the appraisal
+func (o AppraisalExtensions) addExtensionClaims(m map[string]interface{}) {
+	for name, v := range o.ExtensionClaims {
+		if _, ok := m[name]; !ok {
+			m[name] = v
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
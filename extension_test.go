@@ -0,0 +1,178 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAcmeInfo struct {
+	Firmware string `json:"firmware"`
+	Digest   B64Url `json:"digest,omitempty"`
+}
+
+var testAcmeExtension = AppraisalExtension{
+	Prefix: "ear.acme.",
+	New: func(string) interface{} {
+		return &testAcmeInfo{}
+	},
+	Validate: func(name string, v interface{}) error {
+		info, ok := v.(*testAcmeInfo)
+		if !ok {
+			return errors.New("not a *testAcmeInfo")
+		}
+		if info.Firmware == "" {
+			return errors.New(`missing "firmware"`)
+		}
+		return nil
+	},
+}
+
+// withTestExtension registers testAcmeExtension with a fresh default Registry,
+// for the duration of the test
+func withTestExtension(t *testing.T) {
+	saved := defaultRegistry
+	defaultRegistry = NewRegistry()
+	t.Cleanup(func() { defaultRegistry = saved })
+
+	require.NoError(t, RegisterAppraisalExtension(testAcmeExtension))
+}
+
+func TestRegisterAppraisalExtension_fail(t *testing.T) {
+	withTestExtension(t)
+
+	tvs := []struct {
+		ext      AppraisalExtension
+		expected string
+	}{
+		{
+			ext:      AppraisalExtension{New: testAcmeExtension.New},
+			expected: "extension prefix must not be empty",
+		},
+		{
+			ext:      AppraisalExtension{Prefix: "ear.foo."},
+			expected: `extension "ear.foo.": New must be set`,
+		},
+		{
+			ext:      AppraisalExtension{Prefix: "ear.veraison.", New: testAcmeExtension.New},
+			expected: `extension "ear.veraison.": clashes with claim "ear.veraison.annotated-evidence"`,
+		},
+		{
+			ext:      AppraisalExtension{Prefix: "ear.acme.x-", New: testAcmeExtension.New},
+			expected: `extension "ear.acme.x-": overlaps with extension "ear.acme."`,
+		},
+		{
+			ext:      AppraisalExtension{Prefix: "ear.ac", New: testAcmeExtension.New},
+			expected: `extension "ear.ac": overlaps with extension "ear.acme."`,
+		},
+	}
+
+	for i, tv := range tvs {
+		err := RegisterAppraisalExtension(tv.ext)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestAppraisalExtensions_SetGetExtensionClaim(t *testing.T) {
+	withTestExtension(t)
+
+	var ext AppraisalExtensions
+
+	err := ext.SetExtensionClaim("ear.foo.info", &testAcmeInfo{Firmware: "1.0"})
+	assert.EqualError(t, err, `claim "ear.foo.info" does not belong to a registered extension`)
+
+	err = ext.SetExtensionClaim("ear.acme.info", &testAcmeInfo{})
+	assert.EqualError(t, err, `missing "firmware"`)
+
+	_, err = ext.GetExtensionClaim("ear.acme.info")
+	assert.EqualError(t, err, `"ear.acme.info" claim not found`)
+
+	info := &testAcmeInfo{Firmware: "1.0"}
+	require.NoError(t, ext.SetExtensionClaim("ear.acme.info", info))
+
+	actual, err := ext.GetExtensionClaim("ear.acme.info")
+	require.NoError(t, err)
+	assert.Equal(t, info, actual)
+
+	require.NoError(t, ext.SetExtensionClaim("ear.acme.info", nil))
+
+	_, err = ext.GetExtensionClaim("ear.acme.info")
+	assert.Error(t, err)
+}
+
+func TestAppraisalExtensions_extension_claim_roundtrip(t *testing.T) {
+	withTestExtension(t)
+
+	var ext AppraisalExtensions
+	require.NoError(t, ext.SetExtensionClaim("ear.acme.info",
+		&testAcmeInfo{Firmware: "1.0", Digest: B64Url{0xde, 0xad}}))
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = map[string]*Appraisal{
+		"test": {Status: &testStatus, AppraisalExtensions: ext},
+	}
+
+	data, err := ar.MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"ear.acme.info":{"firmware":"1.0","digest":"3q0"}`)
+
+	var actual AttestationResult
+	require.NoError(t, actual.UnmarshalJSON(data))
+	assert.Equal(t, ext.ExtensionClaims, actual.Submods["test"].ExtensionClaims)
+
+	data, err = ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	actual = AttestationResult{}
+	require.NoError(t, actual.UnmarshalCBOR(data))
+	assert.Equal(t, ext.ExtensionClaims, actual.Submods["test"].ExtensionClaims)
+}
+
+func TestToAppraisal_extension_claims(t *testing.T) {
+	tv := map[string]interface{}{
+		"ear.status":    "affirming",
+		"ear.acme.info": map[string]interface{}{"firmware": "1.0"},
+	}
+
+	// unregistered prefixed claims are ignored
+	actual, err := ToAppraisal(tv)
+	require.NoError(t, err)
+	assert.Nil(t, actual.ExtensionClaims)
+
+	withTestExtension(t)
+
+	actual, err = ToAppraisal(tv)
+	require.NoError(t, err)
+	assert.Equal(t, &testAcmeInfo{Firmware: "1.0"}, actual.ExtensionClaims["ear.acme.info"])
+
+	tv["ear.acme.info"] = map[string]interface{}{"firmware": ""}
+	tv["ear.acme.other"] = "not-an-object"
+
+	_, err = ToAppraisal(tv)
+	assert.EqualError(t, err,
+		`invalid extension claim(s): ear.acme.info: missing "firmware"; `+
+			`ear.acme.other: json: cannot unmarshal string into Go value of type ear.testAcmeInfo`)
+}
+
+func TestAppraisal_validate_extension_claims(t *testing.T) {
+	withTestExtension(t)
+
+	a := Appraisal{
+		Status: &testStatus,
+		AppraisalExtensions: AppraisalExtensions{
+			ExtensionClaims: map[string]interface{}{
+				"ear.acme.info": &testAcmeInfo{},
+				"ear.foo.info":  "bar",
+			},
+		},
+	}
+
+	err := a.validate()
+	assert.EqualError(t, err,
+		`invalid value(s) for 'ear.acme.info' (missing "firmware"), 'ear.foo.info' (no registered extension)`)
+}
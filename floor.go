@@ -0,0 +1,66 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import "sort"
+
+// TrustFloor expresses the minimum acceptable trust tier for each dimension of
+// a TrustVector.  A dimension left at TrustTierNone places no constraint on
+// the corresponding claim.  Otherwise, the claim must have been made, and its
+// tier must be no worse than the floor: for example, a Configuration floor of
+// TrustTierWarning accepts both an approved and a "warning" configuration,
+// whereas a floor of TrustTierAffirming only accepts the former.
+type TrustFloor struct {
+	InstanceIdentity TrustTier `json:"instance-identity,omitempty"`
+	Configuration    TrustTier `json:"configuration,omitempty"`
+	Executables      TrustTier `json:"executables,omitempty"`
+	FileSystem       TrustTier `json:"file-system,omitempty"`
+	Hardware         TrustTier `json:"hardware,omitempty"`
+	RuntimeOpaque    TrustTier `json:"runtime-opaque,omitempty"`
+	StorageOpaque    TrustTier `json:"storage-opaque,omitempty"`
+	SourcedData      TrustTier `json:"sourced-data,omitempty"`
+}
+
+// AsMap returns a map[string]TrustTier with claims names mapped onto the
+// corresponding floor tiers.
+func (o TrustFloor) AsMap() map[string]TrustTier {
+	return map[string]TrustTier{
+		"instance-identity": o.InstanceIdentity,
+		"configuration":     o.Configuration,
+		"executables":       o.Executables,
+		"file-system":       o.FileSystem,
+		"hardware":          o.Hardware,
+		"runtime-opaque":    o.RuntimeOpaque,
+		"storage-opaque":    o.StorageOpaque,
+		"sourced-data":      o.SourcedData,
+	}
+}
+
+// MeetsFloor returns true if every claim in the TrustVector is at, or above,
+// the corresponding tier in the supplied floor.
+func (o TrustVector) MeetsFloor(floor TrustFloor) bool {
+	return len(o.BelowFloor(floor)) == 0
+}
+
+// BelowFloor returns the (sorted) names of the claims that do not meet the
+// supplied floor.
+func (o TrustVector) BelowFloor(floor TrustFloor) []string {
+	var below []string
+
+	claims := o.AsMap()
+
+	for name, min := range floor.AsMap() {
+		if min == TrustTierNone {
+			continue
+		}
+
+		if !claims[name].GetTier().IsAtLeast(min) {
+			below = append(below, name)
+		}
+	}
+
+	sort.Strings(below)
+
+	return below
+}
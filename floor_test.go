@@ -0,0 +1,60 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustVector_MeetsFloor(t *testing.T) {
+	floor := TrustFloor{
+		InstanceIdentity: TrustTierAffirming,
+		Configuration:    TrustTierWarning,
+	}
+
+	tvs := []struct {
+		tv    TrustVector
+		below []string
+	}{
+		{
+			tv: TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+				Configuration:    ApprovedConfigClaim,
+			},
+			below: nil,
+		},
+		{
+			tv: TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+				Configuration:    UnsafeConfigClaim,
+				Hardware:         UnsafeHardwareClaim,
+			},
+			below: nil,
+		},
+		{
+			tv: TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+			},
+			below: []string{"configuration"},
+		},
+		{
+			tv: TrustVector{
+				InstanceIdentity: UnrecognizedInstanceClaim,
+				Configuration:    UnsupportableConfigClaim,
+			},
+			below: []string{"configuration", "instance-identity"},
+		},
+	}
+
+	for i, tv := range tvs {
+		assert.Equal(t, tv.below, tv.tv.BelowFloor(floor), "failed test vector at index %d", i)
+		assert.Equal(t, tv.below == nil, tv.tv.MeetsFloor(floor), "failed test vector at index %d", i)
+	}
+}
+
+func TestTrustVector_MeetsFloor_empty(t *testing.T) {
+	assert.True(t, TrustVector{}.MeetsFloor(TrustFloor{}))
+}
@@ -0,0 +1,370 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Verifier identities of the results converted from foreign formats.  The
+// developer is the vendor operating the attestation service, and the build the
+// format of its output.
+const (
+	NitroVerifierDeveloper         = "https://aws.amazon.com"
+	NitroVerifierBuild             = "nitro-attestation-document"
+	DCAPVerifierDeveloper          = "https://www.intel.com"
+	DCAPVerifierBuild              = "dcap-quote-verification"
+	PlayIntegrityVerifierDeveloper = "https://play.google.com"
+	PlayIntegrityVerifierBuild     = "play-integrity-verdict"
+)
+
+// nitroDocument is the payload of an AWS Nitro Enclaves attestation document,
+// as JSON, in which byte strings are base64-encoded
+type nitroDocument struct {
+	ModuleID  string            `json:"module_id"`
+	Digest    string            `json:"digest"`
+	Timestamp int64             `json:"timestamp"`
+	PCRs      map[string][]byte `json:"pcrs"`
+	Nonce     []byte            `json:"nonce,omitempty"`
+}
+
+// FromNitro converts the payload of an AWS Nitro Enclaves attestation
+// document into an AttestationResult, in which the appraisal is placed under
+// the supplied submod name.  The payload is in JSON, with its byte strings
+// (e.g., the PCRs) base64-encoded, and the signature of the document must have
+// been checked against the AWS Nitro Enclaves PKI beforehand.  The document
+// vouches for a genuine enclave with isolated memory; since the PCRs are not
+// compared against any reference value, they are carried as annotated
+// evidence, for relying parties to match, and the executables claim is left
+// unset.  The millisecond timestamp is used as iat, and the nonce, if any, as
+// eat_nonce.  The result is validated.
+func FromNitro(data []byte, submod string) (*AttestationResult, error) {
+	var doc nitroDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.ModuleID == "" {
+		return nil, errors.New(`missing "module_id"`)
+	}
+
+	if doc.Digest != "SHA384" {
+		return nil, fmt.Errorf(`unsupported "digest" %q (expecting "SHA384")`, doc.Digest)
+	}
+
+	if doc.Timestamp <= 0 {
+		return nil, errors.New(`missing "timestamp"`)
+	}
+
+	if len(doc.PCRs) == 0 {
+		return nil, errors.New(`missing "pcrs"`)
+	}
+
+	pcrs := make(map[string]interface{}, len(doc.PCRs))
+	for idx, pcr := range doc.PCRs {
+		if _, err := strconv.ParseUint(idx, 10, 8); err != nil {
+			return nil, fmt.Errorf(`invalid PCR index %q in "pcrs"`, idx)
+		}
+		pcrs[idx] = hex.EncodeToString(pcr)
+	}
+
+	appraisal := Appraisal{
+		TrustVector: &TrustVector{
+			InstanceIdentity: TrustworthyInstanceClaim,
+			Hardware:         GenuineHardwareClaim,
+			RuntimeOpaque:    IsolatedMemoryRuntimeClaim,
+		},
+	}
+
+	appraisal.VeraisonAnnotatedEvidence = &map[string]interface{}{
+		"module_id": doc.ModuleID,
+		"pcrs":      pcrs,
+	}
+
+	return newForeignResult(
+		NitroVerifierDeveloper, NitroVerifierBuild, doc.Timestamp/1000, doc.Nonce, submod, appraisal,
+	)
+}
+
+// dcapVerificationResult is the outcome of the verification of an Intel SGX
+// DCAP quote.  Measurements are hex-encoded.
+type dcapVerificationResult struct {
+	TCBStatus   IntelTCBStatus `json:"tcbStatus"`
+	AdvisoryIDs []string       `json:"advisoryIDs,omitempty"`
+	MRENCLAVE   string         `json:"mrEnclave"`
+	MRSIGNER    string         `json:"mrSigner"`
+	ISVSVN      *uint16        `json:"isvSvn,omitempty"`
+	VerifiedAt  int64          `json:"verifiedAt"`
+	Nonce       []byte         `json:"nonce,omitempty"`
+}
+
+// dcapTrustVectors maps the TCB status of the platform onto the trust vector
+// of the enclave.  The platform of a quote that verifies is genuine, unless
+// it has been revoked; which patches or configuration changes it lacks makes
+// it unsafe.
+var dcapTrustVectors = map[IntelTCBStatus]TrustVector{
+	IntelTCBUpToDate: {
+		Configuration: ApprovedConfigClaim,
+		Hardware:      GenuineHardwareClaim,
+	},
+	IntelTCBSWHardeningNeeded: {
+		Configuration: ApprovedConfigClaim,
+		Hardware:      UnsafeHardwareClaim,
+	},
+	IntelTCBConfigurationNeeded: {
+		Configuration: UnsafeConfigClaim,
+		Hardware:      GenuineHardwareClaim,
+	},
+	IntelTCBConfigurationAndSWHardeningNeeded: {
+		Configuration: UnsafeConfigClaim,
+		Hardware:      UnsafeHardwareClaim,
+	},
+	IntelTCBOutOfDate: {
+		Configuration: ApprovedConfigClaim,
+		Hardware:      UnsafeHardwareClaim,
+	},
+	IntelTCBOutOfDateConfigurationNeeded: {
+		Configuration: UnsafeConfigClaim,
+		Hardware:      UnsafeHardwareClaim,
+	},
+	IntelTCBRevoked: {
+		Hardware: ContraindicatedHardwareClaim,
+	},
+}
+
+// FromDCAP converts the result of the verification of an Intel SGX quote
+// using DCAP (e.g., by the Quote Verification Library) into an
+// AttestationResult, in which the appraisal is placed under the supplied
+// submod name.  The result is a JSON object with the TCB status of the
+// platform ("tcbStatus", one of the Intel TCB status values), the applicable
+// security advisories ("advisoryIDs"), the hex-encoded identity of the enclave
+// ("mrEnclave", "mrSigner" and "isvSvn"), the time of the verification in
+// seconds since the epoch ("verifiedAt"), used as iat, and the nonce of the
+// request, if any ("nonce", base64-encoded).  The TCB status determines the
+// hardware and configuration claims, and is recorded in the
+// ear.veraison.sgx-info claim, along with the identity of the enclave.  The
+// advisories are carried as annotated evidence.  The result is validated.
+func FromDCAP(data []byte, submod string) (*AttestationResult, error) {
+	var res dcapVerificationResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	tv, ok := dcapTrustVectors[res.TCBStatus]
+	if !ok {
+		return nil, fmt.Errorf(`unsupported "tcbStatus" %q`, res.TCBStatus)
+	}
+
+	if res.VerifiedAt <= 0 {
+		return nil, errors.New(`missing "verifiedAt"`)
+	}
+
+	info := VeraisonSGXInfo{ISVSVN: res.ISVSVN, TCBStatus: &res.TCBStatus}
+
+	for _, m := range []struct {
+		name string
+		hex  string
+		dst  **B64Url
+	}{
+		{"mrEnclave", res.MRENCLAVE, &info.MRENCLAVE},
+		{"mrSigner", res.MRSIGNER, &info.MRSIGNER},
+	} {
+		b, err := hex.DecodeString(m.hex)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid %q: expecting a hex-encoded measurement", m.name)
+		}
+		u := B64Url(b)
+		*m.dst = &u
+	}
+
+	tv.InstanceIdentity = TrustworthyInstanceClaim
+	tv.RuntimeOpaque = EncryptedMemoryRuntimeClaim
+
+	appraisal := Appraisal{TrustVector: &tv}
+
+	if err := appraisal.SetSGXInfo(info); err != nil {
+		return nil, fmt.Errorf("setting SGX info: %w", err)
+	}
+
+	if len(res.AdvisoryIDs) > 0 {
+		advisories := make([]interface{}, 0, len(res.AdvisoryIDs))
+		for _, id := range res.AdvisoryIDs {
+			advisories = append(advisories, id)
+		}
+
+		appraisal.VeraisonAnnotatedEvidence = &map[string]interface{}{
+			"advisory-ids": advisories,
+		}
+	}
+
+	return newForeignResult(
+		DCAPVerifierDeveloper, DCAPVerifierBuild, res.VerifiedAt, res.Nonce, submod, appraisal,
+	)
+}
+
+// playIntegrityVerdict is the decoded payload of a Play Integrity API
+// integrity token
+type playIntegrityVerdict struct {
+	RequestDetails struct {
+		RequestPackageName string      `json:"requestPackageName"`
+		Nonce              string      `json:"nonce"`
+		TimestampMillis    json.Number `json:"timestampMillis"`
+	} `json:"requestDetails"`
+	AppIntegrity struct {
+		AppRecognitionVerdict   string      `json:"appRecognitionVerdict"`
+		PackageName             string      `json:"packageName,omitempty"`
+		CertificateSha256Digest []string    `json:"certificateSha256Digest,omitempty"`
+		VersionCode             json.Number `json:"versionCode,omitempty"`
+	} `json:"appIntegrity"`
+	DeviceIntegrity struct {
+		DeviceRecognitionVerdict []string `json:"deviceRecognitionVerdict"`
+	} `json:"deviceIntegrity"`
+	AccountDetails struct {
+		AppLicensingVerdict string `json:"appLicensingVerdict,omitempty"`
+	} `json:"accountDetails"`
+}
+
+// playIntegrityExecutables maps the app recognition verdict onto the
+// executables claim
+var playIntegrityExecutables = map[string]TrustClaim{
+	"PLAY_RECOGNIZED":      ApprovedRuntimeClaim,
+	"UNRECOGNIZED_VERSION": UnrecognizedRuntimeClaim,
+	"UNEVALUATED":          NoClaim,
+}
+
+// FromPlayIntegrity converts the decoded payload of a Play Integrity API
+// integrity token (i.e., the integrity verdict, in JSON) into an
+// AttestationResult, in which the appraisal is placed under the supplied
+// submod name.  The app recognition verdict determines the executables
+// claim, and the device recognition verdict the hardware claim: a device
+// meeting the strong or device integrity requirements is genuine, one only
+// meeting the basic integrity requirements (e.g., a rooted device) is unsafe,
+// an emulator is unrecognized, and a device meeting none of them is
+// contraindicated.  The verdicts, the package name, version code and signing
+// certificate digests of the app, and the licensing verdict are carried as
+// annotated evidence.  The request timestamp is used as iat, and the request
+// nonce as eat_nonce.  The result is validated.
+func FromPlayIntegrity(data []byte, submod string) (*AttestationResult, error) {
+	var v playIntegrityVerdict
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	ms, err := v.RequestDetails.TimestampMillis.Int64()
+	if err != nil || ms <= 0 {
+		return nil, errors.New(`missing or invalid "requestDetails.timestampMillis"`)
+	}
+
+	executables, ok := playIntegrityExecutables[v.AppIntegrity.AppRecognitionVerdict]
+	if !ok {
+		return nil, fmt.Errorf(`unsupported "appIntegrity.appRecognitionVerdict" %q`,
+			v.AppIntegrity.AppRecognitionVerdict)
+	}
+
+	device := v.DeviceIntegrity.DeviceRecognitionVerdict
+
+	hardware := ContraindicatedHardwareClaim
+	switch {
+	case contains(device, "MEETS_STRONG_INTEGRITY"), contains(device, "MEETS_DEVICE_INTEGRITY"):
+		hardware = GenuineHardwareClaim
+	case contains(device, "MEETS_BASIC_INTEGRITY"):
+		hardware = UnsafeHardwareClaim
+	case contains(device, "MEETS_VIRTUAL_INTEGRITY"):
+		hardware = UnrecognizedHardwareClaim
+	}
+
+	verdicts := make([]interface{}, 0, len(device))
+	for _, d := range device {
+		verdicts = append(verdicts, d)
+	}
+
+	annotated := map[string]interface{}{
+		"app-recognition-verdict":    v.AppIntegrity.AppRecognitionVerdict,
+		"device-recognition-verdict": verdicts,
+	}
+
+	for name, value := range map[string]string{
+		"package-name":          v.AppIntegrity.PackageName,
+		"version-code":          v.AppIntegrity.VersionCode.String(),
+		"app-licensing-verdict": v.AccountDetails.AppLicensingVerdict,
+	} {
+		if value != "" {
+			annotated[name] = value
+		}
+	}
+
+	if len(v.AppIntegrity.CertificateSha256Digest) > 0 {
+		digests := append([]string(nil), v.AppIntegrity.CertificateSha256Digest...)
+		sort.Strings(digests)
+
+		certs := make([]interface{}, 0, len(digests))
+		for _, d := range digests {
+			certs = append(certs, d)
+		}
+		annotated["certificate-sha256-digest"] = certs
+	}
+
+	appraisal := Appraisal{
+		TrustVector: &TrustVector{
+			Executables: executables,
+			Hardware:    hardware,
+		},
+	}
+	appraisal.VeraisonAnnotatedEvidence = &annotated
+
+	var nonce []byte
+	if n := v.RequestDetails.Nonce; n != "" {
+		if nonce, err = decodePlayIntegrityNonce(n); err != nil {
+			return nil, fmt.Errorf(`invalid "requestDetails.nonce": %w`, err)
+		}
+	}
+
+	return newForeignResult(
+		PlayIntegrityVerifierDeveloper, PlayIntegrityVerifierBuild, ms/1000, nonce, submod, appraisal,
+	)
+}
+
+// decodePlayIntegrityNonce decodes the request nonce, which Play Integrity
+// requires to be base64url-encoded, with or without padding
+func decodePlayIntegrityNonce(n string) ([]byte, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(n); err == nil {
+		return b, nil
+	}
+
+	return base64.URLEncoding.DecodeString(n)
+}
+
+// newForeignResult wraps the appraisal derived from a foreign attestation
+// result into an AttestationResult, deriving its status from its trust
+// vector, and validates it
+func newForeignResult(
+	developer, build string,
+	iat int64,
+	nonce []byte,
+	submod string,
+	appraisal Appraisal,
+) (*AttestationResult, error) {
+	ar := NewAttestationResult(submod, build, developer)
+	ar.IssuedAt = &iat
+
+	if len(nonce) > 0 {
+		ar.Nonce = &Nonces{base64.RawURLEncoding.EncodeToString(nonce)}
+	}
+
+	appraisal.Status = NewTrustTier(TrustTierNone)
+	appraisal.UpdateStatusFromTrustVector()
+	ar.Submods[submod] = &appraisal
+
+	if err := ar.validate(); err != nil {
+		return nil, err
+	}
+
+	return ar, nil
+}
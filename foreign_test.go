@@ -0,0 +1,189 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromNitro_ok(t *testing.T) {
+	tv := `{
+		"module_id": "i-0123456789abcdef0-enc0123456789abcdef",
+		"digest": "SHA384",
+		"timestamp": 1666091373000,
+		"pcrs": {"0": "AAECAw==", "8": "BAUGBw=="},
+		"nonce": "AAECAwQFBgcICQoLDA0ODw=="
+	}`
+
+	ar, err := FromNitro([]byte(tv), "enclave")
+	require.NoError(t, err)
+
+	assert.Equal(t, testIAT, *ar.IssuedAt)
+	assert.Equal(t, NitroVerifierDeveloper, *ar.VerifierID.Developer)
+	assert.Equal(t, Nonces{"AAECAwQFBgcICQoLDA0ODw"}, *ar.Nonce)
+
+	require.Contains(t, ar.Submods, "enclave")
+	appraisal := ar.Submods["enclave"]
+	assert.Equal(t, TrustTierWarning, *appraisal.Status)
+	assert.Equal(t, GenuineHardwareClaim, appraisal.TrustVector.Hardware)
+	assert.Equal(t, IsolatedMemoryRuntimeClaim, appraisal.TrustVector.RuntimeOpaque)
+	assert.Equal(t, NoClaim, appraisal.TrustVector.Executables)
+
+	require.NotNil(t, appraisal.VeraisonAnnotatedEvidence)
+	assert.Equal(t,
+		map[string]interface{}{"0": "00010203", "8": "04050607"},
+		(*appraisal.VeraisonAnnotatedEvidence)["pcrs"],
+	)
+}
+
+func TestFromDCAP_ok(t *testing.T) {
+	tvs := []struct {
+		status        IntelTCBStatus
+		hardware      TrustClaim
+		configuration TrustClaim
+		expected      TrustTier
+	}{
+		{IntelTCBUpToDate, GenuineHardwareClaim, ApprovedConfigClaim, TrustTierAffirming},
+		{IntelTCBConfigurationNeeded, GenuineHardwareClaim, UnsafeConfigClaim, TrustTierWarning},
+		{IntelTCBOutOfDate, UnsafeHardwareClaim, ApprovedConfigClaim, TrustTierWarning},
+		{IntelTCBRevoked, ContraindicatedHardwareClaim, NoClaim, TrustTierContraindicated},
+	}
+
+	for i, tv := range tvs {
+		data := `{
+			"tcbStatus": "` + string(tv.status) + `",
+			"advisoryIDs": ["INTEL-SA-00615"],
+			"mrEnclave": "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"mrSigner": "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100",
+			"isvSvn": 3,
+			"verifiedAt": 1666091373
+		}`
+
+		ar, err := FromDCAP([]byte(data), "enclave")
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		assert.Equal(t, testIAT, *ar.IssuedAt, "failed test vector at index %d", i)
+
+		appraisal := ar.Submods["enclave"]
+		require.NotNil(t, appraisal, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, *appraisal.Status, "failed test vector at index %d", i)
+		assert.Equal(t, tv.hardware, appraisal.TrustVector.Hardware, "failed test vector at index %d", i)
+		assert.Equal(t, tv.configuration, appraisal.TrustVector.Configuration, "failed test vector at index %d", i)
+
+		require.NotNil(t, appraisal.VeraisonSGXInfo, "failed test vector at index %d", i)
+		assert.Equal(t, tv.status, *appraisal.VeraisonSGXInfo.TCBStatus, "failed test vector at index %d", i)
+		assert.Equal(t, uint16(3), *appraisal.VeraisonSGXInfo.ISVSVN, "failed test vector at index %d", i)
+	}
+}
+
+func TestFromPlayIntegrity_ok(t *testing.T) {
+	tvs := []struct {
+		app         string
+		device      string
+		executables TrustClaim
+		hardware    TrustClaim
+		expected    TrustTier
+	}{
+		{`PLAY_RECOGNIZED`, `["MEETS_BASIC_INTEGRITY", "MEETS_DEVICE_INTEGRITY"]`, ApprovedRuntimeClaim, GenuineHardwareClaim, TrustTierAffirming},
+		{`UNRECOGNIZED_VERSION`, `["MEETS_STRONG_INTEGRITY"]`, UnrecognizedRuntimeClaim, GenuineHardwareClaim, TrustTierWarning},
+		{`PLAY_RECOGNIZED`, `["MEETS_BASIC_INTEGRITY"]`, ApprovedRuntimeClaim, UnsafeHardwareClaim, TrustTierWarning},
+		{`UNEVALUATED`, `["MEETS_VIRTUAL_INTEGRITY"]`, NoClaim, UnrecognizedHardwareClaim, TrustTierContraindicated},
+		{`PLAY_RECOGNIZED`, `[]`, ApprovedRuntimeClaim, ContraindicatedHardwareClaim, TrustTierContraindicated},
+	}
+
+	for i, tv := range tvs {
+		data := `{
+			"requestDetails": {
+				"requestPackageName": "com.example.app",
+				"nonce": "AAECAwQFBgcICQoLDA0ODw==",
+				"timestampMillis": 1666091373000
+			},
+			"appIntegrity": {
+				"appRecognitionVerdict": "` + tv.app + `",
+				"packageName": "com.example.app",
+				"certificateSha256Digest": ["6a6a1474b5cbbb2b1aa57e0bc3"],
+				"versionCode": "42"
+			},
+			"deviceIntegrity": {"deviceRecognitionVerdict": ` + tv.device + `},
+			"accountDetails": {"appLicensingVerdict": "LICENSED"}
+		}`
+
+		ar, err := FromPlayIntegrity([]byte(data), "app")
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		assert.Equal(t, testIAT, *ar.IssuedAt, "failed test vector at index %d", i)
+		assert.Equal(t, Nonces{"AAECAwQFBgcICQoLDA0ODw"}, *ar.Nonce, "failed test vector at index %d", i)
+
+		appraisal := ar.Submods["app"]
+		require.NotNil(t, appraisal, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, *appraisal.Status, "failed test vector at index %d", i)
+		assert.Equal(t, tv.executables, appraisal.TrustVector.Executables, "failed test vector at index %d", i)
+		assert.Equal(t, tv.hardware, appraisal.TrustVector.Hardware, "failed test vector at index %d", i)
+
+		require.NotNil(t, appraisal.VeraisonAnnotatedEvidence, "failed test vector at index %d", i)
+		assert.Equal(t, "42", (*appraisal.VeraisonAnnotatedEvidence)["version-code"], "failed test vector at index %d", i)
+	}
+}
+
+func TestFromForeign_fail(t *testing.T) {
+	tvs := []struct {
+		convert  func([]byte, string) (*AttestationResult, error)
+		data     string
+		expected string
+	}{
+		{
+			convert:  FromNitro,
+			data:     `{"digest": "SHA384", "timestamp": 1666091373000, "pcrs": {"0": "AA=="}}`,
+			expected: `missing "module_id"`,
+		},
+		{
+			convert:  FromNitro,
+			data:     `{"module_id": "i-0", "digest": "SHA256", "timestamp": 1666091373000, "pcrs": {"0": "AA=="}}`,
+			expected: `unsupported "digest" "SHA256" (expecting "SHA384")`,
+		},
+		{
+			convert:  FromNitro,
+			data:     `{"module_id": "i-0", "digest": "SHA384", "timestamp": 1666091373000, "pcrs": {"x": "AA=="}}`,
+			expected: `invalid PCR index "x" in "pcrs"`,
+		},
+		{
+			convert:  FromDCAP,
+			data:     `{"tcbStatus": "Unknown", "verifiedAt": 1666091373}`,
+			expected: `unsupported "tcbStatus" "Unknown"`,
+		},
+		{
+			convert:  FromDCAP,
+			data:     `{"tcbStatus": "UpToDate", "mrEnclave": "zz", "mrSigner": "00", "verifiedAt": 1666091373}`,
+			expected: `invalid "mrEnclave": expecting a hex-encoded measurement`,
+		},
+		{
+			convert:  FromDCAP,
+			data:     `{"tcbStatus": "UpToDate", "mrEnclave": "00", "mrSigner": "00", "verifiedAt": 1666091373}`,
+			expected: `setting SGX info: "mrenclave": 1 bytes, expecting 32`,
+		},
+		{
+			convert:  FromPlayIntegrity,
+			data:     `{"appIntegrity": {"appRecognitionVerdict": "PLAY_RECOGNIZED"}}`,
+			expected: `missing or invalid "requestDetails.timestampMillis"`,
+		},
+		{
+			convert:  FromPlayIntegrity,
+			data:     `{"requestDetails": {"timestampMillis": "1666091373000"}, "appIntegrity": {"appRecognitionVerdict": "SIDELOADED"}}`,
+			expected: `unsupported "appIntegrity.appRecognitionVerdict" "SIDELOADED"`,
+		},
+		{
+			convert:  FromPlayIntegrity,
+			data:     `{"requestDetails": {"timestampMillis": "1666091373000", "nonce": "AAE"}, "appIntegrity": {"appRecognitionVerdict": "PLAY_RECOGNIZED"}}`,
+			expected: "invalid value(s) for eat_nonce (2 bytes, expecting 8 to 64)",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := tv.convert([]byte(tv.data), "test")
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
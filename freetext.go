@@ -0,0 +1,107 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxFreeTextLength is the maximum number of characters allowed in free-text
+// claims, i.e., the verifier's build and developer, and the appraisal policy
+// ID.
+const MaxFreeTextLength = 1024
+
+// checkFreeText makes sure that a free-text claim can be safely written to
+// logs and terminals: it must be valid UTF-8, must not contain control
+// characters (e.g., newlines or ANSI escapes) and must not exceed
+// MaxFreeTextLength characters.
+func checkFreeText(s string) error {
+	if !utf8.ValidString(s) {
+		return errors.New("not valid UTF-8")
+	}
+
+	n := 0
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("contains control character %U", r)
+		}
+		n++
+	}
+
+	if n > MaxFreeTextLength {
+		return fmt.Errorf("too long (%d > %d characters)", n, MaxFreeTextLength)
+	}
+
+	return nil
+}
+
+// sanitizeFreeText returns a copy of s that passes checkFreeText: invalid
+// UTF-8 sequences are replaced with U+FFFD, control characters are dropped,
+// and the result is truncated to MaxFreeTextLength characters.
+func sanitizeFreeText(s string) string {
+	var b strings.Builder
+
+	n := 0
+	for _, r := range strings.ToValidUTF8(s, string(utf8.RuneError)) {
+		if n == MaxFreeTextLength {
+			break
+		}
+
+		if unicode.IsControl(r) {
+			continue
+		}
+
+		b.WriteRune(r)
+		n++
+	}
+
+	return b.String()
+}
+
+func sanitizeFreeTextPtr(p *string) *string {
+	if p == nil {
+		return nil
+	}
+
+	s := sanitizeFreeText(*p)
+
+	return &s
+}
+
+// SanitizeFreeText rewrites the free-text claims of the AttestationResult and
+// of its Appraisals so that they pass validation: invalid UTF-8 is replaced,
+// control characters are removed, and over-long values are truncated to
+// MaxFreeTextLength characters.  Verifiers that fill these claims from
+// untrusted sources can use it before Sign as an alternative to failing.
+func (o *AttestationResult) SanitizeFreeText() {
+	if o.VerifierID != nil {
+		o.VerifierID = &VerifierIdentity{
+			Build:     sanitizeFreeTextPtr(o.VerifierID.Build),
+			Developer: sanitizeFreeTextPtr(o.VerifierID.Developer),
+		}
+	}
+
+	for _, a := range o.Submods {
+		if a != nil {
+			a.AppraisalPolicyID = sanitizePolicyIDs(a.AppraisalPolicyID)
+		}
+	}
+}
+
+func sanitizePolicyIDs(p *PolicyIDs) *PolicyIDs {
+	if p == nil {
+		return nil
+	}
+
+	s := make(PolicyIDs, 0, len(*p))
+	for _, id := range *p {
+		s = append(s, PolicyID(sanitizeFreeText(string(id))))
+	}
+
+	return &s
+}
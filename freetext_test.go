@@ -0,0 +1,83 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFreeText(t *testing.T) {
+	tvs := []struct {
+		text     string
+		expected string
+	}{
+		{text: "Acme Inc. – Ünïcode is fine"},
+		{text: strings.Repeat("x", MaxFreeTextLength)},
+		{
+			text:     "rrtrap\n[INFO] all good",
+			expected: "contains control character U+000A",
+		},
+		{
+			text:     "\x1b[31mred",
+			expected: "contains control character U+001B",
+		},
+		{
+			text:     "bad\xff",
+			expected: "not valid UTF-8",
+		},
+		{
+			text:     strings.Repeat("é", MaxFreeTextLength+1),
+			expected: "too long (1025 > 1024 characters)",
+		},
+	}
+
+	for i, tv := range tvs {
+		err := checkFreeText(tv.text)
+		if tv.expected == "" {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+		} else {
+			assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestValidate_free_text(t *testing.T) {
+	build := "v1\r\nforged log line"
+	policyID := PolicyIDs{"policy://test\x00"}
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.VerifierID = &VerifierIdentity{Build: &build, Developer: &testVidDeveloper}
+	ar.Submods = map[string]*Appraisal{
+		"test": {Status: &testStatus, AppraisalPolicyID: &policyID},
+	}
+
+	err := ar.validate()
+	assert.EqualError(t, err, "invalid value(s) for "+
+		"ear.verifier-id.build (contains control character U+000D), "+
+		"submods[test]: invalid value(s) for ear.appraisal-policy-id (contains control character U+0000)")
+
+	var ve *ValidationError
+	require.True(t, errors.As(err, &ve))
+	assert.Equal(t, []string{
+		"ear.verifier-id.build",
+		"submods[test].ear.appraisal-policy-id",
+	}, ve.Invalid())
+
+	ar.SanitizeFreeText()
+
+	require.NoError(t, ar.validate())
+	assert.Equal(t, "v1forged log line", *ar.VerifierID.Build)
+	assert.Equal(t, PolicyIDs{"policy://test"}, *ar.Submods["test"].AppraisalPolicyID)
+}
+
+func TestSanitizeFreeText(t *testing.T) {
+	assert.Equal(t, "ok�", sanitizeFreeText("ok\xff"))
+	assert.Equal(t, strings.Repeat("é", MaxFreeTextLength),
+		sanitizeFreeText(strings.Repeat("é", MaxFreeTextLength+5)))
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"reflect"
+	"sort"
+)
+
+// FrozenResult is a read-only view of an AttestationResult.  It holds a
+// private deep copy of the result, and its accessors return copies too, so
+// that code handling a verified result (e.g., middleware sitting between
+// verification and policy evaluation) cannot alter it, whether on purpose or
+// by accident.  Use Result to obtain a mutable copy.
+type FrozenResult struct {
+	ar AttestationResult
+}
+
+// Freeze returns a FrozenResult holding a deep copy of ar
+func Freeze(ar AttestationResult) *FrozenResult {
+	return &FrozenResult{ar: deepCopy(ar)}
+}
+
+// WithFrozenResult makes Verify, VerifyCWT and VerifySD fill in dst with a
+// frozen copy of the verified AttestationResult.  dst is only touched if verification succeeds,
+// after the after-verify hooks have been run.
+func WithFrozenResult(dst *FrozenResult) VerifyOption {
+	return func(o *verifyOptions) {
+		o.frozen = dst
+	}
+}
+
+// Result returns a mutable deep copy of the frozen AttestationResult.
+// Changes made to it are not reflected in the FrozenResult.
+func (o FrozenResult) Result() AttestationResult {
+	return deepCopy(o.ar)
+}
+
+// Profile returns the eat_profile claim, or an empty string if it is absent
+func (o FrozenResult) Profile() string {
+	if o.ar.Profile == nil {
+		return ""
+	}
+	return string(*o.ar.Profile)
+}
+
+// IssuedAt returns the iat claim, and whether it is present
+func (o FrozenResult) IssuedAt() (int64, bool) {
+	if o.ar.IssuedAt == nil {
+		return 0, false
+	}
+	return *o.ar.IssuedAt, true
+}
+
+// VerifierID returns a copy of the ear.verifier-id claim, and whether it is
+// present
+func (o FrozenResult) VerifierID() (VerifierIdentity, bool) {
+	if o.ar.VerifierID == nil {
+		return VerifierIdentity{}, false
+	}
+	return deepCopy(*o.ar.VerifierID), true
+}
+
+// Submods returns the names of the submods, sorted
+func (o FrozenResult) Submods() []string {
+	names := make([]string, 0, len(o.ar.Submods))
+	for name := range o.ar.Submods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Appraisal returns a copy of the appraisal for the named submod, and whether
+// it exists
+func (o FrozenResult) Appraisal(submod string) (Appraisal, bool) {
+	a, ok := o.ar.Submods[submod]
+	if !ok || a == nil {
+		return Appraisal{}, false
+	}
+	return deepCopy(*a), true
+}
+
+// StatusMap is the read-only counterpart of AttestationResult.StatusMap
+func (o FrozenResult) StatusMap() map[string]TrustTier {
+	return o.ar.StatusMap()
+}
+
+// OverallStatus is the read-only counterpart of
+// AttestationResult.OverallStatus
+func (o FrozenResult) OverallStatus(opts ...OverallStatusOption) TrustTier {
+	return o.ar.OverallStatus(opts...)
+}
+
+// MarshalJSON serializes the frozen AttestationResult
+func (o FrozenResult) MarshalJSON() ([]byte, error) {
+	return o.ar.MarshalJSON()
+}
+
+// deepCopy returns a copy of v that shares no mutable state with it, except
+// for the Registry, which is configuration rather than claims
+func deepCopy[T any](v T) T {
+	src := reflect.ValueOf(&v).Elem()
+	dst := reflect.New(src.Type()).Elem()
+	copyValue(dst, src)
+	return dst.Interface().(T)
+}
+
+var registryPtrType = reflect.TypeOf((*Registry)(nil))
+
+func copyValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() || src.Type() == registryPtrType {
+			dst.Set(src)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		copyValue(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		e := reflect.New(src.Elem().Type()).Elem()
+		copyValue(e, src.Elem())
+		dst.Set(e)
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			e := reflect.New(src.Type().Elem()).Elem()
+			copyValue(e, iter.Value())
+			m.SetMapIndex(iter.Key(), e)
+		}
+		dst.Set(m)
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			copyValue(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i))
+		}
+	case reflect.Struct:
+		// unexported fields can only be copied along with the whole struct
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				copyValue(dst.Field(i), src.Field(i))
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify_WithFrozenResult(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var (
+		ar     AttestationResult
+		frozen FrozenResult
+	)
+
+	err = ar.Verify(token, jwa.ES256, vfyK, WithFrozenResult(&frozen))
+	require.NoError(t, err)
+
+	// mutating the verified result leaves the frozen view alone
+	contraindicated := TrustTierContraindicated
+	*ar.Submods["test"].Status = contraindicated
+	(*ar.Submods["test"].VeraisonPolicyClaims)["foo"] = "tampered"
+	ar.Submods["extra"] = &Appraisal{Status: &contraindicated}
+
+	assert.Equal(t, []string{"test"}, frozen.Submods())
+	assert.Equal(t, TrustTierAffirming, frozen.OverallStatus())
+	assert.Equal(t, testProfile.String(), frozen.Profile())
+
+	iat, ok := frozen.IssuedAt()
+	assert.True(t, ok)
+	assert.Equal(t, testIAT, iat)
+
+	a, ok := frozen.Appraisal("test")
+	require.True(t, ok)
+	assert.Equal(t, "bar", (*a.VeraisonPolicyClaims)["foo"])
+
+	// and so does mutating what the accessors return
+	(*a.VeraisonPolicyClaims)["foo"] = "tampered"
+	*a.Status = contraindicated
+
+	vid, ok := frozen.VerifierID()
+	require.True(t, ok)
+	*vid.Build = "tampered"
+
+	again, _ := frozen.Appraisal("test")
+	assert.Equal(t, "bar", (*again.VeraisonPolicyClaims)["foo"])
+	assert.Equal(t, TrustTierAffirming, *again.Status)
+
+	vid, _ = frozen.VerifierID()
+	assert.Equal(t, testVidBuild, *vid.Build)
+}
+
+func TestVerifyCWT_VerifySD_WithFrozenResult(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	cwt, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	sigK, vfyK := testKeyPair(t)
+
+	sd, err := testAttestationResultsWithVeraisonExtns.SignSD(jwa.ES256, sigK, nil)
+	require.NoError(t, err)
+
+	for i, verify := range []func(*AttestationResult, ...VerifyOption) error{
+		func(ar *AttestationResult, opts ...VerifyOption) error {
+			return ar.VerifyCWT(cwt, verifier, opts...)
+		},
+		func(ar *AttestationResult, opts ...VerifyOption) error {
+			return ar.VerifySD(sd.Bytes(), jwa.ES256, vfyK, opts...)
+		},
+	} {
+		var (
+			ar     AttestationResult
+			frozen FrozenResult
+		)
+
+		require.NoError(t, verify(&ar, WithFrozenResult(&frozen)), "failed test vector at index %d", i)
+
+		assert.Equal(t, []string{"test"}, frozen.Submods(), "failed test vector at index %d", i)
+		assert.Equal(t, TrustTierAffirming, frozen.OverallStatus(), "failed test vector at index %d", i)
+		assert.Equal(t, testProfile.String(), frozen.Profile(), "failed test vector at index %d", i)
+	}
+}
+
+func TestVerify_WithFrozenResult_untouched_on_failure(t *testing.T) {
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	var (
+		ar     AttestationResult
+		frozen FrozenResult
+	)
+
+	err = ar.Verify([]byte("bogus"), jwa.ES256, vfyK, WithFrozenResult(&frozen))
+	assert.Error(t, err)
+	assert.Equal(t, FrozenResult{}, frozen)
+}
+
+func TestFreeze_Result(t *testing.T) {
+	frozen := Freeze(testAttestationResultsWithVeraisonExtns)
+
+	res := frozen.Result()
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, res)
+
+	res.Submods["test"].AppraisalPolicyID = nil
+	delete(*res.Submods["test"].VeraisonAnnotatedEvidence, "k1")
+
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, frozen.Result())
+	assert.NotNil(t, testAttestationResultsWithVeraisonExtns.Submods["test"].AppraisalPolicyID)
+
+	expected, err := testAttestationResultsWithVeraisonExtns.MarshalJSON()
+	require.NoError(t, err)
+
+	actual, err := frozen.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestFrozenResult_missing(t *testing.T) {
+	var frozen FrozenResult
+
+	assert.Empty(t, frozen.Profile())
+	assert.Empty(t, frozen.Submods())
+
+	_, ok := frozen.IssuedAt()
+	assert.False(t, ok)
+
+	_, ok = frozen.VerifierID()
+	assert.False(t, ok)
+
+	_, ok = frozen.Appraisal("test")
+	assert.False(t, ok)
+}
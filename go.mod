@@ -3,12 +3,15 @@ module github.com/veraison/ear
 go 1.18
 
 require (
+	github.com/fxamacker/cbor/v2 v2.4.0
 	github.com/huandu/xstrings v1.3.3
 	github.com/lestrrat-go/jwx/v2 v2.0.6
 	github.com/spf13/afero v1.9.2
 	github.com/spf13/cobra v1.6.1
 	github.com/spf13/viper v1.13.0
 	github.com/stretchr/testify v1.8.0
+	github.com/veraison/go-cose v1.1.0
+	github.com/x448/float16 v0.8.4
 )
 
 require (
@@ -0,0 +1,282 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	// tdxMeasurementSize is the size of TDX measurements (SHA-384)
+	tdxMeasurementSize = 48
+	// tdxRTMRs is the number of TDX run-time measurement registers
+	tdxRTMRs = 4
+	// sgxMeasurementSize is the size of SGX measurements (SHA-256)
+	sgxMeasurementSize = 32
+)
+
+// IntelTCBStatus is the TCB status of an Intel TDX or SGX platform, as
+// determined by the verification of its quote against the Intel TCB info
+type IntelTCBStatus string
+
+// Intel TCB status values
+const (
+	IntelTCBUpToDate                          IntelTCBStatus = "UpToDate"
+	IntelTCBSWHardeningNeeded                 IntelTCBStatus = "SWHardeningNeeded"
+	IntelTCBConfigurationNeeded               IntelTCBStatus = "ConfigurationNeeded"
+	IntelTCBConfigurationAndSWHardeningNeeded IntelTCBStatus = "ConfigurationAndSWHardeningNeeded"
+	IntelTCBOutOfDate                         IntelTCBStatus = "OutOfDate"
+	IntelTCBOutOfDateConfigurationNeeded      IntelTCBStatus = "OutOfDateConfigurationNeeded"
+	IntelTCBRevoked                           IntelTCBStatus = "Revoked"
+)
+
+// IsValid returns true if the status is one of the known Intel TCB statuses
+func (o IntelTCBStatus) IsValid() bool {
+	switch o {
+	case IntelTCBUpToDate, IntelTCBSWHardeningNeeded, IntelTCBConfigurationNeeded,
+		IntelTCBConfigurationAndSWHardeningNeeded, IntelTCBOutOfDate,
+		IntelTCBOutOfDateConfigurationNeeded, IntelTCBRevoked:
+		return true
+	default:
+		return false
+	}
+}
+
+// VeraisonTDXInfo is the "ear.veraison.tdx-info" claim, in which a verifier
+// appraising Intel TDX evidence records the measurements of the trust domain
+// and the TCB status of the platform.  All the members are optional.
+// Measurements are base64url-encoded in JSON, byte strings in CBOR; when
+// decoding JSON, hex-encoded measurements are also accepted.
+type VeraisonTDXInfo struct {
+	// MRTD is the measurement of the initial contents of the trust domain
+	MRTD *B64Url `json:"mrtd,omitempty"`
+	// RTMRs are the run-time measurement registers, in index order
+	RTMRs []B64Url `json:"rtmrs,omitempty"`
+	// TCBStatus is the TCB status of the platform
+	TCBStatus *IntelTCBStatus `json:"tcb-status,omitempty"`
+}
+
+// VeraisonSGXInfo is the "ear.veraison.sgx-info" claim, in which a verifier
+// appraising Intel SGX evidence records the identity of the enclave and the
+// TCB status of the platform.  All the members are optional.  Measurements
+// are encoded as for VeraisonTDXInfo.
+type VeraisonSGXInfo struct {
+	// MRENCLAVE is the measurement of the enclave
+	MRENCLAVE *B64Url `json:"mrenclave,omitempty"`
+	// MRSIGNER is the hash of the public key that signed the enclave
+	MRSIGNER *B64Url `json:"mrsigner,omitempty"`
+	// ISVSVN is the security version number of the enclave
+	ISVSVN *uint16 `json:"isv-svn,omitempty"`
+	// TCBStatus is the TCB status of the platform
+	TCBStatus *IntelTCBStatus `json:"tcb-status,omitempty"`
+}
+
+// SetTDXInfo validates and sets the "ear.veraison.tdx-info" claim
+func (o *AppraisalExtensions) SetTDXInfo(info VeraisonTDXInfo) error {
+	if err := info.validate(); err != nil {
+		return err
+	}
+
+	o.VeraisonTDXInfo = &info
+
+	return nil
+}
+
+// SetSGXInfo validates and sets the "ear.veraison.sgx-info" claim
+func (o *AppraisalExtensions) SetSGXInfo(info VeraisonSGXInfo) error {
+	if err := info.validate(); err != nil {
+		return err
+	}
+
+	o.VeraisonSGXInfo = &info
+
+	return nil
+}
+
+func (o VeraisonTDXInfo) validate() error {
+	if o.MRTD != nil {
+		if err := checkMeasurementSize(*o.MRTD, tdxMeasurementSize); err != nil {
+			return fmt.Errorf(`"mrtd": %w`, err)
+		}
+	}
+
+	if len(o.RTMRs) > tdxRTMRs {
+		return fmt.Errorf(`"rtmrs": %d registers, at most %d expected`, len(o.RTMRs), tdxRTMRs)
+	}
+
+	for i, m := range o.RTMRs {
+		if err := checkMeasurementSize(m, tdxMeasurementSize); err != nil {
+			return fmt.Errorf(`"rtmrs"[%d]: %w`, i, err)
+		}
+	}
+
+	return checkTCBStatus(o.TCBStatus)
+}
+
+func (o VeraisonSGXInfo) validate() error {
+	if o.MRENCLAVE != nil {
+		if err := checkMeasurementSize(*o.MRENCLAVE, sgxMeasurementSize); err != nil {
+			return fmt.Errorf(`"mrenclave": %w`, err)
+		}
+	}
+
+	if o.MRSIGNER != nil {
+		if err := checkMeasurementSize(*o.MRSIGNER, sgxMeasurementSize); err != nil {
+			return fmt.Errorf(`"mrsigner": %w`, err)
+		}
+	}
+
+	return checkTCBStatus(o.TCBStatus)
+}
+
+func checkMeasurementSize(m []byte, size int) error {
+	if len(m) != size {
+		return fmt.Errorf("%d bytes, expecting %d", len(m), size)
+	}
+	return nil
+}
+
+func checkTCBStatus(s *IntelTCBStatus) error {
+	if s != nil && !s.IsValid() {
+		return fmt.Errorf(`"tcb-status": unknown status %q`, string(*s))
+	}
+	return nil
+}
+
+// measurementParser decodes a measurement of the supplied size, which can be
+// either hex or base64url encoded.  The size disambiguates between the two,
+// since the hex and base64url encodings of the same number of bytes have
+// different lengths.
+func measurementParser(v interface{}, size int) (B64Url, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+
+	if len(s) == hex.EncodedLen(size) {
+		if b, err := hex.DecodeString(s); err == nil {
+			return B64Url(b), nil
+		}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("neither hex nor base64url")
+	}
+
+	if err := checkMeasurementSize(b, size); err != nil {
+		return nil, err
+	}
+
+	return B64Url(b), nil
+}
+
+func tcbStatusParser(v interface{}) (*IntelTCBStatus, error) {
+	s, err := str(v)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid value for "tcb-status": %w`, err)
+	}
+
+	status := IntelTCBStatus(s)
+
+	return &status, nil
+}
+
+func ToVeraisonTDXInfo(v interface{}) (*VeraisonTDXInfo, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "tdx-info"`)
+	}
+
+	var info VeraisonTDXInfo
+
+	for key, val := range vMap {
+		switch key {
+		case "mrtd":
+			m, err := measurementParser(val, tdxMeasurementSize)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "mrtd": %w`, err)
+			}
+			info.MRTD = &m
+		case "rtmrs":
+			a, ok := val.([]interface{})
+			if !ok {
+				return nil, errors.New(`invalid value for "rtmrs": not an array`)
+			}
+			for i, e := range a {
+				m, err := measurementParser(e, tdxMeasurementSize)
+				if err != nil {
+					return nil, fmt.Errorf(`invalid value for "rtmrs"[%d]: %w`, i, err)
+				}
+				info.RTMRs = append(info.RTMRs, m)
+			}
+		case "tcb-status":
+			s, err := tcbStatusParser(val)
+			if err != nil {
+				return nil, err
+			}
+			info.TCBStatus = s
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "tdx-info" object`, key)
+		}
+	}
+
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func ToVeraisonSGXInfo(v interface{}) (*VeraisonSGXInfo, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "sgx-info"`)
+	}
+
+	var info VeraisonSGXInfo
+
+	for key, val := range vMap {
+		switch key {
+		case "mrenclave", "mrsigner":
+			m, err := measurementParser(val, sgxMeasurementSize)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+			}
+			if key == "mrenclave" {
+				info.MRENCLAVE = &m
+			} else {
+				info.MRSIGNER = &m
+			}
+		case "isv-svn":
+			i, err := int64PtrParser(val)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "isv-svn": %w`, err)
+			}
+			n := *(i.(*int64))
+			if n < 0 || n > 0xffff {
+				return nil, fmt.Errorf(`invalid value for "isv-svn": %d out of range`, n)
+			}
+			svn := uint16(n)
+			info.ISVSVN = &svn
+		case "tcb-status":
+			s, err := tcbStatusParser(val)
+			if err != nil {
+				return nil, err
+			}
+			info.TCBStatus = s
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "sgx-info" object`, key)
+		}
+	}
+
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToVeraisonSGXInfo_hex(t *testing.T) {
+	mrenclave := bytes.Repeat([]byte{0xab}, 32)
+
+	info, err := ToVeraisonSGXInfo(map[string]interface{}{
+		"mrenclave": hex.EncodeToString(mrenclave),
+		"isv-svn":   1.0,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, B64Url(mrenclave), *info.MRENCLAVE)
+	assert.Equal(t, uint16(1), *info.ISVSVN)
+}
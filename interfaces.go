@@ -0,0 +1,134 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	cose "github.com/veraison/go-cose"
+)
+
+// The interfaces below abstract the main capabilities of the package, so that
+// services can depend on them rather than on the AttestationResult methods,
+// and substitute test doubles that do not need real keys.  Each comes with
+// concrete implementations wrapping the corresponding methods.  Token storage
+// is abstracted by TokenStore, and the derivation of appraisal status from
+// trust vectors by StatusPolicy (per appraisal) and PolicyEvaluator (per
+// result).
+
+// Encoder serializes an AttestationResult without signing it
+type Encoder interface {
+	Encode(ar AttestationResult) ([]byte, error)
+}
+
+// Signer signs an AttestationResult, returning the signed token
+type Signer interface {
+	Sign(ar AttestationResult) ([]byte, error)
+}
+
+// Verifier verifies a signed token, returning the AttestationResult it
+// carries
+type Verifier interface {
+	Verify(data []byte) (*AttestationResult, error)
+}
+
+// PolicyEvaluator updates the status of the appraisals of an
+// AttestationResult, e.g., after their trust vectors have been set
+type PolicyEvaluator interface {
+	Evaluate(ar *AttestationResult) error
+}
+
+// JSONEncoder is an Encoder that uses MarshalJSON
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(ar AttestationResult) ([]byte, error) {
+	return ar.MarshalJSON()
+}
+
+// CBOREncoder is an Encoder that uses MarshalCBOR
+type CBOREncoder struct{}
+
+func (CBOREncoder) Encode(ar AttestationResult) ([]byte, error) {
+	return ar.MarshalCBOR()
+}
+
+// JWTSigner is a Signer that produces JWTs using the supplied algorithm, key
+// and options
+type JWTSigner struct {
+	Alg     jwa.KeyAlgorithm
+	Key     interface{}
+	Options []SignOption
+}
+
+func (o JWTSigner) Sign(ar AttestationResult) ([]byte, error) {
+	return ar.Sign(o.Alg, o.Key, o.Options...)
+}
+
+// CWTSigner is a Signer that produces COSE_Sign1 messages using the supplied
+// COSE signer and options
+type CWTSigner struct {
+	Signer  cose.Signer
+	Options []SignOption
+}
+
+func (o CWTSigner) Sign(ar AttestationResult) ([]byte, error) {
+	return ar.SignCWT(o.Signer, o.Options...)
+}
+
+// JWTVerifier is a Verifier for JWTs, using the supplied algorithm, key and
+// options
+type JWTVerifier struct {
+	Alg     jwa.KeyAlgorithm
+	Key     interface{}
+	Options []VerifyOption
+}
+
+func (o JWTVerifier) Verify(data []byte) (*AttestationResult, error) {
+	var ar AttestationResult
+
+	if err := ar.Verify(data, o.Alg, o.Key, o.Options...); err != nil {
+		return nil, err
+	}
+
+	return &ar, nil
+}
+
+// CWTVerifier is a Verifier for COSE_Sign1 messages, using the supplied COSE
+// verifier and options
+type CWTVerifier struct {
+	Verifier cose.Verifier
+	Options  []VerifyOption
+}
+
+func (o CWTVerifier) Verify(data []byte) (*AttestationResult, error) {
+	var ar AttestationResult
+
+	if err := ar.VerifyCWT(data, o.Verifier, o.Options...); err != nil {
+		return nil, err
+	}
+
+	return &ar, nil
+}
+
+// StatusEvaluator is a PolicyEvaluator that derives the appraisal status from
+// the trust vector, as UpdateStatusFromTrustVector does with the supplied
+// options
+type StatusEvaluator struct {
+	Options []StatusOption
+}
+
+func (o StatusEvaluator) Evaluate(ar *AttestationResult) error {
+	ar.UpdateStatusFromTrustVector(o.Options...)
+	return nil
+}
+
+var (
+	_ Encoder         = JSONEncoder{}
+	_ Encoder         = CBOREncoder{}
+	_ Signer          = JWTSigner{}
+	_ Signer          = CWTSigner{}
+	_ Verifier        = JWTVerifier{}
+	_ Verifier        = CWTVerifier{}
+	_ PolicyEvaluator = StatusEvaluator{}
+	_ TokenStore      = (*MemoryTokenStore)(nil)
+)
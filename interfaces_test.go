@@ -0,0 +1,96 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner_Verifier_JWT(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	var (
+		s Signer   = JWTSigner{Alg: jwa.ES256, Key: sigK, Options: []SignOption{WithKeyID("k1")}}
+		v Verifier = JWTVerifier{Alg: jwa.ES256, Key: vfyK}
+	)
+
+	token, err := s.Sign(testAttestationResultsWithVeraisonExtns)
+	require.NoError(t, err)
+
+	ar, err := v.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns.Submods, ar.Submods)
+
+	_, err = JWTVerifier{Alg: jwa.ES384, Key: vfyK}.Verify(token)
+	assert.Error(t, err)
+}
+
+func TestSigner_Verifier_CWT(t *testing.T) {
+	signer, verifier := testCOSESignerVerifier(t)
+
+	var (
+		s Signer   = CWTSigner{Signer: signer}
+		v Verifier = CWTVerifier{Verifier: verifier}
+	)
+
+	token, err := s.Sign(testAttestationResultsWithVeraisonExtns)
+	require.NoError(t, err)
+
+	ar, err := v.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns.Submods, ar.Submods)
+}
+
+func TestEncoders(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+
+	for i, e := range []Encoder{JSONEncoder{}, CBOREncoder{}} {
+		_, err := e.Encode(ar)
+		assert.NoError(t, err, "failed test vector at index %d", i)
+
+		_, err = e.Encode(AttestationResult{})
+		assert.Error(t, err, "failed test vector at index %d", i)
+	}
+}
+
+func TestStatusEvaluator(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = map[string]*Appraisal{"test": {
+		Status:      NewTrustTier(TrustTierAffirming),
+		TrustVector: &TrustVector{Executables: UnrecognizedRuntimeClaim},
+	}}
+
+	var pe PolicyEvaluator = StatusEvaluator{}
+	require.NoError(t, pe.Evaluate(&ar))
+
+	assert.Equal(t, TrustTierWarning, *ar.Submods["test"].Status)
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	var store TokenStore = NewMemoryTokenStore(map[string][]byte{
+		"b": []byte("token-b"),
+		"a": []byte("token-a"),
+	})
+
+	ids, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids)
+
+	tok, err := store.Load("a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("token-a"), tok)
+
+	_, err = store.Load("c")
+	assert.EqualError(t, err, `token "c" not found`)
+
+	require.NoError(t, store.Replace(map[string][]byte{"c": []byte("token-c")}))
+
+	ids, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, ids)
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// Introspection renders the AttestationResult as an OAuth 2.0 token
+// introspection response (RFC7662), so that it can be consumed by relying
+// party middleware that expects introspection semantics.  The result is active
+// if it is valid, has neither expired nor is not yet valid (exp and nbf), and
+// is fresh enough (see WithMaxTokenAge).  The clock and tolerance can be set
+// using WithClock and WithAcceptableSkew.  An active response carries the EAR
+// claims next to the "active" member, whereas, as mandated by RFC7662, an
+// inactive one only carries "active".
+//
+// The AttestationResult is expected to come from a verified token: use
+// IntrospectJWT to verify and introspect in one go.
+func (o AttestationResult) Introspection(opts ...VerifyOption) map[string]interface{} {
+	vo := newVerifyOptions(opts)
+	now := vo.now()
+
+	if o.validate() != nil ||
+		o.checkValidity(now, vo.skew) != nil ||
+		o.checkAge(now, vo.maxAge, vo.skew) != nil {
+		return inactiveIntrospection()
+	}
+
+	m := o.AsMap()
+	m["active"] = true
+
+	return m
+}
+
+// IntrospectJWT verifies the signed EAR in data, as Verify does, and returns
+// the corresponding introspection response.  Any verification failure results
+// in an inactive response, which deliberately does not say why.
+func IntrospectJWT(
+	data []byte,
+	alg jwa.KeyAlgorithm,
+	key interface{},
+	opts ...VerifyOption,
+) map[string]interface{} {
+	var ar AttestationResult
+
+	if err := ar.Verify(data, alg, key, opts...); err != nil {
+		return inactiveIntrospection()
+	}
+
+	return ar.Introspection(opts...)
+}
+
+func inactiveIntrospection() map[string]interface{} {
+	return map[string]interface{}{"active": false}
+}
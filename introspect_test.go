@@ -0,0 +1,70 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestationResult_Introspection(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	iat := time.Unix(*ar.IssuedAt, 0)
+
+	exp := iat.Add(time.Hour).Unix()
+	ar.Expiry = &exp
+
+	clock := func(d time.Duration) VerifyOption {
+		return WithClock(func() time.Time { return iat.Add(d) })
+	}
+
+	tvs := []struct {
+		opts   []VerifyOption
+		active bool
+	}{
+		{[]VerifyOption{clock(time.Minute)}, true},
+		{[]VerifyOption{clock(2 * time.Hour)}, false},
+		{[]VerifyOption{clock(2 * time.Hour), WithAcceptableSkew(2 * time.Hour)}, true},
+		{[]VerifyOption{clock(10 * time.Minute), WithMaxTokenAge(5 * time.Minute)}, false},
+	}
+
+	for i, tv := range tvs {
+		resp := ar.Introspection(tv.opts...)
+		assert.Equal(t, tv.active, resp["active"], "failed test vector at index %d", i)
+
+		if tv.active {
+			assert.Equal(t, *ar.IssuedAt, resp["iat"], "failed test vector at index %d", i)
+			assert.Contains(t, resp, "submods", "failed test vector at index %d", i)
+		} else {
+			assert.Len(t, resp, 1, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestAttestationResult_Introspection_invalid(t *testing.T) {
+	resp := AttestationResult{}.Introspection()
+	assert.Equal(t, map[string]interface{}{"active": false}, resp)
+}
+
+func TestIntrospectJWT(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	resp := IntrospectJWT(token, jwa.ES256, vfyK)
+	assert.Equal(t, true, resp["active"])
+	assert.Equal(t, "tag:github.com,2023:veraison/ear", resp["eat_profile"])
+
+	// tamper with the signature
+	token[bytes.LastIndexByte(token, '.')+1] ^= 0x01
+
+	resp = IntrospectJWT(token, jwa.ES256, vfyK)
+	assert.Equal(t, map[string]interface{}{"active": false}, resp)
+}
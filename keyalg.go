@@ -0,0 +1,107 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// curves maps the ECDSA JWS algorithms onto the curve they are defined for
+var curves = map[jwa.SignatureAlgorithm]elliptic.Curve{
+	jwa.ES256: elliptic.P256(),
+	jwa.ES384: elliptic.P384(),
+	jwa.ES512: elliptic.P521(),
+}
+
+// checkKeyAlgorithm makes sure that key can be used with alg, so that
+// mismatches are reported in terms of algorithms and key types rather than
+// through the errors of the underlying JWS implementation.  Keys of unknown
+// types are let through.
+func checkKeyAlgorithm(alg jwa.KeyAlgorithm, key interface{}) error {
+	sigAlg, ok := alg.(jwa.SignatureAlgorithm)
+	if !ok {
+		return fmt.Errorf("%q is not a signature algorithm", alg)
+	}
+
+	if k, ok := key.(jwk.Key); ok {
+		var raw interface{}
+		if err := k.Raw(&raw); err != nil {
+			return fmt.Errorf("extracting raw key: %w", err)
+		}
+		key = raw
+	}
+
+	// look at the public half of private and opaque (e.g., HSM-backed) keys
+	if s, ok := key.(crypto.Signer); ok {
+		key = s.Public()
+	}
+
+	var want string
+
+	switch {
+	case sigAlg == jwa.EdDSA:
+		if _, ok := key.(ed25519.PublicKey); ok {
+			return nil
+		}
+		want = "an Ed25519"
+	case strings.HasPrefix(sigAlg.String(), "RS"), strings.HasPrefix(sigAlg.String(), "PS"):
+		if _, ok := key.(*rsa.PublicKey); ok {
+			return nil
+		}
+		want = "an RSA"
+	case strings.HasPrefix(sigAlg.String(), "ES"):
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			want = "an EC"
+			break
+		}
+		if c, ok := curves[sigAlg]; ok && k.Curve != c {
+			return fmt.Errorf(
+				"%s requires curve %s, but the key uses %s",
+				sigAlg, c.Params().Name, k.Curve.Params().Name,
+			)
+		}
+		return nil
+	case strings.HasPrefix(sigAlg.String(), "HS"):
+		if _, ok := key.([]byte); ok {
+			return nil
+		}
+		want = "a symmetric"
+	default:
+		return nil
+	}
+
+	got := keyTypeName(key)
+	if got == "" {
+		return nil
+	}
+
+	return fmt.Errorf("%s requires %s key, but %s key was supplied", sigAlg, want, got)
+}
+
+// keyTypeName returns a human readable description of the type of a public
+// or symmetric key, or the empty string if the type is not known
+func keyTypeName(key interface{}) string {
+	switch key.(type) {
+	case ed25519.PublicKey:
+		return "an Ed25519"
+	case *rsa.PublicKey:
+		return "an RSA"
+	case *ecdsa.PublicKey:
+		return "an EC"
+	case []byte:
+		return "a symmetric"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testEd25519PrivateKey = `{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"x": "QgVDEBDHV-LpXI6lhq_vXPqX8mKrfyUHwxiKRZvVCtI",
+		"d": "gf-1hxp1FkFbegPLEoCKsv90vgfF85_mG42LbgujWU4"
+	}`
+
+	testEd25519PublicKey = `{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"x": "QgVDEBDHV-LpXI6lhq_vXPqX8mKrfyUHwxiKRZvVCtI"
+	}`
+
+	testRSAPrivateKey = `{
+		"kty": "RSA",
+		"n": "nm3uSbxYqoIUYwTLAhyILQ-a2EBrFlTpOGWDvMelL5GRSNmce7lU7qCa8dkc8c1VYj2obVNvM25q9p3ogY17hL5Uxpkm6CStnXsh6c7BJBhuwBg9ZveoXbvdCz9rtsf-mX0Xk9y93X1LNJqtAjWfE6oBVkMKD1zWBzvqXk6FIiEyUJ_LZNdWkOfAB1QXxqbS54kENHMfQFmQ6VzIRs0B1i012a1zsgl_yxaEEPIRs6GWfnTwIF6POyy9ojJ4PCYoF4p5kaQpXTwagLITwPd1Z19ZV3z_g7097TTeGx9e4vT1MnuW6ybAzd5loHcqtn0MS4mzQZjSb9OsLPEo8b8lmQ",
+		"e": "AQAB",
+		"d": "SZGg4sq8nckz8FtMbhmTvuENNbiMQd2CQ3cYerR256UAQ0USHn4Mi5c2_HGmuD5ylOEu8j2ZWNfAge8IBgkOP7EmquZ5o7lMieGg-PLSy5Ew9LEM6mC85XIUjmGQAlvSxbHKHhaWcGD7aF0fU_RUf4wlfMo6xLGGjetrxQlPV24542ATeXq6yR-_ooi-FGGLxaxeo2-mO85NxtrmZTFCRzJgOxfI5KSEy1XkD28qywS-QDwdmTe74VpcS2lUaYvxH3wVqm9jZwiPNuiIpSSEVUjSLtMbXvrdZ0sq4IU5SC4RTfNFgbqImS5ejLbF5xPeMbdoZqmFgSBJ626D4AgkNQ",
+		"p": "xz9nbiMCe5fz5ME-FBjNP9CwE1-3Cx7zOK4xsSZ8i71lE2NlchtI_6Z4GdAHRVhiYTgAt3PBeX_jZZO27b-H1y6pXvnf5ZdljM8ztd_LCefkVdBx-tXThN3ZjPrNIv-GwaroBxcwQxE6gyJSOTpxkwJEYo52M-ASs9MLVFhckXc",
+		"q": "y44r0KFheaf7u2yCK3DzY7P7HaJv-GjLr8OlY5-bPUU7uB98qBLzVIL6XMiKaZ2hGGoCErEPi4oxR8oyoL7rD4_yXSbpcDnsRV9Cw3tDAH3Y6YEGrROtrOUfwlHXY02i7nnaCp3mK1YKTVlDg_RFGf_c_32Pc5ARF-GjJYaRRW8",
+		"dp": "VyIPJUcCKvKEoiEv-LHv8Fxe49oTvjVGwB7NdF8UUDf_H79z-bac8c_m7cLfqx-_xiwrhA6HKY8X7q_ua4I68YtiXjqAA3N14WIrhvnbXPWgETca4UrpOU2bed-kHg4I7GDFH3SwkmPSZs53jrdUO6neJguO_ZicFS_R4IzTznk",
+		"dq": "hnA7l-s-Ca9cnwinYDGVTkBMleH2ucXc4RUCYq3DRdA4BP8mwYrikup-mFEKuX6WKx24iMfQUITQz21HysrNTNXxaHyOqMRYu-iSm7EJK4XygSXqVt0sU4hwVVLgQoHAHmj0DRcEMZL98uAFq1zlVqqaN1OiLtzzrSYlLl-WCpk",
+		"qi": "TN9BayAYXRdEBHwUtXzkpiQmuMMZ74eN6WcYGmjUMR8ODYCJhVMptLibRH3DzyUGnOkuOQdTP1XF2L6zc-brV6ghlt2D3CXgu3e6MOHlbi-M6SvT4PFKaLV3e9Tejh2ysT0Liqv9uPP0fEcL4AgsxAMT7WeAzdh4lhZS5KV_HQ"
+	}`
+
+	// golden tokens for testAttestationResultsWithVeraisonExtns.  Ed25519
+	// signatures are deterministic, so the EdDSA token can be reproduced
+	// exactly; RSA-PSS signatures are salted, so the PS* tokens can only be
+	// verified.
+	testEdDSAToken = `eyJhbGciOiJFZERTQSIsInR5cCI6IkpXVCJ9.eyJlYXIudmVyaWZpZXItaWQiOnsiYnVpbGQiOiJycnRyYXAtdjEuMC4wIiwiZGV2ZWxvcGVyIjoiQWNtZSBJbmMuIn0sImVhdF9wcm9maWxlIjoidGFnOmdpdGh1Yi5jb20sMjAyMzp2ZXJhaXNvbi9lYXIiLCJpYXQiOjE2NjYwOTEzNzMsInN1Ym1vZHMiOnsidGVzdCI6eyJlYXIuYXBwcmFpc2FsLXBvbGljeS1pZCI6InBvbGljeTovL3Rlc3QvMDEyMzQiLCJlYXIuc3RhdHVzIjoiYWZmaXJtaW5nIiwiZWFyLnZlcmFpc29uLmFubm90YXRlZC1ldmlkZW5jZSI6eyJrMSI6InYxIiwiazIiOiJ2MiJ9LCJlYXIudmVyYWlzb24ua2V5LWF0dGVzdGF0aW9uIjp7ImFrcHViIjoiWVd0d2RXSUsifSwiZWFyLnZlcmFpc29uLnBvbGljeS1jbGFpbXMiOnsiYmFyIjoiYmF6IiwiZm9vIjoiYmFyIn19fX0.Sf10xLDp6buNku_slbJHalhRI1XGVlX5pbzQ_KC4iVt941IIW-ifRCkPixdKJvSLs7-ZqRy9ZXsvgmfV2abWAg`
+	testPS256Token = `eyJhbGciOiJQUzI1NiIsInR5cCI6IkpXVCJ9.eyJlYXIudmVyaWZpZXItaWQiOnsiYnVpbGQiOiJycnRyYXAtdjEuMC4wIiwiZGV2ZWxvcGVyIjoiQWNtZSBJbmMuIn0sImVhdF9wcm9maWxlIjoidGFnOmdpdGh1Yi5jb20sMjAyMzp2ZXJhaXNvbi9lYXIiLCJpYXQiOjE2NjYwOTEzNzMsInN1Ym1vZHMiOnsidGVzdCI6eyJlYXIuYXBwcmFpc2FsLXBvbGljeS1pZCI6InBvbGljeTovL3Rlc3QvMDEyMzQiLCJlYXIuc3RhdHVzIjoiYWZmaXJtaW5nIiwiZWFyLnZlcmFpc29uLmFubm90YXRlZC1ldmlkZW5jZSI6eyJrMSI6InYxIiwiazIiOiJ2MiJ9LCJlYXIudmVyYWlzb24ua2V5LWF0dGVzdGF0aW9uIjp7ImFrcHViIjoiWVd0d2RXSUsifSwiZWFyLnZlcmFpc29uLnBvbGljeS1jbGFpbXMiOnsiYmFyIjoiYmF6IiwiZm9vIjoiYmFyIn19fX0.B7YfrhhxsDMMAhu14FLRO5h03fNT33alHBAxj51bVkMB3bJ7OjPO-qhd0sT-L4amBbn86X2ABgXUdtUGPp_N_uPdgasMtU9cRkPdKDGgvfsK9DNbx__4TQmaqI3bwy51oV3NJC0wMPRfsHWgmIykep0iraf6NG6b0aPXgbsNo0itJn3ckPXtFj9UcKHWhI5Be_N-zYnazuezCz8-SEiSSGBzTeP1GP8fWFbyrm3XxnoO--tvzRtD-8xgp7THHWrybUE9-oodraSsZRUOGgultQV8_uJmT_H-zPk2cWHSgKEzZqZp-YyYxsyvoJM_Qx-WXc-cbharTEStzkxyVOYiuw`
+	testPS384Token = `eyJhbGciOiJQUzM4NCIsInR5cCI6IkpXVCJ9.eyJlYXIudmVyaWZpZXItaWQiOnsiYnVpbGQiOiJycnRyYXAtdjEuMC4wIiwiZGV2ZWxvcGVyIjoiQWNtZSBJbmMuIn0sImVhdF9wcm9maWxlIjoidGFnOmdpdGh1Yi5jb20sMjAyMzp2ZXJhaXNvbi9lYXIiLCJpYXQiOjE2NjYwOTEzNzMsInN1Ym1vZHMiOnsidGVzdCI6eyJlYXIuYXBwcmFpc2FsLXBvbGljeS1pZCI6InBvbGljeTovL3Rlc3QvMDEyMzQiLCJlYXIuc3RhdHVzIjoiYWZmaXJtaW5nIiwiZWFyLnZlcmFpc29uLmFubm90YXRlZC1ldmlkZW5jZSI6eyJrMSI6InYxIiwiazIiOiJ2MiJ9LCJlYXIudmVyYWlzb24ua2V5LWF0dGVzdGF0aW9uIjp7ImFrcHViIjoiWVd0d2RXSUsifSwiZWFyLnZlcmFpc29uLnBvbGljeS1jbGFpbXMiOnsiYmFyIjoiYmF6IiwiZm9vIjoiYmFyIn19fX0.bLysOiYpzz-6CIj8jPVM7AEzhG6_nrjMy3Cvzjs50ZsVp0jNg5jNpLnz0nnrmYlXQkC72x4pjak-ctbQwM0peUS6gi2n7iJLUs9TSxQ7rfXTMo5PmFCaxsJZfQ6bhZ_0WReyKPHCba-8u4q4CwXrqjfOnsfWbscWggb2ulXJsoSI1hBOo0iO4byrreMrFRRNBJOKItGIgC0cXwKv9l8JybuPULgfQCuW8LaNi-lLMLhwO6bDdc8xonb0SwKr2vXzSlndjqIfbPRiIjCIk0bYbOCBJSKIJW_latAW6ltQos1BlIZgEqeU_pWXQSnik_swQCFqYcMFDZTs26ljt9BxVQ`
+	testPS512Token = `eyJhbGciOiJQUzUxMiIsInR5cCI6IkpXVCJ9.eyJlYXIudmVyaWZpZXItaWQiOnsiYnVpbGQiOiJycnRyYXAtdjEuMC4wIiwiZGV2ZWxvcGVyIjoiQWNtZSBJbmMuIn0sImVhdF9wcm9maWxlIjoidGFnOmdpdGh1Yi5jb20sMjAyMzp2ZXJhaXNvbi9lYXIiLCJpYXQiOjE2NjYwOTEzNzMsInN1Ym1vZHMiOnsidGVzdCI6eyJlYXIuYXBwcmFpc2FsLXBvbGljeS1pZCI6InBvbGljeTovL3Rlc3QvMDEyMzQiLCJlYXIuc3RhdHVzIjoiYWZmaXJtaW5nIiwiZWFyLnZlcmFpc29uLmFubm90YXRlZC1ldmlkZW5jZSI6eyJrMSI6InYxIiwiazIiOiJ2MiJ9LCJlYXIudmVyYWlzb24ua2V5LWF0dGVzdGF0aW9uIjp7ImFrcHViIjoiWVd0d2RXSUsifSwiZWFyLnZlcmFpc29uLnBvbGljeS1jbGFpbXMiOnsiYmFyIjoiYmF6IiwiZm9vIjoiYmFyIn19fX0.Rm47gGVaGH4U4jyoDXJoRcDbj7MMBG89DbF4sXrL-CkttOHLEMAXcMzIi_ia2kiOqXwEa4uJeOBV_8ZdRZ1T2G5uKadV5UU3kviQ6IJoa7P2O-L6cqUmIvoBj3r4UMXySazu7ymGPAfkmZcCxlUIUq3rpA_InPbhGCGWdJIZVNrq77WEkgPuSWa-6j4Tc9mxOwIFpnpn15yCQ1rzfSa3FaMj5GZ4lGH_sjaY8W5R0uEpYGudbA98NDQx65ACgIgemGh9ZPRAX42JZDJqUCETG8SzJkSE2yUNd5ziBljVFK_dCJ4iAVlZnQz3sH8avbGJ4zWSqlmbJFGvXM4brmx_bg`
+)
+
+func testParseKey(t *testing.T, data string) jwk.Key {
+	k, err := jwk.ParseKey([]byte(data))
+	require.NoError(t, err)
+	return k
+}
+
+func TestSign_EdDSA_golden(t *testing.T) {
+	sigK := testParseKey(t, testEd25519PrivateKey)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.EdDSA, sigK)
+	require.NoError(t, err)
+	assert.Equal(t, testEdDSAToken, string(token))
+}
+
+func TestVerify_EdDSA_PS_golden(t *testing.T) {
+	edK := testParseKey(t, testEd25519PublicKey)
+
+	rsaK, err := testParseKey(t, testRSAPrivateKey).PublicKey()
+	require.NoError(t, err)
+
+	tvs := []struct {
+		alg   jwa.SignatureAlgorithm
+		key   jwk.Key
+		token string
+	}{
+		{jwa.EdDSA, edK, testEdDSAToken},
+		{jwa.PS256, rsaK, testPS256Token},
+		{jwa.PS384, rsaK, testPS384Token},
+		{jwa.PS512, rsaK, testPS512Token},
+	}
+
+	for i, tv := range tvs {
+		var ar AttestationResult
+
+		err := ar.Verify([]byte(tv.token), tv.alg, tv.key)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, testAttestationResultsWithVeraisonExtns, ar, "failed test vector at index %d", i)
+	}
+}
+
+func TestRoundTrip_EdDSA_PS(t *testing.T) {
+	edK := testParseKey(t, testEd25519PrivateKey)
+	rsaK := testParseKey(t, testRSAPrivateKey)
+
+	var edRaw, rsaRaw interface{}
+	require.NoError(t, edK.Raw(&edRaw))
+	require.NoError(t, rsaK.Raw(&rsaRaw))
+
+	tvs := []struct {
+		alg jwa.SignatureAlgorithm
+		key interface{}
+	}{
+		{jwa.EdDSA, edK},
+		{jwa.EdDSA, edRaw},
+		{jwa.PS256, rsaK},
+		{jwa.PS384, rsaK},
+		{jwa.PS512, rsaRaw},
+		{jwa.RS256, rsaRaw},
+	}
+
+	for i, tv := range tvs {
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(tv.alg, tv.key)
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		var vfyK interface{}
+		if k, ok := tv.key.(jwk.Key); ok {
+			vfyK, err = k.PublicKey()
+			require.NoError(t, err)
+		} else {
+			vfyK = tv.key.(interface{ Public() crypto.PublicKey }).Public()
+		}
+
+		var ar AttestationResult
+
+		err = ar.Verify(token, tv.alg, vfyK)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, testAttestationResultsWithVeraisonExtns, ar, "failed test vector at index %d", i)
+	}
+}
+
+func TestSign_key_algorithm_mismatch(t *testing.T) {
+	ecK := testParseKey(t, testECDSAPrivateKey)
+	edK := testParseKey(t, testEd25519PrivateKey)
+	rsaK := testParseKey(t, testRSAPrivateKey)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	tvs := []struct {
+		alg      jwa.SignatureAlgorithm
+		key      interface{}
+		expected string
+	}{
+		{jwa.EdDSA, ecK, "signing key: EdDSA requires an Ed25519 key, but an EC key was supplied"},
+		{jwa.EdDSA, rsaK, "signing key: EdDSA requires an Ed25519 key, but an RSA key was supplied"},
+		{jwa.PS256, edK, "signing key: PS256 requires an RSA key, but an Ed25519 key was supplied"},
+		{jwa.PS512, ecK, "signing key: PS512 requires an RSA key, but an EC key was supplied"},
+		{jwa.ES256, edK, "signing key: ES256 requires an EC key, but an Ed25519 key was supplied"},
+		{jwa.ES256, p384, "signing key: ES256 requires curve P-256, but the key uses P-384"},
+		{jwa.ES384, ecK, "signing key: ES384 requires curve P-384, but the key uses P-256"},
+		{jwa.HS256, edK, "signing key: HS256 requires a symmetric key, but an Ed25519 key was supplied"},
+	}
+
+	for i, tv := range tvs {
+		_, err := testAttestationResultsWithVeraisonExtns.Sign(tv.alg, tv.key)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestVerify_key_algorithm_mismatch(t *testing.T) {
+	rsaK, err := testParseKey(t, testRSAPrivateKey).PublicKey()
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.Verify([]byte(testEdDSAToken), jwa.EdDSA, rsaK)
+	assert.EqualError(t, err, "failed verifying JWT message: EdDSA requires an Ed25519 key, but an RSA key was supplied")
+}
@@ -0,0 +1,311 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// COSE_Key labels and values (RFC9052 and RFC9053) used to check attested keys
+const (
+	coseKeyLabelKty = 1
+	coseKeyLabelD   = -4
+
+	coseKeyTypeSymmetric = 4
+)
+
+// Members of the "ear.veraison.key-attestation" claim.  Besides "akpub" (the
+// attested public key as a base64url-encoded SubjectPublicKeyInfo), the key
+// can be conveyed as a JWK ("jwk", RFC7517) or as a base64url-encoded
+// COSE_Key ("cose-key", RFC9052), and referenced by a key handle ("kid") or
+// by its base64url-encoded SHA-256 JWK thumbprint ("jkt", RFC7638).  At most
+// one of "akpub", "jwk" and "cose-key" may be present, and only public keys
+// can be attested.
+const (
+	keyAttestationAKPub      = "akpub"
+	keyAttestationJWK        = "jwk"
+	keyAttestationCOSEKey    = "cose-key"
+	keyAttestationKeyID      = "kid"
+	keyAttestationThumbprint = "jkt"
+)
+
+// keyAttestationValues are the members that carry the attested key by value
+var keyAttestationValues = []string{
+	keyAttestationAKPub, keyAttestationJWK, keyAttestationCOSEKey,
+}
+
+// SetKeyAttestationJWK sets the "jwk" and "jkt" members of the
+// "ear.veraison.key-attestation" claim to the supplied key, which can be a
+// JWK or a raw key, replacing any previous key, but not its "kid".  If a
+// private key is supplied, its public counterpart is used.
+func (o *AppraisalExtensions) SetKeyAttestationJWK(key interface{}) error {
+	k, err := asJWK(key)
+	if err != nil {
+		return err
+	}
+
+	if k.KeyType() == "oct" {
+		return errors.New("symmetric keys cannot be attested")
+	}
+
+	pub, err := k.PublicKey()
+	if err != nil {
+		return fmt.Errorf("extracting public key: %w", err)
+	}
+
+	j, err := json.Marshal(pub)
+	if err != nil {
+		return fmt.Errorf("encoding JWK: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return fmt.Errorf("encoding JWK: %w", err)
+	}
+
+	tp, err := keyThumbprint(pub)
+	if err != nil {
+		return err
+	}
+
+	o.setKeyAttestationValue(map[string]interface{}{
+		keyAttestationJWK:        m,
+		keyAttestationThumbprint: tp,
+	})
+
+	return nil
+}
+
+// SetKeyAttestationCOSEKey sets the "cose-key" member of the
+// "ear.veraison.key-attestation" claim to the supplied serialized COSE_Key,
+// which must be a public key, replacing any previous key, but not its "kid"
+func (o *AppraisalExtensions) SetKeyAttestationCOSEKey(data []byte) error {
+	if err := checkCOSEKey(data); err != nil {
+		return err
+	}
+
+	o.setKeyAttestationValue(map[string]interface{}{
+		keyAttestationCOSEKey: base64.RawURLEncoding.EncodeToString(data),
+	})
+
+	return nil
+}
+
+// SetKeyAttestationReference sets the "kid" member of the
+// "ear.veraison.key-attestation" claim, which is created if needed.  This can
+// be used on its own, when the attested key is not conveyed by value, or in
+// addition to SetKeyAttestation, SetKeyAttestationJWK or
+// SetKeyAttestationCOSEKey.
+func (o *AppraisalExtensions) SetKeyAttestationReference(kid string) error {
+	if err := checkAttestedKeyID(kid); err != nil {
+		return err
+	}
+
+	if o.VeraisonKeyAttestation == nil {
+		o.VeraisonKeyAttestation = &map[string]interface{}{}
+	}
+
+	(*o.VeraisonKeyAttestation)[keyAttestationKeyID] = kid
+
+	return nil
+}
+
+// GetKeyAttestationJWK returns the attested key carried as a JWK in the
+// "ear.veraison.key-attestation" claim
+func (o AppraisalExtensions) GetKeyAttestationJWK() (jwk.Key, error) {
+	if o.VeraisonKeyAttestation == nil {
+		return nil, errors.New(`"ear.veraison.key-attestation" claim not found`)
+	}
+
+	v, ok := (*o.VeraisonKeyAttestation)[keyAttestationJWK]
+	if !ok {
+		return nil, errors.New(`"jwk" not found in "ear.veraison.key-attestation"`)
+	}
+
+	return parseAttestedJWK(v)
+}
+
+// setKeyAttestationValue replaces the attested key with the supplied members,
+// keeping the key handle, if any
+func (o *AppraisalExtensions) setKeyAttestationValue(members map[string]interface{}) {
+	if o.VeraisonKeyAttestation != nil {
+		if kid, ok := (*o.VeraisonKeyAttestation)[keyAttestationKeyID]; ok {
+			members[keyAttestationKeyID] = kid
+		}
+	}
+
+	o.VeraisonKeyAttestation = &members
+}
+
+func parseAttestedJWK(v interface{}) (jwk.Key, error) {
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, errors.New(`"jwk" is not a JSON object`)
+	}
+
+	j, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf(`encoding "jwk": %w`, err)
+	}
+
+	k, err := jwk.ParseKey(j)
+	if err != nil {
+		return nil, fmt.Errorf(`parsing "jwk": %w`, err)
+	}
+
+	return k, nil
+}
+
+// validateKeyAttestation checks the members of the
+// "ear.veraison.key-attestation" claim.  Members other than those defined
+// above are left alone.
+func validateKeyAttestation(m map[string]interface{}) error {
+	var byValue []string
+	for _, name := range keyAttestationValues {
+		if _, ok := m[name]; ok {
+			byValue = append(byValue, name)
+		}
+	}
+
+	if len(byValue) > 1 {
+		return fmt.Errorf("%q and %q are mutually exclusive", byValue[0], byValue[1])
+	}
+
+	// the key the thumbprint is checked against, if any
+	var key jwk.Key
+
+	if v, ok := m[keyAttestationAKPub]; ok {
+		akpub, ok := v.(string)
+		if !ok {
+			return errors.New(`"akpub" must be string`)
+		}
+
+		der, err := base64.RawURLEncoding.DecodeString(akpub)
+		if err != nil {
+			return fmt.Errorf(`decoding "akpub": %w`, err)
+		}
+
+		// "akpub" predates the other members and has been used with
+		// placeholder values, so only a parsable key is thumbprinted
+		if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+			key, _ = jwk.FromRaw(pub)
+		}
+	}
+
+	if v, ok := m[keyAttestationJWK]; ok {
+		k, err := parseAttestedJWK(v)
+		if err != nil {
+			return err
+		}
+
+		switch k.(type) {
+		case jwk.ECDSAPublicKey, jwk.OKPPublicKey, jwk.RSAPublicKey:
+		default:
+			return fmt.Errorf(`"jwk" is not a public key (%s)`, k.KeyType())
+		}
+
+		key = k
+	}
+
+	if v, ok := m[keyAttestationCOSEKey]; ok {
+		b, err := b64urlBytesPtrParser(v)
+		if err != nil {
+			return fmt.Errorf(`decoding "cose-key": %w`, err)
+		}
+
+		if err := checkCOSEKey(*b.(*B64Url)); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := m[keyAttestationKeyID]; ok {
+		kid, err := str(v)
+		if err != nil {
+			return fmt.Errorf(`"kid": %w`, err)
+		}
+
+		if err := checkAttestedKeyID(kid); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := m[keyAttestationThumbprint]; ok {
+		jkt, err := str(v)
+		if err != nil {
+			return fmt.Errorf(`"jkt": %w`, err)
+		}
+
+		if tp, err := base64.RawURLEncoding.DecodeString(jkt); err != nil || len(tp) != 32 {
+			return errors.New(`"jkt" is not a base64url-encoded SHA-256 thumbprint`)
+		}
+
+		if key != nil {
+			tp, err := keyThumbprint(key)
+			if err != nil {
+				return err
+			}
+
+			if tp != jkt {
+				return fmt.Errorf(`"jkt" does not match %q`, byValue[0])
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkCOSEKey(data []byte) error {
+	var m map[interface{}]interface{}
+
+	if err := cbor.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf(`decoding "cose-key": %w`, err)
+	}
+
+	var (
+		kty interface{}
+		ok  bool
+	)
+
+	for label, v := range m {
+		l, err := cborInt(label)
+		if err != nil {
+			continue
+		}
+
+		switch l {
+		case coseKeyLabelKty:
+			kty, ok = v, true
+		case coseKeyLabelD:
+			return errors.New(`"cose-key" is not a public key`)
+		}
+	}
+
+	if !ok {
+		return errors.New(`"cose-key" has no key type`)
+	}
+
+	if n, err := cborInt(kty); err == nil && n == coseKeyTypeSymmetric {
+		return errors.New(`"cose-key" is not a public key (symmetric)`)
+	}
+
+	return nil
+}
+
+func checkAttestedKeyID(kid string) error {
+	if kid == "" {
+		return errors.New(`empty "kid"`)
+	}
+
+	if err := checkFreeText(kid); err != nil {
+		return fmt.Errorf(`"kid": %w`, err)
+	}
+
+	return nil
+}
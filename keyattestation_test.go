@@ -0,0 +1,198 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCOSEKey(t *testing.T, private bool) []byte {
+	k := map[int]interface{}{
+		1:  2, // kty: EC2
+		-1: 1, // crv: P-256
+		-2: bytes.Repeat([]byte{0x01}, 32),
+		-3: bytes.Repeat([]byte{0x02}, 32),
+	}
+
+	if private {
+		k[-4] = bytes.Repeat([]byte{0x03}, 32)
+	}
+
+	data, err := cbor.Marshal(k)
+	require.NoError(t, err)
+
+	return data
+}
+
+func testRawPublicKey(t *testing.T, k jwk.Key) *ecdsa.PublicKey {
+	var raw ecdsa.PublicKey
+	require.NoError(t, k.Raw(&raw))
+	return &raw
+}
+
+func TestAppraisalExtensions_SetGetKeyAttestationJWK(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	var ext AppraisalExtensions
+
+	_, err := ext.GetKeyAttestationJWK()
+	assert.EqualError(t, err, `"ear.veraison.key-attestation" claim not found`)
+
+	require.NoError(t, ext.SetKeyAttestationReference("slot-7"))
+
+	// the public part of a private key is attested
+	require.NoError(t, ext.SetKeyAttestationJWK(sigK))
+	assert.NotContains(t, (*ext.VeraisonKeyAttestation)["jwk"], "d")
+
+	expected, err := keyThumbprint(vfyK)
+	require.NoError(t, err)
+	assert.Equal(t, expected, (*ext.VeraisonKeyAttestation)["jkt"])
+
+	// the reference survives the key being set
+	assert.Equal(t, "slot-7", (*ext.VeraisonKeyAttestation)["kid"])
+
+	k, err := ext.GetKeyAttestationJWK()
+	require.NoError(t, err)
+
+	actual, err := keyThumbprint(k)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// setting another form of the key replaces the JWK and its thumbprint
+	require.NoError(t, ext.SetKeyAttestation(testRawPublicKey(t, vfyK)))
+	assert.NotContains(t, *ext.VeraisonKeyAttestation, "jwk")
+	assert.NotContains(t, *ext.VeraisonKeyAttestation, "jkt")
+	assert.Equal(t, "slot-7", (*ext.VeraisonKeyAttestation)["kid"])
+
+	sym, err := jwk.FromRaw([]byte("a shared secret"))
+	require.NoError(t, err)
+	assert.EqualError(t, ext.SetKeyAttestationJWK(sym), "symmetric keys cannot be attested")
+}
+
+func TestAppraisalExtensions_SetKeyAttestationCOSEKey(t *testing.T) {
+	var ext AppraisalExtensions
+
+	require.NoError(t, ext.SetKeyAttestationCOSEKey(testCOSEKey(t, false)))
+
+	_, err := ext.GetKeyAttestationJWK()
+	assert.EqualError(t, err, `"jwk" not found in "ear.veraison.key-attestation"`)
+
+	err = ext.SetKeyAttestationCOSEKey(testCOSEKey(t, true))
+	assert.EqualError(t, err, `"cose-key" is not a public key`)
+}
+
+func TestKeyAttestation_roundtrip(t *testing.T) {
+	_, vfyK := testKeyPair(t)
+
+	coseKey := testCOSEKey(t, false)
+
+	var jwkExt, coseExt AppraisalExtensions
+	require.NoError(t, jwkExt.SetKeyAttestationJWK(vfyK))
+	require.NoError(t, coseExt.SetKeyAttestationCOSEKey(coseKey))
+	require.NoError(t, coseExt.SetKeyAttestationReference("slot-7"))
+
+	for i, ext := range []AppraisalExtensions{jwkExt, coseExt} {
+		ar := testAttestationResultsWithVeraisonExtns
+		ar.Submods = map[string]*Appraisal{
+			"test": {Status: &testStatus, AppraisalExtensions: ext},
+		}
+
+		data, err := ar.MarshalJSON()
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		var actual AttestationResult
+		require.NoError(t, actual.UnmarshalJSON(data), "failed test vector at index %d", i)
+		assert.Equal(t, ext.VeraisonKeyAttestation, actual.Submods["test"].VeraisonKeyAttestation,
+			"failed test vector at index %d", i)
+
+		data, err = ar.MarshalCBOR()
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		actual = AttestationResult{}
+		require.NoError(t, actual.UnmarshalCBOR(data), "failed test vector at index %d", i)
+		assert.Equal(t, ext.VeraisonKeyAttestation, actual.Submods["test"].VeraisonKeyAttestation,
+			"failed test vector at index %d", i)
+	}
+
+	// in CBOR, the COSE_Key is embedded as a byte string
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = map[string]*Appraisal{
+		"test": {Status: &testStatus, AppraisalExtensions: coseExt},
+	}
+
+	data, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	bstr, err := cbor.Marshal(coseKey)
+	require.NoError(t, err)
+	assert.True(t, bytes.Contains(data, bstr))
+}
+
+func TestValidateKeyAttestation(t *testing.T) {
+	_, vfyK := testKeyPair(t)
+
+	var ext AppraisalExtensions
+	require.NoError(t, ext.SetKeyAttestationJWK(vfyK))
+	jwkMember := (*ext.VeraisonKeyAttestation)["jwk"]
+
+	require.NoError(t, ext.SetKeyAttestation(testRawPublicKey(t, vfyK)))
+	akpub := (*ext.VeraisonKeyAttestation)["akpub"]
+
+	other := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+	tvs := []struct {
+		m        map[string]interface{}
+		expected string
+	}{
+		// the placeholder "akpub" values of old are accepted
+		{map[string]interface{}{"akpub": "YWtwdWIK"}, ""},
+		{map[string]interface{}{}, ""},
+		{map[string]interface{}{"kid": "slot-7", "jkt": other}, ""},
+		{map[string]interface{}{"akpub": 7.0}, `"akpub" must be string`},
+		{map[string]interface{}{"akpub": "!"}, `decoding "akpub": illegal base64 data at input byte 0`},
+		{map[string]interface{}{"akpub": akpub, "jkt": other}, `"jkt" does not match "akpub"`},
+		{map[string]interface{}{"jwk": jwkMember, "jkt": other}, `"jkt" does not match "jwk"`},
+		{map[string]interface{}{"kid": 7.0}, `"kid": expecting string, found float64`},
+		{map[string]interface{}{"kid": ""}, `empty "kid"`},
+		{map[string]interface{}{"jkt": "not-a-digest"}, `"jkt" is not a base64url-encoded SHA-256 thumbprint`},
+		{map[string]interface{}{"jwk": "oops"}, `"jwk" is not a JSON object`},
+		{
+			map[string]interface{}{"jwk": map[string]interface{}{"kty": "oct", "k": "c2VjcmV0"}},
+			`"jwk" is not a public key (oct)`,
+		},
+		{
+			map[string]interface{}{"akpub": akpub, "cose-key": "oQEC"},
+			`"akpub" and "cose-key" are mutually exclusive`,
+		},
+		{map[string]interface{}{"cose-key": "oQQB"}, `"cose-key" has no key type`},
+		{map[string]interface{}{"cose-key": "oQEE"}, `"cose-key" is not a public key (symmetric)`},
+	}
+
+	for i, tv := range tvs {
+		err := validateKeyAttestation(tv.m)
+		if tv.expected == "" {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+		} else {
+			assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		}
+	}
+
+	appraisal := Appraisal{
+		Status: &testStatus,
+		AppraisalExtensions: AppraisalExtensions{
+			VeraisonKeyAttestation: &map[string]interface{}{"kid": ""},
+		},
+	}
+
+	err := appraisal.validate()
+	assert.EqualError(t, err, `invalid value(s) for 'ear.veraison.key-attestation' (empty "kid")`)
+	assert.ErrorIs(t, err, ErrInvalidClaim)
+}
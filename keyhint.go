@@ -0,0 +1,172 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// TrustAnchors is the allow-list used by VerifyWithTrustAnchors to resolve the
+// verification key from the hints carried in the JWS header.
+type TrustAnchors struct {
+	// Keys are the trusted verifier keys.  A key is selected if its kid, or
+	// its RFC7638 thumbprint, matches the kid in the JWS header.
+	Keys jwk.Set
+	// JWKSURLs are the JWKS locations that can be referenced using the jku
+	// JWS header parameter.  Any other jku is rejected.
+	JWKSURLs []string
+	// Fetch retrieves the JWKS at the supplied URL.  If nil, jwk.Fetch is
+	// used.
+	Fetch func(url string) (jwk.Set, error)
+}
+
+// VerifyWithTrustAnchors is like Verify, but the verification key and
+// algorithm are obtained from the JWS header rather than being supplied by the
+// caller.  If the header carries a jku, it must be one of the anchors'
+// JWKSURLs, and the key is looked up by kid in the JWKS it points to.
+// Otherwise, the kid is looked up in the anchors' Keys.
+func (o *AttestationResult) VerifyWithTrustAnchors(
+	data []byte,
+	anchors TrustAnchors,
+	opts ...VerifyOption,
+) error {
+	if err := newVerifyOptions(opts).checkTokenSize(data); err != nil {
+		return err
+	}
+
+	hdrs, err := protectedHeaders(data)
+	if err != nil {
+		return err
+	}
+
+	var keys jwk.Set
+
+	if jku := hdrs.JWKSetURL(); jku != "" {
+		if !contains(anchors.JWKSURLs, jku) {
+			return fmt.Errorf("jku %q is not a trust anchor", jku)
+		}
+
+		fetch := anchors.Fetch
+		if fetch == nil {
+			fetch = func(u string) (jwk.Set, error) {
+				return jwk.Fetch(context.Background(), u)
+			}
+		}
+
+		if keys, err = fetch(jku); err != nil {
+			return fmt.Errorf("fetching JWKS from %q: %w", jku, err)
+		}
+	} else {
+		keys = anchors.Keys
+	}
+
+	return o.verifyWithKeyFrom(data, keys, hdrs, opts)
+}
+
+// VerifyWithKeySet is like Verify, but the verification key is selected from
+// the supplied set using the kid in the JWS header (which may also be the
+// RFC7638 thumbprint of the key).  The algorithm is taken from the JWS header,
+// and must match the one the selected key is restricted to, if any.  This
+// allows verifier keys to be rotated without out-of-band key selection.
+func (o *AttestationResult) VerifyWithKeySet(
+	data []byte,
+	keys jwk.Set,
+	opts ...VerifyOption,
+) error {
+	if err := newVerifyOptions(opts).checkTokenSize(data); err != nil {
+		return err
+	}
+
+	hdrs, err := protectedHeaders(data)
+	if err != nil {
+		return err
+	}
+
+	return o.verifyWithKeyFrom(data, keys, hdrs, opts)
+}
+
+func (o *AttestationResult) verifyWithKeyFrom(
+	data []byte,
+	keys jwk.Set,
+	hdrs jws.Headers,
+	opts []VerifyOption,
+) error {
+	key, err := lookupKey(keys, hdrs.KeyID())
+	if err != nil {
+		return err
+	}
+
+	alg, err := keyAlgorithm(key, hdrs.Algorithm())
+	if err != nil {
+		return err
+	}
+
+	return o.Verify(data, alg, key, opts...)
+}
+
+func protectedHeaders(data []byte) (jws.Headers, error) {
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JWS message: %w", err)
+	}
+
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, fmt.Errorf("expecting exactly one signature, found %d", len(sigs))
+	}
+
+	return sigs[0].ProtectedHeaders(), nil
+}
+
+// lookupKey returns the key in the set whose kid, or RFC7638 thumbprint,
+// matches the supplied kid
+func lookupKey(keys jwk.Set, kid string) (jwk.Key, error) {
+	if kid == "" {
+		return nil, errors.New("no kid in JWS header")
+	}
+
+	if keys == nil {
+		return nil, fmt.Errorf("no key found for kid %q", kid)
+	}
+
+	if key, ok := keys.LookupKeyID(kid); ok {
+		return key, nil
+	}
+
+	for i := 0; i < keys.Len(); i++ {
+		key, _ := keys.Key(i)
+
+		tp, err := keyThumbprint(key)
+		if err == nil && tp == kid {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no key found for kid %q", kid)
+}
+
+// keyAlgorithm checks that the algorithm in the JWS header is consistent with
+// the one the key is restricted to (if any)
+func keyAlgorithm(key jwk.Key, alg jwa.SignatureAlgorithm) (jwa.SignatureAlgorithm, error) {
+	if a := key.Algorithm(); a.String() != "" && a.String() != alg.String() {
+		return "", fmt.Errorf("JWS alg %q does not match key alg %q", alg, a)
+	}
+
+	return alg, nil
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
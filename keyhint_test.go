@@ -0,0 +1,166 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWKSURL = "https://verifier.example/.well-known/jwks.json"
+
+func testKeyPair(t *testing.T) (jwk.Key, jwk.Key) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	return sigK, vfyK
+}
+
+func TestVerifyWithTrustAnchors_thumbprint(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	kid, err := keyThumbprint(vfyK)
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID(kid))
+	require.NoError(t, err)
+
+	anchors := TrustAnchors{Keys: jwk.NewSet()}
+	require.NoError(t, anchors.Keys.AddKey(vfyK))
+
+	var actual AttestationResult
+
+	err = actual.VerifyWithTrustAnchors(token, anchors)
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual)
+}
+
+func TestVerifyWithTrustAnchors_jku(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	pub, err := vfyK.Clone()
+	require.NoError(t, err)
+	require.NoError(t, pub.Set(jwk.KeyIDKey, "key-1"))
+
+	jwks := jwk.NewSet()
+	require.NoError(t, jwks.AddKey(pub))
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK,
+		WithKeyID("key-1"), WithJWKSURL(testJWKSURL))
+	require.NoError(t, err)
+
+	var fetched []string
+
+	anchors := TrustAnchors{
+		JWKSURLs: []string{testJWKSURL},
+		Fetch: func(u string) (jwk.Set, error) {
+			fetched = append(fetched, u)
+			return jwks, nil
+		},
+	}
+
+	var actual AttestationResult
+
+	err = actual.VerifyWithTrustAnchors(token, anchors)
+	require.NoError(t, err)
+	assert.Equal(t, []string{testJWKSURL}, fetched)
+}
+
+func TestVerifyWithTrustAnchors_fail(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	restricted, err := vfyK.Clone()
+	require.NoError(t, err)
+	require.NoError(t, restricted.Set(jwk.KeyIDKey, "restricted"))
+	require.NoError(t, restricted.Set(jwk.AlgorithmKey, jwa.ES384))
+
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(restricted))
+
+	failingFetch := func(string) (jwk.Set, error) {
+		return nil, errors.New("boom")
+	}
+
+	tvs := []struct {
+		opts     []SignOption
+		anchors  TrustAnchors
+		expected string
+	}{
+		{
+			expected: "no kid in JWS header",
+		},
+		{
+			opts:     []SignOption{WithKeyID("unknown")},
+			anchors:  TrustAnchors{Keys: keys},
+			expected: `no key found for kid "unknown"`,
+		},
+		{
+			opts:     []SignOption{WithKeyID("key-1"), WithJWKSURL("https://attacker.example/jwks.json")},
+			anchors:  TrustAnchors{JWKSURLs: []string{testJWKSURL}},
+			expected: `jku "https://attacker.example/jwks.json" is not a trust anchor`,
+		},
+		{
+			opts:     []SignOption{WithKeyID("key-1"), WithJWKSURL(testJWKSURL)},
+			anchors:  TrustAnchors{JWKSURLs: []string{testJWKSURL}, Fetch: failingFetch},
+			expected: `fetching JWKS from "https://verifier.example/.well-known/jwks.json": boom`,
+		},
+		{
+			opts:     []SignOption{WithKeyID("restricted")},
+			anchors:  TrustAnchors{Keys: keys},
+			expected: `JWS alg "ES256" does not match key alg "ES384"`,
+		},
+	}
+
+	for i, tv := range tvs {
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, tv.opts...)
+		require.NoError(t, err)
+
+		var actual AttestationResult
+
+		err = actual.VerifyWithTrustAnchors(token, tv.anchors)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestVerifyWithTrustAnchors_bad_token(t *testing.T) {
+	var actual AttestationResult
+
+	err := actual.VerifyWithTrustAnchors([]byte("not a JWS"), TrustAnchors{})
+	assert.ErrorContains(t, err, "parsing JWS message")
+}
+
+func TestVerifyWithKeySet(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	current, err := vfyK.Clone()
+	require.NoError(t, err)
+	require.NoError(t, current.Set(jwk.KeyIDKey, "2023-10"))
+
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(current))
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID("2023-10"))
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.VerifyWithKeySet(token, keys)
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual)
+
+	// rotated out
+	token, err = testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID("2023-04"))
+	require.NoError(t, err)
+
+	err = actual.VerifyWithKeySet(token, keys)
+	assert.EqualError(t, err, `no key found for kid "2023-04"`)
+}
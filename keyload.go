@@ -0,0 +1,173 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// LoadKey parses a signing or verification key, auto-detecting its format.
+// The supported formats are:
+//
+//   - JWK (JSON)
+//   - PEM, containing a PKCS#8 or SEC1 ("EC PRIVATE KEY") private key, a PKCS#1
+//     RSA private or public key, a SubjectPublicKeyInfo ("PUBLIC KEY") public
+//     key, or an X.509 certificate (in which case its public key is returned)
+//   - DER, encoding any of the structures above
+//
+// Besides EC and RSA keys, PKCS#8 and SubjectPublicKeyInfo structures can
+// carry Ed25519 keys (for EdDSA) and RSASSA-PSS keys (for PS256, PS384 and
+// PS512), as generated by "openssl genpkey -algorithm RSA-PSS".  The
+// parameters of RSASSA-PSS keys are not enforced: the signature algorithm is
+// the one supplied to Sign or Verify.
+//
+// If a PEM file contains multiple blocks, the first one carrying a key or a
+// certificate is used.
+func LoadKey(data []byte) (jwk.Key, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	if len(trimmed) == 0 {
+		return nil, errors.New("no key data")
+	}
+
+	if trimmed[0] == '{' {
+		return jwk.ParseKey(trimmed)
+	}
+
+	if bytes.HasPrefix(trimmed, []byte("-----BEGIN ")) {
+		return loadPEMKey(trimmed)
+	}
+
+	raw, err := parseDERKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized key format (expecting JWK, PEM or DER): %w", err)
+	}
+
+	return jwk.FromRaw(raw)
+}
+
+func loadPEMKey(data []byte) (jwk.Key, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no key or certificate found in PEM data")
+		}
+
+		var (
+			raw interface{}
+			err error
+		)
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			raw, err = parsePKCS8PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			raw, err = x509.ParseECPrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			raw, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PUBLIC KEY":
+			raw, err = parsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			raw, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				raw = cert.PublicKey
+			}
+		default:
+			// skip anything else (e.g., "EC PARAMETERS")
+			data = rest
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("parsing PEM block %q: %w", block.Type, err)
+		}
+
+		return jwk.FromRaw(raw)
+	}
+}
+
+// parseDERKey tries each of the supported DER-encoded structures in turn
+func parseDERKey(der []byte) (interface{}, error) {
+	if k, err := parsePKCS8PrivateKey(der); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParseECPrivateKey(der); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return k, nil
+	}
+
+	if k, err := parsePKIXPublicKey(der); err == nil {
+		return k, nil
+	}
+
+	if k, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return k, nil
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.New("not a PKCS#8, SEC1, PKCS#1, SubjectPublicKeyInfo or X.509 certificate structure")
+	}
+
+	return cert.PublicKey, nil
+}
+
+// id-RSASSA-PSS (RFC4055), which the x509 package does not support as a key
+// algorithm
+var oidRSASSAPSS = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+
+// parsePKCS8PrivateKey is like x509.ParsePKCS8PrivateKey, but also accepts
+// RSASSA-PSS keys
+func parsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err == nil {
+		return key, nil
+	}
+
+	var p8 struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}
+
+	if _, e := asn1.Unmarshal(der, &p8); e != nil || !p8.Algo.Algorithm.Equal(oidRSASSAPSS) {
+		return nil, err
+	}
+
+	return x509.ParsePKCS1PrivateKey(p8.PrivateKey)
+}
+
+// parsePKIXPublicKey is like x509.ParsePKIXPublicKey, but also accepts
+// RSASSA-PSS keys
+func parsePKIXPublicKey(der []byte) (interface{}, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err == nil {
+		return key, nil
+	}
+
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	if _, e := asn1.Unmarshal(der, &spki); e != nil || !spki.Algorithm.Algorithm.Equal(oidRSASSAPSS) {
+		return nil, err
+	}
+
+	return x509.ParsePKCS1PublicKey(spki.PublicKey.RightAlign())
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKey_formats(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+	_, leaf := testCertChain(t)
+
+	var priv ecdsa.PrivateKey
+	require.NoError(t, sigK.Raw(&priv))
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(&priv)
+	require.NoError(t, err)
+
+	sec1, err := x509.MarshalECPrivateKey(&priv)
+	require.NoError(t, err)
+
+	spki, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(t, err)
+
+	toPEM := func(typ string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	}
+
+	tvs := []struct {
+		desc    string
+		data    []byte
+		private bool
+	}{
+		{"JWK private", []byte(testECDSAPrivateKey), true},
+		{"JWK public", []byte(testECDSAPublicKey), false},
+		{"PEM PKCS#8", toPEM("PRIVATE KEY", pkcs8), true},
+		{"PEM SEC1", toPEM("EC PRIVATE KEY", sec1), true},
+		{"PEM SEC1 with parameters", append(toPEM("EC PARAMETERS", []byte{0x06}), toPEM("EC PRIVATE KEY", sec1)...), true},
+		{"PEM SubjectPublicKeyInfo", toPEM("PUBLIC KEY", spki), false},
+		{"PEM certificate", toPEM("CERTIFICATE", leaf.Raw), false},
+		{"DER PKCS#8", pkcs8, true},
+		{"DER SEC1", sec1, true},
+		{"DER SubjectPublicKeyInfo", spki, false},
+		{"DER certificate", leaf.Raw, false},
+	}
+
+	expected, err := keyThumbprint(vfyK)
+	require.NoError(t, err)
+
+	for i, tv := range tvs {
+		key, err := LoadKey(tv.data)
+		require.NoError(t, err, "failed test vector at index %d (%s)", i, tv.desc)
+
+		pub := key
+		if tv.private {
+			_, ok := key.(jwk.ECDSAPrivateKey)
+			assert.True(t, ok, "failed test vector at index %d (%s)", i, tv.desc)
+			pub, err = key.PublicKey()
+			require.NoError(t, err)
+		}
+
+		actual, err := keyThumbprint(pub)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, "failed test vector at index %d (%s)", i, tv.desc)
+	}
+}
+
+func TestLoadKey_sign_verify(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+	_, leaf := testCertChain(t)
+
+	var priv ecdsa.PrivateKey
+	require.NoError(t, sigK.Raw(&priv))
+
+	sec1, err := x509.MarshalECPrivateKey(&priv)
+	require.NoError(t, err)
+
+	skey, err := LoadKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))
+	require.NoError(t, err)
+
+	pkey, err := LoadKey(leaf.Raw)
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, skey)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+	assert.NoError(t, ar.Verify(token, jwa.ES256, pkey))
+}
+
+func TestLoadKey_Ed25519_RSA(t *testing.T) {
+	edK := testParseKey(t, testEd25519PrivateKey)
+	rsaK := testParseKey(t, testRSAPrivateKey)
+
+	var (
+		ed      ed25519.PrivateKey
+		rsaPriv rsa.PrivateKey
+	)
+	require.NoError(t, edK.Raw(&ed))
+	require.NoError(t, rsaK.Raw(&rsaPriv))
+
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(ed)
+	require.NoError(t, err)
+
+	edSPKI, err := x509.MarshalPKIXPublicKey(ed.Public())
+	require.NoError(t, err)
+
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(&rsaPriv)
+	require.NoError(t, err)
+
+	// as "openssl genpkey -algorithm RSA-PSS" would produce (without the
+	// optional parameters)
+	pssAlg := pkix.AlgorithmIdentifier{Algorithm: oidRSASSAPSS}
+
+	pssPKCS8, err := asn1.Marshal(struct {
+		Version    int
+		Algo       pkix.AlgorithmIdentifier
+		PrivateKey []byte
+	}{0, pssAlg, x509.MarshalPKCS1PrivateKey(&rsaPriv)})
+	require.NoError(t, err)
+
+	pub := x509.MarshalPKCS1PublicKey(&rsaPriv.PublicKey)
+
+	pssSPKI, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{pssAlg, asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)}})
+	require.NoError(t, err)
+
+	toPEM := func(typ string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	}
+
+	tvs := []struct {
+		desc string
+		alg  jwa.SignatureAlgorithm
+		skey []byte
+		pkey []byte
+	}{
+		{"Ed25519 JWK", jwa.EdDSA, []byte(testEd25519PrivateKey), []byte(testEd25519PublicKey)},
+		{"Ed25519 PEM", jwa.EdDSA, toPEM("PRIVATE KEY", edPKCS8), toPEM("PUBLIC KEY", edSPKI)},
+		{"Ed25519 DER", jwa.EdDSA, edPKCS8, edSPKI},
+		{"RSA PEM", jwa.PS256, toPEM("PRIVATE KEY", rsaPKCS8), toPEM("RSA PUBLIC KEY", pub)},
+		{"RSA DER", jwa.RS256, x509.MarshalPKCS1PrivateKey(&rsaPriv), pub},
+		{"RSASSA-PSS PEM", jwa.PS384, toPEM("PRIVATE KEY", pssPKCS8), toPEM("PUBLIC KEY", pssSPKI)},
+		{"RSASSA-PSS DER", jwa.PS512, pssPKCS8, pssSPKI},
+	}
+
+	for i, tv := range tvs {
+		skey, err := LoadKey(tv.skey)
+		require.NoError(t, err, "failed test vector at index %d (%s)", i, tv.desc)
+
+		pkey, err := LoadKey(tv.pkey)
+		require.NoError(t, err, "failed test vector at index %d (%s)", i, tv.desc)
+
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(tv.alg, skey)
+		require.NoError(t, err, "failed test vector at index %d (%s)", i, tv.desc)
+
+		var ar AttestationResult
+		assert.NoError(t, ar.Verify(token, tv.alg, pkey), "failed test vector at index %d (%s)", i, tv.desc)
+	}
+}
+
+func TestLoadKey_fail(t *testing.T) {
+	tvs := []struct {
+		data     []byte
+		expected string
+	}{
+		{
+			[]byte("  \n"),
+			"no key data",
+		},
+		{
+			[]byte("-----BEGIN EC PARAMETERS-----\nBgg=\n-----END EC PARAMETERS-----\n"),
+			"no key or certificate found in PEM data",
+		},
+		{
+			[]byte("-----BEGIN PUBLIC KEY-----\n-----END PUBLIC KEY-----\n"),
+			`parsing PEM block "PUBLIC KEY": asn1: syntax error: sequence truncated`,
+		},
+		{
+			[]byte{0x30, 0x03, 0x02, 0x01, 0x01},
+			"unrecognized key format (expecting JWK, PEM or DER): not a PKCS#8, SEC1, PKCS#1, SubjectPublicKeyInfo or X.509 certificate structure",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := LoadKey(tv.data)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
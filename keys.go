@@ -0,0 +1,313 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ClaimKeys selects the string key space of the maps returned by AsMap and
+// accepted by FromMap.  The CBOR integer keys are handled by AsCBORMap and
+// FromCBORMap instead.
+type ClaimKeys int
+
+const (
+	// JSONClaimKeys are the claim names used in the JSON serialization,
+	// e.g., "ear.verifier-id"
+	JSONClaimKeys ClaimKeys = iota
+	// GoClaimKeys are the names of the corresponding Go struct fields,
+	// e.g., "VerifierID".  Claims without a corresponding field (e.g.,
+	// those of registered appraisal extensions) use their JSON names.
+	GoClaimKeys
+)
+
+func (o ClaimKeys) String() string {
+	switch o {
+	case JSONClaimKeys:
+		return "json"
+	case GoClaimKeys:
+		return "go"
+	default:
+		return fmt.Sprintf("ClaimKeys(%d)", int(o))
+	}
+}
+
+// MapOption is an option of AsMap
+type MapOption func(*mapOptions)
+
+type mapOptions struct {
+	keys ClaimKeys
+}
+
+// WithClaimKeys has AsMap key the claims-set, including the nested objects,
+// using the supplied key space instead of the JSON claim names.  With
+// GoClaimKeys, values take the form they have in the JSON serialization
+// (e.g., trust tiers as strings, byte strings base64url-encoded), so that the
+// map can be handed over to encoding/json, a logger or a policy engine as is.
+// AsMap panics if the key space is unknown.
+func WithClaimKeys(keys ClaimKeys) MapOption {
+	return func(o *mapOptions) {
+		o.keys = keys
+	}
+}
+
+// asGoKeyedMap renames the claims in m, the JSON claims-set of o, after the
+// Go struct fields
+func (o AttestationResult) asGoKeyedMap(m map[string]interface{}) (map[string]interface{}, error) {
+	j, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic map[string]interface{}
+	if err := json.Unmarshal(j, &generic); err != nil {
+		return nil, err
+	}
+
+	return toKeyedValue(generic, reflect.TypeOf(o)).(map[string]interface{}), nil
+}
+
+// AsCBORMap is like AsMap, but the claims-set, including the nested objects,
+// is keyed using the integer keys of the CBOR serialization (e.g., 1004 for
+// ear.verifier-id), and values are in the form they take in it (e.g., byte
+// strings as []byte).  Claims without a registered key use their JSON names.
+func (o AttestationResult) AsCBORMap() (map[interface{}]interface{}, error) {
+	return o.asCBORMap()
+}
+
+// FromMap is the counterpart of AsMap: it populates an AttestationResult from
+// a claims-set keyed using the supplied key space, and validates it.
+func FromMap(
+	m map[string]interface{},
+	keys ClaimKeys,
+	opts ...DecodeOption,
+) (*AttestationResult, error) {
+	var t reflect.Type
+
+	switch keys {
+	case JSONClaimKeys:
+	case GoClaimKeys:
+		t = reflect.TypeOf(AttestationResult{})
+	default:
+		return nil, fmt.Errorf("unknown claim key space %s", keys)
+	}
+
+	claims, err := fromKeyedMap(m, t)
+	if err != nil {
+		return nil, err
+	}
+
+	// the values may be typed, as in the maps returned by AsMap: bring them
+	// to their JSON form, which is what decodeMap expects
+	j, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	claims = nil
+	if err := json.Unmarshal(j, &claims); err != nil {
+		return nil, err
+	}
+
+	return newFromClaims(claims, opts)
+}
+
+// FromCBORMap is the counterpart of AsCBORMap: it populates an
+// AttestationResult from a claims-set keyed using the CBOR integer keys, and
+// validates it.
+func FromCBORMap(m map[interface{}]interface{}, opts ...DecodeOption) (*AttestationResult, error) {
+	claims, err := fromCBORClaimsSet(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFromClaims(claims, opts)
+}
+
+func newFromClaims(claims map[string]interface{}, opts []DecodeOption) (*AttestationResult, error) {
+	var ar AttestationResult
+
+	if err := ar.decodeMap(claims, newDecodeOptions(opts)); err != nil {
+		return nil, err
+	}
+
+	if err := ar.validate(); err != nil {
+		return nil, err
+	}
+
+	return &ar, nil
+}
+
+type goField struct {
+	name string
+	typ  reflect.Type
+}
+
+// goFields maps the JSON names of the fields of the struct type t (including
+// those of embedded structs) onto their Go names and types
+func goFields(t reflect.Type) map[string]goField {
+	ret := map[string]goField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, ok := f.Tag.Lookup("json")
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				for k, v := range goFields(f.Type) {
+					ret[k] = v
+				}
+			}
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		ret[name] = goField{name: f.Name, typ: f.Type}
+	}
+
+	return ret
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// toKeyedValue renames the members of the JSON objects in v after the fields of
+// t, the Go type v has been serialized from
+func toKeyedValue(v interface{}, t reflect.Type) interface{} {
+	t = derefType(t)
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		var fields map[string]goField
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = goFields(t)
+		}
+
+		ret := make(map[string]interface{}, len(val))
+
+		for k, e := range val {
+			var (
+				key      = k
+				elemType reflect.Type
+			)
+
+			if f, ok := fields[k]; ok {
+				key, elemType = f.name, f.typ
+			} else if t != nil && t.Kind() == reflect.Map {
+				elemType = t.Elem()
+			}
+
+			ret[key] = toKeyedValue(e, elemType)
+		}
+
+		return ret
+	case []interface{}:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+
+		ret := make([]interface{}, len(val))
+		for i, e := range val {
+			ret[i] = toKeyedValue(e, elemType)
+		}
+
+		return ret
+	default:
+		return v
+	}
+}
+
+// fromKeyedMap is the inverse of toKeyedValue for a claims-set
+func fromKeyedMap(m map[string]interface{}, t reflect.Type) (map[string]interface{}, error) {
+	v, err := fromKeyedValue(m, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(map[string]interface{}), nil
+}
+
+func fromKeyedValue(v interface{}, t reflect.Type) (interface{}, error) {
+	t = derefType(t)
+
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		var jsonNames map[string]goField
+		if t != nil && t.Kind() == reflect.Struct {
+			jsonNames = map[string]goField{}
+			for name, f := range goFields(t) {
+				jsonNames[f.name] = goField{name: name, typ: f.typ}
+			}
+		}
+
+		ret := make(map[string]interface{}, len(val))
+
+		for k, e := range val {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected key %v (%T)", k, k)
+			}
+
+			var elemType reflect.Type
+
+			if f, ok := jsonNames[key]; ok {
+				key, elemType = f.name, f.typ
+			} else if t != nil && t.Kind() == reflect.Map {
+				elemType = t.Elem()
+			}
+
+			if _, dup := ret[key]; dup {
+				return nil, fmt.Errorf("duplicate key %q", key)
+			}
+
+			conv, err := fromKeyedValue(e, elemType)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+
+			ret[key] = conv
+		}
+
+		return ret, nil
+	case map[string]interface{}:
+		generic := make(map[interface{}]interface{}, len(val))
+		for k, e := range val {
+			generic[k] = e
+		}
+		return fromKeyedValue(generic, t)
+	case []interface{}:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+
+		ret := make([]interface{}, len(val))
+		for i, e := range val {
+			conv, err := fromKeyedValue(e, elemType)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			ret[i] = conv
+		}
+
+		return ret, nil
+	case nil:
+		return nil, errors.New("null value")
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyedResult() AttestationResult {
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = map[string]*Appraisal{
+		"test": {
+			Status: &testStatus,
+			TrustVector: &TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+				Executables:      ApprovedRuntimeClaim,
+			},
+		},
+	}
+	return ar
+}
+
+func TestAsMap_json_claim_keys(t *testing.T) {
+	m := testKeyedResult().AsMap(WithClaimKeys(JSONClaimKeys))
+
+	assert.Equal(t, testKeyedResult().AsMap(), m)
+	assert.Equal(t, EatProfile, m["eat_profile"])
+}
+
+func TestAsCBORMap(t *testing.T) {
+	m, err := testKeyedResult().AsCBORMap()
+	require.NoError(t, err)
+
+	assert.Equal(t, EatProfile, m[int64(265)])
+	assert.Equal(t, testVidDeveloper, m[int64(1004)].(map[interface{}]interface{})[int64(0)])
+
+	test := m[int64(266)].(map[interface{}]interface{})["test"].(map[interface{}]interface{})
+	assert.Contains(t, test, int64(1000))
+	assert.Contains(t, test, int64(1001))
+}
+
+func TestAsMap_go_claim_keys(t *testing.T) {
+	m := testKeyedResult().AsMap(WithClaimKeys(GoClaimKeys))
+
+	assert.Equal(t, EatProfile, m["Profile"])
+	assert.Equal(t, float64(testIAT), m["IssuedAt"])
+	assert.Equal(t, testVidBuild, m["VerifierID"].(map[string]interface{})["Build"])
+	assert.NotContains(t, m, "eat_profile")
+
+	test := m["Submods"].(map[string]interface{})["test"].(map[string]interface{})
+	assert.Equal(t, "affirming", test["Status"])
+
+	tv := test["TrustVector"].(map[string]interface{})
+	assert.Equal(t, float64(2), tv["Executables"])
+	assert.Equal(t, float64(2), tv["InstanceIdentity"])
+
+	// string-keyed all the way down, so that it can be serialized as is
+	j, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"VerifierID":{"Build":`)
+}
+
+func TestAsMap_unknown_claim_keys(t *testing.T) {
+	assert.PanicsWithValue(t, "unknown claim key space ClaimKeys(42)", func() {
+		testKeyedResult().AsMap(WithClaimKeys(ClaimKeys(42)))
+	})
+}
+
+func TestFromMap_roundtrip(t *testing.T) {
+	expected := testKeyedResult()
+
+	for _, keys := range []ClaimKeys{JSONClaimKeys, GoClaimKeys} {
+		actual, err := FromMap(expected.AsMap(WithClaimKeys(keys)), keys)
+		require.NoError(t, err, keys)
+
+		assert.Equal(t, expected.AsMap(), actual.AsMap(), keys)
+	}
+
+	m, err := expected.AsCBORMap()
+	require.NoError(t, err)
+
+	actual, err := FromCBORMap(m)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected.AsMap(), actual.AsMap())
+}
+
+func TestFromMap_fail(t *testing.T) {
+	tvs := []struct {
+		m        map[string]interface{}
+		keys     ClaimKeys
+		expected string
+	}{
+		{
+			m: map[string]interface{}{
+				"Profile":    EatProfile,
+				"VerifierID": map[interface{}]interface{}{1: "one"},
+			},
+			keys:     GoClaimKeys,
+			expected: "ear.verifier-id: unexpected key 1 (int)",
+		},
+		{
+			m:        map[string]interface{}{"Profile": EatProfile, "eat_profile": EatProfile},
+			keys:     GoClaimKeys,
+			expected: `duplicate key "eat_profile"`,
+		},
+		{
+			m: map[string]interface{}{
+				"eat_profile": EatProfile,
+				"iat":         float64(testIAT),
+			},
+			keys:     JSONClaimKeys,
+			expected: "missing mandatory 'ear.verifier-id', 'submods'",
+		},
+		{
+			m:        map[string]interface{}{},
+			keys:     ClaimKeys(-1),
+			expected: "unknown claim key space ClaimKeys(-1)",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := FromMap(tv.m, tv.keys)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KMSClient is a remote signing service, such as a cloud KMS, holding keys
+// that cannot be exported.  Implementations must be safe for concurrent use.
+type KMSClient interface {
+	// PublicKey returns the public key of the key identified by keyID
+	PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error)
+	// Sign signs digest, computed using opts.HashFunc(), with the key
+	// identified by keyID.  The signature must be in the format used by
+	// crypto.Signer (e.g., ASN.1 DER for ECDSA).  For Ed25519 keys, digest is
+	// the message itself.
+	Sign(ctx context.Context, keyID string, digest []byte, opts crypto.SignerOpts) ([]byte, error)
+}
+
+// KMSSigner is a crypto.Signer for a key held by a KMSClient.  It can be
+// passed to Sign and NewCachedSigner in place of a private key.
+type KMSSigner struct {
+	client KMSClient
+	keyID  string
+	pub    crypto.PublicKey
+}
+
+var _ crypto.Signer = (*KMSSigner)(nil)
+
+// NewKMSSigner returns a KMSSigner for the key identified by keyID.  The
+// public key is fetched upfront, so that the key is known to exist and its
+// type can be checked against the signing algorithm before anything is sent
+// to the KMS for signing.
+func NewKMSSigner(ctx context.Context, client KMSClient, keyID string) (*KMSSigner, error) {
+	if client == nil {
+		return nil, fmt.Errorf("key %q: nil KMS client", keyID)
+	}
+
+	pub, err := client.PublicKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching public key of %q: %w", keyID, err)
+	}
+
+	if pub == nil {
+		return nil, fmt.Errorf("fetching public key of %q: no key returned", keyID)
+	}
+
+	return &KMSSigner{client: client, keyID: keyID, pub: pub}, nil
+}
+
+// KeyID returns the identifier of the remote key
+func (o *KMSSigner) KeyID() string {
+	return o.keyID
+}
+
+// Public returns the public key fetched by NewKMSSigner
+func (o *KMSSigner) Public() crypto.PublicKey {
+	return o.pub
+}
+
+// Sign asks the KMS to sign digest.  The random source is not used, since
+// randomness (if any) is supplied by the KMS.
+func (o *KMSSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	sig, err := o.client.Sign(context.Background(), o.keyID, digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("signing with %q: %w", o.keyID, err)
+	}
+
+	return sig, nil
+}
+
+var (
+	kmsBackendsMu sync.RWMutex
+	kmsBackends   = map[string]KMSClient{}
+)
+
+// RegisterKMSBackend makes the client available, under name, to the "kms" key
+// URIs resolved by OpenSigner, which take the form
+//
+//	kms://<name>/<key-id>
+//
+// where the format of the key ID is up to the backend (e.g., the resource
+// name of a GCP KMS key version, see the kms/gcp package).  Clients for
+// other services, such as AWS KMS or Azure Key Vault, can be supplied by the
+// application.
+func RegisterKMSBackend(name string, client KMSClient) error {
+	if name == "" || strings.ContainsAny(name, "/:") {
+		return fmt.Errorf("invalid KMS backend name %q", name)
+	}
+
+	if client == nil {
+		return fmt.Errorf("KMS backend %q: nil client", name)
+	}
+
+	kmsBackendsMu.Lock()
+	defer kmsBackendsMu.Unlock()
+
+	if _, ok := kmsBackends[name]; ok {
+		return fmt.Errorf("KMS backend %q: already registered", name)
+	}
+
+	kmsBackends[name] = client
+
+	return nil
+}
+
+// KMSBackends returns the names of the backends registered using
+// RegisterKMSBackend, in lexicographic order
+func KMSBackends() []string {
+	kmsBackendsMu.RLock()
+	defer kmsBackendsMu.RUnlock()
+
+	ret := make([]string, 0, len(kmsBackends))
+	for name := range kmsBackends {
+		ret = append(ret, name)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// openKMSSigner is the SignerOpener for "kms" URIs
+func openKMSSigner(uri string) (crypto.Signer, error) {
+	_, rest, _ := cutScheme(uri)
+
+	if !strings.HasPrefix(rest, "//") {
+		return nil, fmt.Errorf("expecting kms://<backend>/<key-id>, got %q", uri)
+	}
+
+	name, keyID, found := strings.Cut(rest[2:], "/")
+	if !found || name == "" || keyID == "" {
+		return nil, fmt.Errorf("expecting kms://<backend>/<key-id>, got %q", uri)
+	}
+
+	kmsBackendsMu.RLock()
+	client := kmsBackends[name]
+	kmsBackendsMu.RUnlock()
+
+	if client == nil {
+		return nil, fmt.Errorf("unknown KMS backend %q (registered: %s)",
+			name, strings.Join(KMSBackends(), ", "))
+	}
+
+	return NewKMSSigner(context.Background(), client, keyID)
+}
+
+func init() {
+	signerOpeners["kms"] = openKMSSigner
+}
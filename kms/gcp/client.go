@@ -0,0 +1,321 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/veraison/ear"
+)
+
+const (
+	// DefaultEndpoint is the default endpoint of the Cloud KMS REST API
+	DefaultEndpoint = "https://cloudkms.googleapis.com/v1/"
+
+	tokenEnv         = "GOOGLE_OAUTH_ACCESS_TOKEN"
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// KMSClient is an ear.KMSClient for Google Cloud KMS, using its REST API.  Keys
+// are identified by the resource name of a key version, e.g.:
+//
+//	projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1
+//
+// EC (P-256, P-384), Ed25519 and RSA (PKCS #1 v1.5 and PSS) signing keys are
+// supported.  Since the padding and digest of a Cloud KMS key are fixed when
+// the key is created, requests that do not match them (e.g., PS256 with a
+// PKCS #1 v1.5 key) are rejected before anything is signed.
+type KMSClient struct {
+	// Endpoint is the base URL of the API (DefaultEndpoint if empty)
+	Endpoint string
+	// Client is used to send requests (http.DefaultClient if nil)
+	Client *http.Client
+	// Token returns the OAuth 2.0 access token sent with each request.  If
+	// nil, the token is taken from the GOOGLE_OAUTH_ACCESS_TOKEN environment
+	// variable or, failing that, from the metadata server of the GCE (or GKE,
+	// Cloud Run, ...) instance.
+	Token func(ctx context.Context) (string, error)
+
+	// algorithms caches the algorithm of each key version, as reported along
+	// with its public key
+	algorithms sync.Map
+}
+
+var _ ear.KMSClient = (*KMSClient)(nil)
+
+type publicKeyResponse struct {
+	PEM       string `json:"pem"`
+	Algorithm string `json:"algorithm"`
+}
+
+type digestValue struct {
+	SHA256 []byte `json:"sha256,omitempty"`
+	SHA384 []byte `json:"sha384,omitempty"`
+	SHA512 []byte `json:"sha512,omitempty"`
+}
+
+type signRequest struct {
+	Digest       *digestValue `json:"digest,omitempty"`
+	DigestCRC32C string       `json:"digestCrc32c,omitempty"`
+	Data         []byte       `json:"data,omitempty"`
+	DataCRC32C   string       `json:"dataCrc32c,omitempty"`
+}
+
+type signResponse struct {
+	Signature            []byte `json:"signature"`
+	SignatureCRC32C      string `json:"signatureCrc32c"`
+	VerifiedDigestCRC32C bool   `json:"verifiedDigestCrc32c"`
+	VerifiedDataCRC32C   bool   `json:"verifiedDataCrc32c"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// PublicKey fetches the public key of the key version keyID
+func (o *KMSClient) PublicKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	var res publicKeyResponse
+
+	if err := o.call(ctx, http.MethodGet, keyID+"/publicKey", nil, &res); err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(res.PEM))
+	if block == nil {
+		return nil, errors.New("no PEM data in public key response")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+
+	o.algorithms.Store(keyID, res.Algorithm)
+
+	return pub, nil
+}
+
+// Sign has the key version keyID sign digest (or, for Ed25519 keys, the
+// message)
+func (o *KMSClient) Sign(
+	ctx context.Context,
+	keyID string,
+	digest []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	alg, ok := o.algorithms.Load(keyID)
+	if !ok {
+		if _, err := o.PublicKey(ctx, keyID); err != nil {
+			return nil, err
+		}
+		alg, _ = o.algorithms.Load(keyID)
+	}
+
+	if err := checkAlgorithm(alg.(string), opts); err != nil {
+		return nil, err
+	}
+
+	crc := crc32c(digest)
+
+	var req signRequest
+
+	switch opts.HashFunc() {
+	case 0:
+		req.Data, req.DataCRC32C = digest, crc
+	case crypto.SHA256:
+		req.Digest, req.DigestCRC32C = &digestValue{SHA256: digest}, crc
+	case crypto.SHA384:
+		req.Digest, req.DigestCRC32C = &digestValue{SHA384: digest}, crc
+	case crypto.SHA512:
+		req.Digest, req.DigestCRC32C = &digestValue{SHA512: digest}, crc
+	default:
+		return nil, fmt.Errorf("unsupported digest %s", opts.HashFunc())
+	}
+
+	var res signResponse
+
+	if err := o.call(ctx, http.MethodPost, keyID+":asymmetricSign", req, &res); err != nil {
+		return nil, err
+	}
+
+	if !res.VerifiedDigestCRC32C && !res.VerifiedDataCRC32C {
+		return nil, errors.New("request corrupted in transit: checksum not verified by KMS")
+	}
+
+	if res.SignatureCRC32C != crc32c(res.Signature) {
+		return nil, errors.New("response corrupted in transit: signature checksum mismatch")
+	}
+
+	return res.Signature, nil
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// crc32c returns the CRC32C checksum of data in the format used by the API
+// for integrity checks
+func crc32c(data []byte) string {
+	return strconv.FormatUint(uint64(crc32.Checksum(data, castagnoli)), 10)
+}
+
+// checkAlgorithm makes sure that a key version with algorithm alg (e.g.,
+// "RSA_SIGN_PSS_2048_SHA256") produces the kind of signature requested by
+// opts
+func checkAlgorithm(alg string, opts crypto.SignerOpts) error {
+	h := opts.HashFunc()
+	hashSuffix := "_" + strings.ReplaceAll(h.String(), "-", "")
+	_, pss := opts.(*rsa.PSSOptions)
+
+	var ok bool
+
+	switch {
+	case alg == "EC_SIGN_ED25519":
+		ok = h == 0
+	case strings.HasPrefix(alg, "EC_SIGN_"):
+		ok = h != 0 && strings.HasSuffix(alg, hashSuffix)
+	case strings.HasPrefix(alg, "RSA_SIGN_PSS_"):
+		ok = pss && strings.HasSuffix(alg, hashSuffix)
+	case strings.HasPrefix(alg, "RSA_SIGN_PKCS1_"):
+		ok = !pss && strings.HasSuffix(alg, hashSuffix)
+	default:
+		return fmt.Errorf("unsupported key algorithm %q", alg)
+	}
+
+	if ok {
+		return nil
+	}
+
+	want := "a pure (Ed25519) signature"
+	switch {
+	case pss:
+		want = "an RSA-PSS signature over a " + h.String() + " digest"
+	case h != 0:
+		want = "a signature over a " + h.String() + " digest"
+	}
+
+	return fmt.Errorf("key algorithm %s cannot produce %s", alg, want)
+}
+
+func (o *KMSClient) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	endpoint := o.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(endpoint, "/")+"/"+path, body)
+	if err != nil {
+		return err
+	}
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	tokenFn := o.Token
+	if tokenFn == nil {
+		tokenFn = o.defaultToken
+	}
+
+	token, err := tokenFn(ctx)
+	if err != nil {
+		return fmt.Errorf("obtaining access token: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := o.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("reading KMS response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+			return fmt.Errorf("KMS responded with %s: %s", res.Status, e.Error.Message)
+		}
+		return fmt.Errorf("KMS responded with %s", res.Status)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding KMS response: %w", err)
+	}
+
+	return nil
+}
+
+func (o *KMSClient) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return http.DefaultClient
+}
+
+func (o *KMSClient) defaultToken(ctx context.Context) (string, error) {
+	if token := os.Getenv(tokenEnv); token != "" {
+		return token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	res, err := o.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%s is not set, and the metadata server is not reachable: %w", tokenEnv, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server responded with %s", res.Status)
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&tok); err != nil {
+		return "", fmt.Errorf("decoding metadata server response: %w", err)
+	}
+
+	if tok.AccessToken == "" {
+		return "", errors.New("no access token in metadata server response")
+	}
+
+	return tok.AccessToken, nil
+}
@@ -0,0 +1,280 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+const testKeyPrefix = "projects/p/locations/global/keyRings/ear/cryptoKeys/"
+
+type testKey struct {
+	algorithm string
+	key       crypto.Signer
+}
+
+// testKMSServer emulates the asymmetric signing subset of the Cloud KMS
+// REST API.  If corrupt is set, the returned signatures fail the integrity
+// check.
+func testKMSServer(t *testing.T, corrupt bool) *httptest.Server {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keys := map[string]testKey{
+		"ec/cryptoKeyVersions/1":      {"EC_SIGN_P256_SHA256", ecKey},
+		"ed25519/cryptoKeyVersions/1": {"EC_SIGN_ED25519", edKey},
+		"pss/cryptoKeyVersions/1":     {"RSA_SIGN_PSS_2048_SHA256", rsaKey},
+		"pkcs1/cryptoKeyVersions/1":   {"RSA_SIGN_PKCS1_2048_SHA256", rsaKey},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"code": 401, "message": "Request had invalid authentication credentials.", "status": "UNAUTHENTICATED"}}`))
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/v1/"+testKeyPrefix)
+		name, method := path, ""
+		if i := strings.LastIndexAny(path, "/:"); i >= 0 {
+			name, method = path[:i], path[i:]
+		}
+
+		k, ok := keys[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "CryptoKeyVersion not found.", "status": "NOT_FOUND"}}`))
+			return
+		}
+
+		var res interface{}
+
+		switch {
+		case method == "/publicKey" && r.Method == http.MethodGet:
+			der, err := x509.MarshalPKIXPublicKey(k.key.Public())
+			require.NoError(t, err)
+
+			res = publicKeyResponse{
+				PEM:       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+				Algorithm: k.algorithm,
+			}
+		case method == ":asymmetricSign" && r.Method == http.MethodPost:
+			var req signRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			var (
+				msg  []byte
+				opts crypto.SignerOpts = crypto.Hash(0)
+			)
+
+			switch {
+			case req.Data != nil:
+				msg = req.Data
+				assert.Equal(t, crc32c(msg), req.DataCRC32C)
+			case req.Digest.SHA256 != nil:
+				msg, opts = req.Digest.SHA256, crypto.SHA256
+				assert.Equal(t, crc32c(msg), req.DigestCRC32C)
+			}
+
+			if strings.HasPrefix(k.algorithm, "RSA_SIGN_PSS_") {
+				opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+			}
+
+			sig, err := k.key.Sign(rand.Reader, msg, opts)
+			require.NoError(t, err)
+
+			crc := crc32c(sig)
+			if corrupt {
+				sig[0] ^= 0xff
+			}
+
+			res = signResponse{
+				Signature:            sig,
+				SignatureCRC32C:      crc,
+				VerifiedDigestCRC32C: req.Digest != nil,
+				VerifiedDataCRC32C:   req.Data != nil,
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+}
+
+func testKMSClient(srv *httptest.Server) *KMSClient {
+	return &KMSClient{
+		Endpoint: srv.URL + "/v1/",
+		Client:   srv.Client(),
+		Token: func(context.Context) (string, error) {
+			return "test-token", nil
+		},
+	}
+}
+
+func testAttestationResult() *ear.AttestationResult {
+	return ear.NewAttestationResult("test", "test-build", "test-developer")
+}
+
+func TestKMSClient_sign(t *testing.T) {
+	srv := testKMSServer(t, false)
+	defer srv.Close()
+
+	client := testKMSClient(srv)
+
+	tvs := []struct {
+		key string
+		alg jwa.SignatureAlgorithm
+	}{
+		{"ec", jwa.ES256},
+		{"ed25519", jwa.EdDSA},
+		{"pss", jwa.PS256},
+		{"pkcs1", jwa.RS256},
+	}
+
+	for i, tv := range tvs {
+		signer, err := ear.NewKMSSigner(context.Background(), client, testKeyPrefix+tv.key+"/cryptoKeyVersions/1")
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		token, err := testAttestationResult().Sign(tv.alg, signer)
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		var ar ear.AttestationResult
+		assert.NoError(t, ar.Verify(token, tv.alg, signer.Public()), "failed test vector at index %d", i)
+	}
+}
+
+func TestKMSClient_kms_URI(t *testing.T) {
+	srv := testKMSServer(t, false)
+	defer srv.Close()
+
+	// backends cannot be unregistered, so use a name of our own
+	require.NoError(t, ear.RegisterKMSBackend("gcp-test", testKMSClient(srv)))
+
+	signer, err := ear.OpenSigner("kms://gcp-test/" + testKeyPrefix + "ec/cryptoKeyVersions/1")
+	require.NoError(t, err)
+
+	cs, err := ear.NewCachedSigner(jwa.ES256, signer)
+	require.NoError(t, err)
+
+	token, err := cs.Sign(*testAttestationResult())
+	require.NoError(t, err)
+
+	var ar ear.AttestationResult
+	assert.NoError(t, ar.Verify(token, jwa.ES256, signer.Public()))
+}
+
+func TestKMSClient_fail(t *testing.T) {
+	srv := testKMSServer(t, false)
+	defer srv.Close()
+
+	client := testKMSClient(srv)
+
+	_, err := client.PublicKey(context.Background(), testKeyPrefix+"missing/cryptoKeyVersions/1")
+	assert.EqualError(t, err, "KMS responded with 404 Not Found: CryptoKeyVersion not found.")
+
+	client.Token = func(context.Context) (string, error) { return "expired", nil }
+
+	_, err = client.PublicKey(context.Background(), testKeyPrefix+"ec/cryptoKeyVersions/1")
+	assert.EqualError(t, err, "KMS responded with 401 Unauthorized: Request had invalid authentication credentials.")
+
+	client = testKMSClient(srv)
+
+	// the padding of the key does not match the JWS algorithm
+	signer, err := ear.NewKMSSigner(context.Background(), client, testKeyPrefix+"pkcs1/cryptoKeyVersions/1")
+	require.NoError(t, err)
+
+	_, err = testAttestationResult().Sign(jwa.PS256, signer)
+	assert.ErrorContains(t, err,
+		"key algorithm RSA_SIGN_PKCS1_2048_SHA256 cannot produce an RSA-PSS signature over a SHA-256 digest")
+
+	// the digest of the key does not match the JWS algorithm
+	signer, err = ear.NewKMSSigner(context.Background(), client, testKeyPrefix+"pss/cryptoKeyVersions/1")
+	require.NoError(t, err)
+
+	_, err = testAttestationResult().Sign(jwa.PS512, signer)
+	assert.ErrorContains(t, err,
+		"key algorithm RSA_SIGN_PSS_2048_SHA256 cannot produce an RSA-PSS signature over a SHA-512 digest")
+}
+
+func TestKMSClient_corrupted_response(t *testing.T) {
+	srv := testKMSServer(t, true)
+	defer srv.Close()
+
+	// the algorithm is looked up on first use, if not known yet
+	_, err := testKMSClient(srv).Sign(
+		context.Background(),
+		testKeyPrefix+"ec/cryptoKeyVersions/1",
+		make([]byte, 32),
+		crypto.SHA256,
+	)
+	assert.EqualError(t, err, "response corrupted in transit: signature checksum mismatch")
+}
+
+func TestKMSClient_token_from_env(t *testing.T) {
+	srv := testKMSServer(t, false)
+	defer srv.Close()
+
+	t.Setenv(tokenEnv, "test-token")
+
+	client := &KMSClient{Endpoint: srv.URL + "/v1", Client: srv.Client()}
+
+	_, err := client.PublicKey(context.Background(), testKeyPrefix+"ed25519/cryptoKeyVersions/1")
+	assert.NoError(t, err)
+}
+
+func TestCheckAlgorithm(t *testing.T) {
+	pss256 := &rsa.PSSOptions{Hash: crypto.SHA256}
+
+	tvs := []struct {
+		alg      string
+		opts     crypto.SignerOpts
+		expected string
+	}{
+		{"EC_SIGN_P384_SHA384", crypto.SHA384, ""},
+		{"EC_SIGN_P256_SHA256", crypto.SHA384, "key algorithm EC_SIGN_P256_SHA256 cannot produce a signature over a SHA-384 digest"},
+		{"EC_SIGN_ED25519", crypto.Hash(0), ""},
+		{"EC_SIGN_ED25519", crypto.SHA256, "key algorithm EC_SIGN_ED25519 cannot produce a signature over a SHA-256 digest"},
+		{"EC_SIGN_P256_SHA256", crypto.Hash(0), "key algorithm EC_SIGN_P256_SHA256 cannot produce a pure (Ed25519) signature"},
+		{"RSA_SIGN_PSS_3072_SHA256", pss256, ""},
+		{"RSA_SIGN_PKCS1_4096_SHA512", crypto.SHA512, ""},
+		{"RSA_SIGN_PKCS1_4096_SHA512", pss256, "key algorithm RSA_SIGN_PKCS1_4096_SHA512 cannot produce an RSA-PSS signature over a SHA-256 digest"},
+		{"RSA_SIGN_RAW_PKCS1_2048", crypto.SHA256, `unsupported key algorithm "RSA_SIGN_RAW_PKCS1_2048"`},
+		{"HMAC_SHA256", crypto.SHA256, `unsupported key algorithm "HMAC_SHA256"`},
+	}
+
+	for i, tv := range tvs {
+		err := checkAlgorithm(tv.alg, tv.opts)
+		if tv.expected == "" {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+		} else {
+			assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		}
+	}
+}
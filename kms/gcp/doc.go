@@ -0,0 +1,18 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package gcp provides an ear.KMSClient for keys held in Google Cloud KMS, so
+that EARs can be signed without the private key leaving the KMS.
+
+The client can be used directly:
+
+	signer, err := ear.NewKMSSigner(ctx, &gcp.KMSClient{}, keyVersion)
+
+or registered as a backend for the "kms" key URIs resolved by ear.OpenSigner:
+
+	err := ear.RegisterKMSBackend("gcp", &gcp.KMSClient{})
+	...
+	signer, err := ear.OpenSigner("kms://gcp/" + keyVersion)
+*/
+package gcp
@@ -0,0 +1,157 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKMS is a KMSClient holding local keys
+type testKMS struct {
+	keys  map[string]crypto.Signer
+	signs int
+}
+
+func newTestKMS(t *testing.T) *testKMS {
+	return &testKMS{
+		keys: map[string]crypto.Signer{
+			"ec":      newTestOpaqueSigner(t, testECDSAPrivateKey).key,
+			"ed25519": newTestOpaqueSigner(t, testEd25519PrivateKey).key,
+			"rsa":     newTestOpaqueSigner(t, testRSAPrivateKey).key,
+		},
+	}
+}
+
+func (o *testKMS) PublicKey(_ context.Context, keyID string) (crypto.PublicKey, error) {
+	k, ok := o.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", keyID)
+	}
+	return k.Public(), nil
+}
+
+func (o *testKMS) Sign(_ context.Context, keyID string, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	k, ok := o.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", keyID)
+	}
+	o.signs++
+	return k.Sign(rand.Reader, digest, opts)
+}
+
+func TestKMSSigner_Sign(t *testing.T) {
+	kms := newTestKMS(t)
+
+	tvs := []struct {
+		keyID string
+		alg   jwa.SignatureAlgorithm
+	}{
+		{"ec", jwa.ES256},
+		{"ed25519", jwa.EdDSA},
+		{"rsa", jwa.PS384},
+		{"rsa", jwa.RS256},
+	}
+
+	for i, tv := range tvs {
+		signer, err := NewKMSSigner(context.Background(), kms, tv.keyID)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.keyID, signer.KeyID())
+
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(tv.alg, signer)
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		var ar AttestationResult
+		assert.NoError(t, ar.Verify(token, tv.alg, signer.Public()), "failed test vector at index %d", i)
+	}
+
+	assert.Equal(t, len(tvs), kms.signs)
+}
+
+func TestKMSSigner_fail(t *testing.T) {
+	kms := newTestKMS(t)
+
+	_, err := NewKMSSigner(context.Background(), kms, "missing")
+	assert.EqualError(t, err, `fetching public key of "missing": key "missing" not found`)
+
+	_, err = NewKMSSigner(context.Background(), nil, "ec")
+	assert.EqualError(t, err, `key "ec": nil KMS client`)
+
+	signer, err := NewKMSSigner(context.Background(), kms, "ec")
+	require.NoError(t, err)
+
+	// the key type is checked locally, before the KMS is asked to sign
+	_, err = testAttestationResultsWithVeraisonExtns.Sign(jwa.PS256, signer)
+	assert.EqualError(t, err, "signing key: PS256 requires an RSA key, but an EC key was supplied")
+	assert.Zero(t, kms.signs)
+
+	delete(kms.keys, "ec")
+
+	_, err = testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, signer)
+	assert.ErrorContains(t, err, `signing with "ec": key "ec" not found`)
+}
+
+func TestOpenSigner_kms(t *testing.T) {
+	kms := newTestKMS(t)
+
+	require.NoError(t, RegisterKMSBackend("test", kms))
+	defer delete(kmsBackends, "test")
+
+	assert.Contains(t, SignerSchemes(), "kms")
+	assert.Contains(t, KMSBackends(), "test")
+
+	signer, err := OpenSigner("kms://test/ed25519")
+	require.NoError(t, err)
+	assert.Equal(t, "ed25519", signer.(*KMSSigner).KeyID())
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.EdDSA, signer)
+	require.NoError(t, err)
+	assert.Equal(t, testEdDSAToken, string(token))
+
+	tvs := []struct {
+		uri      string
+		expected string
+	}{
+		{"kms:test/ec", `opening kms key: expecting kms://<backend>/<key-id>, got "kms:test/ec"`},
+		{"kms://test", `opening kms key: expecting kms://<backend>/<key-id>, got "kms://test"`},
+		{"kms://test/", `opening kms key: expecting kms://<backend>/<key-id>, got "kms://test/"`},
+		{"kms://aws/alias/ear", `opening kms key: unknown KMS backend "aws" (registered: test)`},
+		{"kms://test/missing", `opening kms key: fetching public key of "missing": key "missing" not found`},
+	}
+
+	for i, tv := range tvs {
+		_, err := OpenSigner(tv.uri)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestRegisterKMSBackend_fail(t *testing.T) {
+	kms := newTestKMS(t)
+
+	require.NoError(t, RegisterKMSBackend("test", kms))
+	defer delete(kmsBackends, "test")
+
+	tvs := []struct {
+		name     string
+		client   KMSClient
+		expected string
+	}{
+		{"", kms, `invalid KMS backend name ""`},
+		{"a/b", kms, `invalid KMS backend name "a/b"`},
+		{"aws", nil, `KMS backend "aws": nil client`},
+		{"test", kms, `KMS backend "test": already registered`},
+	}
+
+	for i, tv := range tvs {
+		err := RegisterKMSBackend(tv.name, tv.client)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
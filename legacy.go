@@ -0,0 +1,80 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LegacyVeraisonProfile is the eat_profile used by Veraison attestation results
+// that predate submods, in which a single appraisal is carried at the top
+// level of the claims-set.
+const LegacyVeraisonProfile = "tag:github.com,2022:veraison/ear"
+
+// legacyAppraisalClaims are the top-level claims of the legacy format that
+// belong to the appraisal
+var legacyAppraisalClaims = []string{
+	"ear.status",
+	"ear.trustworthiness-vector",
+	"ear.appraisal-policy-id",
+	"ear.veraison.annotated-evidence",
+	"ear.veraison.policy-claims",
+	"ear.veraison.key-attestation",
+}
+
+// FromLegacyVeraison converts a legacy (pre-submods) Veraison attestation
+// result in JSON format into an AttestationResult, in which the appraisal is
+// placed under the supplied submod name.  A legacy RFC3339 "timestamp" claim
+// is used as iat if the latter is not present.  The result is validated.
+func FromLegacyVeraison(data []byte, submod string) (*AttestationResult, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	if p, ok := m["eat_profile"]; ok && p != LegacyVeraisonProfile {
+		return nil, fmt.Errorf("unexpected eat_profile %v (expecting %q)", p, LegacyVeraisonProfile)
+	}
+	m["eat_profile"] = EatProfile
+
+	if ts, ok := m["timestamp"]; ok {
+		if _, ok := m["iat"]; !ok {
+			s, ok := ts.(string)
+			if !ok {
+				return nil, fmt.Errorf(`invalid value for "timestamp": expecting string, found %T`, ts)
+			}
+
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "timestamp": %w`, err)
+			}
+
+			m["iat"] = t.Unix()
+		}
+		delete(m, "timestamp")
+	}
+
+	appraisal := map[string]interface{}{}
+	for _, claim := range legacyAppraisalClaims {
+		if v, ok := m[claim]; ok {
+			appraisal[claim] = v
+			delete(m, claim)
+		}
+	}
+	m["submods"] = map[string]interface{}{submod: appraisal}
+
+	var ar AttestationResult
+
+	if err := ar.decodeMap(m, newDecodeOptions(nil)); err != nil {
+		return nil, err
+	}
+
+	if err := ar.validate(); err != nil {
+		return nil, err
+	}
+
+	return &ar, nil
+}
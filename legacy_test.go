@@ -0,0 +1,75 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromLegacyVeraison_ok(t *testing.T) {
+	tvs := []string{
+		`{
+			"eat_profile": "tag:github.com,2022:veraison/ear",
+			"iat": 1666091373,
+			"ear.status": "affirming",
+			"ear.trustworthiness-vector": {"executables": 2},
+			"ear.appraisal-policy-id": "policy://test/01234",
+			"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."}
+		}`,
+		`{
+			"timestamp": "2022-10-18T11:09:33Z",
+			"ear.status": "affirming",
+			"ear.trustworthiness-vector": {"executables": 2},
+			"ear.appraisal-policy-id": "policy://test/01234",
+			"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."}
+		}`,
+	}
+
+	for i, tv := range tvs {
+		ar, err := FromLegacyVeraison([]byte(tv), "legacy")
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		assert.Equal(t, EatProfile, ar.Profile.String())
+		assert.Equal(t, testIAT, *ar.IssuedAt)
+		assert.Equal(t, testVerifierID, *ar.VerifierID)
+
+		require.Contains(t, ar.Submods, "legacy")
+		appraisal := ar.Submods["legacy"]
+		assert.Equal(t, TrustTierAffirming, *appraisal.Status)
+		assert.Equal(t, ApprovedRuntimeClaim, appraisal.TrustVector.Executables)
+		assert.Equal(t, testPolicyID, *appraisal.AppraisalPolicyID)
+	}
+}
+
+func TestFromLegacyVeraison_fail(t *testing.T) {
+	tvs := []struct {
+		data     string
+		expected string
+	}{
+		{
+			data:     `[]`,
+			expected: "json: cannot unmarshal array into Go value of type map[string]interface {}",
+		},
+		{
+			data:     `{"eat_profile": "tag:github.com,2023:veraison/ear"}`,
+			expected: `unexpected eat_profile tag:github.com,2023:veraison/ear (expecting "tag:github.com,2022:veraison/ear")`,
+		},
+		{
+			data:     `{"timestamp": "yesterday"}`,
+			expected: `invalid value for "timestamp": parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`,
+		},
+		{
+			data:     `{"iat": 1666091373, "ear.verifier-id": {"build": "b", "developer": "d"}}`,
+			expected: "invalid value(s) for 'submods' (legacy: missing mandatory 'ear.status')",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := FromLegacyVeraison([]byte(tv.data), "legacy")
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
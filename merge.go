@@ -0,0 +1,166 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// SubmodResolver decides which appraisal to keep when the same submod name is
+// found in both results being merged.  ours is the appraisal in the receiver,
+// theirs the one in the merged result.
+type SubmodResolver func(name string, ours, theirs *Appraisal) (*Appraisal, error)
+
+// MergeOption configures the behaviour of AttestationResult.Merge
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	verifierID  *VerifierIdentity
+	resolver    SubmodResolver
+	ignoreNonce bool
+}
+
+// WithMergedVerifierID sets the ear.verifier-id of the merged result.  This is
+// needed when the results come from different verifiers, in which case the
+// entity doing the merge is the one vouching for the combined result.
+func WithMergedVerifierID(vid VerifierIdentity) MergeOption {
+	return func(o *mergeOptions) {
+		o.verifierID = &vid
+	}
+}
+
+// WithSubmodResolver sets the function used to reconcile submods with the
+// same name.  By default, a clash is an error.
+func WithSubmodResolver(r SubmodResolver) MergeOption {
+	return func(o *mergeOptions) {
+		o.resolver = r
+	}
+}
+
+// WithoutNonceCheck allows results bound to different nonces to be merged, in
+// which case the receiver's nonce is retained.  Use with care: the merged
+// result no longer proves freshness for all its submods.
+func WithoutNonceCheck() MergeOption {
+	return func(o *mergeOptions) {
+		o.ignoreNonce = true
+	}
+}
+
+// Merge combines the submods of other into the receiver, e.g., to present the
+// appraisals of a composite attester (say, platform and workload) produced by
+// different verifiers as a single result.  The other claims are reconciled as
+// follows:
+//
+//   - eat_profile must be the same;
+//   - ear.verifier-id must be the same, unless set with WithMergedVerifierID;
+//   - eat_nonce must be the same if present in both (see WithoutNonceCheck),
+//     otherwise the one that is set is used;
+//   - iat and nbf take the latest value, and exp the earliest, so that the
+//     merged result is never valid for longer than any of its parts;
+//   - extensions and raw evidence are only taken from other if they are not
+//     set in the receiver.
+//
+// Submods that appear in both results are an error, unless a SubmodResolver is
+// supplied via WithSubmodResolver.  On error, the receiver is left untouched.
+func (o *AttestationResult) Merge(other *AttestationResult, opts ...MergeOption) error {
+	if other == nil {
+		return errors.New("nil attestation result")
+	}
+
+	mo := &mergeOptions{}
+	for _, opt := range opts {
+		opt(mo)
+	}
+
+	merged := *o
+
+	if !reflect.DeepEqual(o.Profile, other.Profile) {
+		return errors.New("eat_profile mismatch")
+	}
+
+	switch {
+	case mo.verifierID != nil:
+		vid := *mo.verifierID
+		merged.VerifierID = &vid
+	case !reflect.DeepEqual(o.VerifierID, other.VerifierID):
+		return errors.New("ear.verifier-id mismatch (use WithMergedVerifierID)")
+	}
+
+	if merged.Nonce == nil {
+		merged.Nonce = other.Nonce
+	} else if other.Nonce != nil && !reflect.DeepEqual(*other.Nonce, *merged.Nonce) && !mo.ignoreNonce {
+		return errors.New("eat_nonce mismatch")
+	}
+
+	merged.IssuedAt = mergeTime(o.IssuedAt, other.IssuedAt, true)
+	merged.NotBefore = mergeTime(o.NotBefore, other.NotBefore, true)
+	merged.Expiry = mergeTime(o.Expiry, other.Expiry, false)
+
+	if merged.RawEvidence == nil {
+		merged.RawEvidence = other.RawEvidence
+	}
+
+	if merged.VeraisonTeeInfo == nil {
+		merged.VeraisonTeeInfo = other.VeraisonTeeInfo
+	}
+
+	if merged.NAETTSInfo == nil {
+		merged.NAETTSInfo = other.NAETTSInfo
+	}
+
+	merged.Submods = make(map[string]*Appraisal, len(o.Submods)+len(other.Submods))
+
+	for name, a := range o.Submods {
+		merged.Submods[name] = a
+	}
+
+	names := make([]string, 0, len(other.Submods))
+	for name := range other.Submods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		theirs := other.Submods[name]
+
+		ours, ok := merged.Submods[name]
+		if !ok {
+			merged.Submods[name] = theirs
+			continue
+		}
+
+		if mo.resolver == nil {
+			return fmt.Errorf("submod %q: present in both results", name)
+		}
+
+		a, err := mo.resolver(name, ours, theirs)
+		if err != nil {
+			return fmt.Errorf("submod %q: %w", name, err)
+		}
+
+		merged.Submods[name] = a
+	}
+
+	*o = merged
+
+	return nil
+}
+
+// mergeTime returns the later (or earlier, if latest is false) of two
+// optional timestamps
+func mergeTime(a, b *int64, latest bool) *int64 {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case (*b > *a) == latest:
+		return b
+	default:
+		return a
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMergeParts() (AttestationResult, AttestationResult) {
+	platformIAT, workloadIAT := testIAT, testIAT+10
+	platformExp, workloadExp := testIAT+3600, testIAT+600
+
+	platform := AttestationResult{
+		Profile:    &testProfile,
+		VerifierID: &testVerifierID,
+		IssuedAt:   &platformIAT,
+		Expiry:     &platformExp,
+		Nonce:      &Nonces{testNonce},
+		Submods: map[string]*Appraisal{
+			"platform": NewAppraisal(TrustTierAffirming),
+		},
+	}
+
+	workload := AttestationResult{
+		Profile:    &testProfile,
+		VerifierID: &testVerifierID,
+		IssuedAt:   &workloadIAT,
+		Expiry:     &workloadExp,
+		Submods: map[string]*Appraisal{
+			"workload": NewAppraisal(TrustTierWarning),
+		},
+	}
+
+	return platform, workload
+}
+
+func TestMerge_ok(t *testing.T) {
+	platform, workload := testMergeParts()
+
+	err := platform.Merge(&workload)
+	require.NoError(t, err)
+
+	assert.Len(t, platform.Submods, 2)
+	assert.Equal(t, testIAT+10, *platform.IssuedAt)
+	assert.Equal(t, testIAT+600, *platform.Expiry)
+	assert.Equal(t, Nonces{testNonce}, *platform.Nonce)
+	assert.NoError(t, platform.validate())
+}
+
+func TestMerge_verifier_id(t *testing.T) {
+	platform, workload := testMergeParts()
+
+	build, developer := "other-v0.1", "Other Ltd."
+	workload.VerifierID = &VerifierIdentity{Build: &build, Developer: &developer}
+
+	err := platform.Merge(&workload)
+	assert.EqualError(t, err, "ear.verifier-id mismatch (use WithMergedVerifierID)")
+	assert.Len(t, platform.Submods, 1)
+
+	composite, aggregator := "composite-v1", "Aggregator Inc."
+	vid := VerifierIdentity{Build: &composite, Developer: &aggregator}
+
+	err = platform.Merge(&workload, WithMergedVerifierID(vid))
+	require.NoError(t, err)
+	assert.Equal(t, vid, *platform.VerifierID)
+}
+
+func TestMerge_nonce(t *testing.T) {
+	platform, workload := testMergeParts()
+	workload.Nonce = &Nonces{testBadNonce}
+
+	err := platform.Merge(&workload)
+	assert.EqualError(t, err, "eat_nonce mismatch")
+
+	err = platform.Merge(&workload, WithoutNonceCheck())
+	require.NoError(t, err)
+	assert.Equal(t, Nonces{testNonce}, *platform.Nonce)
+}
+
+func TestMerge_submod_conflict(t *testing.T) {
+	platform, workload := testMergeParts()
+	workload.Submods["platform"] = NewAppraisal(TrustTierContraindicated)
+
+	err := platform.Merge(&workload)
+	assert.EqualError(t, err, `submod "platform": present in both results`)
+
+	worst := func(_ string, ours, theirs *Appraisal) (*Appraisal, error) {
+		if *theirs.Status > *ours.Status {
+			return theirs, nil
+		}
+		return ours, nil
+	}
+
+	err = platform.Merge(&workload, WithSubmodResolver(worst))
+	require.NoError(t, err)
+	assert.Equal(t, TrustTierContraindicated, *platform.Submods["platform"].Status)
+
+	platform, _ = testMergeParts()
+
+	refuse := func(string, *Appraisal, *Appraisal) (*Appraisal, error) {
+		return nil, errors.New("refused")
+	}
+
+	err = platform.Merge(&workload, WithSubmodResolver(refuse))
+	assert.EqualError(t, err, `submod "platform": refused`)
+}
+
+func TestMerge_profile_mismatch(t *testing.T) {
+	platform, workload := testMergeParts()
+	workload.Profile = &testUnsupportedProfile
+
+	err := platform.Merge(&workload)
+	assert.EqualError(t, err, "eat_profile mismatch")
+}
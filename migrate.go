@@ -0,0 +1,193 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// VeraisonMigrationInfo is the "ear.veraison.migration" claim, which is added
+// to results that have been upgraded and re-signed by a Migration.  It records
+// where the result came from, so that its provenance can still be traced.
+type VeraisonMigrationInfo struct {
+	// OriginalKeyThumbprint is the base64url-encoded RFC7638 SHA-256
+	// thumbprint of the key that verified the original token
+	OriginalKeyThumbprint *string `json:"original-key-thumbprint"`
+	// OriginalProfile is the eat_profile of the original token
+	OriginalProfile *string `json:"original-profile,omitempty"`
+	// MigratedAt is the time of the migration, in seconds since the epoch
+	MigratedAt *int64 `json:"migrated-at"`
+}
+
+func ToVeraisonMigrationInfo(v interface{}) (*VeraisonMigrationInfo, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "migration"`)
+	}
+
+	var info VeraisonMigrationInfo
+
+	for key, val := range vMap {
+		switch key {
+		case "original-key-thumbprint", "original-profile":
+			s, err := str(val)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+			}
+			if key == "original-profile" {
+				info.OriginalProfile = &s
+			} else {
+				info.OriginalKeyThumbprint = &s
+			}
+		case "migrated-at":
+			t, err := int64PtrParser(val)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+			}
+			info.MigratedAt = t.(*int64)
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "migration" object`, key)
+		}
+	}
+
+	if info.OriginalKeyThumbprint == nil || *info.OriginalKeyThumbprint == "" {
+		return nil, errors.New(`"migration" validation failed: empty or missing "original-key-thumbprint"`)
+	}
+
+	if info.MigratedAt == nil {
+		return nil, errors.New(`"migration" validation failed: missing "migrated-at"`)
+	}
+
+	return &info, nil
+}
+
+// Migration upgrades signed EARs to the current profile and re-signs them.
+// Each token is verified using the old key, its claims-set is upgraded (legacy
+// Veraison results are converted, and the Normalizer, if any, is applied), a
+// VeraisonMigrationInfo claim recording the thumbprint of the old key is
+// added, and the result is signed with the new key.
+type Migration struct {
+	// VerifyAlg and VerifyKey are used to verify the tokens to be migrated
+	VerifyAlg jwa.KeyAlgorithm
+	VerifyKey interface{}
+
+	// SignAlg and SignKey are used to re-sign the migrated tokens, with the
+	// optional SignOptions
+	SignAlg     jwa.KeyAlgorithm
+	SignKey     interface{}
+	SignOptions []SignOption
+
+	// Normalizer, if set, is applied to claims-sets that are not in the
+	// legacy Veraison format
+	Normalizer *Normalizer
+
+	// LegacySubmod is the submod under which the appraisal of a legacy
+	// Veraison result is placed (default "legacy")
+	LegacySubmod string
+
+	// Now returns the time recorded as migrated-at (default time.Now)
+	Now func() time.Time
+}
+
+// MigrateToken migrates a single token, returning the re-signed one
+func (o Migration) MigrateToken(token []byte) ([]byte, error) {
+	payload, err := jws.Verify(token, jws.WithKey(o.VerifyAlg, o.VerifyKey))
+	if err != nil {
+		return nil, fmt.Errorf("verifying original token: %w", err)
+	}
+
+	thumbprint, err := keyThumbprint(o.VerifyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err = json.Unmarshal(payload, &m); err != nil {
+		return nil, fmt.Errorf("decoding original claims-set: %w", err)
+	}
+
+	var (
+		ar      *AttestationResult
+		profile *string
+	)
+
+	if p, ok := m["eat_profile"].(string); ok {
+		profile = &p
+	}
+
+	if profile != nil && *profile == LegacyVeraisonProfile {
+		submod := o.LegacySubmod
+		if submod == "" {
+			submod = "legacy"
+		}
+
+		if ar, err = FromLegacyVeraison(payload, submod); err != nil {
+			return nil, fmt.Errorf("converting legacy claims-set: %w", err)
+		}
+	} else {
+		var opts []DecodeOption
+		if o.Normalizer != nil {
+			opts = append(opts, WithNormalizer(o.Normalizer))
+		}
+
+		ar = &AttestationResult{}
+		if err = ar.DecodeJSON(payload, opts...); err != nil {
+			return nil, fmt.Errorf("upgrading claims-set: %w", err)
+		}
+	}
+
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+	migratedAt := now().Unix()
+
+	ar.VeraisonMigration = &VeraisonMigrationInfo{
+		OriginalKeyThumbprint: &thumbprint,
+		OriginalProfile:       profile,
+		MigratedAt:            &migratedAt,
+	}
+
+	return ar.Sign(o.SignAlg, o.SignKey, o.SignOptions...)
+}
+
+// Run migrates all the tokens in the store.  The migration is transactional:
+// tokens are only written back, using TokenStore.Replace, if all of them have
+// been migrated successfully.  The number of migrated tokens is returned.
+func (o Migration) Run(store TokenStore) (int, error) {
+	ids, err := store.List()
+	if err != nil {
+		return 0, fmt.Errorf("listing tokens: %w", err)
+	}
+
+	// process tokens in a stable order, so that the first error reported
+	// does not depend on the store
+	sort.Strings(ids)
+
+	migrated := make(map[string][]byte, len(ids))
+
+	for _, id := range ids {
+		token, err := store.Load(id)
+		if err != nil {
+			return 0, fmt.Errorf("loading token %q: %w", id, err)
+		}
+
+		if migrated[id], err = o.MigrateToken(token); err != nil {
+			return 0, fmt.Errorf("migrating token %q: %w", id, err)
+		}
+	}
+
+	if err := store.Replace(migrated); err != nil {
+		return 0, fmt.Errorf("writing back migrated tokens: %w", err)
+	}
+
+	return len(migrated), nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLegacyClaimsSet = []byte(`{
+	"eat_profile": "tag:github.com,2022:veraison/ear",
+	"iat": 1666091373,
+	"ear.status": "affirming",
+	"ear.appraisal-policy-id": "policy://test/01234",
+	"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."}
+}`)
+
+func testMigration(t *testing.T) (Migration, jwk.Key, *ecdsa.PublicKey) {
+	oldSigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	oldVfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	m := Migration{
+		VerifyAlg: jwa.ES256,
+		VerifyKey: oldVfyK,
+		SignAlg:   jwa.ES256,
+		SignKey:   newKey,
+		Now:       func() time.Time { return time.Unix(1700000000, 0) },
+	}
+
+	return m, oldSigK, &newKey.PublicKey
+}
+
+func TestMigration_Run_ok(t *testing.T) {
+	m, oldSigK, newPub := testMigration(t)
+
+	legacy, err := jws.Sign(testLegacyClaimsSet, jws.WithKey(jwa.ES256, oldSigK))
+	require.NoError(t, err)
+
+	current, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, oldSigK)
+	require.NoError(t, err)
+
+	store := NewMemoryTokenStore(map[string][]byte{
+		"legacy":  legacy,
+		"current": current,
+	})
+
+	n, err := m.Run(store)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	thumbprint, err := keyThumbprint(m.VerifyKey)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	require.NoError(t, ar.Verify(store.tokens["legacy"], jwa.ES256, newPub))
+	assert.Equal(t, EatProfile, ar.Profile.String())
+	assert.Contains(t, ar.Submods, "legacy")
+	require.NotNil(t, ar.VeraisonMigration)
+	assert.Equal(t, thumbprint, *ar.VeraisonMigration.OriginalKeyThumbprint)
+	assert.Equal(t, LegacyVeraisonProfile, *ar.VeraisonMigration.OriginalProfile)
+	assert.Equal(t, int64(1700000000), *ar.VeraisonMigration.MigratedAt)
+
+	require.NoError(t, ar.Verify(store.tokens["current"], jwa.ES256, newPub))
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns.Submods, ar.Submods)
+	assert.Equal(t, EatProfile, *ar.VeraisonMigration.OriginalProfile)
+}
+
+func TestMigration_Run_all_or_nothing(t *testing.T) {
+	m, oldSigK, _ := testMigration(t)
+
+	good, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, oldSigK)
+	require.NoError(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	forged, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, otherKey)
+	require.NoError(t, err)
+
+	store := NewMemoryTokenStore(map[string][]byte{
+		"a-good":   good,
+		"b-forged": forged,
+	})
+
+	_, err = m.Run(store)
+	assert.ErrorContains(t, err, `migrating token "b-forged": verifying original token: `)
+
+	// nothing has been written back
+	assert.Equal(t, good, store.tokens["a-good"])
+	assert.Equal(t, forged, store.tokens["b-forged"])
+}
+
+func TestVeraisonMigrationInfo_roundtrip(t *testing.T) {
+	thumbprint, migratedAt := "abc", int64(1700000000)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.VeraisonMigration = &VeraisonMigrationInfo{
+		OriginalKeyThumbprint: &thumbprint,
+		MigratedAt:            &migratedAt,
+	}
+
+	data, err := ar.MarshalJSON()
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	require.NoError(t, actual.UnmarshalJSON(data))
+	assert.Equal(t, ar.VeraisonMigration, actual.VeraisonMigration)
+}
+
+func TestToVeraisonMigrationInfo_fail(t *testing.T) {
+	tvs := []struct {
+		v        interface{}
+		expected string
+	}{
+		{
+			v:        "x",
+			expected: `unexpected format for "migration"`,
+		},
+		{
+			v:        map[string]interface{}{"original-key-thumbprint": 1},
+			expected: `invalid value for "original-key-thumbprint": expecting string, found int`,
+		},
+		{
+			v:        map[string]interface{}{"migrated-at": 1.0},
+			expected: `"migration" validation failed: empty or missing "original-key-thumbprint"`,
+		},
+		{
+			v:        map[string]interface{}{"original-key-thumbprint": "abc"},
+			expected: `"migration" validation failed: missing "migrated-at"`,
+		},
+		{
+			v:        map[string]interface{}{"original-key-thumbprint": "abc", "other": 1},
+			expected: `found unknown key "other" in "migration" object`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := ToVeraisonMigrationInfo(tv.v)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -1,26 +1,133 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
 package ear
 
 import (
 	"errors"
 	"fmt"
+	"regexp"
 )
 
+// MaxNAETTSSessionIDLength is the maximum length of the "sessionid" member of
+// the "ear.nae.tts-info" claim
+const MaxNAETTSSessionIDLength = 256
+
+// naeSessionIDRe matches the session identifiers handed out by trusted
+// time-stamping services, which are opaque tokens in one of the usual
+// encodings (hex, UUID, base64 or base64url)
+var naeSessionIDRe = regexp.MustCompile(`^[A-Za-z0-9._~+/=-]+$`)
+
+// NAETTSInfo is the "ear.nae.tts-info" claim, in which a verifier records the
+// session with the trusted time-stamping service (TTS) of a network
+// attestation environment, the infrastructure it belongs to, and the identity
+// the session was established with.  The claim is carried at the top level of
+// the result, since the session applies to all the appraisals.
 type NAETTSInfo struct {
-	SessionID      *string `json:"sessionid"`
+	// SessionID is the opaque identifier of the TTS session
+	SessionID *string `json:"sessionid"`
+	// Infrastructure names the infrastructure hosting the TTS
 	Infrastructure *string `json:"infrastructure"`
-	Identity       *string `json:"identity"`
+	// Identity is the identity of the attester in the session
+	Identity *string `json:"identity"`
+}
+
+// NewNAETTSInfo returns a validated NAETTSInfo.  The infrastructure and
+// identity are optional, and omitted if empty.
+func NewNAETTSInfo(sessionID, infrastructure, identity string) (*NAETTSInfo, error) {
+	info := NAETTSInfo{SessionID: &sessionID}
+
+	if infrastructure != "" {
+		info.Infrastructure = &infrastructure
+	}
+
+	if identity != "" {
+		info.Identity = &identity
+	}
+
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// Validate checks that the session ID is present and well-formed, and that
+// the other members, if present, are non-empty free text
+func (o NAETTSInfo) Validate() error {
+	if o.SessionID == nil || *o.SessionID == "" {
+		return errors.New(`empty or missing "sessionid"`)
+	}
+
+	if len(*o.SessionID) > MaxNAETTSSessionIDLength {
+		return fmt.Errorf(
+			`invalid "sessionid": too long (%d > %d characters)`,
+			len(*o.SessionID), MaxNAETTSSessionIDLength,
+		)
+	}
+
+	if !naeSessionIDRe.MatchString(*o.SessionID) {
+		return fmt.Errorf(`invalid "sessionid" %q: unexpected characters`, *o.SessionID)
+	}
+
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{
+		{"infrastructure", o.Infrastructure},
+		{"identity", o.Identity},
+	} {
+		if f.value == nil {
+			continue
+		}
+
+		if *f.value == "" {
+			return fmt.Errorf(`empty %q`, f.name)
+		}
+
+		if err := checkFreeText(*f.value); err != nil {
+			return fmt.Errorf(`invalid %q: %w`, f.name, err)
+		}
+	}
+
+	return nil
+}
+
+// SetNAETTSInfo validates and sets the "ear.nae.tts-info" claim
+func (o *AttestationResult) SetNAETTSInfo(info NAETTSInfo) error {
+	if err := info.Validate(); err != nil {
+		return err
+	}
+
+	o.NAETTSInfo = &info
+
+	return nil
+}
+
+// GetNAETTSInfo returns the "ear.nae.tts-info" claim, or an error if it is not
+// present
+func (o AttestationResult) GetNAETTSInfo() (*NAETTSInfo, error) {
+	if o.NAETTSInfo == nil {
+		return nil, errors.New(`"ear.nae.tts-info" claim not found`)
+	}
+
+	return o.NAETTSInfo, nil
 }
 
 func ToNAETTSInfo(v interface{}) (*NAETTSInfo, error) {
 	vMap, ok := v.(map[string]interface{})
 	if !ok {
-		return nil, errors.New(`unexpected format for "tee-info"`)
+		return nil, errors.New(`unexpected format for "tts-info"`)
 	}
 
 	var info NAETTSInfo
 
 	for key, val := range vMap {
-		s := str(val)
+		s, err := str(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+		}
+
 		switch key {
 		case "sessionid":
 			info.SessionID = &s
@@ -29,8 +136,13 @@ func ToNAETTSInfo(v interface{}) (*NAETTSInfo, error) {
 		case "identity":
 			info.Identity = &s
 		default:
-			return nil, fmt.Errorf(`found unknown key %q in "naetts" object`, key)
+			return nil, fmt.Errorf(`found unknown key %q in "tts-info" object`, key)
 		}
 	}
+
+	if err := info.Validate(); err != nil {
+		return nil, fmt.Errorf(`"tts-info" validation failed: %w`, err)
+	}
+
 	return &info, nil
 }
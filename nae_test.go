@@ -0,0 +1,166 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToNAETTSInfo_ok(t *testing.T) {
+	tv := map[string]interface{}{
+		"sessionid":      "b5a3c9e2",
+		"infrastructure": "acme-cloud",
+		"identity":       "node-01",
+	}
+
+	actual, err := ToNAETTSInfo(tv)
+	require.NoError(t, err)
+	assert.Equal(t, "b5a3c9e2", *actual.SessionID)
+	assert.Equal(t, "acme-cloud", *actual.Infrastructure)
+	assert.Equal(t, "node-01", *actual.Identity)
+}
+
+func TestToNAETTSInfo_fail(t *testing.T) {
+	tvs := []struct {
+		info     interface{}
+		expected string
+	}{
+		{
+			info:     "not-a-map",
+			expected: `unexpected format for "tts-info"`,
+		},
+		{
+			info: map[string]interface{}{
+				"sessionid": 12345678,
+			},
+			expected: `invalid value for "sessionid": expecting string, found int`,
+		},
+		{
+			info: map[string]interface{}{
+				"identity": float64(42),
+			},
+			expected: `invalid value for "identity": expecting string, found float64`,
+		},
+		{
+			info: map[string]interface{}{
+				"infrastructure": nil,
+			},
+			expected: `invalid value for "infrastructure": expecting string, found <nil>`,
+		},
+		{
+			info: map[string]interface{}{
+				"sessionid": "b5a3c9e2",
+				"tenant":    "acme",
+			},
+			expected: `found unknown key "tenant" in "tts-info" object`,
+		},
+		{
+			info: map[string]interface{}{
+				"identity": "node-01",
+			},
+			expected: `"tts-info" validation failed: empty or missing "sessionid"`,
+		},
+		{
+			info: map[string]interface{}{
+				"sessionid": "b5a3 c9e2",
+			},
+			expected: `"tts-info" validation failed: invalid "sessionid" "b5a3 c9e2": unexpected characters`,
+		},
+		{
+			info: map[string]interface{}{
+				"sessionid": strings.Repeat("a", MaxNAETTSSessionIDLength+1),
+			},
+			expected: `"tts-info" validation failed: invalid "sessionid": too long (257 > 256 characters)`,
+		},
+		{
+			info: map[string]interface{}{
+				"sessionid":      "b5a3c9e2",
+				"infrastructure": "",
+			},
+			expected: `"tts-info" validation failed: empty "infrastructure"`,
+		},
+		{
+			info: map[string]interface{}{
+				"sessionid": "b5a3c9e2",
+				"identity":  "node-01\x1b[2J",
+			},
+			expected: `"tts-info" validation failed: invalid "identity": contains control character U+001B`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := ToNAETTSInfo(tv.info)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestNewNAETTSInfo(t *testing.T) {
+	info, err := NewNAETTSInfo("b5a3c9e2", "", "node-01")
+	require.NoError(t, err)
+	assert.Equal(t, "b5a3c9e2", *info.SessionID)
+	assert.Nil(t, info.Infrastructure)
+	assert.Equal(t, "node-01", *info.Identity)
+
+	_, err = NewNAETTSInfo("", "acme-cloud", "node-01")
+	assert.EqualError(t, err, `empty or missing "sessionid"`)
+}
+
+func TestAttestationResult_SetGetNAETTSInfo(t *testing.T) {
+	var ar AttestationResult
+
+	_, err := ar.GetNAETTSInfo()
+	assert.EqualError(t, err, `"ear.nae.tts-info" claim not found`)
+
+	err = ar.SetNAETTSInfo(NAETTSInfo{})
+	assert.EqualError(t, err, `empty or missing "sessionid"`)
+
+	info, err := NewNAETTSInfo("b5a3c9e2", "acme-cloud", "node-01")
+	require.NoError(t, err)
+	require.NoError(t, ar.SetNAETTSInfo(*info))
+
+	actual, err := ar.GetNAETTSInfo()
+	require.NoError(t, err)
+	assert.Equal(t, info, actual)
+}
+
+func TestNAETTSInfo_roundtrip(t *testing.T) {
+	info, err := NewNAETTSInfo("b5a3c9e2", "acme-cloud", "node-01")
+	require.NoError(t, err)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	require.NoError(t, ar.SetNAETTSInfo(*info))
+
+	data, err := ar.MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data),
+		`"ear.nae.tts-info":{"sessionid":"b5a3c9e2","infrastructure":"acme-cloud","identity":"node-01"}`)
+
+	var actual AttestationResult
+	require.NoError(t, actual.UnmarshalJSON(data))
+	assert.Equal(t, info, actual.NAETTSInfo)
+
+	data, err = ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	actual = AttestationResult{}
+	require.NoError(t, actual.UnmarshalCBOR(data))
+	assert.Equal(t, info, actual.NAETTSInfo)
+}
+
+func TestAttestationResult_validate_nae_tts_info(t *testing.T) {
+	sessionID := "b5a3 c9e2"
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.NAETTSInfo = &NAETTSInfo{SessionID: &sessionID}
+
+	err := ar.Validate()
+	assert.ErrorContains(t, err, `'ear.nae.tts-info' (invalid "sessionid" "b5a3 c9e2": unexpected characters)`)
+
+	_, err = ar.MarshalJSON()
+	assert.Error(t, err)
+}
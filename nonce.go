@@ -0,0 +1,140 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Nonces represents the eat_nonce claim, which can carry either a single nonce
+// or an array of nonces (e.g., one per relying party in a multi-party
+// challenge-response).  Each nonce is base64url-encoded (without padding) and
+// must decode to 8 to 64 bytes.  A single nonce is serialized as a string,
+// multiple nonces as an array.
+type Nonces []string
+
+// NewNonces returns a Nonces object containing the supplied base64url-encoded
+// nonces, or an error if any of them is malformed
+func NewNonces(nonces ...string) (*Nonces, error) {
+	var o Nonces
+
+	for _, n := range nonces {
+		if err := o.Add(n); err != nil {
+			return nil, err
+		}
+	}
+
+	return &o, nil
+}
+
+// Add appends the supplied base64url-encoded nonce, after checking it
+func (o *Nonces) Add(nonce string) error {
+	if err := checkNonce(nonce); err != nil {
+		return err
+	}
+
+	*o = append(*o, nonce)
+
+	return nil
+}
+
+// Get returns the decoded i-th nonce
+func (o Nonces) Get(i int) ([]byte, error) {
+	if i < 0 || i >= len(o) {
+		return nil, fmt.Errorf("no nonce at index %d", i)
+	}
+
+	return base64.RawURLEncoding.DecodeString(o[i])
+}
+
+// Contains tells whether the supplied base64url-encoded nonce is among those
+// in the claim
+func (o Nonces) Contains(nonce string) bool {
+	return contains(o, nonce)
+}
+
+func (o Nonces) MarshalJSON() ([]byte, error) {
+	if len(o) == 1 {
+		return json.Marshal(o[0])
+	}
+
+	return json.Marshal([]string(o))
+}
+
+func (o *Nonces) UnmarshalJSON(data []byte) error {
+	var v interface{}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	n, err := ToNonces(v)
+	if err != nil {
+		return err
+	}
+
+	*o = *n
+
+	return nil
+}
+
+// ToNonces converts the value of an eat_nonce claim, i.e., a string or an
+// array of strings, into a Nonces object
+func ToNonces(v interface{}) (*Nonces, error) {
+	switch t := v.(type) {
+	case string:
+		return &Nonces{t}, nil
+	case []string:
+		n := Nonces(t)
+		return &n, nil
+	case []interface{}:
+		n := make(Nonces, 0, len(t))
+		for i, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("nonce at index %d is not a string", i)
+			}
+			n = append(n, s)
+		}
+		return &n, nil
+	default:
+		return nil, errors.New("neither a string nor an array of strings")
+	}
+}
+
+func checkNonce(nonce string) error {
+	b, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil {
+		return fmt.Errorf("not base64url: %w", err)
+	}
+
+	if len(b) < 8 || len(b) > 64 {
+		return fmt.Errorf("%d bytes, expecting 8 to 64", len(b))
+	}
+
+	return nil
+}
+
+func (o Nonces) validate(ve *ValidationError) {
+	if len(o) == 0 {
+		ve.addInvalid("eat_nonce", "eat_nonce (empty)", nil)
+		return
+	}
+
+	for i, n := range o {
+		err := checkNonce(n)
+		if err == nil {
+			continue
+		}
+
+		if len(o) == 1 {
+			ve.addInvalid("eat_nonce", fmt.Sprintf("eat_nonce (%s)", err), err)
+		} else {
+			ve.addInvalid("eat_nonce", fmt.Sprintf("eat_nonce[%d] (%s)", i, err), err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOtherNonce = "AAECAwQFBgcICQoLDA0ODw"
+
+func TestNonces_Add_Get(t *testing.T) {
+	var n Nonces
+
+	require.NoError(t, n.Add(testNonce))
+	require.NoError(t, n.Add(testOtherNonce))
+
+	assert.EqualError(t, n.Add(testBadNonce), "3 bytes, expecting 8 to 64")
+	assert.ErrorContains(t, n.Add("not/base64url"), "not base64url")
+
+	b, err := n.Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, b)
+
+	_, err = n.Get(2)
+	assert.EqualError(t, err, "no nonce at index 2")
+
+	assert.True(t, n.Contains(testNonce))
+	assert.False(t, n.Contains(testBadNonce))
+}
+
+func TestNonces_JSON(t *testing.T) {
+	tvs := []struct {
+		nonces Nonces
+		json   string
+	}{
+		{nonces: Nonces{testNonce}, json: `"0123456789abcdef"`},
+		{nonces: Nonces{testNonce, testOtherNonce}, json: `["0123456789abcdef","AAECAwQFBgcICQoLDA0ODw"]`},
+	}
+
+	for i, tv := range tvs {
+		data, err := json.Marshal(tv.nonces)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.JSONEq(t, tv.json, string(data), "failed test vector at index %d", i)
+
+		var actual Nonces
+		require.NoError(t, json.Unmarshal(data, &actual), "failed test vector at index %d", i)
+		assert.Equal(t, tv.nonces, actual, "failed test vector at index %d", i)
+	}
+
+	var n Nonces
+	assert.EqualError(t, json.Unmarshal([]byte(`[1]`), &n), "nonce at index 0 is not a string")
+	assert.EqualError(t, json.Unmarshal([]byte(`1`), &n), "neither a string nor an array of strings")
+}
+
+func TestNonces_validate(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+
+	ar.Nonce = &Nonces{testNonce, testBadNonce}
+	assert.EqualError(t, ar.validate(),
+		"invalid value(s) for eat_nonce[1] (3 bytes, expecting 8 to 64)")
+
+	ar.Nonce = &Nonces{}
+	assert.EqualError(t, ar.validate(), "invalid value(s) for eat_nonce (empty)")
+}
+
+func TestNonces_round_trip(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+	signer, verifier := testCOSESignerVerifier(t)
+
+	nonces, err := NewNonces(testNonce, testOtherNonce)
+	require.NoError(t, err)
+
+	expected := testAttestationResultsWithVeraisonExtns
+	expected.Nonce = nonces
+
+	token, err := expected.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	require.NoError(t, actual.Verify(token, jwa.ES256, vfyK))
+	assert.Equal(t, expected, actual)
+
+	cwt, err := expected.SignCWT(signer)
+	require.NoError(t, err)
+
+	actual = AttestationResult{}
+	require.NoError(t, actual.VerifyCWT(cwt, verifier))
+	assert.Equal(t, expected, actual)
+}
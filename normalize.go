@@ -0,0 +1,268 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NormalizationRule rewrites a decoded claims-set (i.e., the map of claim names
+// to values obtained from JSON or CBOR, before it is parsed into an
+// AttestationResult) in place, mapping a verifier-specific quirk onto the
+// canonical form.
+type NormalizationRule interface {
+	Normalize(claims map[string]interface{}) error
+}
+
+// NormalizationRuleFunc is an adapter to allow the use of ordinary functions
+// as NormalizationRules.
+type NormalizationRuleFunc func(claims map[string]interface{}) error
+
+func (o NormalizationRuleFunc) Normalize(claims map[string]interface{}) error {
+	return o(claims)
+}
+
+// Normalizer applies a sequence of NormalizationRules to a claims-set, so
+// that downstream code only ever sees one dialect regardless of which verifier
+// produced the result.  It is supplied to the decoder using WithNormalizer.
+type Normalizer struct {
+	rules []NormalizationRule
+}
+
+// NewNormalizer returns a Normalizer that applies the supplied rules in order
+func NewNormalizer(rules ...NormalizationRule) *Normalizer {
+	return &Normalizer{rules: rules}
+}
+
+// Normalize applies the rules to the claims-set, stopping at the first error
+func (o Normalizer) Normalize(claims map[string]interface{}) error {
+	for i, r := range o.rules {
+		if err := r.Normalize(claims); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ClaimAliases maps alternate claim spellings onto the canonical claim names
+// (e.g., "ear.trust-vector" onto "ear.trustworthiness-vector").  Aliases are
+// resolved in the result, in each appraisal and in each trust vector.  It is an
+// error for a claim to be present under both its canonical name and an alias.
+// Names are matched exactly; use Lenient for case-insensitive matching.
+type ClaimAliases map[string]string
+
+func (o ClaimAliases) Normalize(claims map[string]interface{}) error {
+	return claimAliasResolver{names: o}.normalize(claims)
+}
+
+// ClaimAliasWarning is invoked for each claim that has been renamed by the
+// rule returned by ClaimAliases.Lenient, with the JSON Pointer of the renamed
+// claim (e.g., "/submods/test/ear.appraisal_policy_id") and its canonical name
+type ClaimAliasWarning func(pointer, canonical string)
+
+// Lenient returns a NormalizationRule that resolves the aliases like
+// Normalize does, but matches names case-insensitively, so that canonical
+// names spelled in a different case (e.g., "EAR.Status") are also renamed.  If
+// warn is not nil, it is invoked for each renaming, so that the producers of
+// non-conforming results can be tracked down.
+//
+// Use CompatibilityAliases for the table of the aliases seen in the wild.
+func (o ClaimAliases) Lenient(warn ClaimAliasWarning) NormalizationRule {
+	r := claimAliasResolver{names: map[string]string{}, fold: true, warn: warn}
+
+	for _, name := range canonicalClaimNames() {
+		r.names[strings.ToLower(name)] = name
+	}
+
+	for alias, canonical := range o {
+		r.names[strings.ToLower(alias)] = canonical
+		r.names[strings.ToLower(canonical)] = canonical
+	}
+
+	return NormalizationRuleFunc(r.normalize)
+}
+
+// CompatibilityAliases returns the table of claim name aliases that have been
+// seen in the wild.  It maps:
+//   - the underscore spelling of each hyphenated claim name (e.g.,
+//     "ear.appraisal_policy_id") onto the canonical name;
+//   - "eat-profile" and "eat-nonce" onto "eat_profile" and "eat_nonce".
+//
+// The returned table can be extended before being used, typically through
+// Lenient.
+func CompatibilityAliases() ClaimAliases {
+	aliases := ClaimAliases{
+		"eat-profile": "eat_profile",
+		"eat-nonce":   "eat_nonce",
+	}
+
+	for _, name := range canonicalClaimNames() {
+		if strings.Contains(name, "-") {
+			aliases[strings.ReplaceAll(name, "-", "_")] = name
+		}
+	}
+
+	return aliases
+}
+
+type claimAliasResolver struct {
+	// names maps the aliases (lower-cased, if fold is set) onto the
+	// canonical names
+	names map[string]string
+	fold  bool
+	warn  ClaimAliasWarning
+}
+
+func (o claimAliasResolver) normalize(claims map[string]interface{}) error {
+	if err := o.rename(claims, ""); err != nil {
+		return err
+	}
+
+	submods, ok := claims["submods"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, name := range sortedKeys(submods) {
+		appraisal, ok := submods[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		pointer := "/submods/" + escapeJSONPointer(name)
+
+		if err := o.rename(appraisal, pointer); err != nil {
+			return err
+		}
+
+		if tv, ok := appraisal["ear.trustworthiness-vector"].(map[string]interface{}); ok {
+			if err := o.rename(tv, pointer+"/ear.trustworthiness-vector"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o claimAliasResolver) rename(m map[string]interface{}, pointer string) error {
+	// iterate in a stable order, so that errors and warnings do not depend
+	// on map ordering
+	for _, k := range sortedKeys(m) {
+		alias := k
+		if o.fold {
+			alias = strings.ToLower(k)
+		}
+
+		canonical, ok := o.names[alias]
+		if !ok || canonical == k {
+			continue
+		}
+
+		if _, ok := m[canonical]; ok {
+			return fmt.Errorf("both %q and its alias %q are present",
+				pointer+"/"+escapeJSONPointer(canonical), pointer+"/"+escapeJSONPointer(k))
+		}
+
+		m[canonical] = m[k]
+		delete(m, k)
+
+		if o.warn != nil {
+			o.warn(pointer+"/"+escapeJSONPointer(k), canonical)
+		}
+	}
+
+	return nil
+}
+
+// canonicalClaimNames returns the names of the claims that can occur in the
+// result, in an appraisal or in a trust vector
+func canonicalClaimNames() []string {
+	var names []string
+
+	for _, t := range []reflect.Type{
+		reflect.TypeOf(AttestationResult{}),
+		reflect.TypeOf(Appraisal{}),
+		reflect.TypeOf(TrustVector{}),
+	} {
+		names = append(names, structTagNames(t, "json")...)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// StatusAliases maps legacy ear.status strings (e.g., "pass") onto the
+// corresponding trust tiers.
+type StatusAliases map[string]TrustTier
+
+func (o StatusAliases) Normalize(claims map[string]interface{}) error {
+	return forEachAppraisal(claims, func(appraisal map[string]interface{}) error {
+		s, ok := appraisal["ear.status"].(string)
+		if !ok {
+			return nil
+		}
+
+		if tier, ok := o[s]; ok {
+			appraisal["ear.status"] = tier.String()
+		}
+
+		return nil
+	})
+}
+
+// TierOffset is added to integer ear.status values, for verifiers that encode
+// trust tiers with a constant offset from the AR4SI values.
+type TierOffset int
+
+func (o TierOffset) Normalize(claims map[string]interface{}) error {
+	return forEachAppraisal(claims, func(appraisal map[string]interface{}) error {
+		var i int64
+
+		switch t := appraisal["ear.status"].(type) {
+		case float64:
+			i = int64(t)
+		case int64:
+			i = t
+		case int:
+			i = int64(t)
+		default:
+			return nil
+		}
+
+		appraisal["ear.status"] = i + int64(o)
+
+		return nil
+	})
+}
+
+func forEachAppraisal(claims map[string]interface{}, fn func(map[string]interface{}) error) error {
+	v, ok := claims["submods"]
+	if !ok {
+		return nil
+	}
+
+	submods, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("submods: not a map object")
+	}
+
+	for name, val := range submods {
+		appraisal, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if err := fn(appraisal); err != nil {
+			return fmt.Errorf("submods[%s]: %w", name, err)
+		}
+	}
+
+	return nil
+}
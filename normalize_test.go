@@ -0,0 +1,210 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testQuirkyClaims = `{
+	"eat_profile": "tag:github.com,2023:veraison/ear",
+	"iat": 1666091373,
+	"ear.verifier-id": {
+		"build": "rrtrap-v1.0.0",
+		"developer": "Acme Inc."
+	},
+	"submods": {
+		"test": {
+			"ear.status": "pass",
+			"ear.trust-vector": {
+				"exec": 2
+			}
+		},
+		"other": {
+			"ear.status": 1
+		}
+	}
+}`
+
+var testNormalizer = NewNormalizer(
+	ClaimAliases{
+		"ear.trust-vector": "ear.trustworthiness-vector",
+		"exec":             "executables",
+	},
+	StatusAliases{
+		"pass": TrustTierAffirming,
+		"fail": TrustTierContraindicated,
+	},
+	TierOffset(1),
+)
+
+func TestDecodeJSON_normalizer(t *testing.T) {
+	var ar AttestationResult
+
+	err := ar.DecodeJSON([]byte(testQuirkyClaims), WithNormalizer(testNormalizer))
+	require.NoError(t, err)
+
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
+	assert.Equal(t, ApprovedRuntimeClaim, ar.Submods["test"].TrustVector.Executables)
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["other"].Status)
+}
+
+func TestDecodeJSON_no_normalizer(t *testing.T) {
+	var ar AttestationResult
+
+	err := ar.DecodeJSON([]byte(testQuirkyClaims))
+	assert.ErrorContains(t, err, `not a valid TrustTier name: "pass"`)
+}
+
+func TestClaimAliases_conflict(t *testing.T) {
+	claims := map[string]interface{}{
+		"submods": map[string]interface{}{
+			"test": map[string]interface{}{
+				"ear.status":              "affirming",
+				"ear.appraisal-pid":       "a",
+				"ear.appraisal-policy-id": "b",
+			},
+		},
+	}
+
+	n := NewNormalizer(ClaimAliases{"ear.appraisal-pid": "ear.appraisal-policy-id"})
+
+	err := n.Normalize(claims)
+	assert.EqualError(t, err, `rule 0: both "/submods/test/ear.appraisal-policy-id" and its alias "/submods/test/ear.appraisal-pid" are present`)
+}
+
+func TestVerify_normalizer(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(testQuirkyClaims), &claims))
+
+	token := jwt.New()
+	for k, v := range claims {
+		require.NoError(t, token.Set(k, v))
+	}
+
+	data, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, sigK))
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.Verify(data, jwa.ES256, vfyK,
+		WithDecodeOptions(WithNormalizer(testNormalizer)))
+	require.NoError(t, err)
+
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
+}
+
+var testAliasedClaims = `{
+	"eat-profile": "tag:github.com,2023:veraison/ear",
+	"iat": 1666091373,
+	"ear.verifier_id": {
+		"build": "rrtrap-v1.0.0",
+		"developer": "Acme Inc."
+	},
+	"submods": {
+		"test": {
+			"EAR.Status": "affirming",
+			"ear.appraisal_policy_id": "policy://test/01234",
+			"ear.trustworthiness_vector": {
+				"instance_identity": 2
+			}
+		}
+	}
+}`
+
+func TestCompatibilityAliases(t *testing.T) {
+	aliases := CompatibilityAliases()
+
+	assert.Equal(t, "ear.appraisal-policy-id", aliases["ear.appraisal_policy_id"])
+	assert.Equal(t, "instance-identity", aliases["instance_identity"])
+	assert.Equal(t, "eat_profile", aliases["eat-profile"])
+	assert.NotContains(t, aliases, "ear.status")
+}
+
+func TestClaimAliases_Lenient(t *testing.T) {
+	var warnings []string
+
+	warn := func(pointer, canonical string) {
+		warnings = append(warnings, fmt.Sprintf("%s -> %s", pointer, canonical))
+	}
+
+	var ar AttestationResult
+
+	err := ar.DecodeJSON([]byte(testAliasedClaims),
+		WithNormalizer(NewNormalizer(CompatibilityAliases().Lenient(warn))))
+	require.NoError(t, err)
+
+	assert.Equal(t, EatProfile, ar.Profile.String())
+	assert.Equal(t, "rrtrap-v1.0.0", *ar.VerifierID.Build)
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
+	assert.Equal(t, PolicyIDs{"policy://test/01234"}, *ar.Submods["test"].AppraisalPolicyID)
+	assert.Equal(t, TrustworthyInstanceClaim, ar.Submods["test"].TrustVector.InstanceIdentity)
+
+	expected := []string{
+		"/ear.verifier_id -> ear.verifier-id",
+		"/eat-profile -> eat_profile",
+		"/submods/test/EAR.Status -> ear.status",
+		"/submods/test/ear.appraisal_policy_id -> ear.appraisal-policy-id",
+		"/submods/test/ear.trustworthiness_vector -> ear.trustworthiness-vector",
+		"/submods/test/ear.trustworthiness-vector/instance_identity -> instance-identity",
+	}
+	assert.Equal(t, expected, warnings)
+}
+
+func TestClaimAliases_Lenient_custom_table(t *testing.T) {
+	data := []byte(`{
+		"eat_profile": "tag:github.com,2023:veraison/ear",
+		"iat": 1666091373,
+		"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."},
+		"submods": {"test": {"ear.status": "affirming", "ear.policy": "policy://test/01234"}}
+	}`)
+
+	var ar AttestationResult
+
+	rule := ClaimAliases{"ear.policy": "ear.appraisal-policy-id"}.Lenient(nil)
+
+	err := ar.DecodeJSON(data, WithNormalizer(NewNormalizer(rule)))
+	require.NoError(t, err)
+	assert.Equal(t, PolicyIDs{"policy://test/01234"}, *ar.Submods["test"].AppraisalPolicyID)
+}
+
+func TestClaimAliases_Lenient_conflict(t *testing.T) {
+	data := []byte(`{
+		"eat_profile": "tag:github.com,2023:veraison/ear",
+		"eat-profile": "tag:github.com,2023:veraison/ear",
+		"iat": 1666091373,
+		"ear.verifier-id": {"build": "rrtrap-v1.0.0", "developer": "Acme Inc."},
+		"submods": {"test": {"ear.status": "affirming"}}
+	}`)
+
+	var ar AttestationResult
+
+	err := ar.DecodeJSON(data, WithNormalizer(NewNormalizer(CompatibilityAliases().Lenient(nil))))
+	assert.EqualError(t, err, `normalizing claims-set: rule 0: both "/eat_profile" and its alias "/eat-profile" are present`)
+}
+
+func TestClaimAliases_case_sensitive(t *testing.T) {
+	var ar AttestationResult
+
+	err := ar.DecodeJSON([]byte(testAliasedClaims))
+	assert.ErrorContains(t, err, "missing mandatory 'eat_profile'")
+
+	// without Lenient, aliases are matched exactly
+	err = ar.DecodeJSON([]byte(testAliasedClaims), WithNormalizer(NewNormalizer(CompatibilityAliases())))
+	assert.ErrorContains(t, err, "missing mandatory 'ear.status'")
+}
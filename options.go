@@ -0,0 +1,363 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// Hook is a caller-supplied check run against an AttestationResult at the
+// trust boundary, i.e., just before it is signed or right after it has been
+// verified.  Returning an error aborts the operation.  Hooks are meant to
+// enforce local policies (e.g., that a mandatory extension is present) and
+// should treat the supplied AttestationResult as read-only.
+type Hook func(*AttestationResult) error
+
+// SignOption configures the behaviour of AttestationResult.Sign
+type SignOption func(*signOptions)
+
+type signOptions struct {
+	beforeSign []Hook
+	afterSign  []Hook
+	keyID      string
+	jwksURL    string
+	certChain  []*x509.Certificate
+	canonical  bool
+	tsa        TimestampAuthority
+
+	evidenceDigest bool
+}
+
+func newSignOptions(opts []SignOption) *signOptions {
+	o := &signOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithBeforeSign registers a hook that is invoked once the AttestationResult
+// has been validated, and before it is signed.  Hooks can modify the
+// AttestationResult, which is validated again once they have all run, so that
+// nothing invalid is signed.  Multiple hooks are run in the order in which they
+// have been supplied.
+func WithBeforeSign(h Hook) SignOption {
+	return func(o *signOptions) {
+		o.beforeSign = append(o.beforeSign, h)
+	}
+}
+
+// WithAfterSign registers a hook that is invoked once the AttestationResult
+// has been signed, i.e., only if a token has actually been produced, which
+// makes it the place to record issuance (see AuditLog).  The hook sees the
+// claims-set as signed.  If a hook fails, the token is
+// discarded and the error returned.  Multiple hooks are run in the order in
+// which they have been supplied.
+func WithAfterSign(h Hook) SignOption {
+	return func(o *signOptions) {
+		o.afterSign = append(o.afterSign, h)
+	}
+}
+
+// signed runs the after-sign hooks against the claims-set of a token that has
+// just been produced
+func (o signOptions) signed(ar *AttestationResult) error {
+	if err := runHooks(o.afterSign, ar); err != nil {
+		return fmt.Errorf("after-sign hook: %w", err)
+	}
+
+	return nil
+}
+
+// WithKeyID sets the kid JWS header parameter, which allows the relying party
+// to locate the verification key.  The RFC7638 thumbprint of the key is a good
+// choice, since it can be matched without any prior coordination (see
+// VerifyWithTrustAnchors).
+func WithKeyID(kid string) SignOption {
+	return func(o *signOptions) {
+		o.keyID = kid
+	}
+}
+
+// WithJWKSURL sets the jku JWS header parameter, i.e., the URL of the JWKS
+// from which the verification key can be obtained.
+func WithJWKSURL(u string) SignOption {
+	return func(o *signOptions) {
+		o.jwksURL = u
+	}
+}
+
+// WithCertChain embeds the supplied X.509 certificate chain in the x5c JWS
+// header parameter, so that the signing key can be anchored in a PKI (see
+// VerifyWithCertChain).  The first certificate must be the one containing the
+// signing key, and each following certificate must certify the preceding one.
+func WithCertChain(chain ...*x509.Certificate) SignOption {
+	return func(o *signOptions) {
+		o.certChain = append(o.certChain, chain...)
+	}
+}
+
+// WithCanonicalJSON makes Sign use MarshalCanonicalJSON to encode the payload,
+// so that identical claims-sets yield byte-identical payloads, as needed for
+// transparency-log style auditing.
+func WithCanonicalJSON() SignOption {
+	return func(o *signOptions) {
+		o.canonical = true
+	}
+}
+
+// VerifyOption configures the behaviour of AttestationResult.Verify
+type VerifyOption func(*verifyOptions)
+
+type verifyOptions struct {
+	afterVerify []Hook
+	report      *VerificationReport
+	frozen      *FrozenResult
+	tsv         TimestampVerifier
+	quarantine  QuarantineSink
+	decode      []DecodeOption
+	now         func() time.Time
+	skew        time.Duration
+	maxAge      time.Duration
+	maxSize     int
+
+	strictAlg bool
+	algs      []jwa.SignatureAlgorithm
+
+	// profiles, if set, restricts the accepted eat_profile values further
+	// than the registry does (see CachedVerifier)
+	profiles []string
+}
+
+func newVerifyOptions(opts []VerifyOption) *verifyOptions {
+	o := &verifyOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithAfterVerify registers a hook that is invoked once the token signature
+// has been successfully verified and the AttestationResult has been populated
+// from its claims-set.  Multiple hooks are run in the order in which they have
+// been supplied.
+func WithAfterVerify(h Hook) VerifyOption {
+	return func(o *verifyOptions) {
+		o.afterVerify = append(o.afterVerify, h)
+	}
+}
+
+// WithClock sets the clock against which the exp, nbf and iat claims are
+// checked
+func WithClock(now func() time.Time) VerifyOption {
+	return func(o *verifyOptions) {
+		o.now = now
+	}
+}
+
+// WithAcceptableSkew sets the tolerance allowed when checking the exp and nbf
+// claims, and the maximum token age, to accommodate clock differences between
+// verifier and relying party.
+func WithAcceptableSkew(skew time.Duration) VerifyOption {
+	return func(o *verifyOptions) {
+		o.skew = skew
+	}
+}
+
+// WithMaxTokenAge rejects results that have been issued (as per their iat
+// claim) more than maxAge ago.  This is a freshness backstop for results that
+// carry no exp claim, or an overly generous one.
+func WithMaxTokenAge(maxAge time.Duration) VerifyOption {
+	return func(o *verifyOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// checkValidity checks the exp and nbf claims, if present, against the
+// supplied time
+func (o AttestationResult) checkValidity(now time.Time, skew time.Duration) error {
+	if o.Expiry != nil && !now.Before(time.Unix(*o.Expiry, 0).Add(skew)) {
+		return fmt.Errorf("result has expired (exp: %d)", *o.Expiry)
+	}
+
+	if o.NotBefore != nil && now.Add(skew).Before(time.Unix(*o.NotBefore, 0)) {
+		return fmt.Errorf("result is not yet valid (nbf: %d)", *o.NotBefore)
+	}
+
+	return nil
+}
+
+// checkAge makes sure that the result has been issued no longer than maxAge
+// ago.  A zero maxAge disables the check.
+func (o AttestationResult) checkAge(now time.Time, maxAge, skew time.Duration) error {
+	if maxAge == 0 || o.IssuedAt == nil {
+		return nil
+	}
+
+	if now.Sub(time.Unix(*o.IssuedAt, 0)) > maxAge+skew {
+		return fmt.Errorf("result is too old (iat: %d, max age: %s)", *o.IssuedAt, maxAge)
+	}
+
+	return nil
+}
+
+// WithMaxTokenSize rejects tokens larger than maxSize bytes before any
+// parsing takes place, so that services exposed to untrusted input are not
+// made to allocate memory for bogus multi-megabyte tokens.  A zero maxSize
+// (the default) disables the check.
+func WithMaxTokenSize(maxSize int) VerifyOption {
+	return func(o *verifyOptions) {
+		o.maxSize = maxSize
+	}
+}
+
+func (o verifyOptions) checkTokenSize(data []byte) error {
+	if o.maxSize > 0 && len(data) > o.maxSize {
+		return fmt.Errorf("%w (%d bytes, maximum is %d)", ErrTokenTooLarge, len(data), o.maxSize)
+	}
+	return nil
+}
+
+// WithStrictAlgorithm makes Verify, VerifyCWT and VerifySD fail with an
+// AlgorithmError if the alg in the protected header of the token differs from
+// the algorithm supplied by the caller (or, for CWTs, from the algorithm of
+// the cose.Verifier), rather than relying on the signature check failing.
+func WithStrictAlgorithm() VerifyOption {
+	return func(o *verifyOptions) {
+		o.strictAlg = true
+	}
+}
+
+// WithAllowedAlgorithms makes Verify, VerifyCWT and VerifySD fail with an
+// AlgorithmError if the alg in the protected header of the token is not among
+// the supplied ones.  COSE algorithms are matched by their JOSE name (e.g.,
+// "ES256").  It can be combined with WithStrictAlgorithm, in which case the
+// alg must satisfy both.
+func WithAllowedAlgorithms(algs ...jwa.SignatureAlgorithm) VerifyOption {
+	return func(o *verifyOptions) {
+		o.algs = append(o.algs, algs...)
+	}
+}
+
+// checkAlgorithm checks the JWS alg of the token against the caller's
+// expectations, if any
+func (o verifyOptions) checkAlgorithm(data []byte, alg jwa.KeyAlgorithm) error {
+	if !o.strictAlg && len(o.algs) == 0 {
+		return nil
+	}
+
+	hdrs, err := protectedHeaders(data)
+	if err != nil {
+		return err
+	}
+
+	return o.checkAlgorithmName(hdrs.Algorithm().String(), alg.String())
+}
+
+// checkAlgorithmName checks the alg received in the protected header of a
+// token against the caller's expectations, if any
+func (o verifyOptions) checkAlgorithmName(received, expected string) error {
+	if o.strictAlg && received != expected {
+		return AlgorithmError{Received: received, Accepted: []string{expected}}
+	}
+
+	if len(o.algs) > 0 {
+		accepted := make([]string, 0, len(o.algs))
+		for _, a := range o.algs {
+			accepted = append(accepted, a.String())
+		}
+
+		if !contains(accepted, received) {
+			return AlgorithmError{Received: received, Accepted: accepted}
+		}
+	}
+
+	return nil
+}
+
+// WithDecodeOptions supplies the DecodeOptions used to turn the verified
+// claims-set into an AttestationResult.
+func WithDecodeOptions(opts ...DecodeOption) VerifyOption {
+	return func(o *verifyOptions) {
+		o.decode = append(o.decode, opts...)
+	}
+}
+
+// DecodeOption configures how a decoded claims-set is turned into an
+// AttestationResult by DecodeJSON, DecodeCBOR, and (via WithDecodeOptions)
+// Verify and VerifyCWT.
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	normalizer *Normalizer
+	duplicate  DuplicateClaimHandler
+	unknown    UnknownClaimsMode
+	registry   *Registry
+	decrypt    func(map[string]interface{}) error
+
+	maxRawEvidence int
+}
+
+func newDecodeOptions(opts []DecodeOption) *decodeOptions {
+	o := &decodeOptions{duplicate: RejectDuplicateClaims}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithNormalizer rewrites the claims-set using the supplied Normalizer before
+// it is parsed, so that verifier-specific quirks are mapped onto the canonical
+// form.
+func WithNormalizer(n *Normalizer) DecodeOption {
+	return func(o *decodeOptions) {
+		o.normalizer = n
+	}
+}
+
+// UnknownClaimsMode determines how claims that are not modelled by
+// AttestationResult are dealt with when decoding a claims-set
+type UnknownClaimsMode int
+
+const (
+	// UnknownClaimsDrop silently discards unknown claims (the default)
+	UnknownClaimsDrop UnknownClaimsMode = iota
+	// UnknownClaimsReject fails decoding if any unknown claim is present
+	UnknownClaimsReject
+	// UnknownClaimsPreserve stores unknown claims in the UnknownClaims map
+	// of the AttestationResult, from where they are re-emitted on encoding
+	UnknownClaimsPreserve
+)
+
+// WithUnknownClaims sets the treatment of unknown top-level claims.  Note
+// that the JWT registered claims other than iat, exp and nbf (e.g., iss) are
+// never considered.
+func WithUnknownClaims(mode UnknownClaimsMode) DecodeOption {
+	return func(o *decodeOptions) {
+		o.unknown = mode
+	}
+}
+
+func runHooks(hooks []Hook, ar *AttestationResult) error {
+	for _, h := range hooks {
+		if err := h(ar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithMaxRawEvidenceSize rejects claims-sets whose ear.raw-evidence is larger
+// than maxSize bytes (once decoded).  The check is made before the evidence is
+// decoded, so that the memory used by relying parties is bounded.  A zero
+// maxSize (the default) disables the check.
+func WithMaxRawEvidenceSize(maxSize int) DecodeOption {
+	return func(o *decodeOptions) {
+		o.maxRawEvidence = maxSize
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requireTeeInfo(ar *AttestationResult) error {
+	if ar.VeraisonTeeInfo == nil {
+		return errors.New(`missing mandatory "ear.veraison.tee-info"`)
+	}
+	return nil
+}
+
+func TestSign_WithBeforeSign_fail(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	_, err = testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK,
+		WithBeforeSign(requireTeeInfo))
+	assert.EqualError(t, err, `before-sign hook: missing mandatory "ear.veraison.tee-info"`)
+}
+
+func TestSign_WithBeforeSign_ok(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	var calls []string
+
+	first := func(*AttestationResult) error {
+		calls = append(calls, "first")
+		return nil
+	}
+
+	second := func(*AttestationResult) error {
+		calls = append(calls, "second")
+		return nil
+	}
+
+	_, err = testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK,
+		WithBeforeSign(first), WithBeforeSign(second))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, calls)
+}
+
+func TestSign_WithBeforeSign_not_called_on_invalid(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	called := false
+	hook := func(*AttestationResult) error {
+		called = true
+		return nil
+	}
+
+	var ar AttestationResult
+
+	_, err = ar.Sign(jwa.ES256, sigK, WithBeforeSign(hook))
+	assert.Error(t, err)
+	assert.False(t, called)
+}
+
+func TestSign_WithBeforeSign_changes_validated(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	dropSubmods := func(ar *AttestationResult) error {
+		ar.Submods = nil
+		return nil
+	}
+
+	ar := deepCopy(testAttestationResultsWithVeraisonExtns)
+
+	_, err = ar.Sign(jwa.ES256, sigK, WithBeforeSign(dropSubmods))
+	assert.ErrorContains(t, err, "before-sign hook: ")
+
+	var ve *ValidationError
+	assert.ErrorAs(t, err, &ve)
+
+	signer, _ := testCOSESignerVerifier(t)
+
+	_, err = ar.SignCWT(signer, WithBeforeSign(dropSubmods))
+	assert.ErrorContains(t, err, "before-sign hook: ")
+}
+
+func TestSign_WithAfterSign(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	signer, _ := testCOSESignerVerifier(t)
+
+	cs, err := NewCachedSigner(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	ar := testAttestationResultsWithVeraisonExtns
+
+	tvs := []func(Hook) error{
+		func(h Hook) error {
+			_, err := ar.Sign(jwa.ES256, sigK, WithAfterSign(h))
+			return err
+		},
+		func(h Hook) error {
+			_, err := ar.SignCWT(signer, WithAfterSign(h))
+			return err
+		},
+		func(h Hook) error {
+			_, err := ar.SignSD(jwa.ES256, sigK, nil, WithAfterSign(h))
+			return err
+		},
+		func(h Hook) error {
+			_, err := ar.SignAll(map[MediaType]SignerConfig{
+				MediaTypeJWT: {Alg: jwa.ES256, Key: sigK},
+				MediaTypeCWT: {COSESigner: signer},
+			}, WithAfterSign(h))
+			return err
+		},
+		func(h Hook) error {
+			cs.opts.afterSign = []Hook{h}
+			_, err := cs.Sign(ar)
+			return err
+		},
+	}
+
+	for i, sign := range tvs {
+		calls := 0
+		count := func(*AttestationResult) error {
+			calls++
+			return nil
+		}
+
+		assert.NoError(t, sign(count), "failed test vector at index %d", i)
+		assert.Equal(t, 1, calls, "failed test vector at index %d", i)
+
+		reject := func(*AttestationResult) error { return errors.New("no thanks") }
+
+		assert.EqualError(t, sign(reject), "after-sign hook: no thanks",
+			"failed test vector at index %d", i)
+	}
+}
+
+func TestSign_WithAfterSign_not_called_on_failure(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	called := false
+	hook := func(*AttestationResult) error {
+		called = true
+		return nil
+	}
+
+	ar := testAttestationResultsWithVeraisonExtns
+
+	// rejected by a before-sign hook
+	_, err = ar.Sign(jwa.ES256, sigK, WithBeforeSign(requireTeeInfo), WithAfterSign(hook))
+	assert.Error(t, err)
+
+	// key and algorithm mismatch
+	_, err = ar.Sign(jwa.EdDSA, sigK, WithAfterSign(hook))
+	assert.Error(t, err)
+
+	// no claim matches the pointer
+	_, err = ar.SignSD(jwa.ES256, sigK, []string{"/submods/nope"}, WithAfterSign(hook))
+	assert.Error(t, err)
+
+	assert.False(t, called)
+}
+
+func TestVerify_WithAfterVerify(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.Verify(token, jwa.ES256, vfyK, WithAfterVerify(requireTeeInfo))
+	assert.EqualError(t, err, `after-verify hook: missing mandatory "ear.veraison.tee-info"`)
+
+	var seen *AttestationResult
+	hook := func(ar *AttestationResult) error {
+		seen = ar
+		return nil
+	}
+
+	err = ar.Verify(token, jwa.ES256, vfyK, WithAfterVerify(hook))
+	assert.NoError(t, err)
+	assert.Same(t, &ar, seen)
+}
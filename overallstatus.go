@@ -0,0 +1,105 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+// OverallStatusOption configures how OverallStatus folds the statuses of the
+// submods
+type OverallStatusOption func(*overallStatusOptions)
+
+type overallStatusOptions struct {
+	exclude    map[string]bool
+	ignoreNone bool
+	noneAs     *TrustTier
+}
+
+func newOverallStatusOptions(opts []OverallStatusOption) *overallStatusOptions {
+	o := &overallStatusOptions{exclude: map[string]bool{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithExcludedSubmods excludes the named submods from the overall status
+func WithExcludedSubmods(submods ...string) OverallStatusOption {
+	return func(o *overallStatusOptions) {
+		for _, s := range submods {
+			o.exclude[s] = true
+		}
+	}
+}
+
+// WithNoneStatusIgnored makes OverallStatus skip the submods whose status is
+// TrustTierNone, i.e., those the verifier could not appraise
+func WithNoneStatusIgnored() OverallStatusOption {
+	return func(o *overallStatusOptions) {
+		o.ignoreNone = true
+	}
+}
+
+// WithNoneStatusAs makes OverallStatus treat the submods whose status is
+// TrustTierNone as if their status was the supplied tier (e.g.,
+// TrustTierWarning)
+func WithNoneStatusAs(tier TrustTier) OverallStatusOption {
+	return func(o *overallStatusOptions) {
+		o.noneAs = &tier
+	}
+}
+
+// StatusMap returns the ear.status of each submod, indexed by submod name.  A
+// submod with no status is reported as TrustTierNone.
+func (o AttestationResult) StatusMap() map[string]TrustTier {
+	m := make(map[string]TrustTier, len(o.Submods))
+
+	for name, a := range o.Submods {
+		if a == nil || a.Status == nil {
+			m[name] = TrustTierNone
+			continue
+		}
+		m[name] = *a.Status
+	}
+
+	return m
+}
+
+// OverallStatus folds the statuses of all submods into the worst one, using
+// the ordering implemented by TrustTier.Compare.  By default, a submod with
+// status TrustTierNone is worse than any appraised one, hence makes the overall
+// status TrustTierNone: use WithNoneStatusIgnored or WithNoneStatusAs to
+// change that.  TrustTierNone is also returned if no submod is taken into
+// account.
+func (o AttestationResult) OverallStatus(opts ...OverallStatusOption) TrustTier {
+	oo := newOverallStatusOptions(opts)
+
+	var (
+		overall TrustTier
+		found   bool
+	)
+
+	for name, status := range o.StatusMap() {
+		if oo.exclude[name] {
+			continue
+		}
+
+		if status == TrustTierNone {
+			if oo.ignoreNone {
+				continue
+			}
+			if oo.noneAs != nil {
+				status = *oo.noneAs
+			}
+		}
+
+		if !found || status.Compare(overall) < 0 {
+			overall = status
+			found = true
+		}
+	}
+
+	if !found {
+		return TrustTierNone
+	}
+
+	return overall
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSubmodsWithStatus(statuses map[string]*TrustTier) AttestationResult {
+	ar := AttestationResult{Submods: map[string]*Appraisal{}}
+
+	for name, s := range statuses {
+		ar.Submods[name] = &Appraisal{Status: s}
+	}
+
+	return ar
+}
+
+func TestAttestationResult_StatusMap(t *testing.T) {
+	ar := testSubmodsWithStatus(map[string]*TrustTier{
+		"a": NewTrustTier(TrustTierAffirming),
+		"b": nil,
+	})
+
+	expected := map[string]TrustTier{
+		"a": TrustTierAffirming,
+		"b": TrustTierNone,
+	}
+
+	assert.Equal(t, expected, ar.StatusMap())
+}
+
+func TestAttestationResult_OverallStatus(t *testing.T) {
+	ar := testSubmodsWithStatus(map[string]*TrustTier{
+		"cpu":  NewTrustTier(TrustTierAffirming),
+		"gpu":  NewTrustTier(TrustTierWarning),
+		"nic":  NewTrustTier(TrustTierNone),
+		"disk": NewTrustTier(TrustTierContraindicated),
+	})
+
+	tvs := []struct {
+		opts     []OverallStatusOption
+		expected TrustTier
+	}{
+		{nil, TrustTierNone},
+		{[]OverallStatusOption{WithNoneStatusIgnored()}, TrustTierContraindicated},
+		{[]OverallStatusOption{WithNoneStatusIgnored(), WithExcludedSubmods("disk")}, TrustTierWarning},
+		{[]OverallStatusOption{WithExcludedSubmods("disk", "nic")}, TrustTierWarning},
+		{[]OverallStatusOption{WithExcludedSubmods("disk", "gpu"), WithNoneStatusAs(TrustTierAffirming)}, TrustTierAffirming},
+		{[]OverallStatusOption{WithExcludedSubmods("disk"), WithNoneStatusAs(TrustTierContraindicated)}, TrustTierContraindicated},
+		{[]OverallStatusOption{WithExcludedSubmods("cpu", "gpu", "nic", "disk")}, TrustTierNone},
+	}
+
+	for i, tv := range tvs {
+		assert.Equal(t, tv.expected, ar.OverallStatus(tv.opts...), "failed test vector at index %d", i)
+	}
+}
+
+func TestAttestationResult_OverallStatus_no_submods(t *testing.T) {
+	assert.Equal(t, TrustTierNone, AttestationResult{}.OverallStatus())
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package policy evaluates EARs against the acceptance rules of a relying party,
+e.g., "executables must be approved, hardware must be at least warning, and
+the verifier must come from acme.example".
+
+Policies can be built as Go values:
+
+	min := ear.TrustTierWarning
+
+	p := policy.Policy{
+		Developer: "acme.example",
+		Submods: []policy.SubmodRule{
+			{
+				Name:     "cpu",
+				Required: true,
+				TrustVector: map[string]policy.ClaimRule{
+					"executables": {OneOf: []ear.TrustClaim{ear.ApprovedRuntimeClaim, ear.ApprovedBootClaim}},
+					"hardware":    {MinTier: &min},
+				},
+			},
+		},
+	}
+
+or loaded from a JSON document using Parse:
+
+	{
+	  "developer": "acme.example",
+	  "submods": [
+	    {
+	      "name": "cpu",
+	      "required": true,
+	      "trust-vector": {
+	        "executables": { "one-of": [ "approved_rt", "approved_boot" ] },
+	        "hardware": { "min-tier": "warning" }
+	      }
+	    }
+	  ]
+	}
+
+Evaluate returns the Decision, together with the Reasons for rejecting the
+EAR, if any:
+
+	decision, reasons := p.Evaluate(&ar)
+	if decision != policy.Accept {
+		for _, r := range reasons {
+			log.Print(r)
+		}
+	}
+*/
+package policy
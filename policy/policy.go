@@ -0,0 +1,309 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/veraison/ear"
+)
+
+// AnySubmod is the name of a SubmodRule that applies to every submod in the
+// EAR
+const AnySubmod = "*"
+
+// Policy is a set of acceptance rules for EARs.  Rules that are left at their
+// zero value are not checked.
+type Policy struct {
+	// Profile, if set, is the eat_profile the EAR must have
+	Profile string `json:"profile,omitempty"`
+	// Developer, if set, is the developer of the verifier that must have
+	// issued the EAR (see ear.VerifierIdentity.Matches)
+	Developer string `json:"developer,omitempty"`
+	// MinBuild, if set, is the earliest semantic version of the verifier
+	// that is trusted (see ear.VerifierIdentity.Matches)
+	MinBuild string `json:"min-build,omitempty"`
+	// MinOverallStatus, if set, is the lowest acceptable overall status of
+	// the EAR (see ear.AttestationResult.OverallStatus)
+	MinOverallStatus *ear.TrustTier `json:"min-overall-status,omitempty"`
+	// Submods are the rules applying to individual submods
+	Submods []SubmodRule `json:"submods,omitempty"`
+}
+
+// SubmodRule holds the rules applying to the named submod, or to all the
+// submods if Name is AnySubmod
+type SubmodRule struct {
+	Name string `json:"name"`
+	// Required says that the EAR must carry an appraisal for the submod
+	Required bool `json:"required,omitempty"`
+	// MinStatus, if set, is the lowest acceptable ear.status
+	MinStatus *ear.TrustTier `json:"min-status,omitempty"`
+	// TrustVector maps trust vector claim names (e.g., "hardware") onto the
+	// rules for their values
+	TrustVector map[string]ClaimRule `json:"trust-vector,omitempty"`
+}
+
+// ClaimRule constrains the value of a trust vector claim.  A claim that is
+// absent from the EAR is treated as ear.NoClaim.
+type ClaimRule struct {
+	// MinTier, if set, is the lowest acceptable tier of the claim
+	MinTier *ear.TrustTier `json:"min-tier,omitempty"`
+	// OneOf, if not empty, lists the acceptable claim values
+	OneOf []ear.TrustClaim `json:"one-of,omitempty"`
+}
+
+// UnmarshalJSON accepts the values in "one-of" either as numbers or as the
+// names used by ear.ToTrustClaim (e.g., "approved_rt")
+func (o *ClaimRule) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		MinTier *ear.TrustTier `json:"min-tier"`
+		OneOf   []interface{}  `json:"one-of"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	o.MinTier = raw.MinTier
+	o.OneOf = nil
+
+	for _, v := range raw.OneOf {
+		switch v.(type) {
+		case string, float64:
+		default:
+			return fmt.Errorf("invalid value for %q: %v is neither a number nor a name", "one-of", v)
+		}
+
+		c, err := ear.ToTrustClaim(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for %q: %w", "one-of", err)
+		}
+
+		o.OneOf = append(o.OneOf, *c)
+	}
+
+	return nil
+}
+
+// Parse decodes a policy from its JSON representation, and validates it
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("decoding policy: %w", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// Validate checks that the policy is well-formed
+func (o Policy) Validate() error {
+	if o.MinBuild != "" {
+		if _, err := ear.ParseVersion(o.MinBuild); err != nil {
+			return fmt.Errorf("invalid value for %q: %w", "min-build", err)
+		}
+	}
+
+	seen := map[string]bool{}
+
+	for i, r := range o.Submods {
+		if r.Name == "" {
+			return fmt.Errorf("submod rule at index %d: missing name", i)
+		}
+
+		if seen[r.Name] {
+			return fmt.Errorf("submod rule %q: duplicate name", r.Name)
+		}
+		seen[r.Name] = true
+
+		if r.Name == AnySubmod && r.Required {
+			return fmt.Errorf("submod rule %q: cannot be required", r.Name)
+		}
+
+		known := ear.TrustVector{}.AsMap()
+		for claim := range r.TrustVector {
+			if _, ok := known[claim]; !ok {
+				return fmt.Errorf("submod rule %q: unknown trust vector claim %q", r.Name, claim)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Decision is the outcome of evaluating an EAR against a Policy
+type Decision int
+
+const (
+	// Reject is the zero value, so that an unset Decision fails closed
+	Reject Decision = iota
+	Accept
+)
+
+func (o Decision) String() string {
+	if o == Accept {
+		return "accept"
+	}
+	return "reject"
+}
+
+func (o Decision) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.String())
+}
+
+// Reason explains why a rule of the policy is not met
+type Reason struct {
+	// Rule identifies the rule, e.g., "verifier-id" or
+	// "submods[cpu].trust-vector.hardware"
+	Rule string `json:"rule"`
+	// Message describes the mismatch
+	Message string `json:"message"`
+}
+
+func (o Reason) String() string {
+	return fmt.Sprintf("%s: %s", o.Rule, o.Message)
+}
+
+// Evaluate checks the EAR, which is expected to have been verified, against
+// the policy.  All the rules are checked, and the EAR is accepted only if it
+// meets all of them; otherwise, a Reason is returned for each of the unmet
+// rules, in the order in which they appear in the policy.
+func (o Policy) Evaluate(ar *ear.AttestationResult) (Decision, []Reason) {
+	if ar == nil {
+		return Reject, []Reason{{Rule: "*", Message: "no attestation result"}}
+	}
+
+	var reasons []Reason
+
+	add := func(rule string, err error) {
+		reasons = append(reasons, Reason{Rule: rule, Message: err.Error()})
+	}
+
+	if o.Profile != "" {
+		if ar.Profile == nil || ar.Profile.String() != o.Profile {
+			add("profile", fmt.Errorf("expecting %q", o.Profile))
+		}
+	}
+
+	if o.Developer != "" || o.MinBuild != "" {
+		var vid ear.VerifierIdentity
+		if ar.VerifierID != nil {
+			vid = *ar.VerifierID
+		}
+
+		if err := vid.Matches(o.Developer, o.MinBuild); err != nil {
+			add("verifier-id", err)
+		}
+	}
+
+	if o.MinOverallStatus != nil {
+		if status := ar.OverallStatus(); !status.IsAtLeast(*o.MinOverallStatus) {
+			add("min-overall-status", fmt.Errorf("%s is below %s", status, *o.MinOverallStatus))
+		}
+	}
+
+	for _, r := range o.Submods {
+		if r.Name != AnySubmod {
+			prefix := fmt.Sprintf("submods[%s]", r.Name)
+
+			a, ok := ar.Submods[r.Name]
+			if !ok || a == nil {
+				if r.Required {
+					add(prefix+".required", errors.New("submod not found"))
+				}
+				continue
+			}
+
+			r.check(prefix, a, add)
+			continue
+		}
+
+		names := make([]string, 0, len(ar.Submods))
+		for name := range ar.Submods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if a := ar.Submods[name]; a != nil {
+				r.check(fmt.Sprintf("submods[%s]", name), a, add)
+			}
+		}
+	}
+
+	if len(reasons) != 0 {
+		return Reject, reasons
+	}
+
+	return Accept, nil
+}
+
+func (o SubmodRule) check(prefix string, a *ear.Appraisal, add func(string, error)) {
+	if o.MinStatus != nil {
+		status := ear.TrustTierNone
+		if a.Status != nil {
+			status = *a.Status
+		}
+
+		if !status.IsAtLeast(*o.MinStatus) {
+			add(prefix+".min-status", fmt.Errorf("%s is below %s", status, *o.MinStatus))
+		}
+	}
+
+	if len(o.TrustVector) == 0 {
+		return
+	}
+
+	var tv ear.TrustVector
+	if a.TrustVector != nil {
+		tv = *a.TrustVector
+	}
+	claims := tv.AsMap()
+
+	names := make([]string, 0, len(o.TrustVector))
+	for name := range o.TrustVector {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := o.TrustVector[name].check(claims[name]); err != nil {
+			add(prefix+".trust-vector."+name, err)
+		}
+	}
+}
+
+func (o ClaimRule) check(c ear.TrustClaim) error {
+	if o.MinTier != nil {
+		if tier := c.GetTier(); !tier.IsAtLeast(*o.MinTier) {
+			return fmt.Errorf("%d (%s) is below %s", c, tier, *o.MinTier)
+		}
+	}
+
+	if len(o.OneOf) == 0 {
+		return nil
+	}
+
+	for _, v := range o.OneOf {
+		if c == v {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%d is not one of %v", c, o.OneOf)
+}
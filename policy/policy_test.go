@@ -0,0 +1,182 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+var testPolicyJSON = []byte(`{
+	"profile": "tag:github.com,2023:veraison/ear",
+	"developer": "acme.example",
+	"min-build": "1.2.0",
+	"min-overall-status": "warning",
+	"submods": [
+		{
+			"name": "cpu",
+			"required": true,
+			"trust-vector": {
+				"executables": { "one-of": [ "approved_rt", 3 ] },
+				"hardware": { "min-tier": "warning" }
+			}
+		},
+		{
+			"name": "*",
+			"min-status": "warning"
+		}
+	]
+}`)
+
+func testResult() *ear.AttestationResult {
+	ar := ear.NewAttestationResult("cpu", "rrtrap-v1.3.0", "acme.example")
+
+	a := ar.Submods["cpu"]
+	affirming := ear.TrustTierAffirming
+	a.Status = &affirming
+	a.TrustVector = &ear.TrustVector{
+		Executables: ear.ApprovedBootClaim,
+		Hardware:    ear.GenuineHardwareClaim,
+	}
+
+	return ar
+}
+
+func TestParse_ok(t *testing.T) {
+	p, err := Parse(testPolicyJSON)
+	require.NoError(t, err)
+
+	warning := ear.TrustTierWarning
+
+	expected := Policy{
+		Profile:          ear.EatProfile,
+		Developer:        "acme.example",
+		MinBuild:         "1.2.0",
+		MinOverallStatus: &warning,
+		Submods: []SubmodRule{
+			{
+				Name:     "cpu",
+				Required: true,
+				TrustVector: map[string]ClaimRule{
+					"executables": {OneOf: []ear.TrustClaim{ear.ApprovedRuntimeClaim, ear.ApprovedBootClaim}},
+					"hardware":    {MinTier: &warning},
+				},
+			},
+			{Name: AnySubmod, MinStatus: &warning},
+		},
+	}
+
+	assert.Equal(t, expected, *p)
+}
+
+func TestParse_ko(t *testing.T) {
+	tvs := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    `{"developr": "acme.example"}`,
+			expected: `decoding policy: json: unknown field "developr"`,
+		},
+		{
+			input:    `{"min-build": "latest"}`,
+			expected: `invalid value for "min-build": "latest" is not in MAJOR.MINOR.PATCH format`,
+		},
+		{
+			input:    `{"submods": [{"required": true}]}`,
+			expected: `submod rule at index 0: missing name`,
+		},
+		{
+			input:    `{"submods": [{"name": "a"}, {"name": "a"}]}`,
+			expected: `submod rule "a": duplicate name`,
+		},
+		{
+			input:    `{"submods": [{"name": "*", "required": true}]}`,
+			expected: `submod rule "*": cannot be required`,
+		},
+		{
+			input:    `{"submods": [{"name": "a", "trust-vector": {"firmware": {}}}]}`,
+			expected: `submod rule "a": unknown trust vector claim "firmware"`,
+		},
+		{
+			input:    `{"submods": [{"name": "a", "trust-vector": {"hardware": {"one-of": ["shiny"]}}}]}`,
+			expected: `decoding policy: invalid value for "one-of": not a valid TrustClaim value: "shiny"`,
+		},
+		{
+			input:    `{"submods": [{"name": "a", "trust-vector": {"hardware": {"one-of": [true]}}}]}`,
+			expected: `decoding policy: invalid value for "one-of": true is neither a number nor a name`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := Parse([]byte(tv.input))
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestEvaluate_accept(t *testing.T) {
+	p, err := Parse(testPolicyJSON)
+	require.NoError(t, err)
+
+	decision, reasons := p.Evaluate(testResult())
+	assert.Equal(t, Accept, decision)
+	assert.Empty(t, reasons)
+
+	decision, reasons = Policy{}.Evaluate(testResult())
+	assert.Equal(t, Accept, decision)
+	assert.Empty(t, reasons)
+}
+
+func TestEvaluate_reject(t *testing.T) {
+	p, err := Parse(testPolicyJSON)
+	require.NoError(t, err)
+
+	ar := testResult()
+	build, developer := "rrtrap-v1.1.0", "evil.example"
+	ar.VerifierID.Build = &build
+	ar.VerifierID.Developer = &developer
+
+	cpu := ar.Submods["cpu"]
+	cpu.TrustVector.Executables = ear.UnrecognizedRuntimeClaim
+	cpu.TrustVector.Hardware = ear.ContraindicatedHardwareClaim
+
+	ar.Submods["gpu"] = ear.NewAppraisal(ear.TrustTierNone)
+
+	decision, reasons := p.Evaluate(ar)
+	assert.Equal(t, Reject, decision)
+
+	expected := []Reason{
+		{Rule: "verifier-id", Message: `developer: expecting "acme.example", found "evil.example"`},
+		{Rule: "min-overall-status", Message: "none is below warning"},
+		{Rule: "submods[cpu].trust-vector.executables", Message: "33 is not one of [2 3]"},
+		{Rule: "submods[cpu].trust-vector.hardware", Message: "96 (contraindicated) is below warning"},
+		{Rule: "submods[gpu].min-status", Message: "none is below warning"},
+	}
+	assert.Equal(t, expected, reasons)
+}
+
+func TestEvaluate_required_submod(t *testing.T) {
+	p := Policy{Submods: []SubmodRule{{Name: "tpm", Required: true}, {Name: "dpu"}}}
+
+	decision, reasons := p.Evaluate(testResult())
+	assert.Equal(t, Reject, decision)
+	assert.Equal(t, []Reason{{Rule: "submods[tpm].required", Message: "submod not found"}}, reasons)
+	assert.Equal(t, "submods[tpm].required: submod not found", reasons[0].String())
+}
+
+func TestEvaluate_nil(t *testing.T) {
+	decision, reasons := Policy{}.Evaluate(nil)
+	assert.Equal(t, Reject, decision)
+	assert.Len(t, reasons, 1)
+}
+
+func TestDecision_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]Decision{"a": Accept, "r": Reject})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": "accept", "r": "reject"}`, string(data))
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// PolicyID is the identifier of an appraisal policy, which must be an
+// absolute URI (RFC 3986)
+type PolicyID string
+
+// NewPolicyID returns a PolicyID after checking that the supplied string is an
+// absolute URI
+func NewPolicyID(id string) (PolicyID, error) {
+	if err := checkPolicyID(id); err != nil {
+		return "", err
+	}
+
+	return PolicyID(id), nil
+}
+
+// Scheme returns the scheme of the policy URI (e.g., "https"), or an empty
+// string if the PolicyID is not a valid URI
+func (o PolicyID) Scheme() string {
+	u, err := url.Parse(string(o))
+	if err != nil {
+		return ""
+	}
+
+	return u.Scheme
+}
+
+// Authority returns the authority component of the policy URI, i.e., the host
+// optionally preceded by user information and followed by a port.  An empty
+// string is returned if the URI has no authority (e.g., "urn:" URIs) or if
+// the PolicyID is not a valid URI.
+func (o PolicyID) Authority() string {
+	u, err := url.Parse(string(o))
+	if err != nil {
+		return ""
+	}
+
+	if u.User != nil {
+		return u.User.String() + "@" + u.Host
+	}
+
+	return u.Host
+}
+
+func (o PolicyID) String() string {
+	return string(o)
+}
+
+// PolicyIDs represents the ear.appraisal-policy-id claim, which can carry
+// either a single policy identifier or an array of them, when an appraisal
+// has been carried out using several policies.  A single identifier is
+// serialized as a string, multiple identifiers as an array.
+type PolicyIDs []PolicyID
+
+// NewPolicyIDs returns a PolicyIDs object containing the supplied policy
+// identifiers, or an error if any of them is not an absolute URI
+func NewPolicyIDs(ids ...string) (*PolicyIDs, error) {
+	var o PolicyIDs
+
+	for _, id := range ids {
+		if err := o.Add(id); err != nil {
+			return nil, err
+		}
+	}
+
+	return &o, nil
+}
+
+// Add appends the supplied policy identifier, after checking it
+func (o *PolicyIDs) Add(id string) error {
+	p, err := NewPolicyID(id)
+	if err != nil {
+		return err
+	}
+
+	*o = append(*o, p)
+
+	return nil
+}
+
+// Contains tells whether the supplied policy identifier is among those in the
+// claim
+func (o PolicyIDs) Contains(id string) bool {
+	for _, p := range o {
+		if string(p) == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o PolicyIDs) MarshalJSON() ([]byte, error) {
+	if len(o) == 1 {
+		return json.Marshal(o[0])
+	}
+
+	return json.Marshal([]PolicyID(o))
+}
+
+func (o *PolicyIDs) UnmarshalJSON(data []byte) error {
+	var v interface{}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	p, err := ToPolicyIDs(v)
+	if err != nil {
+		return err
+	}
+
+	*o = *p
+
+	return nil
+}
+
+// ToPolicyIDs converts the value of an ear.appraisal-policy-id claim, i.e., a
+// string or an array of strings, into a PolicyIDs object.  Each identifier
+// must be an absolute URI.
+func ToPolicyIDs(v interface{}) (*PolicyIDs, error) {
+	var ids []string
+
+	switch t := v.(type) {
+	case string:
+		ids = []string{t}
+	case []string:
+		ids = t
+	case []interface{}:
+		for i, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("policy ID at index %d is not a string", i)
+			}
+			ids = append(ids, s)
+		}
+	default:
+		return nil, errors.New("neither a string nor an array of strings")
+	}
+
+	o := make(PolicyIDs, 0, len(ids))
+
+	for i, id := range ids {
+		if err := o.Add(id); err != nil {
+			if len(ids) == 1 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("policy ID at index %d: %w", i, err)
+		}
+	}
+
+	return &o, nil
+}
+
+func checkPolicyID(id string) error {
+	if err := checkFreeText(id); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(id)
+	if err != nil {
+		return fmt.Errorf("not a URI: %w", err)
+	}
+
+	if u.Scheme == "" {
+		return fmt.Errorf("not an absolute URI: %q", id)
+	}
+
+	return nil
+}
+
+func (o PolicyIDs) validate(ve *ValidationError) {
+	if len(o) == 0 {
+		ve.addInvalid("ear.appraisal-policy-id", "ear.appraisal-policy-id (empty)", nil)
+		return
+	}
+
+	for i, p := range o {
+		err := checkPolicyID(string(p))
+		if err == nil {
+			continue
+		}
+
+		if len(o) == 1 {
+			ve.addInvalid("ear.appraisal-policy-id",
+				fmt.Sprintf("ear.appraisal-policy-id (%s)", err), err)
+		} else {
+			ve.addInvalid("ear.appraisal-policy-id",
+				fmt.Sprintf("ear.appraisal-policy-id[%d] (%s)", i, err), err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyID_accessors(t *testing.T) {
+	tvs := []struct {
+		id        string
+		scheme    string
+		authority string
+	}{
+		{"https://veraison.example/policy/1", "https", "veraison.example"},
+		{"https://user@veraison.example:8443/policy", "https", "user@veraison.example:8443"},
+		{"policy://test/01234", "policy", "test"},
+		{"urn:uuid:1a4df345-b512-4f3b-8461-967de7f60eca", "urn", ""},
+	}
+
+	for i, tv := range tvs {
+		p, err := NewPolicyID(tv.id)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.scheme, p.Scheme(), "failed test vector at index %d", i)
+		assert.Equal(t, tv.authority, p.Authority(), "failed test vector at index %d", i)
+	}
+}
+
+func TestNewPolicyID_fail(t *testing.T) {
+	tvs := []struct {
+		id       string
+		expected string
+	}{
+		{"", `not an absolute URI: ""`},
+		{"policy/1", `not an absolute URI: "policy/1"`},
+		{"https://veraison.example/%zz", `not a URI: parse "https://veraison.example/%zz": invalid URL escape "%zz"`},
+		{"policy://test\x00", "contains control character U+0000"},
+	}
+
+	for i, tv := range tvs {
+		_, err := NewPolicyID(tv.id)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestPolicyIDs_JSON_roundtrip(t *testing.T) {
+	tvs := []struct {
+		ids      []string
+		expected string
+	}{
+		{[]string{"policy://test/1"}, `"policy://test/1"`},
+		{[]string{"policy://test/1", "policy://test/2"}, `["policy://test/1","policy://test/2"]`},
+	}
+
+	for i, tv := range tvs {
+		p, err := NewPolicyIDs(tv.ids...)
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		data, err := json.Marshal(p)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.JSONEq(t, tv.expected, string(data), "failed test vector at index %d", i)
+
+		var actual PolicyIDs
+		require.NoError(t, json.Unmarshal(data, &actual), "failed test vector at index %d", i)
+		assert.Equal(t, *p, actual, "failed test vector at index %d", i)
+	}
+}
+
+func TestPolicyIDs_UnmarshalJSON_fail(t *testing.T) {
+	tvs := []struct {
+		data     string
+		expected string
+	}{
+		{`"policy/1"`, `not an absolute URI: "policy/1"`},
+		{`["policy://test/1", "policy/2"]`, `policy ID at index 1: not an absolute URI: "policy/2"`},
+		{`["policy://test/1", 2]`, "policy ID at index 1 is not a string"},
+		{`{}`, "neither a string nor an array of strings"},
+	}
+
+	for i, tv := range tvs {
+		var p PolicyIDs
+		err := json.Unmarshal([]byte(tv.data), &p)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestPolicyIDs_Contains(t *testing.T) {
+	p, err := NewPolicyIDs("policy://test/1", "policy://test/2")
+	require.NoError(t, err)
+
+	assert.True(t, p.Contains("policy://test/2"))
+	assert.False(t, p.Contains("policy://test/3"))
+}
+
+func TestAppraisal_validate_policy_ids(t *testing.T) {
+	tvs := []struct {
+		ids      PolicyIDs
+		expected string
+	}{
+		{PolicyIDs{}, "invalid value(s) for ear.appraisal-policy-id (empty)"},
+		{PolicyIDs{"policy/1"}, `invalid value(s) for ear.appraisal-policy-id (not an absolute URI: "policy/1")`},
+		{
+			PolicyIDs{"policy://test/1", "policy/2"},
+			`invalid value(s) for ear.appraisal-policy-id[1] (not an absolute URI: "policy/2")`,
+		},
+	}
+
+	for i, tv := range tvs {
+		ids := tv.ids
+		a := Appraisal{Status: &testStatus, AppraisalPolicyID: &ids}
+		assert.EqualError(t, a.validate(), tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Profile is the value of the eat_profile claim, which is either a URI (e.g.,
+// EatProfile) or an OID.  In JSON, an OID is represented in dotted-decimal
+// form (e.g., "1.2.3.4.5"); in CBOR, as a byte string containing the BER
+// encoding of the OID, without tag and length, as per EAT.
+type Profile string
+
+// NewProfile returns a Profile with the supplied value, which must be either an
+// absolute URI or an OID in dotted-decimal form
+func NewProfile(v string) (*Profile, error) {
+	p := Profile(v)
+
+	if !p.IsOID() && !p.IsURI() {
+		return nil, fmt.Errorf("profile %q: neither an absolute URI nor an OID", v)
+	}
+
+	return &p, nil
+}
+
+// IsOID returns true if the profile is an OID in dotted-decimal form
+func (o Profile) IsOID() bool {
+	return oidRE.MatchString(string(o))
+}
+
+// IsURI returns true if the profile is an absolute URI
+func (o Profile) IsURI() bool {
+	if o.IsOID() {
+		return false
+	}
+
+	u, err := url.Parse(string(o))
+
+	return err == nil && u.IsAbs()
+}
+
+func (o Profile) String() string {
+	return string(o)
+}
+
+// ToProfile parses the value of the eat_profile claim.  Whether the profile
+// is supported is checked on validation.
+func ToProfile(v interface{}) (*Profile, error) {
+	switch t := v.(type) {
+	case string:
+		p := Profile(t)
+		return &p, nil
+	case Profile:
+		return &t, nil
+	default:
+		return nil, fmt.Errorf("expecting string, found %T", v)
+	}
+}
+
+// oidToBytes returns the BER encoding of the contents of the supplied
+// dotted-decimal OID
+func oidToBytes(oid string) ([]byte, error) {
+	if !oidRE.MatchString(oid) {
+		return nil, fmt.Errorf("%q is not an OID", oid)
+	}
+
+	var arcs []uint64
+
+	for _, a := range strings.Split(oid, ".") {
+		n, err := strconv.ParseUint(a, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("OID %q: %w", oid, err)
+		}
+		arcs = append(arcs, n)
+	}
+
+	if arcs[0] < 2 && arcs[1] > 39 {
+		return nil, fmt.Errorf("OID %q: second arc out of range", oid)
+	}
+
+	if arcs[1] > math.MaxUint64-80 {
+		return nil, fmt.Errorf("OID %q: second arc out of range", oid)
+	}
+
+	var ret []byte
+
+	for _, n := range append([]uint64{arcs[0]*40 + arcs[1]}, arcs[2:]...) {
+		var enc []byte
+		for {
+			enc = append([]byte{byte(n & 0x7f)}, enc...)
+			n >>= 7
+			if n == 0 {
+				break
+			}
+		}
+		for i := 0; i < len(enc)-1; i++ {
+			enc[i] |= 0x80
+		}
+		ret = append(ret, enc...)
+	}
+
+	return ret, nil
+}
+
+// oidFromBytes is the inverse of oidToBytes
+func oidFromBytes(b []byte) (string, error) {
+	var (
+		arcs []string
+		n    uint64
+	)
+
+	if len(b) == 0 {
+		return "", errors.New("empty OID")
+	}
+
+	for i, c := range b {
+		if n == 0 && c == 0x80 {
+			return "", errors.New("non-minimal OID encoding")
+		}
+
+		if n > math.MaxUint64>>7 {
+			return "", errors.New("OID arc overflow")
+		}
+
+		n = n<<7 | uint64(c&0x7f)
+
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return "", errors.New("truncated OID")
+			}
+			continue
+		}
+
+		if arcs == nil {
+			switch {
+			case n < 40:
+				arcs = []string{"0", strconv.FormatUint(n, 10)}
+			case n < 80:
+				arcs = []string{"1", strconv.FormatUint(n-40, 10)}
+			default:
+				arcs = []string{"2", strconv.FormatUint(n-80, 10)}
+			}
+		} else {
+			arcs = append(arcs, strconv.FormatUint(n, 10))
+		}
+
+		n = 0
+	}
+
+	return strings.Join(arcs, "."), nil
+}
+
+// ProfileValidator performs the checks that are specific to an EAT profile, in
+// addition to those made for all AttestationResults.  It must treat the
+// supplied AttestationResult as read-only.
+type ProfileValidator func(*AttestationResult) error
+
+var oidRE = regexp.MustCompile(`^[0-2](\.(0|[1-9][0-9]*))+$`)
+
+// RegisterProfile adds an eat_profile value to those accepted when validating
+// AttestationResults, together with any validators specific to the profile.
+// The profile identifier must be either an absolute URI (e.g., a "tag:" URI)
+// or an OID in dotted-decimal form.  This allows forks and future revisions of
+// the EAR profile to be supported without patching this package.  Profiles
+// cannot be registered twice.  Registration is meant to happen at
+// initialization time.  Use a Registry to confine profiles to some of the
+// AttestationResults.
+func RegisterProfile(id string, validators ...ProfileValidator) error {
+	return defaultRegistry.RegisterProfile(id, validators...)
+}
+
+// validateProfile runs the validators registered for the profile of the
+// AttestationResult
+func (o AttestationResult) validateProfile(ve *ValidationError) {
+	for _, v := range o.registry().profileValidators(string(*o.Profile)) {
+		if err := v(&o); err != nil {
+			ve.addInvalid("eat_profile", fmt.Sprintf("eat_profile (%s: %s)", *o.Profile, err), err)
+		}
+	}
+}
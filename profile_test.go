@@ -0,0 +1,186 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testForkProfile = "tag:example.com,2024:ear-fork"
+
+func requireNonce(ar *AttestationResult) error {
+	if ar.Nonce == nil {
+		return errors.New("eat_nonce is mandatory")
+	}
+	return nil
+}
+
+func TestRegisterProfile_ok(t *testing.T) {
+	require.NoError(t, RegisterProfile(testForkProfile, requireNonce))
+	defer delete(defaultRegistry.profiles, testForkProfile)
+
+	require.NoError(t, RegisterProfile("1.3.6.1.4.1.99999.1"))
+	defer delete(defaultRegistry.profiles, "1.3.6.1.4.1.99999.1")
+
+	expected := []string{EatProfile, "1.3.6.1.4.1.99999.1", testForkProfile}
+	assert.Equal(t, expected, SupportedProfiles())
+
+	sigK, vfyK := testKeyPair(t)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	profile := Profile(testForkProfile)
+	ar.Profile = &profile
+
+	_, err := ar.Sign(jwa.ES256, sigK)
+	assert.EqualError(t, err, "invalid value(s) for eat_profile (tag:example.com,2024:ear-fork: eat_nonce is mandatory)")
+
+	ar.Nonce = &Nonces{"1234567890123456"}
+
+	token, err := ar.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	require.NoError(t, actual.Verify(token, jwa.ES256, vfyK))
+	assert.Equal(t, testForkProfile, actual.Profile.String())
+}
+
+func TestRegisterProfile_validator_on_verify(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	profile := Profile(testForkProfile)
+	ar.Profile = &profile
+
+	// sign while the profile has no validator, then verify with it
+	require.NoError(t, RegisterProfile(testForkProfile))
+
+	token, err := ar.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	defaultRegistry.profiles[testForkProfile] = []ProfileValidator{requireNonce}
+	defer delete(defaultRegistry.profiles, testForkProfile)
+
+	var actual AttestationResult
+	err = actual.Verify(token, jwa.ES256, vfyK)
+	assert.EqualError(t, err, "invalid value(s) for eat_profile (tag:example.com,2024:ear-fork: eat_nonce is mandatory)")
+	assert.ErrorIs(t, err, ErrInvalidClaim)
+}
+
+func TestRegisterProfile_fail(t *testing.T) {
+	tvs := []struct {
+		id       string
+		expected string
+	}{
+		{EatProfile, `profile "tag:github.com,2023:veraison/ear": already registered`},
+		{"ear-fork", `profile "ear-fork": neither an absolute URI nor an OID`},
+		{"1.2.", `profile "1.2.": neither an absolute URI nor an OID`},
+		{"", `profile "": neither an absolute URI nor an OID`},
+	}
+
+	for i, tv := range tvs {
+		err := RegisterProfile(tv.id)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestProfile_IsOID_IsURI(t *testing.T) {
+	tvs := []struct {
+		profile Profile
+		isOID   bool
+		isURI   bool
+	}{
+		{EatProfile, false, true},
+		{"https://example.com/profile", false, true},
+		{"1.3.6.1.4.1.99999.1", true, false},
+		{"1.2", true, false},
+		{"1", false, false},
+		{"not a profile", false, false},
+	}
+
+	for i, tv := range tvs {
+		assert.Equal(t, tv.isOID, tv.profile.IsOID(), "failed test vector at index %d", i)
+		assert.Equal(t, tv.isURI, tv.profile.IsURI(), "failed test vector at index %d", i)
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	p, err := NewProfile("1.3.6.1.4.1.99999.1")
+	require.NoError(t, err)
+	assert.True(t, p.IsOID())
+
+	_, err = NewProfile("relative/path")
+	assert.EqualError(t, err, `profile "relative/path": neither an absolute URI nor an OID`)
+}
+
+func TestOID_round_trip(t *testing.T) {
+	tvs := []struct {
+		oid     string
+		encoded []byte
+	}{
+		{"1.2.840.113549", []byte{0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d}},
+		{"1.3.6.1.4.1.99999.1", []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x86, 0x8d, 0x1f, 0x01}},
+		{"2.999.3", []byte{0x88, 0x37, 0x03}},
+		{"0.0", []byte{0x00}},
+	}
+
+	for i, tv := range tvs {
+		encoded, err := oidToBytes(tv.oid)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.encoded, encoded, "failed test vector at index %d", i)
+
+		decoded, err := oidFromBytes(encoded)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.oid, decoded, "failed test vector at index %d", i)
+	}
+}
+
+func TestOIDFromBytes_fail(t *testing.T) {
+	tvs := []struct {
+		encoded  []byte
+		expected string
+	}{
+		{nil, "empty OID"},
+		{[]byte{0x2b, 0x86}, "truncated OID"},
+		{[]byte{0x2b, 0x80, 0x01}, "non-minimal OID encoding"},
+	}
+
+	for i, tv := range tvs {
+		_, err := oidFromBytes(tv.encoded)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestProfile_OID_CWT_round_trip(t *testing.T) {
+	const oid = "1.3.6.1.4.1.99999.1"
+
+	require.NoError(t, RegisterProfile(oid))
+	defer delete(defaultRegistry.profiles, oid)
+
+	signer, verifier := testCOSESignerVerifier(t)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	profile := Profile(oid)
+	ar.Profile = &profile
+
+	token, err := ar.SignCWT(signer)
+	require.NoError(t, err)
+
+	// bstr(9) followed by the BER-encoded OID
+	assert.True(t, bytes.Contains(token, []byte{0x49, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x86, 0x8d, 0x1f, 0x01}))
+
+	var actual AttestationResult
+	require.NoError(t, actual.VerifyCWT(token, verifier))
+	assert.Equal(t, oid, actual.Profile.String())
+
+	// the OID is carried as a byte string in CBOR, and as a string in JSON
+	j, err := actual.MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(j), `"eat_profile":"1.3.6.1.4.1.99999.1"`)
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+)
+
+// VeraisonClaimProvenance is the "ear.veraison.claim-provenance" claim.  It
+// maps trustworthiness vector claim names (e.g., "executables") onto the ID of
+// the policy rule that determined their value, so that policy authors can find
+// out why a claim has been set without going through the verifier logs.
+type VeraisonClaimProvenance map[string]string
+
+func isTrustVectorClaim(name string) bool {
+	return (&TrustVector{}).claimPtr(name) != nil
+}
+
+func checkClaimProvenance(claim, ruleID string) error {
+	if !isTrustVectorClaim(claim) {
+		return fmt.Errorf("%q is not a trustworthiness vector claim", claim)
+	}
+
+	if ruleID == "" {
+		return fmt.Errorf("empty rule ID for %q", claim)
+	}
+
+	if err := checkFreeText(ruleID); err != nil {
+		return fmt.Errorf("rule ID for %q: %w", claim, err)
+	}
+
+	return nil
+}
+
+// SetClaimProvenance records that the value of the named trustworthiness
+// vector claim has been determined by the policy rule with the supplied ID
+func (o *AppraisalExtensions) SetClaimProvenance(claim, ruleID string) error {
+	if err := checkClaimProvenance(claim, ruleID); err != nil {
+		return err
+	}
+
+	if o.VeraisonClaimProvenance == nil {
+		o.VeraisonClaimProvenance = &VeraisonClaimProvenance{}
+	}
+
+	(*o.VeraisonClaimProvenance)[claim] = ruleID
+
+	return nil
+}
+
+// GetClaimProvenance returns the ID of the policy rule that determined the
+// value of the named trustworthiness vector claim, if it has been recorded
+func (o AppraisalExtensions) GetClaimProvenance(claim string) (string, bool) {
+	if o.VeraisonClaimProvenance == nil {
+		return "", false
+	}
+
+	ruleID, ok := (*o.VeraisonClaimProvenance)[claim]
+
+	return ruleID, ok
+}
+
+func ToVeraisonClaimProvenance(v interface{}) (*VeraisonClaimProvenance, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "claim-provenance"`)
+	}
+
+	prov := VeraisonClaimProvenance{}
+
+	for key, val := range vMap {
+		s, err := str(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+		}
+
+		if err := checkClaimProvenance(key, s); err != nil {
+			return nil, err
+		}
+
+		prov[key] = s
+	}
+
+	return &prov, nil
+}
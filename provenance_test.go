@@ -0,0 +1,30 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppraisalExtensions_SetGetClaimProvenance(t *testing.T) {
+	var ext AppraisalExtensions
+
+	_, ok := ext.GetClaimProvenance("executables")
+	assert.False(t, ok)
+
+	require.NoError(t, ext.SetClaimProvenance("executables", "psa/sw-components#3"))
+
+	ruleID, ok := ext.GetClaimProvenance("executables")
+	assert.True(t, ok)
+	assert.Equal(t, "psa/sw-components#3", ruleID)
+
+	_, ok = ext.GetClaimProvenance("hardware")
+	assert.False(t, ok)
+
+	err := ext.SetClaimProvenance("firmware", "psa/fw#1")
+	assert.EqualError(t, err, `"firmware" is not a trustworthiness vector claim`)
+}
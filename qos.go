@@ -0,0 +1,104 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// VeraisonAppraisalQoS is the "ear.veraison.appraisal-qos" claim, in which the
+// verifier records how costly an appraisal has been, so that service level
+// objectives can be computed from the results themselves.  All the members are
+// optional.
+type VeraisonAppraisalQoS struct {
+	// DurationMicros is the time spent appraising the evidence, in
+	// microseconds
+	DurationMicros *int64 `json:"duration-us,omitempty"`
+	// EvidenceSize is the size of the appraised evidence, in bytes
+	EvidenceSize *int64 `json:"evidence-size,omitempty"`
+	// EndorsementLookups is the number of endorsement and reference value
+	// lookups performed during the appraisal
+	EndorsementLookups *int64 `json:"endorsement-lookups,omitempty"`
+}
+
+// SetDuration records the time spent appraising the evidence
+func (o *VeraisonAppraisalQoS) SetDuration(d time.Duration) {
+	us := d.Microseconds()
+	o.DurationMicros = &us
+}
+
+// Duration returns the time spent appraising the evidence, and whether it has
+// been recorded
+func (o VeraisonAppraisalQoS) Duration() (time.Duration, bool) {
+	if o.DurationMicros == nil {
+		return 0, false
+	}
+	return time.Duration(*o.DurationMicros) * time.Microsecond, true
+}
+
+// SetEvidenceSize records the size of the appraised evidence, in bytes
+func (o *VeraisonAppraisalQoS) SetEvidenceSize(n int) {
+	v := int64(n)
+	o.EvidenceSize = &v
+}
+
+// GetEvidenceSize returns the size of the appraised evidence, and whether it
+// has been recorded
+func (o VeraisonAppraisalQoS) GetEvidenceSize() (int, bool) {
+	if o.EvidenceSize == nil {
+		return 0, false
+	}
+	return int(*o.EvidenceSize), true
+}
+
+// SetEndorsementLookups records the number of endorsement lookups performed
+func (o *VeraisonAppraisalQoS) SetEndorsementLookups(n int) {
+	v := int64(n)
+	o.EndorsementLookups = &v
+}
+
+// GetEndorsementLookups returns the number of endorsement lookups performed,
+// and whether it has been recorded
+func (o VeraisonAppraisalQoS) GetEndorsementLookups() (int, bool) {
+	if o.EndorsementLookups == nil {
+		return 0, false
+	}
+	return int(*o.EndorsementLookups), true
+}
+
+func ToVeraisonAppraisalQoS(v interface{}) (*VeraisonAppraisalQoS, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "appraisal-qos"`)
+	}
+
+	var qos VeraisonAppraisalQoS
+
+	for key, val := range vMap {
+		i, err := int64PtrParser(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+		}
+
+		n := i.(*int64)
+		if *n < 0 {
+			return nil, fmt.Errorf(`invalid value for %q: negative`, key)
+		}
+
+		switch key {
+		case "duration-us":
+			qos.DurationMicros = n
+		case "evidence-size":
+			qos.EvidenceSize = n
+		case "endorsement-lookups":
+			qos.EndorsementLookups = n
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "appraisal-qos" object`, key)
+		}
+	}
+
+	return &qos, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVeraisonAppraisalQoS_accessors(t *testing.T) {
+	var qos VeraisonAppraisalQoS
+
+	_, ok := qos.Duration()
+	assert.False(t, ok)
+	_, ok = qos.GetEvidenceSize()
+	assert.False(t, ok)
+	_, ok = qos.GetEndorsementLookups()
+	assert.False(t, ok)
+
+	qos.SetDuration(1500 * time.Microsecond)
+	qos.SetEvidenceSize(4096)
+	qos.SetEndorsementLookups(3)
+
+	d, ok := qos.Duration()
+	assert.True(t, ok)
+	assert.Equal(t, 1500*time.Microsecond, d)
+
+	n, ok := qos.GetEvidenceSize()
+	assert.True(t, ok)
+	assert.Equal(t, 4096, n)
+
+	n, ok = qos.GetEndorsementLookups()
+	assert.True(t, ok)
+	assert.Equal(t, 3, n)
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// QuarantineRecord describes a token that has failed signature verification
+type QuarantineRecord struct {
+	// Token is the token as it has been received
+	Token []byte `json:"token"`
+	// Reason is the verification error
+	Reason string `json:"reason"`
+	// Algorithm is the signature algorithm the token was expected to use
+	Algorithm string `json:"alg"`
+	// ReceivedAt is the time at which verification was attempted
+	ReceivedAt time.Time `json:"received-at"`
+}
+
+// QuarantineSink persists tokens that have failed signature verification, so
+// that potential forgery attempts can be analysed later, rather than being
+// silently dropped.
+type QuarantineSink interface {
+	Quarantine(QuarantineRecord) error
+}
+
+// QuarantineSinkFunc adapts a function to the QuarantineSink interface
+type QuarantineSinkFunc func(QuarantineRecord) error
+
+func (o QuarantineSinkFunc) Quarantine(r QuarantineRecord) error {
+	return o(r)
+}
+
+// WithQuarantine instructs Verify to hand over tokens that fail signature
+// verification to the supplied QuarantineSink.
+func WithQuarantine(sink QuarantineSink) VerifyOption {
+	return func(o *verifyOptions) {
+		o.quarantine = sink
+	}
+}
+
+// JSONLQuarantineSink is a QuarantineSink that writes each record as a line of
+// JSON to the underlying io.Writer.  It is safe for concurrent use.
+type JSONLQuarantineSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLQuarantineSink returns a JSONLQuarantineSink writing to w
+func NewJSONLQuarantineSink(w io.Writer) *JSONLQuarantineSink {
+	return &JSONLQuarantineSink{enc: json.NewEncoder(w)}
+}
+
+func (o *JSONLQuarantineSink) Quarantine(r QuarantineRecord) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.enc.Encode(r)
+}
+
+// ThrottledQuarantineSink wraps a QuarantineSink so that no more than a fixed
+// number of records is forwarded in any given time window.  Records in excess
+// are dropped and counted.  This prevents a flood of bogus tokens from
+// exhausting the resources backing the wrapped sink.  It is safe for
+// concurrent use.
+type ThrottledQuarantineSink struct {
+	sink     QuarantineSink
+	limit    int
+	interval time.Duration
+	now      func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+	dropped     uint64
+}
+
+// NewThrottledQuarantineSink returns a ThrottledQuarantineSink that forwards
+// at most limit records to sink per interval.
+func NewThrottledQuarantineSink(
+	sink QuarantineSink,
+	limit int,
+	interval time.Duration,
+) *ThrottledQuarantineSink {
+	return &ThrottledQuarantineSink{
+		sink:     sink,
+		limit:    limit,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+func (o *ThrottledQuarantineSink) Quarantine(r QuarantineRecord) error {
+	o.mu.Lock()
+
+	now := o.now()
+	if now.Sub(o.windowStart) >= o.interval {
+		o.windowStart = now
+		o.count = 0
+	}
+
+	if o.count >= o.limit {
+		o.dropped++
+		o.mu.Unlock()
+		return nil
+	}
+
+	o.count++
+	o.mu.Unlock()
+
+	return o.sink.Quarantine(r)
+}
+
+// Dropped returns the number of records that have been dropped so far
+func (o *ThrottledQuarantineSink) Dropped() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.dropped
+}
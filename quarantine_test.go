@@ -0,0 +1,95 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify_WithQuarantine(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var (
+		ar  AttestationResult
+		buf bytes.Buffer
+	)
+
+	sink := NewJSONLQuarantineSink(&buf)
+
+	// a good token is not quarantined
+	err = ar.Verify(token, jwa.ES256, vfyK, WithQuarantine(sink))
+	require.NoError(t, err)
+	assert.Zero(t, buf.Len())
+
+	// a tampered one is
+	token[len(token)-1] ^= 1
+
+	err = ar.Verify(token, jwa.ES256, vfyK, WithQuarantine(sink))
+	assert.ErrorContains(t, err, "failed verifying JWT message")
+
+	var rec QuarantineRecord
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, token, rec.Token)
+	assert.Equal(t, "ES256", rec.Algorithm)
+	assert.NotEmpty(t, rec.Reason)
+	assert.False(t, rec.ReceivedAt.IsZero())
+}
+
+func TestVerify_WithQuarantine_sink_failure(t *testing.T) {
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	sink := QuarantineSinkFunc(func(QuarantineRecord) error {
+		return errors.New("disk full")
+	})
+
+	var ar AttestationResult
+
+	err = ar.Verify([]byte("bogus"), jwa.ES256, vfyK, WithQuarantine(sink))
+	assert.ErrorContains(t, err, "(quarantine failed: disk full)")
+}
+
+func TestThrottledQuarantineSink(t *testing.T) {
+	var forwarded int
+
+	sink := QuarantineSinkFunc(func(QuarantineRecord) error {
+		forwarded++
+		return nil
+	})
+
+	now := time.Unix(1666091373, 0)
+
+	throttled := NewThrottledQuarantineSink(sink, 2, time.Minute)
+	throttled.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, throttled.Quarantine(QuarantineRecord{}))
+	}
+
+	assert.Equal(t, 2, forwarded)
+	assert.Equal(t, uint64(3), throttled.Dropped())
+
+	// a new window starts
+	now = now.Add(time.Minute)
+
+	assert.NoError(t, throttled.Quarantine(QuarantineRecord{}))
+	assert.Equal(t, 3, forwarded)
+	assert.Equal(t, uint64(3), throttled.Dropped())
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// SetRawEvidence sets the ear.raw-evidence claim to a copy of the supplied
+// evidence, which is base64url-encoded when serialized to JSON, and carried as
+// a byte string in CBOR.  A nil evidence removes the claim.
+func (o *AttestationResult) SetRawEvidence(evidence []byte) {
+	if evidence == nil {
+		o.RawEvidence = nil
+		return
+	}
+
+	b := B64Url(append([]byte{}, evidence...))
+	o.RawEvidence = &b
+}
+
+// GetRawEvidence returns the content of the ear.raw-evidence claim, and
+// whether the claim is present
+func (o AttestationResult) GetRawEvidence() ([]byte, bool) {
+	if o.RawEvidence == nil {
+		return nil, false
+	}
+
+	return []byte(*o.RawEvidence), true
+}
+
+// checkRawEvidenceSize makes sure that the ear.raw-evidence claim in m, if
+// any, does not exceed maxSize bytes once decoded.  A zero maxSize disables
+// the check.
+func checkRawEvidenceSize(m map[string]interface{}, maxSize int) error {
+	if maxSize == 0 {
+		return nil
+	}
+
+	s, ok := m["ear.raw-evidence"].(string)
+	if !ok {
+		// missing, or of the wrong type, which is reported by the parser
+		return nil
+	}
+
+	if n := base64.RawURLEncoding.DecodedLen(len(s)); n > maxSize {
+		return fmt.Errorf("ear.raw-evidence is too large (%d bytes, maximum is %d)", n, maxSize)
+	}
+
+	return nil
+}
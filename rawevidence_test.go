@@ -0,0 +1,66 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestationResult_SetGetRawEvidence(t *testing.T) {
+	var ar AttestationResult
+
+	_, ok := ar.GetRawEvidence()
+	assert.False(t, ok)
+
+	evidence := []byte{0xd2, 0x84, 0x43, 0xa1}
+	ar.SetRawEvidence(evidence)
+
+	// the evidence is copied
+	evidence[0] = 0x00
+
+	actual, ok := ar.GetRawEvidence()
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0xd2, 0x84, 0x43, 0xa1}, actual)
+
+	ar.SetRawEvidence(nil)
+	assert.Nil(t, ar.RawEvidence)
+}
+
+func TestB64Url_UnmarshalJSON(t *testing.T) {
+	var b B64Url
+
+	require.NoError(t, json.Unmarshal([]byte(`"0oRDoQ"`), &b))
+	assert.Equal(t, B64Url{0xd2, 0x84, 0x43, 0xa1}, b)
+
+	assert.EqualError(t, json.Unmarshal([]byte(`"0oRDoQ=="`), &b), "illegal base64 data at input byte 6")
+	assert.EqualError(t, json.Unmarshal([]byte(`1`), &b), "json: cannot unmarshal number into Go value of type string")
+}
+
+func TestAttestationResult_WithMaxRawEvidenceSize(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.SetRawEvidence([]byte{0xd2, 0x84, 0x43, 0xa1})
+
+	j, err := ar.MarshalJSON()
+	require.NoError(t, err)
+
+	c, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	assert.NoError(t, actual.DecodeJSON(j, WithMaxRawEvidenceSize(4)))
+	assert.Equal(t, ar.RawEvidence, actual.RawEvidence)
+
+	assert.NoError(t, actual.DecodeCBOR(c, WithMaxRawEvidenceSize(4)))
+	assert.Equal(t, ar.RawEvidence, actual.RawEvidence)
+
+	expected := "ear.raw-evidence is too large (4 bytes, maximum is 3)"
+
+	assert.EqualError(t, actual.DecodeJSON(j, WithMaxRawEvidenceSize(3)), expected)
+	assert.EqualError(t, actual.DecodeCBOR(c, WithMaxRawEvidenceSize(3)), expected)
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RedactionProfile lists the claims that Redact removes from an
+// AttestationResult.  Claims are identified by JSON Pointers (RFC6901) into
+// the claims-set, in which a "*" reference token matches any member, e.g.,
+// "/submods/*/ear.veraison.annotated-evidence".  Pointers to claims that are
+// absent are ignored.
+type RedactionProfile []string
+
+// RedactionProfiles maps profile names (e.g., "partner") onto
+// RedactionProfiles, so that data-sharing agreements can be expressed in
+// configuration and selected by name
+type RedactionProfiles map[string]RedactionProfile
+
+// Names of the predefined redaction profiles
+const (
+	// RedactionInternal keeps all the claims
+	RedactionInternal = "internal"
+	// RedactionPartner removes the evidence and verifier internals
+	RedactionPartner = "partner"
+	// RedactionPublic only keeps the bare appraisal status of each submod,
+	// together with the other mandatory claims
+	RedactionPublic = "public"
+)
+
+var partnerRedactions = RedactionProfile{
+	"/ear.raw-evidence",
+	"/ear.veraison.tee-info",
+	"/submods/*/ear.veraison.annotated-evidence",
+	"/submods/*/ear.veraison.policy-claims",
+}
+
+// DefaultRedactionProfiles returns the predefined "internal", "partner" and
+// "public" redaction profiles
+func DefaultRedactionProfiles() RedactionProfiles {
+	public := append(RedactionProfile{
+		"/eat_nonce",
+		"/ear.nae.tts-info",
+		"/ear.veraison.migration",
+		"/ear.veraison.encrypted-claims",
+		"/ear.veraison.evidence-ref",
+		"/submods/*/ear.trustworthiness-vector",
+		"/submods/*/ear.appraisal-policy-id",
+		"/submods/*/ear.veraison.key-attestation",
+		"/submods/*/ear.veraison.annotated-evidence-digest",
+		"/submods/*/ear.veraison.appraisal-qos",
+		"/submods/*/ear.veraison.claim-provenance",
+		"/submods/*/ear.veraison.cca-info",
+		"/submods/*/ear.veraison.tdx-info",
+		"/submods/*/ear.veraison.sgx-info",
+		"/submods/*/ear.veraison.snp-info",
+	}, partnerRedactions...)
+
+	return RedactionProfiles{
+		RedactionInternal: {},
+		RedactionPartner:  append(RedactionProfile{}, partnerRedactions...),
+		RedactionPublic:   public,
+	}
+}
+
+// ParseRedactionProfiles decodes redaction profiles from a JSON object that
+// maps profile names onto arrays of JSON Pointers, e.g.:
+//
+//	{ "partner": [ "/ear.raw-evidence" ] }
+//
+// The result is merged over the default profiles, so that predefined profiles
+// can be overridden and new ones added.
+func ParseRedactionProfiles(data []byte) (RedactionProfiles, error) {
+	var m map[string]RedactionProfile
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	ret := DefaultRedactionProfiles()
+
+	for name, p := range m {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("redaction profile %q: %w", name, err)
+		}
+		ret[name] = p
+	}
+
+	return ret, nil
+}
+
+// Get returns the named profile
+func (o RedactionProfiles) Get(name string) (RedactionProfile, error) {
+	p, ok := o[name]
+	if !ok {
+		names := make([]string, 0, len(o))
+		for n := range o {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown redaction profile %q (known: %s)", name, strings.Join(names, ", "))
+	}
+
+	return p, nil
+}
+
+// Validate checks that the profile is made of well-formed JSON Pointers
+func (o RedactionProfile) Validate() error {
+	for _, p := range o {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("%q is not a JSON Pointer", p)
+		}
+	}
+
+	return nil
+}
+
+// Redact returns a copy of the AttestationResult from which the claims listed
+// in the profile have been removed.  The redacted result must still be valid,
+// hence mandatory claims (e.g., iat) cannot be redacted.
+func (o AttestationResult) Redact(profile RedactionProfile) (*AttestationResult, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	j, err := json.Marshal(o.AsMap())
+	if err != nil {
+		return nil, fmt.Errorf("encoding claims-set: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return nil, fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	for _, p := range profile {
+		extractClaims(m, strings.Split(p[1:], "/"), "")
+	}
+
+	var ar AttestationResult
+
+	if err := ar.decodeMap(m, newDecodeOptions([]DecodeOption{WithUnknownClaims(UnknownClaimsPreserve)})); err != nil {
+		return nil, fmt.Errorf("decoding redacted claims-set: %w", err)
+	}
+
+	if err := ar.validate(); err != nil {
+		return nil, fmt.Errorf("redacted claims-set: %w", err)
+	}
+
+	return &ar, nil
+}
+
+// extractClaims removes from m the members matching the supplied JSON Pointer
+// reference tokens, in which "*" matches any member, and returns them indexed
+// by their JSON Pointer.  Besides redaction, it picks the claims that are
+// encrypted, or selectively disclosed.
+func extractClaims(m map[string]interface{}, tokens []string, pointer string) map[string]interface{} {
+	ret := map[string]interface{}{}
+
+	tok := jsonPointerUnescaper.Replace(tokens[0])
+
+	var names []string
+	if tok == "*" {
+		names = sortedKeys(m)
+	} else if _, ok := m[tok]; ok {
+		names = []string{tok}
+	}
+
+	for _, name := range names {
+		p := pointer + "/" + escapeJSONPointer(name)
+
+		if len(tokens) == 1 {
+			ret[p] = m[name]
+			delete(m, name)
+			continue
+		}
+
+		if child, ok := m[name].(map[string]interface{}); ok {
+			for k, v := range extractClaims(child, tokens[1:], p) {
+				ret[k] = v
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestationResult_Redact(t *testing.T) {
+	rawEvidence := B64Url{0xde, 0xad, 0xbe, 0xef}
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.RawEvidence = &rawEvidence
+	ar.Nonce = &Nonces{testNonce}
+
+	profiles := DefaultRedactionProfiles()
+
+	internal, err := ar.Redact(profiles[RedactionInternal])
+	require.NoError(t, err)
+	assert.Equal(t, ar, *internal)
+
+	partner, err := ar.Redact(profiles[RedactionPartner])
+	require.NoError(t, err)
+	assert.Nil(t, partner.RawEvidence)
+	assert.NotNil(t, partner.Nonce)
+	assert.Nil(t, partner.Submods["test"].VeraisonAnnotatedEvidence)
+	assert.Nil(t, partner.Submods["test"].VeraisonPolicyClaims)
+	assert.NotNil(t, partner.Submods["test"].VeraisonKeyAttestation)
+	assert.Equal(t, ar.Submods["test"].AppraisalPolicyID, partner.Submods["test"].AppraisalPolicyID)
+
+	public, err := ar.Redact(profiles[RedactionPublic])
+	require.NoError(t, err)
+	assert.Nil(t, public.Nonce)
+	assert.Equal(t, &Appraisal{Status: &testStatus}, public.Submods["test"])
+	assert.Equal(t, ar.VerifierID, public.VerifierID)
+
+	// the original is unaffected
+	assert.NotNil(t, ar.Submods["test"].VeraisonAnnotatedEvidence)
+}
+
+func TestAttestationResult_Redact_fail(t *testing.T) {
+	tvs := []struct {
+		profile  RedactionProfile
+		expected string
+	}{
+		{
+			profile:  RedactionProfile{"iat"},
+			expected: `"iat" is not a JSON Pointer`,
+		},
+		{
+			profile:  RedactionProfile{"/iat"},
+			expected: `decoding redacted claims-set: missing mandatory 'iat'`,
+		},
+		{
+			profile:  RedactionProfile{"/submods/*/ear.status"},
+			expected: `decoding redacted claims-set: invalid value(s) for 'submods' (test: missing mandatory 'ear.status')`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := testAttestationResultsWithVeraisonExtns.Redact(tv.profile)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestParseRedactionProfiles(t *testing.T) {
+	profiles, err := ParseRedactionProfiles([]byte(`{
+		"partner": ["/ear.raw-evidence"],
+		"auditor": ["/submods/*/ear.veraison.policy-claims"]
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, RedactionProfile{"/ear.raw-evidence"}, profiles[RedactionPartner])
+	assert.Equal(t, DefaultRedactionProfiles()[RedactionPublic], profiles[RedactionPublic])
+
+	p, err := profiles.Get("auditor")
+	require.NoError(t, err)
+	assert.Equal(t, RedactionProfile{"/submods/*/ear.veraison.policy-claims"}, p)
+
+	_, err = profiles.Get("nobody")
+	assert.EqualError(t, err, `unknown redaction profile "nobody" (known: auditor, internal, partner, public)`)
+
+	_, err = ParseRedactionProfiles([]byte(`{"bad": ["ear.raw-evidence"]}`))
+	assert.EqualError(t, err, `redaction profile "bad": "ear.raw-evidence" is not a JSON Pointer`)
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry holds the eat_profile values and the AppraisalExtensions accepted
+// when decoding and validating AttestationResults.  The package-level
+// RegisterProfile and RegisterAppraisalExtension functions operate on the
+// default Registry (see DefaultRegistry), which is used unless another one is
+// supplied, either by setting AttestationResult.Registry or when decoding
+// using WithRegistry.  Separate Registries allow components sharing a process
+// to accept conflicting profiles and extensions.  A Registry is safe for
+// concurrent use, including registration.
+//
+// Vendor-defined TrustClaim values (see RegisterTrustClaimValue) are held by
+// the default Registry, since TrustClaims are decoded and described without
+// reference to a particular Registry.
+type Registry struct {
+	mu sync.RWMutex
+
+	// profiles maps the accepted eat_profile values onto their validators
+	profiles map[string][]ProfileValidator
+	// extensions holds the registered extensions, sorted by prefix
+	extensions []AppraisalExtension
+	// claimValues maps the trustworthiness claim names onto the
+	// vendor-defined values registered for them
+	claimValues map[string]detailsMap
+}
+
+// NewRegistry returns a Registry that only accepts EatProfile, and has no
+// registered extensions
+func NewRegistry() *Registry {
+	return &Registry{
+		profiles: map[string][]ProfileValidator{
+			EatProfile: nil,
+		},
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the Registry used by the package-level registration
+// functions, and by AttestationResults that have no Registry of their own
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// RegisterProfile is like the package-level RegisterProfile, but operates on
+// this Registry
+func (o *Registry) RegisterProfile(id string, validators ...ProfileValidator) error {
+	if _, err := NewProfile(id); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.profiles[id]; ok {
+		return fmt.Errorf("profile %q: already registered", id)
+	}
+
+	o.profiles[id] = validators
+
+	return nil
+}
+
+// SupportedProfiles returns the eat_profile values accepted by this Registry:
+// EatProfile, followed by the registered ones in lexicographic order
+func (o *Registry) SupportedProfiles() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	ret := make([]string, 0, len(o.profiles))
+
+	for id := range o.profiles {
+		if id != EatProfile {
+			ret = append(ret, id)
+		}
+	}
+
+	sort.Strings(ret)
+
+	return append([]string{EatProfile}, ret...)
+}
+
+// RegisterAppraisalExtension is like the package-level
+// RegisterAppraisalExtension, but operates on this Registry
+func (o *Registry) RegisterAppraisalExtension(ext AppraisalExtension) error {
+	if ext.Prefix == "" {
+		return errors.New("extension prefix must not be empty")
+	}
+
+	if ext.New == nil {
+		return fmt.Errorf("extension %q: New must be set", ext.Prefix)
+	}
+
+	for _, name := range structTagNames(reflect.TypeOf(Appraisal{}), "json") {
+		if strings.HasPrefix(name, ext.Prefix) {
+			return fmt.Errorf("extension %q: clashes with claim %q", ext.Prefix, name)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, e := range o.extensions {
+		if strings.HasPrefix(e.Prefix, ext.Prefix) || strings.HasPrefix(ext.Prefix, e.Prefix) {
+			return fmt.Errorf("extension %q: overlaps with extension %q", ext.Prefix, e.Prefix)
+		}
+	}
+
+	// copy on write, so that lookups can hand out elements without holding
+	// the lock
+	extensions := append(append([]AppraisalExtension{}, o.extensions...), ext)
+
+	sort.Slice(extensions, func(i, j int) bool {
+		return extensions[i].Prefix < extensions[j].Prefix
+	})
+
+	o.extensions = extensions
+
+	return nil
+}
+
+// SetExtensionClaim is like AppraisalExtensions.SetExtensionClaim, but the
+// claim must belong to an extension registered with this Registry
+func (o *Registry) SetExtensionClaim(ext *AppraisalExtensions, name string, v interface{}) error {
+	return ext.setExtensionClaim(o, name, v)
+}
+
+func (o *Registry) checkProfile(profile string) error {
+	o.mu.RLock()
+	_, ok := o.profiles[profile]
+	o.mu.RUnlock()
+
+	if ok {
+		return nil
+	}
+
+	return ProfileError{Received: profile, Accepted: o.SupportedProfiles()}
+}
+
+func (o *Registry) profileValidators(profile string) []ProfileValidator {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.profiles[profile]
+}
+
+// lookupAppraisalExtension returns the extension owning the named claim, if
+// any
+func (o *Registry) lookupAppraisalExtension(name string) (AppraisalExtension, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	for _, e := range o.extensions {
+		if strings.HasPrefix(name, e.Prefix) {
+			return e, true
+		}
+	}
+
+	return AppraisalExtension{}, false
+}
+
+// WithRegistry makes decoding, and the subsequent validation, use the
+// supplied Registry rather than the default one.  The Registry is recorded in
+// the decoded AttestationResult, so that it is also used if the result is
+// re-encoded.
+func WithRegistry(r *Registry) DecodeOption {
+	return func(o *decodeOptions) {
+		o.registry = r
+	}
+}
+
+// registry returns the Registry in use for the AttestationResult
+func (o AttestationResult) registry() *Registry {
+	if o.Registry != nil {
+		return o.Registry
+	}
+	return defaultRegistry
+}
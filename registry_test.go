@@ -0,0 +1,122 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_isolation(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	// two components register the same profile with different validators
+	strict := NewRegistry()
+	require.NoError(t, strict.RegisterProfile(testForkProfile, requireNonce))
+
+	lenient := NewRegistry()
+	require.NoError(t, lenient.RegisterProfile(testForkProfile))
+
+	assert.Equal(t, []string{EatProfile}, SupportedProfiles())
+	assert.Equal(t, []string{EatProfile, testForkProfile}, strict.SupportedProfiles())
+
+	ar := testAttestationResultsWithVeraisonExtns
+	profile := Profile(testForkProfile)
+	ar.Profile = &profile
+	ar.Registry = lenient
+
+	token, err := ar.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.Verify(token, jwa.ES256, vfyK)
+	assert.ErrorAs(t, err, &ProfileError{})
+
+	err = actual.Verify(token, jwa.ES256, vfyK, WithDecodeOptions(WithRegistry(strict)))
+	assert.EqualError(t, err, "invalid value(s) for eat_profile (tag:example.com,2024:ear-fork: eat_nonce is mandatory)")
+
+	err = actual.Verify(token, jwa.ES256, vfyK, WithDecodeOptions(WithRegistry(lenient)))
+	require.NoError(t, err)
+	assert.Equal(t, lenient, actual.Registry)
+}
+
+func TestRegistry_extension_isolation(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.RegisterAppraisalExtension(testAcmeExtension))
+
+	// a conflicting extension in another registry
+	other := NewRegistry()
+	require.NoError(t, other.RegisterAppraisalExtension(AppraisalExtension{
+		Prefix: "ear.acme.",
+		New:    func(string) interface{} { return &map[string]interface{}{} },
+		Validate: func(string, interface{}) error {
+			return errors.New("rejected")
+		},
+	}))
+
+	var ext AppraisalExtensions
+
+	err := ext.SetExtensionClaim("ear.acme.info", &testAcmeInfo{Firmware: "1.0"})
+	assert.EqualError(t, err, `claim "ear.acme.info" does not belong to a registered extension`)
+
+	require.NoError(t, r.SetExtensionClaim(&ext, "ear.acme.info", &testAcmeInfo{Firmware: "1.0"}))
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.Submods = map[string]*Appraisal{
+		"test": {Status: &testStatus, AppraisalExtensions: ext},
+	}
+
+	// validation uses the default registry, which has no such extension
+	_, err = ar.MarshalJSON()
+	assert.ErrorContains(t, err, "'ear.acme.info' (no registered extension)")
+
+	ar.Registry = r
+
+	data, err := ar.MarshalJSON()
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.DecodeJSON(data, WithRegistry(other))
+	assert.EqualError(t, err, "invalid value(s) for 'submods' (test: invalid extension claim(s): ear.acme.info: rejected)")
+
+	require.NoError(t, actual.DecodeJSON(data, WithRegistry(r)))
+	assert.Equal(t, ext.ExtensionClaims, actual.Submods["test"].ExtensionClaims)
+}
+
+func TestRegistry_concurrent_use(t *testing.T) {
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprintf("tag:example.com,2024:profile-%d", i)
+			assert.NoError(t, r.RegisterProfile(id))
+			assert.NoError(t, r.RegisterAppraisalExtension(AppraisalExtension{
+				Prefix: fmt.Sprintf("ear.vendor%d.", i),
+				New:    testAcmeExtension.New,
+			}))
+
+			assert.NoError(t, r.checkProfile(id))
+			_, ok := r.lookupAppraisalExtension(fmt.Sprintf("ear.vendor%d.info", i))
+			assert.True(t, ok)
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Len(t, r.SupportedProfiles(), 9)
+}
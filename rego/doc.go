@@ -0,0 +1,37 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package rego lets relying parties that already express their authorization
+rules in Rego apply the same language to EAR acceptance.  The attestation
+result is turned into the policy input using Input (a JSON view of
+ear.AttestationResult.AsMap), and handed over to an Evaluator.
+
+OPAEvaluator is backed by the embedded OPA engine.  The package is a module of
+its own, github.com/veraison/ear/rego, so that users of the ear module that do
+not need Rego do not depend on OPA.
+
+For example, with the policy:
+
+	package ear
+
+	default allow := false
+
+	allow {
+		input["ear.verifier-id"].developer == "acme.example"
+		input.submods.cpu["ear.status"] == "affirming"
+		input.submods.cpu["ear.trustworthiness-vector"].executables == 2
+	}
+
+an EAR can be checked on verification:
+
+	e, err := rego.NewOPAEvaluator(ctx, policy, "data.ear.allow")
+	if err != nil {
+		// ...
+	}
+
+	err = ar.Verify(token, jwa.ES256, key, ear.WithAfterVerify(rego.Hook(ctx, e)))
+
+Other engines can be plugged in by implementing Evaluator.
+*/
+package rego
@@ -0,0 +1,53 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package rego
+
+import (
+	"context"
+	"fmt"
+
+	opa "github.com/open-policy-agent/opa/rego"
+)
+
+// OPAEvaluator is an Evaluator backed by the embedded OPA Rego engine
+type OPAEvaluator struct {
+	query opa.PreparedEvalQuery
+}
+
+// NewOPAEvaluator compiles the supplied Rego module and prepares the query
+// (e.g., "data.ear.allow") used to decide.  The query must evaluate to a
+// boolean; an undefined result is treated as false.
+func NewOPAEvaluator(ctx context.Context, module, query string) (*OPAEvaluator, error) {
+	pq, err := opa.New(
+		opa.Query(query),
+		opa.Module("ear.rego", module),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("preparing Rego query: %w", err)
+	}
+
+	return &OPAEvaluator{query: pq}, nil
+}
+
+func (o OPAEvaluator) Eval(ctx context.Context, input interface{}) (bool, error) {
+	rs, err := o.query.Eval(ctx, opa.EvalInput(input))
+	if err != nil {
+		return false, err
+	}
+
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return false, nil
+	}
+
+	if len(rs) != 1 || len(rs[0].Expressions) != 1 {
+		return false, fmt.Errorf("expecting a single result, got %d", len(rs))
+	}
+
+	allowed, ok := rs[0].Expressions[0].Value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expecting a boolean decision, got %T", rs[0].Expressions[0].Value)
+	}
+
+	return allowed, nil
+}
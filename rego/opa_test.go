@@ -0,0 +1,64 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package rego
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+const testPolicy = `
+package ear
+
+default allow = false
+
+allow {
+	input["ear.verifier-id"].developer == "acme.example"
+	input.submods.cpu["ear.status"] == "affirming"
+	input.submods.cpu["ear.trustworthiness-vector"].executables == 2
+}
+`
+
+func TestOPAEvaluator(t *testing.T) {
+	ctx := context.Background()
+
+	e, err := NewOPAEvaluator(ctx, testPolicy, "data.ear.allow")
+	require.NoError(t, err)
+
+	ar := testResult()
+	assert.NoError(t, Hook(ctx, e)(ar))
+
+	ar.Submods["cpu"].TrustVector.Executables = ear.UnrecognizedRuntimeClaim
+	assert.ErrorIs(t, Hook(ctx, e)(ar), ErrDenied)
+}
+
+func TestOPAEvaluator_undefined(t *testing.T) {
+	ctx := context.Background()
+
+	e, err := NewOPAEvaluator(ctx, testPolicy, "data.ear.missing")
+	require.NoError(t, err)
+
+	allowed, err := Evaluate(ctx, e, testResult())
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestOPAEvaluator_not_boolean(t *testing.T) {
+	ctx := context.Background()
+
+	e, err := NewOPAEvaluator(ctx, testPolicy, "input.eat_profile")
+	require.NoError(t, err)
+
+	_, err = Evaluate(ctx, e, testResult())
+	assert.EqualError(t, err, "evaluating policy: expecting a boolean decision, got string")
+}
+
+func TestNewOPAEvaluator_bad_module(t *testing.T) {
+	_, err := NewOPAEvaluator(context.Background(), "package ear\nallow {", "data.ear.allow")
+	assert.ErrorContains(t, err, "preparing Rego query")
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package rego
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/veraison/ear"
+)
+
+// ErrDenied is returned by the Hook when the policy does not allow the
+// attestation result
+var ErrDenied = errors.New("attestation result denied by policy")
+
+// Evaluator evaluates a policy decision over an input document.  Eval returns
+// true if the input is allowed.
+type Evaluator interface {
+	Eval(ctx context.Context, input interface{}) (bool, error)
+}
+
+// EvaluatorFunc adapts a function to the Evaluator interface
+type EvaluatorFunc func(ctx context.Context, input interface{}) (bool, error)
+
+func (o EvaluatorFunc) Eval(ctx context.Context, input interface{}) (bool, error) {
+	return o(ctx, input)
+}
+
+// Input returns the policy input document for the attestation result, i.e.,
+// ar.AsMap() reduced to plain JSON values (maps, slices, strings, float64
+// numbers and booleans), which is the form Rego policies see as input: trust
+// tiers appear as their names (e.g., "affirming"), and trust vector claims as
+// numbers.
+func Input(ar *ear.AttestationResult) (map[string]interface{}, error) {
+	if ar == nil {
+		return nil, errors.New("nil attestation result")
+	}
+
+	data, err := json.Marshal(ar.AsMap())
+	if err != nil {
+		return nil, fmt.Errorf("encoding policy input: %w", err)
+	}
+
+	var input map[string]interface{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("decoding policy input: %w", err)
+	}
+
+	return input, nil
+}
+
+// Evaluate feeds the attestation result to the evaluator and returns its
+// decision
+func Evaluate(ctx context.Context, e Evaluator, ar *ear.AttestationResult) (bool, error) {
+	input, err := Input(ar)
+	if err != nil {
+		return false, err
+	}
+
+	allowed, err := e.Eval(ctx, input)
+	if err != nil {
+		return false, fmt.Errorf("evaluating policy: %w", err)
+	}
+
+	return allowed, nil
+}
+
+// Hook returns an ear.Hook that fails with ErrDenied unless the evaluator
+// allows the attestation result, e.g., to be passed to Verify using
+// ear.WithAfterVerify, or to AttestationResult.Expect
+func Hook(ctx context.Context, e Evaluator) ear.Hook {
+	return func(ar *ear.AttestationResult) error {
+		allowed, err := Evaluate(ctx, e, ar)
+		if err != nil {
+			return err
+		}
+
+		if !allowed {
+			return ErrDenied
+		}
+
+		return nil
+	}
+}
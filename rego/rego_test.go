@@ -0,0 +1,94 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package rego
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+func testResult() *ear.AttestationResult {
+	ar := ear.NewAttestationResult("cpu", "rrtrap-v1.0.0", "acme.example")
+
+	a := ar.Submods["cpu"]
+	affirming := ear.TrustTierAffirming
+	a.Status = &affirming
+	a.TrustVector = &ear.TrustVector{Executables: ear.ApprovedRuntimeClaim}
+
+	return ar
+}
+
+// allowAffirmingCPU mimics a policy looking at the input the way a Rego
+// policy would
+var allowAffirmingCPU = EvaluatorFunc(func(ctx context.Context, input interface{}) (bool, error) {
+	m := input.(map[string]interface{})
+	cpu, ok := m["submods"].(map[string]interface{})["cpu"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	tv, _ := cpu["ear.trustworthiness-vector"].(map[string]interface{})
+
+	return cpu["ear.status"] == "affirming" && tv["executables"] == float64(2), nil
+})
+
+func TestInput(t *testing.T) {
+	input, err := Input(testResult())
+	require.NoError(t, err)
+
+	assert.Equal(t, ear.EatProfile, input["eat_profile"])
+	assert.Equal(t, "acme.example", input["ear.verifier-id"].(map[string]interface{})["developer"])
+
+	cpu := input["submods"].(map[string]interface{})["cpu"].(map[string]interface{})
+	assert.Equal(t, "affirming", cpu["ear.status"])
+	assert.Equal(t, float64(2), cpu["ear.trustworthiness-vector"].(map[string]interface{})["executables"])
+
+	_, err = Input(nil)
+	assert.EqualError(t, err, "nil attestation result")
+}
+
+func TestEvaluate(t *testing.T) {
+	ar := testResult()
+
+	allowed, err := Evaluate(context.Background(), allowAffirmingCPU, ar)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	ar.Submods["cpu"].TrustVector.Executables = ear.UnrecognizedRuntimeClaim
+
+	allowed, err = Evaluate(context.Background(), allowAffirmingCPU, ar)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestHook(t *testing.T) {
+	ctx := context.Background()
+	ar := testResult()
+
+	assert.NoError(t, Hook(ctx, allowAffirmingCPU)(ar))
+
+	warning := ear.TrustTierWarning
+	ar.Submods["cpu"].Status = &warning
+	assert.ErrorIs(t, Hook(ctx, allowAffirmingCPU)(ar), ErrDenied)
+
+	broken := EvaluatorFunc(func(context.Context, interface{}) (bool, error) {
+		return false, errors.New("undefined rule")
+	})
+	assert.EqualError(t, Hook(ctx, broken)(ar), "evaluating policy: undefined rule")
+}
+
+func TestHook_Expect(t *testing.T) {
+	ar := testResult()
+	delete(ar.Submods, "cpu")
+	ar.Submods["gpu"] = ear.NewAppraisal(ear.TrustTierAffirming)
+
+	err := ar.Expect(Hook(context.Background(), allowAffirmingCPU))
+	assert.ErrorIs(t, err, ear.ErrExpectationNotMet)
+	assert.ErrorContains(t, err, ErrDenied.Error())
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// VerificationReport records what has been checked during a successful
+// Verify, so that it can be retained for audit purposes.
+type VerificationReport struct {
+	// Algorithm is the signature algorithm used to verify the token
+	Algorithm string `json:"alg"`
+	// KeyThumbprint is the base64url-encoded RFC7638 SHA-256 thumbprint of
+	// the verification key
+	KeyThumbprint string `json:"key-thumbprint"`
+	// Headers contains the JOSE protected header parameters of the token
+	Headers map[string]interface{} `json:"headers"`
+	// TimeChecks lists the time-related claims that have been validated
+	// against the verification time
+	TimeChecks []string `json:"time-checks"`
+	// ClaimsValidated lists the (top-level) claims that have been decoded
+	// and validated
+	ClaimsValidated []string `json:"claims-validated"`
+	// VerifiedAt is the time at which verification took place
+	VerifiedAt time.Time `json:"verified-at"`
+	// TimestampedAt is the generation time of the RFC3161 time-stamp, if
+	// one has been checked using WithTimestampVerifier
+	TimestampedAt *time.Time `json:"timestamped-at,omitempty"`
+}
+
+// WithVerificationReport instructs Verify to fill in the supplied
+// VerificationReport on success.  On failure, the report is left untouched.
+func WithVerificationReport(r *VerificationReport) VerifyOption {
+	return func(o *verifyOptions) {
+		o.report = r
+	}
+}
+
+func (o *VerificationReport) fill(
+	data []byte,
+	alg jwa.KeyAlgorithm,
+	key interface{},
+	token jwt.Token,
+	ar *AttestationResult,
+) error {
+	msg, err := jws.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parsing JWS: %w", err)
+	}
+
+	// round-trip the protected headers through JSON so that the report only
+	// contains plain values
+	hdrs, err := json.Marshal(msg.Signatures()[0].ProtectedHeaders())
+	if err != nil {
+		return fmt.Errorf("extracting protected headers: %w", err)
+	}
+
+	var headers map[string]interface{}
+	if err = json.Unmarshal(hdrs, &headers); err != nil {
+		return fmt.Errorf("extracting protected headers: %w", err)
+	}
+
+	thumbprint, err := keyThumbprint(key)
+	if err != nil {
+		return err
+	}
+
+	var timeChecks []string
+	if !token.IssuedAt().IsZero() {
+		timeChecks = append(timeChecks, "iat")
+	}
+	if !token.Expiration().IsZero() {
+		timeChecks = append(timeChecks, "exp")
+	}
+	if !token.NotBefore().IsZero() {
+		timeChecks = append(timeChecks, "nbf")
+	}
+
+	claims := make([]string, 0, len(ar.AsMap()))
+	for k := range ar.AsMap() {
+		claims = append(claims, k)
+	}
+	sort.Strings(claims)
+
+	*o = VerificationReport{
+		Algorithm:       alg.String(),
+		KeyThumbprint:   thumbprint,
+		Headers:         headers,
+		TimeChecks:      timeChecks,
+		ClaimsValidated: claims,
+		VerifiedAt:      time.Now(),
+	}
+
+	return nil
+}
+
+// keyThumbprint returns the base64url-encoded RFC7638 SHA-256 thumbprint of
+// the supplied key, which may either be a jwk.Key or a raw key.
+func keyThumbprint(key interface{}) (string, error) {
+	k, ok := key.(jwk.Key)
+	if !ok {
+		var err error
+		if k, err = jwk.FromRaw(key); err != nil {
+			return "", fmt.Errorf("converting key to JWK: %w", err)
+		}
+	}
+
+	tp, err := k.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("computing key thumbprint: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(tp), nil
+}
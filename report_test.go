@@ -0,0 +1,74 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify_WithVerificationReport(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var (
+		ar     AttestationResult
+		report VerificationReport
+	)
+
+	err = ar.Verify(token, jwa.ES256, vfyK, WithVerificationReport(&report))
+	require.NoError(t, err)
+
+	assert.Equal(t, "ES256", report.Algorithm)
+	assert.Equal(t, "ES256", report.Headers["alg"])
+	assert.Equal(t, "JWT", report.Headers["typ"])
+	assert.Equal(t, "xNnfOFTMgZSRM3KtGHQqavZGWGF00Fe54LZBYCIxr88", report.KeyThumbprint)
+	assert.Equal(t, []string{"iat"}, report.TimeChecks)
+	assert.Equal(t,
+		[]string{"ear.verifier-id", "eat_profile", "iat", "submods"},
+		report.ClaimsValidated)
+	assert.False(t, report.VerifiedAt.IsZero())
+}
+
+func TestVerify_WithVerificationReport_untouched_on_failure(t *testing.T) {
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	var (
+		ar     AttestationResult
+		report VerificationReport
+	)
+
+	err = ar.Verify([]byte("bogus"), jwa.ES256, vfyK, WithVerificationReport(&report))
+	assert.Error(t, err)
+	assert.Equal(t, VerificationReport{}, report)
+}
+
+func Test_keyThumbprint_raw_key(t *testing.T) {
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	require.NoError(t, err)
+
+	var raw interface{}
+	require.NoError(t, vfyK.Raw(&raw))
+
+	expected, err := keyThumbprint(vfyK)
+	require.NoError(t, err)
+
+	actual, err := keyThumbprint(raw)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	_, err = keyThumbprint("not a key")
+	assert.ErrorContains(t, err, "converting key to JWK")
+}
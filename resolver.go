@@ -0,0 +1,155 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// KeyResolver locates the key needed to verify a token, given the token's JWS
+// protected header.  Implementations that perform I/O must honour ctx.
+type KeyResolver interface {
+	ResolveKey(ctx context.Context, hdrs jws.Headers) (jwk.Key, error)
+}
+
+// KeyResolverFunc adapts a function to the KeyResolver interface
+type KeyResolverFunc func(ctx context.Context, hdrs jws.Headers) (jwk.Key, error)
+
+func (o KeyResolverFunc) ResolveKey(ctx context.Context, hdrs jws.Headers) (jwk.Key, error) {
+	return o(ctx, hdrs)
+}
+
+// resultChecksKey is the context key under which VerifyContext collects the
+// checks registered by the KeyResolver
+type resultChecksKey struct{}
+
+type resultChecks struct {
+	mu     sync.Mutex
+	checks []Hook
+}
+
+// AddResultCheck is called by a KeyResolver, from within ResolveKey, when its
+// choice of key is only sound if the decoded AttestationResult satisfies the
+// supplied check, e.g., if the verifier identity must be the key's owner.
+// VerifyContext runs the check once the token has been verified, before any
+// of the caller's WithAfterVerify hooks.  Since the check travels with ctx,
+// it is enforced even if the resolver is wrapped by another one (e.g., by a
+// KeyResolverFunc adding caching or logging), as long as the wrapper passes
+// ctx on.  Outside of VerifyContext, AddResultCheck does nothing.
+func AddResultCheck(ctx context.Context, check Hook) {
+	rc, ok := ctx.Value(resultChecksKey{}).(*resultChecks)
+	if !ok {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.checks = append(rc.checks, check)
+}
+
+// VerifyContext is like Verify, but the verification key is obtained from the
+// supplied KeyResolver, and the algorithm from the JWS header (it must match
+// the one the key is restricted to, if any).  Key resolution is abandoned if
+// ctx is cancelled or expires.
+func (o *AttestationResult) VerifyContext(
+	ctx context.Context,
+	data []byte,
+	resolver KeyResolver,
+	opts ...VerifyOption,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := newVerifyOptions(opts).checkTokenSize(data); err != nil {
+		return err
+	}
+
+	hdrs, err := protectedHeaders(data)
+	if err != nil {
+		return err
+	}
+
+	rc := &resultChecks{}
+
+	key, err := resolver.ResolveKey(context.WithValue(ctx, resultChecksKey{}, rc), hdrs)
+	if err != nil {
+		return fmt.Errorf("resolving verification key: %w", err)
+	}
+
+	alg, err := keyAlgorithm(key, hdrs.Algorithm())
+	if err != nil {
+		return err
+	}
+
+	// the resolver's checks go first, so that the application's hooks only
+	// see results it has accepted
+	rc.mu.Lock()
+	checks := rc.checks
+	rc.mu.Unlock()
+
+	if len(checks) > 0 {
+		opts = append([]VerifyOption{WithAfterVerify(func(ar *AttestationResult) error {
+			return runHooks(checks, ar)
+		})}, opts...)
+	}
+
+	return o.Verify(data, alg, key, opts...)
+}
+
+// JWKSResolver is a KeyResolver that selects keys by kid from a remote JWKS.
+// The JWKS is cached and refreshed in the background; in addition, an unknown
+// kid triggers an immediate refresh, so that newly rotated keys are picked up
+// without waiting for the next scheduled one.
+type JWKSResolver struct {
+	url   string
+	cache *jwk.Cache
+}
+
+// NewJWKSResolver returns a JWKSResolver for the JWKS at the supplied URL.
+// minRefresh sets the minimum interval between refreshes (zero means the
+// default of the underlying cache).  The background refresh stops when ctx is
+// done.
+func NewJWKSResolver(ctx context.Context, url string, minRefresh time.Duration) (*JWKSResolver, error) {
+	cache := jwk.NewCache(ctx)
+
+	var opts []jwk.RegisterOption
+	if minRefresh > 0 {
+		opts = append(opts, jwk.WithMinRefreshInterval(minRefresh))
+	}
+
+	if err := cache.Register(url, opts...); err != nil {
+		return nil, fmt.Errorf("registering JWKS %q: %w", url, err)
+	}
+
+	return &JWKSResolver{url: url, cache: cache}, nil
+}
+
+func (o *JWKSResolver) ResolveKey(ctx context.Context, hdrs jws.Headers) (jwk.Key, error) {
+	keys, err := o.cache.Get(ctx, o.url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS from %q: %w", o.url, err)
+	}
+
+	kid := hdrs.KeyID()
+
+	key, err := lookupKey(keys, kid)
+	if err == nil || kid == "" {
+		return key, err
+	}
+
+	// the key may have been rotated in since the JWKS was last fetched
+	if keys, err = o.cache.Refresh(ctx, o.url); err != nil {
+		return nil, fmt.Errorf("refreshing JWKS from %q: %w", o.url, err)
+	}
+
+	return lookupKey(keys, kid)
+}
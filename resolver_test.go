@@ -0,0 +1,128 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJWKSServer serves a JWKS that can be replaced while the server is
+// running
+type testJWKSServer struct {
+	mu   sync.Mutex
+	keys jwk.Set
+}
+
+func (o *testJWKSServer) set(keys jwk.Set) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.keys = keys
+}
+
+func (o *testJWKSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(o.keys)
+}
+
+func testKeySetWithKID(t *testing.T, kid string) jwk.Set {
+	_, vfyK := testKeyPair(t)
+
+	k, err := vfyK.Clone()
+	require.NoError(t, err)
+	require.NoError(t, k.Set(jwk.KeyIDKey, kid))
+
+	keys := jwk.NewSet()
+	require.NoError(t, keys.AddKey(k))
+
+	return keys
+}
+
+func TestVerifyContext_JWKSResolver(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	jwks := &testJWKSServer{keys: testKeySetWithKID(t, "key-1")}
+	srv := httptest.NewServer(jwks)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resolver, err := NewJWKSResolver(ctx, srv.URL, 0)
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID("key-1"))
+	require.NoError(t, err)
+
+	var actual AttestationResult
+
+	err = actual.VerifyContext(ctx, token, resolver)
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual)
+
+	// rotate the key: the new kid is picked up with an immediate refresh
+	jwks.set(testKeySetWithKID(t, "key-2"))
+
+	token, err = testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID("key-2"))
+	require.NoError(t, err)
+
+	err = actual.VerifyContext(ctx, token, resolver)
+	require.NoError(t, err)
+
+	token, err = testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID("key-1"))
+	require.NoError(t, err)
+
+	err = actual.VerifyContext(ctx, token, resolver)
+	assert.EqualError(t, err, `resolving verification key: no key found for kid "key-1"`)
+}
+
+func TestVerifyContext_cancelled(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, WithKeyID("key-1"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resolver := KeyResolverFunc(func(context.Context, jws.Headers) (jwk.Key, error) {
+		t.Fatal("resolver must not be called")
+		return nil, nil
+	})
+
+	var actual AttestationResult
+
+	err = actual.VerifyContext(ctx, token, resolver)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestVerifyContext_resolver_error(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	resolver := KeyResolverFunc(func(context.Context, jws.Headers) (jwk.Key, error) {
+		return nil, errors.New("boom")
+	})
+
+	var actual AttestationResult
+
+	err = actual.VerifyContext(context.Background(), token, resolver)
+	assert.EqualError(t, err, "resolving verification key: boom")
+}
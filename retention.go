@@ -0,0 +1,174 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// RetentionPolicy bounds the growth of a TokenStore used by a long-running
+// service.  Tokens are grouped by attester, and ordered by issuance time
+// within each group.  A token is pruned if it is older than MaxAge, or if
+// more than MaxPerAttester tokens of its attester are more recent, unless it is
+// one of the KeepLastPerTier most recent tokens of its attester with the same
+// overall status: this way, e.g., the last contraindicated result of a device
+// is kept around even when it has long been superseded.  Zero values disable
+// the corresponding rule.
+type RetentionPolicy struct {
+	// MaxAge is the age, relative to Now, past which tokens are pruned
+	MaxAge time.Duration
+	// MaxPerAttester is the number of tokens kept for each attester
+	MaxPerAttester int
+	// KeepLastPerTier is the number of tokens of each attester, for each
+	// overall status, that are kept regardless of MaxAge and MaxPerAttester
+	KeepLastPerTier int
+
+	// Attester returns the identity of the attester a result is about.  If
+	// nil, all tokens are considered to be about the same attester.
+	Attester func(*AttestationResult) string
+
+	// Now returns the time against which MaxAge is checked (default
+	// time.Now)
+	Now func() time.Time
+}
+
+// RetentionReport records what a RetentionPolicy did to a TokenStore, e.g.,
+// to be exported as metrics
+type RetentionReport struct {
+	// Examined is the number of tokens in the store
+	Examined int
+	// Retained is the number of examined tokens left in the store
+	Retained int
+	// PrunedByAge is the number of tokens pruned for being older than
+	// MaxAge
+	PrunedByAge int
+	// PrunedByCount is the number of (otherwise recent enough) tokens
+	// pruned because their attester had more than MaxPerAttester
+	PrunedByCount int
+	// Undecodable is the number of tokens that could not be decoded.  These
+	// are retained, since their age and attester are unknown.
+	Undecodable int
+}
+
+// retainedToken is a decoded token, as considered by RetentionPolicy
+type retainedToken struct {
+	id       string
+	attester string
+	iat      int64
+	tier     TrustTier
+}
+
+// Prune removes the tokens that fall outside the policy from the store.  The
+// tokens are not verified, since the store is trusted to only hold tokens that
+// have been verified before being stored.  Nothing is removed unless all the
+// tokens have been loaded successfully, and then only the evicted ones are,
+// using TokenStore.Delete, so that tokens stored while pruning are kept.
+func (o RetentionPolicy) Prune(store TokenStore) (*RetentionReport, error) {
+	if o.MaxAge < 0 || o.MaxPerAttester < 0 || o.KeepLastPerTier < 0 {
+		return nil, errors.New("negative retention limit")
+	}
+
+	ids, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing tokens: %w", err)
+	}
+
+	sort.Strings(ids)
+
+	report := RetentionReport{Examined: len(ids)}
+	groups := map[string][]retainedToken{}
+
+	for _, id := range ids {
+		token, err := store.Load(id)
+		if err != nil {
+			return nil, fmt.Errorf("loading token %q: %w", id, err)
+		}
+
+		rt, err := o.decode(id, token)
+		if err != nil {
+			report.Undecodable++
+			continue
+		}
+
+		groups[rt.attester] = append(groups[rt.attester], *rt)
+	}
+
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+
+	cutoff := now().Add(-o.MaxAge).Unix()
+
+	var evicted []string
+
+	for _, group := range groups {
+		// most recent first, ties broken by identifier so that the
+		// outcome does not depend on the store
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].iat > group[j].iat
+		})
+
+		perTier := map[TrustTier]int{}
+
+		for i, rt := range group {
+			perTier[rt.tier]++
+			if perTier[rt.tier] <= o.KeepLastPerTier {
+				continue
+			}
+
+			switch {
+			case o.MaxAge > 0 && rt.iat < cutoff:
+				report.PrunedByAge++
+			case o.MaxPerAttester > 0 && i >= o.MaxPerAttester:
+				report.PrunedByCount++
+			default:
+				continue
+			}
+
+			evicted = append(evicted, rt.id)
+		}
+	}
+
+	if len(evicted) > 0 {
+		sort.Strings(evicted)
+
+		if err := store.Delete(evicted...); err != nil {
+			return nil, fmt.Errorf("deleting evicted tokens: %w", err)
+		}
+	}
+
+	report.Retained = len(ids) - len(evicted)
+
+	return &report, nil
+}
+
+func (o RetentionPolicy) decode(id string, token []byte) (*retainedToken, error) {
+	msg, err := jws.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var ar AttestationResult
+	if err := ar.UnmarshalJSON(msg.Payload()); err != nil {
+		return nil, err
+	}
+
+	rt := retainedToken{
+		id:   id,
+		iat:  *ar.IssuedAt,
+		tier: ar.OverallStatus(),
+	}
+
+	if o.Attester != nil {
+		rt.attester = o.Attester(&ar)
+	}
+
+	return &rt, nil
+}
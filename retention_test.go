@@ -0,0 +1,162 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRetainedToken returns a token about the attester whose appraisal is in
+// the named submod
+func testRetainedToken(t *testing.T, attester string, iat int64, status TrustTier) []byte {
+	sigK, _ := testKeyPair(t)
+
+	ar := NewAttestationResult(attester, testVidBuild, testVidDeveloper)
+	ar.IssuedAt = &iat
+	ar.Submods[attester].Status = &status
+
+	token, err := ar.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	return token
+}
+
+func testRetentionAttester(ar *AttestationResult) string {
+	for name := range ar.Submods {
+		return name
+	}
+	return ""
+}
+
+func TestRetentionPolicy_Prune(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	hour := int64(time.Hour / time.Second)
+
+	tokens := map[string][]byte{
+		"a1": testRetainedToken(t, "a", now.Unix()-1*hour, TrustTierAffirming),
+		"a2": testRetainedToken(t, "a", now.Unix()-2*hour, TrustTierAffirming),
+		"a3": testRetainedToken(t, "a", now.Unix()-3*hour, TrustTierAffirming),
+		"a4": testRetainedToken(t, "a", now.Unix()-48*hour, TrustTierContraindicated),
+		"a5": testRetainedToken(t, "a", now.Unix()-72*hour, TrustTierContraindicated),
+		"b1": testRetainedToken(t, "b", now.Unix()-1*hour, TrustTierWarning),
+		"b2": testRetainedToken(t, "b", now.Unix()-30*hour, TrustTierWarning),
+		"x":  []byte("not a token"),
+	}
+
+	tvs := []struct {
+		policy   RetentionPolicy
+		retained []string
+		report   RetentionReport
+	}{
+		{
+			policy:   RetentionPolicy{},
+			retained: []string{"a1", "a2", "a3", "a4", "a5", "b1", "b2", "x"},
+			report:   RetentionReport{Examined: 8, Retained: 8, Undecodable: 1},
+		},
+		{
+			policy:   RetentionPolicy{MaxAge: 24 * time.Hour},
+			retained: []string{"a1", "a2", "a3", "b1", "x"},
+			report:   RetentionReport{Examined: 8, Retained: 5, PrunedByAge: 3, Undecodable: 1},
+		},
+		{
+			policy:   RetentionPolicy{MaxPerAttester: 2},
+			retained: []string{"a1", "a2", "b1", "b2", "x"},
+			report:   RetentionReport{Examined: 8, Retained: 5, PrunedByCount: 3, Undecodable: 1},
+		},
+		{
+			policy:   RetentionPolicy{MaxAge: 24 * time.Hour, MaxPerAttester: 2, KeepLastPerTier: 1},
+			retained: []string{"a1", "a2", "a4", "b1", "x"},
+			report:   RetentionReport{Examined: 8, Retained: 5, PrunedByAge: 2, PrunedByCount: 1, Undecodable: 1},
+		},
+	}
+
+	for i, tv := range tvs {
+		store := NewMemoryTokenStore(tokens)
+
+		tv.policy.Attester = testRetentionAttester
+		tv.policy.Now = func() time.Time { return now }
+
+		report, err := tv.policy.Prune(store)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.report, *report, "failed test vector at index %d", i)
+
+		ids, err := store.List()
+		require.NoError(t, err)
+		assert.Equal(t, tv.retained, ids, "failed test vector at index %d", i)
+	}
+}
+
+func TestRetentionPolicy_Prune_single_attester(t *testing.T) {
+	store := NewMemoryTokenStore(map[string][]byte{
+		"a": testRetainedToken(t, "a", testIAT, TrustTierAffirming),
+		"b": testRetainedToken(t, "b", testIAT+1, TrustTierAffirming),
+	})
+
+	// without an Attester function, all tokens are counted together
+	report, err := RetentionPolicy{MaxPerAttester: 1}.Prune(store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.PrunedByCount)
+
+	ids, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids)
+}
+
+// addingTokenStore stores a token while the first one is loaded, as another
+// user of the store would while it is being pruned
+type addingTokenStore struct {
+	*MemoryTokenStore
+	id    string
+	token []byte
+}
+
+func (o *addingTokenStore) Load(id string) ([]byte, error) {
+	if o.token != nil {
+		o.mu.Lock()
+		o.tokens[o.id] = o.token
+		o.mu.Unlock()
+
+		o.token = nil
+	}
+
+	return o.MemoryTokenStore.Load(id)
+}
+
+func TestRetentionPolicy_Prune_concurrent_store(t *testing.T) {
+	store := &addingTokenStore{
+		MemoryTokenStore: NewMemoryTokenStore(map[string][]byte{
+			"a": testRetainedToken(t, "a", testIAT, TrustTierAffirming),
+			"b": testRetainedToken(t, "a", testIAT+1, TrustTierAffirming),
+		}),
+		id:    "c",
+		token: testRetainedToken(t, "a", testIAT+2, TrustTierAffirming),
+	}
+
+	report, err := RetentionPolicy{MaxPerAttester: 1}.Prune(store)
+	require.NoError(t, err)
+	assert.Equal(t, RetentionReport{Examined: 2, Retained: 1, PrunedByCount: 1}, *report)
+
+	// the token stored while pruning is not lost
+	ids, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, ids)
+}
+
+func TestRetentionPolicy_Prune_fail(t *testing.T) {
+	store := NewMemoryTokenStore(map[string][]byte{
+		"a": testRetainedToken(t, "a", testIAT, TrustTierAffirming),
+	})
+
+	_, err := RetentionPolicy{MaxPerAttester: -1}.Prune(store)
+	assert.EqualError(t, err, "negative retention limit")
+
+	ids, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids)
+}
@@ -0,0 +1,230 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// JSONSchemaDialect is the JSON Schema dialect of the schemas returned by
+// JSONSchema
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema describing the JSON serialization of the
+// EAR claims-set, as accepted by the default Registry.  See
+// Registry.JSONSchema.
+func JSONSchema() ([]byte, error) {
+	return defaultRegistry.JSONSchema()
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the JSON
+// serialization of the EAR claims-set, as produced by this package.  The
+// schema is derived from the Go types modelling the claims, and covers the
+// eat_profile values and the AppraisalExtensions registered with the
+// Registry.  It is meant as a machine-readable contract for implementations
+// in other languages.
+//
+// Decoding is more lenient than the schema: for example, trust claims may be
+// given by tag (e.g., "approved_rt") rather than by value.  Conversely, the
+// schema does not capture the checks that span several claims, such as those
+// made by ProfileValidators and ValidateStrict.  As when decoding, claims that
+// are not modelled are allowed at the top level and in appraisals, but not
+// within the modelled claims.
+func (o *Registry) JSONSchema() ([]byte, error) {
+	return json.MarshalIndent(o.jsonSchema(), "", "  ")
+}
+
+// jsonSchema is an object in a JSON Schema document
+type jsonSchema map[string]interface{}
+
+func (o *Registry) jsonSchema() jsonSchema {
+	appraisal := jsonSchemaObject(reflect.TypeOf(Appraisal{}))
+
+	o.mu.RLock()
+	if len(o.extensions) > 0 {
+		patterns := jsonSchema{}
+		for _, ext := range o.extensions {
+			var s interface{} = true
+			if ext.Schema != nil {
+				s = ext.Schema
+			}
+			patterns["^"+regexp.QuoteMeta(ext.Prefix)] = s
+		}
+		appraisal["patternProperties"] = patterns
+	}
+	o.mu.RUnlock()
+
+	ar := jsonSchemaObject(reflect.TypeOf(AttestationResult{}))
+
+	props := ar["properties"].(jsonSchema)
+	props["eat_profile"] = jsonSchema{
+		"type": "string",
+		"enum": o.SupportedProfiles(),
+	}
+	props["submods"] = jsonSchema{
+		"type":                 "object",
+		"minProperties":        1,
+		"additionalProperties": jsonSchemaRef("appraisal"),
+	}
+
+	ar["$schema"] = JSONSchemaDialect
+	ar["title"] = "EAR claims-set"
+	ar["$defs"] = jsonSchema{
+		"appraisal":              appraisal,
+		"b64url":                 jsonSchema{"type": "string", "pattern": "^[A-Za-z0-9_-]*$"},
+		"nonce":                  jsonSchema{"type": "string", "pattern": "^[A-Za-z0-9_-]{11,86}$"},
+		"trust-claim":            jsonSchema{"type": "integer", "minimum": -128, "maximum": 127},
+		"trust-tier":             jsonSchema{"type": "string", "enum": trustTierNames()},
+		"trustworthiness-vector": jsonSchemaObject(reflect.TypeOf(TrustVector{})),
+	}
+
+	return ar
+}
+
+// jsonSchemaRefs maps the types with a definition of their own (or with a
+// custom JSON serialization) onto their schema
+var jsonSchemaRefs = map[reflect.Type]func() jsonSchema{
+	reflect.TypeOf(B64Url{}):      func() jsonSchema { return jsonSchemaRef("b64url") },
+	reflect.TypeOf(TrustTier(0)):  func() jsonSchema { return jsonSchemaRef("trust-tier") },
+	reflect.TypeOf(TrustClaim(0)): func() jsonSchema { return jsonSchemaRef("trust-claim") },
+	reflect.TypeOf(TrustVector{}): func() jsonSchema { return jsonSchemaRef("trustworthiness-vector") },
+	reflect.TypeOf(Appraisal{}):   func() jsonSchema { return jsonSchemaRef("appraisal") },
+	reflect.TypeOf(Profile("")):   func() jsonSchema { return jsonSchema{"type": "string"} },
+	reflect.TypeOf(Nonces{}): func() jsonSchema {
+		return jsonSchemaOneOrMore(jsonSchemaRef("nonce"))
+	},
+	reflect.TypeOf(PolicyIDs{}): func() jsonSchema {
+		return jsonSchemaOneOrMore(jsonSchema{"type": "string"})
+	},
+}
+
+func jsonSchemaRef(name string) jsonSchema {
+	return jsonSchema{"$ref": "#/$defs/" + name}
+}
+
+// jsonSchemaOneOrMore is the schema of a value serialized as a single item
+// when there is only one, and as an array otherwise
+func jsonSchemaOneOrMore(item jsonSchema) jsonSchema {
+	return jsonSchema{
+		"anyOf": []interface{}{
+			item,
+			jsonSchema{"type": "array", "items": item, "minItems": 1},
+		},
+	}
+}
+
+// jsonSchemaFor derives the schema of the encoding/json serialization of t
+func jsonSchemaFor(t reflect.Type) jsonSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if f, ok := jsonSchemaRefs[t]; ok {
+		return f()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return jsonSchemaObject(t)
+	case reflect.Map:
+		s := jsonSchema{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			s["additionalProperties"] = jsonSchemaFor(t.Elem())
+		}
+		return s
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return jsonSchema{"type": "string", "contentEncoding": "base64"}
+		}
+		return jsonSchema{"type": "array", "items": jsonSchemaFor(t.Elem())}
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		bits := t.Bits()
+		return jsonSchema{"type": "integer", "minimum": -(1 << (bits - 1)), "maximum": 1<<(bits-1) - 1}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return jsonSchema{"type": "integer", "minimum": 0, "maximum": uint64(1)<<t.Bits() - 1}
+	case reflect.Uint, reflect.Uint64:
+		return jsonSchema{"type": "integer", "minimum": 0}
+	case reflect.Int, reflect.Int64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	default:
+		return jsonSchema{}
+	}
+}
+
+// jsonSchemaOpen are the types that may carry members besides the modelled
+// ones.  The decoders of all the other types reject unexpected members.
+var jsonSchemaOpen = map[reflect.Type]bool{
+	reflect.TypeOf(AttestationResult{}): true,
+	reflect.TypeOf(Appraisal{}):         true,
+}
+
+// jsonSchemaObject derives the schema of the struct type t, which is
+// serialized as a JSON object
+func jsonSchemaObject(t reflect.Type) jsonSchema {
+	props := jsonSchema{}
+	required := jsonSchemaFields(t, props)
+
+	s := jsonSchema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	if !jsonSchemaOpen[t] {
+		s["additionalProperties"] = false
+	}
+
+	return s
+}
+
+// jsonSchemaFields adds the schema of each field of the struct type t to
+// props, flattening embedded structs, and returns the names of the mandatory
+// ones
+func jsonSchemaFields(t reflect.Type, props jsonSchema) []string {
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		spec, ok := parseTag(f.Tag, "json")
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				required = append(required, jsonSchemaFields(f.Type, props)...)
+			}
+			continue
+		}
+
+		props[spec.Name] = jsonSchemaFor(f.Type)
+
+		if spec.IsMandatory {
+			required = append(required, spec.Name)
+		}
+	}
+
+	return required
+}
+
+func trustTierNames() []string {
+	tiers := make([]TrustTier, 0, len(TrustTierToString))
+	for t := range TrustTierToString {
+		tiers = append(tiers, t)
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i] < tiers[j] })
+
+	names := make([]string, 0, len(tiers))
+	for _, t := range tiers {
+		names = append(names, TrustTierToString[t])
+	}
+
+	return names
+}
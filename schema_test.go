@@ -0,0 +1,141 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchema_spec_examples(t *testing.T) {
+	schema, err := JSONSchema()
+	require.NoError(t, err)
+
+	examples, err := SpecExamples()
+	require.NoError(t, err)
+
+	// the schema agrees with the specifications on all the examples
+	for _, ex := range examples {
+		err := ValidateAgainstSchema(schema, ex.Claims)
+		if ex.Valid {
+			assert.NoError(t, err, "example %s", ex.Name)
+		} else {
+			assert.Error(t, err, "example %s", ex.Name)
+		}
+	}
+}
+
+func TestJSONSchema_serialized_results(t *testing.T) {
+	schema, err := JSONSchema()
+	require.NoError(t, err)
+
+	for i, ar := range []AttestationResult{
+		testAttestationResultsWithVeraisonExtns,
+		*NewAttestationResult("test", "build", "developer"),
+	} {
+		data, err := ar.MarshalJSON()
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.NoError(t, ValidateAgainstSchema(schema, data), "failed test vector at index %d", i)
+	}
+}
+
+func TestJSONSchema_invalid_claims(t *testing.T) {
+	schema, err := JSONSchema()
+	require.NoError(t, err)
+
+	tvs := []struct {
+		claims   string
+		expected []SchemaViolation
+	}{
+		{
+			`{"iat": "now", "submods": {}}`,
+			[]SchemaViolation{
+				{"", `missing mandatory "ear.verifier-id"`},
+				{"", `missing mandatory "eat_profile"`},
+				{"/iat", "expecting integer, got string"},
+				{"/submods", "expecting at least 1 member(s), got 0"},
+			},
+		},
+		{
+			`{
+				"eat_profile": "tag:example.com,2023:other",
+				"iat": 1666529184,
+				"eat_nonce": ["AAAA"],
+				"ear.verifier-id": {"build": "b", "host": "h"},
+				"submods": {"a/b": {"ear.status": "trusted", "ear.trustworthiness-vector": {"hardware": 2.5}}}
+			}`,
+			[]SchemaViolation{
+				{"/ear.verifier-id", `missing mandatory "developer"`},
+				{"/ear.verifier-id/host", "unexpected member"},
+				{"/eat_nonce", "array does not match any of the allowed forms"},
+				{"/eat_profile", `"tag:example.com,2023:other" is not one of the allowed values`},
+				{"/submods/a~1b/ear.status", `"trusted" is not one of the allowed values`},
+				{"/submods/a~1b/ear.trustworthiness-vector/hardware", "expecting integer, got number"},
+			},
+		},
+	}
+
+	for i, tv := range tvs {
+		err := ValidateAgainstSchema(schema, []byte(tv.claims))
+
+		var se *SchemaError
+		require.True(t, errors.As(err, &se), "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, se.Violations, "failed test vector at index %d", i)
+	}
+}
+
+func TestRegistry_JSONSchema(t *testing.T) {
+	reg := NewRegistry()
+
+	require.NoError(t, reg.RegisterProfile("tag:example.com,2023:acme"))
+	require.NoError(t, reg.RegisterAppraisalExtension(AppraisalExtension{
+		Prefix: "ear.acme.",
+		New:    func(string) interface{} { return new(string) },
+		Schema: map[string]interface{}{"type": "string"},
+	}))
+	require.NoError(t, reg.RegisterAppraisalExtension(AppraisalExtension{
+		Prefix: "ear.other.",
+		New:    func(string) interface{} { return new(interface{}) },
+	}))
+
+	schema, err := reg.JSONSchema()
+	require.NoError(t, err)
+
+	var s map[string]interface{}
+	require.NoError(t, json.Unmarshal(schema, &s))
+
+	assert.Equal(t, JSONSchemaDialect, s["$schema"])
+	assert.Equal(t,
+		[]interface{}{"tag:github.com,2023:veraison/ear", "tag:example.com,2023:acme"},
+		s["properties"].(map[string]interface{})["eat_profile"].(map[string]interface{})["enum"],
+	)
+
+	appraisal := s["$defs"].(map[string]interface{})["appraisal"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		`^ear\.acme\.`:  map[string]interface{}{"type": "string"},
+		`^ear\.other\.`: true,
+	}, appraisal["patternProperties"])
+
+	claims := `{
+		"eat_profile": "tag:example.com,2023:acme",
+		"iat": 1666529184,
+		"ear.verifier-id": {"build": "b", "developer": "d"},
+		"submods": {"test": {"ear.status": "affirming", "ear.acme.fw": %s, "ear.other.x": [1]}}
+	}`
+
+	assert.NoError(t, ValidateAgainstSchema(schema, []byte(fmt.Sprintf(claims, `"1.2"`))))
+
+	err = ValidateAgainstSchema(schema, []byte(fmt.Sprintf(claims, `12`)))
+	assert.EqualError(t, err, "#/submods/test/ear.acme.fw: expecting string, got integer")
+
+	// the default Registry is not affected
+	schema, err = JSONSchema()
+	require.NoError(t, err)
+	assert.NotContains(t, string(schema), "acme")
+}
@@ -0,0 +1,560 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaViolation is a place in a JSON document that does not conform to a
+// JSON Schema
+type SchemaViolation struct {
+	// Pointer locates the offending value, as a JSON Pointer (RFC6901)
+	Pointer string
+	// Message says what is wrong with the value
+	Message string
+}
+
+// SchemaError is returned by ValidateAgainstSchema when the document does not
+// conform to the schema
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (o SchemaError) Error() string {
+	msgs := make([]string, 0, len(o.Violations))
+
+	for _, v := range o.Violations {
+		msgs = append(msgs, fmt.Sprintf("#%s: %s", v.Pointer, v.Message))
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateAgainstSchema checks the JSON document data (e.g., an EAR
+// claims-set) against the JSON Schema schema (e.g., as returned by
+// JSONSchema), and reports all the violations found in a *SchemaError.  Only
+// the JSON Schema keywords needed by the schemas produced by this package are
+// supported; schemas using any other (non-annotation) keyword are rejected,
+// rather than being partially applied.  References must be local (e.g.,
+// "#/$defs/appraisal").
+func ValidateAgainstSchema(schema, data []byte) error {
+	var s interface{}
+
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("decoding schema: %w", err)
+	}
+
+	v := &schemaValidator{
+		root:     s,
+		patterns: map[string]*regexp.Regexp{},
+		acyclic:  map[string]bool{},
+	}
+
+	if err := v.check(s, ""); err != nil {
+		return fmt.Errorf("unsupported schema: %w", err)
+	}
+
+	var doc interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("decoding document: %w", err)
+	}
+
+	if dec.More() {
+		return fmt.Errorf("decoding document: trailing data after JSON value")
+	}
+
+	if problems := v.validate(s, doc, ""); len(problems) > 0 {
+		return &SchemaError{Violations: problems}
+	}
+
+	return nil
+}
+
+// schemaKeywords are the keywords understood by schemaValidator.  Annotations
+// are accepted, and ignored.
+var schemaKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$defs": true, "$ref": true, "$comment": true,
+	"title": true, "description": true, "examples": true, "default": true,
+	"contentEncoding": true, "contentMediaType": true, "deprecated": true,
+	"type": true, "enum": true, "const": true,
+	"properties": true, "patternProperties": true, "additionalProperties": true,
+	"required": true, "minProperties": true, "maxProperties": true,
+	"items": true, "minItems": true, "maxItems": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+	"minimum": true, "maximum": true,
+	"anyOf": true, "oneOf": true, "allOf": true,
+}
+
+type schemaValidator struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+
+	// acyclic are the locations of the schemas from which no chain of
+	// references loops back, see checkCycle
+	acyclic map[string]bool
+}
+
+// check makes sure that the schema s, found at loc, only uses supported
+// keywords, and compiles its patterns
+func (o *schemaValidator) check(s interface{}, loc string) error {
+	if _, ok := s.(bool); ok {
+		return nil
+	}
+
+	m, ok := s.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("#%s: not a schema", loc)
+	}
+
+	for _, kw := range sortedKeys(m) {
+		if !schemaKeywords[kw] {
+			return fmt.Errorf("#%s: keyword %q is not supported", loc, kw)
+		}
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		if _, err := o.resolve(ref); err != nil {
+			return fmt.Errorf("#%s: %w", loc, err)
+		}
+
+		if err := o.checkCycle(m, loc, map[string]bool{}); err != nil {
+			return err
+		}
+	}
+
+	if p, ok := m["pattern"].(string); ok {
+		if err := o.compile(p); err != nil {
+			return fmt.Errorf("#%s: %w", loc, err)
+		}
+	}
+
+	for _, kw := range []string{"$defs", "properties", "patternProperties"} {
+		sub, _ := m[kw].(map[string]interface{})
+		for _, name := range sortedKeys(sub) {
+			if kw == "patternProperties" {
+				if err := o.compile(name); err != nil {
+					return fmt.Errorf("#%s/%s: %w", loc, kw, err)
+				}
+			}
+			if err := o.check(sub[name], loc+"/"+kw+"/"+jsonPointerEscaper.Replace(name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kw := range []string{"additionalProperties", "items"} {
+		if sub, ok := m[kw]; ok {
+			if err := o.check(sub, loc+"/"+kw); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kw := range []string{"anyOf", "oneOf", "allOf"} {
+		subs, _ := m[kw].([]interface{})
+		for i, sub := range subs {
+			if err := o.check(sub, fmt.Sprintf("%s/%s/%d", loc, kw, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkCycle makes sure that following the references, and the in-place
+// applicators, from the schema s found at loc never leads back to a schema on
+// the path, since validate would then recurse forever without descending into
+// the value.  References through "properties", "items", etc. are fine, as each
+// step consumes a level of the (finite) value.
+func (o *schemaValidator) checkCycle(s interface{}, loc string, path map[string]bool) error {
+	m, ok := s.(map[string]interface{})
+	if !ok || o.acyclic[loc] {
+		return nil
+	}
+
+	path[loc] = true
+	defer delete(path, loc)
+
+	if ref, ok := m["$ref"].(string); ok {
+		target, err := o.resolve(ref)
+		if err != nil {
+			// reported by check
+			return nil
+		}
+
+		next := strings.TrimPrefix(ref, "#")
+		if path[next] {
+			return fmt.Errorf("#%s: circular reference %q", loc, ref)
+		}
+
+		if err := o.checkCycle(target, next, path); err != nil {
+			return err
+		}
+	}
+
+	for _, kw := range []string{"anyOf", "oneOf", "allOf"} {
+		subs, _ := m[kw].([]interface{})
+		for i, sub := range subs {
+			next := fmt.Sprintf("%s/%s/%d", loc, kw, i)
+			if err := o.checkCycle(sub, next, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	o.acyclic[loc] = true
+
+	return nil
+}
+
+func (o *schemaValidator) compile(pattern string) error {
+	if _, ok := o.patterns[pattern]; ok {
+		return nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("pattern %q: %w", pattern, err)
+	}
+
+	o.patterns[pattern] = re
+
+	return nil
+}
+
+// resolve returns the subschema referenced by the local reference ref
+func (o *schemaValidator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("reference %q is not local", ref)
+	}
+
+	cur := o.root
+
+	if ref == "#" {
+		return cur, nil
+	}
+
+	for _, tok := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("reference %q does not resolve", ref)
+		}
+
+		if cur, ok = m[jsonPointerUnescaper.Replace(tok)]; !ok {
+			return nil, fmt.Errorf("reference %q does not resolve", ref)
+		}
+	}
+
+	return cur, nil
+}
+
+// validate returns the violations of schema s by the value v found at ptr
+func (o *schemaValidator) validate(s interface{}, v interface{}, ptr string) []SchemaViolation {
+	if b, ok := s.(bool); ok {
+		if b {
+			return nil
+		}
+		return []SchemaViolation{{ptr, "no value is allowed here"}}
+	}
+
+	m := s.(map[string]interface{})
+
+	var problems []SchemaViolation
+
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, SchemaViolation{ptr, fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		target, _ := o.resolve(ref)
+		problems = append(problems, o.validate(target, v, ptr)...)
+	}
+
+	if t, ok := m["type"]; ok && !schemaTypeMatches(t, v) {
+		fail("expecting %s, got %s", schemaTypeNames(t), jsonTypeName(v))
+		return problems
+	}
+
+	if enum, ok := m["enum"].([]interface{}); ok && !schemaContains(enum, v) {
+		fail("%s is not one of the allowed values", schemaValueString(v))
+	}
+
+	if c, ok := m["const"]; ok && !schemaEqual(c, v) {
+		fail("expecting %s, got %s", schemaValueString(c), schemaValueString(v))
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		problems = append(problems, o.validateObject(m, t, ptr)...)
+	case []interface{}:
+		if n, ok := schemaNumber(m, "minItems"); ok && float64(len(t)) < n {
+			fail("expecting at least %v item(s), got %d", n, len(t))
+		}
+		if n, ok := schemaNumber(m, "maxItems"); ok && float64(len(t)) > n {
+			fail("expecting at most %v item(s), got %d", n, len(t))
+		}
+		if items, ok := m["items"]; ok {
+			for i, item := range t {
+				problems = append(problems, o.validate(items, item, ptr+"/"+strconv.Itoa(i))...)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(t)
+		if min, ok := schemaNumber(m, "minLength"); ok && float64(n) < min {
+			fail("expecting at least %v character(s), got %d", min, n)
+		}
+		if max, ok := schemaNumber(m, "maxLength"); ok && float64(n) > max {
+			fail("expecting at most %v character(s), got %d", max, n)
+		}
+		if p, ok := m["pattern"].(string); ok && !o.patterns[p].MatchString(t) {
+			fail("%q does not match %q", t, p)
+		}
+	case json.Number:
+		f, _ := t.Float64()
+		if min, ok := schemaNumber(m, "minimum"); ok && f < min {
+			fail("%s is less than %v", t, min)
+		}
+		if max, ok := schemaNumber(m, "maximum"); ok && f > max {
+			fail("%s is greater than %v", t, max)
+		}
+	}
+
+	if subs, ok := m["allOf"].([]interface{}); ok {
+		for _, sub := range subs {
+			problems = append(problems, o.validate(sub, v, ptr)...)
+		}
+	}
+
+	if subs, ok := m["anyOf"].([]interface{}); ok && o.countMatches(subs, v, ptr) == 0 {
+		fail("%s does not match any of the allowed forms", jsonTypeName(v))
+	}
+
+	if subs, ok := m["oneOf"].([]interface{}); ok {
+		if n := o.countMatches(subs, v, ptr); n != 1 {
+			fail("%s matches %d of the alternative forms, expecting exactly one", jsonTypeName(v), n)
+		}
+	}
+
+	return problems
+}
+
+func (o *schemaValidator) validateObject(
+	m map[string]interface{},
+	obj map[string]interface{},
+	ptr string,
+) []SchemaViolation {
+	var problems []SchemaViolation
+
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, SchemaViolation{ptr, fmt.Sprintf(format, args...)})
+	}
+
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, _ := r.(string); name != "" {
+				if _, ok := obj[name]; !ok {
+					fail("missing mandatory %q", name)
+				}
+			}
+		}
+	}
+
+	if n, ok := schemaNumber(m, "minProperties"); ok && float64(len(obj)) < n {
+		fail("expecting at least %v member(s), got %d", n, len(obj))
+	}
+
+	if n, ok := schemaNumber(m, "maxProperties"); ok && float64(len(obj)) > n {
+		fail("expecting at most %v member(s), got %d", n, len(obj))
+	}
+
+	props, _ := m["properties"].(map[string]interface{})
+	patterns, _ := m["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := m["additionalProperties"]
+
+	for _, name := range sortedKeys(obj) {
+		memberPtr := ptr + "/" + jsonPointerEscaper.Replace(name)
+		matched := false
+
+		if sub, ok := props[name]; ok {
+			matched = true
+			problems = append(problems, o.validate(sub, obj[name], memberPtr)...)
+		}
+
+		for _, p := range sortedKeys(patterns) {
+			if o.patterns[p].MatchString(name) {
+				matched = true
+				problems = append(problems, o.validate(patterns[p], obj[name], memberPtr)...)
+			}
+		}
+
+		if !matched && hasAdditional {
+			if b, ok := additional.(bool); ok && !b {
+				problems = append(problems, SchemaViolation{memberPtr, "unexpected member"})
+				continue
+			}
+			problems = append(problems, o.validate(additional, obj[name], memberPtr)...)
+		}
+	}
+
+	return problems
+}
+
+func (o *schemaValidator) countMatches(subs []interface{}, v interface{}, ptr string) int {
+	n := 0
+
+	for _, sub := range subs {
+		if len(o.validate(sub, v, ptr)) == 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+func schemaNumber(m map[string]interface{}, kw string) (float64, bool) {
+	f, ok := m[kw].(float64)
+	return f, ok
+}
+
+func schemaTypeMatches(t interface{}, v interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return jsonTypeIs(t, v)
+	case []interface{}:
+		for _, alt := range t {
+			if s, ok := alt.(string); ok && jsonTypeIs(s, v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func schemaTypeNames(t interface{}) string {
+	if alts, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(alts))
+		for _, alt := range alts {
+			names = append(names, fmt.Sprint(alt))
+		}
+		return strings.Join(names, " or ")
+	}
+
+	return fmt.Sprint(t)
+}
+
+func jsonTypeIs(t string, v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case []interface{}:
+		return t == "array"
+	case map[string]interface{}:
+		return t == "object"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		f, err := v.Float64()
+		return t == "integer" && err == nil && f == math.Trunc(f)
+	}
+
+	return false
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if jsonTypeIs("integer", v) {
+			return "integer"
+		}
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
+
+func schemaContains(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if schemaEqual(e, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// schemaEqual compares the value e, from the schema, with the value v, from
+// the document, in which numbers are kept as json.Number
+func schemaEqual(e, v interface{}) bool {
+	switch e := e.(type) {
+	case float64:
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == e
+	case []interface{}:
+		a, ok := v.([]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !schemaEqual(e[i], a[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		m, ok := v.(map[string]interface{})
+		if !ok || len(m) != len(e) {
+			return false
+		}
+		for k := range e {
+			if !schemaEqual(e[k], m[k]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return e == v
+	}
+}
+
+func schemaValueString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,118 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAgainstSchema(t *testing.T) {
+	schema := []byte(`{
+		"$defs": {"small": {"type": "integer", "minimum": 0, "maximum": 9}},
+		"type": "object",
+		"properties": {
+			"n": {"$ref": "#/$defs/small"},
+			"id": {"type": ["string", "null"], "minLength": 2, "maxLength": 4},
+			"kind": {"const": "x"},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"v": {"oneOf": [{"type": "number"}, {"type": "integer"}]}
+		},
+		"patternProperties": {"^x-": {"type": "boolean"}},
+		"additionalProperties": false
+	}`)
+
+	tvs := []struct {
+		doc      string
+		expected string
+	}{
+		{`{"n": 3, "id": "ab", "kind": "x", "tags": ["a"], "x-debug": true}`, ""},
+		{`{"n": 3.0, "id": null}`, ""},
+		{`{"n": 10}`, "#/n: 10 is greater than 9"},
+		{`{"n": -1.5}`, "#/n: expecting integer, got number"},
+		{`{"id": "abcde"}`, "#/id: expecting at most 4 character(s), got 5"},
+		{`{"id": 1}`, "#/id: expecting string or null, got integer"},
+		{`{"kind": "y"}`, `#/kind: expecting "x", got "y"`},
+		{`{"tags": ["a", 2, "c"]}`, "#/tags: expecting at most 2 item(s), got 3; #/tags/1: expecting string, got integer"},
+		{`{"v": 1.5}`, ""},
+		{`{"v": 1}`, "#/v: integer matches 2 of the alternative forms, expecting exactly one"},
+		{`{"x-debug": "yes"}`, "#/x-debug: expecting boolean, got string"},
+		{`{"other": 1}`, "#/other: unexpected member"},
+		{`[]`, "#: expecting object, got array"},
+		{`{} {}`, "decoding document: trailing data after JSON value"},
+		{`{`, "decoding document: unexpected EOF"},
+	}
+
+	for i, tv := range tvs {
+		err := ValidateAgainstSchema(schema, []byte(tv.doc))
+		if tv.expected == "" {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+		} else {
+			assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestValidateAgainstSchema_recursive_schema(t *testing.T) {
+	// references that descend into the value are not cycles
+	schema := `{
+		"$defs": {"node": {"type": "object", "properties": {"next": {"$ref": "#/$defs/node"}}}},
+		"$ref": "#/$defs/node"
+	}`
+
+	assert.NoError(t, ValidateAgainstSchema([]byte(schema), []byte(`{"next": {"next": {}}}`)))
+
+	err := ValidateAgainstSchema([]byte(schema), []byte(`{"next": {"next": 1}}`))
+	assert.EqualError(t, err, "#/next/next: expecting object, got integer")
+}
+
+func TestValidateAgainstSchema_unsupported_schema(t *testing.T) {
+	tvs := []struct {
+		schema   string
+		expected string
+	}{
+		{
+			`{"type": "object", "properties": {"a": {"if": {"type": "string"}}}}`,
+			`unsupported schema: #/properties/a: keyword "if" is not supported`,
+		},
+		{
+			`{"$ref": "https://example.com/ear.json"}`,
+			`unsupported schema: #: reference "https://example.com/ear.json" is not local`,
+		},
+		{
+			`{"items": {"$ref": "#/$defs/missing"}}`,
+			`unsupported schema: #/items: reference "#/$defs/missing" does not resolve`,
+		},
+		{
+			`{"patternProperties": {"(": true}}`,
+			"unsupported schema: #/patternProperties: pattern \"(\": error parsing regexp: missing closing ): `(`",
+		},
+		{
+			`{"anyOf": [1]}`,
+			`unsupported schema: #/anyOf/0: not a schema`,
+		},
+		{
+			`{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`,
+			`unsupported schema: #/$defs/a: circular reference "#/$defs/a"`,
+		},
+		{
+			`{"$defs": {"a": {"allOf": [{"$ref": "#/$defs/b"}]}, "b": {"anyOf": [{"$ref": "#/$defs/a"}]}}}`,
+			`unsupported schema: #/$defs/a/allOf/0: circular reference "#/$defs/b"`,
+		},
+		{
+			`{"oneOf": [{"$ref": "#"}]}`,
+			`unsupported schema: #/oneOf/0: circular reference "#"`,
+		},
+		{
+			`[`,
+			`decoding schema: unexpected end of JSON input`,
+		},
+	}
+
+	for i, tv := range tvs {
+		err := ValidateAgainstSchema([]byte(tv.schema), []byte(`{}`))
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
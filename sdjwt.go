@@ -0,0 +1,527 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+const (
+	sdClaim    = "_sd"
+	sdAlgClaim = "_sd_alg"
+	sdHashAlg  = "sha-256"
+	sdSaltSize = 16
+)
+
+// SDJWT is an EAR in SD-JWT (Selective Disclosure for JWTs) form: a JWT in
+// which some of the claims have been replaced by digests, together with the
+// disclosures that reveal them.  The JWT signature covers the digests, so a
+// holder can drop disclosures before passing the SD-JWT on, without
+// invalidating it.
+type SDJWT struct {
+	// Token is the issuer-signed JWT
+	Token []byte
+	// Disclosures are the base64url-encoded disclosures, each one a JSON
+	// array holding a salt, a claim name and a claim value
+	Disclosures []string
+}
+
+// ParseSDJWT parses an SD-JWT in its "<JWT>~<disclosure>~...~" serialization.
+// Key binding JWTs are not supported.
+func ParseSDJWT(data []byte) (*SDJWT, error) {
+	parts := strings.Split(string(data), "~")
+
+	if len(parts) < 2 {
+		return nil, errors.New("not an SD-JWT: missing '~' separator")
+	}
+
+	if parts[len(parts)-1] != "" {
+		return nil, errors.New("key binding JWTs are not supported")
+	}
+
+	if parts[0] == "" {
+		return nil, errors.New("empty JWT")
+	}
+
+	ret := SDJWT{Token: []byte(parts[0])}
+
+	for i, d := range parts[1 : len(parts)-1] {
+		if d == "" {
+			return nil, fmt.Errorf("empty disclosure at index %d", i)
+		}
+		ret.Disclosures = append(ret.Disclosures, d)
+	}
+
+	return &ret, nil
+}
+
+// Bytes returns the "<JWT>~<disclosure>~...~" serialization of the SD-JWT
+func (o SDJWT) Bytes() []byte {
+	var buf bytes.Buffer
+
+	buf.Write(o.Token)
+	buf.WriteByte('~')
+
+	for _, d := range o.Disclosures {
+		buf.WriteString(d)
+		buf.WriteByte('~')
+	}
+
+	return buf.Bytes()
+}
+
+// Select returns a copy of the SD-JWT that only retains the disclosures of
+// the claims matching the supplied JSON Pointers, in which, as in a
+// RedactionProfile, a "*" reference token matches any member.  The
+// disclosures of the claims enclosing, or nested in, a selected claim are
+// retained too.  The signature of the JWT is not checked.
+func (o SDJWT) Select(pointers ...string) (*SDJWT, error) {
+	if err := RedactionProfile(pointers).Validate(); err != nil {
+		return nil, err
+	}
+
+	msg, err := jws.Parse(o.Token)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JWS: %w", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(msg.Payload(), &m); err != nil {
+		return nil, fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	located, err := disclose(m, o.Disclosures)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := SDJWT{Token: o.Token}
+
+	for _, d := range o.Disclosures {
+		for _, p := range pointers {
+			if pointerOverlaps(located[d], p) {
+				ret.Disclosures = append(ret.Disclosures, d)
+				break
+			}
+		}
+	}
+
+	return &ret, nil
+}
+
+// SignSD validates the AttestationResult object and signs it, as Sign does,
+// in SD-JWT form.  The claims identified by the supplied JSON Pointers, in
+// which "*" matches any member (e.g., "/submods/*/ear.veraison.policy-claims"
+// or "/submods/gpu" to disclose a whole submod), are replaced by salted
+// digests, and returned as disclosures alongside the JWT.  Mandatory claims
+// cannot be made selectively disclosable, as the result must remain valid
+// whatever the disclosures passed on by the holder (see SDJWT.Select).  Key
+// discovery hints and time-stamps are added, and the key checked against the
+// algorithm, as they are by Sign.
+func (o AttestationResult) SignSD(
+	alg jwa.KeyAlgorithm,
+	key interface{},
+	pointers []string,
+	opts ...SignOption,
+) (*SDJWT, error) {
+	so := newSignOptions(opts)
+
+	if err := checkKeyAlgorithm(alg, key); err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	if err := RedactionProfile(pointers).Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := o.prepareForSigning(so); err != nil {
+		return nil, err
+	}
+
+	m, err := o.claimsMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var disclosures []string
+
+	for _, p := range pointers {
+		tokens := strings.Split(p[1:], "/")
+
+		found := extractClaims(m, tokens, "")
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no claim matches %q", p)
+		}
+
+		for _, cp := range sortedKeys(found) {
+			cpTokens := strings.Split(cp[1:], "/")
+			name := jsonPointerUnescaper.Replace(cpTokens[len(cpTokens)-1])
+
+			if name == sdClaim {
+				return nil, fmt.Errorf("%q cannot be selectively disclosed", cp)
+			}
+
+			d, err := newDisclosure(name, found[cp])
+			if err != nil {
+				return nil, fmt.Errorf("disclosing %q: %w", cp, err)
+			}
+
+			parent, err := objectAt(m, cpTokens[:len(cpTokens)-1])
+			if err != nil {
+				return nil, fmt.Errorf("disclosing %q: %w", cp, err)
+			}
+
+			digests, _ := parent[sdClaim].([]interface{})
+			parent[sdClaim] = append(digests, disclosureDigest(d))
+
+			disclosures = append(disclosures, d)
+		}
+	}
+
+	// the result must stand on its own, with no disclosures at all
+	if err := checkUndisclosed(m, o.Registry); err != nil {
+		return nil, err
+	}
+
+	sortDigests(m)
+	m[sdAlgClaim] = sdHashAlg
+
+	if so.tsa != nil {
+		delete(m, TimestampClaim)
+
+		j, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("encoding claims-set for time-stamping: %w", err)
+		}
+
+		if m[TimestampClaim], err = obtainTimestamp(so.tsa, j); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("encoding claims-set: %w", err)
+	}
+
+	hdrs, err := so.jwsHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jws.Sign(payload, jws.WithKey(alg, key, jws.WithProtectedHeaders(hdrs)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := so.signed(&o); err != nil {
+		return nil, err
+	}
+
+	return &SDJWT{Token: token, Disclosures: disclosures}, nil
+}
+
+// VerifySD verifies an SD-JWT produced by SignSD, using the supplied public
+// key and algorithm, and populates the receiver with the claims of the JWT
+// and with those revealed by the disclosures it carries.  Disclosures that do
+// not match any digest in the JWT, or that are repeated, are rejected.  The
+// verification options are honoured as in VerifyCWT; verification reports are
+// not supported.
+func (o *AttestationResult) VerifySD(
+	data []byte,
+	alg jwa.KeyAlgorithm,
+	key interface{},
+	opts ...VerifyOption,
+) error {
+	vo := newVerifyOptions(opts)
+
+	if vo.report != nil {
+		return errors.New("verification report is not supported for SD-JWT")
+	}
+
+	if err := vo.checkTokenSize(data); err != nil {
+		return err
+	}
+
+	sd, err := ParseSDJWT(data)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+
+	err = vo.checkAlgorithm(sd.Token, alg)
+	if err == nil {
+		err = checkKeyAlgorithm(alg, key)
+	}
+	if err == nil {
+		payload, err = jws.Verify(sd.Token, jws.WithKey(alg, key))
+	}
+	if err != nil {
+		return vo.verificationFailed(data, alg.String(), "SD-JWT", err)
+	}
+
+	do := newDecodeOptions(vo.decode)
+
+	if err := checkDuplicateClaims(payload, do.duplicate); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	if v, ok := m[sdAlgClaim]; ok && v != sdHashAlg {
+		return fmt.Errorf("unsupported %s: %v", sdAlgClaim, v)
+	}
+	delete(m, sdAlgClaim)
+
+	if _, err := disclose(m, sd.Disclosures); err != nil {
+		return err
+	}
+
+	if err := o.decodeVerified(m, do, vo); err != nil {
+		return err
+	}
+
+	// the time-stamp covers the claims signed by the issuer
+	_, err = o.finishVerification(vo, payload)
+
+	return err
+}
+
+func newDisclosure(name string, v interface{}) (string, error) {
+	salt := make([]byte, sdSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generating salt: %w", err)
+	}
+
+	data, err := json.Marshal([]interface{}{
+		base64.RawURLEncoding.EncodeToString(salt), name, v,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func disclosureDigest(d string) string {
+	sum := sha256.Sum256([]byte(d))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// disclose replaces, throughout m, the digests in the "_sd" members with the
+// claims revealed by the supplied disclosures, and removes the "_sd" members.
+// It returns the JSON Pointer of each disclosed claim, indexed by disclosure.
+func disclose(m map[string]interface{}, disclosures []string) (map[string]string, error) {
+	byDigest := make(map[string]string, len(disclosures))
+
+	for _, d := range disclosures {
+		digest := disclosureDigest(d)
+		if _, ok := byDigest[digest]; ok {
+			return nil, fmt.Errorf("duplicate disclosure %q", d)
+		}
+		byDigest[digest] = d
+	}
+
+	located := map[string]string{}
+
+	if err := discloseObject(m, "", byDigest, located); err != nil {
+		return nil, err
+	}
+
+	for _, d := range disclosures {
+		if _, ok := located[d]; !ok {
+			return nil, fmt.Errorf("disclosure %q does not match any digest", d)
+		}
+	}
+
+	return located, nil
+}
+
+func discloseObject(
+	m map[string]interface{},
+	pointer string,
+	byDigest map[string]string,
+	located map[string]string,
+) error {
+	if v, ok := m[sdClaim]; ok {
+		digests, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s/%s: expecting array, found %T", pointer, sdClaim, v)
+		}
+
+		delete(m, sdClaim)
+
+		for _, dv := range digests {
+			digest, ok := dv.(string)
+			if !ok {
+				return fmt.Errorf("%s/%s: expecting string digest, found %T", pointer, sdClaim, dv)
+			}
+
+			d, ok := byDigest[digest]
+			if !ok {
+				// not disclosed by the holder
+				continue
+			}
+
+			if _, ok := located[d]; ok {
+				return fmt.Errorf("digest %q found more than once", digest)
+			}
+
+			name, claim, err := parseDisclosure(d)
+			if err != nil {
+				return fmt.Errorf("disclosure %q: %w", d, err)
+			}
+
+			if name == sdClaim || name == sdAlgClaim {
+				return fmt.Errorf("disclosure %q: reserved claim name %q", d, name)
+			}
+
+			if _, ok := m[name]; ok {
+				return fmt.Errorf("disclosure %q: claim %q is also present in clear", d, name)
+			}
+
+			m[name] = claim
+			located[d] = pointer + "/" + escapeJSONPointer(name)
+		}
+	}
+
+	for _, name := range sortedKeys(m) {
+		child, ok := m[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		err := discloseObject(child, pointer+"/"+escapeJSONPointer(name), byDigest, located)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseDisclosure(d string) (string, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(d)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var elems []interface{}
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return "", nil, err
+	}
+
+	if len(elems) != 3 {
+		return "", nil, fmt.Errorf("expecting 3 elements, found %d", len(elems))
+	}
+
+	if _, ok := elems[0].(string); !ok {
+		return "", nil, errors.New("salt is not a string")
+	}
+
+	name, ok := elems[1].(string)
+	if !ok {
+		return "", nil, errors.New("claim name is not a string")
+	}
+
+	return name, elems[2], nil
+}
+
+// checkUndisclosed makes sure that the claims-set m, from which the
+// selectively disclosable claims have been extracted, is still valid
+func checkUndisclosed(m map[string]interface{}, reg *Registry) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encoding claims-set: %w", err)
+	}
+
+	var plain map[string]interface{}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	if _, err := disclose(plain, nil); err != nil {
+		return err
+	}
+
+	ar := AttestationResult{Registry: reg}
+
+	do := newDecodeOptions([]DecodeOption{WithUnknownClaims(UnknownClaimsPreserve)})
+
+	if err := ar.decodeMap(plain, do); err != nil {
+		return fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	return ar.validate()
+}
+
+// sortDigests sorts the "_sd" arrays throughout m, so that the order of the
+// digests does not give away the claims they stand for
+func sortDigests(m map[string]interface{}) {
+	for name, v := range m {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			sortDigests(t)
+		case []interface{}:
+			if name == sdClaim {
+				sort.Slice(t, func(i, j int) bool {
+					return t[i].(string) < t[j].(string)
+				})
+			}
+		}
+	}
+}
+
+// objectAt returns the object of m identified by the supplied JSON Pointer
+// reference tokens
+func objectAt(m map[string]interface{}, tokens []string) (map[string]interface{}, error) {
+	for _, t := range tokens {
+		tok := jsonPointerUnescaper.Replace(t)
+
+		child, ok := m[tok].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("no object found at %q", tok)
+		}
+
+		m = child
+	}
+
+	return m, nil
+}
+
+// pointerOverlaps tells whether the claim at JSON Pointer p matches the
+// pattern, in which "*" matches any member, or encloses or is nested in a
+// claim that does
+func pointerOverlaps(p, pattern string) bool {
+	pt := strings.Split(p[1:], "/")
+	st := strings.Split(pattern[1:], "/")
+
+	n := len(pt)
+	if len(st) < n {
+		n = len(st)
+	}
+
+	for i := 0; i < n; i++ {
+		if st[i] != "*" && st[i] != pt[i] {
+			return false
+		}
+	}
+
+	return true
+}
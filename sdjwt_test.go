@@ -0,0 +1,216 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSDJWT(t *testing.T) (AttestationResult, *SDJWT, interface{}) {
+	sigK, vfyK := testKeyPair(t)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.RawEvidence = &B64Url{0xde, 0xad, 0xbe, 0xef}
+
+	sd, err := ar.SignSD(jwa.ES256, sigK, []string{
+		"/ear.raw-evidence",
+		"/submods/*/ear.veraison.policy-claims",
+		"/submods/*/ear.veraison.annotated-evidence",
+	})
+	require.NoError(t, err)
+
+	return ar, sd, vfyK
+}
+
+func TestAttestationResult_SignSD_VerifySD(t *testing.T) {
+	ar, sd, vfyK := testSDJWT(t)
+
+	require.Len(t, sd.Disclosures, 3)
+
+	var actual AttestationResult
+	require.NoError(t, actual.VerifySD(sd.Bytes(), jwa.ES256, vfyK))
+	assert.Equal(t, ar, actual)
+
+	// no disclosures at all
+	undisclosed := SDJWT{Token: sd.Token}
+
+	actual = AttestationResult{}
+	require.NoError(t, actual.VerifySD(undisclosed.Bytes(), jwa.ES256, vfyK))
+	assert.Nil(t, actual.RawEvidence)
+	assert.Nil(t, actual.Submods["test"].VeraisonPolicyClaims)
+	assert.Nil(t, actual.Submods["test"].VeraisonAnnotatedEvidence)
+	assert.Equal(t, ar.Submods["test"].Status, actual.Submods["test"].Status)
+}
+
+func TestAttestationResult_SignSD_VerifySD_EdDSA_PS(t *testing.T) {
+	edK := testParseKey(t, testEd25519PrivateKey)
+	rsaK := testParseKey(t, testRSAPrivateKey)
+
+	tvs := []struct {
+		alg jwa.SignatureAlgorithm
+		key jwk.Key
+	}{
+		{jwa.EdDSA, edK},
+		{jwa.PS256, rsaK},
+	}
+
+	for i, tv := range tvs {
+		sd, err := testAttestationResultsWithVeraisonExtns.SignSD(tv.alg, tv.key,
+			[]string{"/submods/*/ear.veraison.policy-claims"})
+		require.NoError(t, err, "failed test vector at index %d", i)
+		require.Len(t, sd.Disclosures, 1, "failed test vector at index %d", i)
+
+		vfyK, err := tv.key.PublicKey()
+		require.NoError(t, err)
+
+		var actual AttestationResult
+		err = actual.VerifySD(sd.Bytes(), tv.alg, vfyK)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual, "failed test vector at index %d", i)
+	}
+}
+
+func TestAttestationResult_SignSD_VerifySD_key_algorithm_mismatch(t *testing.T) {
+	ecK, vfyK := testKeyPair(t)
+	edK := testParseKey(t, testEd25519PrivateKey)
+
+	_, err := testAttestationResultsWithVeraisonExtns.SignSD(jwa.EdDSA, ecK, nil)
+	assert.EqualError(t, err, "signing key: EdDSA requires an Ed25519 key, but an EC key was supplied")
+
+	sd, err := testAttestationResultsWithVeraisonExtns.SignSD(jwa.EdDSA, edK, nil)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+	err = ar.VerifySD(sd.Bytes(), jwa.EdDSA, vfyK)
+	assert.EqualError(t, err, "failed verifying SD-JWT: EdDSA requires an Ed25519 key, but an EC key was supplied")
+}
+
+func TestSDJWT_Select(t *testing.T) {
+	ar, sd, vfyK := testSDJWT(t)
+
+	selected, err := sd.Select("/submods/test/ear.veraison.policy-claims")
+	require.NoError(t, err)
+	require.Len(t, selected.Disclosures, 1)
+
+	var actual AttestationResult
+	require.NoError(t, actual.VerifySD(selected.Bytes(), jwa.ES256, vfyK))
+	assert.Nil(t, actual.RawEvidence)
+	assert.Nil(t, actual.Submods["test"].VeraisonAnnotatedEvidence)
+	assert.Equal(t, ar.Submods["test"].VeraisonPolicyClaims, actual.Submods["test"].VeraisonPolicyClaims)
+
+	all, err := sd.Select("/submods/*")
+	require.NoError(t, err)
+	assert.Len(t, all.Disclosures, 2)
+}
+
+func TestSDJWT_nested(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	ar := testAttestationResultsWithVeraisonExtns
+	status := TrustTierAffirming
+	ar.Submods = map[string]*Appraisal{
+		"cpu": ar.Submods["test"],
+		"gpu": {Status: &status, AppraisalExtensions: ar.Submods["test"].AppraisalExtensions},
+	}
+
+	sd, err := ar.SignSD(jwa.ES256, sigK, []string{
+		"/submods/gpu/ear.veraison.policy-claims",
+		"/submods/gpu",
+	})
+	require.NoError(t, err)
+
+	// disclosing the policy claims of gpu requires disclosing gpu itself
+	selected, err := sd.Select("/submods/gpu/ear.veraison.policy-claims")
+	require.NoError(t, err)
+	assert.Len(t, selected.Disclosures, 2)
+
+	var actual AttestationResult
+	require.NoError(t, actual.VerifySD(selected.Bytes(), jwa.ES256, vfyK))
+	assert.Equal(t, ar, actual)
+
+	actual = AttestationResult{}
+	require.NoError(t, actual.VerifySD(SDJWT{Token: sd.Token}.Bytes(), jwa.ES256, vfyK))
+	assert.NotContains(t, actual.Submods, "gpu")
+}
+
+func TestAttestationResult_SignSD_fail(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	tvs := []struct {
+		pointers []string
+		expected string
+	}{
+		{
+			pointers: []string{"ear.raw-evidence"},
+			expected: `"ear.raw-evidence" is not a JSON Pointer`,
+		},
+		{
+			pointers: []string{"/ear.raw-evidence"},
+			expected: `no claim matches "/ear.raw-evidence"`,
+		},
+		{
+			pointers: []string{"/iat"},
+			expected: `decoding claims-set: missing mandatory 'iat'`,
+		},
+		{
+			pointers: []string{"/submods/test/ear.veraison.policy-claims", "/submods/test/*"},
+			expected: `"/submods/test/_sd" cannot be selectively disclosed`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := testAttestationResultsWithVeraisonExtns.SignSD(jwa.ES256, sigK, tv.pointers)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestAttestationResult_VerifySD_fail(t *testing.T) {
+	_, sd, vfyK := testSDJWT(t)
+
+	other, err := newDisclosure("ear.raw-evidence", "3q2-7w")
+	require.NoError(t, err)
+
+	tvs := []struct {
+		data     []byte
+		expected string
+	}{
+		{
+			data:     sd.Token,
+			expected: `not an SD-JWT: missing '~' separator`,
+		},
+		{
+			data:     append(sd.Bytes(), "eyJ"...),
+			expected: `key binding JWTs are not supported`,
+		},
+		{
+			data:     SDJWT{Token: sd.Token, Disclosures: []string{other}}.Bytes(),
+			expected: `disclosure "` + other + `" does not match any digest`,
+		},
+		{
+			data:     SDJWT{Token: sd.Token, Disclosures: []string{sd.Disclosures[0], sd.Disclosures[0]}}.Bytes(),
+			expected: `duplicate disclosure "` + sd.Disclosures[0] + `"`,
+		},
+	}
+
+	for i, tv := range tvs {
+		var ar AttestationResult
+		err := ar.VerifySD(tv.data, jwa.ES256, vfyK)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+	err = ar.VerifySD(sd.Bytes(), jwa.ES256, &otherKey.PublicKey)
+	assert.ErrorContains(t, err, "failed verifying SD-JWT")
+}
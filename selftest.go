@@ -0,0 +1,218 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// Names of the checks made by SelfTest
+const (
+	SelfTestAlgorithm = "algorithm"
+	SelfTestKeyMatch  = "key-match"
+	SelfTestClock     = "clock"
+	SelfTestSign      = "sign"
+	SelfTestVerify    = "verify"
+	SelfTestRoundTrip = "round-trip"
+)
+
+// selfTestEpoch is a lower bound for the current time: a clock reporting an
+// earlier time has clearly not been set
+var selfTestEpoch = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SelfTestCheck is the outcome of one of the checks made by SelfTest
+type SelfTestCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SelfTestReport collects the diagnostics produced by SelfTest
+type SelfTestReport struct {
+	// Algorithm is the signature algorithm that has been exercised
+	Algorithm string `json:"alg"`
+	// Checks lists the checks made, in order.  Checks that depend on a
+	// failed one are not made.
+	Checks []SelfTestCheck `json:"checks"`
+}
+
+// OK returns true if all the checks have passed
+func (o SelfTestReport) OK() bool {
+	return o.Err() == nil
+}
+
+// Err returns an error summarising the failed checks, or nil if there is none
+func (o SelfTestReport) Err() error {
+	var failed []string
+
+	for _, c := range o.Checks {
+		if !c.OK {
+			failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Detail))
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("self-test failed (%s)", strings.Join(failed, "; "))
+}
+
+func (o *SelfTestReport) add(name string, err error) bool {
+	c := SelfTestCheck{Name: name, OK: err == nil}
+	if err != nil {
+		c.Detail = err.Error()
+	}
+
+	o.Checks = append(o.Checks, c)
+
+	return c.OK
+}
+
+// SelfTest checks that an AttestationResult can be signed with signKey and
+// verified with verifyKey, e.g., when a verifier starts up, before it is
+// marked ready.  Keys can be JWKs or raw keys.  The signature algorithm is the
+// one the signing key is restricted to or, if none, the most common one for
+// its type (e.g., ES256 for a P-256 key, EdDSA for an Ed25519 key).  The
+// report also covers whether the two keys are a pair, and whether the system
+// clock looks sane.
+func SelfTest(signKey, verifyKey interface{}) *SelfTestReport {
+	var report SelfTestReport
+
+	sk, err := asJWK(signKey)
+	if err != nil {
+		report.add(SelfTestAlgorithm, err)
+		return &report
+	}
+
+	alg, err := defaultAlgorithm(sk)
+	if !report.add(SelfTestAlgorithm, err) {
+		return &report
+	}
+	report.Algorithm = alg.String()
+
+	report.add(SelfTestKeyMatch, checkKeyPair(sk, verifyKey))
+
+	now := time.Now()
+	if now.Before(selfTestEpoch) {
+		report.add(SelfTestClock, fmt.Errorf("system time %s is before %s",
+			now.UTC().Format(time.RFC3339), selfTestEpoch.Format(time.RFC3339)))
+	} else {
+		report.add(SelfTestClock, nil)
+	}
+
+	ar := selfTestResult(now)
+
+	token, err := ar.Sign(alg, signKey)
+	if !report.add(SelfTestSign, err) {
+		return &report
+	}
+
+	var actual AttestationResult
+
+	if !report.add(SelfTestVerify, actual.Verify(token, alg, verifyKey)) {
+		return &report
+	}
+
+	if !reflect.DeepEqual(ar.AsMap(), actual.AsMap()) {
+		report.add(SelfTestRoundTrip, errors.New("verified claims differ from signed ones"))
+	} else {
+		report.add(SelfTestRoundTrip, nil)
+	}
+
+	return &report
+}
+
+// selfTestResult returns the AttestationResult signed by SelfTest
+func selfTestResult(now time.Time) AttestationResult {
+	ar := NewAttestationResult("self-test", "ear", "self-test")
+
+	iat := now.Unix()
+	ar.IssuedAt = &iat
+
+	tv := ar.Submods["self-test"].TrustVector
+	tv.SetAll(NoClaim)
+	tv.InstanceIdentity = TrustworthyInstanceClaim
+	ar.Submods["self-test"].UpdateStatusFromTrustVector()
+
+	return *ar
+}
+
+func asJWK(key interface{}) (jwk.Key, error) {
+	if k, ok := key.(jwk.Key); ok {
+		return k, nil
+	}
+
+	k, err := jwk.FromRaw(key)
+	if err != nil {
+		return nil, fmt.Errorf("converting key to JWK: %w", err)
+	}
+
+	return k, nil
+}
+
+// defaultAlgorithm returns the algorithm the key is restricted to or, if
+// none, the most common one for the key type
+func defaultAlgorithm(key jwk.Key) (jwa.SignatureAlgorithm, error) {
+	if a := key.Algorithm().String(); a != "" {
+		return jwa.SignatureAlgorithm(a), nil
+	}
+
+	switch k := key.(type) {
+	case jwk.ECDSAPrivateKey:
+		switch k.Crv() {
+		case jwa.P256:
+			return jwa.ES256, nil
+		case jwa.P384:
+			return jwa.ES384, nil
+		case jwa.P521:
+			return jwa.ES512, nil
+		}
+		return "", fmt.Errorf("unsupported curve %q", k.Crv())
+	case jwk.OKPPrivateKey:
+		if k.Crv() == jwa.Ed25519 {
+			return jwa.EdDSA, nil
+		}
+		return "", fmt.Errorf("unsupported curve %q", k.Crv())
+	case jwk.RSAPrivateKey:
+		return jwa.PS256, nil
+	case jwk.SymmetricKey:
+		return jwa.HS256, nil
+	}
+
+	return "", fmt.Errorf("cannot sign with a %s key", key.KeyType())
+}
+
+// checkKeyPair makes sure that verifyKey is the public counterpart of signKey
+// (or the same key, for symmetric algorithms, since the "public key" of a
+// symmetric JWK is the key itself)
+func checkKeyPair(signKey jwk.Key, verifyKey interface{}) error {
+	pub, err := signKey.PublicKey()
+	if err != nil {
+		return fmt.Errorf("extracting public key: %w", err)
+	}
+
+	expected, err := keyThumbprint(pub)
+	if err != nil {
+		return err
+	}
+
+	actual, err := keyThumbprint(verifyKey)
+	if err != nil {
+		return err
+	}
+
+	if expected != actual {
+		return errors.New("the verification key does not match the signing key")
+	}
+
+	return nil
+}
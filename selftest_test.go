@@ -0,0 +1,70 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func checkNames(r *SelfTestReport) []string {
+	var names []string
+	for _, c := range r.Checks {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestSelfTest_ok(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	r := SelfTest(sigK, vfyK)
+	require.NoError(t, r.Err())
+	assert.True(t, r.OK())
+	assert.Equal(t, "ES256", r.Algorithm)
+	assert.Equal(t, []string{
+		SelfTestAlgorithm, SelfTestKeyMatch, SelfTestClock, SelfTestSign, SelfTestVerify, SelfTestRoundTrip,
+	}, checkNames(r))
+}
+
+func TestSelfTest_raw_keys(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	r := SelfTest(priv, pub)
+	assert.NoError(t, r.Err())
+	assert.Equal(t, "EdDSA", r.Algorithm)
+
+	r = SelfTest([]byte("a shared secret for HMAC-SHA256!"), []byte("a shared secret for HMAC-SHA256!"))
+	assert.NoError(t, r.Err())
+	assert.Equal(t, "HS256", r.Algorithm)
+}
+
+func TestSelfTest_key_mismatch(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	r := SelfTest(sigK, pub)
+	assert.False(t, r.OK())
+	assert.Equal(t, []string{
+		SelfTestAlgorithm, SelfTestKeyMatch, SelfTestClock, SelfTestSign, SelfTestVerify,
+	}, checkNames(r))
+	assert.ErrorContains(t, r.Err(), "self-test failed (key-match: the verification key does not match the signing key; verify: failed verifying JWT message: ")
+}
+
+func TestSelfTest_bad_signing_key(t *testing.T) {
+	_, vfyK := testKeyPair(t)
+
+	r := SelfTest(vfyK, vfyK)
+	assert.EqualError(t, r.Err(), "self-test failed (algorithm: cannot sign with a EC key)")
+
+	r = SelfTest("not a key", vfyK)
+	assert.EqualError(t, r.Err(), "self-test failed (algorithm: converting key to JWK: invalid key type 'string' for jwk.New)")
+}
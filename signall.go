@@ -0,0 +1,103 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	cose "github.com/veraison/go-cose"
+)
+
+// MediaType identifies one of the serializations of a signed EAR
+type MediaType string
+
+const (
+	// MediaTypeJWT is an EAR claims-set wrapped in a JWT
+	MediaTypeJWT MediaType = "application/eat+jwt"
+	// MediaTypeCWT is an EAR claims-set wrapped in a COSE_Sign1 envelope
+	MediaTypeCWT MediaType = "application/eat+cwt"
+)
+
+// SignerConfig describes how one of the serializations requested from SignAll
+// is signed.  Alg and Key are used for MediaTypeJWT, COSESigner for
+// MediaTypeCWT.  Options only affect the envelope of that serialization (e.g.,
+// WithKeyID): options that change the claims-set must be passed to SignAll
+// instead, so that all the serializations carry the same claims.
+type SignerConfig struct {
+	Alg        jwa.KeyAlgorithm
+	Key        interface{}
+	COSESigner cose.Signer
+	Options    []SignOption
+}
+
+// SignAll signs the AttestationResult once for each of the supplied media
+// types, returning the signed tokens indexed by media type.  The claims-set is
+// validated and finalized (annotated evidence digests and before-sign hooks,
+// as configured by opts) only once, so all the tokens carry exactly the same
+// claims, including iat.  The after-sign hooks in opts are likewise run once,
+// when all the tokens have been produced.  JWTs are signed using canonical
+// JSON.
+func (o AttestationResult) SignAll(
+	signers map[MediaType]SignerConfig,
+	opts ...SignOption,
+) (map[MediaType][]byte, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("no signers supplied")
+	}
+
+	so := newSignOptions(opts)
+
+	if err := o.prepareForSigning(so); err != nil {
+		return nil, err
+	}
+
+	// sign in a stable order, so that the first error reported does not
+	// depend on map iteration
+	mts := make([]string, 0, len(signers))
+	for mt := range signers {
+		mts = append(mts, string(mt))
+	}
+	sort.Strings(mts)
+
+	tokens := make(map[MediaType][]byte, len(signers))
+
+	for _, s := range mts {
+		var (
+			mt    = MediaType(s)
+			cfg   = signers[mt]
+			token []byte
+			err   error
+		)
+
+		switch mt {
+		case MediaTypeJWT:
+			if cfg.Key == nil {
+				return nil, fmt.Errorf("%s: no signing key supplied", mt)
+			}
+			token, err = o.Sign(cfg.Alg, cfg.Key, append(cfg.Options, WithCanonicalJSON())...)
+		case MediaTypeCWT:
+			if cfg.COSESigner == nil {
+				return nil, fmt.Errorf("%s: no COSE signer supplied", mt)
+			}
+			token, err = o.SignCWT(cfg.COSESigner, cfg.Options...)
+		default:
+			return nil, fmt.Errorf("unsupported media type %q", mt)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("signing %s: %w", mt, err)
+		}
+
+		tokens[mt] = token
+	}
+
+	if err := so.signed(&o); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestationResult_SignAll_ok(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+	signer, verifier := testCOSESignerVerifier(t)
+
+	calls := 0
+	countCalls := func(*AttestationResult) error {
+		calls++
+		return nil
+	}
+
+	tokens, err := testAttestationResultsWithVeraisonExtns.SignAll(
+		map[MediaType]SignerConfig{
+			MediaTypeJWT: {Alg: jwa.ES256, Key: sigK, Options: []SignOption{WithKeyID("k1")}},
+			MediaTypeCWT: {COSESigner: signer},
+		},
+		WithBeforeSign(countCalls),
+	)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, 1, calls)
+
+	var fromJWT, fromCWT AttestationResult
+
+	require.NoError(t, fromJWT.Verify(tokens[MediaTypeJWT], jwa.ES256, vfyK))
+	require.NoError(t, fromCWT.VerifyCWT(tokens[MediaTypeCWT], verifier))
+
+	assert.Equal(t, fromJWT.AsMap(), fromCWT.AsMap())
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns.IssuedAt, fromCWT.IssuedAt)
+
+	kid, err := protectedHeaders(tokens[MediaTypeJWT])
+	require.NoError(t, err)
+	assert.Equal(t, "k1", kid.KeyID())
+}
+
+func TestAttestationResult_SignAll_fail(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	tvs := []struct {
+		signers  map[MediaType]SignerConfig
+		expected string
+	}{
+		{
+			nil,
+			"no signers supplied",
+		},
+		{
+			map[MediaType]SignerConfig{"application/json": {}},
+			`unsupported media type "application/json"`,
+		},
+		{
+			map[MediaType]SignerConfig{MediaTypeJWT: {Alg: jwa.ES256}},
+			"application/eat+jwt: no signing key supplied",
+		},
+		{
+			map[MediaType]SignerConfig{
+				MediaTypeJWT: {Alg: jwa.ES256, Key: sigK},
+				MediaTypeCWT: {},
+			},
+			"application/eat+cwt: no COSE signer supplied",
+		},
+		{
+			map[MediaType]SignerConfig{MediaTypeJWT: {Alg: jwa.RS256, Key: sigK}},
+			"signing application/eat+jwt: signing key: RS256 requires an RSA key, but an EC key was supplied",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := testAttestationResultsWithVeraisonExtns.SignAll(tv.signers)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestAttestationResult_SignAll_invalid(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+
+	_, err := AttestationResult{}.SignAll(map[MediaType]SignerConfig{
+		MediaTypeJWT: {Alg: jwa.ES256, Key: sigK},
+	})
+	assert.ErrorContains(t, err, "missing mandatory 'eat_profile'")
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SignerOpener returns the crypto.Signer for a key referenced by URI, e.g., a
+// PKCS#11 URI (RFC7512) naming a key held in an HSM, or the identifier of a
+// cloud KMS key.  The private key material never needs to leave the device:
+// Sign, and NewCachedSigner, accept the returned crypto.Signer in place of a
+// private key.
+type SignerOpener func(uri string) (crypto.Signer, error)
+
+var (
+	signerOpenersMu sync.RWMutex
+	signerOpeners   = map[string]SignerOpener{}
+)
+
+// URI schemes as per RFC3986, section 3.1.  Single letter schemes are not
+// accepted so that Windows paths (e.g., C:\keys\skey.pem) are not taken for
+// URIs.
+var signerSchemeRE = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]+$`)
+
+// RegisterSignerScheme makes OpenSigner use open for the URIs with the
+// supplied scheme (e.g., "pkcs11" or "awskms").  The package does not bundle
+// any opener: they are supplied by the application, which is free to pick the
+// PKCS#11 or KMS client library that suits its deployment.
+func RegisterSignerScheme(scheme string, open SignerOpener) error {
+	if !signerSchemeRE.MatchString(scheme) {
+		return fmt.Errorf("invalid URI scheme %q", scheme)
+	}
+
+	if open == nil {
+		return fmt.Errorf("scheme %q: nil opener", scheme)
+	}
+
+	scheme = strings.ToLower(scheme)
+
+	signerOpenersMu.Lock()
+	defer signerOpenersMu.Unlock()
+
+	if _, ok := signerOpeners[scheme]; ok {
+		return fmt.Errorf("scheme %q: already registered", scheme)
+	}
+
+	signerOpeners[scheme] = open
+
+	return nil
+}
+
+// SignerSchemes returns the URI schemes registered using RegisterSignerScheme,
+// in lexicographic order
+func SignerSchemes() []string {
+	signerOpenersMu.RLock()
+	defer signerOpenersMu.RUnlock()
+
+	ret := make([]string, 0, len(signerOpeners))
+	for s := range signerOpeners {
+		ret = append(ret, s)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// IsSignerURI reports whether s looks like a key URI, rather than, say, the
+// path of a key file.  It does not check whether the scheme is registered.
+func IsSignerURI(s string) bool {
+	_, _, ok := cutScheme(s)
+	return ok
+}
+
+// OpenSigner returns the crypto.Signer for the key referenced by uri, using
+// the opener registered for the URI scheme
+func OpenSigner(uri string) (crypto.Signer, error) {
+	scheme, _, ok := cutScheme(uri)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a key URI", uri)
+	}
+
+	signerOpenersMu.RLock()
+	open := signerOpeners[scheme]
+	signerOpenersMu.RUnlock()
+
+	if open == nil {
+		known := SignerSchemes()
+		if len(known) == 0 {
+			return nil, fmt.Errorf("no opener registered for %q URIs", scheme)
+		}
+		return nil, fmt.Errorf(
+			"no opener registered for %q URIs (registered: %s)",
+			scheme, strings.Join(known, ", "),
+		)
+	}
+
+	signer, err := open(uri)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s key: %w", scheme, err)
+	}
+
+	if signer == nil {
+		return nil, fmt.Errorf("opening %s key: no signer returned", scheme)
+	}
+
+	return signer, nil
+}
+
+// cutScheme splits a URI into its lower-cased scheme and the rest
+func cutScheme(s string) (string, string, bool) {
+	i := strings.IndexByte(s, ':')
+	if i < 0 || !signerSchemeRE.MatchString(s[:i]) {
+		return "", "", false
+	}
+
+	return strings.ToLower(s[:i]), s[i+1:], true
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testOpaqueSigner hides the type of the wrapped key, as an HSM-backed signer
+// would
+type testOpaqueSigner struct {
+	key   crypto.Signer
+	calls int
+}
+
+func (o *testOpaqueSigner) Public() crypto.PublicKey {
+	return o.key.Public()
+}
+
+func (o *testOpaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	o.calls++
+	return o.key.Sign(rand, digest, opts)
+}
+
+func newTestOpaqueSigner(t *testing.T, jwkData string) *testOpaqueSigner {
+	var raw interface{}
+	require.NoError(t, testParseKey(t, jwkData).Raw(&raw))
+
+	return &testOpaqueSigner{key: raw.(crypto.Signer)}
+}
+
+func TestSign_crypto_Signer(t *testing.T) {
+	tvs := []struct {
+		alg jwa.SignatureAlgorithm
+		key string
+	}{
+		{jwa.ES256, testECDSAPrivateKey},
+		{jwa.EdDSA, testEd25519PrivateKey},
+		{jwa.PS256, testRSAPrivateKey},
+		{jwa.RS512, testRSAPrivateKey},
+	}
+
+	for i, tv := range tvs {
+		signer := newTestOpaqueSigner(t, tv.key)
+
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(tv.alg, signer)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, 1, signer.calls, "failed test vector at index %d", i)
+
+		var ar AttestationResult
+
+		err = ar.Verify(token, tv.alg, signer.Public())
+		assert.NoError(t, err, "failed test vector at index %d", i)
+	}
+}
+
+func TestSign_crypto_Signer_algorithm_mismatch(t *testing.T) {
+	signer := newTestOpaqueSigner(t, testEd25519PrivateKey)
+
+	_, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, signer)
+	assert.EqualError(t, err, "signing key: ES256 requires an EC key, but an Ed25519 key was supplied")
+	assert.Zero(t, signer.calls)
+}
+
+func TestCachedSigner_crypto_Signer(t *testing.T) {
+	signer := newTestOpaqueSigner(t, testECDSAPrivateKey)
+
+	cs, err := NewCachedSigner(jwa.ES256, signer, WithKeyID("hsm-key-1"))
+	require.NoError(t, err)
+
+	token, err := cs.Sign(testAttestationResultsWithVeraisonExtns)
+	require.NoError(t, err)
+
+	vfyK, err := jwk.FromRaw(signer.Public())
+	require.NoError(t, err)
+
+	var ar AttestationResult
+	assert.NoError(t, ar.Verify(token, jwa.ES256, vfyK))
+}
+
+func TestOpenSigner(t *testing.T) {
+	signer := newTestOpaqueSigner(t, testECDSAPrivateKey)
+
+	var opened string
+
+	require.NoError(t, RegisterSignerScheme("pkcs11", func(uri string) (crypto.Signer, error) {
+		opened = uri
+		return signer, nil
+	}))
+	defer delete(signerOpeners, "pkcs11")
+
+	require.NoError(t, RegisterSignerScheme("testkms", func(uri string) (crypto.Signer, error) {
+		return nil, errors.New("access denied")
+	}))
+	defer delete(signerOpeners, "testkms")
+
+	assert.Equal(t, []string{"kms", "pkcs11", "testkms"}, SignerSchemes())
+
+	uri := "pkcs11:token=ear;object=signing-key?pin-source=file:/run/pin"
+
+	actual, err := OpenSigner(uri)
+	require.NoError(t, err)
+	assert.Equal(t, signer, actual)
+	assert.Equal(t, uri, opened)
+
+	// schemes are case-insensitive
+	_, err = OpenSigner("PKCS11:object=signing-key")
+	assert.NoError(t, err)
+
+	_, err = OpenSigner("testkms://keys/1")
+	assert.EqualError(t, err, "opening testkms key: access denied")
+
+	_, err = OpenSigner("awskms:///alias/ear")
+	assert.EqualError(t, err, `no opener registered for "awskms" URIs (registered: kms, pkcs11, testkms)`)
+
+	_, err = OpenSigner("skey.pem")
+	assert.EqualError(t, err, `"skey.pem" is not a key URI`)
+
+	err = RegisterSignerScheme("pkcs11", func(string) (crypto.Signer, error) { return nil, nil })
+	assert.EqualError(t, err, `scheme "pkcs11": already registered`)
+}
+
+func TestRegisterSignerScheme_fail(t *testing.T) {
+	open := func(string) (crypto.Signer, error) { return nil, nil }
+
+	tvs := []struct {
+		scheme   string
+		open     SignerOpener
+		expected string
+	}{
+		{"", open, `invalid URI scheme ""`},
+		{"c", open, `invalid URI scheme "c"`},
+		{"1kms", open, `invalid URI scheme "1kms"`},
+		{"my_kms", open, `invalid URI scheme "my_kms"`},
+		{"kms", nil, `scheme "kms": nil opener`},
+	}
+
+	for i, tv := range tvs {
+		err := RegisterSignerScheme(tv.scheme, tv.open)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestIsSignerURI(t *testing.T) {
+	tvs := []struct {
+		s        string
+		expected bool
+	}{
+		{"pkcs11:object=ear", true},
+		{"awskms:///alias/ear", true},
+		{"gcp-kms://projects/p/locations/l/keyRings/r/cryptoKeys/k", true},
+		{"skey.json", false},
+		{"/etc/ear/skey.pem", false},
+		{`C:\keys\skey.pem`, false},
+		{"-", false},
+	}
+
+	for i, tv := range tvs {
+		assert.Equal(t, tv.expected, IsSignerURI(tv.s), "failed test vector at index %d", i)
+	}
+}
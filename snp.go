@@ -0,0 +1,211 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// snpMeasurementSize is the size of the SEV-SNP launch measurement
+	// (SHA-384)
+	snpMeasurementSize = 48
+	// snpReportIDSize is the size of the SEV-SNP guest report ID
+	snpReportIDSize = 32
+)
+
+// SNPGuestPolicy is the policy of an AMD SEV-SNP guest, as found in the
+// attestation report.  The accessors decode the flags defined by the SEV-SNP
+// ABI specification.
+type SNPGuestPolicy uint64
+
+// SEV-SNP guest policy bits
+const (
+	snpPolicySMT          = 1 << 16
+	snpPolicyReserved     = 1 << 17
+	snpPolicyMigrateMA    = 1 << 18
+	snpPolicyDebug        = 1 << 19
+	snpPolicySingleSocket = 1 << 20
+)
+
+// ABIMajor returns the minimum ABI major version required by the guest
+func (o SNPGuestPolicy) ABIMajor() uint8 {
+	return uint8(o >> 8)
+}
+
+// ABIMinor returns the minimum ABI minor version required by the guest
+func (o SNPGuestPolicy) ABIMinor() uint8 {
+	return uint8(o)
+}
+
+// SMTAllowed returns true if the guest can run on a platform with SMT enabled
+func (o SNPGuestPolicy) SMTAllowed() bool {
+	return o&snpPolicySMT != 0
+}
+
+// MigrationAgentAllowed returns true if the guest can be associated with a
+// migration agent
+func (o SNPGuestPolicy) MigrationAgentAllowed() bool {
+	return o&snpPolicyMigrateMA != 0
+}
+
+// DebugAllowed returns true if the guest can be debugged, in which case its
+// memory is not confidential
+func (o SNPGuestPolicy) DebugAllowed() bool {
+	return o&snpPolicyDebug != 0
+}
+
+// SingleSocket returns true if the guest can only be activated on one socket
+func (o SNPGuestPolicy) SingleSocket() bool {
+	return o&snpPolicySingleSocket != 0
+}
+
+// SNPTCBVersion holds the security version numbers of the components of the
+// TCB of an AMD SEV-SNP platform
+type SNPTCBVersion struct {
+	Bootloader *uint8 `json:"bootloader,omitempty"`
+	TEE        *uint8 `json:"tee,omitempty"`
+	SNP        *uint8 `json:"snp,omitempty"`
+	Microcode  *uint8 `json:"microcode,omitempty"`
+}
+
+// VeraisonSNPInfo is the "ear.veraison.snp-info" claim, in which a verifier
+// appraising AMD SEV-SNP evidence records the launch measurement and report ID
+// of the guest, the TCB version the platform reported, and the guest policy.
+// All the members are optional.  Measurements are encoded as for
+// VeraisonTDXInfo.
+type VeraisonSNPInfo struct {
+	// Measurement is the launch measurement of the guest
+	Measurement *B64Url `json:"measurement,omitempty"`
+	// ReportID identifies the guest, and is stable across migrations
+	ReportID *B64Url `json:"report-id,omitempty"`
+	// ReportedTCB is the TCB version used to derive the key that signed
+	// the attestation report
+	ReportedTCB *SNPTCBVersion `json:"reported-tcb,omitempty"`
+	// GuestPolicy is the policy the guest has been launched with
+	GuestPolicy *SNPGuestPolicy `json:"guest-policy,omitempty"`
+}
+
+// SetSNPInfo validates and sets the "ear.veraison.snp-info" claim
+func (o *AppraisalExtensions) SetSNPInfo(info VeraisonSNPInfo) error {
+	if err := info.validate(); err != nil {
+		return err
+	}
+
+	o.VeraisonSNPInfo = &info
+
+	return nil
+}
+
+func (o VeraisonSNPInfo) validate() error {
+	if o.Measurement != nil {
+		if err := checkMeasurementSize(*o.Measurement, snpMeasurementSize); err != nil {
+			return fmt.Errorf(`"measurement": %w`, err)
+		}
+	}
+
+	if o.ReportID != nil {
+		if err := checkMeasurementSize(*o.ReportID, snpReportIDSize); err != nil {
+			return fmt.Errorf(`"report-id": %w`, err)
+		}
+	}
+
+	// the reserved bit is set in all valid policies, so its absence means
+	// that the policy has been mangled (e.g., truncated) on the way
+	if o.GuestPolicy != nil && *o.GuestPolicy&snpPolicyReserved == 0 {
+		return fmt.Errorf(`"guest-policy": reserved bit 17 not set in 0x%x`, uint64(*o.GuestPolicy))
+	}
+
+	return nil
+}
+
+func ToSNPTCBVersion(v interface{}) (*SNPTCBVersion, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "reported-tcb"`)
+	}
+
+	var tcb SNPTCBVersion
+
+	for key, val := range vMap {
+		i, err := int64PtrParser(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+		}
+
+		n := *(i.(*int64))
+		if n < 0 || n > 0xff {
+			return nil, fmt.Errorf(`invalid value for %q: %d out of range`, key, n)
+		}
+
+		svn := uint8(n)
+
+		switch key {
+		case "bootloader":
+			tcb.Bootloader = &svn
+		case "tee":
+			tcb.TEE = &svn
+		case "snp":
+			tcb.SNP = &svn
+		case "microcode":
+			tcb.Microcode = &svn
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "reported-tcb" object`, key)
+		}
+	}
+
+	return &tcb, nil
+}
+
+func ToVeraisonSNPInfo(v interface{}) (*VeraisonSNPInfo, error) {
+	vMap, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(`unexpected format for "snp-info"`)
+	}
+
+	var info VeraisonSNPInfo
+
+	for key, val := range vMap {
+		switch key {
+		case "measurement":
+			m, err := measurementParser(val, snpMeasurementSize)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "measurement": %w`, err)
+			}
+			info.Measurement = &m
+		case "report-id":
+			m, err := measurementParser(val, snpReportIDSize)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "report-id": %w`, err)
+			}
+			info.ReportID = &m
+		case "reported-tcb":
+			tcb, err := ToSNPTCBVersion(val)
+			if err != nil {
+				return nil, err
+			}
+			info.ReportedTCB = tcb
+		case "guest-policy":
+			i, err := int64PtrParser(val)
+			if err != nil {
+				return nil, fmt.Errorf(`invalid value for "guest-policy": %w`, err)
+			}
+			n := *(i.(*int64))
+			if n < 0 {
+				return nil, errors.New(`invalid value for "guest-policy": negative`)
+			}
+			policy := SNPGuestPolicy(n)
+			info.GuestPolicy = &policy
+		default:
+			return nil, fmt.Errorf(`found unknown key %q in "snp-info" object`, key)
+		}
+	}
+
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSNPGuestPolicy(t *testing.T) {
+	p := SNPGuestPolicy(0x1b0102)
+
+	assert.Equal(t, uint8(1), p.ABIMajor())
+	assert.Equal(t, uint8(2), p.ABIMinor())
+	assert.True(t, p.SMTAllowed())
+	assert.True(t, p.DebugAllowed())
+	assert.True(t, p.SingleSocket())
+	assert.False(t, p.MigrationAgentAllowed())
+}
@@ -0,0 +1,352 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StatusPolicy derives the ear.status of an Appraisal from its trust vector.
+// DeriveStatus is given the current status and the trust vector, and returns
+// the status the Appraisal should have.
+type StatusPolicy interface {
+	DeriveStatus(current TrustTier, tv TrustVector) TrustTier
+}
+
+// StatusExplainer is implemented by the StatusPolicies that can tell which
+// trust vector claims drove the status they derive.  ExplainStatus returns the
+// same status as DeriveStatus, together with the (sorted) names of those
+// claims.  All the policies in this package implement it.  For other
+// policies, ExplainStatus works out the claims by masking them one at a time.
+type StatusExplainer interface {
+	ExplainStatus(current TrustTier, tv TrustVector) (TrustTier, []string)
+}
+
+// StatusExplanation says how the status of an Appraisal was derived from its
+// trust vector
+type StatusExplanation struct {
+	// Previous is the status before the update
+	Previous TrustTier `json:"previous"`
+	// Status is the derived status
+	Status TrustTier `json:"status"`
+	// Claims are the names of the trust vector claims that forced Status.
+	// If empty, the status has not been derived from the trust vector,
+	// e.g., because it had been set to a lower tier beforehand.
+	Claims []string `json:"claims,omitempty"`
+}
+
+// WorstClaimPolicy is the default StatusPolicy.  For every claim that has been
+// made (i.e., is not in TrustTierNone), if the claim's trust tier is lower than
+// that of the status, the status is adjusted to the claim's tier.
+type WorstClaimPolicy struct{}
+
+func (WorstClaimPolicy) DeriveStatus(current TrustTier, tv TrustVector) TrustTier {
+	status, _ := worstClaim(current, tv.AsMap(), nil)
+	return status
+}
+
+// ExplainStatus reports the claims whose tier is the derived status
+func (WorstClaimPolicy) ExplainStatus(current TrustTier, tv TrustVector) (TrustTier, []string) {
+	return worstClaim(current, tv.AsMap(), nil)
+}
+
+// ClaimSubsetPolicy is like WorstClaimPolicy, but only takes into account the
+// trust vector claims listed in Claims (e.g., "executables", "hardware").
+// This is useful for schemes that intentionally exclude certain vector
+// dimensions from the overall status.
+type ClaimSubsetPolicy struct {
+	Claims []string
+}
+
+func (o ClaimSubsetPolicy) DeriveStatus(current TrustTier, tv TrustVector) TrustTier {
+	status, _ := o.ExplainStatus(current, tv)
+	return status
+}
+
+// ExplainStatus reports the claims in the subset whose tier is the derived
+// status
+func (o ClaimSubsetPolicy) ExplainStatus(current TrustTier, tv TrustVector) (TrustTier, []string) {
+	include := make(map[string]bool, len(o.Claims))
+	for _, c := range o.Claims {
+		include[c] = true
+	}
+
+	return worstClaim(current, tv.AsMap(), include)
+}
+
+// WeightedPolicy computes the weighted mean of the tiers of the claims that
+// have been made, using the weights associated with the trust vector claim
+// names in Weights (claims with no associated weight, or a zero weight, are
+// ignored).  Tiers, rather than claim values, are averaged since a negative
+// value falls in the same tier as a positive one.  The tier in which the mean
+// falls is then used like the tier of a single claim in WorstClaimPolicy,
+// i.e., the status is only ever lowered.
+type WeightedPolicy struct {
+	Weights map[string]uint
+}
+
+func (o WeightedPolicy) DeriveStatus(current TrustTier, tv TrustVector) TrustTier {
+	status, _ := o.ExplainStatus(current, tv)
+	return status
+}
+
+// ExplainStatus reports the weighted claims whose tier is no better than that
+// of the weighted mean, i.e., those that pulled the mean down to its tier
+func (o WeightedPolicy) ExplainStatus(current TrustTier, tv TrustVector) (TrustTier, []string) {
+	var sum, total int
+
+	claims := tv.AsMap()
+
+	for name, claim := range claims {
+		weight := int(o.Weights[name])
+		if weight == 0 || claim.GetTier() == TrustTierNone {
+			continue
+		}
+
+		sum += weight * int(claim.GetTier())
+		total += weight
+	}
+
+	if total == 0 {
+		return current, nil
+	}
+
+	tier := TrustClaim(sum / total).GetTier()
+	if current >= tier {
+		return current, nil
+	}
+
+	var drivers []string
+
+	for name, claim := range claims {
+		if o.Weights[name] != 0 && claim.GetTier() >= tier {
+			drivers = append(drivers, name)
+		}
+	}
+
+	sort.Strings(drivers)
+
+	return tier, drivers
+}
+
+// AdvisoryPolicy wraps another StatusPolicy so that the trust vector claims
+// listed in Advisory are informational only, i.e., they are not taken into
+// account when deriving the status.  If Policy is nil, WorstClaimPolicy is
+// used.  Advisory must only list known claim names (see Validate): since
+// StatusPolicies cannot report errors, an AdvisoryPolicy that does not derives
+// TrustTierContraindicated, rather than silently taking the misnamed claim
+// into account.
+type AdvisoryPolicy struct {
+	Policy   StatusPolicy
+	Advisory []string
+}
+
+// NewAdvisoryPolicy returns an AdvisoryPolicy that wraps p, after checking
+// that the advisory claim names are known
+func NewAdvisoryPolicy(p StatusPolicy, advisory ...string) (AdvisoryPolicy, error) {
+	o := AdvisoryPolicy{Policy: p, Advisory: advisory}
+
+	if err := o.Validate(); err != nil {
+		return AdvisoryPolicy{}, err
+	}
+
+	return o, nil
+}
+
+// Validate checks that the advisory claim names are known
+func (o AdvisoryPolicy) Validate() error {
+	if _, err := (TrustVector{}).Mask(o.Advisory...); err != nil {
+		return fmt.Errorf("advisory claims: %w", err)
+	}
+	return nil
+}
+
+func (o AdvisoryPolicy) DeriveStatus(current TrustTier, tv TrustVector) TrustTier {
+	status, _ := o.ExplainStatus(current, tv)
+	return status
+}
+
+// ExplainStatus explains the status derived by the wrapped policy, which never
+// involves the advisory claims
+func (o AdvisoryPolicy) ExplainStatus(current TrustTier, tv TrustVector) (TrustTier, []string) {
+	var p StatusPolicy = WorstClaimPolicy{}
+	if o.Policy != nil {
+		p = o.Policy
+	}
+
+	masked, err := tv.Mask(o.Advisory...)
+	if err != nil {
+		return TrustTierContraindicated, nil
+	}
+
+	return explainStatus(p, current, masked)
+}
+
+// FloorPolicy wraps another StatusPolicy so that an Appraisal whose trust
+// vector does not meet Floor is contraindicated, regardless of the status
+// derived by Policy.  If Policy is nil, WorstClaimPolicy is used.
+type FloorPolicy struct {
+	Policy StatusPolicy
+	Floor  TrustFloor
+}
+
+func (o FloorPolicy) DeriveStatus(current TrustTier, tv TrustVector) TrustTier {
+	var p StatusPolicy = WorstClaimPolicy{}
+	if o.Policy != nil {
+		p = o.Policy
+	}
+
+	if !tv.MeetsFloor(o.Floor) {
+		return TrustTierContraindicated
+	}
+
+	return p.DeriveStatus(current, tv)
+}
+
+// ExplainStatus reports the claims below the floor, if any, or else explains
+// the status derived by the wrapped policy
+func (o FloorPolicy) ExplainStatus(current TrustTier, tv TrustVector) (TrustTier, []string) {
+	var p StatusPolicy = WorstClaimPolicy{}
+	if o.Policy != nil {
+		p = o.Policy
+	}
+
+	if below := tv.BelowFloor(o.Floor); len(below) > 0 {
+		return TrustTierContraindicated, below
+	}
+
+	return explainStatus(p, current, tv)
+}
+
+// explainStatus derives the status using p, and works out which claims drove
+// it.  Policies that do not implement StatusExplainer are probed by masking
+// each claim in turn: a claim drove the status if the status derived without
+// it is better.
+func explainStatus(p StatusPolicy, current TrustTier, tv TrustVector) (TrustTier, []string) {
+	if e, ok := p.(StatusExplainer); ok {
+		return e.ExplainStatus(current, tv)
+	}
+
+	status := p.DeriveStatus(current, tv)
+
+	var drivers []string
+
+	for name, claim := range tv.AsMap() {
+		if claim == NoClaim {
+			continue
+		}
+
+		// name comes from AsMap, so it cannot be unknown
+		masked, _ := tv.Mask(name)
+
+		if p.DeriveStatus(current, masked) < status {
+			drivers = append(drivers, name)
+		}
+	}
+
+	sort.Strings(drivers)
+
+	return status, drivers
+}
+
+// worstClaim returns the derived status, and the names of the claims whose
+// tier is that status (none, if the status is not lowered by any claim)
+func worstClaim(
+	current TrustTier,
+	claims map[string]TrustClaim,
+	include map[string]bool,
+) (TrustTier, []string) {
+	// iterate in a stable order, so that results do not depend on map
+	// ordering
+	names := make([]string, 0, len(claims))
+	for name := range claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var drivers []string
+
+	for _, name := range names {
+		if include != nil && !include[name] {
+			continue
+		}
+
+		claimTier := claims[name].GetTier()
+		switch {
+		case claimTier == TrustTierNone:
+		case current < claimTier:
+			current = claimTier
+			drivers = []string{name}
+		case current == claimTier && drivers != nil:
+			drivers = append(drivers, name)
+		}
+	}
+
+	return current, drivers
+}
+
+// StatusOption configures how UpdateStatusFromTrustVector derives the status
+// of Appraisals
+type StatusOption func(*statusOptions)
+
+type statusOptions struct {
+	policy   StatusPolicy
+	submods  map[string]StatusPolicy
+	advisory []string
+}
+
+func newStatusOptions(opts []StatusOption) *statusOptions {
+	o := &statusOptions{
+		policy:  WorstClaimPolicy{},
+		submods: map[string]StatusPolicy{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o statusOptions) policyFor(submod string) StatusPolicy {
+	if p, ok := o.submods[submod]; ok {
+		return o.withAdvisory(p)
+	}
+	return o.withAdvisory(o.policy)
+}
+
+func (o statusOptions) withAdvisory(p StatusPolicy) StatusPolicy {
+	if len(o.advisory) == 0 {
+		return p
+	}
+	return AdvisoryPolicy{Policy: p, Advisory: o.advisory}
+}
+
+// WithStatusPolicy sets the StatusPolicy used to derive the status of all
+// Appraisals that do not have a specific policy set via
+// WithSubmodStatusPolicy.  If not specified, WorstClaimPolicy is used.
+func WithStatusPolicy(p StatusPolicy) StatusOption {
+	return func(o *statusOptions) {
+		o.policy = p
+	}
+}
+
+// WithSubmodStatusPolicy sets the StatusPolicy used to derive the status of
+// the Appraisal associated with the named submod.  It is ignored when updating
+// a stand-alone Appraisal.
+func WithSubmodStatusPolicy(submod string, p StatusPolicy) StatusOption {
+	return func(o *statusOptions) {
+		o.submods[submod] = p
+	}
+}
+
+// WithAdvisoryClaims marks the named trust vector claims (e.g.,
+// "file-system") as advisory for all Appraisals: they are retained in the
+// trust vector, but do not affect the derived status, whatever the
+// StatusPolicy in use.  Unknown claim names are treated as AdvisoryPolicy
+// does, and reported as errors by ValidateStrict.
+func WithAdvisoryClaims(claims ...string) StatusOption {
+	return func(o *statusOptions) {
+		o.advisory = append(o.advisory, claims...)
+	}
+}
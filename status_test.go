@@ -0,0 +1,303 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorstClaimPolicy(t *testing.T) {
+	tv := TrustVector{
+		Executables:   ApprovedRuntimeClaim,
+		Configuration: UnsafeConfigClaim,
+	}
+
+	assert.Equal(t, TrustTierWarning,
+		WorstClaimPolicy{}.DeriveStatus(TrustTierAffirming, tv))
+	assert.Equal(t, TrustTierContraindicated,
+		WorstClaimPolicy{}.DeriveStatus(TrustTierContraindicated, tv))
+}
+
+func TestClaimSubsetPolicy(t *testing.T) {
+	tv := TrustVector{
+		Executables: ApprovedRuntimeClaim,
+		FileSystem:  ContraindicatedFilesClaim,
+	}
+
+	p := ClaimSubsetPolicy{Claims: []string{"executables", "hardware"}}
+	assert.Equal(t, TrustTierAffirming, p.DeriveStatus(TrustTierNone, tv))
+
+	p = ClaimSubsetPolicy{Claims: []string{"file-system"}}
+	assert.Equal(t, TrustTierContraindicated, p.DeriveStatus(TrustTierNone, tv))
+}
+
+func TestWeightedPolicy(t *testing.T) {
+	tv := TrustVector{
+		Executables:   ApprovedRuntimeClaim, // 2
+		Configuration: UnsafeConfigClaim,    // 32
+		Hardware:      NoClaim,
+	}
+
+	p := WeightedPolicy{Weights: map[string]uint{
+		"executables":   3,
+		"configuration": 1,
+		"hardware":      10,
+	}}
+	// (3*2 + 1*32) / 4 = 9 => affirming
+	assert.Equal(t, TrustTierAffirming, p.DeriveStatus(TrustTierNone, tv))
+
+	p.Weights["configuration"] = 3
+	// (3*2 + 3*32) / 6 = 17 => still affirming
+	assert.Equal(t, TrustTierAffirming, p.DeriveStatus(TrustTierNone, tv))
+
+	p.Weights["executables"] = 0
+	assert.Equal(t, TrustTierWarning, p.DeriveStatus(TrustTierNone, tv))
+
+	// the status is never raised
+	assert.Equal(t, TrustTierContraindicated,
+		p.DeriveStatus(TrustTierContraindicated, tv))
+
+	// no weighted claims made
+	p = WeightedPolicy{}
+	assert.Equal(t, TrustTierWarning, p.DeriveStatus(TrustTierWarning, tv))
+}
+
+func TestUpdateStatusFromTrustVector_policies(t *testing.T) {
+	ar := NewAttestationResult("platform", "test", "test")
+	ar.Submods["workload"] = &Appraisal{
+		Status:      NewTrustTier(TrustTierNone),
+		TrustVector: &TrustVector{},
+	}
+
+	for _, appraisal := range ar.Submods {
+		appraisal.TrustVector.Executables = ApprovedRuntimeClaim
+		appraisal.TrustVector.FileSystem = UnrecognizedFilesClaim
+	}
+
+	ar.UpdateStatusFromTrustVector(
+		WithStatusPolicy(ClaimSubsetPolicy{Claims: []string{"executables"}}),
+		WithSubmodStatusPolicy("platform", WorstClaimPolicy{}),
+	)
+
+	assert.Equal(t, TrustTierWarning, *ar.Submods["platform"].Status)
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["workload"].Status)
+}
+
+func TestAppraisal_UpdateStatusFromTrustVector_no_vector(t *testing.T) {
+	appraisal := Appraisal{Status: NewTrustTier(TrustTierWarning)}
+
+	appraisal.UpdateStatusFromTrustVector()
+	assert.Equal(t, TrustTierWarning, *appraisal.Status)
+}
+
+func TestAdvisoryPolicy(t *testing.T) {
+	tv := TrustVector{
+		Executables: ApprovedRuntimeClaim,
+		FileSystem:  ContraindicatedFilesClaim,
+	}
+
+	p := AdvisoryPolicy{Advisory: []string{"file-system"}}
+	assert.Equal(t, TrustTierAffirming, p.DeriveStatus(TrustTierNone, tv))
+
+	p = AdvisoryPolicy{
+		Policy:   ClaimSubsetPolicy{Claims: []string{"file-system", "hardware"}},
+		Advisory: []string{"file-system"},
+	}
+	assert.Equal(t, TrustTierNone, p.DeriveStatus(TrustTierNone, tv))
+}
+
+func TestAdvisoryPolicy_unknown_claim(t *testing.T) {
+	_, err := NewAdvisoryPolicy(nil, "file-system", "filesystem")
+	assert.EqualError(t, err, `advisory claims: unknown trust vector claim "filesystem"`)
+
+	p, err := NewAdvisoryPolicy(nil, "file-system")
+	require.NoError(t, err)
+	assert.NoError(t, p.Validate())
+
+	tv := TrustVector{
+		Executables: ApprovedRuntimeClaim,
+		FileSystem:  ContraindicatedFilesClaim,
+	}
+
+	// the misspelt claim is not silently taken into account
+	p = AdvisoryPolicy{Advisory: []string{"filesystem"}}
+	assert.Equal(t, TrustTierContraindicated, p.DeriveStatus(TrustTierNone, tv))
+
+	ar := NewAttestationResult("test", "test", "test")
+	ar.Submods["test"].TrustVector.Executables = ApprovedRuntimeClaim
+	ar.Submods["test"].TrustVector.FileSystem = UnrecognizedFilesClaim
+
+	ar.UpdateStatusFromTrustVector(WithAdvisoryClaims("filesystem"))
+	assert.Equal(t, TrustTierContraindicated, *ar.Submods["test"].Status)
+
+	err = ar.ValidateStrict(WithAdvisoryClaims("filesystem"))
+	assert.EqualError(t, err, `advisory claims: unknown trust vector claim "filesystem"`)
+}
+
+func TestUpdateStatusFromTrustVector_advisory(t *testing.T) {
+	ar := NewAttestationResult("test", "test", "test")
+	ar.Submods["test"].TrustVector.Executables = ApprovedRuntimeClaim
+	ar.Submods["test"].TrustVector.FileSystem = UnrecognizedFilesClaim
+
+	ar.UpdateStatusFromTrustVector(WithAdvisoryClaims("file-system"))
+	assert.Equal(t, TrustTierAffirming, *ar.Submods["test"].Status)
+
+	// the advisory claim is retained
+	assert.Equal(t, UnrecognizedFilesClaim, ar.Submods["test"].TrustVector.FileSystem)
+
+	ar.Submods["test"].UpdateStatusFromTrustVector()
+	assert.Equal(t, TrustTierWarning, *ar.Submods["test"].Status)
+}
+
+func TestFloorPolicy(t *testing.T) {
+	floor := TrustFloor{Configuration: TrustTierAffirming}
+
+	p := FloorPolicy{Floor: floor}
+
+	tv := TrustVector{
+		Configuration: ApprovedConfigClaim,
+		Executables:   UnrecognizedRuntimeClaim,
+	}
+	assert.Equal(t, TrustTierWarning, p.DeriveStatus(TrustTierNone, tv))
+
+	tv.Configuration = UnsafeConfigClaim
+	assert.Equal(t, TrustTierContraindicated, p.DeriveStatus(TrustTierNone, tv))
+
+	p.Policy = ClaimSubsetPolicy{Claims: []string{"configuration"}}
+	tv.Configuration = ApprovedConfigClaim
+	assert.Equal(t, TrustTierAffirming, p.DeriveStatus(TrustTierNone, tv))
+}
+
+// hardwarePolicy is a StatusPolicy that does not implement StatusExplainer
+type hardwarePolicy struct{}
+
+func (hardwarePolicy) DeriveStatus(current TrustTier, tv TrustVector) TrustTier {
+	if tier := tv.Hardware.GetTier(); current < tier {
+		return tier
+	}
+	return current
+}
+
+func TestExplainStatus_policies(t *testing.T) {
+	tv := TrustVector{
+		InstanceIdentity: TrustworthyInstanceClaim,
+		Executables:      ContraindicatedRuntimeClaim,
+		FileSystem:       ContraindicatedFilesClaim,
+		Configuration:    UnsafeConfigClaim,
+		Hardware:         UnsafeHardwareClaim,
+	}
+
+	tvs := []struct {
+		policy   StatusPolicy
+		current  TrustTier
+		status   TrustTier
+		expected []string
+	}{
+		{
+			policy:   WorstClaimPolicy{},
+			status:   TrustTierContraindicated,
+			expected: []string{"executables", "file-system"},
+		},
+		{
+			policy:   WorstClaimPolicy{},
+			current:  TrustTierContraindicated,
+			status:   TrustTierContraindicated,
+			expected: nil,
+		},
+		{
+			policy:   ClaimSubsetPolicy{Claims: []string{"configuration", "instance-identity"}},
+			status:   TrustTierWarning,
+			expected: []string{"configuration"},
+		},
+		{
+			policy:   AdvisoryPolicy{Advisory: []string{"executables", "file-system"}},
+			status:   TrustTierWarning,
+			expected: []string{"configuration", "hardware"},
+		},
+		{
+			policy:   FloorPolicy{Floor: TrustFloor{Hardware: TrustTierAffirming}},
+			status:   TrustTierContraindicated,
+			expected: []string{"hardware"},
+		},
+		{
+			policy: WeightedPolicy{Weights: map[string]uint{
+				"instance-identity": 3,
+				"configuration":     1,
+			}},
+			// (3*2 + 1*32) / 4 = 9 => affirming
+			status:   TrustTierAffirming,
+			expected: []string{"configuration", "instance-identity"},
+		},
+		{
+			policy:   hardwarePolicy{},
+			status:   TrustTierWarning,
+			expected: []string{"hardware"},
+		},
+		{
+			policy:   AdvisoryPolicy{Policy: hardwarePolicy{}, Advisory: []string{"hardware"}},
+			status:   TrustTierNone,
+			expected: nil,
+		},
+	}
+
+	for i, tv0 := range tvs {
+		status, claims := explainStatus(tv0.policy, tv0.current, tv)
+		assert.Equal(t, tv0.status, status, "failed test vector at index %d", i)
+		assert.Equal(t, tv0.expected, claims, "failed test vector at index %d", i)
+
+		// explaining never changes the outcome
+		assert.Equal(t, tv0.policy.DeriveStatus(tv0.current, tv), status, "failed test vector at index %d", i)
+	}
+}
+
+func TestAttestationResult_ExplainStatus(t *testing.T) {
+	ar := NewAttestationResult("platform", "test", "test")
+	ar.Submods["platform"].TrustVector.Hardware = GenuineHardwareClaim
+	ar.Submods["platform"].TrustVector.Executables = ContraindicatedRuntimeClaim
+	ar.Submods["workload"] = &Appraisal{
+		Status:      NewTrustTier(TrustTierWarning),
+		TrustVector: &TrustVector{Executables: ApprovedRuntimeClaim},
+	}
+	ar.Submods["opaque"] = &Appraisal{Status: NewTrustTier(TrustTierAffirming)}
+
+	actual := ar.ExplainStatus(WithAdvisoryClaims("file-system"))
+
+	assert.Equal(t, map[string]StatusExplanation{
+		"platform": {
+			Previous: TrustTierNone,
+			Status:   TrustTierContraindicated,
+			Claims:   []string{"executables"},
+		},
+		"workload": {
+			Previous: TrustTierWarning,
+			Status:   TrustTierWarning,
+		},
+		"opaque": {
+			Previous: TrustTierAffirming,
+			Status:   TrustTierAffirming,
+		},
+	}, actual)
+
+	assert.Equal(t, TrustTierContraindicated, *ar.Submods["platform"].Status)
+}
+
+func TestAppraisal_ExplainStatus(t *testing.T) {
+	appraisal := Appraisal{
+		TrustVector: &TrustVector{
+			Configuration: UnsafeConfigClaim,
+			FileSystem:    ContraindicatedFilesClaim,
+		},
+	}
+
+	e := appraisal.ExplainStatus(WithAdvisoryClaims("file-system"))
+	assert.Equal(t, StatusExplanation{
+		Previous: TrustTierNone,
+		Status:   TrustTierWarning,
+		Claims:   []string{"configuration"},
+	}, e)
+	assert.Equal(t, TrustTierWarning, *appraisal.Status)
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TokenStore is a collection of signed EARs, each identified by a string (a
+// file name, a database key, etc.) that a Migration or a RetentionPolicy works
+// on
+type TokenStore interface {
+	// List returns the identifiers of the stored tokens
+	List() ([]string, error)
+	// Load returns the token with the supplied identifier
+	Load(id string) ([]byte, error)
+	// Replace overwrites the stored tokens with the supplied ones.  It
+	// should either write all of them, or none.
+	Replace(tokens map[string][]byte) error
+	// Delete removes the tokens with the supplied identifiers, leaving the
+	// others alone.  Identifiers not found in the store are ignored.
+	Delete(ids ...string) error
+}
+
+// MemoryTokenStore is an in-memory TokenStore, safe for concurrent use.  It is
+// mostly useful in tests, and for small batches of tokens.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string][]byte
+}
+
+// NewMemoryTokenStore returns a MemoryTokenStore holding a copy of the
+// supplied tokens
+func NewMemoryTokenStore(tokens map[string][]byte) *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: copyTokens(tokens)}
+}
+
+// List returns the identifiers of the stored tokens, in lexicographic order
+func (o *MemoryTokenStore) List() ([]string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ids := make([]string, 0, len(o.tokens))
+	for id := range o.tokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// Load returns the token with the supplied identifier
+func (o *MemoryTokenStore) Load(id string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	t, ok := o.tokens[id]
+	if !ok {
+		return nil, fmt.Errorf("token %q not found", id)
+	}
+
+	return append([]byte(nil), t...), nil
+}
+
+// Replace overwrites the stored tokens with the supplied ones
+func (o *MemoryTokenStore) Replace(tokens map[string][]byte) error {
+	c := copyTokens(tokens)
+
+	o.mu.Lock()
+	o.tokens = c
+	o.mu.Unlock()
+
+	return nil
+}
+
+// Delete removes the tokens with the supplied identifiers
+func (o *MemoryTokenStore) Delete(ids ...string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, id := range ids {
+		delete(o.tokens, id)
+	}
+
+	return nil
+}
+
+func copyTokens(tokens map[string][]byte) map[string][]byte {
+	ret := make(map[string][]byte, len(tokens))
+	for id, t := range tokens {
+		ret[id] = append([]byte(nil), t...)
+	}
+	return ret
+}
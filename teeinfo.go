@@ -15,12 +15,15 @@ type VeraisonTeeInfo struct {
 	Evidence   *[]byte `json:"evidence,omitempty"`
 }
 
-func str(v interface{}) string {
+// str returns v as a string. Unlike a plain type assertion with a zero-value
+// fallback, it reports an error when v is not a string so that malformed
+// claims are rejected rather than silently coerced.
+func str(v interface{}) (string, error) {
 	s, ok := v.(string)
 	if !ok {
-		return ""
+		return "", fmt.Errorf("expecting string, found %T", v)
 	}
-	return s
+	return s, nil
 }
 
 func ToVeraisonTeeInfo(v interface{}) (*VeraisonTeeInfo, error) {
@@ -32,14 +35,18 @@ func ToVeraisonTeeInfo(v interface{}) (*VeraisonTeeInfo, error) {
 	var teeInfo VeraisonTeeInfo
 
 	for key, val := range vMap {
-		s := str(val)
+		s, err := str(val)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid value for %q: %w`, key, err)
+		}
+
 		switch key {
 		case "tee-name":
 			teeInfo.TeeName = &s
 		case "evidence-id":
 			teeInfo.EvidenceID = &s
 		case "evidence":
-			buf, err := base64.StdEncoding.DecodeString(str(val))
+			buf, err := base64.StdEncoding.DecodeString(s)
 			if err != nil {
 				return nil, fmt.Errorf(`decoding "evidence": %w`, err)
 			}
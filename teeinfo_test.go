@@ -0,0 +1,60 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToVeraisonTeeInfo_ok(t *testing.T) {
+	tv := map[string]interface{}{
+		"tee-name":    testTeeName,
+		"evidence-id": testEvidenceID,
+		"evidence":    "ZXZpZGVuY2U=",
+	}
+
+	actual, err := ToVeraisonTeeInfo(tv)
+	require.NoError(t, err)
+	assert.Equal(t, testTeeName, *actual.TeeName)
+	assert.Equal(t, testEvidenceID, *actual.EvidenceID)
+	assert.Equal(t, testEvidence, *actual.Evidence)
+}
+
+func TestToVeraisonTeeInfo_fail_not_a_string(t *testing.T) {
+	tvs := []struct {
+		info     map[string]interface{}
+		expected string
+	}{
+		{
+			info: map[string]interface{}{
+				"tee-name":    1234,
+				"evidence-id": testEvidenceID,
+			},
+			expected: `invalid value for "tee-name": expecting string, found int`,
+		},
+		{
+			info: map[string]interface{}{
+				"tee-name":    testTeeName,
+				"evidence-id": true,
+			},
+			expected: `invalid value for "evidence-id": expecting string, found bool`,
+		},
+		{
+			info: map[string]interface{}{
+				"tee-name":    testTeeName,
+				"evidence-id": testEvidenceID,
+				"evidence":    []byte("evidence"),
+			},
+			expected: `invalid value for "evidence": expecting string, found []uint8`,
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := ToVeraisonTeeInfo(tv.info)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TimestampClaim is the name of the claim carrying the RFC3161 time-stamp
+// token obtained at signing time
+const TimestampClaim = "ear.veraison.timestamp"
+
+// TimestampAuthority obtains RFC3161 time-stamp tokens.  Timestamp is passed
+// the SHA-256 digest of the data to be time-stamped, and returns the
+// DER-encoded TimeStampToken.  See the tsa package for an implementation
+// using the HTTP transport of RFC3161.
+type TimestampAuthority interface {
+	Timestamp(digest []byte) ([]byte, error)
+}
+
+// TimestampVerifier checks RFC3161 time-stamp tokens.  VerifyTimestamp must
+// check that the token is genuine and that it covers the supplied SHA-256
+// digest, and return the time at which it has been generated.  See the tsa
+// package for an implementation.
+type TimestampVerifier interface {
+	VerifyTimestamp(token, digest []byte) (time.Time, error)
+}
+
+// WithTimestampAuthority makes Sign, SignCWT and SignSD obtain a time-stamp
+// token for the claims-set from the supplied TSA, and carry it in the
+// ear.veraison.timestamp claim.  This gives relying parties independent
+// evidence of when the result has been issued, rather than relying on the iat
+// claim asserted by the verifier alone.  The token covers the SHA-256 digest
+// of the canonical JSON encoding (see MarshalCanonicalJSON) of all the other
+// claims.  For SD-JWTs, these are the claims signed by the issuer, in which
+// the selectively disclosable claims are replaced by their digests, so that
+// the time-stamp can be checked whatever the disclosures.
+func WithTimestampAuthority(tsa TimestampAuthority) SignOption {
+	return func(o *signOptions) {
+		o.tsa = tsa
+	}
+}
+
+// WithTimestampVerifier makes Verify, VerifyCWT and VerifySD require an
+// ear.veraison.timestamp claim, check it using the supplied TimestampVerifier,
+// and check that the iat claim does not postdate the time-stamp (allowing for
+// the skew set with WithAcceptableSkew).  The time-stamp is recorded in the
+// VerificationReport, if one is requested.
+func WithTimestampVerifier(v TimestampVerifier) VerifyOption {
+	return func(o *verifyOptions) {
+		o.tsv = v
+	}
+}
+
+func (o *AttestationResult) addTimestamp(tsa TimestampAuthority) error {
+	o.VeraisonTimestamp = nil
+
+	j, err := json.Marshal(o.AsMap())
+	if err != nil {
+		return fmt.Errorf("encoding claims-set for time-stamping: %w", err)
+	}
+
+	ts, err := obtainTimestamp(tsa, j)
+	if err != nil {
+		return err
+	}
+
+	o.VeraisonTimestamp = &ts
+
+	return nil
+}
+
+// obtainTimestamp obtains from the TSA a time-stamp token covering the JSON
+// claims-set
+func obtainTimestamp(tsa TimestampAuthority, claimsSet []byte) (B64Url, error) {
+	digest, err := timestampDigest(claimsSet)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := tsa.Timestamp(digest)
+	if err != nil {
+		return nil, fmt.Errorf("obtaining time-stamp: %w", err)
+	}
+
+	return B64Url(token), nil
+}
+
+// checkTimestamp verifies the time-stamp carried by the claims-set in payload
+// and returns its generation time
+func (o AttestationResult) checkTimestamp(
+	payload []byte,
+	tsv TimestampVerifier,
+	skew time.Duration,
+) (time.Time, error) {
+	if o.VeraisonTimestamp == nil {
+		return time.Time{}, fmt.Errorf("%q claim not found", TimestampClaim)
+	}
+
+	digest, err := timestampDigest(payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	genTime, err := tsv.VerifyTimestamp(*o.VeraisonTimestamp, digest)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("verifying time-stamp: %w", err)
+	}
+
+	if o.IssuedAt != nil {
+		iat := time.Unix(*o.IssuedAt, 0)
+		if iat.After(genTime.Add(skew)) {
+			return time.Time{}, fmt.Errorf("iat (%s) is later than the time-stamp (%s)",
+				iat.UTC().Format(time.RFC3339), genTime.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return genTime, nil
+}
+
+// timestampDigest returns the SHA-256 digest of the canonical JSON encoding of
+// the claims-set, without the time-stamp claim
+func timestampDigest(claimsSet []byte) ([]byte, error) {
+	var m map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(claimsSet))
+	dec.UseNumber()
+
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	delete(m, TimestampClaim)
+
+	j, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := canonicalizeJSON(j)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalizing claims-set: %w", err)
+	}
+
+	digest := sha256.Sum256(c)
+
+	return digest[:], nil
+}
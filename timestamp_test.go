@@ -0,0 +1,264 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTSA issues mock time-stamp tokens, made of the generation time followed
+// by the time-stamped digest.  The RFC3161 tokens themselves are tested in the
+// tsa package.
+type testTSA struct {
+	now time.Time
+	// digest, if not nil, is stamped in place of the requested one
+	digest []byte
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	return &testTSA{now: time.Unix(testIAT, 0).Add(time.Minute).UTC()}
+}
+
+func (o testTSA) Timestamp(digest []byte) ([]byte, error) {
+	if o.digest != nil {
+		digest = o.digest
+	}
+
+	token := make([]byte, 8, 8+len(digest))
+	binary.BigEndian.PutUint64(token, uint64(o.now.Unix()))
+
+	return append(token, digest...), nil
+}
+
+// testTSV checks the tokens issued by testTSA
+type testTSV struct{}
+
+func (testTSV) VerifyTimestamp(token, digest []byte) (time.Time, error) {
+	if len(token) < 8 {
+		return time.Time{}, errors.New("malformed time-stamp token")
+	}
+
+	if !bytes.Equal(token[8:], digest) {
+		return time.Time{}, errors.New("time-stamp does not cover the claims-set")
+	}
+
+	return time.Unix(int64(binary.BigEndian.Uint64(token)), 0), nil
+}
+
+func testSignTimestamped(t *testing.T, tsa TimestampAuthority, opts ...SignOption) []byte {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	opts = append(opts, WithTimestampAuthority(tsa))
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, opts...)
+	require.NoError(t, err)
+
+	return token
+}
+
+func testVerifyTimestamped(token []byte, opts ...VerifyOption) (*AttestationResult, error) {
+	vfyK, err := jwk.ParseKey([]byte(testECDSAPublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var ar AttestationResult
+
+	return &ar, ar.Verify(token, jwa.ES256, vfyK, opts...)
+}
+
+func TestTimestamp_round_trip(t *testing.T) {
+	tsa := newTestTSA(t)
+
+	for i, opts := range [][]SignOption{nil, {WithCanonicalJSON()}} {
+		token := testSignTimestamped(t, tsa, opts...)
+
+		var report VerificationReport
+
+		ar, err := testVerifyTimestamped(token,
+			WithTimestampVerifier(testTSV{}),
+			WithVerificationReport(&report))
+		require.NoError(t, err, "failed test vector at index %d", i)
+
+		require.NotNil(t, ar.VeraisonTimestamp)
+		require.NotNil(t, report.TimestampedAt)
+		assert.True(t, tsa.now.Equal(*report.TimestampedAt), "failed test vector at index %d", i)
+	}
+}
+
+func TestTimestamp_ignored_without_verifier(t *testing.T) {
+	token := testSignTimestamped(t, newTestTSA(t))
+
+	var report VerificationReport
+
+	ar, err := testVerifyTimestamped(token, WithVerificationReport(&report))
+	require.NoError(t, err)
+	assert.NotNil(t, ar.VeraisonTimestamp)
+	assert.Nil(t, report.TimestampedAt)
+}
+
+func TestTimestamp_missing(t *testing.T) {
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	_, err = testVerifyTimestamped(token, WithTimestampVerifier(testTSV{}))
+	assert.EqualError(t, err, `"ear.veraison.timestamp" claim not found`)
+}
+
+func TestTimestamp_ko(t *testing.T) {
+	stale := newTestTSA(t)
+	stale.now = time.Unix(testIAT, 0).Add(-time.Hour).UTC()
+
+	wrongDigest := newTestTSA(t)
+	wrongDigest.digest = make([]byte, sha256.Size)
+
+	tvs := []struct {
+		tsa      *testTSA
+		expected string
+	}{
+		{
+			tsa:      wrongDigest,
+			expected: "verifying time-stamp: time-stamp does not cover the claims-set",
+		},
+		{
+			tsa:      stale,
+			expected: "iat (2022-10-18T11:09:33Z) is later than the time-stamp (2022-10-18T10:09:33Z)",
+		},
+	}
+
+	for i, tv := range tvs {
+		token := testSignTimestamped(t, tv.tsa)
+
+		_, err := testVerifyTimestamped(token, WithTimestampVerifier(testTSV{}))
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+
+	// a generous skew accommodates a TSA clock running late
+	token := testSignTimestamped(t, stale)
+	_, err := testVerifyTimestamped(token,
+		WithTimestampVerifier(testTSV{}),
+		WithAcceptableSkew(2*time.Hour))
+	assert.NoError(t, err)
+}
+
+func TestTimestamp_tampered_claims(t *testing.T) {
+	tsa := newTestTSA(t)
+
+	// time-stamp a different claims-set, and transplant the token
+	ar := testAttestationResultsWithVeraisonExtns
+	other := TrustTierWarning
+	ar.Submods = map[string]*Appraisal{"test": {Status: &other}}
+	require.NoError(t, ar.addTimestamp(tsa))
+
+	signed := testAttestationResultsWithVeraisonExtns
+	signed.VeraisonTimestamp = ar.VeraisonTimestamp
+
+	sigK, err := jwk.ParseKey([]byte(testECDSAPrivateKey))
+	require.NoError(t, err)
+
+	token, err := signed.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	_, err = testVerifyTimestamped(token, WithTimestampVerifier(testTSV{}))
+	assert.EqualError(t, err, "verifying time-stamp: time-stamp does not cover the claims-set")
+}
+
+func TestTimestamp_CBOR_round_trip(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	require.NoError(t, ar.addTimestamp(newTestTSA(t)))
+
+	data, err := ar.MarshalCBOR()
+	require.NoError(t, err)
+
+	var actual AttestationResult
+	require.NoError(t, actual.UnmarshalCBOR(data))
+	assert.Equal(t, ar.VeraisonTimestamp, actual.VeraisonTimestamp)
+}
+
+func TestTimestamp_CWT(t *testing.T) {
+	tsa := newTestTSA(t)
+	signer, verifier := testCOSESignerVerifier(t)
+
+	untimestamped, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	token, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer, WithTimestampAuthority(tsa))
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	err = ar.VerifyCWT(token, verifier, WithTimestampVerifier(testTSV{}))
+	require.NoError(t, err)
+	assert.NotNil(t, ar.VeraisonTimestamp)
+
+	ar = AttestationResult{}
+	err = ar.VerifyCWT(untimestamped, verifier, WithTimestampVerifier(testTSV{}))
+	assert.EqualError(t, err, `"ear.veraison.timestamp" claim not found`)
+
+	wrongDigest := newTestTSA(t)
+	wrongDigest.digest = make([]byte, sha256.Size)
+
+	token, err = testAttestationResultsWithVeraisonExtns.SignCWT(signer, WithTimestampAuthority(wrongDigest))
+	require.NoError(t, err)
+
+	ar = AttestationResult{}
+	err = ar.VerifyCWT(token, verifier, WithTimestampVerifier(testTSV{}))
+	assert.EqualError(t, err, "verifying time-stamp: time-stamp does not cover the claims-set")
+}
+
+func TestTimestamp_SDJWT(t *testing.T) {
+	tsa := newTestTSA(t)
+	sigK, vfyK := testKeyPair(t)
+
+	pointers := []string{"/submods/*/ear.veraison.policy-claims"}
+
+	untimestamped, err := testAttestationResultsWithVeraisonExtns.SignSD(jwa.ES256, sigK, pointers)
+	require.NoError(t, err)
+
+	sd, err := testAttestationResultsWithVeraisonExtns.SignSD(
+		jwa.ES256, sigK, pointers, WithTimestampAuthority(tsa),
+	)
+	require.NoError(t, err)
+
+	tsv := WithTimestampVerifier(testTSV{})
+
+	// the time-stamp can be checked whatever the disclosures
+	for i, token := range []SDJWT{*sd, {Token: sd.Token}} {
+		var ar AttestationResult
+
+		require.NoError(t, ar.VerifySD(token.Bytes(), jwa.ES256, vfyK, tsv), "failed test vector at index %d", i)
+		assert.NotNil(t, ar.VeraisonTimestamp, "failed test vector at index %d", i)
+	}
+
+	var ar AttestationResult
+
+	err = ar.VerifySD(untimestamped.Bytes(), jwa.ES256, vfyK, tsv)
+	assert.EqualError(t, err, `"ear.veraison.timestamp" claim not found`)
+
+	wrongDigest := newTestTSA(t)
+	wrongDigest.digest = make([]byte, sha256.Size)
+
+	sd, err = testAttestationResultsWithVeraisonExtns.SignSD(
+		jwa.ES256, sigK, pointers, WithTimestampAuthority(wrongDigest),
+	)
+	require.NoError(t, err)
+
+	ar = AttestationResult{}
+	err = ar.VerifySD(sd.Bytes(), jwa.ES256, vfyK, WithTimestampVerifier(testTSV{}))
+	assert.EqualError(t, err, "verifying time-stamp: time-stamp does not cover the claims-set")
+}
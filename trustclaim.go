@@ -353,26 +353,10 @@ func getTrustClaimFromString(s string) (TrustClaim, error) {
 		return getTrustClaimFromInt(i)
 	}
 
-	detailsMaps := []detailsMap{
-		configurationDetails,
-		executablesDetails,
-		fileSystemDetails,
-		hardwareDetails,
-		instanceIdentityDetails,
-		noneDetails,
-		runtimeOpaqueDetails,
-		sourcedDataDetails,
-		storageOpaqueDetails,
-	}
-
 	canon := strings.Trim(xstrings.Translate(xstrings.ToSnakeCase(s), ".- ", "_"), " \t")
 
-	for _, dm := range detailsMaps {
-		for claim, deets := range dm {
-			if deets.tag == canon {
-				return claim, nil
-			}
-		}
+	if claim, ok := defaultRegistry.claimTag(canon); ok {
+		return claim, nil
 	}
 
 	return NoClaim, fmt.Errorf("not a valid TrustClaim value: %q", s)
@@ -476,15 +460,15 @@ func (o TrustClaim) IsContraindicated() bool {
 	return (o >= -128 && o <= -97) || (o >= 96 && o <= 127)
 }
 
-func (o TrustClaim) detailsPrinter(dm detailsMap, short bool, color bool) string {
+func (o TrustClaim) detailsPrinter(claim string, short bool, color bool) string {
 	// "none" statuses have shared semantics
 	if o.IsNone() {
 		return noneToString(o, short, color)
 	}
 
-	// other statuses are per-category therefore they are dispatched to the
-	// associated detailsMap
-	s, ok := dm[o]
+	// other statuses are per-category therefore they are looked up in the
+	// values of the named claim
+	s, ok := defaultRegistry.claimValue(claim, o)
 	if !ok {
 		return fmt.Sprintf("unknown code-point %d", o)
 	}
@@ -496,38 +480,6 @@ func (o TrustClaim) detailsPrinter(dm detailsMap, short bool, color bool) string
 	return s.long
 }
 
-func (o TrustClaim) asInstanceIdentityDetails(short, color bool) string {
-	return o.detailsPrinter(instanceIdentityDetails, short, color)
-}
-
-func (o TrustClaim) asConfigurationDetails(short, color bool) string {
-	return o.detailsPrinter(configurationDetails, short, color)
-}
-
-func (o TrustClaim) asExecutablesDetails(short, color bool) string {
-	return o.detailsPrinter(executablesDetails, short, color)
-}
-
-func (o TrustClaim) asFileSystemDetails(short, color bool) string {
-	return o.detailsPrinter(fileSystemDetails, short, color)
-}
-
-func (o TrustClaim) asHardwareDetails(short, color bool) string {
-	return o.detailsPrinter(hardwareDetails, short, color)
-}
-
-func (o TrustClaim) asRuntimeOpaqueDetails(short, color bool) string {
-	return o.detailsPrinter(runtimeOpaqueDetails, short, color)
-}
-
-func (o TrustClaim) asStorageOpaqueDetails(short, color bool) string {
-	return o.detailsPrinter(storageOpaqueDetails, short, color)
-}
-
-func (o TrustClaim) asSourcedDataDetails(short, color bool) string {
-	return o.detailsPrinter(sourcedDataDetails, short, color)
-}
-
 func noneToString(tc TrustClaim, short, color bool) string {
 	s, ok := noneDetails[tc]
 	if ok {
@@ -163,6 +163,72 @@ func ToTrustTier(v interface{}) (*TrustTier, error) {
 	return &tier, err
 }
 
+// rank maps trust tiers onto the AR4SI ordering, in which TrustTierNone (no
+// appraisal) is below any tier resulting from an appraisal
+func (o TrustTier) rank() int {
+	switch o {
+	case TrustTierAffirming:
+		return 3
+	case TrustTierWarning:
+		return 2
+	case TrustTierContraindicated:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Compare returns a positive number if the receiver conveys more trust than
+// other, a negative number if it conveys less, and zero if they are the same.
+// The ordering is affirming > warning > contraindicated > none.  Note that the
+// numeric values of the tiers are in the opposite order, and must not be
+// compared directly.
+func (o TrustTier) Compare(other TrustTier) int {
+	return o.rank() - other.rank()
+}
+
+// IsAtLeast returns true if the receiver conveys at least as much trust as
+// min.  Any tier (including TrustTierNone) is at least TrustTierNone, whereas
+// TrustTierNone is not at least any other tier: a dimension that has not been
+// appraised does not meet a requirement.
+func (o TrustTier) IsAtLeast(min TrustTier) bool {
+	return o.Compare(min) >= 0
+}
+
+// WorstTier returns the tier conveying the least trust among the supplied
+// ones.  TrustTierNone entries are ignored, as they stand for claims that have
+// not been made, hence TrustTierNone is only returned if there is no other
+// tier.
+func WorstTier(tiers ...TrustTier) TrustTier {
+	worst := TrustTierNone
+
+	for _, t := range tiers {
+		if t == TrustTierNone {
+			continue
+		}
+
+		if worst == TrustTierNone || t.Compare(worst) < 0 {
+			worst = t
+		}
+	}
+
+	return worst
+}
+
+// BestTier returns the tier conveying the most trust among the supplied ones,
+// or TrustTierNone if no tier other than TrustTierNone is supplied.
+func BestTier(tiers ...TrustTier) TrustTier {
+	best := TrustTierNone
+
+	for _, t := range tiers {
+		if t.Compare(best) > 0 {
+			best = t
+		}
+	}
+
+	return best
+}
+
 func (o TrustTier) Format(color bool) string {
 	if color {
 		return o.ColorString()
@@ -173,3 +173,62 @@ func TestTrustTier_ToTrustTier(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, TrustTierAffirming, *tt)
 }
+
+func TestTrustTier_Compare(t *testing.T) {
+	tvs := []struct {
+		a, b     TrustTier
+		expected int
+	}{
+		{TrustTierAffirming, TrustTierWarning, 1},
+		{TrustTierWarning, TrustTierContraindicated, 1},
+		{TrustTierContraindicated, TrustTierNone, 1},
+		{TrustTierContraindicated, TrustTierAffirming, -1},
+		{TrustTierNone, TrustTierContraindicated, -1},
+		{TrustTierWarning, TrustTierWarning, 0},
+		{TrustTierNone, TrustTierNone, 0},
+	}
+
+	for i, tv := range tvs {
+		c := tv.a.Compare(tv.b)
+		switch {
+		case tv.expected > 0:
+			assert.Positive(t, c, "failed test vector at index %d", i)
+		case tv.expected < 0:
+			assert.Negative(t, c, "failed test vector at index %d", i)
+		default:
+			assert.Zero(t, c, "failed test vector at index %d", i)
+		}
+	}
+}
+
+func TestTrustTier_IsAtLeast(t *testing.T) {
+	assert.True(t, TrustTierAffirming.IsAtLeast(TrustTierWarning))
+	assert.True(t, TrustTierWarning.IsAtLeast(TrustTierWarning))
+	assert.False(t, TrustTierContraindicated.IsAtLeast(TrustTierWarning))
+	assert.True(t, TrustTierContraindicated.IsAtLeast(TrustTierNone))
+	assert.True(t, TrustTierNone.IsAtLeast(TrustTierNone))
+	assert.False(t, TrustTierNone.IsAtLeast(TrustTierContraindicated))
+}
+
+func TestTrustTier_WorstTier_BestTier(t *testing.T) {
+	tvs := []struct {
+		tiers []TrustTier
+		worst TrustTier
+		best  TrustTier
+	}{
+		{nil, TrustTierNone, TrustTierNone},
+		{[]TrustTier{TrustTierNone}, TrustTierNone, TrustTierNone},
+		{[]TrustTier{TrustTierAffirming, TrustTierNone}, TrustTierAffirming, TrustTierAffirming},
+		{[]TrustTier{TrustTierWarning, TrustTierAffirming}, TrustTierWarning, TrustTierAffirming},
+		{
+			[]TrustTier{TrustTierAffirming, TrustTierContraindicated, TrustTierNone, TrustTierWarning},
+			TrustTierContraindicated,
+			TrustTierAffirming,
+		},
+	}
+
+	for i, tv := range tvs {
+		assert.Equal(t, tv.worst, WorstTier(tv.tiers...), "failed test vector at index %d", i)
+		assert.Equal(t, tv.best, BestTier(tv.tiers...), "failed test vector at index %d", i)
+	}
+}
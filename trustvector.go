@@ -3,6 +3,11 @@
 
 package ear
 
+import (
+	"fmt"
+	"strings"
+)
+
 // TrustVector is an implementation of the Trustworthiness Vector (and Claims)
 // described in §2.3 of draft-ietf-rats-ar4si-03, using a JSON serialization.
 type TrustVector struct {
@@ -49,6 +54,46 @@ func ToTrustVector(v interface{}) (*TrustVector, error) {
 	return &tv, err
 }
 
+// claimPtr returns a pointer to the claim with the specified name (as used in
+// the JSON serialization), or nil if no such claim exists.
+func (o *TrustVector) claimPtr(name string) *TrustClaim {
+	switch name {
+	case "instance-identity":
+		return &o.InstanceIdentity
+	case "configuration":
+		return &o.Configuration
+	case "executables":
+		return &o.Executables
+	case "file-system":
+		return &o.FileSystem
+	case "hardware":
+		return &o.Hardware
+	case "runtime-opaque":
+		return &o.RuntimeOpaque
+	case "storage-opaque":
+		return &o.StorageOpaque
+	case "sourced-data":
+		return &o.SourcedData
+	default:
+		return nil
+	}
+}
+
+// Mask returns a copy of the TrustVector in which the named claims (e.g.,
+// "file-system") have been reset to NoClaim.  This is used to exclude advisory
+// claims from tier computations.  An unknown claim name is an error, since a
+// misspelt name would otherwise leave the claim it meant in place.
+func (o TrustVector) Mask(claims ...string) (TrustVector, error) {
+	for _, name := range claims {
+		c := o.claimPtr(name)
+		if c == nil {
+			return TrustVector{}, fmt.Errorf("unknown trust vector claim %q", name)
+		}
+		*c = NoClaim
+	}
+	return o, nil
+}
+
 // SetAll sets all vector elements to the specified claim. This is primarily
 // useful with globally-applicable claims such as -1 (verifier malfunction), 0
 // (no claim, in order to "reset" the vector), or 99 (cryptographic validation
@@ -64,57 +109,122 @@ func (o *TrustVector) SetAll(c TrustClaim) {
 	o.SourcedData = c
 }
 
+// Merge returns a copy of the TrustVector combined with other, e.g., to fold
+// the outcome of a runtime appraisal into that of a static appraisal of the
+// same submod.  Claims that are NoClaim in either vector are taken from the
+// other one.  Where both vectors set a claim, other's value supersedes the
+// receiver's, unless preferWorse is true, in which case the value in the
+// higher (worse) trust tier is kept; on a tier tie the receiver's value wins.
+func (o TrustVector) Merge(other TrustVector, preferWorse bool) TrustVector {
+	for name, theirs := range other.AsMap() {
+		ours := o.claimPtr(name)
+
+		switch {
+		case theirs == NoClaim:
+			continue
+		case *ours == NoClaim, !preferWorse:
+			*ours = theirs
+		case theirs.GetTier() > ours.GetTier():
+			*ours = theirs
+		}
+	}
+
+	return o
+}
+
+// ReportVerbosity controls the amount of detail in a TrustVector report
+type ReportVerbosity int
+
+const (
+	// ReportTiersOnly lists each claim with its trust tier only
+	ReportTiersOnly ReportVerbosity = iota
+	// ReportShort adds a short description of each claim
+	ReportShort
+	// ReportDetailed adds the full description of each claim
+	ReportDetailed
+)
+
+// ReportOptions configures ReportWithOptions
+type ReportOptions struct {
+	Verbosity ReportVerbosity
+	// Color enables ANSI colors when printing the trust tiers
+	Color bool
+	// Paragraph renders the report as a single line of text, rather than
+	// one line per claim
+	Paragraph bool
+	// OmitNoClaim skips the claims for which no assertion has been made
+	OmitNoClaim bool
+}
+
 // Report provides an annotated view of the TrustVector state.
 // short and color are used to control the level of details and the use of
 // colors when printing the trust tier, respectively
 func (o TrustVector) Report(short, color bool) string {
-	s := "Instance Identity " +
-		o.InstanceIdentity.trustTierTag(color) +
-		": " +
-		o.InstanceIdentity.asInstanceIdentityDetails(short, color) +
-		"\n"
-
-	s += "Configuration " +
-		o.Configuration.trustTierTag(color) +
-		": " +
-		o.Configuration.asConfigurationDetails(short, color) +
-		"\n"
-
-	s += "Executables " +
-		o.Executables.trustTierTag(color) +
-		": " +
-		o.Executables.asExecutablesDetails(short, color) +
-		"\n"
-
-	s += "File System " +
-		o.FileSystem.trustTierTag(color) +
-		": " +
-		o.FileSystem.asFileSystemDetails(short, color) +
-		"\n"
-
-	s += "Hardware " +
-		o.Hardware.trustTierTag(color) +
-		": " +
-		o.Hardware.asHardwareDetails(short, color) +
-		"\n"
-
-	s += "Runtime Opaque " +
-		o.RuntimeOpaque.trustTierTag(color) +
-		": " +
-		o.RuntimeOpaque.asRuntimeOpaqueDetails(short, color) +
-		"\n"
-
-	s += "Storage Opaque " +
-		o.StorageOpaque.trustTierTag(color) +
-		": " +
-		o.StorageOpaque.asStorageOpaqueDetails(short, color) +
-		"\n"
-
-	s += "Sourced Data " +
-		o.SourcedData.trustTierTag(color) +
-		": " +
-		o.SourcedData.asSourcedDataDetails(short, color) +
-		"\n"
+	verbosity := ReportDetailed
+	if short {
+		verbosity = ReportShort
+	}
+
+	return o.ReportWithOptions(ReportOptions{Verbosity: verbosity, Color: color})
+}
+
+// ReportWithOptions is like Report, but with a finer control over the layout
+// and level of detail, e.g., to embed a one-paragraph summary in a dashboard:
+//
+//	tv.ReportWithOptions(ReportOptions{
+//		Verbosity:   ReportShort,
+//		Paragraph:   true,
+//		OmitNoClaim: true,
+//	})
+func (o TrustVector) ReportWithOptions(opts ReportOptions) string {
+	claims := []struct {
+		name  string
+		claim TrustClaim
+		key   string
+	}{
+		{"Instance Identity", o.InstanceIdentity, "instance-identity"},
+		{"Configuration", o.Configuration, "configuration"},
+		{"Executables", o.Executables, "executables"},
+		{"File System", o.FileSystem, "file-system"},
+		{"Hardware", o.Hardware, "hardware"},
+		{"Runtime Opaque", o.RuntimeOpaque, "runtime-opaque"},
+		{"Storage Opaque", o.StorageOpaque, "storage-opaque"},
+		{"Sourced Data", o.SourcedData, "sourced-data"},
+	}
+
+	var entries []string
+
+	for _, c := range claims {
+		if opts.OmitNoClaim && c.claim == NoClaim {
+			continue
+		}
+
+		e := c.name + " " + c.claim.trustTierTag(opts.Color)
+
+		if opts.Verbosity != ReportTiersOnly {
+			short := opts.Verbosity == ReportShort
+			e += ": " + c.claim.detailsPrinter(c.key, short, opts.Color)
+		}
+
+		entries = append(entries, e)
+	}
+
+	if opts.Paragraph {
+		if len(entries) == 0 {
+			return ""
+		}
+
+		for i, e := range entries {
+			entries[i] = strings.TrimSuffix(e, ".")
+		}
+
+		return strings.Join(entries, "; ") + "."
+	}
+
+	var s string
+	for _, e := range entries {
+		s += e + "\n"
+	}
 
 	return s
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTrustVector_Report_bw_default(t *testing.T) {
@@ -135,3 +136,112 @@ func TestTrustVector_SetAll(t *testing.T) {
 	tv.SetAll(VerifierMalfunctionClaim)
 	assert.Equal(t, VerifierMalfunctionClaim, tv.Configuration)
 }
+
+func TestTrustVector_Mask(t *testing.T) {
+	tv := TrustVector{
+		InstanceIdentity: TrustworthyInstanceClaim,
+		FileSystem:       UnrecognizedFilesClaim,
+		SourcedData:      UntrustedSourcesClaim,
+	}
+
+	masked, err := tv.Mask("file-system", "sourced-data")
+	require.NoError(t, err)
+
+	assert.Equal(t, TrustVector{InstanceIdentity: TrustworthyInstanceClaim}, masked)
+	// the original is unaffected
+	assert.Equal(t, UnrecognizedFilesClaim, tv.FileSystem)
+
+	_, err = tv.Mask("file-system", "filesystem")
+	assert.EqualError(t, err, `unknown trust vector claim "filesystem"`)
+}
+
+func TestTrustVector_Merge(t *testing.T) {
+	static := TrustVector{
+		InstanceIdentity: TrustworthyInstanceClaim,
+		Configuration:    UnsafeConfigClaim,
+		Executables:      ApprovedBootClaim,
+		Hardware:         GenuineHardwareClaim,
+	}
+
+	runtime := TrustVector{
+		Configuration: ApprovedConfigClaim,
+		Executables:   ApprovedRuntimeClaim,
+		Hardware:      ContraindicatedHardwareClaim,
+		RuntimeOpaque: EncryptedMemoryRuntimeClaim,
+	}
+
+	tvs := []struct {
+		preferWorse bool
+		expected    TrustVector
+	}{
+		{
+			preferWorse: false,
+			expected: TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+				Configuration:    ApprovedConfigClaim,
+				Executables:      ApprovedRuntimeClaim,
+				Hardware:         ContraindicatedHardwareClaim,
+				RuntimeOpaque:    EncryptedMemoryRuntimeClaim,
+			},
+		},
+		{
+			preferWorse: true,
+			expected: TrustVector{
+				InstanceIdentity: TrustworthyInstanceClaim,
+				Configuration:    UnsafeConfigClaim,
+				// same tier, the receiver's value is kept
+				Executables:   ApprovedBootClaim,
+				Hardware:      ContraindicatedHardwareClaim,
+				RuntimeOpaque: EncryptedMemoryRuntimeClaim,
+			},
+		},
+	}
+
+	for i, tv := range tvs {
+		actual := static.Merge(runtime, tv.preferWorse)
+		assert.Equal(t, tv.expected, actual, "failed test vector at index %d", i)
+	}
+
+	// the original is unaffected
+	assert.Equal(t, UnsafeConfigClaim, static.Configuration)
+}
+
+func TestTrustVector_ReportWithOptions(t *testing.T) {
+	tv := TrustVector{
+		InstanceIdentity: TrustworthyInstanceClaim,
+		Executables:      UnsafeRuntimeClaim,
+	}
+
+	tvs := []struct {
+		opts     ReportOptions
+		expected string
+	}{
+		{
+			opts: ReportOptions{Verbosity: ReportTiersOnly, OmitNoClaim: true},
+			expected: `Instance Identity [affirming]
+Executables [warning]
+`,
+		},
+		{
+			opts: ReportOptions{Verbosity: ReportShort, Paragraph: true, OmitNoClaim: true},
+			expected: "Instance Identity [affirming]: recognized and not compromised; " +
+				"Executables [warning]: recognized but known bugs or vulnerabilities.",
+		},
+		{
+			opts: ReportOptions{Verbosity: ReportTiersOnly, Paragraph: true},
+			expected: "Instance Identity [affirming]; Configuration [none]; " +
+				"Executables [warning]; File System [none]; Hardware [none]; " +
+				"Runtime Opaque [none]; Storage Opaque [none]; Sourced Data [none].",
+		},
+	}
+
+	for i, v := range tvs {
+		assert.Equal(t, v.expected, tv.ReportWithOptions(v.opts), "failed test vector at index %d", i)
+	}
+
+	detailed := tv.ReportWithOptions(ReportOptions{Verbosity: ReportDetailed, Paragraph: true})
+	assert.NotContains(t, detailed, "\n")
+	assert.NotContains(t, detailed, ".;")
+
+	assert.Equal(t, "", TrustVector{}.ReportWithOptions(ReportOptions{Paragraph: true, OmitNoClaim: true}))
+}
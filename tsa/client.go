@@ -0,0 +1,85 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package tsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+)
+
+// maxResponseSize bounds the size of the time-stamp responses read by
+// HTTPAuthority
+const maxResponseSize = 1 << 20
+
+// HTTPAuthority is an ear.TimestampAuthority that talks to an RFC3161
+// time-stamping service using the HTTP transport of RFC3161 §3.4
+type HTTPAuthority struct {
+	// URL is the endpoint of the time-stamping service
+	URL string
+	// Client is used to send requests (http.DefaultClient if nil)
+	Client *http.Client
+}
+
+func (o HTTPAuthority) Timestamp(digest []byte) ([]byte, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := asn1.Marshal(request{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			HashedMessage: digest,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding time-stamp request: %w", err)
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Post(o.URL, "application/timestamp-query", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA responded with %s", res.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading time-stamp response: %w", err)
+	}
+
+	token, err := parseResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	info, _, _, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("time-stamp nonce mismatch")
+	}
+
+	return token, nil
+}
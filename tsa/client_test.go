@@ -0,0 +1,259 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package tsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/ear"
+)
+
+// testTSA issues RFC3161 time-stamp tokens signed with a self-signed
+// certificate
+type testTSA struct {
+	key   *ecdsa.PrivateKey
+	cert  *x509.Certificate
+	now   time.Time
+	roots *x509.CertPool
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(42),
+		Subject:               pkix.Name{CommonName: "Test TSA"},
+		NotBefore:             time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	return &testTSA{
+		key:   key,
+		cert:  cert,
+		now:   time.Now().Add(time.Minute).UTC().Truncate(time.Second),
+		roots: roots,
+	}
+}
+
+func (o testTSA) token(digest []byte, nonce *big.Int) ([]byte, error) {
+	info, err := asn1.Marshal(tstInfo{
+		Version: 1,
+		Policy:  asn1.ObjectIdentifier{1, 2, 3, 4},
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			HashedMessage: digest,
+		},
+		SerialNumber: big.NewInt(1),
+		GenTime:      o.now,
+		Nonce:        nonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	eContent, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	infoDigest := sha256.Sum256(info)
+
+	attrs, err := asn1.MarshalWithParams([]attribute{
+		{Type: oidAttrContentType, Values: testASN1Set(t2b(asn1.Marshal(oidTSTInfo)))},
+		{Type: oidAttrMessageDigest, Values: testASN1Set(t2b(asn1.Marshal(infoDigest[:])))},
+	}, "set")
+	if err != nil {
+		return nil, err
+	}
+
+	attrsDigest := sha256.Sum256(attrs)
+
+	sig, err := ecdsa.SignASN1(rand.Reader, o.key, attrsDigest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sid, err := asn1.Marshal(issuerAndSerial{
+		Issuer: asn1.RawValue{FullBytes: o.cert.RawIssuer},
+		Serial: o.cert.SerialNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sd, err := asn1.Marshal(signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidSHA256}},
+		EncapContentInfo: encapContentInfo{
+			EContentType: oidTSTInfo,
+			EContent:     testASN1Tagged(0, eContent),
+		},
+		Certificates: testASN1Tagged(0, o.cert.Raw),
+		SignerInfos: []signerInfo{
+			{
+				Version:         1,
+				SID:             asn1.RawValue{FullBytes: sid},
+				DigestAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+				// [0] IMPLICIT in place of the SET OF tag
+				SignedAttrs: asn1.RawValue{FullBytes: append([]byte{0xa0}, attrs[1:]...)},
+				SignatureAlgorithm: pkix.AlgorithmIdentifier{
+					Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2},
+				},
+				Signature: sig,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     testASN1Tagged(0, sd),
+	})
+}
+
+func t2b(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func testASN1Set(content []byte) asn1.RawValue {
+	return asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: content}
+}
+
+func testASN1Tagged(tag int, content []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: content}
+}
+
+func testTSAServer(t *testing.T, tsa *testTSA, status int, nonceDelta int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/timestamp-query", r.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var req request
+		_, err = asn1.Unmarshal(body, &req)
+		require.NoError(t, err)
+		assert.True(t, req.CertReq)
+
+		resp := response{Status: statusInfo{Status: status}}
+
+		if status <= 1 {
+			nonce := new(big.Int).Add(req.Nonce, big.NewInt(nonceDelta))
+
+			token, err := tsa.token(req.MessageImprint.HashedMessage, nonce)
+			require.NoError(t, err)
+
+			resp.Token = asn1.RawValue{FullBytes: token}
+		} else {
+			resp.Status.StatusString = []asn1.RawValue{
+				{Tag: asn1.TagUTF8String, Bytes: []byte("bad request")},
+			}
+		}
+
+		data, err := asn1.Marshal(resp)
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		_, _ = w.Write(data)
+	}))
+}
+
+func testSigningKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func TestHTTPAuthority_round_trip(t *testing.T) {
+	tsa := newTestTSA(t)
+
+	srv := testTSAServer(t, tsa, 0, 0)
+	defer srv.Close()
+
+	key := testSigningKey(t)
+	ar := ear.NewAttestationResult("test", "rrtrap-v1.0.0", "Acme Inc.")
+
+	token, err := ar.Sign(jwa.ES256, key, ear.WithTimestampAuthority(HTTPAuthority{URL: srv.URL}))
+	require.NoError(t, err)
+
+	var report ear.VerificationReport
+
+	var actual ear.AttestationResult
+	err = actual.Verify(token, jwa.ES256, &key.PublicKey,
+		ear.WithTimestampVerifier(Verifier{Roots: tsa.roots}),
+		ear.WithVerificationReport(&report))
+	require.NoError(t, err)
+
+	require.NotNil(t, report.TimestampedAt)
+	assert.True(t, tsa.now.Equal(*report.TimestampedAt))
+
+	// a TSA that is not trusted
+	err = actual.Verify(token, jwa.ES256, &key.PublicKey,
+		ear.WithTimestampVerifier(Verifier{Roots: newTestTSA(t).roots}))
+	assert.ErrorContains(t, err,
+		"verifying time-stamp: verifying TSA certificate: x509: certificate signed by unknown authority")
+}
+
+func TestHTTPAuthority_ko(t *testing.T) {
+	tsa := newTestTSA(t)
+
+	rejecting := testTSAServer(t, tsa, 2, 0)
+	defer rejecting.Close()
+
+	replaying := testTSAServer(t, tsa, 0, 1)
+	defer replaying.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	tvs := []struct {
+		url      string
+		expected string
+	}{
+		{rejecting.URL, "time-stamp request rejected (status 2: bad request)"},
+		{replaying.URL, "time-stamp nonce mismatch"},
+		{failing.URL, "TSA responded with 503 Service Unavailable"},
+	}
+
+	for i, tv := range tvs {
+		_, err := HTTPAuthority{URL: tv.url}.Timestamp(make([]byte, sha256.Size))
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package tsa provides an ear.TimestampAuthority for RFC3161 time-stamping
+services reached over HTTP, and an ear.TimestampVerifier for the tokens they
+issue, so that EARs can carry independent evidence of when they have been
+issued:
+
+	token, err := ar.Sign(alg, key,
+		ear.WithTimestampAuthority(tsa.HTTPAuthority{URL: tsaURL}))
+	...
+	err = ar.Verify(token, alg, key,
+		ear.WithTimestampVerifier(tsa.Verifier{Roots: tsaRoots}))
+
+Only the subset of CMS (RFC5652) used by time-stamp tokens is supported: a
+single signer, with signed attributes, using RSA (PKCS #1 v1.5) or ECDSA.
+Applications that need more can implement ear.TimestampAuthority and
+ear.TimestampVerifier on top of a full CMS library instead.
+*/
+package tsa
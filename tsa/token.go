@@ -0,0 +1,286 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package tsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	oidSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidAttrContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type request struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional"`
+}
+
+type statusInfo struct {
+	Status       int
+	StatusString []asn1.RawValue `asn1:"optional"`
+	FailInfo     asn1.BitString  `asn1:"optional"`
+}
+
+type response struct {
+	Status statusInfo
+	Token  asn1.RawValue `asn1:"optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// parseResponse decodes a TimeStampResp, returning the TimeStampToken it
+// carries if the request has been granted
+func parseResponse(data []byte) ([]byte, error) {
+	var resp response
+	if rest, err := asn1.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decoding time-stamp response: %w", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("decoding time-stamp response: trailing data")
+	}
+
+	// 0 is granted, 1 is granted with modifications
+	if resp.Status.Status > 1 {
+		var reasons []string
+		for _, s := range resp.Status.StatusString {
+			reasons = append(reasons, string(s.Bytes))
+		}
+
+		return nil, fmt.Errorf("time-stamp request rejected (status %d: %s)",
+			resp.Status.Status, strings.Join(reasons, "; "))
+	}
+
+	if len(resp.Token.FullBytes) == 0 {
+		return nil, errors.New("no time-stamp token in response")
+	}
+
+	return resp.Token.FullBytes, nil
+}
+
+// parseToken decodes the TimeStampToken, returning the TSTInfo, the enclosing
+// SignedData, and the DER encoding of the TSTInfo (i.e., the signed content)
+func parseToken(token []byte) (*tstInfo, *signedData, []byte, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(token, &ci); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding time-stamp token: %w", err)
+	} else if len(rest) != 0 {
+		return nil, nil, nil, errors.New("decoding time-stamp token: trailing data")
+	}
+
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, nil, nil, fmt.Errorf("time-stamp token is not signed data (%s)", ci.ContentType)
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding time-stamp signed data: %w", err)
+	}
+
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, nil, nil, fmt.Errorf("unexpected time-stamp content type %s", sd.EncapContentInfo.EContentType)
+	}
+
+	// eContent is an OCTET STRING wrapping the DER-encoded TSTInfo
+	var content []byte
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &content); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding time-stamp content: %w", err)
+	}
+
+	var info tstInfo
+	if _, err := asn1.Unmarshal(content, &info); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding time-stamp info: %w", err)
+	}
+
+	return &info, &sd, content, nil
+}
+
+func findSigner(si signerInfo, certs []*x509.Certificate) (*x509.Certificate, error) {
+	var ias issuerAndSerial
+
+	isSKI := si.SID.Class == asn1.ClassContextSpecific && si.SID.Tag == 0
+	if !isSKI {
+		if _, err := asn1.Unmarshal(si.SID.FullBytes, &ias); err != nil {
+			return nil, fmt.Errorf("decoding signer identifier: %w", err)
+		}
+
+		if ias.Serial == nil {
+			return nil, errors.New("decoding signer identifier: missing serial number")
+		}
+	}
+
+	for _, c := range certs {
+		if isSKI && bytes.Equal(c.SubjectKeyId, si.SID.Bytes) {
+			return c, nil
+		}
+
+		if !isSKI && bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.Serial) == 0 {
+			return c, nil
+		}
+	}
+
+	return nil, errors.New("TSA certificate not found in time-stamp token")
+}
+
+func hashFor(alg pkix.AlgorithmIdentifier) (crypto.Hash, error) {
+	switch {
+	case alg.Algorithm.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case alg.Algorithm.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case alg.Algorithm.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported digest algorithm %s", alg.Algorithm)
+}
+
+// verify checks the signed attributes against the content, and the signature
+// over the signed attributes
+func (o signerInfo) verify(signer *x509.Certificate, content []byte) error {
+	if len(o.SignedAttrs.FullBytes) == 0 {
+		return errors.New("no signed attributes in time-stamp token")
+	}
+
+	h, err := hashFor(o.DigestAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	// the signature covers the DER encoding of the attributes as a SET OF,
+	// rather than with the implicit [0] tag
+	signed := append([]byte{0x31}, o.SignedAttrs.FullBytes[1:]...)
+
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return fmt.Errorf("decoding signed attributes: %w", err)
+	}
+
+	var contentType, messageDigest bool
+
+	for _, a := range attrs {
+		switch {
+		case a.Type.Equal(oidAttrContentType):
+			var ct asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &ct); err != nil || !ct.Equal(oidTSTInfo) {
+				return errors.New("content type attribute mismatch")
+			}
+			contentType = true
+		case a.Type.Equal(oidAttrMessageDigest):
+			var md []byte
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &md); err != nil {
+				return fmt.Errorf("decoding message digest attribute: %w", err)
+			}
+
+			d := h.New()
+			d.Write(content)
+			if !bytes.Equal(md, d.Sum(nil)) {
+				return errors.New("message digest attribute mismatch")
+			}
+			messageDigest = true
+		}
+	}
+
+	if !contentType || !messageDigest {
+		return errors.New("missing content type or message digest attribute")
+	}
+
+	d := h.New()
+	d.Write(signed)
+	sum := d.Sum(nil)
+
+	switch pub := signer.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(pub, h, sum, o.Signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, sum, o.Signature) {
+			err = errors.New("ECDSA verification failure")
+		}
+	default:
+		err = fmt.Errorf("unsupported TSA key type %T", pub)
+	}
+
+	if err != nil {
+		return fmt.Errorf("verifying time-stamp signature: %w", err)
+	}
+
+	return nil
+}
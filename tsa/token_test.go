@@ -0,0 +1,148 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package tsa
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The responses in testdata have been issued by the OpenSSL TSA (openssl ts
+// -reply), using an RSA and an ECDSA TSA certificate issued by testdata/ca.pem,
+// for a query on the SHA-256 digest of testVectorMessage.  They include the
+// TSA certificate, a nonce, the accuracy, ordering and TSA name fields, and
+// an ESSCertIDv2 signed attribute.
+const testVectorMessage = "veraison"
+
+var testVectors = []struct {
+	file    string
+	genTime time.Time
+}{
+	{"rsa.tsr", time.Date(2026, 10, 15, 16, 27, 32, 0, time.UTC)},
+	{"ec.tsr", time.Date(2026, 10, 15, 16, 27, 33, 0, time.UTC)},
+}
+
+func testReadFile(t testing.TB, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func testVectorRoots(t testing.TB) *x509.CertPool {
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(testReadFile(t, "ca.pem")))
+	return roots
+}
+
+func testVectorToken(t testing.TB, name string) []byte {
+	token, err := parseResponse(testReadFile(t, name))
+	require.NoError(t, err)
+	return token
+}
+
+func TestVerifier_openssl_vectors(t *testing.T) {
+	roots := testVectorRoots(t)
+	digest := sha256.Sum256([]byte(testVectorMessage))
+
+	for i, tv := range testVectors {
+		genTime, err := Verifier{Roots: roots}.VerifyTimestamp(testVectorToken(t, tv.file), digest[:])
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.True(t, tv.genTime.Equal(genTime), "failed test vector at index %d", i)
+	}
+}
+
+func TestVerifier_openssl_vectors_ko(t *testing.T) {
+	roots := testVectorRoots(t)
+	digest := sha256.Sum256([]byte(testVectorMessage))
+	other := sha256.Sum256([]byte("other"))
+
+	for i, tv := range testVectors {
+		token := testVectorToken(t, tv.file)
+
+		_, err := Verifier{Roots: roots}.VerifyTimestamp(token, other[:])
+		assert.EqualError(t, err, "time-stamp does not cover the claims-set", "failed test vector at index %d", i)
+
+		_, err = Verifier{Roots: x509.NewCertPool()}.VerifyTimestamp(token, digest[:])
+		assert.ErrorContains(t, err,
+			"verifying TSA certificate: x509: certificate signed by unknown authority",
+			"failed test vector at index %d", i)
+
+		// the signature is at the very end of the token
+		tampered := append([]byte{}, token...)
+		tampered[len(tampered)-1] ^= 0xff
+
+		_, err = Verifier{Roots: roots}.VerifyTimestamp(tampered, digest[:])
+		assert.ErrorContains(t, err, "verifying time-stamp signature: ", "failed test vector at index %d", i)
+
+		_, err = Verifier{Roots: roots}.VerifyTimestamp(append(token, 0), digest[:])
+		assert.EqualError(t, err, "decoding time-stamp token: trailing data", "failed test vector at index %d", i)
+	}
+}
+
+func TestParseResponse_ko(t *testing.T) {
+	tvs := []struct {
+		data     []byte
+		expected string
+	}{
+		{
+			data:     []byte{0x30, 0x03, 0x02, 0x01},
+			expected: "decoding time-stamp response: asn1: syntax error: data truncated",
+		},
+		{
+			// status 0, no token
+			data:     []byte{0x30, 0x05, 0x30, 0x03, 0x02, 0x01, 0x00},
+			expected: "no time-stamp token in response",
+		},
+		{
+			// status 2, no reason
+			data:     []byte{0x30, 0x05, 0x30, 0x03, 0x02, 0x01, 0x02},
+			expected: "time-stamp request rejected (status 2: )",
+		},
+		{
+			data:     append(testReadFile(t, "ec.tsr"), 0),
+			expected: "decoding time-stamp response: trailing data",
+		},
+	}
+
+	for i, tv := range tvs {
+		_, err := parseResponse(tv.data)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+// FuzzParseResponse feeds arbitrary time-stamp responses through the parsers,
+// which must fail cleanly rather than panic.  Signatures are left to the
+// verifier tests, as they exercise the standard library rather than the
+// decoding done in this package.
+func FuzzParseResponse(f *testing.F) {
+	for _, tv := range testVectors {
+		f.Add(testReadFile(f, tv.file))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		token, err := parseResponse(data)
+		if err != nil {
+			return
+		}
+
+		_, sd, _, err := parseToken(token)
+		if err != nil || len(sd.SignerInfos) == 0 {
+			return
+		}
+
+		certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return
+		}
+
+		_, _ = findSigner(sd.SignerInfos[0], certs)
+	})
+}
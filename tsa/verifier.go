@@ -0,0 +1,69 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package tsa
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Verifier is an ear.TimestampVerifier for RFC3161 time-stamp tokens signed
+// using RSA (PKCS #1 v1.5) or ECDSA
+type Verifier struct {
+	// Roots are the trust anchors for the TSA certificates.  The TSA
+	// certificate, and any intermediate, must be included in the token.
+	Roots *x509.CertPool
+}
+
+func (o Verifier) VerifyTimestamp(token, digest []byte) (time.Time, error) {
+	info, sd, content, err := parseToken(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) ||
+		!bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return time.Time{}, errors.New("time-stamp does not cover the claims-set")
+	}
+
+	if len(sd.SignerInfos) != 1 {
+		return time.Time{}, fmt.Errorf("expecting one signer, found %d", len(sd.SignerInfos))
+	}
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing TSA certificates: %w", err)
+	}
+
+	si := sd.SignerInfos[0]
+
+	signer, err := findSigner(si, certs)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := si.verify(signer, content); err != nil {
+		return time.Time{}, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		intermediates.AddCert(c)
+	}
+
+	_, err = signer.Verify(x509.VerifyOptions{
+		Roots:         o.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("verifying TSA certificate: %w", err)
+	}
+
+	return info.GenTime, nil
+}
@@ -449,6 +449,28 @@ func parseTag(t reflect.StructTag, key string) (fieldSpec, bool) {
 	return ret, true
 }
 
+// structTagNames returns the names, as found in the tagKey tag, of the fields
+// of the supplied struct type, including those of embedded structs
+func structTagNames(t reflect.Type, tagKey string) []string {
+	var names []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tagSpec, ok := parseTag(f.Tag, tagKey)
+		if !ok {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				names = append(names, structTagNames(f.Type, tagKey)...)
+			}
+			continue
+		}
+
+		names = append(names, tagSpec.Name)
+	}
+
+	return names
+}
+
 func getExtraKeys(m map[string]interface{}, expected []string) []string {
 	expectedMap := make(map[string]bool, len(expected))
 	for _, e := range expected {
@@ -0,0 +1,143 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidateCandidate runs the whole of the validation that Sign and SignCWT
+// perform before signing, without signing anything, so that verifier plugins
+// can check a result as they build it and report precise errors to their own
+// callers.  Unlike Validate, which only checks the claims-set, this also
+// honours the SignOptions that affect what gets signed (e.g., the annotated
+// evidence digests added by WithAnnotatedEvidenceDigest) and runs the WithBeforeSign
+// hooks.  The supplied options should therefore match the ones that will be
+// passed to Sign.  The checks, including the hooks, run on a deep copy of the
+// AttestationResult, which is therefore not modified.
+//
+// Claim problems are reported in a *ValidationError, as by Validate.
+func ValidateCandidate(ar *AttestationResult, opts ...SignOption) error {
+	if ar == nil {
+		return errors.New("nil AttestationResult")
+	}
+
+	c := deepCopy(*ar)
+
+	return c.prepareForSigning(newSignOptions(opts))
+}
+
+// ValidateStrict is like Validate, but also cross-checks claims that are
+// acceptable on their own but inconsistent with each other, which is what a
+// relying party wants before acting on a result it did not produce:
+//
+//   - the ear.status of each appraisal must be one of the AR4SI tiers;
+//   - the ear.status must not convey more trust than the trustworthiness
+//     vector warrants, as derived by the StatusPolicy selected by opts
+//     (WorstClaimPolicy by default, as in UpdateStatusFromTrustVector);
+//   - each trustworthiness claim value must have a defined meaning for that
+//     claim, either in AR4SI or through RegisterTrustClaimValue;
+//   - exp, if present, must not be before iat.
+//
+// A status that is worse than the trust vector warrants is accepted, since a
+// verifier may have lowered it for reasons that the vector does not capture.
+// The eat_profile checks, including the ProfileValidators registered for the
+// profile, are those of Validate.  All problems are reported together in a
+// *ValidationError.  Unknown claim names passed to WithAdvisoryClaims are a
+// mistake of the caller rather than of the result, and are reported on their
+// own.
+func (o AttestationResult) ValidateStrict(opts ...StatusOption) error {
+	var ve ValidationError
+
+	if err := o.validate(); err != nil {
+		var vErr *ValidationError
+		if !errors.As(err, &vErr) {
+			return err
+		}
+		ve = *vErr
+	}
+
+	if o.Expiry != nil && o.IssuedAt != nil && *o.Expiry < *o.IssuedAt {
+		ve.addInvalid("exp", fmt.Sprintf("exp (%d is before iat)", *o.Expiry), nil)
+	}
+
+	so := newStatusOptions(opts)
+
+	if err := (AdvisoryPolicy{Advisory: so.advisory}).Validate(); err != nil {
+		return err
+	}
+
+	submodNames := make([]string, 0, len(o.Submods))
+	for submodName := range o.Submods {
+		submodNames = append(submodNames, submodName)
+	}
+	sort.Strings(submodNames)
+
+	for _, submodName := range submodNames {
+		o.Submods[submodName].crossCheck(submodName, so.policyFor(submodName), &ve)
+	}
+
+	return ve.orNil()
+}
+
+// crossCheck records, under the named submod, the problems found by
+// ValidateStrict in the appraisal
+func (o Appraisal) crossCheck(submod string, policy StatusPolicy, ve *ValidationError) {
+	add := func(claim, desc string) {
+		ve.Errors = append(ve.Errors, &ClaimError{
+			Claim:  fmt.Sprintf("submods[%s].%s", submod, claim),
+			Submod: submod,
+			Kind:   ErrInvalidClaim,
+		})
+		ve.invalid = append(ve.invalid, fmt.Sprintf("submods[%s]: %s", submod, desc))
+	}
+
+	if o.Status != nil {
+		if _, ok := TrustTierToString[*o.Status]; !ok {
+			add("ear.status", fmt.Sprintf("'ear.status' (undefined trust tier %d)", *o.Status))
+		}
+	}
+
+	if o.TrustVector == nil {
+		return
+	}
+
+	claims := o.TrustVector.AsMap()
+
+	names := make([]string, 0, len(claims))
+	for name := range claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c := claims[name]
+		if c == NoClaim {
+			continue
+		}
+		if _, ok := DescribeClaim(c).Describe(name); !ok {
+			add("ear.trustworthiness-vector",
+				fmt.Sprintf("'ear.trustworthiness-vector' (%d is not a defined %s value)", c, name))
+		}
+	}
+
+	if o.Status == nil {
+		return
+	}
+
+	warranted, drivers := explainStatus(policy, TrustTierNone, *o.TrustVector)
+	if warranted == TrustTierNone || o.Status.Compare(warranted) <= 0 {
+		return
+	}
+
+	desc := fmt.Sprintf("%s, but the trust vector warrants at most %s", *o.Status, warranted)
+	if len(drivers) != 0 {
+		desc += " because of " + strings.Join(drivers, ", ")
+	}
+
+	add("ear.status", fmt.Sprintf("'ear.status' (%s)", desc))
+}
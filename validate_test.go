@@ -0,0 +1,166 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCandidate_ok(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+
+	assert.NoError(t, ValidateCandidate(&ar, WithAnnotatedEvidenceDigest()))
+
+	// the candidate is left untouched
+	assert.Nil(t, ar.Submods["test"].VeraisonAnnotatedEvidenceDigest)
+}
+
+func TestValidateCandidate_invalid(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	ar.IssuedAt = nil
+
+	err := ValidateCandidate(&ar)
+	assert.EqualError(t, err, "missing mandatory 'iat'")
+
+	var ve *ValidationError
+	require.True(t, errors.As(err, &ve))
+	assert.Equal(t, []string{"iat"}, ve.Missing())
+}
+
+func TestValidateCandidate_before_sign_hook(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+
+	reject := func(*AttestationResult) error { return errors.New("no thanks") }
+
+	assert.NoError(t, ar.Validate())
+	assert.EqualError(t, ValidateCandidate(&ar, WithBeforeSign(reject)), "before-sign hook: no thanks")
+}
+
+func TestValidateCandidate_hook_mutations_not_visible(t *testing.T) {
+	ar := testAttestationResultsWithVeraisonExtns
+	status := *ar.Submods["test"].Status
+
+	tamper := func(c *AttestationResult) error {
+		*c.Submods["test"].Status = TrustTierContraindicated
+		c.Submods["test"].AppraisalPolicyID = nil
+		c.Submods["extra"] = NewAppraisal(TrustTierWarning)
+		return nil
+	}
+
+	require.NoError(t, ValidateCandidate(&ar, WithBeforeSign(tamper)))
+
+	require.NotContains(t, ar.Submods, "extra")
+	assert.Equal(t, status, *ar.Submods["test"].Status)
+	assert.Equal(t, &testPolicyID, ar.Submods["test"].AppraisalPolicyID)
+}
+
+func TestValidateCandidate_nil(t *testing.T) {
+	assert.EqualError(t, ValidateCandidate(nil), "nil AttestationResult")
+}
+
+func TestAttestationResult_ValidateStrict(t *testing.T) {
+	affirming := TrustTierAffirming
+	warning := TrustTierWarning
+	undefined := TrustTier(5)
+	exp := testIAT - 1
+
+	tvs := []struct {
+		setup    func(*AttestationResult, *Appraisal)
+		opts     []StatusOption
+		expected string
+		claims   []string
+	}{
+		{
+			setup: func(_ *AttestationResult, a *Appraisal) {
+				a.TrustVector = &TrustVector{Executables: ApprovedRuntimeClaim}
+			},
+		},
+		{
+			// a status worse than the trust vector is fine
+			setup: func(_ *AttestationResult, a *Appraisal) {
+				a.Status = &warning
+				a.TrustVector = &TrustVector{Executables: ApprovedRuntimeClaim}
+			},
+		},
+		{
+			setup: func(_ *AttestationResult, a *Appraisal) {
+				a.Status = &affirming
+				a.TrustVector = &TrustVector{
+					Executables: UnsafeRuntimeClaim,
+					Hardware:    UnsafeHardwareClaim,
+				}
+			},
+			expected: "invalid value(s) for submods[test]: 'ear.status' (affirming, but the trust vector warrants at most warning because of executables, hardware)",
+			claims:   []string{"submods[test].ear.status"},
+		},
+		{
+			// the status is consistent once the hardware claim is advisory
+			setup: func(_ *AttestationResult, a *Appraisal) {
+				a.Status = &affirming
+				a.TrustVector = &TrustVector{Hardware: UnsafeHardwareClaim}
+			},
+			opts: []StatusOption{WithAdvisoryClaims("hardware")},
+		},
+		{
+			setup: func(_ *AttestationResult, a *Appraisal) {
+				a.Status = &undefined
+			},
+			expected: "invalid value(s) for submods[test]: 'ear.status' (undefined trust tier 5)",
+			claims:   []string{"submods[test].ear.status"},
+		},
+		{
+			// 33 is only defined for executables
+			setup: func(_ *AttestationResult, a *Appraisal) {
+				a.Status = &warning
+				a.TrustVector = &TrustVector{
+					Executables: UnrecognizedRuntimeClaim,
+					FileSystem:  TrustClaim(33),
+				}
+			},
+			expected: "invalid value(s) for submods[test]: 'ear.trustworthiness-vector' (33 is not a defined file-system value)",
+			claims:   []string{"submods[test].ear.trustworthiness-vector"},
+		},
+		{
+			setup: func(ar *AttestationResult, a *Appraisal) {
+				ar.Expiry = &exp
+				ar.VerifierID = nil
+			},
+			expected: "missing mandatory 'verifier-id'; invalid value(s) for exp (1666091372 is before iat)",
+			claims:   []string{"ear.verifier-id", "exp"},
+		},
+	}
+
+	for i, tv := range tvs {
+		a := *testAttestationResultsWithVeraisonExtns.Submods["test"]
+		ar := testAttestationResultsWithVeraisonExtns
+		ar.Submods = map[string]*Appraisal{"test": &a}
+		tv.setup(&ar, &a)
+
+		err := ar.ValidateStrict(tv.opts...)
+		if tv.expected == "" {
+			assert.NoError(t, err, "failed test vector at index %d", i)
+			continue
+		}
+
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+
+		var ve *ValidationError
+		require.True(t, errors.As(err, &ve), "failed test vector at index %d", i)
+
+		var claims []string
+		for _, ce := range ve.Errors {
+			claims = append(claims, ce.Claim)
+		}
+		assert.Equal(t, tv.claims, claims, "failed test vector at index %d", i)
+
+		// none of these are caught by Validate, except missing claims
+		if len(ve.Missing()) == 0 {
+			assert.NoError(t, ar.Validate(), "failed test vector at index %d", i)
+		}
+	}
+}
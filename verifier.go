@@ -5,11 +5,16 @@ package ear
 
 import (
 	"errors"
+	"fmt"
 )
 
 // VerifierIdentity is the verifier software identification as defined by AR4SI:
 //
 //	https://datatracker.ietf.org/doc/html/draft-ietf-rats-ar4si-03#section-2.2.2
+//
+// Both claims are free text.  When the build carries a semantic version and
+// the developer is a URI or an FQDN, relying parties can pin the verifiers
+// they trust using Matches.
 type VerifierIdentity struct {
 	// Build uniquely identifies the software build running the verifier.
 	Build *string `json:"build"`
@@ -31,3 +36,23 @@ func ToVerifierIdentity(v interface{}) (*VerifierIdentity, error) {
 
 	return &verifierID, err
 }
+
+func (o VerifierIdentity) validateFreeText(ve *ValidationError) {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"ear.verifier-id.build", o.Build},
+		{"ear.verifier-id.developer", o.Developer},
+	}
+
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+
+		if err := checkFreeText(*f.value); err != nil {
+			ve.addInvalid(f.name, fmt.Sprintf("%s (%s)", f.name, err), err)
+		}
+	}
+}
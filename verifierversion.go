@@ -0,0 +1,343 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version (https://semver.org/spec/v2.0.0.html)
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Metadata   string
+}
+
+// ParseVersion parses a semantic version, optionally prefixed by "v" (e.g.,
+// "v1.2.3-rc.1+build.5")
+func ParseVersion(s string) (*Version, error) {
+	var v Version
+
+	rest := strings.TrimPrefix(s, "v")
+
+	if i := strings.IndexByte(rest, '+'); i != -1 {
+		v.Metadata = rest[i+1:]
+		rest = rest[:i]
+
+		if err := checkVersionIdentifiers(v.Metadata, false); err != nil {
+			return nil, fmt.Errorf("build metadata: %w", err)
+		}
+	}
+
+	if i := strings.IndexByte(rest, '-'); i != -1 {
+		pre := rest[i+1:]
+		rest = rest[:i]
+
+		if err := checkVersionIdentifiers(pre, true); err != nil {
+			return nil, fmt.Errorf("pre-release: %w", err)
+		}
+
+		v.Prerelease = strings.Split(pre, ".")
+	}
+
+	core := strings.Split(rest, ".")
+	if len(core) != 3 {
+		return nil, fmt.Errorf("%q is not in MAJOR.MINOR.PATCH format", rest)
+	}
+
+	for i, dst := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		n, err := parseVersionNumber(core[i])
+		if err != nil {
+			return nil, err
+		}
+		*dst = n
+	}
+
+	return &v, nil
+}
+
+func parseVersionNumber(s string) (uint64, error) {
+	if s == "" || !isDigits(s) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("%q has leading zeros", s)
+	}
+
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func checkVersionIdentifiers(s string, numericNoLeadingZeros bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return errors.New("empty identifier")
+		}
+
+		for _, c := range id {
+			if !(c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+				return fmt.Errorf("invalid character %q in %q", c, id)
+			}
+		}
+
+		if numericNoLeadingZeros && isDigits(id) && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("%q has leading zeros", id)
+		}
+	}
+
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the canonical form of the version, without the "v" prefix
+func (o Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", o.Major, o.Minor, o.Patch)
+
+	if len(o.Prerelease) > 0 {
+		s += "-" + strings.Join(o.Prerelease, ".")
+	}
+
+	if o.Metadata != "" {
+		s += "+" + o.Metadata
+	}
+
+	return s
+}
+
+// Compare returns -1, 0 or +1 depending on whether the version precedes, is
+// equivalent to, or follows other.  As mandated by semver, build metadata is
+// ignored.
+func (o Version) Compare(other Version) int {
+	for _, p := range [][2]uint64{
+		{o.Major, other.Major}, {o.Minor, other.Minor}, {o.Patch, other.Patch},
+	} {
+		if p[0] != p[1] {
+			if p[0] < p[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// a pre-release version has lower precedence than the release
+	switch {
+	case len(o.Prerelease) == 0 && len(other.Prerelease) == 0:
+		return 0
+	case len(o.Prerelease) == 0:
+		return 1
+	case len(other.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(o.Prerelease) && i < len(other.Prerelease); i++ {
+		if c := comparePrereleaseID(o.Prerelease[i], other.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(o.Prerelease) < len(other.Prerelease):
+		return -1
+	case len(o.Prerelease) > len(other.Prerelease):
+		return 1
+	}
+
+	return 0
+}
+
+func comparePrereleaseID(a, b string) int {
+	aNum, bNum := isDigits(a), isDigits(b)
+
+	switch {
+	case aNum && bNum:
+		x, _ := strconv.ParseUint(a, 10, 64)
+		y, _ := strconv.ParseUint(b, 10, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+// BuildVersion extracts the semantic version from the build claim.  The
+// version can either be the whole claim (e.g., "1.0.0" or "v1.0.0"), or follow
+// the name of the verifier (e.g., "rrtrap-v1.0.0" or "rrtrap/1.0.0").
+func (o VerifierIdentity) BuildVersion() (*Version, error) {
+	if o.Build == nil {
+		return nil, errors.New(`"build" claim not found`)
+	}
+
+	build := *o.Build
+
+	if v, err := ParseVersion(build); err == nil {
+		return v, nil
+	}
+
+	for i := 1; i < len(build); i++ {
+		if !strings.ContainsRune("-_/@ ", rune(build[i-1])) {
+			continue
+		}
+
+		if v, err := ParseVersion(build[i:]); err == nil {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no semantic version found in build %q", build)
+}
+
+// DeveloperID returns the developer claim in normalised form, provided that it
+// is either an absolute URI (e.g., "https://veraison-project.org") or a fully
+// qualified domain name (e.g., "veraison-project.org").  Scheme and host name
+// are case-insensitive, and are therefore lower-cased.
+func (o VerifierIdentity) DeveloperID() (string, error) {
+	if o.Developer == nil {
+		return "", errors.New(`"developer" claim not found`)
+	}
+
+	return normalizeDeveloper(*o.Developer)
+}
+
+func normalizeDeveloper(developer string) (string, error) {
+	if strings.Contains(developer, ":") {
+		u, err := url.Parse(developer)
+		if err != nil {
+			return "", fmt.Errorf("developer %q is not a valid URI: %w", developer, err)
+		}
+
+		if !u.IsAbs() {
+			return "", fmt.Errorf("developer %q is not an absolute URI", developer)
+		}
+
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+
+		return u.String(), nil
+	}
+
+	if err := checkFQDN(developer); err != nil {
+		return "", fmt.Errorf("developer %q is neither a URI nor an FQDN: %w", developer, err)
+	}
+
+	return strings.ToLower(strings.TrimSuffix(developer, ".")), nil
+}
+
+func checkFQDN(name string) error {
+	name = strings.TrimSuffix(name, ".")
+
+	if len(name) > 253 {
+		return errors.New("too long")
+	}
+
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return errors.New("not fully qualified")
+	}
+
+	for _, l := range labels {
+		if l == "" || len(l) > 63 {
+			return fmt.Errorf("invalid label %q", l)
+		}
+
+		if l[0] == '-' || l[len(l)-1] == '-' {
+			return fmt.Errorf("label %q starts or ends with a hyphen", l)
+		}
+
+		for _, c := range l {
+			if !(c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+				return fmt.Errorf("invalid character %q in label %q", c, l)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Equal returns true if the two identities have the same build and the same
+// developer.  Developers that are URIs or FQDNs are compared in normalised
+// form, others verbatim.
+func (o VerifierIdentity) Equal(other VerifierIdentity) bool {
+	return equalStringPtr(o.Build, other.Build) &&
+		sameDeveloper(o.Developer, other.Developer)
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameDeveloper(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	x, errX := normalizeDeveloper(*a)
+	y, errY := normalizeDeveloper(*b)
+
+	if errX != nil || errY != nil {
+		return *a == *b
+	}
+
+	return x == y
+}
+
+// Matches checks the identity against the requirements of a relying party,
+// e.g., "only trust EARs from developer X, build >= Y".  If developer is not
+// empty, the developer claim must be the same as developer (see Equal).  If
+// minBuild is not empty, it must be a semantic version, and the version
+// extracted from the build claim (see BuildVersion) must not precede it.  An
+// error describing the mismatch is returned if the requirements are not met.
+func (o VerifierIdentity) Matches(developer, minBuild string) error {
+	if developer != "" && !sameDeveloper(o.Developer, &developer) {
+		if o.Developer == nil {
+			return errors.New(`"developer" claim not found`)
+		}
+		return fmt.Errorf("developer: expecting %q, found %q", developer, *o.Developer)
+	}
+
+	if minBuild == "" {
+		return nil
+	}
+
+	minVersion, err := ParseVersion(minBuild)
+	if err != nil {
+		return fmt.Errorf("minimum build %q: %w", minBuild, err)
+	}
+
+	version, err := o.BuildVersion()
+	if err != nil {
+		return err
+	}
+
+	if version.Compare(*minVersion) < 0 {
+		return fmt.Errorf("build: expecting %s or later, found %s", minVersion, version)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion_ok(t *testing.T) {
+	tvs := []struct {
+		input    string
+		expected Version
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"v0.0.1", Version{Patch: 1}},
+		{"1.0.0-rc.1", Version{Major: 1, Prerelease: []string{"rc", "1"}}},
+		{"1.0.0-alpha+exp.sha.5114f85", Version{Major: 1, Prerelease: []string{"alpha"}, Metadata: "exp.sha.5114f85"}},
+		{"1.0.0+20230101", Version{Major: 1, Metadata: "20230101"}},
+	}
+
+	for i, tv := range tvs {
+		v, err := ParseVersion(tv.input)
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, *v, "failed test vector at index %d", i)
+		assert.Equal(t, strings.TrimPrefix(tv.input, "v"), v.String(), "failed test vector at index %d", i)
+	}
+}
+
+func TestParseVersion_ko(t *testing.T) {
+	tvs := []struct {
+		input    string
+		expected string
+	}{
+		{"1.2", `"1.2" is not in MAJOR.MINOR.PATCH format`},
+		{"1.x.3", `"x" is not a number`},
+		{"01.2.3", `"01" has leading zeros`},
+		{"1.2.3-", `pre-release: empty identifier`},
+		{"1.2.3-01", `pre-release: "01" has leading zeros`},
+		{"1.2.3+b_1", `build metadata: invalid character '_' in "b_1"`},
+	}
+
+	for i, tv := range tvs {
+		_, err := ParseVersion(tv.input)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	// in increasing order of precedence, as per semver section 11
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			a, err := ParseVersion(ordered[i])
+			require.NoError(t, err)
+			b, err := ParseVersion(ordered[j])
+			require.NoError(t, err)
+
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+
+			assert.Equal(t, expected, a.Compare(*b), "%s vs %s", ordered[i], ordered[j])
+		}
+	}
+
+	a, _ := ParseVersion("1.0.0+a")
+	b, _ := ParseVersion("1.0.0+b")
+	assert.Equal(t, 0, a.Compare(*b))
+}
+
+func TestVerifierIdentity_BuildVersion(t *testing.T) {
+	tvs := []struct {
+		build    string
+		expected string
+	}{
+		{"1.0.0", "1.0.0"},
+		{"rrtrap-v1.0.0", "1.0.0"},
+		{"rrtrap-v1.0.0-rc.2", "1.0.0-rc.2"},
+		{"veraison/services@2.3.4", "2.3.4"},
+		{"my-verifier 0.9.1+abc", "0.9.1+abc"},
+	}
+
+	for i, tv := range tvs {
+		vid := VerifierIdentity{Build: &tv.build}
+
+		v, err := vid.BuildVersion()
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, v.String(), "failed test vector at index %d", i)
+	}
+
+	build := "rrtrap-latest"
+	_, err := VerifierIdentity{Build: &build}.BuildVersion()
+	assert.EqualError(t, err, `no semantic version found in build "rrtrap-latest"`)
+
+	_, err = VerifierIdentity{}.BuildVersion()
+	assert.EqualError(t, err, `"build" claim not found`)
+}
+
+func TestVerifierIdentity_DeveloperID(t *testing.T) {
+	tvs := []struct {
+		developer string
+		expected  string
+	}{
+		{"HTTPS://Veraison-Project.org/path", "https://veraison-project.org/path"},
+		{"urn:example:acme", "urn:example:acme"},
+		{"Veraison-Project.org.", "veraison-project.org"},
+	}
+
+	for i, tv := range tvs {
+		id, err := VerifierIdentity{Developer: &tv.developer}.DeveloperID()
+		require.NoError(t, err, "failed test vector at index %d", i)
+		assert.Equal(t, tv.expected, id, "failed test vector at index %d", i)
+	}
+
+	ko := []struct {
+		developer string
+		expected  string
+	}{
+		{"Acme Inc.", `developer "Acme Inc." is neither a URI nor an FQDN: not fully qualified`},
+		{"Acme Inc.com", `developer "Acme Inc.com" is neither a URI nor an FQDN: invalid character ' ' in label "Acme Inc"`},
+		{"localhost", `developer "localhost" is neither a URI nor an FQDN: not fully qualified`},
+		{"-acme.com", `developer "-acme.com" is neither a URI nor an FQDN: label "-acme" starts or ends with a hyphen`},
+		{":acme", `developer ":acme" is not a valid URI: parse ":acme": missing protocol scheme`},
+	}
+
+	for i, tv := range ko {
+		_, err := VerifierIdentity{Developer: &tv.developer}.DeveloperID()
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}
+
+func TestVerifierIdentity_Equal(t *testing.T) {
+	build, other := "rrtrap-v1.0.0", "rrtrap-v1.0.1"
+	dev, devUpper, acme := "https://veraison-project.org", "HTTPS://VERAISON-PROJECT.ORG", "Acme Inc."
+
+	a := VerifierIdentity{Build: &build, Developer: &dev}
+
+	assert.True(t, a.Equal(VerifierIdentity{Build: &build, Developer: &devUpper}))
+	assert.False(t, a.Equal(VerifierIdentity{Build: &other, Developer: &dev}))
+	assert.False(t, a.Equal(VerifierIdentity{Build: &build, Developer: &acme}))
+	assert.False(t, a.Equal(VerifierIdentity{Build: &build}))
+	assert.True(t, VerifierIdentity{}.Equal(VerifierIdentity{}))
+}
+
+func TestVerifierIdentity_Matches(t *testing.T) {
+	build, dev := "rrtrap-v1.2.0", "veraison-project.org"
+	vid := VerifierIdentity{Build: &build, Developer: &dev}
+
+	assert.NoError(t, vid.Matches("", ""))
+	assert.NoError(t, vid.Matches("Veraison-Project.org", "1.2.0"))
+	assert.NoError(t, vid.Matches("", "v1.1.9"))
+
+	tvs := []struct {
+		developer string
+		minBuild  string
+		expected  string
+	}{
+		{"acme.com", "", `developer: expecting "acme.com", found "veraison-project.org"`},
+		{"", "1.2.1", "build: expecting 1.2.1 or later, found 1.2.0"},
+		{"", "1.2", `minimum build "1.2": "1.2" is not in MAJOR.MINOR.PATCH format`},
+	}
+
+	for i, tv := range tvs {
+		err := vid.Matches(tv.developer, tv.minBuild)
+		assert.EqualError(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+
+	assert.EqualError(t, VerifierIdentity{}.Matches("acme.com", ""), `"developer" claim not found`)
+}
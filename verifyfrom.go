@@ -0,0 +1,41 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// VerifyFrom is like Verify, but reads the JWT from r, e.g., an HTTP request
+// body.  If WithMaxTokenSize is supplied, at most that many bytes (plus one, to
+// detect oversized tokens) are read, and an ErrTokenTooLarge error is returned
+// as soon as the limit is exceeded, without buffering the rest of the input.
+// Leading and trailing white space is ignored.
+func (o *AttestationResult) VerifyFrom(
+	r io.Reader,
+	alg jwa.KeyAlgorithm,
+	key interface{},
+	opts ...VerifyOption,
+) error {
+	vo := newVerifyOptions(opts)
+
+	if vo.maxSize > 0 {
+		r = io.LimitReader(r, int64(vo.maxSize)+1)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading token: %w", err)
+	}
+
+	if vo.maxSize > 0 && len(data) > vo.maxSize {
+		return fmt.Errorf("%w (more than %d bytes)", ErrTokenTooLarge, vo.maxSize)
+	}
+
+	return o.Verify(bytes.TrimSpace(data), alg, key, opts...)
+}
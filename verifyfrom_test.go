@@ -0,0 +1,74 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestAttestationResult_VerifyFrom_ok(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	r := bytes.NewReader(append(token, '\n'))
+	require.NoError(t, ar.VerifyFrom(r, jwa.ES256, vfyK, WithMaxTokenSize(len(token)+1)))
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns.AsMap(), ar.AsMap())
+}
+
+func TestAttestationResult_VerifyFrom_fail(t *testing.T) {
+	_, vfyK := testKeyPair(t)
+
+	var ar AttestationResult
+
+	// a reader that never ends must not be read beyond the limit
+	endless := io.MultiReader(strings.NewReader("eyJ"), strings.NewReader(strings.Repeat("A", 1<<20)))
+
+	err := ar.VerifyFrom(endless, jwa.ES256, vfyK, WithMaxTokenSize(1024))
+	assert.EqualError(t, err, "token too large (more than 1024 bytes)")
+	assert.ErrorIs(t, err, ErrTokenTooLarge)
+
+	err = ar.VerifyFrom(failingReader{}, jwa.ES256, vfyK)
+	assert.EqualError(t, err, "reading token: connection reset")
+}
+
+func TestAttestationResult_WithMaxTokenSize(t *testing.T) {
+	sigK, vfyK := testKeyPair(t)
+	signer, verifier := testCOSESignerVerifier(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK)
+	require.NoError(t, err)
+
+	cwt, err := testAttestationResultsWithVeraisonExtns.SignCWT(signer)
+	require.NoError(t, err)
+
+	var ar AttestationResult
+
+	assert.NoError(t, ar.Verify(token, jwa.ES256, vfyK, WithMaxTokenSize(len(token))))
+	assert.ErrorIs(t, ar.Verify(token, jwa.ES256, vfyK, WithMaxTokenSize(len(token)-1)), ErrTokenTooLarge)
+
+	assert.NoError(t, ar.VerifyCWT(cwt, verifier, WithMaxTokenSize(len(cwt))))
+	assert.ErrorIs(t, ar.VerifyCWT(cwt, verifier, WithMaxTokenSize(len(cwt)-1)), ErrTokenTooLarge)
+
+	err = ar.VerifyWithKeySet(token, nil, WithMaxTokenSize(10))
+	assert.EqualError(t, err, fmt.Sprintf("token too large (%d bytes, maximum is 10)", len(token)))
+}
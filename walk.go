@@ -0,0 +1,82 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Walk calls yield for every claim in the AttestationResult, including
+// extensions, nested claims (e.g., those in each submod and trust vector) and
+// array elements.  Each claim is identified by its JSON Pointer (RFC6901) path,
+// e.g., "/submods/PSA_IOT/ear.status", and its value is reported as it appears
+// in the JSON serialization, i.e., using the types produced by
+// encoding/json (numbers are json.Number).  Containers are reported before
+// their contents, and object members are visited in lexicographic order, so
+// the walk is deterministic.  The walk stops as soon as yield returns false.
+//
+// The signature of Walk is that of an iter.Seq2[string, any], so that with Go
+// 1.23 and later the claims can be iterated using "for path, v := range
+// ar.Walk".
+func (o AttestationResult) Walk(yield func(path string, value any) bool) {
+	v, err := o.jsonValue()
+	if err != nil {
+		// as for AsMap, this can only happen because of a bug in the
+		// implementation of AttestationResult
+		panic(err)
+	}
+
+	walkValue("", v, yield)
+}
+
+func (o AttestationResult) jsonValue() (interface{}, error) {
+	j, err := json.Marshal(o.AsMap())
+	if err != nil {
+		return nil, fmt.Errorf("encoding claims-set: %w", err)
+	}
+
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decoding claims-set: %w", err)
+	}
+
+	return v, nil
+}
+
+// walkValue visits the children of v, returning false if the walk has been
+// stopped
+func walkValue(pointer string, v interface{}, yield func(string, any) bool) bool {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			p := pointer + "/" + escapeJSONPointer(k)
+			if !yield(p, t[k]) || !walkValue(p, t[k], yield) {
+				return false
+			}
+		}
+	case []interface{}:
+		for i, e := range t {
+			p := pointer + "/" + strconv.Itoa(i)
+			if !yield(p, e) || !walkValue(p, e, yield) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
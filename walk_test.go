@@ -0,0 +1,73 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttestationResult_Walk(t *testing.T) {
+	ar := AttestationResult{
+		Profile:    &testProfile,
+		IssuedAt:   &testIAT,
+		VerifierID: &testVerifierID,
+		Nonce:      &Nonces{testNonce, testNonce},
+		Submods: map[string]*Appraisal{
+			"a/b": {
+				Status:      &testStatus,
+				TrustVector: &TrustVector{Executables: ApprovedRuntimeClaim},
+			},
+		},
+	}
+
+	var paths []string
+	values := map[string]any{}
+
+	ar.Walk(func(path string, value any) bool {
+		paths = append(paths, path)
+		values[path] = value
+		return true
+	})
+
+	assert.Equal(t, []string{
+		"/ear.verifier-id",
+		"/ear.verifier-id/build",
+		"/ear.verifier-id/developer",
+		"/eat_nonce",
+		"/eat_nonce/0",
+		"/eat_nonce/1",
+		"/eat_profile",
+		"/iat",
+		"/submods",
+		"/submods/a~1b",
+		"/submods/a~1b/ear.status",
+		"/submods/a~1b/ear.trustworthiness-vector",
+		"/submods/a~1b/ear.trustworthiness-vector/configuration",
+		"/submods/a~1b/ear.trustworthiness-vector/executables",
+		"/submods/a~1b/ear.trustworthiness-vector/file-system",
+		"/submods/a~1b/ear.trustworthiness-vector/hardware",
+		"/submods/a~1b/ear.trustworthiness-vector/instance-identity",
+		"/submods/a~1b/ear.trustworthiness-vector/runtime-opaque",
+		"/submods/a~1b/ear.trustworthiness-vector/sourced-data",
+		"/submods/a~1b/ear.trustworthiness-vector/storage-opaque",
+	}, paths)
+
+	assert.Equal(t, "affirming", values["/submods/a~1b/ear.status"])
+	assert.Equal(t, json.Number("1666091373"), values["/iat"])
+	assert.Equal(t, json.Number("2"), values["/submods/a~1b/ear.trustworthiness-vector/executables"])
+}
+
+func TestAttestationResult_Walk_stop(t *testing.T) {
+	var paths []string
+
+	testAttestationResultsWithVeraisonExtns.Walk(func(path string, value any) bool {
+		paths = append(paths, path)
+		return path != "/ear.verifier-id/build"
+	})
+
+	assert.Equal(t, []string{"/ear.verifier-id", "/ear.verifier-id/build"}, paths)
+}
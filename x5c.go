@@ -0,0 +1,72 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// VerifyWithCertChain is like Verify, but the verification key is taken from
+// the certificate chain in the x5c JWS header, which is first validated
+// against the supplied pool of root certificates (at the time given by the
+// clock set with WithClock).  The algorithm is taken from the JWS header.
+func (o *AttestationResult) VerifyWithCertChain(
+	data []byte,
+	roots *x509.CertPool,
+	opts ...VerifyOption,
+) error {
+	if err := newVerifyOptions(opts).checkTokenSize(data); err != nil {
+		return err
+	}
+
+	hdrs, err := protectedHeaders(data)
+	if err != nil {
+		return err
+	}
+
+	chain := hdrs.X509CertChain()
+	if chain == nil || chain.Len() == 0 {
+		return errors.New("no x5c in JWS header")
+	}
+
+	certs := make([]*x509.Certificate, 0, chain.Len())
+
+	for i := 0; i < chain.Len(); i++ {
+		b64, _ := chain.Get(i)
+
+		der, err := base64.StdEncoding.DecodeString(string(b64))
+		if err != nil {
+			return fmt.Errorf("decoding x5c[%d]: %w", i, err)
+		}
+
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("parsing x5c[%d]: %w", i, err)
+		}
+
+		certs = append(certs, c)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+
+	vo := newVerifyOptions(opts)
+
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   vo.now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("validating x5c: %w", err)
+	}
+
+	return o.Verify(data, hdrs.Algorithm(), certs[0].PublicKey, opts...)
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package ear
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCertChain returns a root CA certificate, and a leaf certificate issued
+// by it for the test ECDSA key
+func testCertChain(t *testing.T) (*x509.Certificate, *x509.Certificate) {
+	sigK, _ := testKeyPair(t)
+
+	var leafKey ecdsa.PrivateKey
+	require.NoError(t, sigK.Raw(&leafKey))
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Verifier"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	return ca, leaf
+}
+
+func TestVerifyWithCertChain_pass(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+	ca, leaf := testCertChain(t)
+
+	token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK,
+		WithCertChain(leaf, ca))
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	var actual AttestationResult
+
+	err = actual.VerifyWithCertChain(token, roots)
+	require.NoError(t, err)
+	assert.Equal(t, testAttestationResultsWithVeraisonExtns, actual)
+}
+
+func TestVerifyWithCertChain_fail(t *testing.T) {
+	sigK, _ := testKeyPair(t)
+	ca, leaf := testCertChain(t)
+	otherCA, _ := testCertChain(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(otherCA)
+
+	later := func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	tvs := []struct {
+		opts     []SignOption
+		roots    *x509.CertPool
+		vopts    []VerifyOption
+		expected string
+	}{
+		{
+			roots:    roots,
+			expected: "no x5c in JWS header",
+		},
+		{
+			opts:     []SignOption{WithCertChain(leaf)},
+			roots:    otherRoots,
+			expected: "validating x5c: x509: certificate signed by unknown authority",
+		},
+		{
+			opts:     []SignOption{WithCertChain(leaf)},
+			roots:    roots,
+			vopts:    []VerifyOption{WithClock(later)},
+			expected: "validating x5c: x509: certificate has expired or is not yet valid",
+		},
+	}
+
+	for i, tv := range tvs {
+		token, err := testAttestationResultsWithVeraisonExtns.Sign(jwa.ES256, sigK, tv.opts...)
+		require.NoError(t, err)
+
+		var actual AttestationResult
+
+		err = actual.VerifyWithCertChain(token, tv.roots, tv.vopts...)
+		assert.ErrorContains(t, err, tv.expected, "failed test vector at index %d", i)
+	}
+}